@@ -49,6 +49,11 @@ func GetTeamPerms(c context.Context, user *model.User, org string) (*model.Perm,
 	if err != nil {
 		return nil, err
 	}
+	// remotes that do not support organization permissions grant no
+	// permissions.
+	if perm == nil {
+		perm = new(model.Perm)
+	}
 	Set(c, key, perm)
 	return perm, nil
 }
@@ -89,6 +94,16 @@ func GetRepoMap(c context.Context, user *model.User) (map[string]bool, error) {
 	return repom, nil
 }
 
+// DeleteTeamPerms evicts the cached user permissions of the organization
+// from the cache associated with the current context.
+func DeleteTeamPerms(c context.Context, user *model.User, org string) error {
+	key := fmt.Sprintf("perms:%s:%s",
+		user.Login,
+		org,
+	)
+	return Delete(c, key)
+}
+
 // DeleteRepos evicts the cached user repositories from the cache associated
 // with the current context.
 func DeleteRepos(c context.Context, user *model.User) error {
@@ -11,6 +11,9 @@ type Client interface {
 	// Self returns the currently authenticated user.
 	Self() (*model.User, error)
 
+	// SelfPatch updates the preferences of the currently authenticated user.
+	SelfPatch(*model.UserPatch) (*model.User, error)
+
 	// User returns a user by login.
 	User(string) (*model.User, error)
 
@@ -26,6 +29,31 @@ type Client interface {
 	// UserDel deletes a user account.
 	UserDel(string) error
 
+	// UserSessionRevoke revokes the sessions of a user.
+	UserSessionRevoke(string) error
+
+	// UserTokenCreate creates an access token for a machine user.
+	UserTokenCreate(string, *model.AccessToken) (*model.AccessToken, error)
+
+	// UserPermPost grants a machine user permissions to a repository.
+	UserPermPost(string, string, string, *model.RepoPerm) (*model.RepoPerm, error)
+
+	// UserPermDel revokes the permissions of a machine user to a repository.
+	UserPermDel(string, string, string) error
+
+	// SessionRevoke revokes the sessions of the currently authenticated user.
+	SessionRevoke() error
+
+	// AccessTokenList returns the personal access tokens of the currently
+	// authenticated user.
+	AccessTokenList() ([]*model.AccessToken, error)
+
+	// AccessTokenCreate creates a personal access token.
+	AccessTokenCreate(*model.AccessToken) (*model.AccessToken, error)
+
+	// AccessTokenDelete revokes a personal access token.
+	AccessTokenDelete(int64) error
+
 	// Repo returns a repository by name.
 	Repo(string, string) (*model.Repo, error)
 
@@ -45,6 +73,14 @@ type Client interface {
 	// RepoRepair repairs the repository hooks.
 	RepoRepair(string, string) error
 
+	// RepoExport exports the repository settings, secrets, registries and
+	// cron jobs.
+	RepoExport(string, string) (*model.RepoExport, error)
+
+	// RepoImport imports the repository settings, secrets, registries and
+	// cron jobs.
+	RepoImport(string, string, *model.RepoExport) (*model.RepoExport, error)
+
 	// RepoDel deletes a repository.
 	RepoDel(string, string) error
 
@@ -62,6 +98,21 @@ type Client interface {
 	// BuildQueue returns a list of enqueued builds.
 	BuildQueue() ([]*model.Feed, error)
 
+	// Queue returns the pending and running pipelines in the build queue.
+	Queue() (*model.QueueInfo, error)
+
+	// QueuePause pauses the build queue.
+	QueuePause() error
+
+	// QueueResume resumes the build queue.
+	QueueResume() error
+
+	// LogLevel returns the logging level of the server.
+	LogLevel() (*model.LogLevel, error)
+
+	// SetLogLevel changes the logging level of the server.
+	SetLogLevel(string) (*model.LogLevel, error)
+
 	// BuildStart re-starts a stopped build.
 	BuildStart(string, string, int, map[string]string) (*model.Build, error)
 
@@ -81,10 +132,27 @@ type Client interface {
 	// BuildLogs returns the build logs for the specified job.
 	BuildLogs(string, string, int, int) (io.ReadCloser, error)
 
+	// BuildLogStream returns the server-sent event stream of the logs for
+	// the specified running job.
+	BuildLogStream(string, string, int, int) (io.ReadCloser, error)
+
 	// Deploy triggers a deployment for an existing build using the specified
 	// target environment.
 	Deploy(string, string, int, string, map[string]string) (*model.Build, error)
 
+	// Rollback re-runs the deployment of an earlier successful build against
+	// the specified target environment.
+	Rollback(string, string, int, string, map[string]string) (*model.Build, error)
+
+	// DeploymentList returns the deployments and rollbacks of the
+	// repository to the target environment, or to all environments if the
+	// target is empty.
+	DeploymentList(string, string, string) ([]*model.Build, error)
+
+	// EnvironmentList returns the latest deployment or rollback of each
+	// environment of the repository.
+	EnvironmentList(string, string) ([]*model.Build, error)
+
 	// Registry returns a registry by hostname.
 	Registry(owner, name, hostname string) (*model.Registry, error)
 
@@ -114,4 +182,39 @@ type Client interface {
 
 	// SecretDelete deletes a secret.
 	SecretDelete(owner, name, secret string) error
+
+	// OrgSecret returns an organization secret by name. If the owner is
+	// empty the global secret is returned.
+	OrgSecret(owner, secret string) (*model.OrgSecret, error)
+
+	// OrgSecretList returns a list of all organization secrets. If the owner
+	// is empty the global secrets are returned.
+	OrgSecretList(owner string) ([]*model.OrgSecret, error)
+
+	// OrgSecretCreate creates an organization secret. If the owner is empty
+	// a global secret is created.
+	OrgSecretCreate(owner string, secret *model.OrgSecret) (*model.OrgSecret, error)
+
+	// OrgSecretUpdate updates an organization secret. If the owner is empty
+	// the global secret is updated.
+	OrgSecretUpdate(owner string, secret *model.OrgSecret) (*model.OrgSecret, error)
+
+	// OrgSecretDelete deletes an organization secret. If the owner is empty
+	// the global secret is deleted.
+	OrgSecretDelete(owner, secret string) error
+
+	// Template returns a pipeline template of the namespace by name.
+	Template(namespace, name string) (*model.Template, error)
+
+	// TemplateList returns a list of all pipeline templates of the namespace.
+	TemplateList(namespace string) ([]*model.Template, error)
+
+	// TemplateCreate creates a pipeline template of the namespace.
+	TemplateCreate(namespace string, template *model.Template) (*model.Template, error)
+
+	// TemplateUpdate updates a pipeline template of the namespace.
+	TemplateUpdate(namespace string, template *model.Template) (*model.Template, error)
+
+	// TemplateDelete deletes a pipeline template of the namespace.
+	TemplateDelete(namespace, name string) error
 }
@@ -29,22 +29,45 @@ const (
 	pathSelf           = "%s/api/user"
 	pathFeed           = "%s/api/user/feed"
 	pathRepos          = "%s/api/user/repos"
+	pathTokens         = "%s/api/user/tokens"
+	pathSessionRevoke  = "%s/api/user/sessions/revoke"
+	pathToken          = "%s/api/user/tokens/%d"
 	pathRepo           = "%s/api/repos/%s/%s"
 	pathChown          = "%s/api/repos/%s/%s/chown"
 	pathRepair         = "%s/api/repos/%s/%s/repair"
+	pathRepoExport     = "%s/api/repos/%s/%s/export"
+	pathRepoImport     = "%s/api/repos/%s/%s/import"
 	pathBuilds         = "%s/api/repos/%s/%s/builds"
 	pathBuild          = "%s/api/repos/%s/%s/builds/%v"
 	pathApprove        = "%s/api/repos/%s/%s/builds/%d/approve"
 	pathDecline        = "%s/api/repos/%s/%s/builds/%d/decline"
+	pathPromote        = "%s/api/repos/%s/%s/builds/%d/promote"
+	pathRollback       = "%s/api/repos/%s/%s/builds/%d/rollback"
+	pathDeployments    = "%s/api/repos/%s/%s/deployments"
+	pathEnvironments   = "%s/api/repos/%s/%s/environments"
 	pathJob            = "%s/api/repos/%s/%s/builds/%d/%d"
 	pathLog            = "%s/api/repos/%s/%s/logs/%d/%d"
+	pathLogStream      = "%s/stream/logs/%s/%s/%d/%d"
 	pathRepoSecrets    = "%s/api/repos/%s/%s/secrets"
 	pathRepoSecret     = "%s/api/repos/%s/%s/secrets/%s"
+	pathOrgSecrets     = "%s/api/orgs/%s/secrets"
+	pathOrgSecret      = "%s/api/orgs/%s/secrets/%s"
+	pathGlobalSecrets  = "%s/api/secrets"
+	pathGlobalSecret   = "%s/api/secrets/%s"
+	pathTemplates      = "%s/api/orgs/%s/templates"
+	pathTemplate       = "%s/api/orgs/%s/templates/%s"
 	pathRepoRegistries = "%s/api/repos/%s/%s/registry"
 	pathRepoRegistry   = "%s/api/repos/%s/%s/registry/%s"
 	pathUsers          = "%s/api/users"
 	pathUser           = "%s/api/users/%s"
+	pathUserTokens     = "%s/api/users/%s/tokens"
+	pathUserPerm       = "%s/api/users/%s/perms/%s/%s"
+	pathUserSessions   = "%s/api/users/%s/sessions/revoke"
 	pathBuildQueue     = "%s/api/builds"
+	pathQueue          = "%s/api/queue"
+	pathQueuePause     = "%s/api/queue/pause"
+	pathQueueResume    = "%s/api/queue/resume"
+	pathLogLevel       = "%s/api/debug/log/level"
 )
 
 type client struct {
@@ -102,6 +125,14 @@ func (c *client) Self() (*model.User, error) {
 	return out, err
 }
 
+// SelfPatch updates the preferences of the currently authenticated user.
+func (c *client) SelfPatch(in *model.UserPatch) (*model.User, error) {
+	out := new(model.User)
+	uri := fmt.Sprintf(pathSelf, c.base)
+	err := c.patch(uri, in, out)
+	return out, err
+}
+
 // User returns a user by login.
 func (c *client) User(login string) (*model.User, error) {
 	out := new(model.User)
@@ -141,6 +172,65 @@ func (c *client) UserDel(login string) error {
 	return err
 }
 
+// UserSessionRevoke revokes the sessions of a user, which forces the user
+// to login again.
+func (c *client) UserSessionRevoke(login string) error {
+	uri := fmt.Sprintf(pathUserSessions, c.base, login)
+	return c.post(uri, nil, nil)
+}
+
+// UserTokenCreate creates an access token for a machine user.
+func (c *client) UserTokenCreate(login string, in *model.AccessToken) (*model.AccessToken, error) {
+	out := new(model.AccessToken)
+	uri := fmt.Sprintf(pathUserTokens, c.base, login)
+	err := c.post(uri, in, out)
+	return out, err
+}
+
+// UserPermPost grants a machine user permissions to a repository.
+func (c *client) UserPermPost(login, owner, name string, in *model.RepoPerm) (*model.RepoPerm, error) {
+	out := new(model.RepoPerm)
+	uri := fmt.Sprintf(pathUserPerm, c.base, login, owner, name)
+	err := c.post(uri, in, out)
+	return out, err
+}
+
+// UserPermDel revokes the permissions of a machine user to a repository.
+func (c *client) UserPermDel(login, owner, name string) error {
+	uri := fmt.Sprintf(pathUserPerm, c.base, login, owner, name)
+	return c.delete(uri)
+}
+
+// SessionRevoke revokes the sessions of the currently authenticated user.
+func (c *client) SessionRevoke() error {
+	uri := fmt.Sprintf(pathSessionRevoke, c.base)
+	return c.post(uri, nil, nil)
+}
+
+// AccessTokenList returns the personal access tokens of the currently
+// authenticated user.
+func (c *client) AccessTokenList() ([]*model.AccessToken, error) {
+	var out []*model.AccessToken
+	uri := fmt.Sprintf(pathTokens, c.base)
+	err := c.get(uri, &out)
+	return out, err
+}
+
+// AccessTokenCreate creates a personal access token. The returned token
+// includes the signed token value.
+func (c *client) AccessTokenCreate(in *model.AccessToken) (*model.AccessToken, error) {
+	out := new(model.AccessToken)
+	uri := fmt.Sprintf(pathTokens, c.base)
+	err := c.post(uri, in, out)
+	return out, err
+}
+
+// AccessTokenDelete revokes a personal access token.
+func (c *client) AccessTokenDelete(id int64) error {
+	uri := fmt.Sprintf(pathToken, c.base, id)
+	return c.delete(uri)
+}
+
 // Repo returns a repository by name.
 func (c *client) Repo(owner string, name string) (*model.Repo, error) {
 	out := new(model.Repo)
@@ -188,6 +278,24 @@ func (c *client) RepoPatch(owner, name string, in *model.RepoPatch) (*model.Repo
 	return out, err
 }
 
+// RepoExport exports the repository settings, secrets, registries and cron
+// jobs.
+func (c *client) RepoExport(owner, name string) (*model.RepoExport, error) {
+	out := new(model.RepoExport)
+	uri := fmt.Sprintf(pathRepoExport, c.base, owner, name)
+	err := c.get(uri, out)
+	return out, err
+}
+
+// RepoImport imports the repository settings, secrets, registries and cron
+// jobs.
+func (c *client) RepoImport(owner, name string, in *model.RepoExport) (*model.RepoExport, error) {
+	out := new(model.RepoExport)
+	uri := fmt.Sprintf(pathRepoImport, c.base, owner, name)
+	err := c.post(uri, in, out)
+	return out, err
+}
+
 // RepoDel deletes a repository.
 func (c *client) RepoDel(owner, name string) error {
 	uri := fmt.Sprintf(pathRepo, c.base, owner, name)
@@ -231,6 +339,42 @@ func (c *client) BuildQueue() ([]*model.Feed, error) {
 	return out, err
 }
 
+// Queue returns the pending and running pipelines in the build queue.
+func (c *client) Queue() (*model.QueueInfo, error) {
+	out := new(model.QueueInfo)
+	uri := fmt.Sprintf(pathQueue, c.base)
+	err := c.get(uri, out)
+	return out, err
+}
+
+// QueuePause pauses the build queue.
+func (c *client) QueuePause() error {
+	uri := fmt.Sprintf(pathQueuePause, c.base)
+	return c.post(uri, nil, nil)
+}
+
+// QueueResume resumes the build queue.
+func (c *client) QueueResume() error {
+	uri := fmt.Sprintf(pathQueueResume, c.base)
+	return c.post(uri, nil, nil)
+}
+
+// LogLevel returns the logging level of the server.
+func (c *client) LogLevel() (*model.LogLevel, error) {
+	out := new(model.LogLevel)
+	uri := fmt.Sprintf(pathLogLevel, c.base)
+	err := c.get(uri, out)
+	return out, err
+}
+
+// SetLogLevel changes the logging level of the server.
+func (c *client) SetLogLevel(level string) (*model.LogLevel, error) {
+	out := new(model.LogLevel)
+	uri := fmt.Sprintf(pathLogLevel, c.base)
+	err := c.post(uri, &model.LogLevel{Level: level}, out)
+	return out, err
+}
+
 // BuildStart re-starts a stopped build.
 func (c *client) BuildStart(owner, name string, num int, params map[string]string) (*model.Build, error) {
 	out := new(model.Build)
@@ -280,19 +424,59 @@ func (c *client) BuildLogs(owner, name string, num, job int) (io.ReadCloser, err
 	return stream(c.client, uri, "GET", nil, nil)
 }
 
+// BuildLogStream returns the server-sent event stream of the logs for the
+// specified running job.
+func (c *client) BuildLogStream(owner, name string, num, job int) (io.ReadCloser, error) {
+	uri := fmt.Sprintf(pathLogStream, c.base, owner, name, num, job)
+	return stream(c.client, uri, "GET", nil, nil)
+}
+
 // Deploy triggers a deployment for an existing build using the
 // specified target environment.
 func (c *client) Deploy(owner, name string, num int, env string, params map[string]string) (*model.Build, error) {
 	out := new(model.Build)
 	val := parseToQueryParams(params)
-	val.Set("fork", "true")
-	val.Set("event", "deployment")
-	val.Set("deploy_to", env)
-	uri := fmt.Sprintf(pathBuild, c.base, owner, name, num)
+	val.Set("target", env)
+	uri := fmt.Sprintf(pathPromote, c.base, owner, name, num)
 	err := c.post(uri+"?"+val.Encode(), nil, out)
 	return out, err
 }
 
+// Rollback re-runs the deployment of an earlier successful build against
+// the specified target environment.
+func (c *client) Rollback(owner, name string, num int, env string, params map[string]string) (*model.Build, error) {
+	out := new(model.Build)
+	val := parseToQueryParams(params)
+	if env != "" {
+		val.Set("target", env)
+	}
+	uri := fmt.Sprintf(pathRollback, c.base, owner, name, num)
+	err := c.post(uri+"?"+val.Encode(), nil, out)
+	return out, err
+}
+
+// DeploymentList returns the deployments and rollbacks of the repository to
+// the target environment, or to all environments if the target is empty.
+func (c *client) DeploymentList(owner, name, env string) ([]*model.Build, error) {
+	var out []*model.Build
+	val := url.Values{}
+	if env != "" {
+		val.Set("target", env)
+	}
+	uri := fmt.Sprintf(pathDeployments, c.base, owner, name)
+	err := c.get(uri+"?"+val.Encode(), &out)
+	return out, err
+}
+
+// EnvironmentList returns the latest deployment or rollback of each
+// environment of the repository.
+func (c *client) EnvironmentList(owner, name string) ([]*model.Build, error) {
+	var out []*model.Build
+	uri := fmt.Sprintf(pathEnvironments, c.base, owner, name)
+	err := c.get(uri, &out)
+	return out, err
+}
+
 // Registry returns a registry by hostname.
 func (c *client) Registry(owner, name, hostname string) (*model.Registry, error) {
 	out := new(model.Registry)
@@ -369,6 +553,92 @@ func (c *client) SecretDelete(owner, name, secret string) error {
 	return c.delete(uri)
 }
 
+// OrgSecret returns an organization secret by name.
+func (c *client) OrgSecret(owner, secret string) (*model.OrgSecret, error) {
+	out := new(model.OrgSecret)
+	err := c.get(c.orgSecretPath(owner, secret), out)
+	return out, err
+}
+
+// OrgSecretList returns a list of all organization secrets.
+func (c *client) OrgSecretList(owner string) ([]*model.OrgSecret, error) {
+	var out []*model.OrgSecret
+	err := c.get(c.orgSecretPath(owner, ""), &out)
+	return out, err
+}
+
+// OrgSecretCreate creates an organization secret.
+func (c *client) OrgSecretCreate(owner string, in *model.OrgSecret) (*model.OrgSecret, error) {
+	out := new(model.OrgSecret)
+	err := c.post(c.orgSecretPath(owner, ""), in, out)
+	return out, err
+}
+
+// OrgSecretUpdate updates an organization secret.
+func (c *client) OrgSecretUpdate(owner string, in *model.OrgSecret) (*model.OrgSecret, error) {
+	out := new(model.OrgSecret)
+	err := c.patch(c.orgSecretPath(owner, in.Name), in, out)
+	return out, err
+}
+
+// OrgSecretDelete deletes an organization secret.
+func (c *client) OrgSecretDelete(owner, secret string) error {
+	return c.delete(c.orgSecretPath(owner, secret))
+}
+
+// Template returns a pipeline template of the namespace by name.
+func (c *client) Template(namespace, name string) (*model.Template, error) {
+	out := new(model.Template)
+	uri := fmt.Sprintf(pathTemplate, c.base, namespace, name)
+	err := c.get(uri, out)
+	return out, err
+}
+
+// TemplateList returns a list of all pipeline templates of the namespace.
+func (c *client) TemplateList(namespace string) ([]*model.Template, error) {
+	var out []*model.Template
+	uri := fmt.Sprintf(pathTemplates, c.base, namespace)
+	err := c.get(uri, &out)
+	return out, err
+}
+
+// TemplateCreate creates a pipeline template of the namespace.
+func (c *client) TemplateCreate(namespace string, in *model.Template) (*model.Template, error) {
+	out := new(model.Template)
+	uri := fmt.Sprintf(pathTemplates, c.base, namespace)
+	err := c.post(uri, in, out)
+	return out, err
+}
+
+// TemplateUpdate updates a pipeline template of the namespace.
+func (c *client) TemplateUpdate(namespace string, in *model.Template) (*model.Template, error) {
+	out := new(model.Template)
+	uri := fmt.Sprintf(pathTemplate, c.base, namespace, in.Name)
+	err := c.patch(uri, in, out)
+	return out, err
+}
+
+// TemplateDelete deletes a pipeline template of the namespace.
+func (c *client) TemplateDelete(namespace, name string) error {
+	uri := fmt.Sprintf(pathTemplate, c.base, namespace, name)
+	return c.delete(uri)
+}
+
+// helper function returns the organization secret path, or the global
+// secret path if the owner is empty.
+func (c *client) orgSecretPath(owner, secret string) string {
+	switch {
+	case owner == "" && secret == "":
+		return fmt.Sprintf(pathGlobalSecrets, c.base)
+	case owner == "":
+		return fmt.Sprintf(pathGlobalSecret, c.base, secret)
+	case secret == "":
+		return fmt.Sprintf(pathOrgSecrets, c.base, owner)
+	default:
+		return fmt.Sprintf(pathOrgSecret, c.base, owner, secret)
+	}
+}
+
 //
 // http request helper functions
 //
@@ -3,21 +3,24 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"math"
 	"net/url"
+	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/cncd/pipeline/pipeline"
 	"github.com/cncd/pipeline/pipeline/backend"
-	"github.com/cncd/pipeline/pipeline/backend/docker"
 	"github.com/cncd/pipeline/pipeline/interrupt"
 	"github.com/cncd/pipeline/pipeline/multipart"
 	"github.com/cncd/pipeline/pipeline/rpc"
+	"github.com/drone/drone/shared/trace"
 	"github.com/drone/drone/version"
 
 	"github.com/tevino/abool"
@@ -65,6 +68,7 @@ var Command = cli.Command{
 		cli.IntFlag{
 			Name:   "max-procs",
 			EnvVar: "DRONE_MAX_PROCS",
+			Usage:  "total job weight processed concurrently by this agent",
 			Value:  1,
 		},
 		cli.StringFlag{
@@ -72,6 +76,71 @@ var Command = cli.Command{
 			EnvVar: "DRONE_PLATFORM",
 			Value:  "linux/amd64",
 		},
+		cli.StringSliceFlag{
+			Name:   "node-label",
+			EnvVar: "DRONE_NODE_LABELS",
+			Usage:  "agent label matched by the node selectors of the pipelines (e.g. gpu=true)",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_ENGINE",
+			Name:   "engine",
+			Usage:  "container engine (docker or podman)",
+			Value:  "docker",
+		},
+		cli.IntFlag{
+			EnvVar: "DRONE_CLONE_DEPTH",
+			Name:   "clone-depth",
+			Usage:  "default clone depth",
+		},
+		cli.BoolFlag{
+			EnvVar: "DRONE_CLONE_RECURSIVE",
+			Name:   "clone-recursive",
+			Usage:  "clone submodules by default",
+		},
+		cli.IntFlag{
+			EnvVar: "DRONE_CLONE_RETRIES",
+			Name:   "clone-retries",
+			Usage:  "number of times a failed clone step is retried",
+			Value:  3,
+		},
+		cli.DurationFlag{
+			EnvVar: "DRONE_CLONE_BACKOFF",
+			Name:   "clone-backoff",
+			Usage:  "clone step retry backoff interval",
+			Value:  time.Second * 5,
+		},
+		cli.Int64Flag{
+			EnvVar: "DRONE_MAX_ARTIFACT_SIZE",
+			Name:   "max-artifact-size",
+			Usage:  "maximum size in bytes of each uploaded artifact file",
+			Value:  100000000,
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_HOOK_PRE",
+			Name:   "hook-pre",
+			Usage:  "command executed before each pipeline",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_HOOK_POST",
+			Name:   "hook-post",
+			Usage:  "command executed after each pipeline",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_HOOK_IMAGE",
+			Name:   "hook-image",
+			Usage:  "image that executes the hook commands with the container engine, instead of on the host",
+		},
+		cli.DurationFlag{
+			EnvVar: "DRONE_HOOK_TIMEOUT",
+			Name:   "hook-timeout",
+			Usage:  "hook command timeout",
+			Value:  time.Minute * 10,
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_TRACING_ENDPOINT",
+			Name:   "tracing-endpoint",
+			Usage:  "zipkin compatible span collector (e.g. http://localhost:9411/api/v2/spans)",
+		},
 	},
 }
 
@@ -86,8 +155,18 @@ func loop(c *cli.Context) error {
 		Labels: map[string]string{
 			"platform": c.String("platform"),
 		},
+		Node: map[string]string{},
+	}
+	for _, label := range c.StringSlice("node-label") {
+		parts := strings.SplitN(label, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("Invalid node label %q, expected key=value", label)
+		}
+		filter.Node[parts[0]] = parts[1]
 	}
 
+	hostname, _ := os.Hostname()
+
 	client, err := rpc.NewClient(
 		endpoint.String(),
 		rpc.WithRetryLimit(
@@ -103,12 +182,22 @@ func loop(c *cli.Context) error {
 			"X-Drone-Version",
 			version.Version.String(),
 		),
+		rpc.WithHeader(
+			"X-Drone-Hostname",
+			hostname,
+		),
 	)
 	if err != nil {
 		return err
 	}
 	defer client.Close()
 
+	var tracer *trace.Tracer
+	if endpoint := c.String("tracing-endpoint"); endpoint != "" {
+		tracer = trace.New(endpoint, "drone-agent")
+		defer tracer.Close()
+	}
+
 	sigterm := abool.New()
 	ctx := context.Background()
 	ctx = interrupt.WithContextFunc(ctx, func() {
@@ -116,49 +205,103 @@ func loop(c *cli.Context) error {
 		sigterm.Set()
 	})
 
+	r := &runner{
+		client: client,
+		tracer: tracer,
+		engine: c.String("engine"),
+		limit:  c.Int64("max-artifact-size"),
+		clone: &cloneOpts{
+			depth:     c.Int("clone-depth"),
+			recursive: c.Bool("clone-recursive"),
+			retries:   c.Int("clone-retries"),
+			backoff:   c.Duration("clone-backoff"),
+		},
+		hooks: &hookOpts{
+			pre:     c.String("hook-pre"),
+			post:    c.String("hook-post"),
+			image:   c.String("hook-image"),
+			timeout: c.Duration("hook-timeout"),
+		},
+	}
+
+	// verify the container engine is supported before
+	// requesting work from the queue.
+	if _, err := newEngine(r.engine); err != nil {
+		return err
+	}
+
+	// the agent schedules jobs by total weight. A job occupies
+	// one slot unless it declares a weight label in the yaml, and
+	// at most every slot of the agent.
+	slots := newSlots(c.Int("max-procs"))
+	filter.Capacity = c.Int("max-procs")
+
 	var wg sync.WaitGroup
-	parallel := c.Int("max-procs")
-	wg.Add(parallel)
+	defer wg.Wait()
 
-	for i := 0; i < parallel; i++ {
+	for {
+		if sigterm.IsSet() {
+			return nil
+		}
+		filter.Weight = slots.wait()
+
+		log.Println("pipeline: request next execution")
+
+		// get the next job from the queue
+		polled := time.Now()
+		work, err := client.Next(ctx, filter)
+		if err != nil {
+			log.Printf("build runner encountered error: exiting: %s", err)
+			return nil
+		}
+		if work == nil {
+			continue
+		}
+		log.Printf("pipeline: received next execution: %s", work.ID)
+		tracer.StartAt("rpc.next", work.Trace, polled).Finish()
+
+		weight := work.Weight
+		if weight < 1 {
+			weight = 1
+		}
+		slots.acquire(weight)
+
+		wg.Add(1)
 		go func() {
-			defer wg.Done()
-			for {
-				if sigterm.IsSet() {
-					return
-				}
-				if err := run(ctx, client, filter); err != nil {
-					log.Printf("build runner encountered error: exiting: %s", err)
-					return
-				}
+			defer func() {
+				slots.release(weight)
+				wg.Done()
+			}()
+			if err := r.run(work); err != nil {
+				log.Printf("build runner encountered error: %s: %s", work.ID, err)
 			}
 		}()
 	}
-
-	wg.Wait()
-	return nil
 }
 
-const (
-	maxFileUpload = 5000000
-	maxLogsUpload = 5000000
-)
+const maxLogsUpload = 5000000
 
-func run(ctx context.Context, client rpc.Peer, filter rpc.Filter) error {
-	log.Println("pipeline: request next execution")
+// runner executes pipelines received from the queue.
+type runner struct {
+	client rpc.Peer
+	tracer *trace.Tracer
+	engine string
+	limit  int64
+	clone  *cloneOpts
+	hooks  *hookOpts
+}
 
-	// get the next job from the queue
-	work, err := client.Next(ctx, filter)
-	if err != nil {
-		return err
-	}
-	if work == nil {
-		return nil
-	}
-	log.Printf("pipeline: received next execution: %s", work.ID)
+func (r *runner) run(work *rpc.Pipeline) error {
+	client := r.client
+	tracer := r.tracer
+	r.clone.apply(work.Config)
 
-	// new docker engine
-	engine, err := docker.NewEnv()
+	span := tracer.Start("pipeline", work.Trace)
+	span.Tag("proc", work.ID)
+	defer span.Finish()
+
+	// new container engine
+	engine, err := newEngine(r.engine)
 	if err != nil {
 		return err
 	}
@@ -182,26 +325,61 @@ func run(ctx context.Context, client rpc.Peer, filter rpc.Filter) error {
 		}
 	}()
 
+	// the queue lease is extended until the pipeline is complete, which
+	// includes the steps that run after the pipeline is cancelled.
+	finished := make(chan struct{})
+	defer close(finished)
 	go func() {
 		for {
 			select {
-			case <-ctx.Done():
+			case <-finished:
 				log.Printf("pipeline: cancel ping loop: %s", work.ID)
 				return
 			case <-time.After(time.Minute):
 				log.Printf("pipeline: ping queue: %s", work.ID)
-				client.Extend(ctx, work.ID)
+				client.Extend(context.Background(), work.ID)
 			}
 		}
 	}()
 
 	state := rpc.State{}
 	state.Started = time.Now().Unix()
-	err = client.Init(context.Background(), work.ID, state)
+	err = traceRPC(tracer, "rpc.init", span, func() error {
+		return client.Init(context.Background(), work.ID, state)
+	})
 	if err != nil {
 		log.Printf("pipeline: error signaling pipeline init: %s: %s", work.ID, err)
 	}
 
+	storage := traceUploads(tracer, span, newUploader(work, client))
+
+	// records the time each step is started, before the image is
+	// pulled, and the time the image pull completes, before the container
+	// is created.
+	var startedMu sync.Mutex
+	started := map[string]time.Time{}
+	steps := map[string]*trace.Span{}
+	logstreams := map[string]*rpc.LineWriter{}
+	attempts := map[string]int{}
+
+	var secrets []string
+	for _, secret := range work.Config.Secrets {
+		if secret.Mask {
+			secrets = append(secrets, secret.Value)
+		}
+	}
+
+	// returns the log stream of the step, which is shared by the image
+	// pull and the attempts of the step. The caller holds the lock.
+	logstreamOf := func(proc *backend.Step) *rpc.LineWriter {
+		logstream := logstreams[proc.Alias]
+		if logstream == nil {
+			logstream = rpc.NewLineWriter(client, work.ID, proc.Alias, secrets...)
+			logstreams[proc.Alias] = logstream
+		}
+		return logstream
+	}
+
 	var uploads sync.WaitGroup
 	defaultLogger := pipeline.LogFunc(func(proc *backend.Step, rc multipart.Reader) error {
 		part, rerr := rc.NextPart()
@@ -210,15 +388,25 @@ func run(ctx context.Context, client rpc.Peer, filter rpc.Filter) error {
 		}
 		uploads.Add(1)
 
-		var secrets []string
-		for _, secret := range work.Config.Secrets {
-			if secret.Mask {
-				secrets = append(secrets, secret.Value)
-			}
-		}
-
 		limitedPart := io.LimitReader(part, maxLogsUpload)
-		logstream := rpc.NewLineWriter(client, work.ID, proc.Alias, secrets...)
+
+		// the logger is invoked for each attempt of a retried step. The
+		// logs of each attempt are appended to the logs of the previous
+		// attempts, which are uploaded again when the attempt completes.
+		startedMu.Lock()
+		since, ok := started[proc.Alias]
+		logstream := logstreamOf(proc)
+		attempt := attempts[proc.Alias] + 1
+		attempts[proc.Alias] = attempt
+		startedMu.Unlock()
+		if proc.Retries != 0 {
+			logstream.Attempt(attempt, proc.Retries+1)
+		}
+		// the setup phase starts once the image is pulled, if the engine
+		// pulls the image separately.
+		if ok && attempt == 1 {
+			logstream.Meta(rpc.PhaseSetup, time.Since(since))
+		}
 		io.Copy(logstream, limitedPart)
 
 		file := &rpc.File{}
@@ -229,7 +417,7 @@ func run(ctx context.Context, client rpc.Peer, filter rpc.Filter) error {
 		file.Size = len(file.Data)
 		file.Time = time.Now().Unix()
 
-		if serr := client.Upload(context.Background(), work.ID, file); serr != nil {
+		if serr := storage.Upload(context.Background(), work.ID, file); serr != nil {
 			log.Printf("pipeline: cannot upload logs: %s: %s: %s", work.ID, file.Mime, serr)
 		} else {
 			log.Printf("pipeline: finish uploading logs: %s: step %s: %s", file.Mime, work.ID, proc.Alias)
@@ -244,8 +432,7 @@ func run(ctx context.Context, client rpc.Peer, filter rpc.Filter) error {
 		if rerr != nil {
 			return nil
 		}
-		// TODO should be configurable
-		limitedPart = io.LimitReader(part, maxFileUpload)
+		limitedPart = io.LimitReader(part, r.limit)
 		file = &rpc.File{}
 		file.Mime = part.Header().Get("Content-Type")
 		file.Proc = proc.Alias
@@ -254,7 +441,7 @@ func run(ctx context.Context, client rpc.Peer, filter rpc.Filter) error {
 		file.Size = len(file.Data)
 		file.Time = time.Now().Unix()
 
-		if serr := client.Upload(context.Background(), work.ID, file); serr != nil {
+		if serr := storage.Upload(context.Background(), work.ID, file); serr != nil {
 			log.Printf("pipeline: cannot upload artifact: %s: %s: %s", work.ID, file.Mime, serr)
 		} else {
 			log.Printf("pipeline: finish uploading artifact: %s: step %s: %s", file.Mime, work.ID, proc.Alias)
@@ -262,22 +449,61 @@ func run(ctx context.Context, client rpc.Peer, filter rpc.Filter) error {
 		return nil
 	})
 
+	// the image pull is logged while the engine pulls the image, and the
+	// setup phase is measured from the end of the pull.
+	pullLogger := func(proc *backend.Step, r io.Reader) error {
+		startedMu.Lock()
+		since := started[proc.Alias]
+		logstream := logstreamOf(proc)
+		startedMu.Unlock()
+
+		logPull(logstream, r)
+		logstream.Meta(rpc.PhasePull, time.Since(since))
+
+		startedMu.Lock()
+		started[proc.Alias] = time.Now()
+		startedMu.Unlock()
+		return nil
+	}
+
 	defaultTracer := pipeline.TraceFunc(func(state *pipeline.State) error {
 		procState := rpc.State{
-			Proc:     state.Pipeline.Step.Alias,
-			Exited:   state.Process.Exited,
-			ExitCode: state.Process.ExitCode,
-			Started:  time.Now().Unix(), // TODO do not do this
-			Finished: time.Now().Unix(),
+			Proc:         state.Pipeline.Step.Alias,
+			Exited:       state.Process.Exited,
+			ExitCode:     state.Process.ExitCode,
+			Started:      time.Now().Unix(), // TODO do not do this
+			Finished:     time.Now().Unix(),
+			AllowFailure: state.Pipeline.Step.AllowFailure,
+		}
+		if state.Process.TimedOut {
+			procState.Error = fmt.Sprintf("step timed out after %s", state.Pipeline.Step.Timeout)
 		}
 		defer func() {
-			if uerr := client.Update(context.Background(), work.ID, procState); uerr != nil {
+			uerr := traceRPC(tracer, "rpc.update", span, func() error {
+				return client.Update(context.Background(), work.ID, procState)
+			})
+			if uerr != nil {
 				log.Printf("Pipeine: error updating pipeline step status: %s: %s: %s", work.ID, procState.Proc, uerr)
 			}
 		}()
 		if state.Process.Exited {
+			startedMu.Lock()
+			step := steps[state.Pipeline.Step.Alias]
+			delete(steps, state.Pipeline.Step.Alias)
+			startedMu.Unlock()
+			step.Tag("exit_code", strconv.Itoa(state.Process.ExitCode))
+			step.Finish()
+
+			if len(state.Pipeline.Step.Artifacts) != 0 {
+				uploadArtifacts(engine, storage, work.ID, state.Pipeline.Step, r.limit)
+			}
 			return nil
 		}
+		startedMu.Lock()
+		started[state.Pipeline.Step.Alias] = time.Now()
+		steps[state.Pipeline.Step.Alias] = tracer.Start("step", span.Context())
+		steps[state.Pipeline.Step.Alias].Tag("step", state.Pipeline.Step.Alias)
+		startedMu.Unlock()
 		if state.Pipeline.Step.Environment == nil {
 			state.Pipeline.Step.Environment = map[string]string{}
 		}
@@ -301,15 +527,37 @@ func run(ctx context.Context, client rpc.Peer, filter rpc.Filter) error {
 			state.Pipeline.Step.Environment["DRONE_BUILD_STATUS"] = "failure"
 			state.Pipeline.Step.Environment["DRONE_JOB_STATUS"] = "failure"
 		}
+		if state.Pipeline.Killed {
+			state.Pipeline.Step.Environment["CI_BUILD_STATUS"] = "killed"
+			state.Pipeline.Step.Environment["CI_JOB_STATUS"] = "killed"
+			state.Pipeline.Step.Environment["DRONE_BUILD_STATUS"] = "killed"
+			state.Pipeline.Step.Environment["DRONE_JOB_STATUS"] = "killed"
+		}
 		return nil
 	})
 
-	err = pipeline.New(work.Config,
-		pipeline.WithContext(ctx),
-		pipeline.WithLogger(defaultLogger),
-		pipeline.WithTracer(defaultTracer),
-		pipeline.WithEngine(engine),
-	).Run()
+	environ := hookEnviron(work.Config)
+	err = r.hooks.exec(engine, client, storage, work, rpc.HookPre, r.hooks.pre, environ)
+	if err == nil {
+		err = pipeline.New(work.Config,
+			pipeline.WithContext(ctx),
+			pipeline.WithLogger(&stepLogger{LogFunc: defaultLogger, pull: pullLogger}),
+			pipeline.WithTracer(defaultTracer),
+			pipeline.WithEngine(engine),
+		).Run()
+	}
+
+	// the post hook is always executed, regardless of the pipeline
+	// outcome, so that external resources are destroyed.
+	environ["DRONE_BUILD_STATUS"] = "success"
+	if err == pipeline.ErrCancel {
+		environ["DRONE_BUILD_STATUS"] = "killed"
+	} else if err != nil {
+		environ["DRONE_BUILD_STATUS"] = "failure"
+	}
+	if herr := r.hooks.exec(engine, client, storage, work, rpc.HookPost, r.hooks.post, environ); herr != nil && err == nil {
+		err = herr
+	}
 
 	state.Finished = time.Now().Unix()
 	state.Exited = true
@@ -330,7 +578,10 @@ func run(ctx context.Context, client rpc.Peer, filter rpc.Filter) error {
 
 	uploads.Wait()
 
-	err = client.Done(context.Background(), work.ID, state)
+	span.Tag("exit_code", strconv.Itoa(state.ExitCode))
+	err = traceRPC(tracer, "rpc.done", span, func() error {
+		return client.Done(context.Background(), work.ID, state)
+	})
 	if err != nil {
 		log.Printf("Pipeine: error signaling pipeline done: %s: %s", work.ID, err)
 	}
@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"io/ioutil"
+	"log"
+	"mime"
+	"path"
+	"time"
+
+	"github.com/cncd/pipeline/pipeline/backend"
+	"github.com/cncd/pipeline/pipeline/rpc"
+)
+
+// uploadArtifacts copies the artifacts declared by the step from the step
+// container, and uploads each file in the artifact paths. Files larger than
+// the limit are skipped.
+func uploadArtifacts(engine backend.Engine, storage uploader, id string, step *backend.Step, limit int64) {
+	copier, ok := engine.(backend.Copier)
+	if !ok {
+		return
+	}
+	for _, artifact := range step.Artifacts {
+		rc, err := copier.Copy(step, artifact)
+		if err != nil {
+			log.Printf("pipeline: cannot copy artifact: %s: %s: %s", id, artifact, err)
+			continue
+		}
+		tr := tar.NewReader(rc)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				log.Printf("pipeline: cannot read artifact: %s: %s: %s", id, artifact, err)
+				break
+			}
+			if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+				continue
+			}
+			if header.Size > limit {
+				log.Printf("pipeline: artifact exceeds the size limit: %s: %s", id, header.Name)
+				continue
+			}
+
+			file := &rpc.File{}
+			file.Mime = mime.TypeByExtension(path.Ext(header.Name))
+			if file.Mime == "" {
+				file.Mime = "application/octet-stream"
+			}
+			file.Proc = step.Alias
+			file.Name = header.Name
+			file.Data, _ = ioutil.ReadAll(tr)
+			file.Size = len(file.Data)
+			file.Time = time.Now().Unix()
+
+			if serr := storage.Upload(context.Background(), id, file); serr != nil {
+				log.Printf("pipeline: cannot upload artifact: %s: %s: %s", id, file.Name, serr)
+			}
+		}
+		rc.Close()
+	}
+}
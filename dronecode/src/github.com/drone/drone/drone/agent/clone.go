@@ -0,0 +1,55 @@
+package agent
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cncd/pipeline/pipeline/backend"
+)
+
+// cloneOpts defines the agent default clone settings. These are
+// applied to the clone steps of every pipeline, unless the pipeline
+// overrides them in the clone section of the yaml.
+type cloneOpts struct {
+	depth     int
+	recursive bool
+	retries   int
+	backoff   time.Duration
+}
+
+// apply applies the default clone settings to the clone steps in
+// the pipeline configuration.
+func (o *cloneOpts) apply(conf *backend.Config) {
+	for _, stage := range conf.Stages {
+		if !isClone(stage) {
+			continue
+		}
+		for _, step := range stage.Steps {
+			if step.Environment == nil {
+				step.Environment = map[string]string{}
+			}
+			if _, ok := step.Environment["PLUGIN_DEPTH"]; !ok && o.depth != 0 {
+				step.Environment["PLUGIN_DEPTH"] = strconv.Itoa(o.depth)
+			}
+			if _, ok := step.Environment["PLUGIN_RECURSIVE"]; !ok && o.recursive {
+				step.Environment["PLUGIN_RECURSIVE"] = "true"
+			}
+			if s, ok := step.Environment["PLUGIN_RETRIES"]; ok {
+				step.Retries, _ = strconv.Atoi(s)
+			} else if step.Retries == 0 {
+				step.Retries = o.retries
+			}
+			if step.RetryBackoff == 0 {
+				step.RetryBackoff = o.backoff
+			}
+		}
+	}
+}
+
+// isClone returns true if the stage is the default clone stage, or
+// a clone stage defined in the yaml.
+func isClone(stage *backend.Stage) bool {
+	return strings.HasSuffix(stage.Name, "_clone") ||
+		strings.Contains(stage.Name, "_clone_")
+}
@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cncd/pipeline/pipeline/backend"
+	"github.com/cncd/pipeline/pipeline/frontend/yaml"
+	"github.com/cncd/pipeline/pipeline/frontend/yaml/compiler"
+)
+
+func TestCloneOpts(t *testing.T) {
+	opts := &cloneOpts{depth: 50, recursive: true, retries: 3, backoff: time.Second}
+	conf := &backend.Config{
+		Stages: []*backend.Stage{
+			{Name: "pipeline_clone", Steps: []*backend.Step{{Name: "pipeline_clone"}}},
+			{Name: "pipeline_clone_0", Steps: []*backend.Step{{
+				Name:         "pipeline_clone_0",
+				Environment:  map[string]string{"PLUGIN_DEPTH": "1", "PLUGIN_RETRIES": "5"},
+				RetryBackoff: time.Minute,
+			}}},
+			{Name: "pipeline_stage_0", Steps: []*backend.Step{{Name: "pipeline_step_0"}}},
+		},
+	}
+	opts.apply(conf)
+
+	step := conf.Stages[0].Steps[0]
+	if step.Environment["PLUGIN_DEPTH"] != "50" || step.Environment["PLUGIN_RECURSIVE"] != "true" {
+		t.Errorf("Want the default clone depth and submodules, got %v", step.Environment)
+	}
+	if step.Retries != 3 || step.RetryBackoff != time.Second {
+		t.Errorf("Want the default clone retries, got %d retries with backoff %s", step.Retries, step.RetryBackoff)
+	}
+
+	step = conf.Stages[1].Steps[0]
+	if step.Environment["PLUGIN_DEPTH"] != "1" {
+		t.Errorf("Want the clone depth of the yaml, got %s", step.Environment["PLUGIN_DEPTH"])
+	}
+	if step.Retries != 5 || step.RetryBackoff != time.Minute {
+		t.Errorf("Want the clone retries of the yaml, got %d retries with backoff %s", step.Retries, step.RetryBackoff)
+	}
+
+	step = conf.Stages[2].Steps[0]
+	if len(step.Environment) != 0 || step.Retries != 0 {
+		t.Errorf("Want the clone settings applied to clone steps only, got %v", step.Environment)
+	}
+}
+
+func TestCloneOptsUnset(t *testing.T) {
+	conf := &backend.Config{
+		Stages: []*backend.Stage{
+			{Name: "pipeline_clone", Steps: []*backend.Step{{Name: "pipeline_clone"}}},
+		},
+	}
+	new(cloneOpts).apply(conf)
+	step := conf.Stages[0].Steps[0]
+	if len(step.Environment) != 0 || step.Retries != 0 || step.RetryBackoff != 0 {
+		t.Errorf("Want no clone settings without agent defaults, got %v", step.Environment)
+	}
+}
+
+func TestCloneOptsCompiled(t *testing.T) {
+	tests := []struct {
+		clone string
+		depth string
+	}{
+		{clone: "", depth: "50"},
+		{clone: "clone: {depth: 1}", depth: "1"},
+	}
+	for _, test := range tests {
+		config, err := yaml.ParseString(test.clone + "\npipeline:\n  build:\n    image: golang\n")
+		if err != nil {
+			t.Fatal(err)
+		}
+		conf := compiler.New(compiler.WithPrefix("test")).Compile(config)
+		(&cloneOpts{depth: 50}).apply(conf)
+		if got := conf.Stages[0].Steps[0].Environment["PLUGIN_DEPTH"]; got != test.depth {
+			t.Errorf("Want clone %q depth %s, got %q", test.clone, test.depth, got)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package agent
+
+import (
+	"fmt"
+
+	"github.com/cncd/pipeline/pipeline/backend"
+	"github.com/cncd/pipeline/pipeline/backend/docker"
+	"github.com/cncd/pipeline/pipeline/backend/podman"
+)
+
+// newEngine returns a new container engine by name.
+func newEngine(name string) (backend.Engine, error) {
+	switch name {
+	case "docker", "":
+		return docker.NewEnv()
+	case "podman":
+		return podman.NewEnv()
+	default:
+		return nil, fmt.Errorf("unknown container engine %q", name)
+	}
+}
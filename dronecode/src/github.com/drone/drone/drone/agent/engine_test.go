@@ -0,0 +1,14 @@
+package agent
+
+import "testing"
+
+func TestNewEngine(t *testing.T) {
+	for _, name := range []string{"", "docker", "podman"} {
+		if _, err := newEngine(name); err != nil {
+			t.Errorf("Want container engine %q, got %s", name, err)
+		}
+	}
+	if _, err := newEngine("rkt"); err == nil {
+		t.Errorf("Want error for an unknown container engine")
+	}
+}
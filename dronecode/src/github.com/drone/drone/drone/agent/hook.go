@@ -0,0 +1,178 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/cncd/pipeline/pipeline"
+	"github.com/cncd/pipeline/pipeline/backend"
+	"github.com/cncd/pipeline/pipeline/rpc"
+)
+
+// hookOpts defines the commands the agent executes before and after each
+// pipeline, to provision and destroy external resources. The commands are
+// executed on the host, or in a container of the hook image using the
+// container engine of the pipeline.
+type hookOpts struct {
+	pre     string
+	post    string
+	image   string
+	timeout time.Duration
+}
+
+// exec executes the hook command and streams its output to the build
+// logs. A non-zero exit code is returned as a pipeline.ExitError.
+func (h *hookOpts) exec(engine backend.Engine, client rpc.Peer, storage uploader, work *rpc.Pipeline, name, command string, environ map[string]string) error {
+	if command == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+
+	state := rpc.State{
+		Proc:    name,
+		Started: time.Now().Unix(),
+	}
+	if err := client.Update(ctx, work.ID, state); err != nil {
+		return err
+	}
+
+	var secrets []string
+	for _, secret := range work.Config.Secrets {
+		if secret.Mask {
+			secrets = append(secrets, secret.Value)
+		}
+	}
+	logstream := rpc.NewLineWriter(client, work.ID, name, secrets...)
+
+	var err error
+	if h.image != "" {
+		err = h.runImage(engine, work, name, command, environ, logstream)
+	} else {
+		err = h.runHost(ctx, name, command, environ, logstream)
+	}
+	switch xerr := err.(type) {
+	case nil:
+	case *pipeline.ExitError:
+		state.ExitCode = xerr.Code
+	default:
+		state.ExitCode = 1
+		state.Error = err.Error()
+	}
+
+	file := &rpc.File{}
+	file.Mime = "application/json+logs"
+	file.Proc = name
+	file.Name = "logs.json"
+	file.Data, _ = json.Marshal(logstream.Lines())
+	file.Size = len(file.Data)
+	file.Time = time.Now().Unix()
+	if uerr := storage.Upload(context.Background(), work.ID, file); uerr != nil {
+		log.Printf("pipeline: cannot upload hook logs: %s: %s: %s", work.ID, name, uerr)
+	}
+
+	state.Exited = true
+	state.Finished = time.Now().Unix()
+	if uerr := client.Update(context.Background(), work.ID, state); uerr != nil {
+		log.Printf("pipeline: cannot update hook state: %s: %s: %s", work.ID, name, uerr)
+	}
+	return err
+}
+
+// runHost executes the hook command on the host.
+func (h *hookOpts) runHost(ctx context.Context, name, command string, environ map[string]string, out io.Writer) error {
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	cmd.Env = os.Environ()
+	for k, v := range environ {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	err := cmd.Run()
+	if xerr, ok := err.(*exec.ExitError); ok {
+		code := 1
+		if status, ok := xerr.Sys().(syscall.WaitStatus); ok {
+			code = status.ExitStatus()
+		}
+		return &pipeline.ExitError{Name: name, Code: code}
+	}
+	return err
+}
+
+// runImage executes the hook command in a container of the hook image,
+// which is killed after the hook timeout and removed once it exits.
+func (h *hookOpts) runImage(engine backend.Engine, work *rpc.Pipeline, name, command string, environ map[string]string, out io.Writer) error {
+	step := &backend.Step{
+		Name:        fmt.Sprintf("%s_%s", work.ID, name),
+		Alias:       name,
+		Image:       h.image,
+		Entrypoint:  []string{"/bin/sh", "-c"},
+		Command:     []string{command},
+		Environment: environ,
+		OnSuccess:   true,
+		Timeout:     h.timeout,
+	}
+	conf := &backend.Config{
+		Stages: []*backend.Stage{{Name: step.Name, Alias: name, Steps: []*backend.Step{step}}},
+	}
+	defer engine.Destroy(conf)
+
+	if err := engine.Exec(step); err != nil {
+		return err
+	}
+	var timedOut int32
+	timer := time.AfterFunc(h.timeout, func() {
+		atomic.StoreInt32(&timedOut, 1)
+		engine.Kill(step)
+	})
+	defer timer.Stop()
+
+	rc, err := engine.Tail(step)
+	if err != nil {
+		return err
+	}
+	io.Copy(out, rc)
+	rc.Close()
+
+	state, err := engine.Wait(step)
+	switch {
+	case err != nil:
+		return err
+	case atomic.LoadInt32(&timedOut) == 1:
+		return &pipeline.TimeoutError{Name: name, Timeout: h.timeout}
+	case state.ExitCode != 0:
+		return &pipeline.ExitError{Name: name, Code: state.ExitCode}
+	}
+	return nil
+}
+
+// hookEnviron returns the pipeline metadata environment variables that
+// are passed to the hook commands. The netrc credentials are excluded.
+func hookEnviron(conf *backend.Config) map[string]string {
+	environ := map[string]string{}
+	for _, stage := range conf.Stages {
+		for _, step := range stage.Steps {
+			for k, v := range step.Environment {
+				if strings.Contains(k, "_NETRC_") {
+					continue
+				}
+				if strings.HasPrefix(k, "DRONE_") || strings.HasPrefix(k, "CI_") {
+					environ[k] = v
+				}
+			}
+			return environ
+		}
+	}
+	return environ
+}
@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cncd/pipeline/pipeline"
+	"github.com/cncd/pipeline/pipeline/backend"
+	"github.com/cncd/pipeline/pipeline/rpc"
+)
+
+// hookEngine fakes a container engine that runs the hook step, which
+// writes the output and exits with the exit code, or blocks until it is
+// killed.
+type hookEngine struct {
+	backend.Engine
+
+	output    string
+	code      int
+	block     bool
+	killed    chan struct{}
+	step      *backend.Step
+	destroyed bool
+}
+
+func (e *hookEngine) Exec(step *backend.Step) error {
+	e.step = step
+	return nil
+}
+
+func (e *hookEngine) Kill(*backend.Step) error {
+	close(e.killed)
+	return nil
+}
+
+func (e *hookEngine) Tail(*backend.Step) (io.ReadCloser, error) {
+	return ioutil.NopCloser(strings.NewReader(e.output)), nil
+}
+
+func (e *hookEngine) Wait(*backend.Step) (*backend.State, error) {
+	if e.block {
+		<-e.killed
+		return &backend.State{Exited: true, ExitCode: 137}, nil
+	}
+	return &backend.State{Exited: true, ExitCode: e.code}, nil
+}
+
+func (e *hookEngine) Destroy(*backend.Config) error {
+	e.destroyed = true
+	return nil
+}
+
+func TestHookImage(t *testing.T) {
+	hooks := &hookOpts{image: "alpine", timeout: time.Minute}
+	work := &rpc.Pipeline{ID: "1"}
+	environ := map[string]string{"DRONE_BUILD_NUMBER": "1"}
+
+	engine := &hookEngine{output: "terraform apply", killed: make(chan struct{})}
+	var out bytes.Buffer
+	if err := hooks.runImage(engine, work, rpc.HookPre, "terraform apply", environ, &out); err != nil {
+		t.Error(err)
+		return
+	}
+	if got := out.String(); got != "terraform apply" {
+		t.Errorf("Want hook output, got %q", got)
+	}
+	step := engine.step
+	if step.Name != "1_pre_hook" || step.Image != "alpine" || strings.Join(step.Command, " ") != "terraform apply" {
+		t.Errorf("Want hook command executed in the hook image, got %+v", step)
+	}
+	if step.Environment["DRONE_BUILD_NUMBER"] != "1" {
+		t.Errorf("Want hook environment passed to the step")
+	}
+	if !engine.destroyed {
+		t.Errorf("Want hook container removed")
+	}
+
+	engine = &hookEngine{code: 2, killed: make(chan struct{})}
+	err := hooks.runImage(engine, work, rpc.HookPost, "exit 2", environ, ioutil.Discard)
+	if xerr, ok := err.(*pipeline.ExitError); !ok || xerr.Code != 2 || xerr.Name != rpc.HookPost {
+		t.Errorf("Want exit error with code 2, got %v", err)
+	}
+
+	hooks.timeout = time.Millisecond
+	engine = &hookEngine{block: true, killed: make(chan struct{})}
+	err = hooks.runImage(engine, work, rpc.HookPost, "sleep 60", environ, ioutil.Discard)
+	if _, ok := err.(*pipeline.TimeoutError); !ok {
+		t.Errorf("Want timeout error, got %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+
+	"github.com/cncd/pipeline/pipeline"
+	"github.com/cncd/pipeline/pipeline/backend"
+	"github.com/cncd/pipeline/pipeline/rpc"
+)
+
+// stepLogger logs the output of the steps, and the image pull of the steps
+// when the engine pulls the image separately.
+type stepLogger struct {
+	pipeline.LogFunc
+
+	pull func(*backend.Step, io.Reader) error
+}
+
+// LogPull logs the image pull of the step.
+func (l *stepLogger) LogPull(proc *backend.Step, r io.Reader) error {
+	return l.pull(proc, r)
+}
+
+// pullMessage is a progress message of the image pull.
+type pullMessage struct {
+	ID       string `json:"id"`
+	Status   string `json:"status"`
+	Progress string `json:"progress"`
+	Error    string `json:"error"`
+}
+
+// logPull writes the progress messages of the image pull to the log stream.
+// The download and extract progress of each layer is omitted, so that only
+// the status changes are logged. The pull errors are logged, but do not fail
+// the step, since the engine pulls the image again if it does not exist.
+func logPull(w *rpc.LineWriter, r io.Reader) {
+	dec := json.NewDecoder(r)
+	for {
+		msg := new(pullMessage)
+		if err := dec.Decode(msg); err != nil {
+			// the remaining progress is read, so that the pull is not
+			// cancelled before it completes.
+			io.Copy(ioutil.Discard, r)
+			return
+		}
+		switch {
+		case msg.Error != "":
+			w.Pull(msg.Error)
+		case msg.Progress != "" || msg.Status == "":
+			continue
+		case msg.ID != "":
+			w.Pull(msg.ID + ": " + msg.Status)
+		default:
+			w.Pull(msg.Status)
+		}
+	}
+}
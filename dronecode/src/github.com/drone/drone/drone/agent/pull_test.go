@@ -0,0 +1,49 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cncd/pipeline/pipeline/rpc"
+)
+
+// logPeer records the log lines.
+type logPeer struct {
+	rpc.Peer
+
+	lines []*rpc.Line
+}
+
+func (p *logPeer) Log(c context.Context, id string, line *rpc.Line) error {
+	p.lines = append(p.lines, line)
+	return nil
+}
+
+func TestLogPull(t *testing.T) {
+	progress := strings.NewReader(`{"status":"Pulling from library/golang","id":"1.10"}
+{"status":"Downloading","progressDetail":{"current":1,"total":2},"progress":"[==>  ]","id":"a3ed95caeb02"}
+{"status":"Pull complete","id":"a3ed95caeb02"}
+{"error":"unauthorized: authentication required"}
+{"status":"Status: Downloaded newer image for golang:1.10"}
+`)
+	peer := new(logPeer)
+	logPull(rpc.NewLineWriter(peer, "1", "build"), progress)
+
+	var got []string
+	for _, line := range peer.lines {
+		if line.Phase != rpc.PhasePull || line.Type != rpc.LineProgress {
+			t.Errorf("Want pull progress line, got %+v", line)
+		}
+		got = append(got, line.Out)
+	}
+	want := []string{
+		"1.10: Pulling from library/golang",
+		"a3ed95caeb02: Pull complete",
+		"unauthorized: authentication required",
+		"Status: Downloaded newer image for golang:1.10",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Want pull lines %q, got %q", want, got)
+	}
+}
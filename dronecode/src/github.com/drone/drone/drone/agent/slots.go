@@ -0,0 +1,42 @@
+package agent
+
+import "sync"
+
+// slots tracks the weighted job capacity of the agent.
+type slots struct {
+	sync.Mutex
+	cond *sync.Cond
+	free int
+}
+
+func newSlots(size int) *slots {
+	s := &slots{free: size}
+	s.cond = sync.NewCond(s)
+	return s
+}
+
+// wait blocks until at least one slot is available and returns the
+// number of available slots.
+func (s *slots) wait() int {
+	s.Lock()
+	defer s.Unlock()
+	for s.free <= 0 {
+		s.cond.Wait()
+	}
+	return s.free
+}
+
+// acquire acquires n slots.
+func (s *slots) acquire(n int) {
+	s.Lock()
+	s.free -= n
+	s.Unlock()
+}
+
+// release releases n slots.
+func (s *slots) release(n int) {
+	s.Lock()
+	s.free += n
+	s.Unlock()
+	s.cond.Broadcast()
+}
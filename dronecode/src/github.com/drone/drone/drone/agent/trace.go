@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/cncd/pipeline/pipeline/rpc"
+	"github.com/drone/drone/shared/trace"
+)
+
+// traceRPC records the duration of the rpc call as a child of the pipeline
+// span.
+func traceRPC(tracer *trace.Tracer, name string, parent *trace.Span, fn func() error) error {
+	span := tracer.Start(name, parent.Context())
+	err := fn()
+	if err != nil {
+		span.Tag("error", err.Error())
+	}
+	span.Finish()
+	return err
+}
+
+// tracedUploader is an uploader that records the duration of each upload
+// as a child of the pipeline span.
+type tracedUploader struct {
+	tracer *trace.Tracer
+	parent *trace.Span
+	next   uploader
+}
+
+// traceUploads returns an uploader that traces the uploads of the uploader,
+// if tracing is enabled.
+func traceUploads(tracer *trace.Tracer, parent *trace.Span, next uploader) uploader {
+	if tracer == nil {
+		return next
+	}
+	return &tracedUploader{tracer: tracer, parent: parent, next: next}
+}
+
+func (u *tracedUploader) Upload(c context.Context, id string, file *rpc.File) error {
+	return traceRPC(u.tracer, "upload", u.parent, func() error {
+		return u.next.Upload(c, id, file)
+	})
+}
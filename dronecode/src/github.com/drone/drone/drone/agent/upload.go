@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/cncd/pipeline/pipeline/rpc"
+)
+
+// uploader uploads pipeline logs and artifacts.
+type uploader interface {
+	Upload(c context.Context, id string, file *rpc.File) error
+}
+
+// signedUploader is an uploader that writes the step logs directly to the
+// pre-signed urls provided with the pipeline (e.g. S3, GCS or Minio),
+// instead of streaming them through the server. Artifacts, and logs without
+// a pre-signed url, are uploaded using the fallback.
+type signedUploader struct {
+	client   *http.Client
+	uploads  map[string]*rpc.Upload
+	fallback uploader
+}
+
+// newUploader returns an uploader for the pipeline.
+func newUploader(work *rpc.Pipeline, fallback uploader) uploader {
+	if len(work.Uploads) == 0 {
+		return fallback
+	}
+	return &signedUploader{
+		client:   http.DefaultClient,
+		uploads:  work.Uploads,
+		fallback: fallback,
+	}
+}
+
+func (u *signedUploader) Upload(c context.Context, id string, file *rpc.File) error {
+	upload, ok := u.uploads[file.Proc]
+	if !ok || upload.Logs == "" || file.Mime != "application/json+logs" {
+		return u.fallback.Upload(c, id, file)
+	}
+
+	req, err := http.NewRequest("PUT", upload.Logs, bytes.NewReader(file.Data))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(c)
+	req.Header.Set("Content-Type", file.Mime)
+	req.ContentLength = int64(len(file.Data))
+
+	// the logs are uploaded through the server if the url is not
+	// reachable, or expired before the step completed.
+	res, err := u.client.Do(req)
+	if err == nil {
+		res.Body.Close()
+		if res.StatusCode > 299 {
+			err = fmt.Errorf("upload failed with status code %d", res.StatusCode)
+		}
+	}
+	if err != nil {
+		log.Printf("pipeline: cannot upload logs to the pre-signed url: %s: %s: %s", id, file.Proc, err)
+		return u.fallback.Upload(c, id, file)
+	}
+	return nil
+}
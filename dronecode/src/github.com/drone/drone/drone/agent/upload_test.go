@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cncd/pipeline/pipeline/rpc"
+)
+
+// rpcUploader records the files uploaded through the server.
+type rpcUploader struct {
+	files []*rpc.File
+}
+
+func (u *rpcUploader) Upload(c context.Context, id string, file *rpc.File) error {
+	u.files = append(u.files, file)
+	return nil
+}
+
+func TestSignedUploader(t *testing.T) {
+	var uploaded []byte
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" || r.URL.Path != "/logs/1" {
+			w.WriteHeader(403)
+			return
+		}
+		uploaded, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer s.Close()
+
+	fallback := new(rpcUploader)
+	if u := newUploader(&rpc.Pipeline{}, fallback); u != fallback {
+		t.Errorf("Want uploads through the server without pre-signed urls")
+	}
+
+	u := newUploader(&rpc.Pipeline{Uploads: map[string]*rpc.Upload{
+		"build":  {Logs: s.URL + "/logs/1"},
+		"expire": {Logs: s.URL + "/logs/2"},
+	}}, fallback)
+
+	logs := &rpc.File{Proc: "build", Mime: "application/json+logs", Data: []byte("[]")}
+	if err := u.Upload(context.Background(), "1", logs); err != nil {
+		t.Fatal(err)
+	}
+	if string(uploaded) != "[]" || len(fallback.files) != 0 {
+		t.Errorf("Want the logs uploaded to the pre-signed url, got %q", uploaded)
+	}
+
+	for _, file := range []*rpc.File{
+		{Proc: "build", Mime: "application/zip", Name: "dist.zip"},
+		{Proc: "test", Mime: "application/json+logs"},
+		{Proc: "expire", Mime: "application/json+logs"},
+	} {
+		fallback.files = nil
+		if err := u.Upload(context.Background(), "1", file); err != nil {
+			t.Fatal(err)
+		}
+		if len(fallback.files) != 1 {
+			t.Errorf("Want %s of step %s uploaded through the server", file.Mime, file.Proc)
+		}
+	}
+}
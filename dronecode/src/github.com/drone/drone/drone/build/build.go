@@ -10,9 +10,11 @@ var Command = cli.Command{
 		buildListCmd,
 		buildLastCmd,
 		buildLogsCmd,
+		buildFollowCmd,
 		buildInfoCmd,
 		buildStopCmd,
 		buildStartCmd,
+		buildRollbackCmd,
 		buildApproveCmd,
 		buildDeclineCmd,
 		buildQueueCmd,
@@ -0,0 +1,65 @@
+package build
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cncd/pipeline/pipeline/rpc"
+	"github.com/drone/drone/drone/internal"
+	"github.com/urfave/cli"
+)
+
+var buildFollowCmd = cli.Command{
+	Name:      "follow",
+	Usage:     "follow the logs of a running build",
+	ArgsUsage: "<repo/name> <build> [job]",
+	Action:    buildFollow,
+}
+
+func buildFollow(c *cli.Context) error {
+	repo := c.Args().First()
+	owner, name, err := internal.ParseRepo(repo)
+	if err != nil {
+		return err
+	}
+	number, err := strconv.Atoi(c.Args().Get(1))
+	if err != nil {
+		return fmt.Errorf("Error: Invalid number or missing build number. eg 100")
+	}
+	job, _ := strconv.Atoi(c.Args().Get(2))
+	if job == 0 {
+		job = 1
+	}
+
+	client, err := internal.NewClient(c)
+	if err != nil {
+		return err
+	}
+
+	r, err := client.BuildLogStream(owner, name, number, job)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		text := scanner.Text()
+		if text == "event: eof" {
+			return nil
+		}
+		if !strings.HasPrefix(text, "data: ") {
+			continue
+		}
+		var line rpc.Line
+		if err := json.Unmarshal([]byte(text[6:]), &line); err != nil {
+			continue
+		}
+		fmt.Print(line.Out)
+	}
+	return scanner.Err()
+}
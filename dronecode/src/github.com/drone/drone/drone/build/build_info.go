@@ -71,4 +71,5 @@ Branch: {{ .Branch }}
 Ref: {{ .Ref }}
 Message: {{ .Message }}
 Author: {{ .Author }}
-`
+{{ if .Labels }}Labels:{{ range $k, $v := .Labels }} {{ $k }}={{ $v }}{{ end }}
+{{ end }}`
@@ -1,7 +1,9 @@
 package build
 
 import (
+	"fmt"
 	"os"
+	"strings"
 	"text/template"
 
 	"github.com/drone/drone/drone/internal"
@@ -30,6 +32,10 @@ var buildListCmd = cli.Command{
 			Name:  "status",
 			Usage: "status filter",
 		},
+		cli.StringSliceFlag{
+			Name:  "label",
+			Usage: "label filter, in the key:value format",
+		},
 		cli.IntFlag{
 			Name:  "limit",
 			Usage: "limit the list size",
@@ -65,6 +71,15 @@ func buildList(c *cli.Context) error {
 	status := c.String("status")
 	limit := c.Int("limit")
 
+	labels := map[string]string{}
+	for _, label := range c.StringSlice("label") {
+		parts := strings.SplitN(label, ":", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("Invalid label %q, expected key:value", label)
+		}
+		labels[parts[0]] = parts[1]
+	}
+
 	var count int
 	for _, build := range builds {
 		if count >= limit {
@@ -79,12 +94,25 @@ func buildList(c *cli.Context) error {
 		if status != "" && build.Status != status {
 			continue
 		}
+		if !hasLabels(build.Labels, labels) {
+			continue
+		}
 		tmpl.Execute(os.Stdout, build)
 		count++
 	}
 	return nil
 }
 
+// helper function returns true if the build has all of the labels.
+func hasLabels(build, labels map[string]string) bool {
+	for k, v := range labels {
+		if build[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
 // template for build list information
 var tmplBuildList = "\x1b[33mBuild #{{ .Number }} \x1b[0m" + `
 Status: {{ .Status }}
@@ -94,4 +122,5 @@ Branch: {{ .Branch }}
 Ref: {{ .Ref }}
 Author: {{ .Author }} {{ if .Email }}<{{.Email}}>{{ end }}
 Message: {{ .Message }}
-`
+{{ if .Labels }}Labels:{{ range $k, $v := .Labels }} {{ $k }}={{ $v }}{{ end }}
+{{ end }}`
@@ -0,0 +1,48 @@
+package build
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/drone/drone/drone/internal"
+	"github.com/urfave/cli"
+)
+
+var buildRollbackCmd = cli.Command{
+	Name:      "rollback",
+	Usage:     "rollback an environment to a build",
+	ArgsUsage: "<repo/name> <build> [environment]",
+	Action:    buildRollback,
+	Flags: []cli.Flag{
+		cli.StringSliceFlag{
+			Name:  "param, p",
+			Usage: "custom parameters to be injected into the job environment. Format: KEY=value",
+		},
+	},
+}
+
+func buildRollback(c *cli.Context) error {
+	repo := c.Args().First()
+	owner, name, err := internal.ParseRepo(repo)
+	if err != nil {
+		return err
+	}
+	number, err := strconv.Atoi(c.Args().Get(1))
+	if err != nil {
+		return err
+	}
+
+	client, err := internal.NewClient(c)
+	if err != nil {
+		return err
+	}
+
+	params := internal.ParseKeyPair(c.StringSlice("param"))
+	build, err := client.Rollback(owner, name, number, c.Args().Get(2), params)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Rolling back %s to %s/%s#%d with build %d\n", build.Deploy, owner, name, number, build.Number)
+	return nil
+}
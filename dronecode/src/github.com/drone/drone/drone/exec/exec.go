@@ -331,7 +331,7 @@ func exec(c *cli.Context) error {
 	}
 
 	// lint the yaml file
-	if lerr := linter.New(linter.WithTrusted(true)).Lint(conf); lerr != nil {
+	if lerr := linter.New(linter.WithTrusted(true), linter.WithNamedVolumes("*")).Lint(conf); lerr != nil {
 		return lerr
 	}
 
@@ -0,0 +1,37 @@
+package loglevel
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli"
+
+	"github.com/drone/drone/drone/internal"
+	"github.com/drone/drone/model"
+)
+
+// Command exports the log-level command.
+var Command = cli.Command{
+	Name:      "log-level",
+	Usage:     "get or set the logging level of the server",
+	ArgsUsage: "[level]",
+	Action:    logLevel,
+}
+
+func logLevel(c *cli.Context) error {
+	client, err := internal.NewClient(c)
+	if err != nil {
+		return err
+	}
+
+	var level *model.LogLevel
+	if arg := c.Args().First(); arg != "" {
+		level, err = client.SetLogLevel(arg)
+	} else {
+		level, err = client.LogLevel()
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(level.Level)
+	return nil
+}
@@ -11,10 +11,14 @@ import (
 	"github.com/drone/drone/drone/deploy"
 	"github.com/drone/drone/drone/exec"
 	"github.com/drone/drone/drone/info"
+	"github.com/drone/drone/drone/loglevel"
+	"github.com/drone/drone/drone/queue"
 	"github.com/drone/drone/drone/registry"
 	"github.com/drone/drone/drone/repo"
 	"github.com/drone/drone/drone/secret"
 	"github.com/drone/drone/drone/server"
+	"github.com/drone/drone/drone/template"
+	"github.com/drone/drone/drone/token"
 	"github.com/drone/drone/drone/user"
 	"github.com/drone/drone/version"
 
@@ -48,10 +52,14 @@ func main() {
 		deploy.Command,
 		exec.Command,
 		info.Command,
+		loglevel.Command,
+		queue.Command,
 		registry.Command,
 		secret.Command,
 		server.Command,
 		repo.Command,
+		template.Command,
+		token.Command,
 		user.Command,
 	}
 
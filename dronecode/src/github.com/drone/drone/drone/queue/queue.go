@@ -0,0 +1,14 @@
+package queue
+
+import "github.com/urfave/cli"
+
+// Command exports the queue command set.
+var Command = cli.Command{
+	Name:  "queue",
+	Usage: "manage the build queue",
+	Subcommands: []cli.Command{
+		queueListCmd,
+		queuePauseCmd,
+		queueResumeCmd,
+	},
+}
@@ -0,0 +1,65 @@
+package queue
+
+import (
+	"fmt"
+	"os"
+	"text/template"
+
+	"github.com/drone/drone/drone/internal"
+	"github.com/urfave/cli"
+)
+
+var queueListCmd = cli.Command{
+	Name:   "ls",
+	Usage:  "list pending and running pipelines",
+	Action: queueList,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "format",
+			Usage: "format output",
+			Value: tmplQueueList,
+		},
+	},
+}
+
+func queueList(c *cli.Context) error {
+	client, err := internal.NewClient(c)
+	if err != nil {
+		return err
+	}
+
+	info, err := client.Queue()
+	if err != nil {
+		return err
+	}
+
+	if info.Paused {
+		fmt.Println("the queue is paused")
+	}
+	if len(info.Pending) == 0 && len(info.Running) == 0 {
+		fmt.Println("there are no pending or running pipelines")
+		return nil
+	}
+
+	tmpl, err := template.New("_").Parse(c.String("format") + "\n")
+	if err != nil {
+		return err
+	}
+	for _, item := range info.Running {
+		tmpl.Execute(os.Stdout, map[string]interface{}{"Status": "running", "Item": item})
+	}
+	for _, item := range info.Pending {
+		tmpl.Execute(os.Stdout, map[string]interface{}{"Status": "pending", "Item": item})
+	}
+	return nil
+}
+
+// template for queue list information
+var tmplQueueList = "\x1b[33m{{ .Item.Repo }} #{{ .Item.Build }} \x1b[0m" + `
+ID: {{ .Item.ID }}
+Status: {{ .Status }}
+Pipeline: {{ .Item.Pipeline }}
+Wait: {{ .Item.Waiting }}s
+Agent: {{ .Item.Agent }}
+Labels: {{ range $k, $v := .Item.Labels }}{{ $k }}={{ $v }} {{ end }}
+`
@@ -0,0 +1,26 @@
+package queue
+
+import (
+	"fmt"
+
+	"github.com/drone/drone/drone/internal"
+	"github.com/urfave/cli"
+)
+
+var queuePauseCmd = cli.Command{
+	Name:   "pause",
+	Usage:  "pause the build queue",
+	Action: queuePause,
+}
+
+func queuePause(c *cli.Context) error {
+	client, err := internal.NewClient(c)
+	if err != nil {
+		return err
+	}
+	if err := client.QueuePause(); err != nil {
+		return err
+	}
+	fmt.Println("Successfully paused the queue")
+	return nil
+}
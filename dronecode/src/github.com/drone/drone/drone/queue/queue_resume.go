@@ -0,0 +1,26 @@
+package queue
+
+import (
+	"fmt"
+
+	"github.com/drone/drone/drone/internal"
+	"github.com/urfave/cli"
+)
+
+var queueResumeCmd = cli.Command{
+	Name:   "resume",
+	Usage:  "resume the build queue",
+	Action: queueResume,
+}
+
+func queueResume(c *cli.Context) error {
+	client, err := internal.NewClient(c)
+	if err != nil {
+		return err
+	}
+	if err := client.QueueResume(); err != nil {
+		return err
+	}
+	fmt.Println("Successfully resumed the queue")
+	return nil
+}
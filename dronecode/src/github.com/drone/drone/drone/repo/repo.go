@@ -14,5 +14,7 @@ var Command = cli.Command{
 		repoRemoveCmd,
 		repoRepairCmd,
 		repoChownCmd,
+		repoExportCmd,
+		repoImportCmd,
 	},
 }
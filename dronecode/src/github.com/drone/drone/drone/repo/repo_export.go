@@ -0,0 +1,37 @@
+package repo
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/drone/drone/drone/internal"
+	"github.com/urfave/cli"
+)
+
+var repoExportCmd = cli.Command{
+	Name:      "export",
+	Usage:     "export repository settings, secrets, registries and cron jobs",
+	ArgsUsage: "<repo/name>",
+	Action:    repoExport,
+}
+
+func repoExport(c *cli.Context) error {
+	repo := c.Args().First()
+	owner, name, err := internal.ParseRepo(repo)
+	if err != nil {
+		return err
+	}
+
+	client, err := internal.NewClient(c)
+	if err != nil {
+		return err
+	}
+
+	export, err := client.RepoExport(owner, name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(export)
+}
@@ -0,0 +1,53 @@
+package repo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/drone/drone/drone/internal"
+	"github.com/drone/drone/model"
+	"github.com/urfave/cli"
+)
+
+var repoImportCmd = cli.Command{
+	Name:      "import",
+	Usage:     "import repository settings, secrets, registries and cron jobs",
+	ArgsUsage: "<repo/name> <file>",
+	Action:    repoImport,
+}
+
+func repoImport(c *cli.Context) error {
+	repo := c.Args().First()
+	owner, name, err := internal.ParseRepo(repo)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	switch path := c.Args().Get(1); path {
+	case "", "-":
+		data, err = ioutil.ReadAll(os.Stdin)
+	default:
+		data, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return err
+	}
+	in := new(model.RepoExport)
+	if err := json.Unmarshal(data, in); err != nil {
+		return err
+	}
+
+	client, err := internal.NewClient(c)
+	if err != nil {
+		return err
+	}
+
+	if _, err := client.RepoImport(owner, name, in); err != nil {
+		return err
+	}
+	fmt.Printf("Successfully imported settings into repository %s/%s\n", owner, name)
+	return nil
+}
@@ -53,5 +53,7 @@ Config: {{ .Config }}
 Private: {{ .IsPrivate }}
 Trusted: {{ .IsTrusted }}
 Gated: {{ .IsGated }}
+Auto Cancel Pull Requests: {{ .CancelPulls }}
+Auto Cancel Pushes: {{ .CancelPush }}
 Remote: {{ .Clone }}
 `
@@ -22,14 +22,54 @@ var repoUpdateCmd = cli.Command{
 			Name:  "gated",
 			Usage: "repository is gated",
 		},
+		cli.BoolFlag{
+			Name:  "require-signed",
+			Usage: "repository requires signed yaml",
+		},
+		cli.BoolFlag{
+			Name:  "auto-cancel-pull-requests",
+			Usage: "cancel pending and running pull request builds when the pull request is updated",
+		},
+		cli.BoolFlag{
+			Name:  "auto-cancel-pushes",
+			Usage: "cancel pending and running push builds when the branch is updated",
+		},
+		cli.BoolFlag{
+			Name:  "email-notify",
+			Usage: "send email notifications for builds of the default branch",
+		},
+		cli.StringSliceFlag{
+			Name:  "email-recipient",
+			Usage: "email notification recipient (e.g. dev@example.com)",
+		},
 		cli.DurationFlag{
 			Name:  "timeout",
 			Usage: "repository timeout",
 		},
+		cli.IntFlag{
+			Name:  "throttle",
+			Usage: "repository concurrent build limit",
+		},
+		cli.IntFlag{
+			Name:  "rate-limit",
+			Usage: "repository builds per hour limit, overriding the user limit",
+		},
+		cli.IntFlag{
+			Name:  "priority",
+			Usage: "repository build queue priority",
+		},
 		cli.StringFlag{
 			Name:  "config",
 			Usage: "repository configuration path (e.g. .drone.yml)",
 		},
+		cli.StringSliceFlag{
+			Name:  "skip-pattern",
+			Usage: "commit message pattern that skips the build (e.g. ^wip:)",
+		},
+		cli.StringSliceFlag{
+			Name:  "downstream",
+			Usage: "repository built when a build succeeds (e.g. octocat/hello-world@master)",
+		},
 	},
 }
 
@@ -48,8 +88,15 @@ func repoUpdate(c *cli.Context) error {
 	var (
 		config  = c.String("config")
 		timeout = c.Duration("timeout")
+		limit   = c.Int("throttle")
+		rate    = c.Int("rate-limit")
+		prio    = c.Int("priority")
 		trusted = c.Bool("trusted")
 		gated   = c.Bool("gated")
+		signed  = c.Bool("require-signed")
+		pulls   = c.Bool("auto-cancel-pull-requests")
+		pushes  = c.Bool("auto-cancel-pushes")
+		notify  = c.Bool("email-notify")
 	)
 
 	patch := new(model.RepoPatch)
@@ -59,13 +106,46 @@ func repoUpdate(c *cli.Context) error {
 	if c.IsSet("gated") {
 		patch.IsGated = &gated
 	}
+	if c.IsSet("require-signed") {
+		patch.RequireSigned = &signed
+	}
+	if c.IsSet("auto-cancel-pull-requests") {
+		patch.CancelPulls = &pulls
+	}
+	if c.IsSet("auto-cancel-pushes") {
+		patch.CancelPush = &pushes
+	}
+	if c.IsSet("email-notify") {
+		patch.EmailNotify = &notify
+	}
+	if c.IsSet("email-recipient") {
+		recipients := c.StringSlice("email-recipient")
+		patch.EmailTo = &recipients
+	}
 	if c.IsSet("timeout") {
 		v := int64(timeout / time.Minute)
 		patch.Timeout = &v
 	}
+	if c.IsSet("throttle") {
+		patch.Throttle = &limit
+	}
+	if c.IsSet("rate-limit") {
+		patch.RateLimit = &rate
+	}
+	if c.IsSet("priority") {
+		patch.Priority = &prio
+	}
 	if c.IsSet("config") {
 		patch.Config = &config
 	}
+	if c.IsSet("skip-pattern") {
+		patterns := c.StringSlice("skip-pattern")
+		patch.SkipPatterns = &patterns
+	}
+	if c.IsSet("downstream") {
+		downstream := c.StringSlice("downstream")
+		patch.Downstream = &downstream
+	}
 
 	if _, err := client.RepoPatch(owner, name, patch); err != nil {
 		return err
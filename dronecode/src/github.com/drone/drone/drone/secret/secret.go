@@ -2,6 +2,32 @@ package secret
 
 import "github.com/urfave/cli"
 
+// orgFlags are the flags used to manage organization and global secrets
+// instead of repository secrets.
+var orgFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "organization",
+		Usage: "organization name (e.g. octocat)",
+	},
+	cli.BoolFlag{
+		Name:  "global",
+		Usage: "global secret available to all repositories",
+	},
+}
+
+// helper function returns the organization owner, or an empty owner for
+// global secrets, and true if an organization or global secret was
+// requested.
+func orgOwner(c *cli.Context) (string, bool) {
+	if c.Bool("global") {
+		return "", true
+	}
+	if org := c.String("organization"); org != "" {
+		return org, true
+	}
+	return "", false
+}
+
 // Command exports the secret command.
 var Command = cli.Command{
 	Name:  "secret",
@@ -14,7 +14,7 @@ var secretCreateCmd = cli.Command{
 	Name:   "add",
 	Usage:  "adds a secret",
 	Action: secretCreate,
-	Flags: []cli.Flag{
+	Flags: append(orgFlags,
 		cli.StringFlag{
 			Name:  "repository",
 			Usage: "repository name (e.g. octocat/hello-world)",
@@ -35,10 +35,17 @@ var secretCreateCmd = cli.Command{
 			Name:  "image",
 			Usage: "secret limited to these images",
 		},
-	},
+		cli.StringSliceFlag{
+			Name:  "branch",
+			Usage: "secret limited to these branches",
+		},
+	),
 }
 
 func secretCreate(c *cli.Context) error {
+	if owner, ok := orgOwner(c); ok {
+		return orgSecretCreate(c, owner)
+	}
 	reponame := c.String("repository")
 	if reponame == "" {
 		reponame = c.Args().First()
@@ -52,10 +59,11 @@ func secretCreate(c *cli.Context) error {
 		return err
 	}
 	secret := &model.Secret{
-		Name:   c.String("name"),
-		Value:  c.String("value"),
-		Images: c.StringSlice("image"),
-		Events: c.StringSlice("event"),
+		Name:     c.String("name"),
+		Value:    c.String("value"),
+		Images:   c.StringSlice("image"),
+		Events:   c.StringSlice("event"),
+		Branches: c.StringSlice("branch"),
 	}
 	if len(secret.Events) == 0 {
 		secret.Events = defaultSecretEvents
@@ -72,6 +80,33 @@ func secretCreate(c *cli.Context) error {
 	return err
 }
 
+func orgSecretCreate(c *cli.Context, owner string) error {
+	client, err := internal.NewClient(c)
+	if err != nil {
+		return err
+	}
+	secret := &model.OrgSecret{
+		Name:     c.String("name"),
+		Value:    c.String("value"),
+		Images:   c.StringSlice("image"),
+		Events:   c.StringSlice("event"),
+		Branches: c.StringSlice("branch"),
+	}
+	if len(secret.Events) == 0 {
+		secret.Events = defaultSecretEvents
+	}
+	if strings.HasPrefix(secret.Value, "@") {
+		path := strings.TrimPrefix(secret.Value, "@")
+		out, ferr := ioutil.ReadFile(path)
+		if ferr != nil {
+			return ferr
+		}
+		secret.Value = string(out)
+	}
+	_, err = client.OrgSecretCreate(owner, secret)
+	return err
+}
+
 var defaultSecretEvents = []string{
 	model.EventPush,
 	model.EventTag,
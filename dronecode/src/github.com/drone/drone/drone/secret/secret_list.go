@@ -14,7 +14,7 @@ var secretListCmd = cli.Command{
 	Name:   "ls",
 	Usage:  "list secrets",
 	Action: secretList,
-	Flags: []cli.Flag{
+	Flags: append(orgFlags,
 		cli.StringFlag{
 			Name:  "repository",
 			Usage: "repository name (e.g. octocat/hello-world)",
@@ -25,7 +25,7 @@ var secretListCmd = cli.Command{
 			Value:  tmplSecretList,
 			Hidden: true,
 		},
-	},
+	),
 }
 
 func secretList(c *cli.Context) error {
@@ -33,6 +33,9 @@ func secretList(c *cli.Context) error {
 		format   = c.String("format") + "\n"
 		reponame = c.String("repository")
 	)
+	if owner, ok := orgOwner(c); ok {
+		return orgSecretList(c, owner, format)
+	}
 	if reponame == "" {
 		reponame = c.Args().First()
 	}
@@ -58,6 +61,25 @@ func secretList(c *cli.Context) error {
 	return nil
 }
 
+func orgSecretList(c *cli.Context, owner, format string) error {
+	client, err := internal.NewClient(c)
+	if err != nil {
+		return err
+	}
+	list, err := client.OrgSecretList(owner)
+	if err != nil {
+		return err
+	}
+	tmpl, err := template.New("_").Funcs(secretFuncMap).Parse(format)
+	if err != nil {
+		return err
+	}
+	for _, secret := range list {
+		tmpl.Execute(os.Stdout, secret)
+	}
+	return nil
+}
+
 // template for secret list items
 var tmplSecretList = "\x1b[33m{{ .Name }} \x1b[0m" + `
 Events: {{ list .Events }}
@@ -66,6 +88,11 @@ Images: {{ list .Images }}
 {{- else }}
 Images: <any>
 {{- end }}
+{{- if .Branches }}
+Branches: {{ list .Branches }}
+{{- else }}
+Branches: <any>
+{{- end }}
 `
 
 var secretFuncMap = template.FuncMap{
@@ -10,7 +10,7 @@ var secretDeleteCmd = cli.Command{
 	Name:   "rm",
 	Usage:  "remove a secret",
 	Action: secretDelete,
-	Flags: []cli.Flag{
+	Flags: append(orgFlags,
 		cli.StringFlag{
 			Name:  "repository",
 			Usage: "repository name (e.g. octocat/hello-world)",
@@ -19,7 +19,7 @@ var secretDeleteCmd = cli.Command{
 			Name:  "name",
 			Usage: "secret name",
 		},
-	},
+	),
 }
 
 func secretDelete(c *cli.Context) error {
@@ -27,6 +27,13 @@ func secretDelete(c *cli.Context) error {
 		secret   = c.String("name")
 		reponame = c.String("repository")
 	)
+	if owner, ok := orgOwner(c); ok {
+		client, err := internal.NewClient(c)
+		if err != nil {
+			return err
+		}
+		return client.OrgSecretDelete(owner, secret)
+	}
 	if reponame == "" {
 		reponame = c.Args().First()
 	}
@@ -35,6 +35,10 @@ var secretUpdateCmd = cli.Command{
 			Name:  "image",
 			Usage: "secret limited to these images",
 		},
+		cli.StringSliceFlag{
+			Name:  "branch",
+			Usage: "secret limited to these branches",
+		},
 	},
 }
 
@@ -52,10 +56,11 @@ func secretUpdate(c *cli.Context) error {
 		return err
 	}
 	secret := &model.Secret{
-		Name:   c.String("name"),
-		Value:  c.String("value"),
-		Images: c.StringSlice("image"),
-		Events: c.StringSlice("events"),
+		Name:     c.String("name"),
+		Value:    c.String("value"),
+		Images:   c.StringSlice("image"),
+		Events:   c.StringSlice("event"),
+		Branches: c.StringSlice("branch"),
 	}
 	if strings.HasPrefix(secret.Value, "@") {
 		path := strings.TrimPrefix(secret.Value, "@")
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/store/datastore"
+
+	"github.com/urfave/cli"
+)
+
+// datastoreFlags defines the database flags of the commands that connect to
+// the database directly.
+var datastoreFlags = append([]cli.Flag{
+	cli.StringFlag{
+		EnvVar: "DRONE_DATABASE_DRIVER,DATABASE_DRIVER",
+		Name:   "driver",
+		Usage:  "database driver (sqlite3, mysql, postgres or cockroach)",
+		Value:  "sqlite3",
+	},
+	cli.StringFlag{
+		EnvVar: "DRONE_DATABASE_DATASOURCE,DATABASE_CONFIG",
+		Name:   "datasource",
+		Usage:  "database driver configuration string",
+		Value:  "drone.sqlite",
+	},
+}, databaseFlags...)
+
+// backupFlags defines the flags of the backup and restore commands.
+var backupFlags = append([]cli.Flag{
+	cli.StringFlag{
+		EnvVar: "DRONE_BACKUP_KEY",
+		Name:   "backup-key",
+		Usage:  "key used to encrypt the secrets in the backup",
+	},
+}, datastoreFlags...)
+
+var backupCmd = cli.Command{
+	Name:      "backup",
+	Usage:     "backup the database to a portable archive",
+	ArgsUsage: "<file>",
+	Action:    backup,
+	Flags:     backupFlags,
+}
+
+var restoreCmd = cli.Command{
+	Name:      "restore",
+	Usage:     "restore the database from a portable archive",
+	ArgsUsage: "<file>",
+	Action:    restore,
+	Flags:     backupFlags,
+}
+
+func backup(c *cli.Context) error {
+	path := c.Args().First()
+	if path == "" {
+		return fmt.Errorf("Error: missing backup file")
+	}
+	s := datastore.NewOpts(databaseOpts(c))
+
+	archive, err := s.Backup()
+	if err != nil {
+		return err
+	}
+	if err := archive.Seal(c.String("backup-key")); err != nil {
+		return fmt.Errorf("Error: cannot encrypt backup. %s", err)
+	}
+
+	var out io.Writer = os.Stdout
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	w := gzip.NewWriter(out)
+	if err := json.NewEncoder(w).Encode(archive); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Successfully backed up %d users and %d repositories\n",
+		len(archive.Tables["users"]),
+		len(archive.Tables["repos"]),
+	)
+	return nil
+}
+
+func restore(c *cli.Context) error {
+	path := c.Args().First()
+	if path == "" {
+		return fmt.Errorf("Error: missing backup file")
+	}
+
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	r, err := gzip.NewReader(in)
+	if err != nil {
+		return err
+	}
+	archive := new(model.Backup)
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if err := dec.Decode(archive); err != nil {
+		return err
+	}
+	if err := archive.Open(c.String("backup-key")); err != nil {
+		return fmt.Errorf("Error: cannot decrypt backup. %s", err)
+	}
+
+	s := datastore.NewOpts(databaseOpts(c))
+	if err := s.Restore(archive); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Successfully restored %d users and %d repositories\n",
+		len(archive.Tables["users"]),
+		len(archive.Tables["repos"]),
+	)
+	return nil
+}
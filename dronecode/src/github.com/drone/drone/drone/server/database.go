@@ -0,0 +1,73 @@
+package server
+
+import (
+	"github.com/drone/drone/store/datastore"
+
+	"github.com/urfave/cli"
+)
+
+// databaseFlags defines the connection options of the database, shared by
+// the server, backup and restore commands.
+var databaseFlags = []cli.Flag{
+	cli.StringFlag{
+		EnvVar: "DRONE_DATABASE_REPLICA_DATASOURCE",
+		Name:   "datasource-replica",
+		Usage:  "read replica database driver configuration string, used by the build list, log and badge endpoints",
+	},
+	cli.IntFlag{
+		EnvVar: "DRONE_DATABASE_MAX_OPEN",
+		Name:   "database-max-open",
+		Usage:  "maximum number of open database connections",
+	},
+	cli.IntFlag{
+		EnvVar: "DRONE_DATABASE_MAX_IDLE",
+		Name:   "database-max-idle",
+		Usage:  "maximum number of idle database connections",
+	},
+	cli.DurationFlag{
+		EnvVar: "DRONE_DATABASE_MAX_LIFETIME",
+		Name:   "database-max-lifetime",
+		Usage:  "maximum duration a database connection is reused",
+	},
+	cli.StringFlag{
+		EnvVar: "DRONE_DATABASE_SSL_MODE",
+		Name:   "database-ssl-mode",
+		Usage:  "database ssl mode (disable, require, verify-ca or verify-full)",
+	},
+	cli.StringFlag{
+		EnvVar: "DRONE_DATABASE_SSL_CA",
+		Name:   "database-ssl-ca",
+		Usage:  "database certificate authority file",
+	},
+	cli.StringFlag{
+		EnvVar: "DRONE_DATABASE_SSL_CERT",
+		Name:   "database-ssl-cert",
+		Usage:  "database client certificate file",
+	},
+	cli.StringFlag{
+		EnvVar: "DRONE_DATABASE_SSL_KEY",
+		Name:   "database-ssl-key",
+		Usage:  "database client key file",
+	},
+	cli.DurationFlag{
+		EnvVar: "DRONE_DATABASE_STATEMENT_TIMEOUT",
+		Name:   "database-statement-timeout",
+		Usage:  "maximum duration of a database statement",
+	},
+}
+
+// helper function returns the database connection options.
+func databaseOpts(c *cli.Context) datastore.Opts {
+	return datastore.Opts{
+		Driver:           c.String("driver"),
+		Config:           c.String("datasource"),
+		MaxOpen:          c.Int("database-max-open"),
+		MaxIdle:          c.Int("database-max-idle"),
+		MaxLifetime:      c.Duration("database-max-lifetime"),
+		SSLMode:          c.String("database-ssl-mode"),
+		SSLCA:            c.String("database-ssl-ca"),
+		SSLCert:          c.String("database-ssl-cert"),
+		SSLKey:           c.String("database-ssl-key"),
+		StatementTimeout: c.Duration("database-statement-timeout"),
+	}
+}
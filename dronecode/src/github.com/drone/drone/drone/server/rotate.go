@@ -0,0 +1,80 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/drone/drone/plugins/secrets"
+	"github.com/drone/drone/store/datastore"
+
+	"github.com/urfave/cli"
+)
+
+var rotateSecretsCmd = cli.Command{
+	Name:   "rotate-secrets",
+	Usage:  "re-encrypt the secrets, registry credentials and user tokens with a new secret key",
+	Action: rotateSecrets,
+	Flags: append([]cli.Flag{
+		cli.StringFlag{
+			EnvVar: "DRONE_SECRET_KEY",
+			Name:   "old-key",
+			Usage:  "secret key the values are encrypted with, or empty if the values are not encrypted",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_SECRET_KEY_NEW",
+			Name:   "new-key",
+			Usage:  "secret key the values are encrypted with, or empty to decrypt the values",
+		},
+		cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "decrypt the values without writing them to the database",
+		},
+	}, datastoreFlags...),
+}
+
+// rotateSecrets re-encrypts the values with the new key in a single
+// transaction. The server must be restarted with the new key afterwards.
+func rotateSecrets(c *cli.Context) error {
+	oldKey, newKey := c.String("old-key"), c.String("new-key")
+	if oldKey == "" && newKey == "" {
+		return fmt.Errorf("Error: missing old or new secret key")
+	}
+	var from, to secrets.KeyService
+	if oldKey != "" {
+		from = secrets.NewLocalKeys(oldKey)
+	}
+	if newKey != "" {
+		to = secrets.NewLocalKeys(newKey)
+	}
+	reseal := secrets.NewResealer(from, to)
+
+	counts := map[string]int{}
+	fn := func(table, value string) (string, error) {
+		counts[table]++
+		if n := counts[table]; n%1000 == 0 {
+			fmt.Fprintf(os.Stderr, "%s: %d values\n", table, n)
+		}
+		return reseal(value)
+	}
+
+	s := datastore.NewOpts(databaseOpts(c))
+	count, err := s.Reseal(fn, c.Bool("dry-run"))
+	if err != nil {
+		return fmt.Errorf("Error: cannot re-encrypt values. %s", err)
+	}
+	var tables []string
+	for table := range counts {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		fmt.Fprintf(os.Stderr, "%s: %d values\n", table, counts[table])
+	}
+	if c.Bool("dry-run") {
+		fmt.Fprintf(os.Stderr, "Successfully checked %d values, no values were written\n", count)
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "Successfully re-encrypted %d values\n", count)
+	return nil
+}
@@ -2,25 +2,36 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"net/http"
 	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
-	"golang.org/x/crypto/acme/autocert"
+	"golang.org/x/crypto/acme"
 	"golang.org/x/sync/errgroup"
 
-	"github.com/cncd/logging"
-	"github.com/cncd/pubsub"
+	"github.com/drone/drone/plugins/config"
+	"github.com/drone/drone/plugins/email"
 	"github.com/drone/drone/plugins/registry"
 	"github.com/drone/drone/plugins/secrets"
 	"github.com/drone/drone/plugins/sender"
+	"github.com/drone/drone/plugins/slack"
+	"github.com/drone/drone/plugins/webhook"
 	"github.com/drone/drone/router"
 	"github.com/drone/drone/router/middleware"
+	"github.com/drone/drone/router/middleware/logger"
 	droneserver "github.com/drone/drone/server"
+	"github.com/drone/drone/server/metrics"
+	"github.com/drone/drone/shared/httputil"
+	"github.com/drone/drone/shared/oidc"
+	"github.com/drone/drone/shared/trace"
 	"github.com/drone/drone/store"
 
 	"github.com/Sirupsen/logrus"
-	"github.com/gin-gonic/contrib/ginrus"
+	"github.com/cncd/pipeline/pipeline/frontend/yaml"
 	"github.com/urfave/cli"
 )
 
@@ -29,17 +40,60 @@ var Command = cli.Command{
 	Name:   "server",
 	Usage:  "starts the drone server daemon",
 	Action: server,
-	Flags: []cli.Flag{
+	Subcommands: []cli.Command{
+		backupCmd,
+		restoreCmd,
+		rotateSecretsCmd,
+	},
+	Flags: append([]cli.Flag{
 		cli.BoolFlag{
 			EnvVar: "DRONE_DEBUG",
 			Name:   "debug",
 			Usage:  "start the server in debug mode",
 		},
+		cli.StringFlag{
+			EnvVar: "DRONE_LOG_LEVEL",
+			Name:   "log-level",
+			Usage:  "log level (debug, info, warn or error)",
+			Value:  "warn",
+		},
+		cli.BoolFlag{
+			EnvVar: "DRONE_LOG_JSON",
+			Name:   "log-json",
+			Usage:  "write structured logs in json format",
+		},
 		cli.StringFlag{
 			EnvVar: "DRONE_SERVER_HOST,DRONE_HOST",
 			Name:   "server-host",
 			Usage:  "server host",
 		},
+		cli.StringFlag{
+			EnvVar: "DRONE_SERVER_ROOT",
+			Name:   "server-root",
+			Usage:  "server root path, when served under a path of the server host. defaults to the path of the server host",
+		},
+		cli.BoolFlag{
+			EnvVar: "DRONE_SERVER_TRUST_PROXY",
+			Name:   "server-trust-proxy",
+			Usage:  "trust the X-Forwarded-Host and X-Forwarded-Prefix headers of a reverse proxy",
+		},
+		cli.DurationFlag{
+			EnvVar: "DRONE_SHUTDOWN_TIMEOUT",
+			Name:   "shutdown-timeout",
+			Usage:  "maximum duration in-flight requests are completed on shutdown",
+			Value:  time.Second * 30,
+		},
+		cli.DurationFlag{
+			EnvVar: "DRONE_PROC_UPDATE_INTERVAL",
+			Name:   "proc-update-interval",
+			Usage:  "interval of batched step status updates, or zero to write step status updates immediately",
+			Value:  time.Second,
+		},
+		cli.DurationFlag{
+			EnvVar: "DRONE_SHUTDOWN_DELAY",
+			Name:   "shutdown-delay",
+			Usage:  "duration the readiness check fails before the server stops accepting connections on shutdown",
+		},
 		cli.StringFlag{
 			EnvVar: "DRONE_SERVER_ADDR",
 			Name:   "server-addr",
@@ -61,6 +115,62 @@ var Command = cli.Command{
 			Name:   "lets-encrypt",
 			Usage:  "lets encrypt enabled",
 		},
+		cli.StringSliceFlag{
+			EnvVar: "DRONE_LETS_ENCRYPT_HOST",
+			Name:   "lets-encrypt-host",
+			Usage:  "additional hostnames of the lets encrypt certificates",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_LETS_ENCRYPT_DIRECTORY",
+			Name:   "lets-encrypt-directory",
+			Usage:  "acme directory url, used to request certificates from an internal certificate authority",
+			Value:  acme.LetsEncryptURL,
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_LETS_ENCRYPT_CA_CERT",
+			Name:   "lets-encrypt-ca-cert",
+			Usage:  "ca certificate used to verify the acme directory",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_LETS_ENCRYPT_EMAIL",
+			Name:   "lets-encrypt-email",
+			Usage:  "contact email of the acme account",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_LETS_ENCRYPT_CACHE",
+			Name:   "lets-encrypt-cache",
+			Usage:  "lets encrypt certificate cache directory",
+		},
+		cli.BoolFlag{
+			EnvVar: "DRONE_SERVER_REDIRECT",
+			Name:   "server-redirect",
+			Usage:  "redirect http requests on port 80 to https when tls is enabled",
+		},
+		cli.BoolFlag{
+			EnvVar: "DRONE_HOOK_UNSIGNED",
+			Name:   "hook-unsigned",
+			Usage:  "accept hooks without a valid signature, while the hooks registered without the hook secret are repaired",
+		},
+		cli.IntFlag{
+			EnvVar: "DRONE_RATE_LIMIT_HOOK",
+			Name:   "rate-limit-hook",
+			Usage:  "maximum hook requests per minute of each client",
+		},
+		cli.IntFlag{
+			EnvVar: "DRONE_RATE_LIMIT_API",
+			Name:   "rate-limit-api",
+			Usage:  "maximum api requests per minute of each client",
+		},
+		cli.IntFlag{
+			EnvVar: "DRONE_RATE_LIMIT_BADGE",
+			Name:   "rate-limit-badge",
+			Usage:  "maximum badge requests per minute of each client",
+		},
+		cli.IntFlag{
+			EnvVar: "DRONE_RATE_LIMIT_LOGIN",
+			Name:   "rate-limit-login",
+			Usage:  "maximum login requests per minute of each client",
+		},
 		cli.StringSliceFlag{
 			EnvVar: "DRONE_ADMIN",
 			Name:   "admin",
@@ -82,6 +192,92 @@ var Command = cli.Command{
 			Usage:  "cache duration",
 			Value:  time.Minute * 15,
 		},
+		cli.StringSliceFlag{
+			EnvVar: "DRONE_QUEUE_PRIORITY",
+			Name:   "queue-priority",
+			Usage:  "queue priority for build events and branches (e.g. deployment=10,push:master=5)",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_LOGS_PATH",
+			Name:   "logs-path",
+			Usage:  "directory used to store build logs instead of the database",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_LOGS_S3_BUCKET",
+			Name:   "logs-s3-bucket",
+			Usage:  "s3 bucket used to store build logs instead of the database",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_LOGS_S3_PREFIX",
+			Name:   "logs-s3-prefix",
+			Usage:  "object key prefix of the stored build logs",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_LOGS_S3_ENDPOINT",
+			Name:   "logs-s3-endpoint",
+			Usage:  "s3 compatible endpoint, such as a minio server, used to store build logs",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_LOGS_GCS_BUCKET",
+			Name:   "logs-gcs-bucket",
+			Usage:  "google cloud storage bucket used to store build logs, accessed with hmac keys provided as aws credentials",
+		},
+		cli.BoolFlag{
+			EnvVar: "DRONE_LOGS_PRESIGN",
+			Name:   "logs-presign",
+			Usage:  "agents upload the build logs directly to the s3 or gcs bucket using pre-signed urls, instead of through the server",
+		},
+		cli.DurationFlag{
+			EnvVar: "DRONE_ARTIFACTS_RETENTION",
+			Name:   "artifacts-retention",
+			Usage:  "duration build artifacts are kept before they are deleted",
+		},
+		cli.DurationFlag{
+			EnvVar: "DRONE_RETENTION_LOGS",
+			Name:   "retention-logs",
+			Usage:  "duration build logs are kept before they are deleted",
+		},
+		cli.IntFlag{
+			EnvVar: "DRONE_RETENTION_BUILDS",
+			Name:   "retention-builds",
+			Usage:  "number of most recent builds kept for each repository",
+		},
+		cli.DurationFlag{
+			EnvVar: "DRONE_RETENTION_HOOKS",
+			Name:   "retention-hooks",
+			Usage:  "duration received hooks are kept before they are deleted",
+		},
+		cli.DurationFlag{
+			EnvVar: "DRONE_SESSION_EXPIRES",
+			Name:   "session-expires",
+			Usage:  "duration user sessions are valid before the user must login again",
+			Value:  time.Hour * 72,
+		},
+		cli.DurationFlag{
+			EnvVar: "DRONE_USER_TOKEN_EXPIRES",
+			Name:   "user-token-expires",
+			Usage:  "duration user api tokens are valid. user api tokens never expire if unset",
+		},
+		cli.DurationFlag{
+			EnvVar: "DRONE_REPO_SYNC_INTERVAL",
+			Name:   "repo-sync-interval",
+			Usage:  "interval the metadata of active repositories is synced from the remote",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_REDIS_URL",
+			Name:   "redis-url",
+			Usage:  "redis server url used to share the queue, events and logs between servers",
+		},
+		cli.IntFlag{
+			EnvVar: "DRONE_ORG_THROTTLE",
+			Name:   "org-throttle",
+			Usage:  "maximum number of concurrent builds for each organization",
+		},
+		cli.IntFlag{
+			EnvVar: "DRONE_BUILD_RATE_LIMIT",
+			Name:   "build-rate-limit",
+			Usage:  "maximum number of builds per hour triggered by hooks and restarts in the repositories of each user",
+		},
 		cli.StringSliceFlag{
 			EnvVar: "DRONE_ESCALATE",
 			Name:   "escalate",
@@ -95,6 +291,57 @@ var Command = cli.Command{
 			EnvVar: "DRONE_VOLUME",
 			Name:   "volume",
 		},
+		cli.StringSliceFlag{
+			EnvVar: "DRONE_VOLUME_PATHS",
+			Name:   "volume-paths",
+			Usage:  "host paths that trusted repositories may mount, any path if empty",
+		},
+		cli.StringSliceFlag{
+			EnvVar: "DRONE_VOLUME_NAMES",
+			Name:   "volume-names",
+			Usage:  "name patterns of the named volumes that persist between builds, which repositories may define",
+		},
+		cli.StringSliceFlag{
+			EnvVar: "DRONE_INCLUDE_REPOS",
+			Name:   "include-repos",
+			Usage:  "name patterns of the config repositories that pipelines may include yaml fragments from",
+		},
+		cli.BoolFlag{
+			EnvVar: "DRONE_CACHE_SERVER",
+			Name:   "cache-server",
+			Usage:  "store the pipeline cache on the server, instead of a named volume of the agent",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_CACHE_IMAGE",
+			Name:   "cache-image",
+			Usage:  "image of the steps that restore and save the pipeline cache",
+		},
+		cli.Int64Flag{
+			EnvVar: "DRONE_CACHE_MAX_SIZE",
+			Name:   "cache-max-size",
+			Usage:  "maximum size in bytes of each pipeline cache stored on the server",
+			Value:  256 << 20,
+		},
+		cli.BoolFlag{
+			EnvVar: "DRONE_JSONNET",
+			Name:   "jsonnet",
+			Usage:  "enable .drone.jsonnet configurations, which are evaluated without imports",
+		},
+		cli.BoolFlag{
+			EnvVar: "DRONE_STARLARK",
+			Name:   "starlark",
+			Usage:  "enable .drone.star configurations, which are evaluated without load statements",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_MAX_STEP_CPU",
+			Name:   "max-step-cpu",
+			Usage:  "maximum cpu resources of a pipeline step, such as 2 or 1500m, which is unlimited if empty",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_MAX_STEP_MEMORY",
+			Name:   "max-step-memory",
+			Usage:  "maximum memory resources of a pipeline step, such as 4GiB, which is unlimited if empty",
+		},
 		cli.StringSliceFlag{
 			EnvVar: "DRONE_NETWORK",
 			Name:   "network",
@@ -104,6 +351,76 @@ var Command = cli.Command{
 			Name:   "agent-secret",
 			Usage:  "agent secret passcode",
 		},
+		cli.StringFlag{
+			EnvVar: "DRONE_PROMETHEUS_AUTH_TOKEN",
+			Name:   "prometheus-auth-token",
+			Usage:  "token to secure prometheus metrics endpoint",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_VAULT_ADDR",
+			Name:   "vault-addr",
+			Usage:  "vault server address",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_VAULT_TOKEN",
+			Name:   "vault-token",
+			Usage:  "vault authentication token",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_VAULT_ROLE_ID",
+			Name:   "vault-role-id",
+			Usage:  "vault approle role id",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_VAULT_SECRET_ID",
+			Name:   "vault-secret-id",
+			Usage:  "vault approle secret id",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_VAULT_MOUNT",
+			Name:   "vault-mount",
+			Usage:  "vault kv version 2 secrets engine mount",
+			Value:  "secret",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_VAULT_PATH",
+			Name:   "vault-path",
+			Usage:  "vault path prefix for repository and organization secrets",
+			Value:  "drone",
+		},
+		cli.DurationFlag{
+			EnvVar: "DRONE_VAULT_CACHE_TTL",
+			Name:   "vault-cache-ttl",
+			Usage:  "vault secret cache duration",
+			Value:  time.Minute,
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_SECRET_KEY",
+			Name:   "secret-key",
+			Usage:  "key used to encrypt the secrets, registry credentials and user tokens stored in the database",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_KMS_KEY_ID",
+			Name:   "kms-key-id",
+			Usage:  "aws kms key used to encrypt secrets stored in the database",
+		},
+		cli.BoolFlag{
+			EnvVar: "DRONE_AWS_SECRETS_MANAGER",
+			Name:   "aws-secrets-manager",
+			Usage:  "source secrets from aws secrets manager",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_AWS_SECRETS_PREFIX",
+			Name:   "aws-secrets-prefix",
+			Usage:  "aws secrets manager name prefix for repository and organization secrets",
+			Value:  "drone",
+		},
+		cli.DurationFlag{
+			EnvVar: "DRONE_AWS_SECRETS_CACHE_TTL",
+			Name:   "aws-secrets-cache-ttl",
+			Usage:  "aws secrets manager cache duration",
+			Value:  time.Minute,
+		},
 		cli.StringFlag{
 			EnvVar: "DRONE_SECRET_ENDPOINT",
 			Name:   "secret-service",
@@ -114,6 +431,108 @@ var Command = cli.Command{
 			Name:   "registry-service",
 			Usage:  "registry plugin endpoint",
 		},
+		cli.StringFlag{
+			EnvVar: "DRONE_SECRET_EXTENSION_ENDPOINT",
+			Name:   "secret-extension",
+			Usage:  "secret extension endpoint, used to resolve secrets at build time",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_SECRET_EXTENSION_SECRET",
+			Name:   "secret-extension-secret",
+			Usage:  "secret extension shared secret",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_YAML_ENDPOINT",
+			Name:   "yaml-service",
+			Usage:  "pipeline configuration plugin endpoint",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_YAML_SECRET",
+			Name:   "yaml-secret",
+			Usage:  "pipeline configuration plugin shared secret",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_VALIDATE_ENDPOINT",
+			Name:   "validate-service",
+			Usage:  "pipeline validation plugin endpoint",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_VALIDATE_SECRET",
+			Name:   "validate-secret",
+			Usage:  "pipeline validation plugin shared secret",
+		},
+		cli.StringSliceFlag{
+			EnvVar: "DRONE_WEBHOOK_ENDPOINT",
+			Name:   "webhook-endpoint",
+			Usage:  "system webhook endpoints that receive build lifecycle events",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_WEBHOOK_SECRET",
+			Name:   "webhook-secret",
+			Usage:  "system webhook shared secret used to sign the payloads",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_SMTP_HOST",
+			Name:   "smtp-host",
+			Usage:  "smtp server host used to send email notifications",
+		},
+		cli.IntFlag{
+			EnvVar: "DRONE_SMTP_PORT",
+			Name:   "smtp-port",
+			Usage:  "smtp server port",
+			Value:  587,
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_SMTP_USERNAME",
+			Name:   "smtp-username",
+			Usage:  "smtp server username",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_SMTP_PASSWORD",
+			Name:   "smtp-password",
+			Usage:  "smtp server password",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_SMTP_FROM",
+			Name:   "smtp-from",
+			Usage:  "email notification sender address",
+			Value:  "drone@localhost",
+		},
+		cli.BoolFlag{
+			EnvVar: "DRONE_SMTP_SKIP_VERIFY",
+			Name:   "smtp-skip-verify",
+			Usage:  "skip smtp server certificate verification",
+		},
+		cli.StringSliceFlag{
+			EnvVar: "DRONE_SLACK_WEBHOOK",
+			Name:   "slack-webhook",
+			Usage:  "slack or mattermost incoming webhooks that receive build notifications",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_SLACK_CHANNEL",
+			Name:   "slack-channel",
+			Usage:  "slack channel of build notifications",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_SLACK_TEMPLATE",
+			Name:   "slack-template",
+			Usage:  "slack build notification message template",
+		},
+		cli.StringSliceFlag{
+			EnvVar: "DRONE_SLACK_REPOS",
+			Name:   "slack-repos",
+			Usage:  "repositories of slack build notifications (e.g. octocat/*)",
+		},
+		cli.StringSliceFlag{
+			EnvVar: "DRONE_SLACK_BRANCHES",
+			Name:   "slack-branches",
+			Usage:  "branches of slack build notifications (e.g. master)",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_TRACING_ENDPOINT",
+			Name:   "tracing-endpoint",
+			Usage:  "zipkin compatible span collector (e.g. http://localhost:9411/api/v2/spans)",
+		},
 		cli.StringFlag{
 			EnvVar: "DRONE_GATEKEEPER_ENDPOINT",
 			Name:   "gating-service",
@@ -122,7 +541,7 @@ var Command = cli.Command{
 		cli.StringFlag{
 			EnvVar: "DRONE_DATABASE_DRIVER,DATABASE_DRIVER",
 			Name:   "driver",
-			Usage:  "database driver",
+			Usage:  "database driver (sqlite3, mysql, postgres or cockroach)",
 			Value:  "sqlite3",
 		},
 		cli.StringFlag{
@@ -194,6 +613,21 @@ var Command = cli.Command{
 			Name:   "github-skip-verify",
 			Usage:  "github skip ssl verification",
 		},
+		cli.Int64Flag{
+			EnvVar: "DRONE_GITHUB_APP_ID",
+			Name:   "github-app-id",
+			Usage:  "github app id",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_GITHUB_APP_PRIVATE_KEY",
+			Name:   "github-app-private-key",
+			Usage:  "github app private key file",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_GITHUB_APP_SECRET",
+			Name:   "github-app-secret",
+			Usage:  "github app webhook secret",
+		},
 		cli.BoolFlag{
 			EnvVar: "DRONE_GOGS",
 			Name:   "gogs",
@@ -225,6 +659,141 @@ var Command = cli.Command{
 			Name:   "gogs-skip-verify",
 			Usage:  "gogs skip ssl verification",
 		},
+		cli.BoolFlag{
+			EnvVar: "DRONE_OIDC",
+			Name:   "oidc",
+			Usage:  "openid connect login enabled",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_OIDC_ISSUER",
+			Name:   "oidc-issuer",
+			Usage:  "openid connect issuer url",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_OIDC_CLIENT_ID",
+			Name:   "oidc-client-id",
+			Usage:  "openid connect client id",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_OIDC_CLIENT_SECRET",
+			Name:   "oidc-client-secret",
+			Usage:  "openid connect client secret",
+		},
+		cli.StringSliceFlag{
+			EnvVar: "DRONE_OIDC_SCOPES",
+			Name:   "oidc-scopes",
+			Usage:  "openid connect scopes, which default to openid, profile and email",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_OIDC_GROUPS_CLAIM",
+			Name:   "oidc-groups-claim",
+			Usage:  "openid connect id token claim of the user groups",
+			Value:  "groups",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_OIDC_ADMIN_GROUP",
+			Name:   "oidc-admin-group",
+			Usage:  "openid connect group of the system administrators",
+		},
+		cli.BoolFlag{
+			EnvVar: "DRONE_LDAP",
+			Name:   "ldap",
+			Usage:  "ldap authentication enabled",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_LDAP_SERVER",
+			Name:   "ldap-server",
+			Usage:  "ldap server url, using the ldap or ldaps scheme",
+		},
+		cli.BoolFlag{
+			EnvVar: "DRONE_LDAP_STARTTLS",
+			Name:   "ldap-starttls",
+			Usage:  "ldap upgrade connections using starttls",
+		},
+		cli.BoolFlag{
+			EnvVar: "DRONE_LDAP_SKIP_VERIFY",
+			Name:   "ldap-skip-verify",
+			Usage:  "ldap skip ssl verification",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_LDAP_BIND_DN",
+			Name:   "ldap-bind-dn",
+			Usage:  "ldap service account used to search users",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_LDAP_BIND_PASSWORD",
+			Name:   "ldap-bind-password",
+			Usage:  "ldap service account password",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_LDAP_BASE_DN",
+			Name:   "ldap-base-dn",
+			Usage:  "ldap base distinguished name of the user search",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_LDAP_USER_FILTER",
+			Name:   "ldap-user-filter",
+			Usage:  "ldap user search filter",
+			Value:  "(uid=%s)",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_LDAP_EMAIL_ATTR",
+			Name:   "ldap-email-attr",
+			Usage:  "ldap attribute of the user email address",
+			Value:  "mail",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_LDAP_GROUP_ATTR",
+			Name:   "ldap-group-attr",
+			Usage:  "ldap attribute of the user groups",
+			Value:  "memberOf",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_LDAP_ADMIN_GROUP",
+			Name:   "ldap-admin-group",
+			Usage:  "ldap group of the system administrators",
+		},
+		cli.BoolFlag{
+			EnvVar: "DRONE_GITEA",
+			Name:   "gitea",
+			Usage:  "gitea driver is enabled",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_GITEA_URL",
+			Name:   "gitea-server",
+			Usage:  "gitea server address",
+			Value:  "https://try.gitea.io",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_GITEA_CLIENT",
+			Name:   "gitea-client",
+			Usage:  "gitea oauth2 client id",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_GITEA_SECRET",
+			Name:   "gitea-secret",
+			Usage:  "gitea oauth2 client secret",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_GITEA_GIT_USERNAME",
+			Name:   "gitea-git-username",
+			Usage:  "gitea service account username",
+		},
+		cli.StringFlag{
+			EnvVar: "DRONE_GITEA_GIT_PASSWORD",
+			Name:   "gitea-git-password",
+			Usage:  "gitea service account password",
+		},
+		cli.BoolFlag{
+			EnvVar: "DRONE_GITEA_PRIVATE_MODE",
+			Name:   "gitea-private-mode",
+			Usage:  "gitea private mode enabled",
+		},
+		cli.BoolFlag{
+			EnvVar: "DRONE_GITEA_SKIP_VERIFY",
+			Name:   "gitea-skip-verify",
+			Usage:  "gitea skip ssl verification",
+		},
 		cli.BoolFlag{
 			EnvVar: "DRONE_BITBUCKET",
 			Name:   "bitbucket",
@@ -321,7 +890,7 @@ var Command = cli.Command{
 			Name:   "stash-skip-verify",
 			Usage:  "stash skip ssl verification",
 		},
-	},
+	}, databaseFlags...),
 }
 
 func server(c *cli.Context) error {
@@ -330,58 +899,172 @@ func server(c *cli.Context) error {
 	if c.Bool("debug") {
 		logrus.SetLevel(logrus.DebugLevel)
 	} else {
-		logrus.SetLevel(logrus.WarnLevel)
+		level, err := logrus.ParseLevel(c.String("log-level"))
+		if err != nil {
+			logrus.Fatalln(err)
+		}
+		logrus.SetLevel(level)
+	}
+	if c.Bool("log-json") {
+		logrus.SetFormatter(&logrus.JSONFormatter{})
 	}
 
 	s := setupStore(c)
 	setupEvilGlobals(c, s)
 
+	r, err := middleware.SetupRemote(c)
+	if err != nil {
+		logrus.Fatalln(err)
+	}
+
+	// the server drains connections and shuts down when it receives a
+	// termination signal.
+	ctx := signalContext()
+
+	// register the queue and build gauges
+	metrics.Collect(droneserver.Config.Services.Queue, s)
+
+	// start the scheduler for cron jobs
+	go droneserver.CronScheduler(ctx, s, r, time.Minute)
+
+	// start the pruner for expired artifacts
+	if retention := c.Duration("artifacts-retention"); retention != 0 {
+		go droneserver.ArtifactPruner(ctx, s, retention, time.Hour)
+	}
+
+	// start the garbage collector for expired logs, builds and hooks
+	if c.Duration("retention-logs") != 0 || c.Int("retention-builds") != 0 || c.Duration("retention-hooks") != 0 {
+		go droneserver.GarbageCollector(ctx, s, time.Hour)
+	}
+
+	// start the background sync of repository metadata
+	if interval := c.Duration("repo-sync-interval"); interval != 0 {
+		go droneserver.RepoSyncer(ctx, s, r, interval)
+	}
+
 	// setup the server and start the listener
 	handler := router.Load(
-		ginrus.Ginrus(logrus.StandardLogger(), time.RFC3339, true),
+		middleware.RateLimit(c),
+		logger.Middleware(logrus.StandardLogger()),
 		middleware.Version,
 		middleware.Config(c),
 		middleware.Cache(c),
 		middleware.Store(c, s),
-		middleware.Remote(c),
+		middleware.Replica(setupReplica(c)),
+		middleware.Remote(r),
 	)
+	handler = httputil.Root(serverRoot(c), handler)
+	if !c.Bool("server-trust-proxy") {
+		handler = httputil.Untrusted(handler)
+	}
 
-	// start the server with tls enabled
-	if c.String("server-cert") != "" {
-		return http.ListenAndServeTLS(
-			c.String("server-addr"),
+	g, gctx := errgroup.WithContext(ctx)
+	var servers []*http.Server
+	serve := func(srv *http.Server, fn func(*http.Server) error) {
+		servers = append(servers, srv)
+		g.Go(func() error {
+			if err := fn(srv); err != http.ErrServerClosed {
+				return err
+			}
+			return nil
+		})
+	}
+	listen := func(srv *http.Server) error {
+		return srv.ListenAndServe()
+	}
+
+	switch {
+	case c.String("server-cert") != "":
+		// start the server with tls enabled. the certificate is reloaded
+		// when the certificate files change.
+		certs, err := newCertReloader(
 			c.String("server-cert"),
 			c.String("server-key"),
-			handler,
 		)
-	}
+		if err != nil {
+			return err
+		}
+		if c.Bool("server-redirect") {
+			serve(&http.Server{Addr: ":http", Handler: redirectHandler()}, listen)
+		}
+		serve(&http.Server{
+			Addr:      c.String("server-addr"),
+			Handler:   handler,
+			TLSConfig: &tls.Config{GetCertificate: certs.GetCertificate},
+		}, func(srv *http.Server) error {
+			return srv.ListenAndServeTLS("", "")
+		})
 
-	// start the server without tls enabled
-	if !c.Bool("lets-encrypt") {
-		return http.ListenAndServe(
-			c.String("server-addr"),
-			handler,
-		)
+	case c.Bool("lets-encrypt"):
+		// start the server with lets encrypt enabled
+		// listen on ports 443 and 80
+		manager, err := setupAutocert(c)
+		if err != nil {
+			return err
+		}
+		if c.Bool("server-redirect") {
+			serve(&http.Server{Addr: ":http", Handler: redirectHandler()}, listen)
+		} else {
+			serve(&http.Server{Addr: ":http", Handler: handler}, listen)
+		}
+		serve(&http.Server{Handler: handler}, func(srv *http.Server) error {
+			return srv.Serve(manager.Listener())
+		})
+
+	default:
+		// start the server without tls enabled
+		serve(&http.Server{Addr: c.String("server-addr"), Handler: handler}, listen)
 	}
 
-	// start the server with lets encrypt enabled
-	// listen on ports 443 and 80
-	var g errgroup.Group
+	// on shutdown, hooks and agents are rejected and agents are
+	// disconnected, then in-flight requests are completed until the
+	// shutdown timeout expires.
 	g.Go(func() error {
-		return http.ListenAndServe(":http", handler)
-	})
+		<-gctx.Done()
+		logrus.Warnln("server: shutting down, draining connections")
+		droneserver.Drain()
+		time.Sleep(c.Duration("shutdown-delay"))
 
-	g.Go(func() error {
-		address, err := url.Parse(c.String("server-host"))
-		if err != nil {
-			return err
+		sctx, cancel := context.WithTimeout(context.Background(), c.Duration("shutdown-timeout"))
+		defer cancel()
+		for _, srv := range servers {
+			if err := srv.Shutdown(sctx); err != nil {
+				logrus.Errorf("server: cannot drain connections. %s", err)
+				srv.Close()
+			}
 		}
-		return http.Serve(autocert.NewListener(address.Host), handler)
+		droneserver.FlushProcUpdates()
+		droneserver.Config.Services.Tracer.Close()
+		return nil
 	})
-
 	return g.Wait()
 }
 
+// helper function returns a context that is cancelled when the process
+// receives an interrupt or termination signal.
+func signalContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+		<-c
+		cancel()
+	}()
+	return ctx
+}
+
+// helper function returns the root path of the server, which defaults to
+// the path of the server host.
+func serverRoot(c *cli.Context) string {
+	if root := c.String("server-root"); root != "" {
+		return root
+	}
+	if address, err := url.Parse(c.String("server-host")); err == nil {
+		return address.Path
+	}
+	return ""
+}
+
 // HACK please excuse the message during this period of heavy refactoring.
 // We are currently transitioning from storing services (ie database, queue)
 // in the gin.Context to storing them in a struct. We are also moving away
@@ -392,15 +1075,22 @@ func setupEvilGlobals(c *cli.Context, v store.Store) {
 	// storage
 	droneserver.Config.Storage.Files = v
 	droneserver.Config.Storage.Config = v
+	droneserver.Config.Storage.Templates = v
 
 	// services
 	droneserver.Config.Services.Queue = setupQueue(c, v)
-	droneserver.Config.Services.Logs = logging.New()
-	droneserver.Config.Services.Pubsub = pubsub.New()
+	if interval := c.Duration("proc-update-interval"); interval > 0 {
+		droneserver.BatchProcUpdates(v, interval)
+	}
+	droneserver.Config.Services.Logs = setupLogs(c)
+	droneserver.Config.Services.Uploads = setupUploads(c, v)
+	droneserver.Config.Services.Pubsub = setupPubsub(c)
 	droneserver.Config.Services.Pubsub.Create(context.Background(), "topic/events")
 	droneserver.Config.Services.Registries = setupRegistryService(c, v)
 	droneserver.Config.Services.Secrets = setupSecretService(c, v)
+	droneserver.Config.Storage.OrgSecrets = setupOrgSecretStore(c, v)
 	droneserver.Config.Services.Senders = sender.New(v, v)
+	droneserver.Config.Services.Configs = config.New()
 	if endpoint := c.String("registry-service"); endpoint != "" {
 		droneserver.Config.Services.Registries = registry.NewRemote(endpoint)
 	}
@@ -410,6 +1100,44 @@ func setupEvilGlobals(c *cli.Context, v store.Store) {
 	if endpoint := c.String("gating-service"); endpoint != "" {
 		droneserver.Config.Services.Senders = sender.NewRemote(endpoint)
 	}
+	if endpoint := c.String("secret-extension"); endpoint != "" {
+		droneserver.Config.Services.Resolver = secrets.NewExtension(endpoint, c.String("secret-extension-secret"))
+	}
+	if endpoint := c.String("yaml-service"); endpoint != "" {
+		droneserver.Config.Services.Configs = config.NewRemote(endpoint, c.String("yaml-secret"))
+	}
+	if endpoint := c.String("validate-service"); endpoint != "" {
+		droneserver.Config.Services.Validator = config.NewValidator(endpoint, c.String("validate-secret"))
+	}
+	if endpoints := c.StringSlice("webhook-endpoint"); len(endpoints) != 0 {
+		droneserver.Config.Services.Webhooks = webhook.New(v, endpoints, c.String("webhook-secret"))
+	}
+	if host := c.String("smtp-host"); host != "" {
+		droneserver.Config.Services.Email = email.New(email.Opts{
+			Host:       host,
+			Port:       c.Int("smtp-port"),
+			Username:   c.String("smtp-username"),
+			Password:   c.String("smtp-password"),
+			From:       c.String("smtp-from"),
+			SkipVerify: c.Bool("smtp-skip-verify"),
+		})
+	}
+	if webhooks := c.StringSlice("slack-webhook"); len(webhooks) != 0 {
+		chat, err := slack.New(slack.Opts{
+			Webhooks: webhooks,
+			Channel:  c.String("slack-channel"),
+			Template: c.String("slack-template"),
+			Repos:    c.StringSlice("slack-repos"),
+			Branches: c.StringSlice("slack-branches"),
+		})
+		if err != nil {
+			logrus.Fatalf("slack: cannot parse template. %s", err)
+		}
+		droneserver.Config.Services.Chat = chat
+	}
+	if endpoint := c.String("tracing-endpoint"); endpoint != "" {
+		droneserver.Config.Services.Tracer = trace.New(endpoint, "drone-server")
+	}
 
 	// server configuration
 	droneserver.Config.Server.Cert = c.String("server-cert")
@@ -417,9 +1145,55 @@ func setupEvilGlobals(c *cli.Context, v store.Store) {
 	droneserver.Config.Server.Pass = c.String("agent-secret")
 	droneserver.Config.Server.Host = c.String("server-host")
 	droneserver.Config.Server.Port = c.String("server-addr")
+	droneserver.Config.Server.HookUnsigned = c.Bool("hook-unsigned")
 	droneserver.Config.Pipeline.Networks = c.StringSlice("network")
 	droneserver.Config.Pipeline.Volumes = c.StringSlice("volume")
+	droneserver.Config.Pipeline.VolumePaths = c.StringSlice("volume-paths")
+	droneserver.Config.Pipeline.VolumeNames = c.StringSlice("volume-names")
+	droneserver.Config.Pipeline.CacheServer = c.Bool("cache-server")
+	droneserver.Config.Pipeline.CacheImage = c.String("cache-image")
+	droneserver.Config.Pipeline.CacheSize = c.Int64("cache-max-size")
+	droneserver.Config.Pipeline.Jsonnet = c.Bool("jsonnet")
+	droneserver.Config.Pipeline.Starlark = c.Bool("starlark")
+	droneserver.Config.Pipeline.IncludeRepos = c.StringSlice("include-repos")
+	if s := c.String("max-step-cpu"); s != "" {
+		cpu, err := yaml.ParseCPU(s)
+		if err != nil {
+			logrus.Fatalf("cannot parse the maximum step cpu. %s", err)
+		}
+		droneserver.Config.Pipeline.MaxCPU = cpu
+	}
+	if s := c.String("max-step-memory"); s != "" {
+		memory, err := yaml.ParseMemory(s)
+		if err != nil {
+			logrus.Fatalf("cannot parse the maximum step memory. %s", err)
+		}
+		droneserver.Config.Pipeline.MaxMemory = memory
+	}
 	droneserver.Config.Pipeline.Privileged = c.StringSlice("escalate")
+	droneserver.Config.Pipeline.Throttle = c.Int("org-throttle")
+	droneserver.Config.Pipeline.RateLimit = c.Int("build-rate-limit")
+	droneserver.Config.Pipeline.Priority = c.StringSlice("queue-priority")
+	droneserver.Config.Retention.Logs = c.Duration("retention-logs")
+	droneserver.Config.Retention.Builds = c.Int("retention-builds")
+	droneserver.Config.Retention.Hooks = c.Duration("retention-hooks")
+	droneserver.Config.Session.Expires = c.Duration("session-expires")
+	droneserver.Config.Session.TokenExpires = c.Duration("user-token-expires")
+
+	// openid connect
+	if c.Bool("oidc") {
+		provider := oidc.New(
+			c.String("oidc-issuer"),
+			c.String("oidc-client-id"),
+			c.String("oidc-client-secret"),
+		)
+		if scopes := c.StringSlice("oidc-scopes"); len(scopes) != 0 {
+			provider.Scopes = scopes
+		}
+		provider.GroupsClaim = c.String("oidc-groups-claim")
+		droneserver.Config.OIDC.Provider = provider
+		droneserver.Config.OIDC.AdminGroup = c.String("oidc-admin-group")
+	}
 	// droneserver.Config.Server.Open = cli.Bool("open")
 	// droneserver.Config.Server.Orgs = sliceToMap(cli.StringSlice("orgs"))
 	// droneserver.Config.Server.Admins = sliceToMap(cli.StringSlice("admin"))
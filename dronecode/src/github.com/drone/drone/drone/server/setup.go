@@ -3,35 +3,195 @@
 package server
 
 import (
+	"context"
+	"time"
+
+	"github.com/cncd/logging"
+	"github.com/cncd/pubsub"
 	"github.com/cncd/queue"
 	"github.com/drone/drone/model"
+	"github.com/drone/drone/plugins/logs"
+	"github.com/drone/drone/plugins/redis"
 	"github.com/drone/drone/plugins/registry"
 	"github.com/drone/drone/plugins/secrets"
+	droneserver "github.com/drone/drone/server"
+	"github.com/drone/drone/shared/aws"
 	"github.com/drone/drone/store"
 	"github.com/drone/drone/store/datastore"
 
+	"github.com/Sirupsen/logrus"
+	"github.com/hashicorp/vault/api"
 	"github.com/urfave/cli"
 )
 
 func setupStore(c *cli.Context) store.Store {
-	return datastore.New(
-		c.String("driver"),
-		c.String("datasource"),
-	)
+	return setupSealedStore(c, setupLogStore(c, datastore.NewOpts(databaseOpts(c))))
+}
+
+// helper function returns the read replica store, or nil if no read
+// replica is configured.
+func setupReplica(c *cli.Context) store.Store {
+	opts := databaseOpts(c)
+	if opts.Config = c.String("datasource-replica"); opts.Config == "" {
+		return nil
+	}
+	return setupSealedStore(c, setupLogStore(c, datastore.NewReplica(opts)))
+}
+
+// helper function returns the store that encrypts the secrets, registry
+// credentials and user tokens with the secret key, if configured.
+func setupSealedStore(c *cli.Context, s store.Store) store.Store {
+	key := c.String("secret-key")
+	if key == "" {
+		return s
+	}
+	if c.String("kms-key-id") != "" {
+		logrus.Fatalln("secret key and kms key cannot be used together")
+	}
+	return secrets.NewStore(s, secrets.NewLocalKeys(key))
+}
+
+// helper function returns the store with the configured log storage.
+func setupLogStore(c *cli.Context, s store.Store) store.Store {
+	if bucket := setupLogBucket(c); bucket != nil {
+		return logs.New(s, logs.NewS3(bucket, c.String("logs-s3-prefix")))
+	}
+	if c.String("logs-path") != "" {
+		return logs.New(s, logs.NewFilesystem(c.String("logs-path")))
+	}
+	return s
+}
+
+// helper function returns the s3 or gcs bucket of the log storage, or nil
+// if the logs are not stored in a bucket.
+func setupLogBucket(c *cli.Context) *aws.S3 {
+	switch {
+	case c.String("logs-s3-bucket") != "":
+		client := aws.NewEnv()
+		client.Endpoint = c.String("logs-s3-endpoint")
+		return aws.NewS3(client, c.String("logs-s3-bucket"))
+	case c.String("logs-gcs-bucket") != "":
+		// google cloud storage is accessed using the s3 compatible api,
+		// with hmac keys provided as aws credentials.
+		client := aws.NewEnv()
+		client.Region = "auto"
+		client.Endpoint = "https://storage.googleapis.com"
+		return aws.NewS3(client, c.String("logs-gcs-bucket"))
+	}
+	return nil
+}
+
+// helper function returns the service that pre-signs the log uploads of
+// the agents, or nil if the agents upload the logs through the server. The
+// agents upload through the server once the urls expire.
+func setupUploads(c *cli.Context, s store.Store) model.UploadService {
+	if !c.Bool("logs-presign") {
+		return nil
+	}
+	bucket := setupLogBucket(c)
+	if bucket == nil {
+		logrus.Fatalln("pre-signed log uploads require an s3 or gcs log bucket")
+	}
+	return logs.NewUploads(s, bucket, c.String("logs-s3-prefix"), 24*time.Hour)
 }
 
 func setupQueue(c *cli.Context, s store.Store) queue.Queue {
-	return model.WithTaskStore(queue.New(), s)
+	if client := setupRedis(c); client != nil {
+		return redis.NewQueue(client, droneserver.Throttle)
+	}
+	return model.WithTaskStore(queue.NewLimited(droneserver.Throttle), s)
+}
+
+func setupPubsub(c *cli.Context) pubsub.Publisher {
+	if client := setupRedis(c); client != nil {
+		return redis.NewPubsub(client)
+	}
+	return pubsub.New()
+}
+
+func setupLogs(c *cli.Context) logging.Log {
+	if client := setupRedis(c); client != nil {
+		return redis.NewLogs(client)
+	}
+	return logging.New()
+}
+
+// helper function to create the redis client shared by the queue, pubsub
+// and logs, or nil if redis is not configured.
+func setupRedis(c *cli.Context) *redis.Client {
+	rawurl := c.String("redis-url")
+	if rawurl == "" {
+		return nil
+	}
+	client, err := redis.New(rawurl)
+	if err != nil {
+		logrus.Fatalf("redis: cannot create client. %s", err)
+	}
+	return client
 }
 
 func setupSecretService(c *cli.Context, s store.Store) model.SecretService {
+	switch {
+	case c.String("vault-addr") != "":
+		return setupVault(c)
+	case c.Bool("aws-secrets-manager"):
+		return secrets.NewSecretsManager(
+			aws.NewSecretsManager(aws.NewEnv()),
+			c.String("aws-secrets-prefix"),
+			c.Duration("aws-secrets-cache-ttl"),
+		)
+	case c.String("kms-key-id") != "":
+		return secrets.New(secrets.NewEnvelope(s,
+			aws.NewKMS(aws.NewEnv(), c.String("kms-key-id")),
+		))
+	}
 	return secrets.New(s)
 }
 
+func setupOrgSecretStore(c *cli.Context, s store.Store) model.OrgSecretStore {
+	if c.String("kms-key-id") != "" {
+		return secrets.NewOrgEnvelope(s,
+			aws.NewKMS(aws.NewEnv(), c.String("kms-key-id")),
+		)
+	}
+	return s
+}
+
+// helper function to create the vault secret service, authenticating with
+// either a token or an approle.
+func setupVault(c *cli.Context) model.SecretService {
+	config := api.DefaultConfig()
+	config.Address = c.String("vault-addr")
+	client, err := api.NewClient(config)
+	if err != nil {
+		logrus.Fatalf("vault: cannot create client. %s", err)
+	}
+	if token := c.String("vault-token"); token != "" {
+		client.SetToken(token)
+		return secrets.NewVault(client,
+			c.String("vault-mount"),
+			c.String("vault-path"),
+			c.Duration("vault-cache-ttl"),
+		)
+	}
+	role := secrets.AppRole{
+		RoleID:   c.String("vault-role-id"),
+		SecretID: c.String("vault-secret-id"),
+	}
+	service, err := secrets.NewVaultAppRole(context.Background(), client, role,
+		c.String("vault-mount"),
+		c.String("vault-path"),
+		c.Duration("vault-cache-ttl"),
+	)
+	if err != nil {
+		logrus.Fatalf("vault: cannot authenticate approle. %s", err)
+	}
+	return service
+}
+
 func setupRegistryService(c *cli.Context, s store.Store) model.RegistryService {
 	return registry.New(s)
 }
 
-func setupPubsub(c *cli.Context)        {}
 func setupStream(c *cli.Command)        {}
 func setupGatingService(c *cli.Command) {}
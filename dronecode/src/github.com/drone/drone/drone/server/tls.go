@@ -0,0 +1,176 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+// certReloadInterval is the minimum interval between checks of the server
+// certificate files for changes.
+const certReloadInterval = 10 * time.Second
+
+// certReloader loads the server certificate and key from disk, and
+// reloads them when the files change, so that renewed certificates are
+// used without restarting the server.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+	checked time.Time
+}
+
+// newCertReloader returns a certificate reloader for the certificate and
+// key files, returning an error if the files cannot be loaded.
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate returns the current certificate, reloading the
+// certificate if the files changed since it was last loaded. If the
+// changed files cannot be loaded, the previous certificate is used.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.Lock()
+	defer r.Unlock()
+	if time.Since(r.checked) > certReloadInterval {
+		r.checked = time.Now()
+		if modTime := r.lastModified(); modTime.After(r.modTime) {
+			if err := r.load(); err != nil {
+				logrus.Errorf("tls: cannot reload certificate %s. %s", r.certFile, err)
+			} else {
+				logrus.Infof("tls: reloaded certificate %s", r.certFile)
+			}
+		}
+	}
+	return r.cert, nil
+}
+
+// helper function loads the certificate and key. The lock must be held.
+func (r *certReloader) load() error {
+	modTime := r.lastModified()
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	r.cert = &cert
+	r.modTime = modTime
+	return nil
+}
+
+// helper function returns the latest modification time of the
+// certificate and key files.
+func (r *certReloader) lastModified() time.Time {
+	var modTime time.Time
+	for _, path := range []string{r.certFile, r.keyFile} {
+		if info, err := os.Stat(path); err == nil && info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+	return modTime
+}
+
+// setupAutocert returns the lets encrypt certificate manager for the server
+// host and the additional hostnames. The acme directory defaults to lets
+// encrypt, and can be configured to use an internal certificate authority.
+func setupAutocert(c *cli.Context) (*autocert.Manager, error) {
+	address, err := url.Parse(c.String("server-host"))
+	if err != nil {
+		return nil, err
+	}
+	hosts := append([]string{hostname(address.Host)}, c.StringSlice("lets-encrypt-host")...)
+
+	client := &acme.Client{
+		DirectoryURL: c.String("lets-encrypt-directory"),
+	}
+	if path := c.String("lets-encrypt-ca-cert"); path != "" {
+		pem, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("Error: no certificates found in %s", path)
+		}
+		client.HTTPClient = &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{RootCAs: pool},
+			},
+		}
+	}
+
+	dir := c.String("lets-encrypt-cache")
+	if dir == "" {
+		dir = autocertDir()
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(hosts...),
+		Cache:      autocert.DirCache(dir),
+		Client:     client,
+		Email:      c.String("lets-encrypt-email"),
+	}, nil
+}
+
+// redirectHandler returns an http handler that redirects requests to the
+// https address of the server.
+func redirectHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := url.URL{
+			Scheme:   "https",
+			Host:     hostname(r.Host),
+			Path:     r.URL.Path,
+			RawQuery: r.URL.RawQuery,
+		}
+		http.Redirect(w, r, target.String(), http.StatusMovedPermanently)
+	})
+}
+
+// helper function returns the host without the port.
+func hostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+// helper function returns the default certificate cache directory, which
+// is also used by the autocert package.
+func autocertDir() string {
+	if xdg := os.Getenv("XDG_CACHE_HOME"); xdg != "" {
+		return filepath.Join(xdg, "golang-autocert")
+	}
+	home := os.Getenv("HOME")
+	if home == "" {
+		home = "/"
+	}
+	return filepath.Join(home, ".cache", "golang-autocert")
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"flag"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+func TestCertReloader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "drone-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile := filepath.Join(dir, "server.crt")
+	keyFile := filepath.Join(dir, "server.key")
+
+	writeTestCert(t, certFile, keyFile, "a.example.com")
+	r, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := testCertName(t, r); got != "a.example.com" {
+		t.Errorf("Want certificate a.example.com, got %s", got)
+	}
+
+	// the renewed certificate is loaded once the files change.
+	writeTestCert(t, certFile, keyFile, "b.example.com")
+	touch(t, certFile, keyFile)
+	r.checked = time.Time{}
+	if got := testCertName(t, r); got != "b.example.com" {
+		t.Errorf("Want reloaded certificate b.example.com, got %s", got)
+	}
+
+	// the previous certificate is used if the changed files are invalid.
+	ioutil.WriteFile(certFile, []byte("invalid"), 0600)
+	touch(t, certFile)
+	r.checked = time.Time{}
+	if got := testCertName(t, r); got != "b.example.com" {
+		t.Errorf("Want previous certificate b.example.com, got %s", got)
+	}
+
+	if _, err := newCertReloader(certFile, keyFile); err == nil {
+		t.Errorf("Want error loading an invalid certificate")
+	}
+}
+
+func TestSetupAutocert(t *testing.T) {
+	dir, err := ioutil.TempDir("", "drone-autocert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	set := flag.NewFlagSet("test", 0)
+	set.String("server-host", "https://ci.example.com:8443", "")
+	set.String("lets-encrypt-cache", dir, "")
+	set.String("lets-encrypt-directory", "https://acme.example.com/directory", "")
+	set.Var(&cli.StringSlice{"drone.example.com"}, "lets-encrypt-host", "")
+
+	m, err := setupAutocert(cli.NewContext(nil, set, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Client.DirectoryURL != "https://acme.example.com/directory" {
+		t.Errorf("Want custom acme directory, got %s", m.Client.DirectoryURL)
+	}
+	for _, host := range []string{"ci.example.com", "drone.example.com"} {
+		if err := m.HostPolicy(context.Background(), host); err != nil {
+			t.Errorf("Want certificates for host %s, got %s", host, err)
+		}
+	}
+	if err := m.HostPolicy(context.Background(), "example.com"); err == nil {
+		t.Errorf("Want error requesting a certificate for another host")
+	}
+}
+
+func TestRedirectHandler(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://ci.example.com:80/octocat/hello-world?page=2", nil)
+	w := httptest.NewRecorder()
+	redirectHandler().ServeHTTP(w, req)
+	if w.Code != http.StatusMovedPermanently {
+		t.Errorf("Want status %d, got %d", http.StatusMovedPermanently, w.Code)
+	}
+	if got, want := w.Header().Get("Location"), "https://ci.example.com/octocat/hello-world?page=2"; got != want {
+		t.Errorf("Want redirect to %s, got %s", want, got)
+	}
+}
+
+// helper function returns the common name of the current certificate.
+func testCertName(t *testing.T, r *certReloader) string {
+	cert, err := r.GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+// helper function writes a self-signed certificate and key.
+func writeTestCert(t *testing.T, certFile, keyFile, name string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+}
+
+// helper function advances the modification time of the files.
+func touch(t *testing.T, paths ...string) {
+	later := time.Now().Add(time.Minute)
+	for _, path := range paths {
+		if err := os.Chtimes(path, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
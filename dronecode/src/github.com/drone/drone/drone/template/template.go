@@ -0,0 +1,16 @@
+package template
+
+import "github.com/urfave/cli"
+
+// Command exports the template command set.
+var Command = cli.Command{
+	Name:  "template",
+	Usage: "manage pipeline templates",
+	Subcommands: []cli.Command{
+		templateCreateCmd,
+		templateDeleteCmd,
+		templateUpdateCmd,
+		templateInfoCmd,
+		templateListCmd,
+	},
+}
@@ -0,0 +1,63 @@
+package template
+
+import (
+	"io/ioutil"
+	"strings"
+
+	"github.com/drone/drone/drone/internal"
+	"github.com/drone/drone/model"
+
+	"github.com/urfave/cli"
+)
+
+var templateCreateCmd = cli.Command{
+	Name:      "add",
+	Usage:     "adds a pipeline template",
+	ArgsUsage: "[namespace]",
+	Action:    templateCreate,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "namespace",
+			Usage: "template namespace (e.g. octocat)",
+		},
+		cli.StringFlag{
+			Name:  "name",
+			Usage: "template name (e.g. golang.yaml)",
+		},
+		cli.StringFlag{
+			Name:  "data",
+			Usage: "template data, or @ followed by the template file path",
+		},
+	},
+}
+
+func templateCreate(c *cli.Context) error {
+	namespace := c.String("namespace")
+	if namespace == "" {
+		namespace = c.Args().First()
+	}
+	client, err := internal.NewClient(c)
+	if err != nil {
+		return err
+	}
+	data, err := readData(c.String("data"))
+	if err != nil {
+		return err
+	}
+	template := &model.Template{
+		Name: c.String("name"),
+		Data: data,
+	}
+	_, err = client.TemplateCreate(namespace, template)
+	return err
+}
+
+// helper function reads the template data from the file, if the data is
+// prefixed with @.
+func readData(data string) (string, error) {
+	if !strings.HasPrefix(data, "@") {
+		return data, nil
+	}
+	out, err := ioutil.ReadFile(strings.TrimPrefix(data, "@"))
+	return string(out), err
+}
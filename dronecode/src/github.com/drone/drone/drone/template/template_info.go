@@ -0,0 +1,60 @@
+package template
+
+import (
+	"os"
+	"text/template"
+
+	"github.com/drone/drone/drone/internal"
+
+	"github.com/urfave/cli"
+)
+
+var templateInfoCmd = cli.Command{
+	Name:      "info",
+	Usage:     "display pipeline template info",
+	ArgsUsage: "[namespace]",
+	Action:    templateInfo,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "namespace",
+			Usage: "template namespace (e.g. octocat)",
+		},
+		cli.StringFlag{
+			Name:  "name",
+			Usage: "template name (e.g. golang.yaml)",
+		},
+		cli.StringFlag{
+			Name:   "format",
+			Usage:  "format output",
+			Value:  tmplTemplateInfo,
+			Hidden: true,
+		},
+	},
+}
+
+func templateInfo(c *cli.Context) error {
+	var (
+		namespace = c.String("namespace")
+		format    = c.String("format") + "\n"
+	)
+	if namespace == "" {
+		namespace = c.Args().First()
+	}
+	client, err := internal.NewClient(c)
+	if err != nil {
+		return err
+	}
+	item, err := client.Template(namespace, c.String("name"))
+	if err != nil {
+		return err
+	}
+	tmpl, err := template.New("_").Parse(format)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(os.Stdout, item)
+}
+
+// template for pipeline template information
+var tmplTemplateInfo = tmplTemplateList + `Data:
+{{ .Data }}`
@@ -0,0 +1,60 @@
+package template
+
+import (
+	"os"
+	"text/template"
+
+	"github.com/drone/drone/drone/internal"
+
+	"github.com/urfave/cli"
+)
+
+var templateListCmd = cli.Command{
+	Name:      "ls",
+	Usage:     "list pipeline templates",
+	ArgsUsage: "[namespace]",
+	Action:    templateList,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "namespace",
+			Usage: "template namespace (e.g. octocat)",
+		},
+		cli.StringFlag{
+			Name:   "format",
+			Usage:  "format output",
+			Value:  tmplTemplateList,
+			Hidden: true,
+		},
+	},
+}
+
+func templateList(c *cli.Context) error {
+	var (
+		namespace = c.String("namespace")
+		format    = c.String("format") + "\n"
+	)
+	if namespace == "" {
+		namespace = c.Args().First()
+	}
+	client, err := internal.NewClient(c)
+	if err != nil {
+		return err
+	}
+	list, err := client.TemplateList(namespace)
+	if err != nil {
+		return err
+	}
+	tmpl, err := template.New("_").Parse(format)
+	if err != nil {
+		return err
+	}
+	for _, item := range list {
+		tmpl.Execute(os.Stdout, item)
+	}
+	return nil
+}
+
+// template for pipeline template list information
+var tmplTemplateList = "\x1b[33m{{ .Name }} \x1b[0m" + `
+Namespace: {{ .Namespace }}
+`
@@ -0,0 +1,36 @@
+package template
+
+import (
+	"github.com/drone/drone/drone/internal"
+
+	"github.com/urfave/cli"
+)
+
+var templateDeleteCmd = cli.Command{
+	Name:      "rm",
+	Usage:     "remove a pipeline template",
+	ArgsUsage: "[namespace]",
+	Action:    templateDelete,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "namespace",
+			Usage: "template namespace (e.g. octocat)",
+		},
+		cli.StringFlag{
+			Name:  "name",
+			Usage: "template name (e.g. golang.yaml)",
+		},
+	},
+}
+
+func templateDelete(c *cli.Context) error {
+	namespace := c.String("namespace")
+	if namespace == "" {
+		namespace = c.Args().First()
+	}
+	client, err := internal.NewClient(c)
+	if err != nil {
+		return err
+	}
+	return client.TemplateDelete(namespace, c.String("name"))
+}
@@ -0,0 +1,50 @@
+package template
+
+import (
+	"github.com/drone/drone/drone/internal"
+	"github.com/drone/drone/model"
+
+	"github.com/urfave/cli"
+)
+
+var templateUpdateCmd = cli.Command{
+	Name:      "update",
+	Usage:     "update a pipeline template",
+	ArgsUsage: "[namespace]",
+	Action:    templateUpdate,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "namespace",
+			Usage: "template namespace (e.g. octocat)",
+		},
+		cli.StringFlag{
+			Name:  "name",
+			Usage: "template name (e.g. golang.yaml)",
+		},
+		cli.StringFlag{
+			Name:  "data",
+			Usage: "template data, or @ followed by the template file path",
+		},
+	},
+}
+
+func templateUpdate(c *cli.Context) error {
+	namespace := c.String("namespace")
+	if namespace == "" {
+		namespace = c.Args().First()
+	}
+	client, err := internal.NewClient(c)
+	if err != nil {
+		return err
+	}
+	data, err := readData(c.String("data"))
+	if err != nil {
+		return err
+	}
+	template := &model.Template{
+		Name: c.String("name"),
+		Data: data,
+	}
+	_, err = client.TemplateUpdate(namespace, template)
+	return err
+}
@@ -0,0 +1,14 @@
+package token
+
+import "github.com/urfave/cli"
+
+// Command exports the token command set.
+var Command = cli.Command{
+	Name:  "token",
+	Usage: "manage personal access tokens",
+	Subcommands: []cli.Command{
+		tokenListCmd,
+		tokenCreateCmd,
+		tokenRemoveCmd,
+	},
+}
@@ -0,0 +1,55 @@
+package token
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/urfave/cli"
+
+	"github.com/drone/drone/drone/internal"
+	"github.com/drone/drone/model"
+)
+
+var tokenCreateCmd = cli.Command{
+	Name:      "create",
+	Usage:     "create a personal access token",
+	ArgsUsage: "<name>",
+	Action:    tokenCreate,
+	Flags: []cli.Flag{
+		cli.StringSliceFlag{
+			Name:  "scope",
+			Usage: "token scope (read, build:restart, secrets:write, admin)",
+			Value: &cli.StringSlice{model.ScopeRead},
+		},
+		cli.DurationFlag{
+			Name:  "expires",
+			Usage: "duration until the token expires",
+		},
+	},
+}
+
+func tokenCreate(c *cli.Context) error {
+	name := c.Args().First()
+	if name == "" {
+		return fmt.Errorf("Missing or invalid token name")
+	}
+
+	client, err := internal.NewClient(c)
+	if err != nil {
+		return err
+	}
+
+	in := &model.AccessToken{
+		Name:   name,
+		Scopes: c.StringSlice("scope"),
+	}
+	if expires := c.Duration("expires"); expires != 0 {
+		in.Expires = time.Now().Add(expires).Unix()
+	}
+	token, err := client.AccessTokenCreate(in)
+	if err != nil {
+		return err
+	}
+	fmt.Println(token.Token)
+	return nil
+}
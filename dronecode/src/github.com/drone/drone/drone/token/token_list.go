@@ -0,0 +1,51 @@
+package token
+
+import (
+	"os"
+	"text/template"
+
+	"github.com/urfave/cli"
+
+	"github.com/drone/drone/drone/internal"
+)
+
+var tokenListCmd = cli.Command{
+	Name:   "ls",
+	Usage:  "list personal access tokens",
+	Action: tokenList,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "format",
+			Usage: "format output",
+			Value: tmplTokenList,
+		},
+	},
+}
+
+func tokenList(c *cli.Context) error {
+	client, err := internal.NewClient(c)
+	if err != nil {
+		return err
+	}
+
+	list, err := client.AccessTokenList()
+	if err != nil {
+		return err
+	}
+
+	tmpl, err := template.New("_").Parse(c.String("format") + "\n")
+	if err != nil {
+		return err
+	}
+	for _, token := range list {
+		tmpl.Execute(os.Stdout, token)
+	}
+	return nil
+}
+
+// template for token list information
+var tmplTokenList = "\x1b[33m{{ .Name }} \x1b[0m" + `
+ID: {{ .ID }}
+Scopes: {{ range .Scopes }}{{ . }} {{ end }}
+Expires: {{ if .Expires }}{{ .Expires }}{{ else }}never{{ end }}
+`
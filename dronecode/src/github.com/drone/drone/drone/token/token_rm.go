@@ -0,0 +1,35 @@
+package token
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/urfave/cli"
+
+	"github.com/drone/drone/drone/internal"
+)
+
+var tokenRemoveCmd = cli.Command{
+	Name:      "rm",
+	Usage:     "revoke a personal access token",
+	ArgsUsage: "<id>",
+	Action:    tokenRemove,
+}
+
+func tokenRemove(c *cli.Context) error {
+	id, err := strconv.ParseInt(c.Args().First(), 10, 64)
+	if err != nil {
+		return fmt.Errorf("Missing or invalid token id")
+	}
+
+	client, err := internal.NewClient(c)
+	if err != nil {
+		return err
+	}
+
+	if err := client.AccessTokenDelete(id); err != nil {
+		return err
+	}
+	fmt.Printf("Successfully revoked token %d\n", id)
+	return nil
+}
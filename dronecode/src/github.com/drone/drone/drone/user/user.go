@@ -11,5 +11,8 @@ var Command = cli.Command{
 		userInfoCmd,
 		userAddCmd,
 		userRemoveCmd,
+		userPermCmd,
+		userTokenCmd,
+		userLogoutCmd,
 	},
 }
@@ -13,6 +13,12 @@ var userAddCmd = cli.Command{
 	Name:   "add",
 	Usage:  "adds a user",
 	Action: userAdd,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "machine",
+			Usage: "add a machine user that authenticates with access tokens",
+		},
+	},
 }
 
 func userAdd(c *cli.Context) error {
@@ -23,7 +29,10 @@ func userAdd(c *cli.Context) error {
 		return err
 	}
 
-	user, err := client.UserPost(&model.User{Login: login})
+	user, err := client.UserPost(&model.User{
+		Login:   login,
+		Machine: c.Bool("machine"),
+	})
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,30 @@
+package user
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli"
+
+	"github.com/drone/drone/drone/internal"
+)
+
+var userLogoutCmd = cli.Command{
+	Name:   "logout",
+	Usage:  "revoke the sessions of a user",
+	Action: userLogout,
+}
+
+func userLogout(c *cli.Context) error {
+	login := c.Args().First()
+
+	client, err := internal.NewClient(c)
+	if err != nil {
+		return err
+	}
+
+	if err := client.UserSessionRevoke(login); err != nil {
+		return err
+	}
+	fmt.Printf("Successfully revoked the sessions of user %s\n", login)
+	return nil
+}
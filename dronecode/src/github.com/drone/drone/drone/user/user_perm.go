@@ -0,0 +1,67 @@
+package user
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli"
+
+	"github.com/drone/drone/drone/internal"
+	"github.com/drone/drone/model"
+)
+
+var userPermCmd = cli.Command{
+	Name:      "perm",
+	Usage:     "grant a machine user permissions to a repository",
+	ArgsUsage: "<login> <repo/name>",
+	Action:    userPerm,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "pull",
+			Usage: "grant pull permission",
+		},
+		cli.BoolFlag{
+			Name:  "push",
+			Usage: "grant push permission",
+		},
+		cli.BoolFlag{
+			Name:  "admin",
+			Usage: "grant admin permission",
+		},
+		cli.BoolFlag{
+			Name:  "revoke",
+			Usage: "revoke all permissions",
+		},
+	},
+}
+
+func userPerm(c *cli.Context) error {
+	login := c.Args().First()
+	owner, name, err := internal.ParseRepo(c.Args().Get(1))
+	if err != nil {
+		return err
+	}
+
+	client, err := internal.NewClient(c)
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("revoke") {
+		if err := client.UserPermDel(login, owner, name); err != nil {
+			return err
+		}
+		fmt.Printf("Successfully revoked %s permissions to %s/%s\n", login, owner, name)
+		return nil
+	}
+
+	perm := &model.RepoPerm{
+		Pull:  c.Bool("pull"),
+		Push:  c.Bool("push"),
+		Admin: c.Bool("admin"),
+	}
+	if _, err := client.UserPermPost(login, owner, name, perm); err != nil {
+		return err
+	}
+	fmt.Printf("Successfully granted %s permissions to %s/%s\n", login, owner, name)
+	return nil
+}
@@ -0,0 +1,56 @@
+package user
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/urfave/cli"
+
+	"github.com/drone/drone/drone/internal"
+	"github.com/drone/drone/model"
+)
+
+var userTokenCmd = cli.Command{
+	Name:      "token",
+	Usage:     "create an access token for a machine user",
+	ArgsUsage: "<login> <name>",
+	Action:    userToken,
+	Flags: []cli.Flag{
+		cli.StringSliceFlag{
+			Name:  "scope",
+			Usage: "token scope (read, build:restart, secrets:write, admin)",
+			Value: &cli.StringSlice{model.ScopeRead},
+		},
+		cli.DurationFlag{
+			Name:  "expires",
+			Usage: "duration until the token expires",
+		},
+	},
+}
+
+func userToken(c *cli.Context) error {
+	login := c.Args().First()
+	name := c.Args().Get(1)
+	if name == "" {
+		return fmt.Errorf("Missing or invalid token name")
+	}
+
+	client, err := internal.NewClient(c)
+	if err != nil {
+		return err
+	}
+
+	in := &model.AccessToken{
+		Name:   name,
+		Scopes: c.StringSlice("scope"),
+	}
+	if expires := c.Duration("expires"); expires != 0 {
+		in.Expires = time.Now().Add(expires).Unix()
+	}
+	token, err := client.UserTokenCreate(login, in)
+	if err != nil {
+		return err
+	}
+	fmt.Println(token.Token)
+	return nil
+}
@@ -0,0 +1,56 @@
+package model
+
+// Audit actions recorded in the audit log.
+const (
+	AuditRepoActivate   = "repo:activate"
+	AuditRepoDeactivate = "repo:deactivate"
+	AuditRepoUpdate     = "repo:update"
+	AuditRepoRename     = "repo:rename"
+	AuditSecretCreate   = "secret:create"
+	AuditSecretUpdate   = "secret:update"
+	AuditSecretDelete   = "secret:delete"
+	AuditTemplateCreate = "template:create"
+	AuditTemplateUpdate = "template:update"
+	AuditTemplateDelete = "template:delete"
+	AuditBuildApprove   = "build:approve"
+	AuditBuildDecline   = "build:decline"
+	AuditBuildRestart   = "build:restart"
+	AuditBuildKill      = "build:kill"
+	AuditBuildPromote   = "build:promote"
+	AuditBuildRollback  = "build:rollback"
+	AuditDeployDenied   = "deploy:denied"
+	AuditUserCreate     = "user:create"
+	AuditUserUpdate     = "user:update"
+	AuditUserDelete     = "user:delete"
+	AuditTokenCreate    = "token:create"
+	AuditTokenDelete    = "token:delete"
+	AuditSessionRevoke  = "session:revoke"
+)
+
+// AuditStore persists the audit log to storage. The audit log is append
+// only, and entries cannot be updated or deleted.
+type AuditStore interface {
+	AuditCreate(*Audit) error
+	AuditList(*AuditFilter) ([]*Audit, error)
+}
+
+// Audit represents an administrative or security relevant action recorded
+// in the audit log.
+type Audit struct {
+	ID      int64  `json:"id"               meddler:"audit_id,pk"`
+	Action  string `json:"action"           meddler:"audit_action"`
+	User    string `json:"user"             meddler:"audit_user"`
+	Repo    string `json:"repo,omitempty"   meddler:"audit_repo"`
+	Target  string `json:"target,omitempty" meddler:"audit_target"`
+	Created int64  `json:"created_at"       meddler:"audit_created"`
+}
+
+// AuditFilter filters the audit log by user login, repository full name
+// and time. Empty fields match all entries.
+type AuditFilter struct {
+	User   string
+	Repo   string
+	After  int64
+	Before int64
+	Limit  int
+}
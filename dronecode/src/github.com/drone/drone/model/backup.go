@@ -0,0 +1,169 @@
+package model
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+)
+
+// BackupVersion is the version of the backup archive format.
+const BackupVersion = 1
+
+var (
+	errBackupKeyInvalid   = errors.New("Invalid Backup Key")
+	errBackupValueInvalid = errors.New("Invalid Encrypted Backup Value")
+)
+
+// backupSecrets defines the columns that are encrypted when the backup is
+// sealed.
+var backupSecrets = map[string]bool{
+	"user_token":        true,
+	"user_secret":       true,
+	"user_hash":         true,
+	"token_hash":        true,
+	"repo_hash":         true,
+	"secret_value":      true,
+	"org_secret_value":  true,
+	"registry_password": true,
+	"registry_token":    true,
+}
+
+// backupCheck is the value encrypted to verify the backup key.
+const backupCheck = "drone"
+
+// backupIterations is the number of pbkdf2 iterations used to derive the
+// encryption key from the backup key.
+const backupIterations = 100000
+
+// BackupRow represents a row of a database table, mapping the column names
+// to the column values.
+type BackupRow map[string]interface{}
+
+// Backup represents a portable archive of the database, independent of the
+// database driver, that can be restored into an empty database.
+type Backup struct {
+	Version int                    `json:"version"`
+	Created int64                  `json:"created"`
+	Sealed  bool                   `json:"sealed"`
+	Salt    []byte                 `json:"salt,omitempty"`
+	Check   string                 `json:"check,omitempty"`
+	Tables  map[string][]BackupRow `json:"tables"`
+}
+
+// Seal encrypts the secret values, registry credentials and user tokens of
+// the backup with the backup key.
+func (b *Backup) Seal(key string) error {
+	if len(key) == 0 {
+		return errBackupKeyInvalid
+	}
+	b.Salt = make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, b.Salt); err != nil {
+		return err
+	}
+	gcm, err := backupCipher(key, b.Salt)
+	if err != nil {
+		return err
+	}
+	seal := func(value string) (string, error) {
+		nonce := make([]byte, gcm.NonceSize())
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return "", err
+		}
+		sealed := gcm.Seal(nonce, nonce, []byte(value), nil)
+		return base64.StdEncoding.EncodeToString(sealed), nil
+	}
+	// the check value is used to verify the backup key when the backup
+	// is opened, even if the backup does not contain any secrets.
+	if b.Check, err = seal(backupCheck); err != nil {
+		return err
+	}
+	err = b.each(seal)
+	b.Sealed = err == nil
+	return err
+}
+
+// Open decrypts the secret values, registry credentials and user tokens of
+// the sealed backup with the backup key.
+func (b *Backup) Open(key string) error {
+	if !b.Sealed {
+		return nil
+	}
+	gcm, err := backupCipher(key, b.Salt)
+	if err != nil {
+		return err
+	}
+	open := func(value string) (string, error) {
+		sealed, err := base64.StdEncoding.DecodeString(value)
+		if err != nil || len(sealed) < gcm.NonceSize() {
+			return "", errBackupValueInvalid
+		}
+		nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+		plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+		if err != nil {
+			return "", errBackupKeyInvalid
+		}
+		return string(plaintext), nil
+	}
+	if check, err := open(b.Check); err != nil || check != backupCheck {
+		return errBackupKeyInvalid
+	}
+	err = b.each(open)
+	b.Sealed = err != nil
+	return err
+}
+
+// helper function replaces the non-empty values of the secret columns.
+func (b *Backup) each(fn func(string) (string, error)) error {
+	for _, rows := range b.Tables {
+		for _, row := range rows {
+			for column, value := range row {
+				s, ok := value.(string)
+				if !ok || s == "" || !backupSecrets[column] {
+					continue
+				}
+				s, err := fn(s)
+				if err != nil {
+					return err
+				}
+				row[column] = s
+			}
+		}
+	}
+	return nil
+}
+
+// helper function returns the cipher used to encrypt the backup, using a
+// key derived from the backup key and salt.
+func backupCipher(key string, salt []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(pbkdf2([]byte(key), salt, backupIterations))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// helper function derives a 32 byte key from the password and salt using
+// pbkdf2 with hmac-sha256, as defined in RFC 2898.
+func pbkdf2(password, salt []byte, iter int) []byte {
+	prf := hmac.New(sha256.New, password)
+	prf.Write(salt)
+	prf.Write([]byte{0, 0, 0, 1})
+	u := prf.Sum(nil)
+
+	key := make([]byte, len(u))
+	copy(key, u)
+	for i := 1; i < iter; i++ {
+		prf.Reset()
+		prf.Write(u)
+		u = prf.Sum(u[:0])
+		for j := range key {
+			key[j] ^= u[j]
+		}
+	}
+	return key
+}
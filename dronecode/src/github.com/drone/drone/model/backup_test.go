@@ -0,0 +1,54 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/franela/goblin"
+)
+
+func TestBackup(t *testing.T) {
+
+	g := goblin.Goblin(t)
+	g.Describe("Backup", func() {
+
+		newBackup := func() *Backup {
+			return &Backup{
+				Version: BackupVersion,
+				Tables: map[string][]BackupRow{
+					"secrets": {
+						{"secret_name": "password", "secret_value": "correct-horse"},
+					},
+				},
+			}
+		}
+
+		g.It("should seal secret values", func() {
+			b := newBackup()
+			g.Assert(b.Seal("foo") == nil).IsTrue()
+			g.Assert(b.Sealed).IsTrue()
+			g.Assert(b.Tables["secrets"][0]["secret_name"]).Equal("password")
+			g.Assert(b.Tables["secrets"][0]["secret_value"] == "correct-horse").IsFalse()
+		})
+		g.It("should open secret values", func() {
+			b := newBackup()
+			b.Seal("foo")
+			g.Assert(b.Open("foo") == nil).IsTrue()
+			g.Assert(b.Sealed).IsFalse()
+			g.Assert(b.Tables["secrets"][0]["secret_value"]).Equal("correct-horse")
+		})
+		g.It("should not open with the wrong key", func() {
+			b := newBackup()
+			b.Seal("foo")
+			g.Assert(b.Open("bar") == nil).IsFalse()
+			g.Assert(b.Sealed).IsTrue()
+		})
+		g.It("should not open an empty backup with the wrong key", func() {
+			b := &Backup{Version: BackupVersion}
+			b.Seal("foo")
+			g.Assert(b.Open("bar") == nil).IsFalse()
+		})
+		g.It("should require a key", func() {
+			g.Assert(newBackup().Seal("") == nil).IsFalse()
+		})
+	})
+}
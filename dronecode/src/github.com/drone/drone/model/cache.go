@@ -0,0 +1,21 @@
+package model
+
+import "io"
+
+// CacheStore persists the pipeline cache archives to storage.
+type CacheStore interface {
+	CacheFind(*Repo, string) (*Cache, error)
+	CacheFindPrefix(*Repo, string) (*Cache, error)
+	CacheRead(*Cache) (io.ReadCloser, error)
+	CacheCreate(*Cache, io.Reader) error
+}
+
+// Cache represents the pipeline cache archive of a repository, which is
+// saved once for each cache key.
+type Cache struct {
+	ID      int64  `json:"id"      meddler:"cache_id,pk"`
+	RepoID  int64  `json:"-"       meddler:"cache_repo_id"`
+	Key     string `json:"key"     meddler:"cache_key"`
+	Size    int64  `json:"size"    meddler:"cache_size"`
+	Created int64  `json:"created" meddler:"cache_created"`
+}
@@ -2,13 +2,14 @@ package model
 
 import (
 	"encoding/xml"
+	"fmt"
 	"strconv"
 	"time"
 )
 
 type CCProjects struct {
-	XMLName xml.Name   `xml:"Projects"`
-	Project *CCProject `xml:"Project"`
+	XMLName  xml.Name     `xml:"Projects"`
+	Projects []*CCProject `xml:"Project"`
 }
 
 type CCProject struct {
@@ -19,11 +20,42 @@ type CCProject struct {
 	LastBuildLabel  string   `xml:"lastBuildLabel,attr"`
 	LastBuildTime   string   `xml:"lastBuildTime,attr"`
 	WebURL          string   `xml:"webUrl,attr"`
+
+	// LastBuildDuration is the duration of the last build in seconds. It is
+	// not part of the cctray format, and is ignored by most clients.
+	LastBuildDuration string `xml:"lastBuildDuration,attr,omitempty"`
 }
 
+// NewCC returns the cctray projects of the repository build.
 func NewCC(r *Repo, b *Build, link string) *CCProjects {
+	return &CCProjects{Projects: []*CCProject{NewCCProject(r.FullName, b, link)}}
+}
+
+// NewCCFeed returns the cctray projects of the latest builds of the feed.
+// Repositories without builds are excluded. The link is the url of the
+// server.
+func NewCCFeed(feed []*Feed, link string) *CCProjects {
+	projects := &CCProjects{Projects: []*CCProject{}}
+	for _, f := range feed {
+		if f.Number == 0 {
+			continue
+		}
+		b := &Build{
+			Number:   f.Number,
+			Status:   f.Status,
+			Started:  f.Started,
+			Finished: f.Finished,
+		}
+		url := fmt.Sprintf("%s/%s/%d", link, f.FullName, f.Number)
+		projects.Projects = append(projects.Projects, NewCCProject(f.FullName, b, url))
+	}
+	return projects
+}
+
+// NewCCProject returns the cctray project of the build.
+func NewCCProject(name string, b *Build, link string) *CCProject {
 	proj := &CCProject{
-		Name:            r.FullName,
+		Name:            name,
 		WebURL:          link,
 		Activity:        "Building",
 		LastBuildStatus: "Unknown",
@@ -37,6 +69,9 @@ func NewCC(r *Repo, b *Build, link string) *CCProjects {
 		proj.Activity = "Sleeping"
 		proj.LastBuildTime = time.Unix(b.Started, 0).Format(time.RFC3339)
 		proj.LastBuildLabel = strconv.Itoa(b.Number)
+		if b.Finished > b.Started && b.Started != 0 {
+			proj.LastBuildDuration = strconv.FormatInt(b.Finished-b.Started, 10)
+		}
 	}
 
 	// ensure the last build Status accepts a valid
@@ -50,5 +85,5 @@ func NewCC(r *Repo, b *Build, link string) *CCProjects {
 		proj.LastBuildStatus = "Failure"
 	}
 
-	return &CCProjects{Project: proj}
+	return proj
 }
@@ -25,12 +25,28 @@ func TestCC(t *testing.T) {
 			}
 			cc := NewCC(r, b, "http://localhost/foo/bar/1")
 
-			g.Assert(cc.Project.Name).Equal("foo/bar")
-			g.Assert(cc.Project.Activity).Equal("Sleeping")
-			g.Assert(cc.Project.LastBuildStatus).Equal("Success")
-			g.Assert(cc.Project.LastBuildLabel).Equal("1")
-			g.Assert(cc.Project.LastBuildTime).Equal(now_fmt)
-			g.Assert(cc.Project.WebURL).Equal("http://localhost/foo/bar/1")
+			g.Assert(cc.Projects[0].Name).Equal("foo/bar")
+			g.Assert(cc.Projects[0].Activity).Equal("Sleeping")
+			g.Assert(cc.Projects[0].LastBuildStatus).Equal("Success")
+			g.Assert(cc.Projects[0].LastBuildLabel).Equal("1")
+			g.Assert(cc.Projects[0].LastBuildTime).Equal(now_fmt)
+			g.Assert(cc.Projects[0].WebURL).Equal("http://localhost/foo/bar/1")
+		})
+
+		g.It("Should create projects of a feed", func() {
+			feed := []*Feed{
+				{FullName: "foo/bar", Number: 2, Status: StatusSuccess, Started: 1257894000, Finished: 1257894060},
+				{FullName: "foo/baz"},
+				{FullName: "foo/qux", Number: 1, Status: StatusRunning, Started: 1257894000},
+			}
+			cc := NewCCFeed(feed, "http://localhost")
+			g.Assert(len(cc.Projects)).Equal(2)
+			g.Assert(cc.Projects[0].Name).Equal("foo/bar")
+			g.Assert(cc.Projects[0].WebURL).Equal("http://localhost/foo/bar/2")
+			g.Assert(cc.Projects[0].LastBuildDuration).Equal("60")
+			g.Assert(cc.Projects[1].Name).Equal("foo/qux")
+			g.Assert(cc.Projects[1].Activity).Equal("Building")
+			g.Assert(cc.Projects[1].LastBuildDuration).Equal("")
 		})
 
 		g.It("Should properly label exceptions", func() {
@@ -41,8 +57,8 @@ func TestCC(t *testing.T) {
 				Started: 1257894000,
 			}
 			cc := NewCC(r, b, "http://localhost/foo/bar/1")
-			g.Assert(cc.Project.LastBuildStatus).Equal("Exception")
-			g.Assert(cc.Project.Activity).Equal("Sleeping")
+			g.Assert(cc.Projects[0].LastBuildStatus).Equal("Exception")
+			g.Assert(cc.Projects[0].Activity).Equal("Sleeping")
 		})
 
 		g.It("Should properly label success", func() {
@@ -53,8 +69,8 @@ func TestCC(t *testing.T) {
 				Started: 1257894000,
 			}
 			cc := NewCC(r, b, "http://localhost/foo/bar/1")
-			g.Assert(cc.Project.LastBuildStatus).Equal("Success")
-			g.Assert(cc.Project.Activity).Equal("Sleeping")
+			g.Assert(cc.Projects[0].LastBuildStatus).Equal("Success")
+			g.Assert(cc.Projects[0].Activity).Equal("Sleeping")
 		})
 
 		g.It("Should properly label failure", func() {
@@ -65,8 +81,8 @@ func TestCC(t *testing.T) {
 				Started: 1257894000,
 			}
 			cc := NewCC(r, b, "http://localhost/foo/bar/1")
-			g.Assert(cc.Project.LastBuildStatus).Equal("Failure")
-			g.Assert(cc.Project.Activity).Equal("Sleeping")
+			g.Assert(cc.Projects[0].LastBuildStatus).Equal("Failure")
+			g.Assert(cc.Projects[0].Activity).Equal("Sleeping")
 		})
 
 		g.It("Should properly label running", func() {
@@ -77,9 +93,9 @@ func TestCC(t *testing.T) {
 				Started: 1257894000,
 			}
 			cc := NewCC(r, b, "http://localhost/foo/bar/1")
-			g.Assert(cc.Project.Activity).Equal("Building")
-			g.Assert(cc.Project.LastBuildStatus).Equal("Unknown")
-			g.Assert(cc.Project.LastBuildLabel).Equal("Unknown")
+			g.Assert(cc.Projects[0].Activity).Equal("Building")
+			g.Assert(cc.Projects[0].LastBuildStatus).Equal("Unknown")
+			g.Assert(cc.Projects[0].LastBuildLabel).Equal("Unknown")
 		})
 	})
 }
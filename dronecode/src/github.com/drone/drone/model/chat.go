@@ -0,0 +1,8 @@
+package model
+
+// ChatService defines a service for posting build notifications to a chat
+// service, such as Slack or Mattermost. The link is the url of the build.
+type ChatService interface {
+	ChatBuild(*Repo, *Build, string) error
+	ChatApproval(*Repo, *Build, string) error
+}
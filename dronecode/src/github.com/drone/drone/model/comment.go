@@ -0,0 +1,9 @@
+package model
+
+// Comment represents a pull request comment received by a hook.
+type Comment struct {
+	Repo   *Repo
+	Number int
+	Author string
+	Body   string
+}
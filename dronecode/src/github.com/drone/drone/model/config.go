@@ -8,6 +8,19 @@ type ConfigStore interface {
 	ConfigCreate(*Config) error
 }
 
+// ConfigService resolves the pipeline configuration for a build. The
+// configuration fetched from the repository may be replaced or modified,
+// for example by an external configuration service.
+type ConfigService interface {
+	ConfigResolve(repo *Repo, build *Build, data []byte) ([]byte, error)
+}
+
+// ValidateService validates the pipeline configuration before a build is
+// queued. An error is returned if the configuration is rejected.
+type ValidateService interface {
+	ConfigValidate(repo *Repo, build *Build, data []byte) error
+}
+
 // Config represents a pipeline configuration.
 type Config struct {
 	ID     int64  `json:"-"    meddler:"config_id,pk"`
@@ -1,22 +1,25 @@
 package model
 
 const (
-	EventPush   = "push"
-	EventPull   = "pull_request"
-	EventTag    = "tag"
-	EventDeploy = "deployment"
+	EventPush     = "push"
+	EventPull     = "pull_request"
+	EventTag      = "tag"
+	EventDeploy   = "deployment"
+	EventCron     = "cron"
+	EventRollback = "rollback"
 )
 
 const (
-	StatusSkipped  = "skipped"
-	StatusPending  = "pending"
-	StatusRunning  = "running"
-	StatusSuccess  = "success"
-	StatusFailure  = "failure"
-	StatusKilled   = "killed"
-	StatusError    = "error"
-	StatusBlocked  = "blocked"
-	StatusDeclined = "declined"
+	StatusSkipped        = "skipped"
+	StatusPending        = "pending"
+	StatusRunning        = "running"
+	StatusSuccess        = "success"
+	StatusFailure        = "failure"
+	StatusFailureAllowed = "failure_allowed"
+	StatusKilled         = "killed"
+	StatusError          = "error"
+	StatusBlocked        = "blocked"
+	StatusDeclined       = "declined"
 )
 
 const (
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"github.com/drone/drone/shared/cron"
+)
+
+var (
+	errCronNameInvalid     = errors.New("Invalid Cron Name")
+	errCronScheduleInvalid = errors.New("Invalid Cron Schedule")
+	errCronScheduleNever   = errors.New("Invalid Cron Schedule, never runs")
+)
+
+// CronStore persists cron job information to storage.
+type CronStore interface {
+	CronFind(*Repo, string) (*Cron, error)
+	CronList(*Repo) ([]*Cron, error)
+	CronListDue(int64) ([]*Cron, error)
+	CronCreate(*Cron) error
+	CronUpdate(*Cron) error
+	CronDelete(*Cron) error
+}
+
+// Cron represents a scheduled build.
+// swagger:model cron
+type Cron struct {
+	ID       int64  `json:"id"       meddler:"cron_id,pk"`
+	RepoID   int64  `json:"-"        meddler:"cron_repo_id"`
+	Name     string `json:"name"     meddler:"cron_name"`
+	Schedule string `json:"schedule" meddler:"cron_schedule"`
+	Branch   string `json:"branch"   meddler:"cron_branch"`
+	Next     int64  `json:"next"     meddler:"cron_next"`
+	Prev     int64  `json:"prev"     meddler:"cron_prev"`
+	Created  int64  `json:"created"  meddler:"cron_created"`
+}
+
+// Validate validates the required fields and formats.
+func (c *Cron) Validate() error {
+	if len(c.Name) == 0 {
+		return errCronNameInvalid
+	}
+	schedule, err := cron.Parse(c.Schedule)
+	if err != nil {
+		return errCronScheduleInvalid
+	}
+	if schedule.Next(time.Now()).IsZero() {
+		return errCronScheduleNever
+	}
+	return nil
+}
+
+// SetNext computes the next execution time of the cron job after
+// the given time. The next execution time is reset to zero if the
+// schedule never runs again, and the cron job is no longer due.
+func (c *Cron) SetNext(t time.Time) error {
+	c.Next = 0
+	schedule, err := cron.Parse(c.Schedule)
+	if err != nil {
+		return err
+	}
+	next := schedule.Next(t)
+	if next.IsZero() {
+		return errCronScheduleNever
+	}
+	c.Next = next.Unix()
+	return nil
+}
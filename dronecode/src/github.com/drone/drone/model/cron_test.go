@@ -0,0 +1,43 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronValidate(t *testing.T) {
+	tests := []struct {
+		cron Cron
+		err  error
+	}{
+		{Cron{Name: "nightly", Schedule: "@daily"}, nil},
+		{Cron{Schedule: "@daily"}, errCronNameInvalid},
+		{Cron{Name: "nightly", Schedule: "* *"}, errCronScheduleInvalid},
+		{Cron{Name: "never", Schedule: "0 0 30 2 *"}, errCronScheduleNever},
+	}
+	for _, test := range tests {
+		if err := test.cron.Validate(); err != test.err {
+			t.Errorf("Want cron %q error %v, got %v", test.cron.Schedule, test.err, err)
+		}
+	}
+}
+
+func TestCronSetNext(t *testing.T) {
+	now := time.Date(2017, 1, 1, 12, 30, 0, 0, time.UTC)
+
+	cron := &Cron{Schedule: "@daily"}
+	if err := cron.SetNext(now); err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2017, 1, 2, 0, 0, 0, 0, time.UTC).Unix(); cron.Next != want {
+		t.Errorf("Want next execution %d, got %d", want, cron.Next)
+	}
+
+	cron.Schedule = "0 0 30 2 *"
+	if err := cron.SetNext(now); err != errCronScheduleNever {
+		t.Errorf("Want error for a schedule that never runs, got %v", err)
+	}
+	if cron.Next != 0 {
+		t.Errorf("Want next execution reset, got %d", cron.Next)
+	}
+}
@@ -0,0 +1,68 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+var errDeployRuleEnvironmentInvalid = errors.New("Invalid Deploy Rule Environment")
+
+// DeployRule restricts who may deploy to the environments that match the
+// environment pattern, and which branches may be deployed. An empty list
+// of users or branches does not restrict the deployments.
+type DeployRule struct {
+	Environment string   `json:"environment"`
+	Users       []string `json:"users,omitempty"`
+	Branches    []string `json:"branches,omitempty"`
+}
+
+// Match returns true if the environment matches the rule.
+func (r *DeployRule) Match(env string) bool {
+	match, _ := filepath.Match(r.Environment, env)
+	return match
+}
+
+// Allow returns an error if the user may not deploy the branch.
+func (r *DeployRule) Allow(login, branch string) error {
+	if len(r.Users) != 0 && !matchAny(r.Users, login) {
+		return fmt.Errorf("User %s may not deploy to %s", login, r.Environment)
+	}
+	if len(r.Branches) != 0 && !matchAny(r.Branches, branch) {
+		return fmt.Errorf("Branch %s may not be deployed to %s", branch, r.Environment)
+	}
+	return nil
+}
+
+// Validate validates the required fields and formats.
+func (r *DeployRule) Validate() error {
+	if r.Environment == "" {
+		return errDeployRuleEnvironmentInvalid
+	}
+	_, err := filepath.Match(r.Environment, "")
+	return err
+}
+
+// CheckDeploy returns an error if a rule matching the environment does not
+// allow the user to deploy the branch.
+func CheckDeploy(rules []*DeployRule, env, login, branch string) error {
+	for _, rule := range rules {
+		if !rule.Match(env) {
+			continue
+		}
+		if err := rule.Allow(login, branch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// helper function returns true if the value matches any of the patterns.
+func matchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if match, _ := filepath.Match(pattern, value); match {
+			return true
+		}
+	}
+	return false
+}
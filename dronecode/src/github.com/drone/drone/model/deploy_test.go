@@ -0,0 +1,38 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/franela/goblin"
+)
+
+func TestDeployRule(t *testing.T) {
+
+	g := goblin.Goblin(t)
+	g.Describe("DeployRule", func() {
+
+		rules := []*DeployRule{
+			{
+				Environment: "prod*",
+				Users:       []string{"octocat"},
+				Branches:    []string{"master", "release/*"},
+			},
+		}
+
+		g.It("should allow user and branch", func() {
+			g.Assert(CheckDeploy(rules, "production", "octocat", "release/1.0") == nil).IsTrue()
+		})
+		g.It("should deny user", func() {
+			g.Assert(CheckDeploy(rules, "production", "spaceghost", "master") == nil).IsFalse()
+		})
+		g.It("should deny branch", func() {
+			g.Assert(CheckDeploy(rules, "production", "octocat", "develop") == nil).IsFalse()
+		})
+		g.It("should ignore other environments", func() {
+			g.Assert(CheckDeploy(rules, "staging", "spaceghost", "develop") == nil).IsTrue()
+		})
+		g.It("should fail validation without environment", func() {
+			g.Assert((&DeployRule{}).Validate() == nil).IsFalse()
+		})
+	})
+}
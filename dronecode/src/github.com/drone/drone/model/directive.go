@@ -0,0 +1,72 @@
+package model
+
+import (
+	"regexp"
+	"strings"
+)
+
+// skipRe matches any case-insensitive combination of the words "skip" and
+// "ci" wrapped in square brackets.
+var skipRe = regexp.MustCompile(`\[(?i:ci *skip|skip *ci)\]`)
+
+// onlyRe matches the directive that restricts the build to the named
+// pipelines, such as [ci build:only=docs,lint].
+var onlyRe = regexp.MustCompile(`\[(?i:ci) +build:only=([^\]]*)\]`)
+
+// Directives defines the commit message directives of a build.
+type Directives struct {
+	Skip string   // directive that skips the build
+	Only []string // pipelines the build is restricted to
+}
+
+// ParseDirectives parses the directives of the commit message. The build
+// is skipped if the message contains [ci skip], or matches any of the
+// repository skip patterns.
+func ParseDirectives(message string, patterns []string) *Directives {
+	d := new(Directives)
+	if match := skipRe.FindString(message); match != "" {
+		d.Skip = match
+	}
+	for _, pattern := range patterns {
+		if d.Skip != "" {
+			break
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			continue
+		}
+		d.Skip = re.FindString(message)
+	}
+	if match := onlyRe.FindStringSubmatch(message); match != nil {
+		for _, name := range strings.Split(match[1], ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				d.Only = append(d.Only, name)
+			}
+		}
+	}
+	return d
+}
+
+// Includes returns true if the directives do not exclude the pipeline.
+func (d *Directives) Includes(name string) bool {
+	if len(d.Only) == 0 {
+		return true
+	}
+	for _, only := range d.Only {
+		if only == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateSkipPatterns returns an error if a skip pattern is not a valid
+// regular expression.
+func ValidateSkipPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
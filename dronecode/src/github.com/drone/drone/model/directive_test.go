@@ -0,0 +1,44 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/franela/goblin"
+)
+
+func TestDirectives(t *testing.T) {
+
+	g := goblin.Goblin(t)
+	g.Describe("Directives", func() {
+
+		g.It("should skip with default directive", func() {
+			for _, msg := range []string{"[ci skip]", "fix [SKIP CI]", "[CI  Skip] docs"} {
+				g.Assert(ParseDirectives(msg, nil).Skip != "").IsTrue()
+			}
+		})
+		g.It("should not skip without directive", func() {
+			g.Assert(ParseDirectives("ci skip", nil).Skip).Equal("")
+		})
+		g.It("should skip with custom pattern", func() {
+			d := ParseDirectives("wip: update docs", []string{`^wip:`})
+			g.Assert(d.Skip).Equal("wip:")
+		})
+		g.It("should ignore invalid pattern", func() {
+			g.Assert(ParseDirectives("update docs", []string{`(`}).Skip).Equal("")
+		})
+		g.It("should restrict to pipelines", func() {
+			d := ParseDirectives("update docs [ci build:only=docs, lint]", nil)
+			g.Assert(d.Skip).Equal("")
+			g.Assert(d.Only).Equal([]string{"docs", "lint"})
+			g.Assert(d.Includes("docs")).IsTrue()
+			g.Assert(d.Includes("test")).IsFalse()
+		})
+		g.It("should include all pipelines", func() {
+			g.Assert(ParseDirectives("update docs", nil).Includes("test")).IsTrue()
+		})
+		g.It("should validate patterns", func() {
+			g.Assert(ValidateSkipPatterns([]string{`^wip`}) == nil).IsTrue()
+			g.Assert(ValidateSkipPatterns([]string{`(`}) == nil).IsFalse()
+		})
+	})
+}
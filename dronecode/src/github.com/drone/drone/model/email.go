@@ -0,0 +1,13 @@
+package model
+
+// EmailService defines a service for sending email notifications.
+type EmailService interface {
+	EmailSend(*Email) error
+}
+
+// Email represents an email notification.
+type Email struct {
+	To      []string
+	Subject string
+	Body    string
+}
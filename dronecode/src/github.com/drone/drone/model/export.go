@@ -0,0 +1,11 @@
+package model
+
+// RepoExport represents the settings, secrets, registries and cron jobs of
+// a repository, exported to be imported into another repository. Secret
+// values and registry passwords are not exported.
+type RepoExport struct {
+	Settings   *RepoPatch  `json:"settings"`
+	Secrets    []*Secret   `json:"secrets"`
+	Registries []*Registry `json:"registries"`
+	Crons      []*Cron     `json:"crons"`
+}
@@ -4,6 +4,7 @@ package model
 //
 // swagger:model feed
 type Feed struct {
+	ID       int64  `json:"id,omitempty"  meddler:"build_id,zeroisnull"`
 	Owner    string `json:"owner"         meddler:"repo_owner"`
 	Name     string `json:"name"          meddler:"repo_name"`
 	FullName string `json:"full_name"     meddler:"repo_full_name"`
@@ -25,3 +26,14 @@ type Feed struct {
 	Avatar   string `json:"author_avatar,omitempty" meddler:"build_avatar,zeroisnull"`
 	Email    string `json:"author_email,omitempty"  meddler:"build_email,zeroisnull"`
 }
+
+// FeedFilter filters the builds of a feed by status, branch and event.
+// Empty fields match all builds. Before selects the builds with a lower
+// build id, for keyset pagination.
+type FeedFilter struct {
+	Status []string
+	Branch string
+	Event  string
+	Before int64
+	Limit  int
+}
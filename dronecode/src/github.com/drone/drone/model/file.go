@@ -8,6 +8,8 @@ type FileStore interface {
 	FileFind(*Proc, string) (*File, error)
 	FileRead(*Proc, string) (io.ReadCloser, error)
 	FileCreate(*File, io.Reader) error
+	FileListBefore(int64, int) ([]*File, error)
+	FileDelete(*File) error
 }
 
 // File represents a pipeline artifact.
@@ -0,0 +1,21 @@
+package model
+
+// HoldStore persists held pipelines to storage.
+type HoldStore interface {
+	HoldList(*Build) ([]*Hold, error)
+	HoldCreate(*Hold) error
+	HoldDelete(*Hold) (bool, error)
+	HoldClear(*Build) error
+}
+
+// Hold represents a pipeline that is held until the pipelines it depends
+// on have completed. The queue task of the pipeline is stored with the
+// hold, so that the pipeline can be queued after a restart, or by another
+// server.
+type Hold struct {
+	ID        int64    `meddler:"hold_id,pk"`
+	BuildID   int64    `meddler:"hold_build_id"`
+	ProcID    int64    `meddler:"hold_proc_id"`
+	DependsOn []string `meddler:"hold_depends_on,json"`
+	Task      []byte   `meddler:"hold_task"`
+}
@@ -0,0 +1,27 @@
+package model
+
+import "net/http"
+
+// HookStore persists received hooks to storage, so that hooks that failed
+// to process can be replayed.
+type HookStore interface {
+	HookFind(int64) (*Hook, error)
+	HookListFailed(limit int) ([]*Hook, error)
+	HookCreate(*Hook) error
+	HookUpdate(*Hook) error
+}
+
+// Hook represents a hook received from the remote system, and its
+// processing status.
+type Hook struct {
+	ID      int64       `json:"id"              meddler:"hook_id,pk"`
+	Repo    string      `json:"repo,omitempty"  meddler:"hook_repo"`
+	Query   string      `json:"-"               meddler:"hook_query"`
+	Headers http.Header `json:"-"               meddler:"hook_headers,json"`
+	Payload string      `json:"-"               meddler:"hook_payload"`
+	Status  string      `json:"status"          meddler:"hook_status"`
+	Code    int         `json:"code"            meddler:"hook_code"`
+	Error   string      `json:"error,omitempty" meddler:"hook_error"`
+	Created int64       `json:"created_at"      meddler:"hook_created"`
+	Updated int64       `json:"updated_at"      meddler:"hook_updated"`
+}
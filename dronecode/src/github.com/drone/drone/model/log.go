@@ -0,0 +1,25 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// TailLogs returns the trailing lines of the logs, which are a json array of
+// lines. Logs that are not a json array are returned in full.
+func TailLogs(data []byte, lines int) []byte {
+	var list []json.RawMessage
+	if err := json.Unmarshal(data, &list); err != nil || len(list) <= lines {
+		return data
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, line := range list[len(list)-lines:] {
+		if i != 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(line)
+	}
+	buf.WriteByte(']')
+	return buf.Bytes()
+}
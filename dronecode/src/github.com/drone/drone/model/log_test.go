@@ -0,0 +1,16 @@
+package model
+
+import "testing"
+
+func TestTailLogs(t *testing.T) {
+	data := []byte(`[{"pos":0}, {"pos":1}, {"pos":2}]`)
+	if got, want := string(TailLogs(data, 2)), `[{"pos":1},{"pos":2}]`; got != want {
+		t.Errorf("Want tail %s, got %s", want, got)
+	}
+	if got := string(TailLogs(data, 3)); got != string(data) {
+		t.Errorf("Want full logs when tail exceeds the lines, got %s", got)
+	}
+	if got := string(TailLogs([]byte("echo hi"), 1)); got != "echo hi" {
+		t.Errorf("Want logs that are not json returned in full, got %s", got)
+	}
+}
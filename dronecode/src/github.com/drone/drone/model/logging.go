@@ -0,0 +1,6 @@
+package model
+
+// LogLevel defines the logging level of the server.
+type LogLevel struct {
+	Level string `json:"level"`
+}
@@ -0,0 +1,10 @@
+package model
+
+// Org represents an organization of the repositories in the remote version
+// control system, with the number of repositories and active repositories.
+type Org struct {
+	Name   string `json:"name"`
+	Avatar string `json:"avatar_url"`
+	Repos  int    `json:"repos"`
+	Active int    `json:"active"`
+}
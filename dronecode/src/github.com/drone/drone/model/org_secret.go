@@ -0,0 +1,59 @@
+package model
+
+// OrgSecretStore persists organization and global secrets to storage. Global
+// secrets are stored with an empty owner.
+type OrgSecretStore interface {
+	OrgSecretFind(owner, name string) (*OrgSecret, error)
+	OrgSecretList(owner string) ([]*OrgSecret, error)
+	OrgSecretCreate(*OrgSecret) error
+	OrgSecretUpdate(*OrgSecret) error
+	OrgSecretDelete(*OrgSecret) error
+}
+
+// OrgSecret represents a secret shared by all repositories in an
+// organization, or by all repositories when the owner is empty.
+type OrgSecret struct {
+	ID       int64    `json:"id"              meddler:"org_secret_id,pk"`
+	Owner    string   `json:"owner,omitempty" meddler:"org_secret_owner"`
+	Name     string   `json:"name"            meddler:"org_secret_name"`
+	Value    string   `json:"value,omitempty" meddler:"org_secret_value"`
+	Images   []string `json:"image"           meddler:"org_secret_images,json"`
+	Events   []string `json:"event"           meddler:"org_secret_events,json"`
+	Branches []string `json:"branch"          meddler:"org_secret_branches,json"`
+}
+
+// Validate validates the required fields and formats.
+func (s *OrgSecret) Validate() error {
+	switch {
+	case len(s.Name) == 0:
+		return errSecretNameInvalid
+	case len(s.Value) == 0:
+		return errSecretValueInvalid
+	default:
+		return nil
+	}
+}
+
+// Copy makes a copy of the secret without the value.
+func (s *OrgSecret) Copy() *OrgSecret {
+	return &OrgSecret{
+		ID:       s.ID,
+		Owner:    s.Owner,
+		Name:     s.Name,
+		Images:   s.Images,
+		Events:   s.Events,
+		Branches: s.Branches,
+	}
+}
+
+// Secret returns the organization secret as a repository secret, subject
+// to the same event, branch and image restrictions.
+func (s *OrgSecret) Secret() *Secret {
+	return &Secret{
+		Name:     s.Name,
+		Value:    s.Value,
+		Images:   s.Images,
+		Events:   s.Events,
+		Branches: s.Branches,
+	}
+}
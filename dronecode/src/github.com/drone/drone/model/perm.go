@@ -1,7 +1,36 @@
 package model
 
+// PermStore persists the repository permissions of machine users to
+// storage. The permissions of other users are fetched from the remote.
+type PermStore interface {
+	PermFind(*User, *Repo) (*RepoPerm, error)
+	PermList(*User) ([]*RepoPerm, error)
+	PermUpsert(*RepoPerm) error
+	PermDelete(*RepoPerm) error
+}
+
 type Perm struct {
 	Pull  bool `json:"pull"`
 	Push  bool `json:"push"`
 	Admin bool `json:"admin"`
 }
+
+// RepoPerm represents the permissions of a machine user to a repository.
+type RepoPerm struct {
+	ID     int64  `json:"-"     meddler:"perm_id,pk"`
+	UserID int64  `json:"-"     meddler:"perm_user_id"`
+	RepoID int64  `json:"-"     meddler:"perm_repo_id"`
+	Repo   string `json:"repo"  meddler:"-"`
+	Pull   bool   `json:"pull"  meddler:"perm_pull"`
+	Push   bool   `json:"push"  meddler:"perm_push"`
+	Admin  bool   `json:"admin" meddler:"perm_admin"`
+}
+
+// Perm returns the repository permissions.
+func (p *RepoPerm) Perm() *Perm {
+	return &Perm{
+		Pull:  p.Pull || p.Push || p.Admin,
+		Push:  p.Push || p.Admin,
+		Admin: p.Admin,
+	}
+}
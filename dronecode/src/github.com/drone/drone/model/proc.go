@@ -28,6 +28,7 @@ type Proc struct {
 	Machine  string            `json:"machine,omitempty"    meddler:"proc_machine"`
 	Platform string            `json:"platform,omitempty"   meddler:"proc_platform"`
 	Environ  map[string]string `json:"environ,omitempty"    meddler:"proc_environ,json"`
+	Labels   map[string]string `json:"labels,omitempty"     meddler:"proc_labels,json"`
 	Children []*Proc           `json:"children,omitempty"   meddler:"-"`
 }
 
@@ -44,14 +45,18 @@ func (p *Proc) Failing() bool {
 // Tree creates a process tree from a flat process list.
 func Tree(procs []*Proc) []*Proc {
 	var (
-		nodes  []*Proc
-		parent *Proc
+		nodes   []*Proc
+		parent  *Proc
+		parents = map[int]*Proc{}
 	)
 	for _, proc := range procs {
 		if proc.PPID == 0 {
 			nodes = append(nodes, proc)
 			parent = proc
+			parents[proc.PID] = proc
 			continue
+		} else if p, ok := parents[proc.PPID]; ok {
+			p.Children = append(p.Children, proc)
 		} else {
 			parent.Children = append(parent.Children, proc)
 		}
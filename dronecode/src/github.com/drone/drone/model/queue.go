@@ -9,9 +9,11 @@ import (
 
 // Task defines scheduled pipeline Task.
 type Task struct {
-	ID     string            `meddler:"task_id"`
-	Data   []byte            `meddler:"task_data"`
-	Labels map[string]string `meddler:"task_labels,json"`
+	ID       string            `meddler:"task_id"`
+	Data     []byte            `meddler:"task_data"`
+	Labels   map[string]string `meddler:"task_labels,json"`
+	Priority int               `meddler:"task_priority"`
+	Running  bool              `meddler:"task_running"`
 }
 
 // TaskStore defines storage for scheduled Tasks.
@@ -19,18 +21,28 @@ type TaskStore interface {
 	TaskList() ([]*Task, error)
 	TaskInsert(*Task) error
 	TaskDelete(string) error
+	TaskUpdateRunning(string, bool) error
 }
 
 // WithTaskStore returns a queue that is backed by the TaskStore. This
-// ensures the task Queue can be restored when the system starts.
+// ensures the task Queue can be restored when the system starts. Tasks
+// that were running are restored as running if the queue supports leases,
+// and are returned to the pending tasks if the agent does not report back
+// before the lease expires.
 func WithTaskStore(q queue.Queue, s TaskStore) queue.Queue {
 	tasks, _ := s.TaskList()
 	for _, task := range tasks {
-		q.Push(context.Background(), &queue.Task{
-			ID:     task.ID,
-			Data:   task.Data,
-			Labels: task.Labels,
-		})
+		t := &queue.Task{
+			ID:       task.ID,
+			Data:     task.Data,
+			Labels:   task.Labels,
+			Priority: task.Priority,
+		}
+		if leaser, ok := q.(queue.Leaser); ok && task.Running {
+			leaser.Lease(context.Background(), t)
+		} else {
+			q.Push(context.Background(), t)
+		}
 	}
 	return &persistentQueue{q, s}
 }
@@ -43,9 +55,10 @@ type persistentQueue struct {
 // Push pushes an task to the tail of this queue.
 func (q *persistentQueue) Push(c context.Context, task *queue.Task) error {
 	q.store.TaskInsert(&Task{
-		ID:     task.ID,
-		Data:   task.Data,
-		Labels: task.Labels,
+		ID:       task.ID,
+		Data:     task.Data,
+		Labels:   task.Labels,
+		Priority: task.Priority,
 	})
 	err := q.Queue.Push(c, task)
 	if err != nil {
@@ -54,20 +67,32 @@ func (q *persistentQueue) Push(c context.Context, task *queue.Task) error {
 	return err
 }
 
-// Poll retrieves and removes a task head of this queue.
+// Poll retrieves and removes a task head of this queue. The task remains
+// in the backup, marked as running, until the task is complete.
 func (q *persistentQueue) Poll(c context.Context, f queue.Filter) (*queue.Task, error) {
 	task, err := q.Queue.Poll(c, f)
 	if task != nil {
-		logrus.Debugf("pull queue item: %s: remove from backup", task.ID)
-		if derr := q.store.TaskDelete(task.ID); derr != nil {
-			logrus.Errorf("pull queue item: %s: failed to remove from backup: %s", task.ID, derr)
-		} else {
-			logrus.Errorf("pull queue item: %s: successfully removed from backup", task.ID)
+		logrus.Debugf("pull queue item: %s: mark as running in backup", task.ID)
+		if derr := q.store.TaskUpdateRunning(task.ID, true); derr != nil {
+			logrus.Errorf("pull queue item: %s: failed to mark as running in backup: %s", task.ID, derr)
 		}
 	}
 	return task, err
 }
 
+// Done signals the task is complete.
+func (q *persistentQueue) Done(c context.Context, id string) error {
+	return q.Error(c, id, nil)
+}
+
+// Error signals the task is complete with errors.
+func (q *persistentQueue) Error(c context.Context, id string, err error) error {
+	if derr := q.store.TaskDelete(id); derr != nil {
+		logrus.Errorf("complete queue item: %s: failed to remove from backup: %s", id, derr)
+	}
+	return q.Queue.Error(c, id, err)
+}
+
 // Evict removes a pending task from the queue.
 func (q *persistentQueue) Evict(c context.Context, id string) error {
 	err := q.Queue.Evict(c, id)
@@ -76,3 +101,23 @@ func (q *persistentQueue) Evict(c context.Context, id string) error {
 	}
 	return err
 }
+
+// QueueInfo represents the pending and running pipelines in the build
+// queue.
+type QueueInfo struct {
+	Paused  bool         `json:"paused"`
+	Pending []*QueueItem `json:"pending"`
+	Running []*QueueItem `json:"running"`
+}
+
+// QueueItem represents a pipeline in the build queue.
+type QueueItem struct {
+	ID       string            `json:"id"`
+	Repo     string            `json:"repo"`
+	Build    int               `json:"build"`
+	Pipeline string            `json:"pipeline,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Priority int               `json:"priority,omitempty"`
+	Waiting  int64             `json:"wait_time"`
+	Agent    string            `json:"agent,omitempty"`
+}
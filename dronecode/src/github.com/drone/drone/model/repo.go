@@ -11,37 +11,62 @@ type RepoLite struct {
 //
 // swagger:model repo
 type Repo struct {
-	ID          int64  `json:"id,omitempty"             meddler:"repo_id,pk"`
-	UserID      int64  `json:"-"                        meddler:"repo_user_id"`
-	Owner       string `json:"owner"                    meddler:"repo_owner"`
-	Name        string `json:"name"                     meddler:"repo_name"`
-	FullName    string `json:"full_name"                meddler:"repo_full_name"`
-	Avatar      string `json:"avatar_url,omitempty"     meddler:"repo_avatar"`
-	Link        string `json:"link_url,omitempty"       meddler:"repo_link"`
-	Kind        string `json:"scm,omitempty"            meddler:"repo_scm"`
-	Clone       string `json:"clone_url,omitempty"      meddler:"repo_clone"`
-	Branch      string `json:"default_branch,omitempty" meddler:"repo_branch"`
-	Timeout     int64  `json:"timeout,omitempty"        meddler:"repo_timeout"`
-	IsPrivate   bool   `json:"private,omitempty"        meddler:"repo_private"`
-	IsTrusted   bool   `json:"trusted"                  meddler:"repo_trusted"`
-	IsStarred   bool   `json:"starred,omitempty"        meddler:"-"`
-	IsGated     bool   `json:"gated"                    meddler:"repo_gated"`
-	AllowPull   bool   `json:"allow_pr"                 meddler:"repo_allow_pr"`
-	AllowPush   bool   `json:"allow_push"               meddler:"repo_allow_push"`
-	AllowDeploy bool   `json:"allow_deploys"            meddler:"repo_allow_deploys"`
-	AllowTag    bool   `json:"allow_tags"               meddler:"repo_allow_tags"`
-	Config      string `json:"config_file"              meddler:"repo_config_path"`
-	Hash        string `json:"-"                        meddler:"repo_hash"`
+	ID            int64         `json:"id,omitempty"             meddler:"repo_id,pk"`
+	UserID        int64         `json:"-"                        meddler:"repo_user_id"`
+	Owner         string        `json:"owner"                    meddler:"repo_owner"`
+	Name          string        `json:"name"                     meddler:"repo_name"`
+	FullName      string        `json:"full_name"                meddler:"repo_full_name"`
+	Avatar        string        `json:"avatar_url,omitempty"     meddler:"repo_avatar"`
+	Link          string        `json:"link_url,omitempty"       meddler:"repo_link"`
+	Kind          string        `json:"scm,omitempty"            meddler:"repo_scm"`
+	Clone         string        `json:"clone_url,omitempty"      meddler:"repo_clone"`
+	Branch        string        `json:"default_branch,omitempty" meddler:"repo_branch"`
+	Timeout       int64         `json:"timeout,omitempty"        meddler:"repo_timeout"`
+	Throttle      int           `json:"throttle,omitempty"       meddler:"repo_throttle"`
+	RateLimit     int           `json:"rate_limit,omitempty"     meddler:"repo_rate_limit"`
+	Priority      int           `json:"priority,omitempty"       meddler:"repo_priority"`
+	IsPrivate     bool          `json:"private,omitempty"        meddler:"repo_private"`
+	IsTrusted     bool          `json:"trusted"                  meddler:"repo_trusted"`
+	IsStarred     bool          `json:"starred,omitempty"        meddler:"-"`
+	IsGated       bool          `json:"gated"                    meddler:"repo_gated"`
+	RequireSigned bool          `json:"require_signed" meddler:"repo_require_signed"`
+	HookSigned    bool          `json:"hook_signed"              meddler:"repo_hook_signed"`
+	AllowPull     bool          `json:"allow_pr"                 meddler:"repo_allow_pr"`
+	AllowPush     bool          `json:"allow_push"               meddler:"repo_allow_push"`
+	AllowDeploy   bool          `json:"allow_deploys"            meddler:"repo_allow_deploys"`
+	AllowTag      bool          `json:"allow_tags"               meddler:"repo_allow_tags"`
+	AllowComments bool          `json:"allow_comments" meddler:"repo_allow_comments"`
+	CancelPulls   bool          `json:"auto_cancel_pull_requests" meddler:"repo_cancel_pulls"`
+	CancelPush    bool          `json:"auto_cancel_pushes"        meddler:"repo_cancel_push"`
+	EmailNotify   bool          `json:"email_notify"              meddler:"repo_email_notify"`
+	EmailTo       []string      `json:"email_recipients,omitempty" meddler:"repo_email_recipients,json"`
+	SkipPatterns  []string      `json:"skip_patterns,omitempty"  meddler:"repo_skip_patterns,json"`
+	Config        string        `json:"config_file"              meddler:"repo_config_path"`
+	Downstream    []string      `json:"downstream,omitempty"   meddler:"repo_downstream,json"`
+	DeployRules   []*DeployRule `json:"deploy_rules,omitempty" meddler:"repo_deploy_rules,json"`
+	Hash          string        `json:"-"                        meddler:"repo_hash"`
 }
 
 // RepoPatch represents a repository patch object.
 type RepoPatch struct {
-	Config      *string `json:"config_file,omitempty"`
-	IsTrusted   *bool   `json:"trusted,omitempty"`
-	IsGated     *bool   `json:"gated,omitempty"`
-	Timeout     *int64  `json:"timeout,omitempty"`
-	AllowPull   *bool   `json:"allow_pr,omitempty"`
-	AllowPush   *bool   `json:"allow_push,omitempty"`
-	AllowDeploy *bool   `json:"allow_deploy,omitempty"`
-	AllowTag    *bool   `json:"allow_tag,omitempty"`
+	Config        *string        `json:"config_file,omitempty"`
+	IsTrusted     *bool          `json:"trusted,omitempty"`
+	IsGated       *bool          `json:"gated,omitempty"`
+	RequireSigned *bool          `json:"require_signed,omitempty"`
+	Timeout       *int64         `json:"timeout,omitempty"`
+	Throttle      *int           `json:"throttle,omitempty"`
+	RateLimit     *int           `json:"rate_limit,omitempty"`
+	Priority      *int           `json:"priority,omitempty"`
+	AllowPull     *bool          `json:"allow_pr,omitempty"`
+	AllowPush     *bool          `json:"allow_push,omitempty"`
+	AllowDeploy   *bool          `json:"allow_deploy,omitempty"`
+	AllowTag      *bool          `json:"allow_tag,omitempty"`
+	AllowComments *bool          `json:"allow_comments,omitempty"`
+	CancelPulls   *bool          `json:"auto_cancel_pull_requests,omitempty"`
+	CancelPush    *bool          `json:"auto_cancel_pushes,omitempty"`
+	EmailNotify   *bool          `json:"email_notify,omitempty"`
+	EmailTo       *[]string      `json:"email_recipients,omitempty"`
+	SkipPatterns  *[]string      `json:"skip_patterns,omitempty"`
+	Downstream    *[]string      `json:"downstream,omitempty"`
+	DeployRules   *[]*DeployRule `json:"deploy_rules,omitempty"`
 }
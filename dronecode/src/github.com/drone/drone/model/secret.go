@@ -20,6 +20,12 @@ type SecretService interface {
 	SecretDelete(*Repo, string) error
 }
 
+// SecretResolver defines a service for resolving the secrets requested by
+// a pipeline step at build time, without storing the secret.
+type SecretResolver interface {
+	SecretResolve(repo *Repo, build *Build, name, image string) (*Secret, error)
+}
+
 // SecretStore persists secret information to storage.
 type SecretStore interface {
 	SecretFind(*Repo, string) (*Secret, error)
@@ -38,16 +44,37 @@ type Secret struct {
 	Value      string   `json:"value,omitempty" meddler:"secret_value"`
 	Images     []string `json:"image"           meddler:"secret_images,json"`
 	Events     []string `json:"event"           meddler:"secret_events,json"`
+	Branches   []string `json:"branch"          meddler:"secret_branches,json"`
 	SkipVerify bool     `json:"-"               meddler:"secret_skip_verify"`
 	Conceal    bool     `json:"-"               meddler:"secret_conceal"`
 }
 
-// Match returns true if an image and event match the restricted list.
+// Match returns true if an image and event match the restricted list. A
+// rollback matches the secrets exposed to deployments.
 func (s *Secret) Match(event string) bool {
 	for _, pattern := range s.Events {
 		if match, _ := filepath.Match(pattern, event); match {
 			return true
 		}
+		if event == EventRollback {
+			if match, _ := filepath.Match(pattern, EventDeploy); match {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MatchBranch returns true if the branch matches the restricted list. A
+// secret without a list of branches matches all branches.
+func (s *Secret) MatchBranch(branch string) bool {
+	if len(s.Branches) == 0 {
+		return true
+	}
+	for _, pattern := range s.Branches {
+		if match, _ := filepath.Match(pattern, branch); match {
+			return true
+		}
 	}
 	return false
 }
@@ -67,10 +94,11 @@ func (s *Secret) Validate() error {
 // Copy makes a copy of the secret without the value.
 func (s *Secret) Copy() *Secret {
 	return &Secret{
-		ID:     s.ID,
-		RepoID: s.RepoID,
-		Name:   s.Name,
-		Images: s.Images,
-		Events: s.Events,
+		ID:       s.ID,
+		RepoID:   s.RepoID,
+		Name:     s.Name,
+		Images:   s.Images,
+		Events:   s.Events,
+		Branches: s.Branches,
 	}
 }
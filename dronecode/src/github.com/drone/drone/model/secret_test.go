@@ -21,6 +21,26 @@ func TestSecret(t *testing.T) {
 			secret.Events = []string{"pull_request"}
 			g.Assert(secret.Match("push")).IsFalse()
 		})
+		g.It("should match deployment event on rollback", func() {
+			secret := Secret{}
+			secret.Events = []string{"deployment"}
+			g.Assert(secret.Match("rollback")).IsTrue()
+		})
+		g.It("should match any branch", func() {
+			secret := Secret{}
+			g.Assert(secret.MatchBranch("feature/foo")).IsTrue()
+		})
+		g.It("should match branch pattern", func() {
+			secret := Secret{}
+			secret.Branches = []string{"master", "release/*"}
+			g.Assert(secret.MatchBranch("master")).IsTrue()
+			g.Assert(secret.MatchBranch("release/1.0")).IsTrue()
+		})
+		g.It("should not match branch", func() {
+			secret := Secret{}
+			secret.Branches = []string{"master", "release/*"}
+			g.Assert(secret.MatchBranch("feature/foo")).IsFalse()
+		})
 		g.It("should pass validation")
 		g.Describe("should fail validation", func() {
 			g.It("when no image")
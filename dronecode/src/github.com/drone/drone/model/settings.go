@@ -2,15 +2,17 @@ package model
 
 // Settings defines system configuration parameters.
 type Settings struct {
-	Open   bool            // Enables open registration
-	Secret string          // Secret token used to authenticate agents
-	Admins map[string]bool // Administrative users
-	Orgs   map[string]bool // Organization whitelist
+	Open      bool            // Enables open registration
+	Secret    string          // Secret token used to authenticate agents
+	PromToken string          // Secret token used to authenticate metrics
+	Admins    map[string]bool // Administrative users
+	Orgs      map[string]bool // Organization whitelist
 }
 
-// IsAdmin returns true if the user is a member of the administrator list.
+// IsAdmin returns true if the user is a member of the administrator list,
+// or of the administrator group of the directory.
 func (c *Settings) IsAdmin(user *User) bool {
-	return c.Admins[user.Login]
+	return c.Admins[user.Login] || user.GroupAdmin
 }
 
 // IsMember returns true if the user is a member of the whitelisted teams.
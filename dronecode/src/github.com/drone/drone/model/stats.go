@@ -0,0 +1,93 @@
+package model
+
+import (
+	"math"
+	"sort"
+)
+
+// BuildTime represents the status and timestamps of a completed build.
+type BuildTime struct {
+	Status   string `meddler:"build_status"`
+	Enqueued int64  `meddler:"build_enqueued"`
+	Started  int64  `meddler:"build_started"`
+	Finished int64  `meddler:"build_finished"`
+}
+
+// RepoStats represents the build statistics of a repository over a window
+// of time. Durations are in seconds.
+type RepoStats struct {
+	Window      string    `json:"window"`
+	Builds      int       `json:"builds"`
+	Success     int       `json:"success"`
+	Failure     int       `json:"failure"`
+	SuccessRate float64   `json:"success_rate"`
+	Duration    Durations `json:"duration"`
+	Wait        Durations `json:"wait"`
+}
+
+// Durations represents the average and percentiles of durations in seconds.
+type Durations struct {
+	Avg float64 `json:"avg"`
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+}
+
+// NewRepoStats returns the build statistics of the completed builds. The
+// duration of a build is measured from start to finish, and the wait time
+// from enqueue to start.
+func NewRepoStats(window string, builds []*BuildTime) *RepoStats {
+	stats := &RepoStats{Window: window}
+	var durations, waits []float64
+	for _, build := range builds {
+		stats.Builds++
+		switch build.Status {
+		case StatusSuccess:
+			stats.Success++
+		case StatusFailure, StatusError, StatusKilled:
+			stats.Failure++
+		}
+		if build.Started == 0 {
+			continue
+		}
+		durations = append(durations, float64(build.Finished-build.Started))
+		if build.Enqueued != 0 && build.Started >= build.Enqueued {
+			waits = append(waits, float64(build.Started-build.Enqueued))
+		}
+	}
+	if stats.Builds != 0 {
+		stats.SuccessRate = float64(stats.Success) / float64(stats.Builds)
+	}
+	stats.Duration = newDurations(durations)
+	stats.Wait = newDurations(waits)
+	return stats
+}
+
+// helper function returns the average and percentiles of the values.
+func newDurations(values []float64) Durations {
+	if len(values) == 0 {
+		return Durations{}
+	}
+	sort.Float64s(values)
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return Durations{
+		Avg: sum / float64(len(values)),
+		P50: percentile(values, 50),
+		P90: percentile(values, 90),
+		P95: percentile(values, 95),
+		P99: percentile(values, 99),
+	}
+}
+
+// helper function returns the nearest rank percentile of the sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
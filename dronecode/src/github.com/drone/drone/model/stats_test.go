@@ -0,0 +1,41 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/franela/goblin"
+)
+
+func TestRepoStats(t *testing.T) {
+
+	g := goblin.Goblin(t)
+	g.Describe("RepoStats", func() {
+
+		g.It("should compute success rate and durations", func() {
+			builds := []*BuildTime{
+				{Status: StatusSuccess, Enqueued: 100, Started: 110, Finished: 170},
+				{Status: StatusSuccess, Enqueued: 200, Started: 200, Finished: 320},
+				{Status: StatusFailure, Enqueued: 300, Started: 330, Finished: 360},
+				{Status: StatusKilled, Enqueued: 400},
+			}
+			stats := NewRepoStats("168h", builds)
+			g.Assert(stats.Window).Equal("168h")
+			g.Assert(stats.Builds).Equal(4)
+			g.Assert(stats.Success).Equal(2)
+			g.Assert(stats.Failure).Equal(2)
+			g.Assert(stats.SuccessRate).Equal(0.5)
+			g.Assert(stats.Duration.Avg).Equal(70.0)
+			g.Assert(stats.Duration.P50).Equal(60.0)
+			g.Assert(stats.Duration.P99).Equal(120.0)
+			g.Assert(stats.Wait.Avg).Equal(40.0 / 3)
+			g.Assert(stats.Wait.P90).Equal(30.0)
+		})
+
+		g.It("should return zero stats without builds", func() {
+			stats := NewRepoStats("24h", nil)
+			g.Assert(stats.Builds).Equal(0)
+			g.Assert(stats.SuccessRate).Equal(0.0)
+			g.Assert(stats.Duration.P50).Equal(0.0)
+		})
+	})
+}
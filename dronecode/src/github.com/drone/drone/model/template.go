@@ -0,0 +1,61 @@
+package model
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"text/template"
+)
+
+var (
+	errTemplateNameInvalid = errors.New("Invalid Template Name")
+	errTemplateDataInvalid = errors.New("Invalid Template Data")
+)
+
+var templateNameRe = regexp.MustCompile(`^[A-Za-z0-9._-]{1,250}$`)
+
+// TemplateStore persists pipeline templates to storage.
+type TemplateStore interface {
+	TemplateFind(namespace, name string) (*Template, error)
+	TemplateList(namespace string) ([]*Template, error)
+	TemplateCreate(*Template) error
+	TemplateUpdate(*Template) error
+	TemplateDelete(*Template) error
+}
+
+// Template represents a pipeline template shared by the repositories of
+// the namespace, which is the repository owner. Repositories load the
+// template with the template input parameters.
+type Template struct {
+	ID        int64  `json:"id"         meddler:"template_id,pk"`
+	Namespace string `json:"namespace"  meddler:"template_namespace"`
+	Name      string `json:"name"       meddler:"template_name"`
+	Data      string `json:"data"       meddler:"template_data"`
+	Created   int64  `json:"created_at" meddler:"template_created"`
+	Updated   int64  `json:"updated_at" meddler:"template_updated"`
+}
+
+// Validate validates the required fields and formats.
+func (t *Template) Validate() error {
+	switch {
+	case !templateNameRe.MatchString(t.Name):
+		return errTemplateNameInvalid
+	case len(t.Data) == 0:
+		return errTemplateDataInvalid
+	}
+	_, err := template.New(t.Name).Parse(t.Data)
+	return err
+}
+
+// Render renders the pipeline configuration of the template. The input
+// parameters are available to the template as .input, such as
+// {{ .input.image }}.
+func (t *Template) Render(input interface{}) (string, error) {
+	tmpl, err := template.New(t.Name).Option("missingkey=zero").Parse(t.Data)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]interface{}{"input": input})
+	return buf.String(), err
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/franela/goblin"
+)
+
+func TestTemplate(t *testing.T) {
+
+	g := goblin.Goblin(t)
+	g.Describe("Template", func() {
+
+		g.It("should render input", func() {
+			tmpl := &Template{
+				Name: "golang.yaml",
+				Data: "pipeline:\n  build:\n    image: golang:{{ .input.version }}\n",
+			}
+			out, err := tmpl.Render(map[interface{}]interface{}{"version": "1.10"})
+			g.Assert(err == nil).IsTrue()
+			g.Assert(out).Equal("pipeline:\n  build:\n    image: golang:1.10\n")
+		})
+		g.It("should validate name", func() {
+			tmpl := &Template{Name: "../golang.yaml", Data: "pipeline: {}"}
+			g.Assert(tmpl.Validate()).Equal(errTemplateNameInvalid)
+		})
+		g.It("should validate data", func() {
+			g.Assert((&Template{Name: "golang.yaml"}).Validate()).Equal(errTemplateDataInvalid)
+			tmpl := &Template{Name: "golang.yaml", Data: "{{ .input"}
+			g.Assert(tmpl.Validate() == nil).IsFalse()
+			tmpl.Data = "pipeline: {}"
+			g.Assert(tmpl.Validate() == nil).IsTrue()
+		})
+	})
+}
@@ -0,0 +1,74 @@
+package model
+
+import "errors"
+
+var (
+	errTokenNameInvalid  = errors.New("Invalid Token Name")
+	errTokenScopeInvalid = errors.New("Invalid Token Scope")
+)
+
+// Access token scopes. The admin scope grants every other scope.
+const (
+	ScopeRead         = "read"
+	ScopeBuildRestart = "build:restart"
+	ScopeSecretsWrite = "secrets:write"
+	ScopeAdmin        = "admin"
+)
+
+// LegacyTokenScopes are the scopes granted to the legacy user tokens, which
+// are deprecated in favor of access tokens. Legacy user tokens are not
+// granted the admin scope, so that changes to the repository settings, the
+// access tokens and the system administration require an access token with
+// the admin scope, or a web session.
+var LegacyTokenScopes = []string{ScopeRead, ScopeBuildRestart, ScopeSecretsWrite}
+
+// AccessTokenStore persists personal access tokens to storage.
+type AccessTokenStore interface {
+	AccessTokenFind(int64) (*AccessToken, error)
+	AccessTokenList(*User) ([]*AccessToken, error)
+	AccessTokenCreate(*AccessToken) error
+	AccessTokenDelete(*AccessToken) error
+}
+
+// AccessToken represents a named personal access token, limited to a set
+// of scopes and an optional expiration date.
+type AccessToken struct {
+	ID      int64    `json:"id"              meddler:"token_id,pk"`
+	UserID  int64    `json:"-"               meddler:"token_user_id"`
+	Name    string   `json:"name"            meddler:"token_name"`
+	Scopes  []string `json:"scopes"          meddler:"token_scopes,json"`
+	Hash    string   `json:"-"               meddler:"token_hash"`
+	Expires int64    `json:"expires_at"      meddler:"token_expires"`
+	Created int64    `json:"created_at"      meddler:"token_created"`
+	Token   string   `json:"token,omitempty" meddler:"-"`
+}
+
+// HasScope returns true if the token is granted the scope.
+func (t *AccessToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired returns true if the token is expired at the time.
+func (t *AccessToken) Expired(now int64) bool {
+	return t.Expires != 0 && t.Expires <= now
+}
+
+// Validate validates the required fields and formats.
+func (t *AccessToken) Validate() error {
+	if len(t.Name) == 0 {
+		return errTokenNameInvalid
+	}
+	for _, scope := range t.Scopes {
+		switch scope {
+		case ScopeRead, ScopeBuildRestart, ScopeSecretsWrite, ScopeAdmin:
+		default:
+			return errTokenScopeInvalid
+		}
+	}
+	return nil
+}
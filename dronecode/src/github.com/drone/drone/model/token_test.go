@@ -0,0 +1,35 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/franela/goblin"
+)
+
+func TestAccessToken(t *testing.T) {
+
+	g := goblin.Goblin(t)
+	g.Describe("AccessToken", func() {
+
+		g.It("should have granted scope", func() {
+			token := AccessToken{Scopes: []string{ScopeRead}}
+			g.Assert(token.HasScope(ScopeRead)).IsTrue()
+			g.Assert(token.HasScope(ScopeSecretsWrite)).IsFalse()
+		})
+		g.It("should have every scope with admin scope", func() {
+			token := AccessToken{Scopes: []string{ScopeAdmin}}
+			g.Assert(token.HasScope(ScopeBuildRestart)).IsTrue()
+		})
+		g.It("should expire", func() {
+			token := AccessToken{Expires: 100}
+			g.Assert(token.Expired(99)).IsFalse()
+			g.Assert(token.Expired(100)).IsTrue()
+			token.Expires = 0
+			g.Assert(token.Expired(100)).IsFalse()
+		})
+		g.It("should fail validation with unknown scope", func() {
+			token := AccessToken{Name: "ci", Scopes: []string{"write"}}
+			g.Assert(token.Validate() != nil).IsTrue()
+		})
+	})
+}
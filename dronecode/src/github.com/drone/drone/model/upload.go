@@ -0,0 +1,8 @@
+package model
+
+// UploadService defines a service that returns pre-signed urls, so that the
+// agent uploads the step logs directly to blob storage, instead of through
+// the server.
+type UploadService interface {
+	UploadLogs(*Proc) (string, error)
+}
@@ -40,9 +40,31 @@ type User struct {
 	// longer persisted in the database.
 	Admin bool `json:"admin,omitempty" meddler:"-"`
 
+	// GroupAdmin indicates the user is a system administrator by membership
+	// of the administrator group of the directory, such as LDAP. It is
+	// updated when the user logs in.
+	GroupAdmin bool `json:"-" meddler:"user_group_admin"`
+
+	// Subject is the subject of the OpenID Connect identity linked to the
+	// user account.
+	Subject string `json:"-" meddler:"user_subject"`
+
+	// EmailNotify indicates the user receives email notifications for the
+	// builds of the user.
+	EmailNotify bool `json:"email_notify" meddler:"user_email_notify"`
+
+	// Machine indicates the user is a machine user, which does not have an
+	// account in the remote system and authenticates with access tokens.
+	Machine bool `json:"machine,omitempty" meddler:"user_machine"`
+
 	// Hash is a unique token used to sign tokens.
 	Hash string `json:"-" meddler:"user_hash"`
 
 	// DEPRECATED Admin indicates the user is a system administrator.
 	XAdmin bool `json:"-" meddler:"user_admin"`
 }
+
+// UserPatch represents a patch of the preferences of the authenticated user.
+type UserPatch struct {
+	EmailNotify *bool `json:"email_notify,omitempty"`
+}
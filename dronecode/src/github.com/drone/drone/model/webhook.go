@@ -0,0 +1,46 @@
+package model
+
+// System webhook events.
+const (
+	WebhookBuildCreated  = "build:created"
+	WebhookBuildStarted  = "build:started"
+	WebhookBuildFinished = "build:finished"
+	WebhookRepoActivated = "repo:activated"
+	WebhookUserLogin     = "user:login"
+)
+
+// WebhookService defines a service for sending system webhooks to the
+// configured endpoints.
+type WebhookService interface {
+	WebhookSend(*WebhookPayload) error
+	WebhookRedeliver(*Delivery) error
+}
+
+// DeliveryStore persists the webhook delivery log to storage.
+type DeliveryStore interface {
+	DeliveryFind(int64) (*Delivery, error)
+	DeliveryList(int) ([]*Delivery, error)
+	DeliveryCreate(*Delivery) error
+	DeliveryUpdate(*Delivery) error
+}
+
+// WebhookPayload represents the json payload of a system webhook.
+type WebhookPayload struct {
+	Event string `json:"event"`
+	Repo  *Repo  `json:"repo,omitempty"`
+	Build *Build `json:"build,omitempty"`
+	User  *User  `json:"user,omitempty"`
+}
+
+// Delivery represents the delivery of a system webhook to an endpoint.
+type Delivery struct {
+	ID       int64  `json:"id"              meddler:"delivery_id,pk"`
+	Endpoint string `json:"endpoint"        meddler:"delivery_endpoint"`
+	Event    string `json:"event"           meddler:"delivery_event"`
+	Payload  string `json:"payload"         meddler:"delivery_payload"`
+	Code     int    `json:"code"            meddler:"delivery_code"`
+	Error    string `json:"error,omitempty" meddler:"delivery_error"`
+	Attempts int    `json:"attempts"        meddler:"delivery_attempts"`
+	Created  int64  `json:"created_at"      meddler:"delivery_created"`
+	Updated  int64  `json:"updated_at"      meddler:"delivery_updated"`
+}
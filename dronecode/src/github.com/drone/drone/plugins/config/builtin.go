@@ -0,0 +1,17 @@
+package config
+
+import (
+	"github.com/drone/drone/model"
+)
+
+type builtin struct{}
+
+// New returns a new local configuration service, which uses the pipeline
+// configuration fetched from the repository as-is.
+func New() model.ConfigService {
+	return new(builtin)
+}
+
+func (b *builtin) ConfigResolve(repo *model.Repo, build *model.Build, data []byte) ([]byte, error) {
+	return data, nil
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/ghodss/yaml"
+)
+
+// evalTimeout limits the evaluation time of a generated configuration.
+const evalTimeout = time.Minute
+
+var errConfigEmpty = errors.New("Configuration does not define a pipeline")
+
+// helper function converts the json output of a generated configuration to
+// yaml. Each pipeline of a list of pipelines is converted to a yaml
+// document.
+func jsonToYaml(data []byte) ([]byte, error) {
+	var docs []json.RawMessage
+	if err := json.Unmarshal(data, &docs); err != nil {
+		docs = []json.RawMessage{data}
+	}
+	if len(docs) == 0 {
+		return nil, errConfigEmpty
+	}
+
+	var buf bytes.Buffer
+	for i, doc := range docs {
+		out, err := yaml.JSONToYAML(doc)
+		if err != nil {
+			return nil, err
+		}
+		if i != 0 {
+			buf.WriteString("---\n")
+		}
+		buf.Write(out)
+	}
+	return buf.Bytes(), nil
+}
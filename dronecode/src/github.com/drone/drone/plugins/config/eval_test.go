@@ -0,0 +1,27 @@
+package config
+
+import "testing"
+
+func TestJsonToYaml(t *testing.T) {
+	out, err := jsonToYaml([]byte(`{"pipeline": [{"name": "build", "image": "golang"}]}`))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := string(out), "pipeline:\n- image: golang\n  name: build\n"; got != want {
+		t.Errorf("Want yaml %q, got %q", want, got)
+	}
+
+	out, err = jsonToYaml([]byte(`[{"name": "test"}, {"name": "deploy"}]`))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := string(out), "name: test\n---\nname: deploy\n"; got != want {
+		t.Errorf("Want yaml documents %q, got %q", want, got)
+	}
+
+	if _, err := jsonToYaml([]byte(`[]`)); err != errConfigEmpty {
+		t.Errorf("Want error when the configuration is empty, got %v", err)
+	}
+}
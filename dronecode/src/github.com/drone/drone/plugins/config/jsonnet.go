@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/drone/drone/model"
+
+	"github.com/google/go-jsonnet"
+)
+
+// EvalJsonnet evaluates the jsonnet pipeline configuration, and returns the
+// yaml configuration. The build and repository metadata are available as
+// external variables, such as std.extVar("build.branch"). The configuration
+// evaluates to a pipeline, or to a list of pipelines, which are converted to
+// yaml documents.
+//
+// The configuration is evaluated in process, without access to the
+// environment of the server, and imports are disabled so that the
+// configuration cannot read files of the server.
+func EvalJsonnet(repo *model.Repo, build *model.Build, data []byte) ([]byte, error) {
+	vm := jsonnet.MakeVM()
+	vm.Importer(&jsonnet.MemoryImporter{Data: map[string]jsonnet.Contents{}})
+	for _, v := range jsonnetVars(repo, build) {
+		parts := strings.SplitN(v, "=", 2)
+		vm.ExtVar(parts[0], parts[1])
+	}
+
+	type result struct {
+		out string
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		out, err := vm.EvaluateSnippet(".drone.jsonnet", string(data))
+		done <- result{out, err}
+	}()
+
+	select {
+	case res := <-done:
+		if res.err != nil {
+			return nil, fmt.Errorf("Error evaluating jsonnet configuration. %s", strings.TrimSpace(res.err.Error()))
+		}
+		return jsonToYaml([]byte(res.out))
+	case <-time.After(evalTimeout):
+		return nil, fmt.Errorf("Error evaluating jsonnet configuration. Timeout after %s", evalTimeout)
+	}
+}
+
+// helper function returns the external variables of the build, in the
+// key=value format.
+func jsonnetVars(repo *model.Repo, build *model.Build) []string {
+	return []string{
+		"build.event=" + build.Event,
+		"build.branch=" + build.Branch,
+		"build.commit=" + build.Commit,
+		"build.ref=" + build.Ref,
+		"build.message=" + build.Message,
+		"build.author=" + build.Author,
+		"build.sender=" + build.Sender,
+		"build.target=" + build.Deploy,
+		"repo.owner=" + repo.Owner,
+		"repo.name=" + repo.Name,
+		"repo.full_name=" + repo.FullName,
+		"repo.branch=" + repo.Branch,
+		"repo.private=" + strconv.FormatBool(repo.IsPrivate),
+	}
+}
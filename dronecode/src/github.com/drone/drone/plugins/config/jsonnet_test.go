@@ -0,0 +1,51 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/drone/drone/model"
+)
+
+func TestEvalJsonnet(t *testing.T) {
+	repo := &model.Repo{FullName: "octocat/hello-world"}
+	build := &model.Build{Event: model.EventPush, Branch: "master"}
+
+	out, err := EvalJsonnet(repo, build, []byte(`{name: std.extVar("build.branch")}`))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := string(out), "name: master\n"; got != want {
+		t.Errorf("Want yaml %q, got %q", want, got)
+	}
+
+	_, err = EvalJsonnet(repo, build, []byte(`{name: `))
+	if err == nil || !strings.HasPrefix(err.Error(), "Error evaluating jsonnet") {
+		t.Errorf("Want evaluation error, got %v", err)
+	}
+}
+
+func TestEvalJsonnetSandbox(t *testing.T) {
+	repo := &model.Repo{FullName: "octocat/hello-world"}
+	build := &model.Build{Event: model.EventPull}
+
+	os.Setenv("DRONE_JSONNET_SECRET", "correct-horse-battery-staple")
+	defer os.Unsetenv("DRONE_JSONNET_SECRET")
+
+	for _, config := range []string{
+		`{env: importstr "/proc/self/environ"}`,
+		`{passwd: importstr "/etc/passwd"}`,
+		`import "../config.libsonnet"`,
+		`{secret: std.extVar("DRONE_JSONNET_SECRET")}`,
+	} {
+		out, err := EvalJsonnet(repo, build, []byte(config))
+		if err == nil {
+			t.Errorf("Want error evaluating %s, got %q", config, out)
+		}
+		if strings.Contains(string(out), "correct-horse") {
+			t.Errorf("Want the environment of the server hidden from %s", config)
+		}
+	}
+}
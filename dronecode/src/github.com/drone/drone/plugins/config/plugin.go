@@ -0,0 +1,48 @@
+package config
+
+import (
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/plugins/internal"
+)
+
+type plugin struct {
+	endpoint string
+	secret   string
+}
+
+type request struct {
+	Repo  *model.Repo  `json:"repo"`
+	Build *model.Build `json:"build"`
+	Data  string       `json:"data"`
+}
+
+type response struct {
+	Data string `json:"data"`
+}
+
+// NewRemote returns a new remote configuration service. The build metadata
+// and the pipeline configuration fetched from the repository are posted to
+// the endpoint, which may return a generated or modified configuration.
+// Requests are signed with the shared secret.
+func NewRemote(endpoint, secret string) model.ConfigService {
+	return &plugin{endpoint, secret}
+}
+
+func (p *plugin) ConfigResolve(repo *model.Repo, build *model.Build, data []byte) ([]byte, error) {
+	in := &request{
+		Repo:  repo,
+		Build: build,
+		Data:  string(data),
+	}
+	out := new(response)
+	err := internal.SendSigned("POST", p.endpoint, p.secret, in, out)
+	if err != nil {
+		return nil, err
+	}
+	// the configuration is unchanged if the endpoint returns an empty
+	// response, for example a 204 status.
+	if out.Data == "" {
+		return data, nil
+	}
+	return []byte(out.Data), nil
+}
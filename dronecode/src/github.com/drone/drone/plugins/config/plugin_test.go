@@ -0,0 +1,59 @@
+package config
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/plugins/internal"
+)
+
+func TestResolve(t *testing.T) {
+	var signature string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		signature = internal.Sign(body, "correct-horse-battery-staple")
+		if r.Header.Get(internal.SignatureHeader) != signature {
+			w.WriteHeader(403)
+			return
+		}
+		in := new(request)
+		json.Unmarshal(body, in)
+		if in.Repo.FullName == "octocat/unchanged" {
+			w.WriteHeader(204)
+			return
+		}
+		json.NewEncoder(w).Encode(&response{Data: "pipeline: { build: { image: golang } }"})
+	}))
+	defer s.Close()
+
+	build := &model.Build{Event: model.EventPush}
+	data := []byte("pipeline: {}")
+
+	p := NewRemote(s.URL, "correct-horse-battery-staple")
+	out, err := p.ConfigResolve(&model.Repo{FullName: "octocat/hello-world"}, build, data)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := string(out), "pipeline: { build: { image: golang } }"; got != want {
+		t.Errorf("Want configuration %q, got %q", want, got)
+	}
+
+	out, err = p.ConfigResolve(&model.Repo{FullName: "octocat/unchanged"}, build, data)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := string(out), string(data); got != want {
+		t.Errorf("Want unchanged configuration %q, got %q", want, got)
+	}
+
+	p = NewRemote(s.URL, "invalid")
+	if _, err := p.ConfigResolve(&model.Repo{}, build, data); err == nil {
+		t.Errorf("Expect error when the request signature is invalid")
+	}
+}
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/drone/drone/model"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// starlarkMaxSteps limits the number of computation steps of a starlark
+// configuration.
+const starlarkMaxSteps = 10000000
+
+var (
+	errStarlarkMain = errors.New("main function not found")
+	errStarlarkLoad = errors.New("load statements are not supported")
+)
+
+// starlarkContext defines the context of the starlark configuration.
+type starlarkContext struct {
+	Repo  *model.Repo  `json:"repo"`
+	Build *model.Build `json:"build"`
+}
+
+// EvalStarlark evaluates the starlark pipeline configuration, and returns
+// the yaml configuration. The main function of the script is called with
+// the context, which provides the repository and build metadata, such as
+// ctx.build.branch, and returns a pipeline, or a list of pipelines, which
+// are converted to yaml documents.
+//
+// The configuration is evaluated in process. Load statements are not
+// supported, and the evaluation is cancelled once it exceeds the step limit
+// or the timeout.
+func EvalStarlark(repo *model.Repo, build *model.Build, data []byte) ([]byte, error) {
+	out, err := evalStarlark(repo, build, data)
+	if err != nil {
+		return nil, fmt.Errorf("Error evaluating starlark configuration. %s", err)
+	}
+	return jsonToYaml(out)
+}
+
+func evalStarlark(repo *model.Repo, build *model.Build, data []byte) ([]byte, error) {
+	thread := &starlark.Thread{
+		Name:  "drone",
+		Print: func(*starlark.Thread, string) {},
+		Load: func(*starlark.Thread, string) (starlark.StringDict, error) {
+			return nil, errStarlarkLoad
+		},
+	}
+	thread.SetMaxExecutionSteps(starlarkMaxSteps)
+	timer := time.AfterFunc(evalTimeout, func() {
+		thread.Cancel(fmt.Sprintf("timeout after %s", evalTimeout))
+	})
+	defer timer.Stop()
+
+	globals, err := starlark.ExecFile(thread, ".drone.star", data, nil)
+	if err != nil {
+		return nil, err
+	}
+	main, ok := globals["main"].(starlark.Callable)
+	if !ok {
+		return nil, errStarlarkMain
+	}
+
+	ctx, err := starlarkValue(&starlarkContext{Repo: repo, Build: build})
+	if err != nil {
+		return nil, err
+	}
+	res, err := starlark.Call(thread, main, starlark.Tuple{ctx}, nil)
+	if err != nil {
+		return nil, err
+	}
+	out, err := goValue(res)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(out)
+}
+
+// helper function converts the value to a starlark value, using the json
+// encoding of the value. Objects are converted to structs, so that the
+// fields are accessed as attributes.
+func starlarkValue(v interface{}) (starlark.Value, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var obj interface{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	return toStarlark(obj), nil
+}
+
+func toStarlark(v interface{}) starlark.Value {
+	switch v := v.(type) {
+	case bool:
+		return starlark.Bool(v)
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return starlark.MakeInt64(int64(v))
+		}
+		return starlark.Float(v)
+	case string:
+		return starlark.String(v)
+	case []interface{}:
+		list := make([]starlark.Value, len(v))
+		for i, item := range v {
+			list[i] = toStarlark(item)
+		}
+		return starlark.NewList(list)
+	case map[string]interface{}:
+		dict := starlark.StringDict{}
+		for k, item := range v {
+			dict[k] = toStarlark(item)
+		}
+		return starlarkstruct.FromStringDict(starlarkstruct.Default, dict)
+	}
+	return starlark.None
+}
+
+// helper function converts the starlark value returned by the main function
+// to a value that is encoded as json.
+func goValue(v starlark.Value) (interface{}, error) {
+	switch v := v.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case starlark.Bool:
+		return bool(v), nil
+	case starlark.Int:
+		if i, ok := v.Int64(); ok {
+			return i, nil
+		}
+		return v.String(), nil
+	case starlark.Float:
+		return float64(v), nil
+	case starlark.String:
+		return v.GoString(), nil
+	case starlark.Indexable:
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			item, err := goValue(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			list[i] = item
+		}
+		return list, nil
+	case *starlark.Dict:
+		obj := map[string]interface{}{}
+		for _, item := range v.Items() {
+			key, ok := item[0].(starlark.String)
+			if !ok {
+				return nil, fmt.Errorf("dict key %s is not a string", item[0])
+			}
+			value, err := goValue(item[1])
+			if err != nil {
+				return nil, err
+			}
+			obj[key.GoString()] = value
+		}
+		return obj, nil
+	case *starlarkstruct.Struct:
+		dict := starlark.StringDict{}
+		v.ToStringDict(dict)
+		obj := map[string]interface{}{}
+		for key, item := range dict {
+			value, err := goValue(item)
+			if err != nil {
+				return nil, err
+			}
+			obj[key] = value
+		}
+		return obj, nil
+	}
+	return nil, fmt.Errorf("cannot convert %s to json", v.Type())
+}
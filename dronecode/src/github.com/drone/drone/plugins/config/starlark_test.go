@@ -0,0 +1,77 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/drone/drone/model"
+)
+
+func TestEvalStarlark(t *testing.T) {
+	repo := &model.Repo{FullName: "octocat/hello-world"}
+	build := &model.Build{Event: model.EventPush, Branch: "master"}
+
+	out, err := EvalStarlark(repo, build, []byte(`
+def main(ctx):
+  return {
+    "pipeline": {
+      "build": {
+        "image": "golang",
+        "commands": ["echo " + ctx.repo.full_name + " " + ctx.build.branch],
+      },
+    },
+  }
+`))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := string(out), "pipeline:\n  build:\n    commands:\n    - echo octocat/hello-world master\n    image: golang\n"; got != want {
+		t.Errorf("Want yaml %q, got %q", want, got)
+	}
+
+	out, err = EvalStarlark(repo, build, []byte(`
+def main(ctx):
+  return [{"name": name} for name in ["test", "deploy"]]
+`))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := string(out), "name: test\n---\nname: deploy\n"; got != want {
+		t.Errorf("Want yaml documents %q, got %q", want, got)
+	}
+}
+
+func TestEvalStarlarkSandbox(t *testing.T) {
+	repo := &model.Repo{FullName: "octocat/hello-world"}
+	build := &model.Build{Event: model.EventPush, Branch: "master"}
+
+	tests := []struct {
+		data string
+		want string
+	}{
+		{
+			data: "load(\"@stdlib//json.star\", \"json\")\ndef main(ctx):\n  return {}\n",
+			want: errStarlarkLoad.Error(),
+		},
+		{
+			data: "def main(ctx):\n  for i in range(1000000000):\n    pass\n  return {}\n",
+			want: "too many steps",
+		},
+		{
+			data: "def build(ctx):\n  return {}\n",
+			want: errStarlarkMain.Error(),
+		},
+		{
+			data: "def main(ctx):\n  return {1: \"build\"}\n",
+			want: "is not a string",
+		},
+	}
+	for _, test := range tests {
+		_, err := EvalStarlark(repo, build, []byte(test.data))
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("Want error %q evaluating %q, got %v", test.want, test.data, err)
+		}
+	}
+}
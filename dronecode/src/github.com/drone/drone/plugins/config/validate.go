@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/plugins/internal"
+	"gopkg.in/yaml.v2"
+)
+
+type validator struct {
+	endpoint string
+	secret   string
+}
+
+type validateRequest struct {
+	Repo   *model.Repo  `json:"repo"`
+	Build  *model.Build `json:"build"`
+	Config interface{}  `json:"config"`
+	Data   string       `json:"data"`
+}
+
+// NewValidator returns a new remote validation service. The parsed pipeline
+// configuration and the build metadata are posted to the endpoint before
+// the build is queued, and the build is rejected if the endpoint responds
+// with an error status. The response body is used as the error message.
+// Requests are signed with the shared secret.
+func NewValidator(endpoint, secret string) model.ValidateService {
+	return &validator{endpoint, secret}
+}
+
+func (v *validator) ConfigValidate(repo *model.Repo, build *model.Build, data []byte) error {
+	var parsed interface{}
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		return err
+	}
+	in := &validateRequest{
+		Repo:   repo,
+		Build:  build,
+		Config: convertMap(parsed),
+		Data:   string(data),
+	}
+	err := internal.SendSigned("POST", v.endpoint, v.secret, in, nil)
+	if err, ok := err.(*internal.Error); ok {
+		return fmt.Errorf("Pipeline rejected by the validation service: %s", strings.TrimSpace(err.Error()))
+	}
+	return err
+}
+
+// helper function converts the yaml maps, which are keyed with interface
+// values, to maps keyed with strings so they can be encoded to json.
+func convertMap(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for k, v := range t {
+			m[fmt.Sprint(k)] = convertMap(v)
+		}
+		return m
+	case []interface{}:
+		for i, v := range t {
+			t[i] = convertMap(v)
+		}
+		return t
+	default:
+		return v
+	}
+}
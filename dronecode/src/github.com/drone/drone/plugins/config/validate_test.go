@@ -0,0 +1,51 @@
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/drone/drone/model"
+)
+
+func TestValidate(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := struct {
+			Config struct {
+				Pipeline map[string]struct {
+					Privileged bool `json:"privileged"`
+				} `json:"pipeline"`
+			} `json:"config"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			w.WriteHeader(400)
+			return
+		}
+		for name, step := range in.Config.Pipeline {
+			if step.Privileged {
+				w.WriteHeader(403)
+				w.Write([]byte("privileged step " + name + " is forbidden\n"))
+				return
+			}
+		}
+	}))
+	defer s.Close()
+
+	v := NewValidator(s.URL, "")
+	repo := &model.Repo{FullName: "octocat/hello-world"}
+	build := &model.Build{Event: model.EventPush}
+
+	err := v.ConfigValidate(repo, build, []byte("pipeline: { build: { image: golang } }"))
+	if err != nil {
+		t.Errorf("Want configuration accepted, got %s", err)
+	}
+
+	err = v.ConfigValidate(repo, build, []byte("pipeline: { build: { image: docker, privileged: true } }"))
+	if err == nil {
+		t.Errorf("Want configuration rejected")
+	} else if !strings.HasSuffix(err.Error(), "privileged step build is forbidden") {
+		t.Errorf("Want rejection message returned, got %q", err)
+	}
+}
@@ -0,0 +1,124 @@
+// Package email implements email notifications, which are sent with an
+// SMTP server.
+package email
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/drone/drone/model"
+)
+
+// Opts defines the SMTP server configuration.
+type Opts struct {
+	Host       string
+	Port       int
+	Username   string
+	Password   string
+	From       string
+	SkipVerify bool
+}
+
+type email struct {
+	opts    Opts
+	timeout time.Duration
+}
+
+// New returns an email service that sends email notifications with the
+// SMTP server. The connection is upgraded with STARTTLS if the server
+// supports it, and port 465 uses implicit TLS.
+func New(opts Opts) model.EmailService {
+	if opts.Port == 0 {
+		opts.Port = 587
+	}
+	return &email{
+		opts:    opts,
+		timeout: time.Second * 30,
+	}
+}
+
+// EmailSend sends the email to the recipients.
+func (e *email) EmailSend(m *model.Email) error {
+	if len(m.To) == 0 {
+		return nil
+	}
+	config := &tls.Config{
+		ServerName:         e.opts.Host,
+		InsecureSkipVerify: e.opts.SkipVerify,
+	}
+	addr := net.JoinHostPort(e.opts.Host, strconv.Itoa(e.opts.Port))
+	dialer := &net.Dialer{Timeout: e.timeout}
+
+	var conn net.Conn
+	var err error
+	if e.opts.Port == 465 {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, config)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(e.timeout))
+
+	client, err := smtp.NewClient(conn, e.opts.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(config); err != nil {
+			return err
+		}
+	}
+	if e.opts.Username != "" {
+		auth := smtp.PlainAuth("", e.opts.Username, e.opts.Password, e.opts.Host)
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(e.opts.From); err != nil {
+		return err
+	}
+	for _, to := range m.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message(e.opts.From, m)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// helper function returns the plain text message of the email.
+func message(from string, m *model.Email) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	body := strings.Replace(m.Body, "\r\n", "\n", -1)
+	buf.WriteString(strings.Replace(body, "\n", "\r\n", -1))
+	return buf.Bytes()
+}
@@ -0,0 +1,99 @@
+package email
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/drone/drone/model"
+)
+
+func TestEmailSend(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	var (
+		rcpts []string
+		data  []string
+		done  = make(chan struct{})
+	)
+	go func() {
+		defer close(done)
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimSpace(line)
+			switch {
+			case strings.HasPrefix(line, "EHLO"):
+				reply("250 localhost")
+			case strings.HasPrefix(line, "MAIL FROM:"):
+				reply("250 OK")
+			case strings.HasPrefix(line, "RCPT TO:"):
+				rcpts = append(rcpts, strings.TrimPrefix(line, "RCPT TO:"))
+				reply("250 OK")
+			case line == "DATA":
+				reply("354 Go ahead")
+				for {
+					line, _ := r.ReadString('\n')
+					line = strings.TrimRight(line, "\r\n")
+					if line == "." {
+						break
+					}
+					data = append(data, line)
+				}
+				reply("250 OK")
+			case line == "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("502 Not implemented")
+			}
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+	p, _ := strconv.Atoi(port)
+	e := New(Opts{Host: host, Port: p, From: "drone@localhost"})
+	err = e.EmailSend(&model.Email{
+		To:      []string{"octocat@github.com", "janedoe@github.com"},
+		Subject: "[octocat/hello-world] Failed build #1",
+		Body:    "Build #1 failed.\nhttp://drone.example.com/octocat/hello-world/1",
+	})
+	if err != nil {
+		t.Fatalf("Want email sent, got error %s", err)
+	}
+	<-done
+
+	if got, want := strings.Join(rcpts, ","), "<octocat@github.com>,<janedoe@github.com>"; got != want {
+		t.Errorf("Want recipients %s, got %s", want, got)
+	}
+	message := strings.Join(data, "\n")
+	if !strings.Contains(message, "Subject: [octocat/hello-world] Failed build #1\n") {
+		t.Errorf("Want subject header in message %q", message)
+	}
+	if !strings.HasSuffix(message, "\n\nBuild #1 failed.\nhttp://drone.example.com/octocat/hello-world/1") {
+		t.Errorf("Want body in message %q", message)
+	}
+}
+
+func TestEmailSendNoRecipients(t *testing.T) {
+	e := New(Opts{Host: "invalid.", From: "drone@localhost"})
+	if err := e.EmailSend(&model.Email{Subject: "test"}); err != nil {
+		t.Errorf("Want no error without recipients, got %s", err)
+	}
+}
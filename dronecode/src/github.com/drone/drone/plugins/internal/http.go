@@ -2,6 +2,9 @@ package internal
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"io"
 	"io/ioutil"
@@ -9,9 +12,21 @@ import (
 	"net/url"
 )
 
+// SignatureHeader is the http header used to send the hex encoded hmac
+// sha256 signature of the request body, when the request is signed.
+const SignatureHeader = "X-Drone-Signature"
+
 // Send makes an http request to the given endpoint, writing the input
 // to the request body and unmarshaling the output from the response body.
 func Send(method, path string, in, out interface{}) error {
+	return SendSigned(method, path, "", in, out)
+}
+
+// SendSigned makes an http request to the given endpoint, like Send, and
+// signs the request body with the shared secret so that the endpoint can
+// authenticate the request. The request is not signed if the secret is
+// empty.
+func SendSigned(method, path, secret string, in, out interface{}) error {
 	uri, err := url.Parse(path)
 	if err != nil {
 		return err
@@ -19,7 +34,7 @@ func Send(method, path string, in, out interface{}) error {
 
 	// if we are posting or putting data, we need to
 	// write it to the body of the request.
-	var buf io.ReadWriter
+	var buf *bytes.Buffer
 	if in != nil {
 		buf = new(bytes.Buffer)
 		jsonerr := json.NewEncoder(buf).Encode(in)
@@ -27,15 +42,26 @@ func Send(method, path string, in, out interface{}) error {
 			return jsonerr
 		}
 	}
+	var body io.Reader
+	if buf != nil {
+		body = buf
+	}
 
 	// creates a new http request to bitbucket.
-	req, err := http.NewRequest(method, uri.String(), buf)
+	req, err := http.NewRequest(method, uri.String(), body)
 	if err != nil {
 		return err
 	}
 	if in != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
+	if secret != "" {
+		var data []byte
+		if buf != nil {
+			data = buf.Bytes()
+		}
+		req.Header.Set(SignatureHeader, Sign(data, secret))
+	}
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
@@ -57,14 +83,22 @@ func Send(method, path string, in, out interface{}) error {
 	}
 
 	// if a json response is expected, parse and return
-	// the json response.
-	if out != nil {
+	// the json response. A no content response leaves the
+	// output unchanged.
+	if out != nil && resp.StatusCode != http.StatusNoContent {
 		return json.NewDecoder(resp.Body).Decode(out)
 	}
 
 	return nil
 }
 
+// Sign returns the hex encoded hmac sha256 signature of the data.
+func Sign(data []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
 // Error represents a http error.
 type Error struct {
 	code int
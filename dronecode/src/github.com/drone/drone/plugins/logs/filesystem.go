@@ -0,0 +1,41 @@
+package logs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+type filesystem struct {
+	root string
+}
+
+// NewFilesystem returns blob storage that writes each blob to a file in
+// the root directory.
+func NewFilesystem(root string) Blob {
+	return &filesystem{root}
+}
+
+func (f *filesystem) Put(key string, data []byte) error {
+	path := filepath.Join(f.root, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+func (f *filesystem) Get(key string) ([]byte, error) {
+	data, err := ioutil.ReadFile(filepath.Join(f.root, filepath.FromSlash(key)))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (f *filesystem) Delete(key string) error {
+	err := os.Remove(filepath.Join(f.root, filepath.FromSlash(key)))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
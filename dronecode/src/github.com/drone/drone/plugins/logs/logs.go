@@ -0,0 +1,190 @@
+// Package logs stores the build logs, artifacts and pipeline caches in blob
+// storage, such as Amazon S3 or the filesystem, instead of the database.
+package logs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"path"
+	"strconv"
+
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/store"
+)
+
+// ErrNotFound is returned when the blob does not exist.
+var ErrNotFound = errors.New("logs: blob not found")
+
+// Blob defines blob storage for build logs.
+type Blob interface {
+	// Put writes the data to the key.
+	Put(key string, data []byte) error
+
+	// Get returns the data of the key, or ErrNotFound.
+	Get(key string) ([]byte, error)
+
+	// Delete deletes the data of the key.
+	Delete(key string) error
+}
+
+type logStore struct {
+	store.Store
+	blob Blob
+}
+
+// New returns a store that saves the build logs, artifacts and pipeline
+// caches to blob storage, keeping only the artifact and cache metadata in
+// the database. Logs, artifacts and caches saved to the database before the
+// blob storage was configured are read from the database.
+func New(s store.Store, blob Blob) store.Store {
+	return &logStore{s, blob}
+}
+
+func (s *logStore) LogFind(proc *model.Proc) (io.ReadCloser, error) {
+	data, err := s.blob.Get(key(proc))
+	if err == ErrNotFound {
+		return s.Store.LogFind(proc)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *logStore) LogTail(proc *model.Proc, lines int) (io.ReadCloser, error) {
+	data, err := s.blob.Get(key(proc))
+	if err == ErrNotFound {
+		return s.Store.LogTail(proc, lines)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if lines > 0 {
+		data = model.TailLogs(data, lines)
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *logStore) LogSave(proc *model.Proc, r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if err := s.blob.Put(key(proc), data); err != nil {
+		return err
+	}
+	// an empty log is saved to the database, so that the log is pruned
+	// with the logs of the database.
+	return s.Store.LogSave(proc, bytes.NewReader(nil))
+}
+
+// LogPrune deletes the logs of the steps completed before the time, and
+// the log blobs.
+func (s *logStore) LogPrune(before int64) (int64, error) {
+	procs, err := s.Store.LogPruneList(before)
+	if err != nil {
+		return 0, err
+	}
+	pruned, err := s.Store.LogPrune(before)
+	if err != nil {
+		return 0, err
+	}
+	for _, proc := range procs {
+		if err := s.blob.Delete(key(proc)); err != nil {
+			return pruned, err
+		}
+	}
+	return pruned, nil
+}
+
+// BuildPrune deletes the expired builds, and the log and artifact blobs of
+// the builds.
+func (s *logStore) BuildPrune(keep int) (int64, error) {
+	procs, files, err := s.Store.BuildPruneList(keep)
+	if err != nil {
+		return 0, err
+	}
+	pruned, err := s.Store.BuildPrune(keep)
+	if err != nil {
+		return 0, err
+	}
+	for _, proc := range procs {
+		if err := s.blob.Delete(key(proc)); err != nil {
+			return pruned, err
+		}
+	}
+	for _, file := range files {
+		if err := s.blob.Delete(fileKey(file.ProcID, file.Name)); err != nil {
+			return pruned, err
+		}
+	}
+	return pruned, nil
+}
+
+func (s *logStore) FileRead(proc *model.Proc, name string) (io.ReadCloser, error) {
+	data, err := s.blob.Get(fileKey(proc.ID, name))
+	if err == ErrNotFound {
+		return s.Store.FileRead(proc, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *logStore) FileCreate(file *model.File, r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if err := s.blob.Put(fileKey(file.ProcID, file.Name), data); err != nil {
+		return err
+	}
+	return s.Store.FileCreate(file, bytes.NewReader(nil))
+}
+
+func (s *logStore) FileDelete(file *model.File) error {
+	if err := s.blob.Delete(fileKey(file.ProcID, file.Name)); err != nil {
+		return err
+	}
+	return s.Store.FileDelete(file)
+}
+
+func (s *logStore) CacheRead(cache *model.Cache) (io.ReadCloser, error) {
+	data, err := s.blob.Get(cacheKey(cache))
+	if err == ErrNotFound {
+		return s.Store.CacheRead(cache)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *logStore) CacheCreate(cache *model.Cache, r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if err := s.blob.Put(cacheKey(cache), data); err != nil {
+		return err
+	}
+	return s.Store.CacheCreate(cache, bytes.NewReader(nil))
+}
+
+// helper function returns the blob key of the proc logs.
+func key(proc *model.Proc) string {
+	return strconv.FormatInt(proc.ID, 10)
+}
+
+// helper function returns the blob key of the pipeline cache.
+func cacheKey(cache *model.Cache) string {
+	return path.Join("cache", strconv.FormatInt(cache.RepoID, 10), path.Clean("/"+cache.Key))
+}
+
+// helper function returns the blob key of the proc artifact.
+func fileKey(proc int64, name string) string {
+	return path.Join("artifacts", strconv.FormatInt(proc, 10), path.Clean("/"+name))
+}
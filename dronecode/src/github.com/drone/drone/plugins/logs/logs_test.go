@@ -0,0 +1,223 @@
+package logs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/shared/aws"
+	"github.com/drone/drone/store/datastore"
+)
+
+func TestFilesystem(t *testing.T) {
+	root, err := ioutil.TempDir("", "drone-logs")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(root)
+
+	blob := NewFilesystem(root)
+	if _, err := blob.Get("1"); err != ErrNotFound {
+		t.Errorf("Want ErrNotFound for missing blob, got %v", err)
+	}
+	if err := blob.Put("1", []byte("hello world")); err != nil {
+		t.Error(err)
+		return
+	}
+	data, err := blob.Get("1")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := string(data), "hello world"; got != want {
+		t.Errorf("Want blob %q, got %q", want, got)
+	}
+
+	if err := blob.Put("artifacts/1/dist/app.tar", []byte("tar")); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := blob.Delete("artifacts/1/dist/app.tar"); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := blob.Get("artifacts/1/dist/app.tar"); err != ErrNotFound {
+		t.Errorf("Want ErrNotFound for deleted blob, got %v", err)
+	}
+}
+
+func TestFileKey(t *testing.T) {
+	if got, want := fileKey(1, "../../etc/passwd"), "artifacts/1/etc/passwd"; got != want {
+		t.Errorf("Want key %q, got %q", want, got)
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	cache := &model.Cache{RepoID: 1, Key: "../go-abc"}
+	if got, want := cacheKey(cache), "cache/1/go-abc"; got != want {
+		t.Errorf("Want key %q, got %q", want, got)
+	}
+}
+
+func TestS3(t *testing.T) {
+	objects := map[string][]byte{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			w.WriteHeader(403)
+			return
+		}
+		switch r.Method {
+		case "PUT":
+			objects[r.URL.Path], _ = ioutil.ReadAll(r.Body)
+		case "GET":
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(404)
+				return
+			}
+			w.Write(data)
+		}
+	}))
+	defer s.Close()
+
+	client := aws.New("us-east-1", aws.Credentials{AccessKey: "key", SecretKey: "secret"})
+	client.Endpoint = s.URL
+	blob := NewS3(aws.NewS3(client, "bucket"), "logs")
+
+	if _, err := blob.Get("1"); err != ErrNotFound {
+		t.Errorf("Want ErrNotFound for missing blob, got %v", err)
+	}
+	if err := blob.Put("1", []byte("hello world")); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, ok := objects["/bucket/logs/1"]; !ok {
+		t.Errorf("Want object uploaded to /bucket/logs/1")
+	}
+	data, err := blob.Get("1")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := string(data), "hello world"; got != want {
+		t.Errorf("Want blob %q, got %q", want, got)
+	}
+}
+
+func TestUploads(t *testing.T) {
+	objects := map[string][]byte{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" && r.URL.Query().Get("X-Amz-Signature") == "" {
+			w.WriteHeader(403)
+			return
+		}
+		switch r.Method {
+		case "PUT":
+			objects[r.URL.Path], _ = ioutil.ReadAll(r.Body)
+		case "GET":
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(404)
+				return
+			}
+			w.Write(data)
+		}
+	}))
+	defer s.Close()
+
+	client := aws.New("us-east-1", aws.Credentials{AccessKey: "key", SecretKey: "secret"})
+	client.Endpoint = s.URL
+	bucket := aws.NewS3(client, "bucket")
+	store := New(datastore.New("sqlite3", ":memory:"), NewS3(bucket, "logs"))
+
+	proc := &model.Proc{PID: 2, PPID: 1, Name: "build"}
+	if err := store.CreateBuild(&model.Build{RepoID: 1}, proc); err != nil {
+		t.Fatal(err)
+	}
+	link, err := NewUploads(store, bucket, "logs", time.Hour).UploadLogs(proc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(link, s.URL+"/bucket/logs/") {
+		t.Errorf("Want the url of the log in the bucket, got %s", link)
+	}
+
+	// the agent uploads the logs to the url without credentials.
+	req, _ := http.NewRequest("PUT", link, strings.NewReader("hello world"))
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	rc, err := store.LogFind(proc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadAll(rc)
+	rc.Close()
+	if got, want := string(data), "hello world"; got != want {
+		t.Errorf("Want the uploaded log %q, got %q", want, got)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	root, err := ioutil.TempDir("", "drone-logs")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(root)
+
+	blob := NewFilesystem(root)
+	s := New(datastore.New("sqlite3", ":memory:"), blob)
+
+	var procs []*model.Proc
+	for i := 0; i < 2; i++ {
+		proc := &model.Proc{PID: 1, State: model.StatusSuccess, Stopped: 100}
+		build := &model.Build{RepoID: 1, Status: model.StatusSuccess}
+		if err := s.CreateBuild(build, proc); err != nil {
+			t.Error(err)
+			return
+		}
+		s.LogSave(proc, bytes.NewBufferString("hello world"))
+		s.FileCreate(&model.File{BuildID: build.ID, ProcID: proc.ID, Name: "app.tar"}, bytes.NewBufferString("tar"))
+		procs = append(procs, proc)
+	}
+
+	if _, err := s.BuildPrune(1); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := blob.Get(key(procs[0])); err != ErrNotFound {
+		t.Errorf("Want log blob of the pruned build deleted, got %v", err)
+	}
+	if _, err := blob.Get(fileKey(procs[0].ID, "app.tar")); err != ErrNotFound {
+		t.Errorf("Want artifact blob of the pruned build deleted, got %v", err)
+	}
+	if _, err := blob.Get(key(procs[1])); err != nil {
+		t.Errorf("Want log blob of the kept build, got %v", err)
+	}
+
+	pruned, err := s.LogPrune(200)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if pruned != 1 {
+		t.Errorf("Want 1 pruned log, got %d", pruned)
+	}
+	if _, err := blob.Get(key(procs[1])); err != ErrNotFound {
+		t.Errorf("Want pruned log blob deleted, got %v", err)
+	}
+	if _, err := blob.Get(fileKey(procs[1].ID, "app.tar")); err != nil {
+		t.Errorf("Want artifact blob of the kept build, got %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+package logs
+
+import (
+	"path"
+
+	"github.com/drone/drone/shared/aws"
+)
+
+type s3 struct {
+	client *aws.S3
+	prefix string
+}
+
+// NewS3 returns blob storage that uploads each blob to the S3 bucket, with
+// the key prefixed by prefix.
+func NewS3(client *aws.S3, prefix string) Blob {
+	return &s3{client, prefix}
+}
+
+func (s *s3) Put(key string, data []byte) error {
+	return s.client.PutObject(path.Join(s.prefix, key), data)
+}
+
+func (s *s3) Get(key string) ([]byte, error) {
+	data, err := s.client.GetObject(path.Join(s.prefix, key))
+	if err, ok := err.(*aws.Error); ok && err.Code == 404 {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (s *s3) Delete(key string) error {
+	return s.client.DeleteObject(path.Join(s.prefix, key))
+}
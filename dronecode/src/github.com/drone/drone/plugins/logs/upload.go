@@ -0,0 +1,36 @@
+package logs
+
+import (
+	"bytes"
+	"path"
+	"time"
+
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/shared/aws"
+	"github.com/drone/drone/store"
+)
+
+type uploads struct {
+	store   store.Store
+	client  *aws.S3
+	prefix  string
+	expires time.Duration
+}
+
+// NewUploads returns an upload service that pre-signs the upload urls of
+// the step logs in the S3 bucket, with the key prefixed by prefix. The urls
+// expire after the duration. The store must save the logs to the same
+// bucket and prefix.
+func NewUploads(s store.Store, client *aws.S3, prefix string, expires time.Duration) model.UploadService {
+	return &uploads{s, client, prefix, expires}
+}
+
+func (u *uploads) UploadLogs(proc *model.Proc) (string, error) {
+	link, err := u.client.PresignPut(path.Join(u.prefix, key(proc)), u.expires)
+	if err != nil {
+		return "", err
+	}
+	// an empty log is saved, so that the uploaded log is pruned with the
+	// logs of the database.
+	return link, u.store.LogSave(proc, bytes.NewReader(nil))
+}
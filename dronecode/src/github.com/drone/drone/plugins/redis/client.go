@@ -0,0 +1,295 @@
+// Package redis implements the build queue, the event pubsub and the log
+// streams on top of a Redis server, so that multiple server replicas can
+// share the same state and agents can connect to any replica.
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNil is returned when the reply is a nil bulk string.
+var ErrNil = errors.New("redis: nil reply")
+
+// Error represents an error reply from the Redis server.
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// Client is a minimal Redis client that speaks the RESP protocol and
+// maintains a small pool of connections.
+type Client struct {
+	addr     string
+	password string
+	db       int
+	timeout  time.Duration
+	pool     chan *conn
+}
+
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// New returns a client for the Redis server at the url, for example
+// redis://:password@localhost:6379/0.
+func New(rawurl string) (*Client, error) {
+	uri, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if uri.Scheme != "redis" {
+		return nil, fmt.Errorf("redis: invalid url scheme %q", uri.Scheme)
+	}
+	c := &Client{
+		addr:    uri.Host,
+		timeout: time.Second * 10,
+		pool:    make(chan *conn, 10),
+	}
+	if _, _, err := net.SplitHostPort(c.addr); err != nil {
+		c.addr = net.JoinHostPort(c.addr, "6379")
+	}
+	if uri.User != nil {
+		c.password, _ = uri.User.Password()
+	}
+	if path := strings.Trim(uri.Path, "/"); path != "" {
+		c.db, err = strconv.Atoi(path)
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid database %q", path)
+		}
+	}
+	return c, nil
+}
+
+// Do sends the command to the server and returns the reply.
+func (c *Client) Do(args ...string) (interface{}, error) {
+	cn, err := c.get()
+	if err != nil {
+		return nil, err
+	}
+	cn.SetDeadline(time.Now().Add(c.timeout))
+	reply, err := cn.do(args...)
+	if _, ok := err.(Error); err != nil && !ok {
+		cn.Close()
+		return nil, err
+	}
+	c.put(cn)
+	return reply, err
+}
+
+// Int sends the command to the server and returns the integer reply.
+func (c *Client) Int(args ...string) (int64, error) {
+	reply, err := c.Do(args...)
+	if err != nil {
+		return 0, err
+	}
+	switch v := reply.(type) {
+	case int64:
+		return v, nil
+	case []byte:
+		return strconv.ParseInt(string(v), 10, 64)
+	case nil:
+		return 0, ErrNil
+	}
+	return 0, fmt.Errorf("redis: unexpected reply type %T", reply)
+}
+
+// Bytes sends the command to the server and returns the bulk string reply.
+func (c *Client) Bytes(args ...string) ([]byte, error) {
+	reply, err := c.Do(args...)
+	if err != nil {
+		return nil, err
+	}
+	switch v := reply.(type) {
+	case []byte:
+		return v, nil
+	case nil:
+		return nil, ErrNil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply type %T", reply)
+}
+
+// Strings sends the command to the server and returns the array reply as
+// a list of strings. Nil elements are returned as empty strings.
+func (c *Client) Strings(args ...string) ([]string, error) {
+	reply, err := c.Do(args...)
+	if err != nil {
+		return nil, err
+	}
+	values, ok := reply.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("redis: unexpected reply type %T", reply)
+	}
+	out := make([]string, len(values))
+	for i, value := range values {
+		if b, ok := value.([]byte); ok {
+			out[i] = string(b)
+		}
+	}
+	return out, nil
+}
+
+// Subscribe subscribes to the channel on a dedicated connection and calls
+// the function for each message, until the context is cancelled or the
+// connection is closed.
+func (c *Client) Subscribe(ctx context.Context, channel string, fn func([]byte)) error {
+	return c.subscribe(ctx, channel, func() {}, fn)
+}
+
+// helper function subscribes to the channel and calls the ready function
+// once the server confirms the subscription.
+func (c *Client) subscribe(ctx context.Context, channel string, ready func(), fn func([]byte)) error {
+	cn, err := c.dial()
+	if err != nil {
+		return err
+	}
+	defer cn.Close()
+
+	if err := writeCommand(cn, "SUBSCRIBE", channel); err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		cn.Close()
+	}()
+	for {
+		reply, err := readReply(cn.r)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		values, ok := reply.([]interface{})
+		if !ok || len(values) != 3 {
+			continue
+		}
+		kind, _ := values[0].([]byte)
+		if string(kind) == "subscribe" {
+			ready()
+			continue
+		}
+		if string(kind) != "message" {
+			continue
+		}
+		if data, ok := values[2].([]byte); ok {
+			fn(data)
+		}
+	}
+}
+
+func (c *Client) get() (*conn, error) {
+	select {
+	case cn := <-c.pool:
+		return cn, nil
+	default:
+		return c.dial()
+	}
+}
+
+func (c *Client) put(cn *conn) {
+	cn.SetDeadline(time.Time{})
+	select {
+	case c.pool <- cn:
+	default:
+		cn.Close()
+	}
+}
+
+func (c *Client) dial() (*conn, error) {
+	nc, err := net.DialTimeout("tcp", c.addr, c.timeout)
+	if err != nil {
+		return nil, err
+	}
+	cn := &conn{Conn: nc, r: bufio.NewReader(nc)}
+	if c.password != "" {
+		if _, err := cn.do("AUTH", c.password); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	if c.db != 0 {
+		if _, err := cn.do("SELECT", strconv.Itoa(c.db)); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	return cn, nil
+}
+
+func (cn *conn) do(args ...string) (interface{}, error) {
+	if err := writeCommand(cn, args...); err != nil {
+		return nil, err
+	}
+	return readReply(cn.r)
+}
+
+// helper function writes the command as an array of bulk strings.
+func writeCommand(w io.Writer, args ...string) error {
+	buf := make([]byte, 0, 64)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// helper function reads a single reply. Error replies are returned as an
+// Error, and nil bulk strings and arrays are returned as nil.
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if len(line) == 0 {
+		return nil, errors.New("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, Error(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		values := make([]interface{}, n)
+		for i := range values {
+			values[i], err = readReply(r)
+			if _, ok := err.(Error); err != nil && !ok {
+				return nil, err
+			}
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
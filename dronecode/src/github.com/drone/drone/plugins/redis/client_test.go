@@ -0,0 +1,65 @@
+package redis
+
+import (
+	"bufio"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	c, err := New("redis://:password@localhost/2")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := c.addr, "localhost:6379"; got != want {
+		t.Errorf("Want address %q, got %q", want, got)
+	}
+	if got, want := c.password, "password"; got != want {
+		t.Errorf("Want password %q, got %q", want, got)
+	}
+	if got, want := c.db, 2; got != want {
+		t.Errorf("Want database %d, got %d", want, got)
+	}
+
+	if _, err := New("http://localhost"); err == nil {
+		t.Errorf("Want error for invalid url scheme")
+	}
+	if _, err := New("redis://localhost/foo"); err == nil {
+		t.Errorf("Want error for invalid database")
+	}
+}
+
+func TestWriteCommand(t *testing.T) {
+	var buf bytes.Buffer
+	writeCommand(&buf, "SET", "foo", "bar")
+	if got, want := buf.String(), "*3\r\n$3\r\nSET\r\n$3\r\nfoo\r\n$3\r\nbar\r\n"; got != want {
+		t.Errorf("Want command %q, got %q", want, got)
+	}
+}
+
+func TestReadReply(t *testing.T) {
+	tests := []struct {
+		data  string
+		reply interface{}
+		err   error
+	}{
+		{data: "+OK\r\n", reply: "OK"},
+		{data: "-ERR unknown command\r\n", err: Error("ERR unknown command")},
+		{data: ":42\r\n", reply: int64(42)},
+		{data: "$3\r\nfoo\r\n", reply: []byte("foo")},
+		{data: "$-1\r\n", reply: nil},
+		{data: "*2\r\n$3\r\nfoo\r\n$-1\r\n", reply: []interface{}{[]byte("foo"), nil}},
+	}
+	for _, test := range tests {
+		reply, err := readReply(bufio.NewReader(strings.NewReader(test.data)))
+		if err != test.err {
+			t.Errorf("Want error %v for %q, got %v", test.err, test.data, err)
+		}
+		if !reflect.DeepEqual(reply, test.reply) {
+			t.Errorf("Want reply %#v for %q, got %#v", test.reply, test.data, reply)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package redis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/cncd/logging"
+)
+
+const (
+	keyLog     = "drone:logs:"
+	keyLogOpen = "drone:logs:open:"
+)
+
+// logExpiry is the number of seconds the log entries are kept after the
+// log is opened, in case the log is never closed.
+const logExpiry = "86400"
+
+type redisLog struct {
+	client *Client
+}
+
+// NewLogs returns a log multiplexer that stores the log entries in Redis,
+// so that logs written by an agent connected to one server can be tailed
+// from any server. Entries are kept until the log is closed.
+func NewLogs(client *Client) logging.Log {
+	return &redisLog{client}
+}
+
+// Open opens the log.
+func (l *redisLog) Open(c context.Context, path string) error {
+	_, err := l.client.Do("SET", keyLogOpen+path, "1", "EX", logExpiry)
+	return err
+}
+
+// Write writes the entry to the log and notifies the subscribers. Each
+// notification is prefixed with the position of the entry, so that entries
+// already read from the history are not sent twice.
+func (l *redisLog) Write(c context.Context, path string, entry *logging.Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	n, err := l.client.Int("RPUSH", keyLog+path, string(data))
+	if err != nil {
+		return err
+	}
+	if n == 1 {
+		l.client.Do("EXPIRE", keyLog+path, logExpiry)
+	}
+	_, err = l.client.Do("PUBLISH", keyLog+path, strconv.FormatInt(n, 10)+":"+string(data))
+	return err
+}
+
+// Tail tails the log until the log is closed or the context is cancelled.
+func (l *redisLog) Tail(c context.Context, path string, handler logging.Handler) error {
+	open, err := l.client.Int("EXISTS", keyLogOpen+path)
+	if err != nil {
+		return err
+	}
+	if open == 0 {
+		return logging.ErrNotFound
+	}
+
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	entries := make(chan []byte, 100)
+	ready := make(chan struct{})
+	errc := make(chan error, 1)
+	go func() {
+		errc <- l.client.subscribe(ctx, keyLog+path, func() { close(ready) }, func(data []byte) {
+			select {
+			case entries <- data:
+			case <-ctx.Done():
+			}
+		})
+	}()
+
+	// the history is read once the subscription is confirmed, so that no
+	// entries are missed in between.
+	select {
+	case <-ready:
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		return nil
+	}
+
+	hist, err := l.entries(path)
+	if err != nil {
+		return err
+	}
+	if len(hist) != 0 {
+		handler(hist...)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errc:
+			return err
+		case data := <-entries:
+			i := bytes.IndexByte(data, ':')
+			if i == -1 {
+				continue
+			}
+			n, _ := strconv.Atoi(string(data[:i]))
+			if n == 0 {
+				return nil // the log is closed
+			}
+			if n <= len(hist) {
+				continue
+			}
+			entry := new(logging.Entry)
+			if err := json.Unmarshal(data[i+1:], entry); err == nil {
+				handler(entry)
+			}
+		}
+	}
+}
+
+// Close closes the log, removing the log entries and notifying the
+// subscribers.
+func (l *redisLog) Close(c context.Context, path string) error {
+	removed, err := l.client.Int("DEL", keyLogOpen+path)
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return logging.ErrNotFound
+	}
+	if _, err := l.client.Do("PUBLISH", keyLog+path, "0:"); err != nil {
+		return err
+	}
+	_, err = l.client.Do("DEL", keyLog+path)
+	return err
+}
+
+// Snapshot snapshots the log entries to Writer w.
+func (l *redisLog) Snapshot(c context.Context, path string, w io.Writer) error {
+	open, err := l.client.Int("EXISTS", keyLogOpen+path)
+	if err != nil {
+		return err
+	}
+	if open == 0 {
+		return logging.ErrNotFound
+	}
+	entries, err := l.entries(path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		w.Write(entry.Data)
+		w.Write([]byte{'\n'})
+	}
+	return nil
+}
+
+// helper function returns the log entries written to the log.
+func (l *redisLog) entries(path string) ([]*logging.Entry, error) {
+	values, err := l.client.Strings("LRANGE", keyLog+path, "0", "-1")
+	if err != nil {
+		return nil, err
+	}
+	var entries []*logging.Entry
+	for _, value := range values {
+		entry := new(logging.Entry)
+		if err := json.Unmarshal([]byte(value), entry); err == nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
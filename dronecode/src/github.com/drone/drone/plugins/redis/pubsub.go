@@ -0,0 +1,52 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/cncd/pubsub"
+)
+
+const keyTopic = "drone:pubsub:"
+
+type redisPubsub struct {
+	client *Client
+}
+
+// NewPubsub returns a publisher that publishes messages to the subscribers
+// connected to any server.
+func NewPubsub(client *Client) pubsub.Publisher {
+	return &redisPubsub{client}
+}
+
+// Create creates the named topic. Redis channels do not need to be
+// created, so this is a no-op.
+func (p *redisPubsub) Create(c context.Context, topic string) error {
+	return nil
+}
+
+// Publish publishes the message.
+func (p *redisPubsub) Publish(c context.Context, topic string, message pubsub.Message) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	_, err = p.client.Do("PUBLISH", keyTopic+topic, string(data))
+	return err
+}
+
+// Subscribe subscribes to the topic until the context is cancelled.
+func (p *redisPubsub) Subscribe(c context.Context, topic string, receiver pubsub.Receiver) error {
+	return p.client.Subscribe(c, keyTopic+topic, func(data []byte) {
+		var message pubsub.Message
+		if err := json.Unmarshal(data, &message); err == nil {
+			receiver(message)
+		}
+	})
+}
+
+// Remove removes the named topic. Subscribers are not notified, so this is
+// a no-op.
+func (p *redisPubsub) Remove(c context.Context, topic string) error {
+	return nil
+}
@@ -0,0 +1,371 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/cncd/queue"
+)
+
+const (
+	keyTasks   = "drone:queue:tasks"
+	keyPending = "drone:queue:pending"
+	keyRunning = "drone:queue:running"
+	keyPaused  = "drone:queue:paused"
+	keySeq     = "drone:queue:seq"
+	keyVersion = "drone:queue:version"
+	keyError   = "drone:queue:error:"
+)
+
+// pollPage is the number of pending tasks loaded at a time when polling,
+// so that the pending set is scanned until the first task that matches.
+const pollPage = 100
+
+// claimScript atomically moves the task from the pending set to the running
+// hash, and returns 0 if the task was claimed by another server.
+const claimScript = `
+if redis.call("ZREM", KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call("HSET", KEYS[2], ARGV[1], ARGV[2])
+return 1
+`
+
+// priorityWeight separates the priority classes in the pending set, which
+// is ordered by priority and then by the order the tasks were pushed.
+const priorityWeight = 1e12
+
+type redisQueue struct {
+	client    *Client
+	limit     queue.Limit
+	interval  time.Duration
+	extension time.Duration
+	workers   int64
+}
+
+// NewQueue returns a queue that stores the pending and running tasks in
+// Redis. Workers poll the pending tasks, so that tasks pushed to one server
+// are dispatched to agents connected to any server. Pending tasks are held
+// while the limit is exceeded by the running tasks.
+func NewQueue(client *Client, limit queue.Limit) queue.Queue {
+	return &redisQueue{
+		client:    client,
+		limit:     limit,
+		interval:  time.Second,
+		extension: time.Minute * 10,
+	}
+}
+
+// Push pushes a task to the tail of this queue, ahead of any pending tasks
+// with a lower priority.
+func (q *redisQueue) Push(c context.Context, task *queue.Task) error {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	if _, err := q.client.Do("HSET", keyTasks, task.ID, string(data)); err != nil {
+		return err
+	}
+	seq, err := q.client.Int("INCR", keySeq)
+	if err != nil {
+		return err
+	}
+	if _, err := q.client.Do("ZADD", keyPending, score(task, seq), task.ID); err != nil {
+		return err
+	}
+	return q.changed()
+}
+
+// Poll retrieves and removes the head of this queue that matches the
+// filter, waiting until a task is available or the context is cancelled.
+func (q *redisQueue) Poll(c context.Context, f queue.Filter) (*queue.Task, error) {
+	atomic.AddInt64(&q.workers, 1)
+	defer atomic.AddInt64(&q.workers, -1)
+
+	// the pending tasks are scanned again only once the queue changed, for
+	// example when a task is pushed or completed.
+	seen := int64(-1)
+	for {
+		task, version, err := q.next(f, seen)
+		if err != nil {
+			return nil, err
+		}
+		seen = version
+		if task != nil {
+			return task, nil
+		}
+		select {
+		case <-c.Done():
+			return nil, nil
+		case <-time.After(q.interval):
+		}
+	}
+}
+
+// helper function that claims the first pending task that matches the
+// filter, and returns the version of the queue that was scanned. The
+// pending tasks are not scanned if the version matches the version of the
+// previous scan. The task is claimed by the server that removes it from the
+// pending set.
+func (q *redisQueue) next(f queue.Filter, seen int64) (*queue.Task, int64, error) {
+	if err := q.expire(); err != nil {
+		return nil, seen, err
+	}
+	paused, err := q.client.Int("EXISTS", keyPaused)
+	if err != nil || paused != 0 {
+		return nil, seen, err
+	}
+	version, err := q.client.Int("GET", keyVersion)
+	if err == ErrNil {
+		version, err = 0, nil
+	}
+	if err != nil || version == seen {
+		return nil, seen, err
+	}
+
+	var running []*queue.Task
+	if q.limit != nil {
+		if running, err = q.tasks(keyRunning); err != nil {
+			return nil, seen, err
+		}
+	}
+	for start := 0; ; start += pollPage {
+		ids, err := q.client.Strings("ZRANGE", keyPending, strconv.Itoa(start), strconv.Itoa(start+pollPage-1))
+		if err != nil {
+			return nil, seen, err
+		}
+		pending, err := q.load(ids)
+		if err != nil {
+			return nil, seen, err
+		}
+		for _, task := range pending {
+			if q.limit != nil && q.limit(task, running) {
+				continue
+			}
+			if !f(task) {
+				continue
+			}
+			claimed, err := q.claim(task.ID)
+			if err != nil {
+				return nil, seen, err
+			}
+			if claimed {
+				return task, version, nil
+			}
+		}
+		if len(ids) < pollPage {
+			return nil, version, nil
+		}
+	}
+}
+
+// helper function that atomically moves the task from the pending set to
+// the running hash, and returns false if the task was claimed by another
+// server.
+func (q *redisQueue) claim(id string) (bool, error) {
+	deadline := strconv.FormatInt(time.Now().Add(q.extension).Unix(), 10)
+	claimed, err := q.client.Int("EVAL", claimScript, "2", keyPending, keyRunning, id, deadline)
+	return claimed == 1, err
+}
+
+// helper function that increments the version of the queue, when tasks may
+// become available to the workers.
+func (q *redisQueue) changed() error {
+	_, err := q.client.Do("INCR", keyVersion)
+	return err
+}
+
+// helper function that returns the running tasks to the front of the
+// queue if the deadline expires.
+func (q *redisQueue) expire() error {
+	values, err := q.client.Strings("HGETALL", keyRunning)
+	if err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	for i := 0; i+1 < len(values); i += 2 {
+		id := values[i]
+		deadline, _ := strconv.ParseInt(values[i+1], 10, 64)
+		if deadline > now {
+			continue
+		}
+		removed, err := q.client.Int("HDEL", keyRunning, id)
+		if err != nil || removed == 0 {
+			continue
+		}
+		task, err := q.task(id)
+		if err != nil || task == nil {
+			continue
+		}
+		q.client.Do("ZADD", keyPending, score(task, 0), id)
+		q.changed()
+	}
+	return nil
+}
+
+// Extend extends the task execution deadline.
+func (q *redisQueue) Extend(c context.Context, id string) error {
+	exists, err := q.client.Int("HEXISTS", keyRunning, id)
+	if err != nil {
+		return err
+	}
+	if exists == 0 {
+		return queue.ErrNotFound
+	}
+	deadline := strconv.FormatInt(time.Now().Add(q.extension).Unix(), 10)
+	_, err = q.client.Do("HSET", keyRunning, id, deadline)
+	return err
+}
+
+// Done signals the task is complete.
+func (q *redisQueue) Done(c context.Context, id string) error {
+	return q.Error(c, id, nil)
+}
+
+// Error signals the task is complete with errors. The error is kept for a
+// day so that it is returned to the workers waiting on the task.
+func (q *redisQueue) Error(c context.Context, id string, err error) error {
+	if err != nil {
+		if _, derr := q.client.Do("SET", keyError+id, err.Error(), "EX", "86400"); derr != nil {
+			return derr
+		}
+	}
+	if _, derr := q.client.Do("HDEL", keyRunning, id); derr != nil {
+		return derr
+	}
+	if _, derr := q.client.Do("HDEL", keyTasks, id); derr != nil {
+		return derr
+	}
+	return q.changed()
+}
+
+// Evict removes a pending task from the queue.
+func (q *redisQueue) Evict(c context.Context, id string) error {
+	removed, err := q.client.Int("ZREM", keyPending, id)
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return queue.ErrNotFound
+	}
+	_, err = q.client.Do("HDEL", keyTasks, id)
+	return err
+}
+
+// Wait waits until the task is complete.
+func (q *redisQueue) Wait(c context.Context, id string) error {
+	for {
+		running, err := q.client.Int("HEXISTS", keyRunning, id)
+		if err != nil {
+			return err
+		}
+		if running == 0 {
+			break
+		}
+		select {
+		case <-c.Done():
+			return nil
+		case <-time.After(q.interval):
+		}
+	}
+	data, err := q.client.Bytes("GET", keyError+id)
+	if err == ErrNil {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if string(data) == queue.ErrCancel.Error() {
+		return queue.ErrCancel
+	}
+	return errors.New(string(data))
+}
+
+// Info returns internal queue information. The worker count includes the
+// workers connected to this server only.
+func (q *redisQueue) Info(c context.Context) queue.InfoT {
+	info := queue.InfoT{}
+	info.Pending, _ = q.tasks(keyPending)
+	info.Running, _ = q.tasks(keyRunning)
+	paused, _ := q.client.Int("EXISTS", keyPaused)
+	info.Paused = paused != 0
+	info.Stats.Workers = int(atomic.LoadInt64(&q.workers))
+	info.Stats.Pending = len(info.Pending)
+	info.Stats.Running = len(info.Running)
+	return info
+}
+
+// Pause stops the queue from dispatching pending tasks to workers.
+func (q *redisQueue) Pause() {
+	q.client.Do("SET", keyPaused, "1")
+}
+
+// Resume resumes dispatching pending tasks to workers.
+func (q *redisQueue) Resume() {
+	q.client.Do("DEL", keyPaused)
+	q.changed()
+}
+
+// helper function that returns the tasks in the pending set, in order, or
+// the tasks in the running hash.
+func (q *redisQueue) tasks(key string) ([]*queue.Task, error) {
+	var (
+		ids []string
+		err error
+	)
+	if key == keyPending {
+		ids, err = q.client.Strings("ZRANGE", key, "0", "-1")
+	} else {
+		ids, err = q.client.Strings("HKEYS", key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return q.load(ids)
+}
+
+// helper function that returns the tasks by id, in order. Tasks that do
+// not exist are skipped.
+func (q *redisQueue) load(ids []string) ([]*queue.Task, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	values, err := q.client.Strings(append([]string{"HMGET", keyTasks}, ids...)...)
+	if err != nil {
+		return nil, err
+	}
+	var tasks []*queue.Task
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		task := new(queue.Task)
+		if err := json.Unmarshal([]byte(value), task); err == nil {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, nil
+}
+
+// helper function that returns the task by id, or nil if the task does
+// not exist.
+func (q *redisQueue) task(id string) (*queue.Task, error) {
+	data, err := q.client.Bytes("HGET", keyTasks, id)
+	if err == ErrNil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	task := new(queue.Task)
+	err = json.Unmarshal(data, task)
+	return task, err
+}
+
+// helper function returns the pending set score of the task, which orders
+// tasks by priority and then by sequence.
+func score(task *queue.Task, seq int64) string {
+	return strconv.FormatFloat(float64(seq)-float64(task.Priority)*priorityWeight, 'f', 0, 64)
+}
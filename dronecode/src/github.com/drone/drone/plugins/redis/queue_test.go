@@ -0,0 +1,234 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/cncd/queue"
+)
+
+// fakeRedis implements the subset of the Redis commands used by the queue,
+// and counts the commands it receives.
+type fakeRedis struct {
+	sync.Mutex
+
+	strings map[string]string
+	hashes  map[string]map[string]string
+	zsets   map[string]map[string]float64
+	calls   map[string]int
+}
+
+func newFakeRedis(t *testing.T) (*fakeRedis, *Client) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	s := &fakeRedis{
+		strings: map[string]string{},
+		hashes:  map[string]map[string]string{},
+		zsets:   map[string]map[string]float64{},
+		calls:   map[string]int{},
+	}
+	go func() {
+		for {
+			nc, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(nc)
+		}
+	}()
+	client, err := New("redis://" + l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, client
+}
+
+func (s *fakeRedis) serve(nc net.Conn) {
+	defer nc.Close()
+	r := bufio.NewReader(nc)
+	for {
+		reply, err := readReply(r)
+		if err != nil {
+			return
+		}
+		var args []string
+		for _, arg := range reply.([]interface{}) {
+			args = append(args, string(arg.([]byte)))
+		}
+		s.Lock()
+		s.calls[args[0]]++
+		out := s.exec(args)
+		s.Unlock()
+		nc.Write(out)
+	}
+}
+
+func (s *fakeRedis) hash(key string) map[string]string {
+	if s.hashes[key] == nil {
+		s.hashes[key] = map[string]string{}
+	}
+	return s.hashes[key]
+}
+
+func (s *fakeRedis) exec(args []string) []byte {
+	switch args[0] {
+	case "SET":
+		s.strings[args[1]] = args[2]
+		return []byte("+OK\r\n")
+	case "GET":
+		v, ok := s.strings[args[1]]
+		return bulk(v, ok)
+	case "DEL", "EXISTS":
+		_, ok := s.strings[args[1]]
+		if args[0] == "DEL" {
+			delete(s.strings, args[1])
+		}
+		return integer(ok)
+	case "INCR":
+		n, _ := strconv.Atoi(s.strings[args[1]])
+		s.strings[args[1]] = strconv.Itoa(n + 1)
+		return []byte(":" + strconv.Itoa(n+1) + "\r\n")
+	case "HSET":
+		s.hash(args[1])[args[2]] = args[3]
+		return []byte(":1\r\n")
+	case "HGET":
+		v, ok := s.hash(args[1])[args[2]]
+		return bulk(v, ok)
+	case "HEXISTS", "HDEL":
+		_, ok := s.hash(args[1])[args[2]]
+		if args[0] == "HDEL" {
+			delete(s.hash(args[1]), args[2])
+		}
+		return integer(ok)
+	case "HMGET":
+		var out [][]byte
+		for _, field := range args[2:] {
+			v, ok := s.hash(args[1])[field]
+			out = append(out, bulk(v, ok))
+		}
+		return array(out)
+	case "HKEYS", "HGETALL":
+		var out [][]byte
+		for k, v := range s.hash(args[1]) {
+			out = append(out, bulk(k, true))
+			if args[0] == "HGETALL" {
+				out = append(out, bulk(v, true))
+			}
+		}
+		return array(out)
+	case "ZADD":
+		if s.zsets[args[1]] == nil {
+			s.zsets[args[1]] = map[string]float64{}
+		}
+		s.zsets[args[1]][args[3]], _ = strconv.ParseFloat(args[2], 64)
+		return []byte(":1\r\n")
+	case "ZREM":
+		_, ok := s.zsets[args[1]][args[2]]
+		delete(s.zsets[args[1]], args[2])
+		return integer(ok)
+	case "ZRANGE":
+		zset := s.zsets[args[1]]
+		var ids []string
+		for id := range zset {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return zset[ids[i]] < zset[ids[j]] })
+		start, _ := strconv.Atoi(args[2])
+		stop, _ := strconv.Atoi(args[3])
+		var out [][]byte
+		for i := start; i <= stop && i < len(ids); i++ {
+			out = append(out, bulk(ids[i], true))
+		}
+		return array(out)
+	case "EVAL":
+		if args[1] != claimScript {
+			return []byte("-ERR unknown script\r\n")
+		}
+		if _, ok := s.zsets[args[3]][args[5]]; !ok {
+			return integer(false)
+		}
+		delete(s.zsets[args[3]], args[5])
+		s.hash(args[4])[args[5]] = args[6]
+		return integer(true)
+	}
+	return []byte("-ERR unknown command\r\n")
+}
+
+func bulk(v string, ok bool) []byte {
+	if !ok {
+		return []byte("$-1\r\n")
+	}
+	return []byte("$" + strconv.Itoa(len(v)) + "\r\n" + v + "\r\n")
+}
+
+func integer(ok bool) []byte {
+	if ok {
+		return []byte(":1\r\n")
+	}
+	return []byte(":0\r\n")
+}
+
+func array(values [][]byte) []byte {
+	out := []byte("*" + strconv.Itoa(len(values)) + "\r\n")
+	for _, v := range values {
+		out = append(out, v...)
+	}
+	return out
+}
+
+func TestQueueNext(t *testing.T) {
+	s, client := newFakeRedis(t)
+	q := NewQueue(client, nil).(*redisQueue)
+
+	// the task that matches the filter is behind more than a page of
+	// pending tasks.
+	for i := 0; i < pollPage*2+10; i++ {
+		task := &queue.Task{ID: strconv.Itoa(i), Labels: map[string]string{"platform": "linux/amd64"}}
+		if i == pollPage*2+5 {
+			task.Labels["platform"] = "linux/arm"
+		}
+		if err := q.Push(context.Background(), task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	arm := func(task *queue.Task) bool { return task.Labels["platform"] == "linux/arm" }
+
+	task, version, err := q.next(arm, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task == nil || task.ID != strconv.Itoa(pollPage*2+5) {
+		t.Fatalf("Want the matching task claimed, got %v", task)
+	}
+	if s.hash(keyRunning)[task.ID] == "" {
+		t.Errorf("Want the claimed task running")
+	}
+	if _, ok := s.zsets[keyPending][task.ID]; ok {
+		t.Errorf("Want the claimed task removed from the pending set")
+	}
+
+	// once no task matches, the pending tasks are not scanned again until
+	// the queue changes.
+	task, version, err = q.next(arm, version)
+	if err != nil || task != nil {
+		t.Fatalf("Want no matching task, got %v, %v", task, err)
+	}
+	s.calls["ZRANGE"] = 0
+	if task, _, _ = q.next(arm, version); task != nil || s.calls["ZRANGE"] != 0 {
+		t.Errorf("Want the pending tasks not scanned while the queue is unchanged, got %d scans", s.calls["ZRANGE"])
+	}
+
+	q.Push(context.Background(), &queue.Task{ID: "arm", Labels: map[string]string{"platform": "linux/arm"}})
+	if task, _, _ = q.next(arm, version); task == nil || task.ID != "arm" {
+		t.Errorf("Want the pushed task claimed once the queue changed, got %v", task)
+	}
+}
@@ -0,0 +1,133 @@
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/shared/aws"
+)
+
+var errSecretsReadOnly = errors.New("secrets: aws secrets manager is read-only")
+
+// secretsManager sources secrets from AWS Secrets Manager. Repository
+// secrets are named <prefix>/<owner>/<name>/<secret> and organization
+// secrets <prefix>/<owner>/<secret>, where a repository secret takes
+// precedence over an organization secret with the same name. The secret
+// string is either the plain value or a JSON object with value, event and
+// image fields.
+type secretsManager struct {
+	client *aws.SecretsManager
+	prefix string
+	cache  *cache
+}
+
+// NewSecretsManager returns a new read-only secret service backed by AWS
+// Secrets Manager. Secret lists are cached for the specified duration.
+func NewSecretsManager(client *aws.SecretsManager, prefix string, ttl time.Duration) model.SecretService {
+	return &secretsManager{
+		client: client,
+		prefix: prefix,
+		cache:  newCache(ttl),
+	}
+}
+
+func (s *secretsManager) SecretFind(repo *model.Repo, name string) (*model.Secret, error) {
+	return s.read(path.Join(s.prefix, repo.Owner, repo.Name, name), name)
+}
+
+func (s *secretsManager) SecretList(repo *model.Repo) ([]*model.Secret, error) {
+	return s.list(repo)
+}
+
+func (s *secretsManager) SecretListBuild(repo *model.Repo, build *model.Build) ([]*model.Secret, error) {
+	return s.list(repo)
+}
+
+func (s *secretsManager) SecretCreate(repo *model.Repo, in *model.Secret) error {
+	return errSecretsReadOnly
+}
+
+func (s *secretsManager) SecretUpdate(repo *model.Repo, in *model.Secret) error {
+	return errSecretsReadOnly
+}
+
+func (s *secretsManager) SecretDelete(repo *model.Repo, name string) error {
+	return errSecretsReadOnly
+}
+
+func (s *secretsManager) list(repo *model.Repo) ([]*model.Secret, error) {
+	if secrets, ok := s.cache.get(repo); ok {
+		return secrets, nil
+	}
+
+	org := path.Join(s.prefix, repo.Owner) + "/"
+	names, err := s.client.ListSecrets(org)
+	if err != nil {
+		return nil, err
+	}
+
+	// organization secrets are read first so that they are replaced by
+	// repository secrets of the same name.
+	sort.Slice(names, func(i, j int) bool {
+		return strings.Count(names[i], "/") < strings.Count(names[j], "/")
+	})
+
+	secrets := map[string]*model.Secret{}
+	for _, full := range names {
+		name := strings.TrimPrefix(full, org)
+		if i := strings.Index(name, "/"); i != -1 {
+			if name[:i] != repo.Name {
+				continue
+			}
+			name = name[i+1:]
+		}
+		if name == "" || strings.Contains(name, "/") {
+			continue
+		}
+		secret, err := s.read(full, name)
+		if err != nil {
+			return nil, err
+		}
+		secrets[name] = secret
+	}
+
+	var out []*model.Secret
+	for _, secret := range secrets {
+		out = append(out, secret)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	s.cache.set(repo, out)
+	return out, nil
+}
+
+func (s *secretsManager) read(full, name string) (*model.Secret, error) {
+	value, err := s.client.GetSecretValue(full)
+	if err != nil {
+		return nil, err
+	}
+	secret := &model.Secret{Name: name, Value: value}
+
+	data := struct {
+		Value  string `json:"value"`
+		Event  string `json:"event"`
+		Image  string `json:"image"`
+		Branch string `json:"branch"`
+	}{}
+	if json.Unmarshal([]byte(value), &data) == nil && data.Value != "" {
+		secret.Value = data.Value
+		secret.Events = split(data.Event)
+		secret.Images = split(data.Image)
+		secret.Branches = split(data.Branch)
+	}
+	if len(secret.Events) == 0 {
+		secret.Events = defaultEvents
+	}
+	return secret, nil
+}
@@ -0,0 +1,61 @@
+package secrets
+
+import (
+	"sync"
+	"time"
+
+	"github.com/drone/drone/model"
+)
+
+// cache caches secret lists by repository for a fixed duration.
+type cache struct {
+	sync.Mutex
+
+	ttl     time.Duration
+	entries map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	secrets []*model.Secret
+	expires time.Time
+}
+
+func newCache(ttl time.Duration) *cache {
+	return &cache{
+		ttl:     ttl,
+		entries: map[string]*cacheEntry{},
+	}
+}
+
+func (c *cache) get(repo *model.Repo) ([]*model.Secret, bool) {
+	c.Lock()
+	defer c.Unlock()
+	entry, ok := c.entries[repo.FullName]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.secrets, true
+}
+
+func (c *cache) set(repo *model.Repo, secrets []*model.Secret) {
+	c.Lock()
+	c.entries[repo.FullName] = &cacheEntry{
+		secrets: secrets,
+		expires: time.Now().Add(c.ttl),
+	}
+	c.Unlock()
+}
+
+func (c *cache) expire(repo *model.Repo) {
+	c.Lock()
+	delete(c.entries, repo.FullName)
+	c.Unlock()
+}
+
+// defaultEvents are the events exposed to secrets stored in an external
+// service without a list of events, matching the user interface defaults.
+var defaultEvents = []string{
+	model.EventPush,
+	model.EventTag,
+	model.EventDeploy,
+}
@@ -0,0 +1,248 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/drone/drone/model"
+)
+
+// envelopePrefix identifies secret values encrypted with a data key. Values
+// without the prefix are stored in plaintext and returned unchanged, so that
+// existing secrets continue to work until they are updated.
+const envelopePrefix = "envelope:v1:"
+
+var errEnvelopeInvalid = errors.New("secrets: invalid encrypted secret value")
+
+// KeyService generates and decrypts the data keys used for envelope
+// encryption, for example using AWS KMS.
+type KeyService interface {
+	GenerateDataKey() (plaintext, ciphertext []byte, err error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+type envelope struct {
+	model.SecretStore
+	*sealer
+}
+
+type orgEnvelope struct {
+	model.OrgSecretStore
+	*sealer
+}
+
+// sealer encrypts and decrypts values using envelope encryption.
+type sealer struct {
+	keys KeyService
+
+	sync.Mutex
+	cache map[string][]byte
+}
+
+func newSealer(keys KeyService) *sealer {
+	return &sealer{
+		keys:  keys,
+		cache: map[string][]byte{},
+	}
+}
+
+// NewEnvelope returns a secret store that encrypts secret values at rest.
+// Each value is encrypted with a unique data key, which is itself encrypted
+// by the key service and stored alongside the value.
+func NewEnvelope(store model.SecretStore, keys KeyService) model.SecretStore {
+	return &envelope{
+		SecretStore: store,
+		sealer:      newSealer(keys),
+	}
+}
+
+// NewOrgEnvelope returns an organization secret store that encrypts secret
+// values at rest.
+func NewOrgEnvelope(store model.OrgSecretStore, keys KeyService) model.OrgSecretStore {
+	return &orgEnvelope{
+		OrgSecretStore: store,
+		sealer:         newSealer(keys),
+	}
+}
+
+func (e *envelope) SecretFind(repo *model.Repo, name string) (*model.Secret, error) {
+	secret, err := e.SecretStore.SecretFind(repo, name)
+	if err != nil {
+		return nil, err
+	}
+	secret.Value, err = e.open(secret.Value)
+	return secret, err
+}
+
+func (e *envelope) SecretList(repo *model.Repo) ([]*model.Secret, error) {
+	secrets, err := e.SecretStore.SecretList(repo)
+	if err != nil {
+		return nil, err
+	}
+	for _, secret := range secrets {
+		if secret.Value, err = e.open(secret.Value); err != nil {
+			return nil, err
+		}
+	}
+	return secrets, nil
+}
+
+func (e *envelope) SecretCreate(in *model.Secret) error {
+	sealed := *in
+	value, err := e.seal(in.Value)
+	if err != nil {
+		return err
+	}
+	sealed.Value = value
+	err = e.SecretStore.SecretCreate(&sealed)
+	in.ID = sealed.ID
+	return err
+}
+
+func (e *envelope) SecretUpdate(in *model.Secret) error {
+	sealed := *in
+	value, err := e.seal(in.Value)
+	if err != nil {
+		return err
+	}
+	sealed.Value = value
+	return e.SecretStore.SecretUpdate(&sealed)
+}
+
+func (e *orgEnvelope) OrgSecretFind(owner, name string) (*model.OrgSecret, error) {
+	secret, err := e.OrgSecretStore.OrgSecretFind(owner, name)
+	if err != nil {
+		return nil, err
+	}
+	secret.Value, err = e.open(secret.Value)
+	return secret, err
+}
+
+func (e *orgEnvelope) OrgSecretList(owner string) ([]*model.OrgSecret, error) {
+	secrets, err := e.OrgSecretStore.OrgSecretList(owner)
+	if err != nil {
+		return nil, err
+	}
+	for _, secret := range secrets {
+		if secret.Value, err = e.open(secret.Value); err != nil {
+			return nil, err
+		}
+	}
+	return secrets, nil
+}
+
+func (e *orgEnvelope) OrgSecretCreate(in *model.OrgSecret) error {
+	sealed := *in
+	value, err := e.seal(in.Value)
+	if err != nil {
+		return err
+	}
+	sealed.Value = value
+	err = e.OrgSecretStore.OrgSecretCreate(&sealed)
+	in.ID = sealed.ID
+	return err
+}
+
+func (e *orgEnvelope) OrgSecretUpdate(in *model.OrgSecret) error {
+	sealed := *in
+	value, err := e.seal(in.Value)
+	if err != nil {
+		return err
+	}
+	sealed.Value = value
+	return e.OrgSecretStore.OrgSecretUpdate(&sealed)
+}
+
+// seal encrypts the value with a new data key. Empty values are not
+// encrypted.
+func (e *sealer) seal(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	key, encryptedKey, err := e.keys.GenerateDataKey()
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	ciphertext := gcm.Seal(nonce, nonce, []byte(value), nil)
+	return envelopePrefix +
+		base64.StdEncoding.EncodeToString(encryptedKey) + ":" +
+		base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// open decrypts the value, returning values without the envelope prefix
+// unchanged.
+func (e *sealer) open(value string) (string, error) {
+	if !strings.HasPrefix(value, envelopePrefix) {
+		return value, nil
+	}
+	parts := strings.SplitN(strings.TrimPrefix(value, envelopePrefix), ":", 2)
+	if len(parts) != 2 {
+		return "", errEnvelopeInvalid
+	}
+	encryptedKey, err := base64.StdEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", errEnvelopeInvalid
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", errEnvelopeInvalid
+	}
+	key, err := e.dataKey(encryptedKey)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return "", errEnvelopeInvalid
+	}
+	nonce, ciphertext := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// dataKey decrypts the data key, caching the result to avoid calling the
+// key service for every secret in every build.
+func (e *sealer) dataKey(encryptedKey []byte) ([]byte, error) {
+	e.Lock()
+	key, ok := e.cache[string(encryptedKey)]
+	e.Unlock()
+	if ok {
+		return key, nil
+	}
+	key, err := e.keys.Decrypt(encryptedKey)
+	if err != nil {
+		return nil, err
+	}
+	e.Lock()
+	e.cache[string(encryptedKey)] = key
+	e.Unlock()
+	return key, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
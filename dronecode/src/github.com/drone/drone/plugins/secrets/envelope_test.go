@@ -0,0 +1,108 @@
+package secrets
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/drone/drone/model"
+)
+
+func TestEnvelope(t *testing.T) {
+	store := &memoryStore{}
+	keys := &xorKeys{}
+	env := NewEnvelope(store, keys)
+
+	in := &model.Secret{Name: "password", Value: "correct-horse-battery-staple"}
+	if err := env.SecretCreate(in); err != nil {
+		t.Fatal(err)
+	}
+	if in.Value != "correct-horse-battery-staple" {
+		t.Errorf("Want input secret unchanged, got %q", in.Value)
+	}
+	if stored := store.secrets[0].Value; !strings.HasPrefix(stored, envelopePrefix) {
+		t.Errorf("Want encrypted value stored, got %q", stored)
+	}
+
+	out, err := env.SecretFind(nil, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Value != in.Value {
+		t.Errorf("Want decrypted value %q, got %q", in.Value, out.Value)
+	}
+
+	env.SecretList(nil)
+	if keys.decrypted != 1 {
+		t.Errorf("Want data key decrypted once, got %d", keys.decrypted)
+	}
+}
+
+func TestEnvelopePlaintext(t *testing.T) {
+	store := &memoryStore{
+		secrets: []*model.Secret{{Name: "password", Value: "plaintext"}},
+	}
+	out, err := NewEnvelope(store, &xorKeys{}).SecretFind(nil, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Value != "plaintext" {
+		t.Errorf("Want plaintext value unchanged, got %q", out.Value)
+	}
+}
+
+// xorKeys is a fake key service that encrypts data keys with a fixed pad.
+type xorKeys struct {
+	decrypted int
+}
+
+func (k *xorKeys) GenerateDataKey() ([]byte, []byte, error) {
+	key := bytes.Repeat([]byte{0x2a}, 32)
+	return key, xor(key), nil
+}
+
+func (k *xorKeys) Decrypt(ciphertext []byte) ([]byte, error) {
+	k.decrypted++
+	return xor(ciphertext), nil
+}
+
+func xor(in []byte) []byte {
+	out := make([]byte, len(in))
+	for i, b := range in {
+		out[i] = b ^ 0xff
+	}
+	return out
+}
+
+// memoryStore is an in-memory secret store.
+type memoryStore struct {
+	secrets []*model.Secret
+}
+
+func (m *memoryStore) SecretFind(repo *model.Repo, name string) (*model.Secret, error) {
+	for _, secret := range m.secrets {
+		if secret.Name == name {
+			copy := *secret
+			return &copy, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryStore) SecretList(repo *model.Repo) ([]*model.Secret, error) {
+	var out []*model.Secret
+	for _, secret := range m.secrets {
+		copy := *secret
+		out = append(out, &copy)
+	}
+	return out, nil
+}
+
+func (m *memoryStore) SecretCreate(in *model.Secret) error {
+	in.ID = int64(len(m.secrets) + 1)
+	m.secrets = append(m.secrets, in)
+	return nil
+}
+
+func (m *memoryStore) SecretUpdate(in *model.Secret) error { return nil }
+func (m *memoryStore) SecretDelete(in *model.Secret) error { return nil }
@@ -0,0 +1,52 @@
+package secrets
+
+import (
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/plugins/internal"
+)
+
+type extension struct {
+	endpoint string
+	secret   string
+}
+
+type extensionRequest struct {
+	Repo  *model.Repo  `json:"repo"`
+	Build *model.Build `json:"build"`
+	Event string       `json:"event"`
+	Name  string       `json:"name"`
+	Image string       `json:"image"`
+}
+
+// NewExtension returns a new secret extension. The repository, the build
+// event and the step image are posted to the endpoint each time a step
+// requests a secret, so that the secret broker decides whether the secret
+// is exposed to the step. Requests are signed with the shared secret.
+func NewExtension(endpoint, secret string) model.SecretResolver {
+	return &extension{endpoint, secret}
+}
+
+func (e *extension) SecretResolve(repo *model.Repo, build *model.Build, name, image string) (*model.Secret, error) {
+	in := &extensionRequest{
+		Repo:  repo,
+		Build: build,
+		Event: build.Event,
+		Name:  name,
+		Image: image,
+	}
+	out := new(model.Secret)
+	err := internal.SendSigned("POST", e.endpoint, e.secret, in, out)
+	if err, ok := err.(*internal.Error); ok && err.Code() == 404 {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// the secret is not available to the step if the endpoint returns
+	// an empty response, for example a 204 status.
+	if out.Value == "" {
+		return nil, nil
+	}
+	out.Name = name
+	return out, nil
+}
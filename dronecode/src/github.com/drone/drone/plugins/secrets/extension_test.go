@@ -0,0 +1,55 @@
+package secrets
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/plugins/internal"
+)
+
+func TestExtension(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get(internal.SignatureHeader) != internal.Sign(body, "correct-horse-battery-staple") {
+			w.WriteHeader(403)
+			return
+		}
+		in := new(extensionRequest)
+		json.Unmarshal(body, in)
+		if in.Event != model.EventPush || in.Image != "plugins/docker" {
+			w.WriteHeader(404)
+			return
+		}
+		json.NewEncoder(w).Encode(&model.Secret{Value: "hunter2"})
+	}))
+	defer s.Close()
+
+	repo := &model.Repo{FullName: "octocat/hello-world"}
+	push := &model.Build{Event: model.EventPush}
+
+	e := NewExtension(s.URL, "correct-horse-battery-staple")
+	secret, err := e.SecretResolve(repo, push, "docker_password", "plugins/docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == nil || secret.Name != "docker_password" || secret.Value != "hunter2" {
+		t.Errorf("Want secret resolved for the step image, got %v", secret)
+	}
+
+	secret, err = e.SecretResolve(repo, &model.Build{Event: model.EventPull}, "docker_password", "plugins/docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret != nil {
+		t.Errorf("Want secret not resolved for the pull request event")
+	}
+
+	e = NewExtension(s.URL, "invalid")
+	if _, err := e.SecretResolve(repo, push, "docker_password", "plugins/docker"); err == nil {
+		t.Errorf("Expect error when the request signature is invalid")
+	}
+}
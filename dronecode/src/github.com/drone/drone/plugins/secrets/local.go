@@ -0,0 +1,50 @@
+package secrets
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+)
+
+var errDataKeyInvalid = errors.New("secrets: invalid encrypted data key")
+
+type localKeys struct {
+	key []byte
+}
+
+// NewLocalKeys returns a key service that encrypts the data keys with a
+// master key derived from the secret key, for envelope encryption without
+// an external key management service.
+func NewLocalKeys(key string) KeyService {
+	sum := sha256.Sum256([]byte(key))
+	return &localKeys{key: sum[:]}
+}
+
+func (k *localKeys) GenerateDataKey() (plaintext, ciphertext []byte, err error) {
+	plaintext = make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, plaintext); err != nil {
+		return nil, nil, err
+	}
+	gcm, err := newGCM(k.key)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, nil, err
+	}
+	return plaintext, gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (k *localKeys) Decrypt(ciphertext []byte) ([]byte, error) {
+	gcm, err := newGCM(k.key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, errDataKeyInvalid
+	}
+	nonce, ciphertext := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
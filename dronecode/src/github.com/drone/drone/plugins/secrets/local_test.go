@@ -0,0 +1,58 @@
+package secrets
+
+import (
+	"testing"
+
+	"github.com/drone/drone/model"
+)
+
+func TestLocalKeys(t *testing.T) {
+	store := &memoryStore{}
+	env := NewEnvelope(store, NewLocalKeys("correct-horse-battery-staple"))
+	if err := env.SecretCreate(&model.Secret{Name: "password", Value: "hunter2"}); err != nil {
+		t.Fatal(err)
+	}
+	out, err := env.SecretFind(nil, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Value != "hunter2" {
+		t.Errorf("Want decrypted value hunter2, got %q", out.Value)
+	}
+
+	if _, err := NewEnvelope(store, NewLocalKeys("wrong")).SecretFind(nil, "password"); err == nil {
+		t.Errorf("Want error decrypting with the wrong key")
+	}
+}
+
+func TestResealer(t *testing.T) {
+	oldKeys := NewLocalKeys("old")
+	newKeys := NewLocalKeys("new")
+	sealed, _ := newSealer(oldKeys).seal("hunter2")
+
+	resealed, err := NewResealer(oldKeys, newKeys)(sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := newSealer(newKeys).open(resealed); got != "hunter2" {
+		t.Errorf("Want value encrypted with the new key, got %q", got)
+	}
+
+	plaintext, err := NewResealer(nil, newKeys)("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := newSealer(newKeys).open(plaintext); got != "hunter2" {
+		t.Errorf("Want plaintext value encrypted, got %q", got)
+	}
+
+	if got, _ := NewResealer(oldKeys, nil)(sealed); got != "hunter2" {
+		t.Errorf("Want value decrypted without the new key, got %q", got)
+	}
+	if _, err := NewResealer(nil, newKeys)(sealed); err == nil {
+		t.Errorf("Want error resealing an encrypted value without the old key")
+	}
+	if _, err := NewResealer(newKeys, oldKeys)(sealed); err == nil {
+		t.Errorf("Want error resealing with the wrong old key")
+	}
+}
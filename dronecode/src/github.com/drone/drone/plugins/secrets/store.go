@@ -0,0 +1,231 @@
+package secrets
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/store"
+)
+
+var errKeyMissing = errors.New("secrets: cannot decrypt value without the key")
+
+type sealedStore struct {
+	store.Store
+	*sealer
+
+	secrets    model.SecretStore
+	orgSecrets model.OrgSecretStore
+}
+
+// NewStore returns a store that encrypts the secret values, registry
+// credentials and user tokens at rest, using envelope encryption.
+func NewStore(s store.Store, keys KeyService) store.Store {
+	sealer := newSealer(keys)
+	return &sealedStore{
+		Store:      s,
+		sealer:     sealer,
+		secrets:    &envelope{s, sealer},
+		orgSecrets: &orgEnvelope{s, sealer},
+	}
+}
+
+// NewResealer returns a function that decrypts the value with the old key
+// service and encrypts it with the new key service. Plaintext values are
+// encrypted, and values are decrypted to plaintext if the new key service
+// is nil. It is used to rotate the key of the encrypted values.
+func NewResealer(from, to KeyService) func(string) (string, error) {
+	var src, dst *sealer
+	if from != nil {
+		src = newSealer(from)
+	}
+	if to != nil {
+		dst = newSealer(to)
+	}
+	return func(value string) (string, error) {
+		if strings.HasPrefix(value, envelopePrefix) {
+			if src == nil {
+				return "", errKeyMissing
+			}
+			var err error
+			if value, err = src.open(value); err != nil {
+				return "", err
+			}
+		}
+		if dst == nil {
+			return value, nil
+		}
+		return dst.seal(value)
+	}
+}
+
+func (s *sealedStore) GetUser(id int64) (*model.User, error) {
+	user, err := s.Store.GetUser(id)
+	if err != nil {
+		return nil, err
+	}
+	return user, s.openUser(user)
+}
+
+func (s *sealedStore) GetUserLogin(login string) (*model.User, error) {
+	user, err := s.Store.GetUserLogin(login)
+	if err != nil {
+		return nil, err
+	}
+	return user, s.openUser(user)
+}
+
+func (s *sealedStore) GetUserSubject(subject string) (*model.User, error) {
+	user, err := s.Store.GetUserSubject(subject)
+	if err != nil {
+		return nil, err
+	}
+	return user, s.openUser(user)
+}
+
+func (s *sealedStore) GetUserList() ([]*model.User, error) {
+	users, err := s.Store.GetUserList()
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		if err := s.openUser(user); err != nil {
+			return nil, err
+		}
+	}
+	return users, nil
+}
+
+func (s *sealedStore) CreateUser(user *model.User) error {
+	sealed, err := s.sealUser(user)
+	if err != nil {
+		return err
+	}
+	err = s.Store.CreateUser(sealed)
+	user.ID = sealed.ID
+	return err
+}
+
+func (s *sealedStore) UpdateUser(user *model.User) error {
+	sealed, err := s.sealUser(user)
+	if err != nil {
+		return err
+	}
+	return s.Store.UpdateUser(sealed)
+}
+
+func (s *sealedStore) SecretFind(repo *model.Repo, name string) (*model.Secret, error) {
+	return s.secrets.SecretFind(repo, name)
+}
+
+func (s *sealedStore) SecretList(repo *model.Repo) ([]*model.Secret, error) {
+	return s.secrets.SecretList(repo)
+}
+
+func (s *sealedStore) SecretCreate(secret *model.Secret) error {
+	return s.secrets.SecretCreate(secret)
+}
+
+func (s *sealedStore) SecretUpdate(secret *model.Secret) error {
+	return s.secrets.SecretUpdate(secret)
+}
+
+func (s *sealedStore) OrgSecretFind(owner, name string) (*model.OrgSecret, error) {
+	return s.orgSecrets.OrgSecretFind(owner, name)
+}
+
+func (s *sealedStore) OrgSecretList(owner string) ([]*model.OrgSecret, error) {
+	return s.orgSecrets.OrgSecretList(owner)
+}
+
+func (s *sealedStore) OrgSecretCreate(secret *model.OrgSecret) error {
+	return s.orgSecrets.OrgSecretCreate(secret)
+}
+
+func (s *sealedStore) OrgSecretUpdate(secret *model.OrgSecret) error {
+	return s.orgSecrets.OrgSecretUpdate(secret)
+}
+
+func (s *sealedStore) RegistryFind(repo *model.Repo, addr string) (*model.Registry, error) {
+	registry, err := s.Store.RegistryFind(repo, addr)
+	if err != nil {
+		return nil, err
+	}
+	return registry, s.openRegistry(registry)
+}
+
+func (s *sealedStore) RegistryList(repo *model.Repo) ([]*model.Registry, error) {
+	registries, err := s.Store.RegistryList(repo)
+	if err != nil {
+		return nil, err
+	}
+	for _, registry := range registries {
+		if err := s.openRegistry(registry); err != nil {
+			return nil, err
+		}
+	}
+	return registries, nil
+}
+
+func (s *sealedStore) RegistryCreate(registry *model.Registry) error {
+	sealed, err := s.sealRegistry(registry)
+	if err != nil {
+		return err
+	}
+	err = s.Store.RegistryCreate(sealed)
+	registry.ID = sealed.ID
+	return err
+}
+
+func (s *sealedStore) RegistryUpdate(registry *model.Registry) error {
+	sealed, err := s.sealRegistry(registry)
+	if err != nil {
+		return err
+	}
+	return s.Store.RegistryUpdate(sealed)
+}
+
+// helper function decrypts the oauth tokens of the user.
+func (s *sealedStore) openUser(user *model.User) (err error) {
+	if user.Token, err = s.open(user.Token); err != nil {
+		return err
+	}
+	user.Secret, err = s.open(user.Secret)
+	return err
+}
+
+// helper function returns a copy of the user with encrypted oauth tokens.
+func (s *sealedStore) sealUser(user *model.User) (*model.User, error) {
+	sealed := *user
+	var err error
+	if sealed.Token, err = s.seal(user.Token); err != nil {
+		return nil, err
+	}
+	if sealed.Secret, err = s.seal(user.Secret); err != nil {
+		return nil, err
+	}
+	return &sealed, nil
+}
+
+// helper function decrypts the credentials of the registry.
+func (s *sealedStore) openRegistry(registry *model.Registry) (err error) {
+	if registry.Password, err = s.open(registry.Password); err != nil {
+		return err
+	}
+	registry.Token, err = s.open(registry.Token)
+	return err
+}
+
+// helper function returns a copy of the registry with encrypted
+// credentials.
+func (s *sealedStore) sealRegistry(registry *model.Registry) (*model.Registry, error) {
+	sealed := *registry
+	var err error
+	if sealed.Password, err = s.seal(registry.Password); err != nil {
+		return nil, err
+	}
+	if sealed.Token, err = s.seal(registry.Token); err != nil {
+		return nil, err
+	}
+	return &sealed, nil
+}
@@ -0,0 +1,288 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/drone/drone/model"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/hashicorp/vault/api"
+)
+
+// vault stores secrets in a Vault KV version 2 secrets engine. Repository
+// secrets are stored at <mount>/data/<prefix>/<owner>/<name>/<secret> and
+// organization secrets at <mount>/data/<prefix>/<owner>/<secret>, where a
+// repository secret takes precedence over an organization secret with the
+// same name.
+type vault struct {
+	sync.RWMutex // guards the token of the client
+
+	client *api.Client
+	mount  string
+	prefix string
+	cache  *cache
+	unit   time.Duration // unit of the lease durations
+}
+
+// AppRole defines the approle used to authenticate with Vault.
+type AppRole struct {
+	RoleID   string
+	SecretID string
+}
+
+// vaultRetry is the number of seconds to wait before authenticating
+// again when the token cannot be renewed.
+const vaultRetry = 10
+
+// NewVault returns a new secret service backed by Vault. Secret lists are
+// cached for the specified duration.
+func NewVault(client *api.Client, mount, prefix string, ttl time.Duration) model.SecretService {
+	return newVault(client, mount, prefix, ttl)
+}
+
+// NewVaultAppRole returns a new secret service backed by Vault, which
+// authenticates with the approle. The token is renewed in the background
+// until the context is cancelled, and the approle is used to authenticate
+// again once the token can no longer be renewed.
+func NewVaultAppRole(ctx context.Context, client *api.Client, role AppRole, mount, prefix string, ttl time.Duration) (model.SecretService, error) {
+	v := newVault(client, mount, prefix, ttl)
+	auth, err := v.login(role)
+	if err != nil {
+		return nil, err
+	}
+	go v.renew(ctx, role, auth)
+	return v, nil
+}
+
+func newVault(client *api.Client, mount, prefix string, ttl time.Duration) *vault {
+	return &vault{
+		client: client,
+		mount:  mount,
+		prefix: prefix,
+		cache:  newCache(ttl),
+		unit:   time.Second,
+	}
+}
+
+func (v *vault) SecretFind(repo *model.Repo, name string) (*model.Secret, error) {
+	secret, err := v.read(v.repoPath(repo), name)
+	if err == nil && secret == nil {
+		secret, err = v.read(v.orgPath(repo), name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return nil, fmt.Errorf("secret %s not found", name)
+	}
+	return secret, nil
+}
+
+func (v *vault) SecretList(repo *model.Repo) ([]*model.Secret, error) {
+	return v.list(repo)
+}
+
+func (v *vault) SecretListBuild(repo *model.Repo, build *model.Build) ([]*model.Secret, error) {
+	return v.list(repo)
+}
+
+func (v *vault) SecretCreate(repo *model.Repo, in *model.Secret) error {
+	return v.write(repo, in)
+}
+
+func (v *vault) SecretUpdate(repo *model.Repo, in *model.Secret) error {
+	return v.write(repo, in)
+}
+
+func (v *vault) SecretDelete(repo *model.Repo, name string) error {
+	defer v.cache.expire(repo)
+	v.RLock()
+	defer v.RUnlock()
+	_, err := v.client.Logical().Delete(
+		path.Join(v.mount, "metadata", v.repoPath(repo), name),
+	)
+	return err
+}
+
+// list returns the organization and repository secrets, serving the
+// result from the cache when possible.
+func (v *vault) list(repo *model.Repo) ([]*model.Secret, error) {
+	if secrets, ok := v.cache.get(repo); ok {
+		return secrets, nil
+	}
+
+	secrets := map[string]*model.Secret{}
+	for _, dir := range []string{v.orgPath(repo), v.repoPath(repo)} {
+		names, err := v.keys(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			secret, err := v.read(dir, name)
+			if err != nil {
+				return nil, err
+			}
+			if secret != nil {
+				secrets[name] = secret
+			}
+		}
+	}
+
+	var out []*model.Secret
+	for _, secret := range secrets {
+		out = append(out, secret)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+
+	v.cache.set(repo, out)
+	return out, nil
+}
+
+// keys returns the names of the secrets stored in the directory,
+// excluding sub-directories.
+func (v *vault) keys(dir string) ([]string, error) {
+	v.RLock()
+	res, err := v.client.Logical().List(path.Join(v.mount, "metadata", dir))
+	v.RUnlock()
+	if err != nil || res == nil {
+		return nil, err
+	}
+	keys, _ := res.Data["keys"].([]interface{})
+
+	var names []string
+	for _, key := range keys {
+		name, _ := key.(string)
+		if name == "" || strings.HasSuffix(name, "/") {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// read returns the named secret, or nil if the secret does not exist.
+func (v *vault) read(dir, name string) (*model.Secret, error) {
+	v.RLock()
+	res, err := v.client.Logical().Read(path.Join(v.mount, "data", dir, name))
+	v.RUnlock()
+	if err != nil || res == nil {
+		return nil, err
+	}
+	data, _ := res.Data["data"].(map[string]interface{})
+	if data == nil {
+		return nil, nil
+	}
+	value, _ := data["value"].(string)
+	event, _ := data["event"].(string)
+	image, _ := data["image"].(string)
+	branch, _ := data["branch"].(string)
+	secret := &model.Secret{
+		Name:     name,
+		Value:    value,
+		Events:   split(event),
+		Images:   split(image),
+		Branches: split(branch),
+	}
+	// secrets written directly to vault without a list of events are
+	// exposed to the same events as secrets created in the user interface.
+	if len(secret.Events) == 0 {
+		secret.Events = defaultEvents
+	}
+	return secret, nil
+}
+
+func (v *vault) write(repo *model.Repo, in *model.Secret) error {
+	defer v.cache.expire(repo)
+	v.RLock()
+	defer v.RUnlock()
+	_, err := v.client.Logical().Write(
+		path.Join(v.mount, "data", v.repoPath(repo), in.Name),
+		map[string]interface{}{
+			"data": map[string]interface{}{
+				"value":  in.Value,
+				"event":  strings.Join(in.Events, ","),
+				"image":  strings.Join(in.Images, ","),
+				"branch": strings.Join(in.Branches, ","),
+			},
+		},
+	)
+	return err
+}
+
+// login authenticates with the approle and replaces the token of the
+// client.
+func (v *vault) login(role AppRole) (*api.SecretAuth, error) {
+	res, err := v.client.Logical().Write("auth/approle/login", map[string]interface{}{
+		"role_id":   role.RoleID,
+		"secret_id": role.SecretID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if res == nil || res.Auth == nil {
+		return nil, errors.New("vault: approle login returned no token")
+	}
+	v.Lock()
+	v.client.SetToken(res.Auth.ClientToken)
+	v.Unlock()
+	return res.Auth, nil
+}
+
+// renew renews the token once two thirds of the lease have elapsed, until
+// the context is cancelled or the token does not expire.
+func (v *vault) renew(ctx context.Context, role AppRole, auth *api.SecretAuth) {
+	for auth.LeaseDuration > 0 {
+		wait := time.Duration(auth.LeaseDuration) * v.unit * 2 / 3
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		next, err := v.refresh(role, auth)
+		if err != nil {
+			logrus.Errorf("vault: cannot renew the approle token. %s", err)
+			next = &api.SecretAuth{LeaseDuration: vaultRetry}
+		}
+		auth = next
+	}
+}
+
+// refresh renews the token, or authenticates with the approle again if
+// the token is not renewable or reached its maximum ttl.
+func (v *vault) refresh(role AppRole, auth *api.SecretAuth) (*api.SecretAuth, error) {
+	if auth.Renewable {
+		res, err := v.client.Auth().Token().RenewSelf(auth.LeaseDuration)
+		if err == nil && res != nil && res.Auth != nil && res.Auth.LeaseDuration >= auth.LeaseDuration {
+			return res.Auth, nil
+		}
+	}
+	return v.login(role)
+}
+
+func (v *vault) orgPath(repo *model.Repo) string {
+	return path.Join(v.prefix, repo.Owner)
+}
+
+func (v *vault) repoPath(repo *model.Repo) string {
+	return path.Join(v.prefix, repo.Owner, repo.Name)
+}
+
+// helper function splits a comma-separated list, ignoring empty items.
+func split(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
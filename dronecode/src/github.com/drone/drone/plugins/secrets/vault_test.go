@@ -0,0 +1,242 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/drone/drone/model"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// fakeVault is a Vault server with a kv version 2 secrets engine mounted
+// at secret, and an approle that issues renewable tokens.
+type fakeVault struct {
+	sync.Mutex
+
+	data   map[string]map[string]interface{}
+	tokens map[string]bool
+	logins int
+	renews int
+	expire bool // reject renewals once set
+}
+
+func newFakeVault() *fakeVault {
+	return &fakeVault{
+		data:   map[string]map[string]interface{}{},
+		tokens: map[string]bool{"root": true},
+	}
+}
+
+func (f *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	switch r.URL.Path {
+	case "/v1/auth/approle/login":
+		in := map[string]string{}
+		json.NewDecoder(r.Body).Decode(&in)
+		if in["role_id"] != "drone" || in["secret_id"] != "hunter2" {
+			w.WriteHeader(400)
+			return
+		}
+		f.logins++
+		token := "token" + strconv.Itoa(f.logins)
+		f.tokens[token] = true
+		json.NewEncoder(w).Encode(&api.Secret{Auth: &api.SecretAuth{
+			ClientToken:   token,
+			LeaseDuration: 30,
+			Renewable:     true,
+		}})
+		return
+	}
+
+	token := r.Header.Get("X-Vault-Token")
+	if !f.tokens[token] {
+		w.WriteHeader(403)
+		return
+	}
+
+	switch key := strings.TrimPrefix(r.URL.Path, "/v1/"); {
+	case key == "auth/token/renew-self":
+		if f.expire {
+			delete(f.tokens, token)
+			w.WriteHeader(403)
+			return
+		}
+		f.renews++
+		json.NewEncoder(w).Encode(&api.Secret{Auth: &api.SecretAuth{
+			ClientToken:   token,
+			LeaseDuration: 30,
+			Renewable:     true,
+		}})
+	case r.Method == "LIST" || r.FormValue("list") == "true":
+		dir := strings.TrimPrefix(key, "secret/metadata/") + "/"
+		var keys []string
+		for name := range f.data {
+			if strings.HasPrefix(name, dir) {
+				rest := strings.TrimPrefix(name, dir)
+				if i := strings.Index(rest, "/"); i != -1 {
+					rest = rest[:i+1]
+				}
+				keys = append(keys, rest)
+			}
+		}
+		if len(keys) == 0 {
+			w.WriteHeader(404)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"keys": keys},
+		})
+	case r.Method == "GET":
+		data, ok := f.data[strings.TrimPrefix(key, "secret/data/")]
+		if !ok {
+			w.WriteHeader(404)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"data": data},
+		})
+	case r.Method == "PUT":
+		in := map[string]map[string]interface{}{}
+		json.NewDecoder(r.Body).Decode(&in)
+		f.data[strings.TrimPrefix(key, "secret/data/")] = in["data"]
+		w.WriteHeader(204)
+	case r.Method == "DELETE":
+		delete(f.data, strings.TrimPrefix(key, "secret/metadata/"))
+		w.WriteHeader(204)
+	}
+}
+
+func (f *fakeVault) put(key string, data map[string]interface{}) {
+	f.Lock()
+	f.data[key] = data
+	f.Unlock()
+}
+
+func newVaultClient(t *testing.T, server *httptest.Server) *api.Client {
+	config := api.DefaultConfig()
+	config.Address = server.URL
+	client, err := api.NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestVault(t *testing.T) {
+	fake := newFakeVault()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := newVaultClient(t, server)
+	client.SetToken("root")
+	v := NewVault(client, "secret", "drone", 0)
+
+	repo := &model.Repo{Owner: "octocat", Name: "hello-world"}
+	fake.put("drone/octocat/docker_username", map[string]interface{}{"value": "octocat"})
+	fake.put("drone/octocat/docker_password", map[string]interface{}{"value": "org"})
+	fake.put("drone/octocat/hello-world/other/token", map[string]interface{}{"value": "nested"})
+
+	in := &model.Secret{Name: "docker_password", Value: "repo", Events: []string{model.EventPush}}
+	if err := v.SecretCreate(repo, in); err != nil {
+		t.Fatal(err)
+	}
+
+	secret, err := v.SecretFind(repo, "docker_password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.Value != "repo" || len(secret.Events) != 1 || secret.Events[0] != model.EventPush {
+		t.Errorf("Want the repository secret, got %v", secret)
+	}
+	secret, err = v.SecretFind(repo, "docker_username")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.Value != "octocat" || len(secret.Events) != len(defaultEvents) {
+		t.Errorf("Want the organization secret with the default events, got %v", secret)
+	}
+	if _, err := v.SecretFind(repo, "unknown"); err == nil {
+		t.Errorf("Want error finding an unknown secret")
+	}
+
+	list, err := v.SecretList(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].Name != "docker_password" || list[0].Value != "repo" || list[1].Name != "docker_username" {
+		t.Errorf("Want the repository secret to take precedence, got %v", list)
+	}
+
+	if err := v.SecretDelete(repo, "docker_password"); err != nil {
+		t.Fatal(err)
+	}
+	if secret, err := v.SecretFind(repo, "docker_password"); err != nil || secret.Value != "org" {
+		t.Errorf("Want the organization secret once the repository secret is deleted, got %v, %v", secret, err)
+	}
+}
+
+func TestVaultAppRole(t *testing.T) {
+	fake := newFakeVault()
+	fake.put("drone/octocat/hello-world/password", map[string]interface{}{"value": "hunter2"})
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if _, err := NewVaultAppRole(ctx, newVaultClient(t, server), AppRole{RoleID: "drone", SecretID: "invalid"}, "secret", "drone", 0); err == nil {
+		t.Errorf("Want error authenticating with an invalid approle")
+	}
+
+	// the lease durations are in milliseconds for the test.
+	role := AppRole{RoleID: "drone", SecretID: "hunter2"}
+	v := newVault(newVaultClient(t, server), "secret", "drone", 0)
+	v.unit = time.Millisecond
+	auth, err := v.login(role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go v.renew(ctx, role, auth)
+
+	wait := func(cond func() bool) bool {
+		for i := 0; i < 100; i++ {
+			fake.Lock()
+			ok := cond()
+			fake.Unlock()
+			if ok {
+				return true
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return false
+	}
+	if !wait(func() bool { return fake.renews >= 2 }) {
+		t.Errorf("Want the token renewed")
+	}
+	fake.Lock()
+	fake.expire = true
+	fake.Unlock()
+	if !wait(func() bool { return fake.logins >= 2 }) {
+		t.Errorf("Want a new login once the token cannot be renewed")
+	}
+	// the client switches to the new token once the login returns.
+	repo := &model.Repo{Owner: "octocat", Name: "hello-world"}
+	_, err = v.SecretFind(repo, "password")
+	for i := 0; i < 100 && err != nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+		_, err = v.SecretFind(repo, "password")
+	}
+	if err != nil {
+		t.Errorf("Want the secret found with the new token, got %s", err)
+	}
+}
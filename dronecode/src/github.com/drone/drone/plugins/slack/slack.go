@@ -0,0 +1,161 @@
+// Package slack implements system chat notifications, which post build
+// results to Slack or Mattermost incoming webhooks.
+package slack
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"text/template"
+
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/plugins/internal"
+)
+
+// DefaultTemplate is the default template of build result messages.
+const DefaultTemplate = `*{{ .Build.Status }}* <{{ .Link }}|{{ .Repo.FullName }}#{{ .Build.Number }}> ({{ .Build.Branch }}) by {{ .Build.Author }}`
+
+// Opts defines the notification configuration. Notifications are posted
+// for the builds of the repositories and branches matching the glob
+// patterns, or for all builds if no patterns are configured.
+type Opts struct {
+	Webhooks []string
+	Channel  string
+	Username string
+	Template string
+	Repos    []string
+	Branches []string
+}
+
+type slack struct {
+	opts Opts
+	tmpl *template.Template
+}
+
+// New returns a chat service that posts build notifications to the
+// webhooks. It returns an error if the message template is invalid.
+func New(opts Opts) (model.ChatService, error) {
+	if opts.Template == "" {
+		opts.Template = DefaultTemplate
+	}
+	if opts.Username == "" {
+		opts.Username = "drone"
+	}
+	tmpl, err := template.New("_").Parse(opts.Template)
+	if err != nil {
+		return nil, err
+	}
+	return &slack{opts: opts, tmpl: tmpl}, nil
+}
+
+type payload struct {
+	Channel     string        `json:"channel,omitempty"`
+	Username    string        `json:"username,omitempty"`
+	Attachments []*attachment `json:"attachments"`
+}
+
+type attachment struct {
+	Fallback string    `json:"fallback"`
+	Text     string    `json:"text"`
+	Color    string    `json:"color"`
+	Markdown []string  `json:"mrkdwn_in"`
+	Actions  []*action `json:"actions,omitempty"`
+}
+
+type action struct {
+	Type  string `json:"type"`
+	Text  string `json:"text"`
+	URL   string `json:"url"`
+	Style string `json:"style,omitempty"`
+}
+
+// ChatBuild posts the result of the build, if the repository and branch
+// match the configured patterns.
+func (s *slack) ChatBuild(repo *model.Repo, build *model.Build, link string) error {
+	if !s.match(repo, build) {
+		return nil
+	}
+	var buf bytes.Buffer
+	err := s.tmpl.Execute(&buf, map[string]interface{}{
+		"Repo":  repo,
+		"Build": build,
+		"Link":  link,
+	})
+	if err != nil {
+		return err
+	}
+	return s.post(&attachment{
+		Fallback: buf.String(),
+		Text:     buf.String(),
+		Color:    color(build.Status),
+	})
+}
+
+// ChatApproval posts an approval request for the blocked build, with a link
+// to the build page where the build is approved or declined, if the
+// repository and branch match the configured patterns.
+func (s *slack) ChatApproval(repo *model.Repo, build *model.Build, link string) error {
+	if !s.match(repo, build) {
+		return nil
+	}
+	text := fmt.Sprintf("<%s|%s#%d> (%s) by %s is waiting for approval",
+		link, repo.FullName, build.Number, build.Branch, build.Sender)
+	return s.post(&attachment{
+		Fallback: text,
+		Text:     text,
+		Color:    "warning",
+		Actions: []*action{
+			{Type: "button", Text: "Approve or decline", URL: link, Style: "primary"},
+		},
+	})
+}
+
+// helper function posts the message to each webhook.
+func (s *slack) post(a *attachment) error {
+	a.Markdown = []string{"text"}
+	in := &payload{
+		Channel:     s.opts.Channel,
+		Username:    s.opts.Username,
+		Attachments: []*attachment{a},
+	}
+	var last error
+	for _, webhook := range s.opts.Webhooks {
+		if err := internal.Send("POST", webhook, in, nil); err != nil {
+			last = err
+		}
+	}
+	return last
+}
+
+// helper function returns true if the repository and branch of the build
+// match the configured patterns.
+func (s *slack) match(repo *model.Repo, build *model.Build) bool {
+	return matchAny(s.opts.Repos, repo.FullName) &&
+		matchAny(s.opts.Branches, build.Branch)
+}
+
+// helper function returns true if the value matches any of the glob
+// patterns, or if the list of patterns is empty.
+func matchAny(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if match, _ := filepath.Match(pattern, value); match {
+			return true
+		}
+	}
+	return false
+}
+
+// helper function returns the attachment color of the build status.
+func color(status string) string {
+	switch status {
+	case model.StatusSuccess:
+		return "good"
+	case model.StatusFailure, model.StatusError, model.StatusKilled:
+		return "danger"
+	default:
+		return "warning"
+	}
+}
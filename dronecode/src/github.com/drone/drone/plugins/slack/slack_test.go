@@ -0,0 +1,75 @@
+package slack
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/drone/drone/model"
+)
+
+func TestChatBuild(t *testing.T) {
+	var received []*payload
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := new(payload)
+		json.NewDecoder(r.Body).Decode(in)
+		received = append(received, in)
+	}))
+	defer s.Close()
+
+	chat, err := New(Opts{
+		Webhooks: []string{s.URL},
+		Channel:  "#builds",
+		Repos:    []string{"octocat/*"},
+		Branches: []string{"master"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &model.Repo{FullName: "octocat/hello-world"}
+	build := &model.Build{Number: 1, Status: model.StatusFailure, Branch: "master", Author: "octocat"}
+	if err := chat.ChatBuild(repo, build, "http://drone.example.com/octocat/hello-world/1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != 1 {
+		t.Fatalf("Want 1 message, got %d", len(received))
+	}
+	got := received[0]
+	if got.Channel != "#builds" || got.Username != "drone" {
+		t.Errorf("Unexpected channel %s or username %s", got.Channel, got.Username)
+	}
+	want := "*failure* <http://drone.example.com/octocat/hello-world/1|octocat/hello-world#1> (master) by octocat"
+	if got.Attachments[0].Text != want {
+		t.Errorf("Want message %q, got %q", want, got.Attachments[0].Text)
+	}
+	if got.Attachments[0].Color != "danger" {
+		t.Errorf("Want danger color, got %s", got.Attachments[0].Color)
+	}
+
+	// builds of other branches and repositories are not posted.
+	chat.ChatBuild(repo, &model.Build{Branch: "develop"}, "")
+	chat.ChatBuild(&model.Repo{FullName: "drone/drone"}, build, "")
+	if len(received) != 1 {
+		t.Errorf("Want unmatched builds ignored, got %d messages", len(received))
+	}
+
+	build.Status = model.StatusBlocked
+	build.Sender = "janedoe"
+	if err := chat.ChatApproval(repo, build, "http://drone.example.com/octocat/hello-world/1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != 2 || len(received[1].Attachments[0].Actions) != 1 {
+		t.Fatalf("Want approval message with action link")
+	}
+	if got := received[1].Attachments[0].Actions[0].URL; got != "http://drone.example.com/octocat/hello-world/1" {
+		t.Errorf("Want action link to the build, got %s", got)
+	}
+}
+
+func TestChatTemplate(t *testing.T) {
+	if _, err := New(Opts{Template: "{{ .Build.Number "}); err == nil {
+		t.Errorf("Want error parsing invalid template")
+	}
+}
@@ -0,0 +1,140 @@
+// Package webhook implements system webhooks, which post signed json
+// payloads of build lifecycle events to external endpoints.
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/plugins/internal"
+)
+
+// Headers sent with each webhook delivery, in addition to the signature
+// header, which contains the hex encoded hmac sha256 signature of the
+// request body.
+const (
+	EventHeader    = "X-Drone-Event"
+	DeliveryHeader = "X-Drone-Delivery"
+)
+
+type webhook struct {
+	store     model.DeliveryStore
+	endpoints []string
+	secret    string
+	client    *http.Client
+	attempts  int
+	backoff   time.Duration
+}
+
+// New returns a webhook service that posts the payloads to the endpoints,
+// signed with the shared secret. Failed deliveries are retried with an
+// exponential backoff, and each delivery is recorded in the store.
+func New(store model.DeliveryStore, endpoints []string, secret string) model.WebhookService {
+	return &webhook{
+		store:     store,
+		endpoints: endpoints,
+		secret:    secret,
+		client:    &http.Client{Timeout: time.Second * 30},
+		attempts:  5,
+		backoff:   time.Second * 5,
+	}
+}
+
+// WebhookSend posts the payload to each endpoint in the background.
+func (w *webhook) WebhookSend(payload *model.WebhookPayload) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	for _, endpoint := range w.endpoints {
+		delivery := &model.Delivery{
+			Endpoint: endpoint,
+			Event:    payload.Event,
+			Payload:  string(data),
+			Created:  now,
+			Updated:  now,
+		}
+		if err := w.store.DeliveryCreate(delivery); err != nil {
+			return err
+		}
+		go w.deliver(delivery, w.attempts)
+	}
+	return nil
+}
+
+// WebhookRedeliver posts the payload of a previous delivery to the
+// endpoint again, once.
+func (w *webhook) WebhookRedeliver(delivery *model.Delivery) error {
+	return w.post(delivery)
+}
+
+// helper function posts the delivery until it succeeds or the number of
+// attempts is exceeded.
+func (w *webhook) deliver(delivery *model.Delivery, attempts int) {
+	backoff := w.backoff
+	for i := 0; i < attempts; i++ {
+		if i != 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		err := w.post(delivery)
+		if err == nil {
+			return
+		}
+		logrus.Debugf("webhook: cannot deliver %s to %s. attempt %d. %s",
+			delivery.Event, delivery.Endpoint, delivery.Attempts, err)
+	}
+	logrus.Errorf("webhook: cannot deliver %s to %s. %s",
+		delivery.Event, delivery.Endpoint, delivery.Error)
+}
+
+// helper function posts the delivery once and records the result.
+func (w *webhook) post(delivery *model.Delivery) error {
+	err := w.do(delivery)
+	delivery.Attempts++
+	delivery.Updated = time.Now().Unix()
+	delivery.Error = ""
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+	if uerr := w.store.DeliveryUpdate(delivery); uerr != nil {
+		logrus.Errorf("webhook: cannot update delivery %d. %s", delivery.ID, uerr)
+	}
+	return err
+}
+
+func (w *webhook) do(delivery *model.Delivery) error {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequest("POST", delivery.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	if w.secret != "" {
+		req.Header.Set(internal.SignatureHeader, internal.Sign(body, w.secret))
+	}
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		delivery.Code = 0
+		return err
+	}
+	defer res.Body.Close()
+	delivery.Code = res.StatusCode
+	if res.StatusCode > 299 {
+		out, _ := ioutil.ReadAll(io.LimitReader(res.Body, 500))
+		return fmt.Errorf("endpoint responded with status %d: %s", res.StatusCode, bytes.TrimSpace(out))
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/plugins/internal"
+)
+
+func TestWebhookSend(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests int
+		received = make(chan *model.WebhookPayload, 1)
+	)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		n := requests
+		mu.Unlock()
+
+		body, _ := ioutil.ReadAll(r.Body)
+		if got, want := r.Header.Get(internal.SignatureHeader), internal.Sign(body, "correct-horse-battery-staple"); got != want {
+			t.Errorf("Want signature %s, got %s", want, got)
+		}
+		if got, want := r.Header.Get(EventHeader), model.WebhookBuildFinished; got != want {
+			t.Errorf("Want event header %s, got %s", want, got)
+		}
+		// fail the first delivery attempt to test retries.
+		if n == 1 {
+			w.WriteHeader(500)
+			return
+		}
+		payload := new(model.WebhookPayload)
+		json.Unmarshal(body, payload)
+		received <- payload
+	}))
+	defer s.Close()
+
+	store := &deliveryStore{}
+	hook := New(store, []string{s.URL}, "correct-horse-battery-staple").(*webhook)
+	hook.backoff = time.Millisecond
+
+	err := hook.WebhookSend(&model.WebhookPayload{
+		Event: model.WebhookBuildFinished,
+		Repo:  &model.Repo{FullName: "octocat/hello-world"},
+		Build: &model.Build{Number: 1, Status: model.StatusSuccess},
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	select {
+	case payload := <-received:
+		if got, want := payload.Repo.FullName, "octocat/hello-world"; got != want {
+			t.Errorf("Want payload repo %s, got %s", want, got)
+		}
+	case <-time.After(time.Second * 5):
+		t.Errorf("Want payload delivered")
+		return
+	}
+
+	time.Sleep(time.Millisecond * 50)
+	delivery := store.get()
+	if got, want := delivery.Attempts, 2; got != want {
+		t.Errorf("Want %d delivery attempts, got %d", want, got)
+	}
+	if got, want := delivery.Code, 200; got != want {
+		t.Errorf("Want delivery status %d, got %d", want, got)
+	}
+	if delivery.Error != "" {
+		t.Errorf("Want delivery error cleared, got %s", delivery.Error)
+	}
+}
+
+// deliveryStore is an in-memory delivery store that records the most
+// recent delivery.
+type deliveryStore struct {
+	sync.Mutex
+	delivery model.Delivery
+}
+
+func (s *deliveryStore) get() model.Delivery {
+	s.Lock()
+	defer s.Unlock()
+	return s.delivery
+}
+
+func (s *deliveryStore) DeliveryFind(int64) (*model.Delivery, error) { return nil, nil }
+func (s *deliveryStore) DeliveryList(int) ([]*model.Delivery, error) { return nil, nil }
+
+func (s *deliveryStore) DeliveryCreate(d *model.Delivery) error {
+	s.Lock()
+	defer s.Unlock()
+	d.ID = 1
+	s.delivery = *d
+	return nil
+}
+
+func (s *deliveryStore) DeliveryUpdate(d *model.Delivery) error {
+	s.Lock()
+	defer s.Unlock()
+	s.delivery = *d
+	return nil
+}
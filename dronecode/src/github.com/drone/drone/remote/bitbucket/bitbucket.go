@@ -37,6 +37,11 @@ func New(client, secret string) remote.Remote {
 	}
 }
 
+// Ping returns an error if the remote system is not reachable.
+func (c *config) Ping() error {
+	return remote.PingURL(c.API, false)
+}
+
 // Login authenticates an account with Bitbucket using the oauth2 protocol. The
 // Bitbucket account details are returned when the user is successfully authenticated.
 func (c *config) Login(w http.ResponseWriter, req *http.Request) (*model.User, error) {
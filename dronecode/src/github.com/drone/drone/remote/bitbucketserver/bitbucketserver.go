@@ -26,7 +26,9 @@ const (
 	accessTokenURL    = "%s/plugins/servlet/oauth/access-token"
 )
 
-// Opts defines configuration options.
+// Opts defines configuration options. When the oauth1 consumer key is not
+// configured, users authenticate with their username and a personal access
+// token instead.
 type Opts struct {
 	URL               string // Stash server url.
 	Username          string // Git machine account username.
@@ -55,13 +57,17 @@ func New(opts Opts) (remote.Remote, error) {
 		SkipVerify: opts.SkipVerify,
 	}
 
+	// personal access tokens are used to authenticate the api and, unless a
+	// machine account is configured, to clone the repository.
+	if opts.ConsumerKey == "" {
+		return config, nil
+	}
+
 	switch {
 	case opts.Username == "":
 		return nil, fmt.Errorf("Must have a git machine account username")
 	case opts.Password == "":
 		return nil, fmt.Errorf("Must have a git machine account password")
-	case opts.ConsumerKey == "":
-		return nil, fmt.Errorf("Must have a oauth1 consumer key")
 	}
 
 	if opts.ConsumerRSA == "" && opts.ConsumerRSAString == "" {
@@ -89,7 +95,15 @@ func New(opts Opts) (remote.Remote, error) {
 	return config, nil
 }
 
+// Ping returns an error if the remote system is not reachable.
+func (c *Config) Ping() error {
+	return remote.PingURL(c.URL, c.SkipVerify)
+}
+
 func (c *Config) Login(res http.ResponseWriter, req *http.Request) (*model.User, error) {
+	if c.Consumer == nil {
+		return c.loginToken(res, req)
+	}
 	requestToken, url, err := c.Consumer.GetRequestTokenAndUrl("oob")
 	if err != nil {
 		return nil, err
@@ -116,14 +130,43 @@ func (c *Config) Login(res http.ResponseWriter, req *http.Request) (*model.User,
 
 }
 
+// loginToken authenticates an account using the username and a personal
+// access token submitted with the login form.
+func (c *Config) loginToken(res http.ResponseWriter, req *http.Request) (*model.User, error) {
+	var (
+		username = req.FormValue("username")
+		password = req.FormValue("password")
+	)
+
+	// if the username or token is empty we re-direct to the login screen.
+	if len(username) == 0 || len(password) == 0 {
+		http.Redirect(res, req, "/login/form", http.StatusSeeOther)
+		return nil, nil
+	}
+
+	client := internal.NewClientWithBearer(c.URL, password, c.SkipVerify)
+	user, err := client.FindUser(username)
+	if err != nil {
+		return nil, err
+	}
+	return convertUser(user, &oauth.AccessToken{Token: password}), nil
+}
+
 // Auth is not supported by the Stash driver.
 func (*Config) Auth(token, secret string) (string, error) {
 	return "", fmt.Errorf("Not Implemented")
 }
 
-// Teams is not supported by the Stash driver.
-func (*Config) Teams(u *model.User) ([]*model.Team, error) {
+// Teams returns the Bitbucket projects visible to the account.
+func (c *Config) Teams(u *model.User) ([]*model.Team, error) {
+	projects, err := c.newClient(u).FindProjects()
+	if err != nil {
+		return nil, err
+	}
 	var teams []*model.Team
+	for _, project := range projects {
+		teams = append(teams, convertTeam(project))
+	}
 	return teams, nil
 }
 
@@ -133,7 +176,7 @@ func (*Config) TeamPerm(u *model.User, org string) (*model.Perm, error) {
 }
 
 func (c *Config) Repo(u *model.User, owner, name string) (*model.Repo, error) {
-	repo, err := internal.NewClientWithToken(c.URL, c.Consumer, u.Token).FindRepo(owner, name)
+	repo, err := c.newClient(u).FindRepo(owner, name)
 	if err != nil {
 		return nil, err
 	}
@@ -141,7 +184,7 @@ func (c *Config) Repo(u *model.User, owner, name string) (*model.Repo, error) {
 }
 
 func (c *Config) Repos(u *model.User) ([]*model.RepoLite, error) {
-	repos, err := internal.NewClientWithToken(c.URL, c.Consumer, u.Token).FindRepos()
+	repos, err := c.newClient(u).FindRepos()
 	if err != nil {
 		return nil, err
 	}
@@ -154,19 +197,19 @@ func (c *Config) Repos(u *model.User) ([]*model.RepoLite, error) {
 }
 
 func (c *Config) Perm(u *model.User, owner, repo string) (*model.Perm, error) {
-	client := internal.NewClientWithToken(c.URL, c.Consumer, u.Token)
+	client := c.newClient(u)
 
 	return client.FindRepoPerms(owner, repo)
 }
 
 func (c *Config) File(u *model.User, r *model.Repo, b *model.Build, f string) ([]byte, error) {
-	client := internal.NewClientWithToken(c.URL, c.Consumer, u.Token)
+	client := c.newClient(u)
 
 	return client.FindFileForRepo(r.Owner, r.Name, f, b.Ref)
 }
 
 func (c *Config) FileRef(u *model.User, r *model.Repo, ref, f string) ([]byte, error) {
-	client := internal.NewClientWithToken(c.URL, c.Consumer, u.Token)
+	client := c.newClient(u)
 
 	return client.FindFileForRepo(r.Owner, r.Name, f, ref)
 }
@@ -181,7 +224,7 @@ func (c *Config) Status(u *model.User, r *model.Repo, b *model.Build, link strin
 		Url:   link,
 	}
 
-	client := internal.NewClientWithToken(c.URL, c.Consumer, u.Token)
+	client := c.newClient(u)
 
 	return client.CreateStatus(b.Commit, &status)
 }
@@ -198,6 +241,13 @@ func (c *Config) Netrc(user *model.User, r *model.Repo) (*model.Netrc, error) {
 	if err != nil {
 		return nil, err
 	}
+	if c.Password == "" {
+		return &model.Netrc{
+			Machine:  host,
+			Login:    user.Login,
+			Password: user.Token,
+		}, nil
+	}
 	return &model.Netrc{
 		Machine:  host,
 		Login:    c.Username,
@@ -206,13 +256,19 @@ func (c *Config) Netrc(user *model.User, r *model.Repo) (*model.Netrc, error) {
 }
 
 func (c *Config) Activate(u *model.User, r *model.Repo, link string) error {
-	client := internal.NewClientWithToken(c.URL, c.Consumer, u.Token)
-
-	return client.CreateHook(r.Owner, r.Name, link)
+	return c.newClient(u).CreateWebhook(r.Owner, r.Name, link)
 }
 
+// Deactivate removes the native webhooks and, when the consumer is
+// configured, the post-receive plugin hooks registered with the repository.
 func (c *Config) Deactivate(u *model.User, r *model.Repo, link string) error {
-	client := internal.NewClientWithToken(c.URL, c.Consumer, u.Token)
+	client := c.newClient(u)
+	if err := client.DeleteWebhook(r.Owner, r.Name, link); err != nil {
+		return err
+	}
+	if c.Consumer == nil {
+		return nil
+	}
 	return client.DeleteHook(r.Owner, r.Name, link)
 }
 
@@ -220,6 +276,15 @@ func (c *Config) Hook(r *http.Request) (*model.Repo, *model.Build, error) {
 	return parseHook(r, c.URL)
 }
 
+// helper function to return the Bitbucket client, authenticated with the
+// oauth1 access token or the personal access token.
+func (c *Config) newClient(u *model.User) *internal.Client {
+	if c.Consumer == nil {
+		return internal.NewClientWithBearer(c.URL, u.Token, c.SkipVerify)
+	}
+	return internal.NewClientWithToken(c.URL, c.Consumer, u.Token)
+}
+
 func CreateConsumer(URL string, ConsumerKey string, PrivateKey *rsa.PrivateKey) *oauth.Consumer {
 	consumer := oauth.NewRSAConsumer(
 		ConsumerKey,
@@ -136,6 +136,74 @@ func convertPushHook(hook *internal.PostHook, baseURL string) *model.Build {
 	return build
 }
 
+// convertRefsChangedHook is a helper function used to convert a native
+// Bitbucket refs changed webhook to the Drone build struct.
+func convertRefsChangedHook(hook *internal.RefsChangedHook, baseURL string) *model.Build {
+	change := hook.Changes[0]
+	build := &model.Build{
+		Commit:    change.ToHash,
+		Branch:    strings.TrimPrefix(strings.TrimPrefix(change.RefID, "refs/heads/"), "refs/tags/"),
+		Avatar:    avatarLink(hook.Actor.EmailAddress),
+		Author:    hook.Actor.Slug,
+		Email:     hook.Actor.EmailAddress,
+		Sender:    hook.Actor.Slug,
+		Timestamp: time.Now().UTC().Unix(),
+		Ref:       change.RefID,
+		Link:      fmt.Sprintf("%s/projects/%s/repos/%s/commits/%s", baseURL, hook.Repository.Project.Key, hook.Repository.Slug, change.ToHash),
+	}
+	if strings.HasPrefix(change.RefID, "refs/tags/") {
+		build.Event = model.EventTag
+	} else {
+		build.Event = model.EventPush
+	}
+	return build
+}
+
+// convertPullRequestHook is a helper function used to convert a native
+// Bitbucket pull request webhook to the Drone build struct.
+func convertPullRequestHook(hook *internal.PullRequestHook, baseURL string) *model.Build {
+	pr := hook.PullRequest
+	build := &model.Build{
+		Event:     model.EventPull,
+		Commit:    pr.FromRef.LatestCommit,
+		Branch:    pr.ToRef.DisplayID,
+		Ref:       fmt.Sprintf("refs/pull-requests/%d/from", pr.ID),
+		Refspec:   fmt.Sprintf("%s:%s", pr.FromRef.DisplayID, pr.ToRef.DisplayID),
+		Remote:    fmt.Sprintf("%s/scm/%s/%s.git", baseURL, strings.ToLower(pr.FromRef.Repository.Project.Key), pr.FromRef.Repository.Slug),
+		Title:     pr.Title,
+		Message:   pr.Title,
+		Avatar:    avatarLink(hook.Actor.EmailAddress),
+		Author:    hook.Actor.Slug,
+		Email:     hook.Actor.EmailAddress,
+		Sender:    hook.Actor.Slug,
+		Timestamp: time.Now().UTC().Unix(),
+	}
+	for _, link := range pr.Links.Self {
+		build.Link = link.Href
+	}
+	return build
+}
+
+// convertHookRepo is a helper function used to convert a native Bitbucket
+// webhook repository to the Drone repository structure.
+func convertHookRepo(from *internal.HookRepo) *model.Repo {
+	return &model.Repo{
+		Name:     from.Slug,
+		Owner:    from.Project.Key,
+		FullName: fmt.Sprintf("%s/%s", from.Project.Key, from.Slug),
+		Branch:   "master",
+		Kind:     model.RepoGit,
+	}
+}
+
+// convertTeam is a helper function used to convert a Bitbucket project to
+// the Drone team structure.
+func convertTeam(from *internal.Project) *model.Team {
+	return &model.Team{
+		Login: from.Key,
+	}
+}
+
 // convertUser is a helper function used to convert a Bitbucket user account
 // structure to the Drone User structure.
 func convertUser(from *internal.User, token *oauth.AccessToken) *model.User {
@@ -145,5 +145,47 @@ func Test_helper(t *testing.T) {
 			g.Assert(build.Ref).Equal("refs/tags/v1")
 			g.Assert(build.Message).Equal("message")
 		})
+
+		g.It("should convert refs changed webhook to build", func() {
+			hook := &internal.RefsChangedHook{}
+			hook.Actor.Slug = "octocat"
+			hook.Actor.EmailAddress = "octocat@github.com"
+			hook.Repository.Slug = "hello-world"
+			hook.Repository.Project.Key = "PRJ"
+			hook.Changes = append(hook.Changes, struct {
+				RefID    string `json:"refId"`
+				FromHash string `json:"fromHash"`
+				ToHash   string `json:"toHash"`
+				Type     string `json:"type"`
+			}{RefID: "refs/heads/master", ToHash: "73f9c44d", Type: "UPDATE"})
+
+			build := convertRefsChangedHook(hook, "http://base.com")
+			g.Assert(build.Event).Equal(model.EventPush)
+			g.Assert(build.Branch).Equal("master")
+			g.Assert(build.Commit).Equal("73f9c44d")
+			g.Assert(build.Sender).Equal("octocat")
+			g.Assert(build.Link).Equal("http://base.com/projects/PRJ/repos/hello-world/commits/73f9c44d")
+		})
+
+		g.It("should convert pull request webhook to build", func() {
+			hook := &internal.PullRequestHook{}
+			hook.Actor.Slug = "octocat"
+			hook.PullRequest.ID = 42
+			hook.PullRequest.Title = "Update the readme"
+			hook.PullRequest.FromRef.DisplayID = "feature"
+			hook.PullRequest.FromRef.LatestCommit = "73f9c44d"
+			hook.PullRequest.FromRef.Repository.Slug = "hello-world"
+			hook.PullRequest.FromRef.Repository.Project.Key = "PRJ"
+			hook.PullRequest.ToRef.DisplayID = "master"
+
+			build := convertPullRequestHook(hook, "http://base.com")
+			g.Assert(build.Event).Equal(model.EventPull)
+			g.Assert(build.Branch).Equal("master")
+			g.Assert(build.Commit).Equal("73f9c44d")
+			g.Assert(build.Ref).Equal("refs/pull-requests/42/from")
+			g.Assert(build.Refspec).Equal("feature:master")
+			g.Assert(build.Remote).Equal("http://base.com/scm/prj/hello-world.git")
+			g.Assert(build.Title).Equal("Update the readme")
+		})
 	})
 }
@@ -2,6 +2,7 @@ package internal
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -27,8 +28,18 @@ const (
 	pathHookEnabled  = "%s/rest/api/1.0/projects/%s/repos/%s/settings/hooks/%s/enabled"
 	pathHookSettings = "%s/rest/api/1.0/projects/%s/repos/%s/settings/hooks/%s/settings"
 	pathStatus       = "%s/rest/build-status/1.0/commits/%s"
+	pathProjects     = "%s/rest/api/1.0/projects?start=%d&limit=%d"
+	pathWebhooks     = "%s/rest/api/1.0/projects/%s/repos/%s/webhooks"
+	pathWebhook      = "%s/rest/api/1.0/projects/%s/repos/%s/webhooks/%d"
 )
 
+// webhookEvents are the native webhook events that trigger builds.
+var webhookEvents = []string{
+	"repo:refs_changed",
+	"pr:opened",
+	"pr:from_ref_updated",
+}
+
 type Client struct {
 	client      *http.Client
 	base        string
@@ -45,6 +56,37 @@ func NewClientWithToken(url string, consumer *oauth.Consumer, AccessToken string
 	return &Client{client, url, AccessToken}
 }
 
+// NewClientWithBearer returns a client that authenticates using a personal
+// access token.
+func NewClientWithBearer(url string, AccessToken string, skipVerify bool) *Client {
+	transport := &bearerTransport{
+		token: AccessToken,
+		base:  http.DefaultTransport,
+	}
+	if skipVerify {
+		transport.base = &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+	}
+	return &Client{&http.Client{Transport: transport}, url, AccessToken}
+}
+
+// bearerTransport adds the personal access token to each request.
+type bearerTransport struct {
+	token string
+	base  http.RoundTripper
+}
+
+func (t *bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	clone := *req
+	clone.Header = http.Header{}
+	for k, v := range req.Header {
+		clone.Header[k] = v
+	}
+	clone.Header.Set("Authorization", "Bearer "+t.token)
+	return t.base.RoundTrip(&clone)
+}
+
 func (c *Client) FindCurrentUser() (*User, error) {
 	CurrentUserIdResponse, err := c.client.Get(fmt.Sprintf(currentUserId, c.base))
 	if err != nil {
@@ -78,6 +120,112 @@ func (c *Client) FindCurrentUser() (*User, error) {
 
 }
 
+// FindUser returns the named user. It is used to verify personal access
+// tokens, which are not supported by the whoami endpoint.
+func (c *Client) FindUser(slug string) (*User, error) {
+	response, err := c.client.Get(fmt.Sprintf(pathUser, c.base, slug))
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unable to find user %s: %s", slug, response.Status)
+	}
+	user := new(User)
+	err = json.NewDecoder(response.Body).Decode(user)
+	return user, err
+}
+
+// FindProjects returns the projects visible to the user.
+func (c *Client) FindProjects() ([]*Project, error) {
+	var projects []*Project
+	for start := 0; ; {
+		response, err := c.client.Get(fmt.Sprintf(pathProjects, c.base, start, 100))
+		if err != nil {
+			return nil, err
+		}
+		page := new(Projects)
+		err = json.NewDecoder(response.Body).Decode(page)
+		response.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, page.Values...)
+		if page.IsLastPage || len(page.Values) == 0 {
+			return projects, nil
+		}
+		start = page.NextPageStart
+	}
+}
+
+// CreateWebhook registers a native repository webhook for push and pull
+// request events, unless a webhook for the link already exists.
+func (c *Client) CreateWebhook(owner string, name string, link string) error {
+	hooks, err := c.findWebhooks(owner, name)
+	if err != nil {
+		return err
+	}
+	for _, hook := range hooks {
+		if hook.URL == link {
+			return nil
+		}
+	}
+	body, err := json.Marshal(&Webhook{
+		Name:   "drone",
+		URL:    link,
+		Events: webhookEvents,
+		Active: true,
+	})
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest("POST", fmt.Sprintf(pathWebhooks, c.base, owner, name), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Add("Content-Type", "application/json")
+	response, err := c.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode > 299 {
+		return fmt.Errorf("Unable to create webhook: %s", response.Status)
+	}
+	return nil
+}
+
+// DeleteWebhook removes the native repository webhooks matching the link.
+func (c *Client) DeleteWebhook(owner string, name string, link string) error {
+	hooks, err := c.findWebhooks(owner, name)
+	if err != nil {
+		return err
+	}
+	for _, hook := range hooks {
+		if !strings.Contains(hook.URL, link) {
+			continue
+		}
+		if err := c.doDelete(fmt.Sprintf(pathWebhook, c.base, owner, name, hook.ID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) findWebhooks(owner string, name string) ([]*Webhook, error) {
+	response, err := c.client.Get(fmt.Sprintf(pathWebhooks, c.base, owner, name))
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unable to list webhooks: %s", response.Status)
+	}
+	hooks := new(Webhooks)
+	err = json.NewDecoder(response.Body).Decode(hooks)
+	return hooks.Values, err
+}
+
 func (c *Client) FindRepo(owner string, name string) (*Repo, error) {
 	urlString := fmt.Sprintf(pathRepo, c.base, owner, name)
 	response, err := c.client.Get(urlString)
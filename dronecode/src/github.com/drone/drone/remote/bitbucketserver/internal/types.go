@@ -205,3 +205,72 @@ type HookSettings struct {
 	HookURL18 string `json:"hook-url-18,omitempty"`
 	HookURL19 string `json:"hook-url-19,omitempty"`
 }
+
+type Project struct {
+	ID     int    `json:"id"`
+	Key    string `json:"key"`
+	Name   string `json:"name"`
+	Public bool   `json:"public"`
+	Type   string `json:"type"`
+}
+
+type Projects struct {
+	IsLastPage    bool       `json:"isLastPage"`
+	NextPageStart int        `json:"nextPageStart"`
+	Values        []*Project `json:"values"`
+}
+
+// Webhook represents a native Bitbucket Server repository webhook.
+type Webhook struct {
+	ID     int      `json:"id,omitempty"`
+	Name   string   `json:"name"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Active bool     `json:"active"`
+}
+
+type Webhooks struct {
+	IsLastPage bool       `json:"isLastPage"`
+	Values     []*Webhook `json:"values"`
+}
+
+type HookRepo struct {
+	Slug    string  `json:"slug"`
+	Name    string  `json:"name"`
+	Project Project `json:"project"`
+}
+
+type HookRef struct {
+	ID           string   `json:"id"`
+	DisplayID    string   `json:"displayId"`
+	LatestCommit string   `json:"latestCommit"`
+	Repository   HookRepo `json:"repository"`
+}
+
+// RefsChangedHook is the payload of the native repo:refs_changed webhook.
+type RefsChangedHook struct {
+	EventKey   string   `json:"eventKey"`
+	Actor      User     `json:"actor"`
+	Repository HookRepo `json:"repository"`
+	Changes    []struct {
+		RefID    string `json:"refId"`
+		FromHash string `json:"fromHash"`
+		ToHash   string `json:"toHash"`
+		Type     string `json:"type"`
+	} `json:"changes"`
+}
+
+// PullRequestHook is the payload of the native pull request webhooks.
+type PullRequestHook struct {
+	EventKey    string `json:"eventKey"`
+	Actor       User   `json:"actor"`
+	PullRequest struct {
+		ID      int     `json:"id"`
+		Title   string  `json:"title"`
+		FromRef HookRef `json:"fromRef"`
+		ToRef   HookRef `json:"toRef"`
+		Links   struct {
+			Self []SelfRefLink `json:"self"`
+		} `json:"links"`
+	} `json:"pullRequest"`
+}
@@ -3,14 +3,33 @@ package bitbucketserver
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
+
 	"github.com/drone/drone/model"
 	"github.com/drone/drone/remote/bitbucketserver/internal"
-	"net/http"
+)
+
+const (
+	hookEvent       = "X-Event-Key"
+	hookRefsChanged = "repo:refs_changed"
+	hookPullOpened  = "pr:opened"
+	hookPullUpdated = "pr:from_ref_updated"
 )
 
 // parseHook parses a Bitbucket hook from an http.Request request and returns
-// Repo and Build detail. TODO: find a way to support PR hooks
+// Repo and Build detail. Native webhooks are identified by the event key
+// header, otherwise the payload is parsed as a post-receive plugin hook.
 func parseHook(r *http.Request, baseURL string) (*model.Repo, *model.Build, error) {
+	switch r.Header.Get(hookEvent) {
+	case hookRefsChanged:
+		return parseRefsChangedHook(r, baseURL)
+	case hookPullOpened, hookPullUpdated:
+		return parsePullRequestHook(r, baseURL)
+	case "":
+	default:
+		return nil, nil, nil
+	}
+
 	hook := new(internal.PostHook)
 	if err := json.NewDecoder(r.Body).Decode(hook); err != nil {
 		return nil, nil, err
@@ -26,3 +45,23 @@ func parseHook(r *http.Request, baseURL string) (*model.Repo, *model.Build, erro
 
 	return repo, build, nil
 }
+
+func parseRefsChangedHook(r *http.Request, baseURL string) (*model.Repo, *model.Build, error) {
+	hook := new(internal.RefsChangedHook)
+	if err := json.NewDecoder(r.Body).Decode(hook); err != nil {
+		return nil, nil, err
+	}
+	// branch deletions are ignored.
+	if len(hook.Changes) == 0 || hook.Changes[0].Type == "DELETE" {
+		return nil, nil, nil
+	}
+	return convertHookRepo(&hook.Repository), convertRefsChangedHook(hook, baseURL), nil
+}
+
+func parsePullRequestHook(r *http.Request, baseURL string) (*model.Repo, *model.Build, error) {
+	hook := new(internal.PullRequestHook)
+	if err := json.NewDecoder(r.Body).Decode(hook); err != nil {
+		return nil, nil, err
+	}
+	return convertHookRepo(&hook.PullRequest.ToRef.Repository), convertPullRequestHook(hook, baseURL), nil
+}
@@ -52,6 +52,11 @@ func New(opts Opts) (remote.Remote, error) {
 	}, nil
 }
 
+// Ping returns an error if the remote system is not reachable.
+func (c *client) Ping() error {
+	return remote.PingURL(c.URL, c.SkipVerify)
+}
+
 // Login authenticates an account with Gerrit using oauth authenticaiton. The
 // Gerrit account details are returned when the user is successfully authenticated.
 func (c *client) Login(res http.ResponseWriter, req *http.Request) (*model.User, error) {
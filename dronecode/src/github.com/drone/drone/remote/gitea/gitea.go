@@ -0,0 +1,296 @@
+package gitea
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/remote"
+	"github.com/drone/drone/remote/gogs"
+	"github.com/drone/drone/shared/httputil"
+
+	"golang.org/x/oauth2"
+)
+
+// Opts defines configuration options.
+type Opts struct {
+	URL         string // Gitea server url.
+	Client      string // Gitea oauth2 client id.
+	Secret      string // Gitea oauth2 client secret.
+	Username    string // Optional machine account username.
+	Password    string // Optional machine account password.
+	PrivateMode bool   // Gitea is running in private mode.
+	SkipVerify  bool   // Skip ssl verification.
+}
+
+// client embeds the Gogs remote, since the Gitea api and webhook payloads
+// are compatible with Gogs, and implements oauth2 login, commit status and
+// hook removal using the Gitea api.
+type client struct {
+	remote.Remote
+
+	URL        string
+	Client     string
+	Secret     string
+	Machine    string
+	Username   string
+	Password   string
+	SkipVerify bool
+}
+
+// New returns a Remote implementation that integrates with Gitea, an open
+// source Git service forked from Gogs. See https://gitea.io/
+func New(opts Opts) (remote.Remote, error) {
+	u, err := url.Parse(opts.URL)
+	if err != nil {
+		return nil, err
+	}
+	host, _, err := net.SplitHostPort(u.Host)
+	if err == nil {
+		u.Host = host
+	}
+	base, err := gogs.New(gogs.Opts{
+		URL:         opts.URL,
+		Username:    opts.Username,
+		Password:    opts.Password,
+		PrivateMode: opts.PrivateMode,
+		SkipVerify:  opts.SkipVerify,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &client{
+		Remote:     base,
+		URL:        strings.TrimSuffix(opts.URL, "/"),
+		Client:     opts.Client,
+		Secret:     opts.Secret,
+		Machine:    u.Host,
+		Username:   opts.Username,
+		Password:   opts.Password,
+		SkipVerify: opts.SkipVerify,
+	}, nil
+}
+
+// Ping returns an error if the remote system is not reachable.
+func (c *client) Ping() error {
+	return remote.PingURL(c.URL, c.SkipVerify)
+}
+
+// Login authenticates an account with Gitea using the oauth2 protocol. The
+// Gitea account details are returned when the user is successfully
+// authenticated.
+func (c *client) Login(res http.ResponseWriter, req *http.Request) (*model.User, error) {
+	config := c.newConfig(httputil.GetURL(req))
+
+	// get the OAuth errors
+	if err := req.FormValue("error"); err != "" {
+		return nil, &remote.AuthError{
+			Err:         err,
+			Description: req.FormValue("error_description"),
+			URI:         req.FormValue("error_uri"),
+		}
+	}
+
+	// get the OAuth code
+	code := req.FormValue("code")
+	if len(code) == 0 {
+		http.Redirect(res, req, config.AuthCodeURL("drone"), http.StatusSeeOther)
+		return nil, nil
+	}
+
+	token, err := config.Exchange(c.newContext(), code)
+	if err != nil {
+		return nil, err
+	}
+
+	account := new(user)
+	if err := c.do(token.AccessToken, "GET", "/api/v1/user", nil, account); err != nil {
+		return nil, err
+	}
+	return &model.User{
+		Token:  token.AccessToken,
+		Secret: token.RefreshToken,
+		Expiry: token.Expiry.UTC().Unix(),
+		Login:  account.Login,
+		Email:  account.Email,
+		Avatar: account.Avatar,
+	}, nil
+}
+
+// Auth uses the Gitea oauth2 access token to authenticate a session and
+// return the Gitea account login.
+func (c *client) Auth(token, secret string) (string, error) {
+	account := new(user)
+	if err := c.do(token, "GET", "/api/v1/user", nil, account); err != nil {
+		return "", err
+	}
+	return account.Login, nil
+}
+
+// Refresh refreshes the Gitea oauth2 access token. If the token is
+// refreshed the user is updated and a true value is returned.
+func (c *client) Refresh(u *model.User) (bool, error) {
+	config := c.newConfig("")
+	source := config.TokenSource(
+		c.newContext(), &oauth2.Token{RefreshToken: u.Secret})
+
+	token, err := source.Token()
+	if err != nil || len(token.AccessToken) == 0 {
+		return false, err
+	}
+
+	u.Token = token.AccessToken
+	u.Secret = token.RefreshToken
+	u.Expiry = token.Expiry.UTC().Unix()
+	return true, nil
+}
+
+// Status sends the commit status to the Gitea repository.
+func (c *client) Status(u *model.User, r *model.Repo, b *model.Build, link string) error {
+	in := map[string]string{
+		"state":       convertStatus(b.Status),
+		"target_url":  link,
+		"description": convertDesc(b.Status),
+		"context":     "continuous-integration/drone",
+	}
+	path := fmt.Sprintf("/api/v1/repos/%s/%s/statuses/%s", r.Owner, r.Name, b.Commit)
+	return c.do(u.Token, "POST", path, in, nil)
+}
+
+// BranchHead returns the sha of the head commit of the named branch.
+func (c *client) BranchHead(u *model.User, r *model.Repo, branch string) (string, error) {
+	return c.Remote.(remote.Brancher).BranchHead(u, r, branch)
+}
+
+// Netrc returns a netrc file capable of authenticating Gitea requests and
+// cloning Gitea repositories. The netrc will use the global machine account
+// when configured.
+func (c *client) Netrc(u *model.User, r *model.Repo) (*model.Netrc, error) {
+	if c.Password != "" {
+		return &model.Netrc{
+			Login:    c.Username,
+			Password: c.Password,
+			Machine:  c.Machine,
+		}, nil
+	}
+	return &model.Netrc{
+		Login:    u.Login,
+		Password: u.Token,
+		Machine:  c.Machine,
+	}, nil
+}
+
+// Activate activates the repository by registering post-commit hooks with
+// the Gitea repository.
+func (c *client) Activate(u *model.User, r *model.Repo, link string) error {
+	in := map[string]interface{}{
+		"type": "gitea",
+		"config": map[string]string{
+			"url":          link,
+			"secret":       r.Hash,
+			"content_type": "json",
+		},
+		"events": []string{"push", "create", "pull_request"},
+		"active": true,
+	}
+	path := fmt.Sprintf("/api/v1/repos/%s/%s/hooks", r.Owner, r.Name)
+	return c.do(u.Token, "POST", path, in, nil)
+}
+
+// Deactivate removes the hooks registered with the Gitea repository that
+// match the link.
+func (c *client) Deactivate(u *model.User, r *model.Repo, link string) error {
+	var hooks []*hook
+	path := fmt.Sprintf("/api/v1/repos/%s/%s/hooks", r.Owner, r.Name)
+	if err := c.do(u.Token, "GET", path, nil, &hooks); err != nil {
+		return err
+	}
+	for _, h := range hooks {
+		if !matchLink(h.Config["url"], link) {
+			continue
+		}
+		err := c.do(u.Token, "DELETE", fmt.Sprintf("%s/%d", path, h.ID), nil, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Verify verifies the hmac signature of the hook payload, sent by Gitea in
+// the X-Gitea-Signature header.
+func (c *client) Verify(r *http.Request, payload []byte, secret string) error {
+	if !validSignature(payload, r.Header.Get(hookSignature), secret) {
+		return remote.ErrSignature
+	}
+	return nil
+}
+
+// helper function to return the oauth2 configuration.
+func (c *client) newConfig(redirect string) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     c.Client,
+		ClientSecret: c.Secret,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  c.URL + "/login/oauth/authorize",
+			TokenURL: c.URL + "/login/oauth/access_token",
+		},
+		RedirectURL: fmt.Sprintf("%s/authorize", redirect),
+	}
+}
+
+// helper function to return the oauth2 context, which uses an http client
+// that skips ssl verification when configured.
+func (c *client) newContext() context.Context {
+	return context.WithValue(oauth2.NoContext, oauth2.HTTPClient, c.newHTTPClient())
+}
+
+// helper function to return the http client.
+func (c *client) newHTTPClient() *http.Client {
+	if !c.SkipVerify {
+		return http.DefaultClient
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+}
+
+// helper function to send an authenticated request to the Gitea api and
+// decode the json response.
+func (c *client) do(token, method, path string, in, out interface{}) error {
+	var body bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, c.URL+path, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "token "+token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	res, err := c.newHTTPClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode > 299 {
+		return fmt.Errorf("gitea: %s %s: %s", method, path, res.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
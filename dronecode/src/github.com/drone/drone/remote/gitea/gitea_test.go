@@ -0,0 +1,121 @@
+package gitea
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/remote"
+
+	"github.com/franela/goblin"
+)
+
+func Test_gitea(t *testing.T) {
+	var (
+		status  map[string]string
+		deleted []string
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/user", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"login":"octocat","email":"octocat@github.com"}`))
+	})
+	mux.HandleFunc("/api/v1/repos/octocat/hello-world/statuses/9ecad50", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&status)
+	})
+	mux.HandleFunc("/api/v1/repos/octocat/hello-world/hooks", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"id":1,"config":{"url":"http://drone.io/hook?access_token=x"}},
+			{"id":2,"config":{"url":"http://example.com/hook"}}
+		]`))
+	})
+	mux.HandleFunc("/api/v1/repos/octocat/hello-world/hooks/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "DELETE" {
+			deleted = append(deleted, r.URL.Path)
+		}
+	})
+	s := httptest.NewServer(mux)
+	c, _ := New(Opts{URL: s.URL})
+
+	g := goblin.Goblin(t)
+	g.Describe("Gitea", func() {
+
+		g.After(func() {
+			s.Close()
+		})
+
+		g.Describe("Creating a remote", func() {
+			g.It("Should return client with specified options", func() {
+				remote, _ := New(Opts{
+					URL:        "http://localhost:3000/",
+					Client:     "f0b461ca586c27872b43a0685cbc2847",
+					Secret:     "976f22a5eef7caacb7e678d6c52f49b1",
+					SkipVerify: true,
+				})
+				g.Assert(remote.(*client).URL).Equal("http://localhost:3000")
+				g.Assert(remote.(*client).Machine).Equal("localhost")
+				g.Assert(remote.(*client).Client).Equal("f0b461ca586c27872b43a0685cbc2847")
+				g.Assert(remote.(*client).Secret).Equal("976f22a5eef7caacb7e678d6c52f49b1")
+				g.Assert(remote.(*client).SkipVerify).Equal(true)
+			})
+			g.It("Should handle malformed url", func() {
+				_, err := New(Opts{URL: "%gh&%ij"})
+				g.Assert(err != nil).IsTrue()
+			})
+		})
+
+		g.Describe("Generating a netrc file", func() {
+			g.It("Should return a netrc with the user token", func() {
+				netrc, _ := c.Netrc(fakeUser, nil)
+				g.Assert(netrc.Login).Equal(fakeUser.Login)
+				g.Assert(netrc.Password).Equal(fakeUser.Token)
+			})
+		})
+
+		g.It("Should return the authenticated user login", func() {
+			login, err := c.Auth(fakeUser.Token, "")
+			g.Assert(err == nil).IsTrue()
+			g.Assert(login).Equal("octocat")
+		})
+
+		g.It("Should send the commit status", func() {
+			err := c.Status(fakeUser, fakeRepo, fakeBuild, "http://drone.io/octocat/hello-world/1")
+			g.Assert(err == nil).IsTrue()
+			g.Assert(status["state"]).Equal("success")
+			g.Assert(status["target_url"]).Equal("http://drone.io/octocat/hello-world/1")
+		})
+
+		g.It("Should verify the hook signature", func() {
+			req, _ := http.NewRequest("POST", "/hook", nil)
+			payload := []byte("The quick brown fox jumps over the lazy dog")
+			g.Assert(c.(remote.Verifier).Verify(req, payload, "key") != nil).IsTrue()
+			req.Header.Set(hookSignature, "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8")
+			g.Assert(c.(remote.Verifier).Verify(req, payload, "key") == nil).IsTrue()
+		})
+
+		g.It("Should remove the matching hooks", func() {
+			err := c.Deactivate(fakeUser, fakeRepo, "http://drone.io/hook")
+			g.Assert(err == nil).IsTrue()
+			g.Assert(deleted).Equal([]string{"/api/v1/repos/octocat/hello-world/hooks/1"})
+		})
+	})
+}
+
+var (
+	fakeUser = &model.User{
+		Login: "octocat",
+		Token: "cfcd2084",
+	}
+
+	fakeRepo = &model.Repo{
+		Owner:    "octocat",
+		Name:     "hello-world",
+		FullName: "octocat/hello-world",
+	}
+
+	fakeBuild = &model.Build{
+		Status: model.StatusSuccess,
+		Commit: "9ecad50",
+	}
+)
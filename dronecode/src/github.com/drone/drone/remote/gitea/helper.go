@@ -0,0 +1,97 @@
+package gitea
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+
+	"github.com/drone/drone/model"
+)
+
+// hookSignature is the header used by Gitea to send the hmac sha256
+// signature of the hook payload.
+const hookSignature = "X-Gitea-Signature"
+
+const (
+	statusPending = "pending"
+	statusSuccess = "success"
+	statusFailure = "failure"
+	statusError   = "error"
+)
+
+const (
+	descPending  = "this build is pending"
+	descSuccess  = "the build was successful"
+	descFailure  = "the build failed"
+	descBlocked  = "the build requires approval"
+	descDeclined = "the build was rejected"
+	descError    = "oops, something went wrong"
+)
+
+type user struct {
+	Login  string `json:"login"`
+	Email  string `json:"email"`
+	Avatar string `json:"avatar_url"`
+}
+
+type hook struct {
+	ID     int64             `json:"id"`
+	Config map[string]string `json:"config"`
+}
+
+// helper function that converts a Drone status to a Gitea status.
+func convertStatus(status string) string {
+	switch status {
+	case model.StatusPending, model.StatusRunning, model.StatusBlocked:
+		return statusPending
+	case model.StatusSuccess:
+		return statusSuccess
+	case model.StatusFailure, model.StatusDeclined:
+		return statusFailure
+	default:
+		return statusError
+	}
+}
+
+// helper function that converts a Drone status to a Gitea status
+// description.
+func convertDesc(status string) string {
+	switch status {
+	case model.StatusPending, model.StatusRunning:
+		return descPending
+	case model.StatusSuccess:
+		return descSuccess
+	case model.StatusFailure:
+		return descFailure
+	case model.StatusBlocked:
+		return descBlocked
+	case model.StatusDeclined:
+		return descDeclined
+	default:
+		return descError
+	}
+}
+
+// helper function that returns true if the hook url points to the same
+// host as the link, ignoring the query parameters.
+func matchLink(hookURL, link string) bool {
+	a, err := url.Parse(hookURL)
+	if err != nil {
+		return false
+	}
+	b, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	return a.Host == b.Host && a.Path == b.Path
+}
+
+// helper function returns true if the signature is the hex encoded hmac
+// sha256 of the payload.
+func validSignature(payload []byte, signature, secret string) bool {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
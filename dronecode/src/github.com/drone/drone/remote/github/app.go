@@ -0,0 +1,125 @@
+package github
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// mediaTypeApp is the preview media type required by the GitHub App
+// installation endpoints.
+const mediaTypeApp = "application/vnd.github.machine-man-preview+json"
+
+// app authenticates with the GitHub api as a GitHub App, exchanging
+// a signed jwt for short-lived installation access tokens.
+type app struct {
+	id     int64
+	key    *rsa.PrivateKey
+	api    string
+	client *http.Client
+
+	sync.Mutex
+	tokens map[string]*installToken
+}
+
+type installToken struct {
+	Token   string    `json:"token"`
+	Expires time.Time `json:"expires_at"`
+}
+
+type installation struct {
+	ID int64 `json:"id"`
+}
+
+// newApp returns a GitHub App authenticator for the app id and the PEM
+// encoded private key.
+func newApp(id int64, key []byte, api string, client *http.Client) (*app, error) {
+	rsakey, err := jwt.ParseRSAPrivateKeyFromPEM(key)
+	if err != nil {
+		return nil, err
+	}
+	return &app{
+		id:     id,
+		key:    rsakey,
+		api:    strings.TrimSuffix(api, "/"),
+		client: client,
+		tokens: map[string]*installToken{},
+	}, nil
+}
+
+// jwt returns a jwt signed with the app private key, used to authenticate
+// as the app itself. GitHub limits the expiration to ten minutes.
+func (a *app) jwt() (string, error) {
+	now := time.Now()
+	token := jwt.New(jwt.SigningMethodRS256)
+	token.Claims["iss"] = a.id
+	token.Claims["iat"] = now.Add(-time.Minute).Unix()
+	token.Claims["exp"] = now.Add(9 * time.Minute).Unix()
+	return token.SignedString(a.key)
+}
+
+// token returns an installation access token for the repository. Tokens
+// are cached until shortly before they expire.
+func (a *app) token(owner, name string) (string, error) {
+	key := owner + "/" + name
+
+	a.Lock()
+	defer a.Unlock()
+
+	if t, ok := a.tokens[key]; ok && time.Now().Add(time.Minute).Before(t.Expires) {
+		return t.Token, nil
+	}
+
+	signed, err := a.jwt()
+	if err != nil {
+		return "", err
+	}
+	in := new(installation)
+	path := fmt.Sprintf("/repos/%s/%s/installation", owner, name)
+	if err := a.do(signed, "GET", path, nil, in); err != nil {
+		return "", err
+	}
+	t := new(installToken)
+	path = fmt.Sprintf("/app/installations/%d/access_tokens", in.ID)
+	if err := a.do(signed, "POST", path, nil, t); err != nil {
+		return "", err
+	}
+	a.tokens[key] = t
+	return t.Token, nil
+}
+
+// helper function to send a request to the GitHub App api, authenticated
+// with the app jwt.
+func (a *app) do(signed, method, path string, in, out interface{}) error {
+	var body bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, a.api+path, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+signed)
+	req.Header.Set("Accept", mediaTypeApp)
+	res, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode > 299 {
+		return fmt.Errorf("github: %s %s: %s", method, path, res.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
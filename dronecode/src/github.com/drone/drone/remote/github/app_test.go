@@ -0,0 +1,73 @@
+package github
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/drone/drone/model"
+	"github.com/franela/goblin"
+)
+
+func Test_app(t *testing.T) {
+	var requests int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/octocat/hello-world/installation", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			w.WriteHeader(401)
+			return
+		}
+		w.Write([]byte(`{"id":1}`))
+	})
+	mux.HandleFunc("/app/installations/1/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"token":"v1.1f699f1069f60xxx","expires_at":"2099-01-01T00:00:00Z"}`))
+	})
+	s := httptest.NewServer(mux)
+
+	key, _ := rsa.GenerateKey(rand.Reader, 1024)
+	block := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+
+	g := goblin.Goblin(t)
+	g.Describe("GitHub App", func() {
+
+		g.After(func() {
+			s.Close()
+		})
+
+		g.It("Should handle a malformed private key", func() {
+			_, err := newApp(1, []byte("not a key"), s.URL, http.DefaultClient)
+			g.Assert(err != nil).IsTrue()
+		})
+
+		g.It("Should return and cache the installation token", func() {
+			a, err := newApp(1, block, s.URL, http.DefaultClient)
+			g.Assert(err == nil).IsTrue()
+
+			token, err := a.token("octocat", "hello-world")
+			g.Assert(err == nil).IsTrue()
+			g.Assert(token).Equal("v1.1f699f1069f60xxx")
+
+			token, _ = a.token("octocat", "hello-world")
+			g.Assert(token).Equal("v1.1f699f1069f60xxx")
+			g.Assert(requests).Equal(1)
+		})
+
+		g.It("Should return a netrc with the installation token", func() {
+			a, _ := newApp(1, block, s.URL, http.DefaultClient)
+			c := &client{Machine: "github.com", app: a}
+			netrc, err := c.Netrc(fakeUser, &model.Repo{Owner: "octocat", Name: "hello-world"})
+			g.Assert(err == nil).IsTrue()
+			g.Assert(netrc.Login).Equal("x-access-token")
+			g.Assert(netrc.Password).Equal("v1.1f699f1069f60xxx")
+		})
+	})
+}
@@ -0,0 +1,246 @@
+package github
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/drone/drone/model"
+)
+
+// mediaTypeChecks is the preview media type required by the GitHub Checks
+// api endpoints.
+const mediaTypeChecks = "application/vnd.github.antiope-preview+json"
+
+const (
+	hookCheckRun      = "check_run"
+	hookSignature     = "X-Hub-Signature"
+	actionRerequested = "rerequested"
+)
+
+// ErrAppSecret is returned when a GitHub App webhook is received but the
+// app webhook secret is not configured, and the request cannot be verified.
+var ErrAppSecret = errors.New("github: app webhook secret is not configured")
+
+type checkRun struct {
+	ID          int64        `json:"id,omitempty"`
+	Name        string       `json:"name,omitempty"`
+	HeadSHA     string       `json:"head_sha,omitempty"`
+	DetailsURL  string       `json:"details_url,omitempty"`
+	ExternalID  string       `json:"external_id,omitempty"`
+	Status      string       `json:"status,omitempty"`
+	Conclusion  string       `json:"conclusion,omitempty"`
+	StartedAt   *time.Time   `json:"started_at,omitempty"`
+	CompletedAt *time.Time   `json:"completed_at,omitempty"`
+	Output      *checkOutput `json:"output,omitempty"`
+}
+
+type checkOutput struct {
+	Title   string `json:"title"`
+	Summary string `json:"summary"`
+	Text    string `json:"text,omitempty"`
+}
+
+type checkRunList struct {
+	CheckRuns []*checkRun `json:"check_runs"`
+}
+
+// Check reports the pipeline step to the GitHub Checks api as a check run,
+// including the step duration and an excerpt of the step logs. Check runs
+// are only reported when the GitHub App is configured.
+func (c *client) Check(u *model.User, r *model.Repo, b *model.Build, p *model.Proc, link, logs string) error {
+	if c.app == nil {
+		return nil
+	}
+	run := &checkRun{
+		Name:       p.Name,
+		HeadSHA:    b.Commit,
+		DetailsURL: link,
+		ExternalID: fmt.Sprintf("%d/%d", b.Number, p.PID),
+		StartedAt:  unixTime(p.Started),
+		Output: &checkOutput{
+			Title:   checkTitle(p),
+			Summary: checkSummary(p),
+		},
+	}
+	run.Status, run.Conclusion = convertCheckStatus(p.State)
+	if run.Status == checkCompleted {
+		run.CompletedAt = unixTime(p.Stopped)
+	}
+	if logs != "" {
+		run.Output.Text = "```\n" + logs + "\n```"
+	}
+	return c.check(r, run)
+}
+
+// helper function to report the overall build status to the GitHub Checks
+// api as a check run, in place of the commit status.
+func (c *client) buildCheck(r *model.Repo, b *model.Build, link string) error {
+	run := &checkRun{
+		Name:       c.Context,
+		HeadSHA:    b.Commit,
+		DetailsURL: link,
+		ExternalID: strconv.Itoa(b.Number),
+		StartedAt:  unixTime(b.Started),
+		Output: &checkOutput{
+			Title:   convertDesc(b.Status),
+			Summary: convertDesc(b.Status),
+		},
+	}
+	run.Status, run.Conclusion = convertCheckStatus(b.Status)
+	if run.Status == checkCompleted {
+		run.CompletedAt = unixTime(b.Finished)
+	}
+	return c.check(r, run)
+}
+
+// helper function to create the check run, or update the existing check
+// run with the same name and external id.
+func (c *client) check(r *model.Repo, run *checkRun) error {
+	token, err := c.app.token(r.Owner, r.Name)
+	if err != nil {
+		return err
+	}
+
+	list := new(checkRunList)
+	path := fmt.Sprintf("/repos/%s/%s/commits/%s/check-runs?check_name=%s",
+		r.Owner, r.Name, run.HeadSHA, url.QueryEscape(run.Name))
+	if err := c.checkDo(token, "GET", path, nil, list); err != nil {
+		return err
+	}
+	for _, existing := range list.CheckRuns {
+		if existing.ExternalID != run.ExternalID {
+			continue
+		}
+		run.HeadSHA = ""
+		path = fmt.Sprintf("/repos/%s/%s/check-runs/%d", r.Owner, r.Name, existing.ID)
+		return c.checkDo(token, "PATCH", path, run, nil)
+	}
+	path = fmt.Sprintf("/repos/%s/%s/check-runs", r.Owner, r.Name)
+	return c.checkDo(token, "POST", path, run, nil)
+}
+
+// helper function to send a request to the GitHub Checks api, authenticated
+// with the installation token.
+func (c *client) checkDo(token, method, path string, in, out interface{}) error {
+	var body bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.API, "/")+path, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "token "+token)
+	req.Header.Set("Accept", mediaTypeChecks)
+	res, err := c.app.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode > 299 {
+		return fmt.Errorf("github: %s %s: %s", method, path, res.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+// Rerun parses a check run re-run request delivered to the GitHub App
+// webhook, and returns the repository and the number of the build to
+// restart. A zero build number is returned for any other event.
+func (c *client) Rerun(r *http.Request) (*model.Repo, int, error) {
+	if c.AppSecret == "" {
+		return nil, 0, ErrAppSecret
+	}
+	raw, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !validSignature(raw, r.Header.Get(hookSignature), c.AppSecret) {
+		return nil, 0, errors.New("github: invalid webhook signature")
+	}
+	if r.Header.Get(hookEvent) != hookCheckRun {
+		return nil, 0, nil
+	}
+	return parseRerunHook(raw)
+}
+
+// parseRerunHook parses a check run hook and returns the Repo and build
+// number. If the action is not a re-run request a zero number is returned.
+func parseRerunHook(payload []byte) (*model.Repo, int, error) {
+	hook := new(webhook)
+	if err := json.Unmarshal(payload, hook); err != nil {
+		return nil, 0, err
+	}
+	if hook.Action != actionRerequested {
+		return nil, 0, nil
+	}
+	// the external id is the build number, optionally followed by the
+	// step pid for step check runs.
+	parts := strings.SplitN(hook.CheckRun.ExternalID, "/", 2)
+	number, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil, 0, err
+	}
+	return convertRepoHook(hook), number, nil
+}
+
+// helper function returns true if the signature is the hex encoded hmac
+// sha1 of the payload, as sent by GitHub in the X-Hub-Signature header.
+func validSignature(payload []byte, signature, secret string) bool {
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write(payload)
+	expected := "sha1=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// helper function returns the title of the step check run.
+func checkTitle(p *model.Proc) string {
+	if p.State == model.StatusFailureAllowed {
+		return fmt.Sprintf("%s failed (allowed)", p.Name)
+	}
+	return fmt.Sprintf("%s %s", p.Name, p.State)
+}
+
+// helper function returns a summary of the step state and duration.
+func checkSummary(p *model.Proc) string {
+	switch p.State {
+	case model.StatusPending:
+		return fmt.Sprintf("Step %s is pending", p.Name)
+	case model.StatusRunning:
+		return fmt.Sprintf("Step %s is running", p.Name)
+	}
+	summary := fmt.Sprintf("Step %s finished in %s", p.Name,
+		time.Duration(p.Stopped-p.Started)*time.Second)
+	if p.ExitCode != 0 {
+		summary += fmt.Sprintf(" with exit code %d", p.ExitCode)
+	}
+	if p.Error != "" {
+		summary += ": " + p.Error
+	}
+	return summary
+}
+
+// helper function returns the unix timestamp as a time, or nil if the
+// timestamp is not set.
+func unixTime(ts int64) *time.Time {
+	if ts == 0 {
+		return nil
+	}
+	t := time.Unix(ts, 0).UTC()
+	return &t
+}
@@ -27,6 +27,17 @@ const (
 	descError    = "oops, something went wrong"
 )
 
+const (
+	checkQueued     = "queued"
+	checkInProgress = "in_progress"
+	checkCompleted  = "completed"
+
+	conclusionSuccess   = "success"
+	conclusionFailure   = "failure"
+	conclusionCancelled = "cancelled"
+	conclusionNeutral   = "neutral"
+)
+
 const (
 	headRefs  = "refs/pull/%d/head"  // pull request unmerged
 	mergeRefs = "refs/pull/%d/merge" // pull request merged with base
@@ -67,6 +78,26 @@ func convertDesc(status string) string {
 	}
 }
 
+// convertCheckStatus is a helper function used to convert a Drone status to
+// a GitHub check run status and conclusion. The conclusion is empty until
+// the check run is completed.
+func convertCheckStatus(status string) (string, string) {
+	switch status {
+	case model.StatusPending, model.StatusBlocked:
+		return checkQueued, ""
+	case model.StatusRunning:
+		return checkInProgress, ""
+	case model.StatusSuccess:
+		return checkCompleted, conclusionSuccess
+	case model.StatusKilled, model.StatusDeclined:
+		return checkCompleted, conclusionCancelled
+	case model.StatusSkipped, model.StatusFailureAllowed:
+		return checkCompleted, conclusionNeutral
+	default:
+		return checkCompleted, conclusionFailure
+	}
+}
+
 // convertRepo is a helper function used to convert a GitHub repository
 // structure to the common Drone repository structure.
 func convertRepo(from *github.Repository, private bool) *model.Repo {
@@ -101,15 +132,19 @@ func convertPerm(from *github.Repository) *model.Perm {
 }
 
 // convertTeamPerm is a helper function used to convert a GitHub organization
-// permissions to the common Drone permissions structure.
+// permissions to the common Drone permissions structure. Organization owners
+// are granted admin access, and active members are granted read access.
 func convertTeamPerm(from *github.Membership) *model.Perm {
-	admin := false
-	if *from.Role == "admin" {
-		admin = true
+	perm := new(model.Perm)
+	if from.State != nil && *from.State != "active" {
+		return perm
 	}
-	return &model.Perm{
-		Admin: admin,
+	if from.Role != nil && *from.Role == "admin" {
+		perm.Admin = true
+		perm.Push = true
 	}
+	perm.Pull = true
+	return perm
 }
 
 // convertRepoList is a helper function used to convert a GitHub repository
@@ -152,6 +187,20 @@ func convertTeam(from github.Organization) *model.Team {
 	}
 }
 
+// convertPermLevel is a helper function used to convert a GitHub
+// collaborator permission level to the common permissions structure.
+func convertPermLevel(level string) *model.Perm {
+	switch level {
+	case "admin":
+		return &model.Perm{Admin: true, Push: true, Pull: true}
+	case "write":
+		return &model.Perm{Push: true, Pull: true}
+	case "read":
+		return &model.Perm{Pull: true}
+	}
+	return &model.Perm{}
+}
+
 // convertRepoHook is a helper function used to extract the Repository details
 // from a webhook and convert to the common Drone repository structure.
 func convertRepoHook(from *webhook) *model.Repo {
@@ -199,6 +248,11 @@ func convertPushHook(from *webhook) *model.Build {
 	if len(build.Email) == 0 {
 		// default to gravatar?
 	}
+	// the push hook lists the files changed by at most 20 commits, and
+	// the changed files are otherwise fetched using the api.
+	if len(from.Commits) != 0 && len(from.Commits) < maxHookCommits {
+		build.Changed = convertChanges(from)
+	}
 	if strings.HasPrefix(build.Ref, "refs/tags/") {
 		// just kidding, this is actually a tag event. Why did this come as a push
 		// event we'll never know!
@@ -262,3 +316,25 @@ func convertPullHook(from *webhook, merge bool) *model.Build {
 	}
 	return build
 }
+
+// maxHookCommits is the maximum number of commits included in the push
+// hook.
+const maxHookCommits = 20
+
+// convertChanges is a helper function used to list the unique files added,
+// removed or modified by the commits in the push hook.
+func convertChanges(from *webhook) []string {
+	seen := map[string]bool{}
+	changed := []string{}
+	for _, commit := range from.Commits {
+		for _, files := range [][]string{commit.Added, commit.Removed, commit.Modified} {
+			for _, file := range files {
+				if !seen[file] {
+					seen[file] = true
+					changed = append(changed, file)
+				}
+			}
+		}
+	}
+	return changed
+}
@@ -32,6 +32,19 @@ func Test_helper(t *testing.T) {
 			g.Assert(convertStatus(model.StatusError)).Equal(statusError)
 		})
 
+		g.It("should convert check run status", func() {
+			status, conclusion := convertCheckStatus(model.StatusRunning)
+			g.Assert(status).Equal(checkInProgress)
+			g.Assert(conclusion).Equal("")
+			status, conclusion = convertCheckStatus(model.StatusKilled)
+			g.Assert(status).Equal(checkCompleted)
+			g.Assert(conclusion).Equal(conclusionCancelled)
+			_, conclusion = convertCheckStatus(model.StatusError)
+			g.Assert(conclusion).Equal(conclusionFailure)
+			_, conclusion = convertCheckStatus(model.StatusFailureAllowed)
+			g.Assert(conclusion).Equal(conclusionNeutral)
+		})
+
 		g.It("should convert passing desc", func() {
 			g.Assert(convertDesc(model.StatusSuccess)).Equal(descSuccess)
 		})
@@ -127,6 +140,31 @@ func Test_helper(t *testing.T) {
 			g.Assert(to.Admin).IsTrue()
 		})
 
+		g.It("should convert team permissions", func() {
+			owner := convertTeamPerm(&github.Membership{
+				State: github.String("active"),
+				Role:  github.String("admin"),
+			})
+			g.Assert(owner.Admin).IsTrue()
+			g.Assert(owner.Push).IsTrue()
+			g.Assert(owner.Pull).IsTrue()
+
+			member := convertTeamPerm(&github.Membership{
+				State: github.String("active"),
+				Role:  github.String("member"),
+			})
+			g.Assert(member.Admin).IsFalse()
+			g.Assert(member.Push).IsFalse()
+			g.Assert(member.Pull).IsTrue()
+
+			pending := convertTeamPerm(&github.Membership{
+				State: github.String("pending"),
+				Role:  github.String("admin"),
+			})
+			g.Assert(pending.Admin).IsFalse()
+			g.Assert(pending.Pull).IsFalse()
+		})
+
 		g.It("should convert team", func() {
 			from := github.Organization{
 				Login:     github.String("octocat"),
@@ -243,6 +281,21 @@ func Test_helper(t *testing.T) {
 			g.Assert(build.Remote).Equal(from.Repo.CloneURL)
 		})
 
+		g.It("should convert the changed files from webhook", func() {
+			from := &webhook{}
+			from.Commits = make([]struct {
+				Added    []string `json:"added"`
+				Removed  []string `json:"removed"`
+				Modified []string `json:"modified"`
+			}, 2)
+			from.Commits[0].Added = []string{"services/api/main.go"}
+			from.Commits[0].Modified = []string{"README.md"}
+			from.Commits[1].Modified = []string{"README.md"}
+
+			build := convertPushHook(from)
+			g.Assert(build.Changed).Equal([]string{"services/api/main.go", "README.md"})
+		})
+
 		g.It("should convert a tag from webhook", func() {
 			from := &webhook{}
 			from.Ref = "refs/tags/v1.0.0"
@@ -160,3 +160,76 @@ const HookDeploy = `
   }
 }
 `
+
+// HookCheckRunRerequested is a check run hook requesting a re-run.
+var HookCheckRunRerequested = `
+{
+  "action": "rerequested",
+  "check_run": {
+    "id": 4,
+    "name": "test",
+    "head_sha": "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c",
+    "external_id": "42/3",
+    "status": "completed",
+    "conclusion": "failure"
+  },
+  "repository": {
+    "id": 35129377,
+    "name": "public-repo",
+    "full_name": "baxterthehacker/public-repo",
+    "owner": {
+      "login": "baxterthehacker",
+      "avatar_url": "https://avatars.githubusercontent.com/u/6752317?v=3"
+    },
+    "private": true,
+    "html_url": "https://github.com/baxterthehacker/public-repo",
+    "clone_url": "https://github.com/baxterthehacker/public-repo.git",
+    "default_branch": "master"
+  },
+  "sender": {
+    "login": "baxterthehacker",
+    "avatar_url": "https://avatars.githubusercontent.com/u/6752317?v=3"
+  }
+}
+`
+
+// HookComment is a sample pull request comment hook.
+// https://developer.github.com/v3/activity/events/types/#issuecommentevent
+const HookComment = `
+{
+  "action": "created",
+  "issue": {
+    "number": 2,
+    "pull_request": {
+      "url": "https://api.github.com/repos/baxterthehacker/public-repo/pulls/2"
+    }
+  },
+  "comment": {
+    "body": "/drone deploy staging",
+    "user": {
+      "login": "octocat"
+    }
+  },
+  "repository": {
+    "name": "public-repo",
+    "full_name": "baxterthehacker/public-repo",
+    "owner": {
+      "login": "baxterthehacker"
+    }
+  }
+}
+`
+
+// HookCommentIssue is a sample issue comment hook that is not a pull request
+// comment, and is expected to be ignored.
+const HookCommentIssue = `
+{
+  "action": "created",
+  "issue": {
+    "number": 2
+  },
+  "comment": {
+    "body": "/drone rebuild"
+  }
+}
+`
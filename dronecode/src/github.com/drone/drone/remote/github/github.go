@@ -36,6 +36,9 @@ type Opts struct {
 	PrivateMode bool     // GitHub is running in private mode.
 	SkipVerify  bool     // Skip ssl verification.
 	MergeRef    bool     // Clone pull requests using the merge ref.
+	AppID       int64    // Optional GitHub App id.
+	AppKey      []byte   // Optional GitHub App PEM encoded private key.
+	AppSecret   string   // Optional GitHub App webhook secret.
 }
 
 // New returns a Remote implementation that integrates with a GitHub Cloud or
@@ -62,11 +65,18 @@ func New(opts Opts) (remote.Remote, error) {
 		Machine:     url.Host,
 		Username:    opts.Username,
 		Password:    opts.Password,
+		AppSecret:   opts.AppSecret,
 	}
 	if opts.URL != defaultURL {
 		remote.URL = strings.TrimSuffix(opts.URL, "/")
 		remote.API = remote.URL + "/api/v3/"
 	}
+	if opts.AppID != 0 {
+		remote.app, err = newApp(opts.AppID, opts.AppKey, remote.API, remote.newHTTPClient())
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	// Hack to enable oauth2 access in older GHE
 	oauth2.RegisterBrokenAuthHeaderProvider(remote.URL)
@@ -86,6 +96,16 @@ type client struct {
 	PrivateMode bool
 	SkipVerify  bool
 	MergeRef    bool
+	AppSecret   string
+
+	// app is used to authenticate as a GitHub App, and is nil if the app
+	// is not configured.
+	app *app
+}
+
+// Ping returns an error if the remote system is not reachable.
+func (c *client) Ping() error {
+	return remote.PingURL(c.API, c.SkipVerify)
 }
 
 // Login authenticates the session and returns the remote user details.
@@ -226,7 +246,10 @@ func (c *client) File(u *model.User, r *model.Repo, b *model.Build, f string) ([
 
 // FileRef fetches the file from the GitHub repository and returns its contents.
 func (c *client) FileRef(u *model.User, r *model.Repo, ref, f string) ([]byte, error) {
-	client := c.newClientToken(u.Token)
+	client, err := c.newClientRepo(u, r)
+	if err != nil {
+		return nil, err
+	}
 
 	opts := new(github.RepositoryContentGetOptions)
 	opts.Ref = ref
@@ -237,9 +260,62 @@ func (c *client) FileRef(u *model.User, r *model.Repo, ref, f string) ([]byte, e
 	return data.Decode()
 }
 
+// BranchHead returns the sha of the head commit of the named branch.
+func (c *client) BranchHead(u *model.User, r *model.Repo, branch string) (string, error) {
+	client, err := c.newClientRepo(u, r)
+	if err != nil {
+		return "", err
+	}
+	data, _, err := client.Repositories.GetBranch(r.Owner, r.Name, branch)
+	if err != nil {
+		return "", err
+	}
+	return *data.Commit.SHA, nil
+}
+
+// Changes returns the files changed by the pull request, or by the head
+// commit of the push.
+func (c *client) Changes(u *model.User, r *model.Repo, b *model.Build) ([]string, error) {
+	client, err := c.newClientRepo(u, r)
+	if err != nil {
+		return nil, err
+	}
+
+	var number int
+	if _, err := fmt.Sscanf(b.Ref, "refs/pull/%d/", &number); err == nil && b.Event == model.EventPull {
+		changed := []string{}
+		opts := &github.ListOptions{PerPage: 100}
+		for {
+			files, resp, err := client.PullRequests.ListFiles(r.Owner, r.Name, number, opts)
+			if err != nil {
+				return nil, err
+			}
+			for _, file := range files {
+				changed = append(changed, *file.Filename)
+			}
+			if resp.NextPage == 0 {
+				break
+			}
+			opts.Page = resp.NextPage
+		}
+		return changed, nil
+	}
+
+	commit, _, err := client.Repositories.GetCommit(r.Owner, r.Name, b.Commit)
+	if err != nil {
+		return nil, err
+	}
+	changed := []string{}
+	for _, file := range commit.Files {
+		changed = append(changed, *file.Filename)
+	}
+	return changed, nil
+}
+
 // Netrc returns a netrc file capable of authenticating GitHub requests and
 // cloning GitHub repositories. The netrc will use the global machine account
-// when configured.
+// when configured, or the GitHub App installation token when the app is
+// configured.
 func (c *client) Netrc(u *model.User, r *model.Repo) (*model.Netrc, error) {
 	if c.Password != "" {
 		return &model.Netrc{
@@ -248,6 +324,17 @@ func (c *client) Netrc(u *model.User, r *model.Repo) (*model.Netrc, error) {
 			Machine:  c.Machine,
 		}, nil
 	}
+	if c.app != nil {
+		token, err := c.app.token(r.Owner, r.Name)
+		if err != nil {
+			return nil, err
+		}
+		return &model.Netrc{
+			Login:    "x-access-token",
+			Password: token,
+			Machine:  c.Machine,
+		}, nil
+	}
 	return &model.Netrc{
 		Login:    u.Token,
 		Password: "x-oauth-basic",
@@ -287,6 +374,22 @@ func (c *client) newContext() context.Context {
 	})
 }
 
+// helper function to return the http client, which skips ssl verification
+// when configured.
+func (c *client) newHTTPClient() *http.Client {
+	if !c.SkipVerify {
+		return http.DefaultClient
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: true,
+			},
+		},
+	}
+}
+
 // helper function to return the GitHub oauth2 config
 func (c *client) newConfig(redirect string) *oauth2.Config {
 	return &oauth2.Config{
@@ -320,6 +423,21 @@ func (c *client) newClientToken(token string) *github.Client {
 	return github
 }
 
+// helper function to return a GitHub client for the repository. The client
+// authenticates as the GitHub App installation when the app is configured,
+// so that repository access does not depend on the user account, and
+// otherwise uses the user oauth token.
+func (c *client) newClientRepo(u *model.User, r *model.Repo) (*github.Client, error) {
+	if c.app == nil {
+		return c.newClientToken(u.Token), nil
+	}
+	token, err := c.app.token(r.Owner, r.Name)
+	if err != nil {
+		return nil, err
+	}
+	return c.newClientToken(token), nil
+}
+
 // helper function to return matching user email.
 func matchingEmail(emails []github.UserEmail, rawurl string) *github.UserEmail {
 	for _, email := range emails {
@@ -369,12 +487,18 @@ func matchingHooks(hooks []github.Hook, rawurl string) *github.Hook {
 //
 
 // Status sends the commit status to the remote system.
-// An example would be the GitHub pull request status.
+// An example would be the GitHub pull request status. When the GitHub App
+// is configured the status is reported as a check run instead.
 func (c *client) Status(u *model.User, r *model.Repo, b *model.Build, link string) error {
-	client := c.newClientToken(u.Token)
-	switch b.Event {
-	case "deployment":
+	client, err := c.newClientRepo(u, r)
+	if err != nil {
+		return err
+	}
+	switch {
+	case b.Event == "deployment":
 		return deploymentStatus(client, r, b, link)
+	case c.app != nil:
+		return c.buildCheck(r, b, link)
 	default:
 		return repoStatus(client, r, b, link, c.Context)
 	}
@@ -432,9 +556,11 @@ func (c *client) Activate(u *model.User, r *model.Repo, link string) error {
 			"push",
 			"pull_request",
 			"deployment",
+			"issue_comment",
 		},
 		Config: map[string]interface{}{
 			"url":          link,
+			"secret":       r.Hash,
 			"content_type": "form",
 		},
 	}
@@ -447,3 +573,42 @@ func (c *client) Activate(u *model.User, r *model.Repo, link string) error {
 func (c *client) Hook(r *http.Request) (*model.Repo, *model.Build, error) {
 	return parseHook(r, c.MergeRef)
 }
+
+// Comment parses the pull request comment hook from the Request body.
+func (c *client) Comment(r *http.Request) (*model.Comment, error) {
+	if r.Header.Get(hookEvent) != hookComment {
+		return nil, nil
+	}
+	raw, err := readHook(r)
+	if err != nil {
+		return nil, err
+	}
+	return parseCommentHook(raw)
+}
+
+// Collaborator returns the permissions of the user login to the GitHub
+// repository, using the collaborator permission level.
+func (c *client) Collaborator(u *model.User, r *model.Repo, login string) (*model.Perm, error) {
+	client := c.newClientToken(u.Token)
+	uri := fmt.Sprintf("repos/%s/%s/collaborators/%s/permission", r.Owner, r.Name, login)
+	req, err := client.NewRequest("GET", uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	out := struct {
+		Permission string `json:"permission"`
+	}{}
+	if _, err := client.Do(req, &out); err != nil {
+		return nil, err
+	}
+	return convertPermLevel(out.Permission), nil
+}
+
+// Verify verifies the hmac signature of the hook payload, sent by GitHub
+// in the X-Hub-Signature header.
+func (c *client) Verify(r *http.Request, payload []byte, secret string) error {
+	if !validSignature(payload, r.Header.Get(hookSignature), secret) {
+		return remote.ErrSignature
+	}
+	return nil
+}
@@ -11,14 +11,16 @@ import (
 )
 
 const (
-	hookEvent  = "X-Github-Event"
-	hookField  = "payload"
-	hookDeploy = "deployment"
-	hookPush   = "push"
-	hookPull   = "pull_request"
+	hookEvent   = "X-Github-Event"
+	hookField   = "payload"
+	hookDeploy  = "deployment"
+	hookPush    = "push"
+	hookPull    = "pull_request"
+	hookComment = "issue_comment"
 
-	actionOpen = "opened"
-	actionSync = "synchronize"
+	actionOpen    = "opened"
+	actionSync    = "synchronize"
+	actionCreated = "created"
 
 	stateOpen = "open"
 )
@@ -26,13 +28,7 @@ const (
 // parseHook parses a Bitbucket hook from an http.Request request and returns
 // Repo and Build detail. If a hook type is unsupported nil values are returned.
 func parseHook(r *http.Request, merge bool) (*model.Repo, *model.Build, error) {
-	var reader io.Reader = r.Body
-
-	if payload := r.FormValue(hookField); payload != "" {
-		reader = bytes.NewBufferString(payload)
-	}
-
-	raw, err := ioutil.ReadAll(reader)
+	raw, err := readHook(r)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -48,6 +44,36 @@ func parseHook(r *http.Request, merge bool) (*model.Repo, *model.Build, error) {
 	return nil, nil, nil
 }
 
+// readHook reads the hook payload from the form field, or from the request
+// body if the payload is not form encoded.
+func readHook(r *http.Request) ([]byte, error) {
+	var reader io.Reader = r.Body
+
+	if payload := r.FormValue(hookField); payload != "" {
+		reader = bytes.NewBufferString(payload)
+	}
+	return ioutil.ReadAll(reader)
+}
+
+// parseCommentHook parses an issue comment hook and returns the pull
+// request comment details. If the comment is not a new pull request
+// comment a nil value is returned.
+func parseCommentHook(payload []byte) (*model.Comment, error) {
+	hook := new(webhook)
+	if err := json.Unmarshal(payload, hook); err != nil {
+		return nil, err
+	}
+	if hook.Action != actionCreated || hook.Issue.PullRequest == nil {
+		return nil, nil
+	}
+	return &model.Comment{
+		Repo:   convertRepoHook(hook),
+		Number: hook.Issue.Number,
+		Author: hook.Comment.User.Login,
+		Body:   hook.Comment.Body,
+	}, nil
+}
+
 // parsePushHook parses a push hook and returns the Repo and Build details.
 // If the commit type is unsupported nil values are returned.
 func parsePushHook(payload []byte) (*model.Repo, *model.Build, error) {
@@ -49,6 +49,25 @@ func Test_parser(t *testing.T) {
 			})
 		})
 
+		g.Describe("given a comment hook", func() {
+			g.It("should skip when not a pull request", func() {
+				raw := []byte(fixtures.HookCommentIssue)
+				comment, err := parseCommentHook(raw)
+				g.Assert(comment == nil).IsTrue()
+				g.Assert(err == nil).IsTrue()
+			})
+			g.It("should extract comment details", func() {
+				raw := []byte(fixtures.HookComment)
+				comment, err := parseCommentHook(raw)
+				g.Assert(err == nil).IsTrue()
+				g.Assert(comment != nil).IsTrue()
+				g.Assert(comment.Repo.FullName).Equal("baxterthehacker/public-repo")
+				g.Assert(comment.Number).Equal(2)
+				g.Assert(comment.Author).Equal("octocat")
+				g.Assert(comment.Body).Equal("/drone deploy staging")
+			})
+		})
+
 		g.Describe("given a pull request hook", func() {
 			g.It("should skip when action is not open or sync", func() {
 				raw := []byte(fixtures.HookPullRequestInvalidAction)
@@ -93,5 +112,32 @@ func Test_parser(t *testing.T) {
 			})
 		})
 
+		g.Describe("given a check run hook", func() {
+			g.It("should extract repository and build number", func() {
+				r, n, err := parseRerunHook([]byte(fixtures.HookCheckRunRerequested))
+				g.Assert(err == nil).IsTrue()
+				g.Assert(r.FullName).Equal("baxterthehacker/public-repo")
+				g.Assert(n).Equal(42)
+			})
+			g.It("should verify the signature", func() {
+				raw := []byte(fixtures.HookCheckRunRerequested)
+				c := &client{AppSecret: "correct horse battery staple"}
+				req, _ := http.NewRequest("POST", "/hook/checks", bytes.NewReader(raw))
+				req.Header.Set(hookEvent, hookCheckRun)
+				req.Header.Set(hookSignature, "sha1=0000")
+				_, _, err := c.Rerun(req)
+				g.Assert(err != nil).IsTrue()
+				g.Assert(validSignature(raw, "sha1=0000", c.AppSecret)).IsFalse()
+			})
+			g.It("should verify the push hook signature", func() {
+				payload := []byte("The quick brown fox jumps over the lazy dog")
+				req, _ := http.NewRequest("POST", "/hook", nil)
+				req.Header.Set(hookSignature, "sha1=de7c9b85b8b78aa6bc8a7a36f70a90701c9db4d9")
+				c := new(client)
+				g.Assert(c.Verify(req, payload, "key") == nil).IsTrue()
+				g.Assert(c.Verify(req, payload, "invalid") != nil).IsTrue()
+			})
+		})
+
 	})
 }
@@ -24,6 +24,12 @@ type webhook struct {
 		} `json:"committer"`
 	} `json:"head_commit"`
 
+	Commits []struct {
+		Added    []string `json:"added"`
+		Removed  []string `json:"removed"`
+		Modified []string `json:"modified"`
+	} `json:"commits"`
+
 	Sender struct {
 		Login  string `json:"login"`
 		Avatar string `json:"avatar_url"`
@@ -56,6 +62,26 @@ type webhook struct {
 		Desc string `json:"description"`
 	} `json:"deployment"`
 
+	// issue comment hook details
+	Issue struct {
+		Number      int `json:"number"`
+		PullRequest *struct {
+			URL string `json:"url"`
+		} `json:"pull_request"`
+	} `json:"issue"`
+
+	Comment struct {
+		Body string `json:"body"`
+		User struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	} `json:"comment"`
+
+	// check run details
+	CheckRun struct {
+		ExternalID string `json:"external_id"`
+	} `json:"check_run"`
+
 	// pull request details
 	PullRequest struct {
 		Number  int    `json:"number"`
@@ -3,10 +3,12 @@ package client
 import (
 	"encoding/json"
 	"strconv"
+	"strings"
 )
 
 const (
-	groupsUrl = "/groups"
+	groupsUrl      = "/groups"
+	groupMemberUrl = "/groups/:id/members/all/:user_id"
 )
 
 // Get a list of all projects owned by the authenticated user.
@@ -51,3 +53,21 @@ func (g *Client) Groups(page, perPage int) ([]*Namespace, error) {
 
 	return groups, err
 }
+
+// Get the membership of the user in the group, including the membership
+// inherited from parent groups.
+func (g *Client) GroupMember(id string, userId int) (*GroupMember, error) {
+	url, opaque := g.ResourceUrl(groupMemberUrl, QMap{
+		":id":      strings.Replace(id, "/", "%2F", -1),
+		":user_id": strconv.Itoa(userId),
+	}, nil)
+
+	var member *GroupMember
+
+	contents, err := g.Do("GET", url, opaque, nil)
+	if err == nil {
+		err = json.Unmarshal(contents, &member)
+	}
+
+	return member, err
+}
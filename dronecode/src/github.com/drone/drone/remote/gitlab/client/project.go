@@ -7,12 +7,10 @@ import (
 )
 
 const (
-	searchUrl         = "/projects/search/:query"
-	projectsUrl       = "/projects"
-	projectUrl        = "/projects/:id"
-	repoUrlRawFile    = "/projects/:id/repository/blobs/:sha"
-	repoUrlRawFileRef = "/projects/:id/repository/files"
-	commitStatusUrl   = "/projects/:id/statuses/:sha"
+	projectsUrl     = "/projects"
+	projectUrl      = "/projects/:id"
+	repoUrlRawFile  = "/projects/:id/repository/files/:filepath/raw"
+	commitStatusUrl = "/projects/:id/statuses/:sha"
 )
 
 // Get a list of all projects owned by the authenticated user.
@@ -45,8 +43,9 @@ func (g *Client) AllProjects(hide_archives bool) ([]*Project, error) {
 // Get a list of projects owned by the authenticated user.
 func (c *Client) Projects(page int, per_page int, hide_archives bool) ([]*Project, error) {
 	projectsOptions := QMap{
-		"page":     strconv.Itoa(page),
-		"per_page": strconv.Itoa(per_page),
+		"page":       strconv.Itoa(page),
+		"per_page":   strconv.Itoa(per_page),
+		"membership": "true",
 	}
 
 	if hide_archives {
@@ -79,33 +78,21 @@ func (c *Client) Project(id string) (*Project, error) {
 	return project, err
 }
 
-// Get Raw file content
+// Get Raw file content at the commit sha
 func (c *Client) RepoRawFile(id, sha, filepath string) ([]byte, error) {
-	url, opaque := c.ResourceUrl(
-		repoUrlRawFile,
-		QMap{
-			":id":  id,
-			":sha": sha,
-		},
-		QMap{
-			"filepath": filepath,
-		},
-	)
-
-	contents, err := c.Do("GET", url, opaque, nil)
-
-	return contents, err
+	return c.RepoRawFileRef(id, sha, filepath)
 }
 
+// Get Raw file content at the ref
 func (c *Client) RepoRawFileRef(id, ref, filepath string) ([]byte, error) {
 	url, opaque := c.ResourceUrl(
-		repoUrlRawFileRef,
+		repoUrlRawFile,
 		QMap{
-			":id": id,
+			":id":       id,
+			":filepath": strings.Replace(filepath, "/", "%2F", -1),
 		},
 		QMap{
-			"filepath": filepath,
-			"ref":      ref,
+			"ref": ref,
 		},
 	)
 
@@ -114,8 +101,9 @@ func (c *Client) RepoRawFileRef(id, ref, filepath string) ([]byte, error) {
 	return contents, err
 }
 
-//
-func (c *Client) SetStatus(id, sha, state, desc, ref, link string) error {
+// Set the commit status. The name is displayed as the pipeline name in
+// the GitLab merge request and commit views.
+func (c *Client) SetStatus(id, sha, state, desc, ref, link, name string) error {
 	url, opaque := c.ResourceUrl(
 		commitStatusUrl,
 		QMap{
@@ -127,7 +115,7 @@ func (c *Client) SetStatus(id, sha, state, desc, ref, link string) error {
 			"ref":         ref,
 			"target_url":  link,
 			"description": desc,
-			"context":     "ci/drone",
+			"name":        name,
 		},
 	)
 
@@ -138,8 +126,9 @@ func (c *Client) SetStatus(id, sha, state, desc, ref, link string) error {
 // Get a list of projects by query owned by the authenticated user.
 func (c *Client) SearchProjectId(namespace string, name string) (id int, err error) {
 
-	url, opaque := c.ResourceUrl(searchUrl, nil, QMap{
-		":query": strings.ToLower(name),
+	url, opaque := c.ResourceUrl(projectsUrl, nil, QMap{
+		"search":     strings.ToLower(name),
+		"membership": "true",
 	})
 
 	var projects []*Project
@@ -152,7 +141,7 @@ func (c *Client) SearchProjectId(namespace string, name string) (id int, err err
 	}
 
 	for _, project := range projects {
-		if project.Namespace.Name == namespace && strings.ToLower(project.Name) == strings.ToLower(name) {
+		if strings.EqualFold(project.PathWithNamespace, namespace+"/"+name) {
 			id = project.Id
 		}
 	}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+const (
+	projectHooksUrl = "/projects/:id/hooks"
+	projectHookUrl  = "/projects/:id/hooks/:hook_id"
+)
+
+type ProjectHook struct {
+	Id  int    `json:"id,omitempty"`
+	Url string `json:"url,omitempty"`
+}
+
+// Get a list of the project hooks.
+func (c *Client) ProjectHooks(id string) ([]*ProjectHook, error) {
+	url, opaque := c.ResourceUrl(projectHooksUrl, QMap{":id": id}, nil)
+
+	var hooks []*ProjectHook
+
+	contents, err := c.Do("GET", url, opaque, nil)
+	if err == nil {
+		err = json.Unmarshal(contents, &hooks)
+	}
+
+	return hooks, err
+}
+
+// Add a project hook. The token is sent by GitLab in the X-Gitlab-Token
+// header, and is used to verify the hook.
+func (c *Client) AddProjectHook(id string, params QMap) error {
+	url, opaque := c.ResourceUrl(
+		projectHooksUrl,
+		QMap{":id": id},
+		params,
+	)
+
+	_, err := c.Do("POST", url, opaque, nil)
+	return err
+}
+
+// Delete a project hook.
+func (c *Client) DeleteProjectHook(id string, hookId int) error {
+	url, opaque := c.ResourceUrl(
+		projectHookUrl,
+		QMap{
+			":id":      id,
+			":hook_id": strconv.Itoa(hookId),
+		},
+		nil,
+	)
+
+	_, err := c.Do("DELETE", url, opaque, nil)
+	return err
+}
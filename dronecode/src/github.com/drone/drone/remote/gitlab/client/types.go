@@ -20,6 +20,13 @@ type GroupAccess struct {
 	NotificationLevel int `json:"notification_level,omitempty"`
 }
 
+type GroupMember struct {
+	Id          int    `json:"id,omitempty"`
+	Username    string `json:"username,omitempty"`
+	State       string `json:"state,omitempty"`
+	AccessLevel int    `json:"access_level,omitempty"`
+}
+
 type Permissions struct {
 	ProjectAccess *ProjectAccess `json:"project_access,omitempty"`
 	GroupAccess   *GroupAccess   `json:"group_access,omitempty"`
@@ -42,6 +49,7 @@ type Project struct {
 	Description       string       `json:"description,omitempty"`
 	DefaultBranch     string       `json:"default_branch,omitempty"`
 	Public            bool         `json:"public,omitempty"`
+	Visibility        string       `json:"visibility,omitempty"`
 	Path              string       `json:"path,omitempty"`
 	PathWithNamespace string       `json:"path_with_namespace,omitempty"`
 	Namespace         *Namespace   `json:"namespace,omitempty"`
@@ -53,9 +61,10 @@ type Project struct {
 }
 
 type Namespace struct {
-	Id   int    `json:"id,omitempty"`
-	Name string `json:"name,omitempty"`
-	Path string `json:"path,omitempty"`
+	Id       int    `json:"id,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Path     string `json:"path,omitempty"`
+	FullPath string `json:"full_path,omitempty"`
 }
 
 type Person struct {
@@ -117,6 +126,8 @@ type HookObjAttr struct {
 	MergeStatus     string    `json:"merge_status,omitempty"`
 	TargetProjectId int       `json:"target_project_id,omitempty"`
 	Url             string    `json:"url,omiyempty"`
+	Action          string    `json:"action,omitempty"`
+	Oldrev          string    `json:"oldrev,omitempty"`
 	Source          *hProject `json:"source,omitempty"`
 	Target          *hProject `json:"target,omitempty"`
 	LastCommit      *hCommit  `json:"last_commit,omitempty"`
@@ -1,6 +1,7 @@
 package gitlab
 
 import (
+	"crypto/subtle"
 	"crypto/tls"
 	"fmt"
 	"io/ioutil"
@@ -20,6 +21,10 @@ import (
 
 const DefaultScope = "api"
 
+// statusContext is the prefix of the commit status name, which is
+// suffixed with the build event.
+const statusContext = "ci/drone"
+
 // Opts defines configuration options.
 type Opts struct {
 	URL         string // Gogs server url.
@@ -97,6 +102,11 @@ func Load(config string) *Gitlab {
 	return &gitlab
 }
 
+// Ping returns an error if the remote system is not reachable.
+func (g *Gitlab) Ping() error {
+	return remote.PingURL(g.URL, g.SkipVerify)
+}
+
 // Login authenticates the session and returns the
 // remote user details.
 func (g *Gitlab) Login(res http.ResponseWriter, req *http.Request) (*model.User, error) {
@@ -196,16 +206,32 @@ func (g *Gitlab) Teams(u *model.User) ([]*model.Team, error) {
 	}
 	var teams []*model.Team
 	for _, group := range groups {
+		// subgroups are identified by the full group path, which matches
+		// the owner of subgroup repositories.
+		login := group.FullPath
+		if login == "" {
+			login = group.Name
+		}
 		teams = append(teams, &model.Team{
-			Login: group.Name,
+			Login: login,
 		})
 	}
 	return teams, nil
 }
 
-// TeamPerm is not supported by the Gitlab driver.
+// TeamPerm fetches the group permissions of the user from the access level
+// of the group membership.
 func (g *Gitlab) TeamPerm(u *model.User, org string) (*model.Perm, error) {
-	return nil, nil
+	client := NewClient(g.URL, u.Token, g.SkipVerify)
+	login, err := client.CurrentUser()
+	if err != nil {
+		return nil, err
+	}
+	member, err := client.GroupMember(org, login.Id)
+	if err != nil {
+		return nil, err
+	}
+	return GroupPerm(member), nil
 }
 
 // Repo fetches the named repository from the remote system.
@@ -241,7 +267,7 @@ func (g *Gitlab) Repo(u *model.User, owner, name string) (*model.Repo, error) {
 	if g.PrivateMode {
 		repo.IsPrivate = true
 	} else {
-		repo.IsPrivate = !repo_.Public
+		repo.IsPrivate = !repo_.Public && repo_.Visibility != "public"
 	}
 
 	return repo, err
@@ -259,9 +285,10 @@ func (g *Gitlab) Repos(u *model.User) ([]*model.RepoLite, error) {
 	}
 
 	for _, repo := range all {
-		var parts = strings.Split(repo.PathWithNamespace, "/")
-		var owner = parts[0]
-		var name = parts[1]
+		owner, name, err := ExtractFromPath(repo.PathWithNamespace)
+		if err != nil {
+			continue
+		}
 		var avatar = repo.AvatarUrl
 
 		if len(avatar) != 0 && !strings.HasPrefix(avatar, "http") {
@@ -276,7 +303,7 @@ func (g *Gitlab) Repos(u *model.User) ([]*model.RepoLite, error) {
 		})
 	}
 
-	return repos, err
+	return repos, nil
 }
 
 // Perm fetches the named repository from the remote system.
@@ -336,15 +363,24 @@ func (g *Gitlab) FileRef(u *model.User, r *model.Repo, ref, f string) ([]byte, e
 	return out, err
 }
 
-// NOTE Currently gitlab doesn't support status for commits and events,
-//      also if we want get MR status in gitlab we need implement a special plugin for gitlab,
-//      gitlab uses API to fetch build status on client side. But for now we skip this.
+// Status sends the commit status to the GitLab repository. The status is
+// named after the build event, for example ci/drone/push.
 func (g *Gitlab) Status(u *model.User, repo *model.Repo, b *model.Build, link string) error {
 	client := NewClient(g.URL, u.Token, g.SkipVerify)
 
 	status := getStatus(b.Status)
 	desc := getDesc(b.Status)
 
+	name := statusContext
+	switch b.Event {
+	case model.EventPull:
+		name += "/mr"
+	default:
+		if len(b.Event) > 0 {
+			name += "/" + b.Event
+		}
+	}
+
 	client.SetStatus(
 		ns(repo.Owner, repo.Name),
 		b.Commit,
@@ -352,6 +388,7 @@ func (g *Gitlab) Status(u *model.User, repo *model.Repo, b *model.Build, link st
 		desc,
 		strings.Replace(b.Ref, "refs/heads/", "", -1),
 		link,
+		name,
 	)
 
 	// Gitlab statuses it's a new feature, just ignore error
@@ -399,8 +436,8 @@ func (g *Gitlab) Netrc(u *model.User, r *model.Repo) (*model.Netrc, error) {
 	}, nil
 }
 
-// Activate activates a repository by adding a Post-commit hook and
-// a Public Deploy key, if applicable.
+// Activate activates a repository by adding a project hook. The hook token
+// is the repository secret, which GitLab sends in the X-Gitlab-Token header.
 func (g *Gitlab) Activate(user *model.User, repo *model.Repo, link string) error {
 	var client = NewClient(g.URL, user.Token, g.SkipVerify)
 	id, err := GetProjectId(g, client, repo.Owner, repo.Name)
@@ -412,20 +449,22 @@ func (g *Gitlab) Activate(user *model.User, repo *model.Repo, link string) error
 	if err != nil {
 		return err
 	}
+	if uri.Query().Get("access_token") == "" {
+		return fmt.Errorf("Hook link requires an access token")
+	}
 
-	droneUrl := fmt.Sprintf("%s://%s", uri.Scheme, uri.Host)
-	droneToken := uri.Query().Get("access_token")
-	ssl_verify := strconv.FormatBool(!g.SkipVerify)
-
-	return client.AddDroneService(id, map[string]string{
-		"token":                   droneToken,
-		"drone_url":               droneUrl,
-		"enable_ssl_verification": ssl_verify,
+	return client.AddProjectHook(id, map[string]string{
+		"url":                     link,
+		"token":                   repo.Hash,
+		"push_events":             "true",
+		"tag_push_events":         "true",
+		"merge_requests_events":   "true",
+		"enable_ssl_verification": strconv.FormatBool(!g.SkipVerify),
 	})
 }
 
-// Deactivate removes a repository by removing all the post-commit hooks
-// which are equal to link and removing the SSH deploy key.
+// Deactivate removes a repository by removing all the project hooks which
+// match the link host, and the legacy Drone CI project service.
 func (g *Gitlab) Deactivate(user *model.User, repo *model.Repo, link string) error {
 	var client = NewClient(g.URL, user.Token, g.SkipVerify)
 	id, err := GetProjectId(g, client, repo.Owner, repo.Name)
@@ -433,7 +472,33 @@ func (g *Gitlab) Deactivate(user *model.User, repo *model.Repo, link string) err
 		return err
 	}
 
-	return client.DeleteDroneService(id)
+	hooks, err := client.ProjectHooks(id)
+	if err != nil {
+		return err
+	}
+	for _, hook := range hooks {
+		if !matchingHook(hook.Url, link) {
+			continue
+		}
+		if err := client.DeleteProjectHook(id, hook.Id); err != nil {
+			return err
+		}
+	}
+
+	// repositories activated before project hooks were used are
+	// integrated using the Drone CI service.
+	client.DeleteDroneService(id)
+	return nil
+}
+
+// Verify verifies the hook token, sent by GitLab in the X-Gitlab-Token
+// header, matches the repository secret.
+func (g *Gitlab) Verify(req *http.Request, payload []byte, secret string) error {
+	token := req.Header.Get("X-Gitlab-Token")
+	if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+		return remote.ErrSignature
+	}
+	return nil
 }
 
 // ParseHook parses the post-commit hook from the Request body
@@ -465,6 +530,15 @@ func mergeRequest(parsed *client.HookPayload, req *http.Request) (*model.Repo, *
 		return nil, nil, fmt.Errorf("object_attributes key expected in merge request hook")
 	}
 
+	// ignore merge requests that are closed or merged, and updates that
+	// do not change the source branch, such as title changes.
+	if obj.State != "" && obj.State != "opened" {
+		return nil, nil, nil
+	}
+	if obj.Action == "update" && obj.Oldrev == "" {
+		return nil, nil, nil
+	}
+
 	target := obj.Target
 	source := obj.Source
 
@@ -507,7 +581,7 @@ func mergeRequest(parsed *client.HookPayload, req *http.Request) (*model.Repo, *
 	}
 
 	build := &model.Build{}
-	build.Event = "pull_request"
+	build.Event = model.EventPull
 
 	lastCommit := obj.LastCommit
 	if lastCommit == nil {
@@ -516,11 +590,18 @@ func mergeRequest(parsed *client.HookPayload, req *http.Request) (*model.Repo, *
 
 	build.Message = lastCommit.Message
 	build.Commit = lastCommit.Id
-	//build.Remote = parsed.ObjectAttributes.Source.HttpUrl
 
+	// the merge request number is included in the ref, and the source
+	// and target branches in the refspec.
 	build.Ref = fmt.Sprintf("refs/merge-requests/%d/head", obj.IId)
+	build.Refspec = fmt.Sprintf("%s:%s", obj.SourceBranch, obj.TargetBranch)
+	build.Branch = obj.TargetBranch
 
-	build.Branch = obj.SourceBranch
+	if source.GitHttpUrl != "" {
+		build.Remote = source.GitHttpUrl
+	} else {
+		build.Remote = source.HttpUrl
+	}
 
 	author := lastCommit.Author
 	if author == nil {
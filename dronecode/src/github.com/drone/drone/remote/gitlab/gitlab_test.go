@@ -27,6 +27,7 @@ func Test_Gitlab(t *testing.T) {
 	var repo = model.Repo{
 		Name:  "diaspora-client",
 		Owner: "diaspora",
+		Hash:  "6b3fd1a1b7b6",
 	}
 
 	g := goblin.Goblin(t)
@@ -91,6 +92,25 @@ func Test_Gitlab(t *testing.T) {
 			})
 		})
 
+		g.Describe("TeamPerm", func() {
+			g.It("Should return group permissions", func() {
+				perm, err := gitlab.TeamPerm(&user, "diaspora")
+				g.Assert(err == nil).IsTrue()
+				g.Assert(perm.Admin).Equal(false)
+				g.Assert(perm.Pull).Equal(true)
+				g.Assert(perm.Push).Equal(true)
+			})
+			g.It("Should return subgroup permissions", func() {
+				perm, err := gitlab.TeamPerm(&user, "diaspora/core")
+				g.Assert(err == nil).IsTrue()
+				g.Assert(perm.Pull).Equal(true)
+			})
+			g.It("Should return error, when group is not exist", func() {
+				_, err := gitlab.TeamPerm(&user, "not-existed")
+				g.Assert(err != nil).IsTrue()
+			})
+		})
+
 		// Test activate method
 		g.Describe("Activate", func() {
 			g.It("Should be success", func() {
@@ -115,6 +135,31 @@ func Test_Gitlab(t *testing.T) {
 			})
 		})
 
+		// Test file ref method
+		g.Describe("FileRef", func() {
+			g.It("Should return the file in a subdirectory", func() {
+				out, err := gitlab.FileRef(&user, &repo, "abc", "ci/base.yml")
+				g.Assert(err == nil).IsTrue()
+				g.Assert(string(out)).Equal("pipeline:\n  build:\n    image: golang\n")
+			})
+		})
+
+		// Test verify method
+		g.Describe("Verify", func() {
+			g.It("Should accept the matching token", func() {
+				req, _ := http.NewRequest("POST", "http://example.com/hook", nil)
+				req.Header.Set("X-Gitlab-Token", repo.Hash)
+				g.Assert(gitlab.Verify(req, nil, repo.Hash) == nil).IsTrue()
+			})
+
+			g.It("Should reject a missing or invalid token", func() {
+				req, _ := http.NewRequest("POST", "http://example.com/hook", nil)
+				g.Assert(gitlab.Verify(req, nil, repo.Hash) != nil).IsTrue()
+				req.Header.Set("X-Gitlab-Token", "invalid")
+				g.Assert(gitlab.Verify(req, nil, repo.Hash) != nil).IsTrue()
+			})
+		})
+
 		// Test login method
 		// g.Describe("Login", func() {
 		// 	g.It("Should return user", func() {
@@ -131,6 +176,29 @@ func Test_Gitlab(t *testing.T) {
 		// 	})
 		// })
 
+		// Test subgroup paths
+		g.Describe("ExtractFromPath", func() {
+			g.It("Should return the owner and name", func() {
+				owner, name, err := ExtractFromPath("diaspora/diaspora-client")
+				g.Assert(err == nil).IsTrue()
+				g.Assert(owner).Equal("diaspora")
+				g.Assert(name).Equal("diaspora-client")
+			})
+
+			g.It("Should return the subgroup owner", func() {
+				owner, name, err := ExtractFromPath("diaspora/clients/diaspora-client")
+				g.Assert(err == nil).IsTrue()
+				g.Assert(owner).Equal("diaspora/clients")
+				g.Assert(name).Equal("diaspora-client")
+				g.Assert(ns(owner, name)).Equal("diaspora%2Fclients%2Fdiaspora-client")
+			})
+
+			g.It("Should return error, when path has no namespace", func() {
+				_, _, err := ExtractFromPath("diaspora")
+				g.Assert(err != nil).IsTrue()
+			})
+		})
+
 		// Test hook method
 		g.Describe("Hook", func() {
 			g.Describe("Push hook", func() {
@@ -224,6 +292,26 @@ func Test_Gitlab(t *testing.T) {
 					g.Assert(repo.Name).Equal("awesome_project")
 
 					g.Assert(build.Title).Equal("MS-Viewport")
+					g.Assert(build.Event).Equal(model.EventPull)
+					g.Assert(build.Branch).Equal("master")
+					g.Assert(build.Ref).Equal("refs/merge-requests/1/head")
+					g.Assert(build.Refspec).Equal("ms-viewport:master")
+					g.Assert(build.Remote).Equal("http://example.com/awesome_space/awesome_project.git")
+				})
+
+				g.It("Should ignore merged merge request hook", func() {
+					payload := bytes.Replace(testdata.MergeRequestHook, []byte(`"state": "opened"`), []byte(`"state": "merged"`), 1)
+					req, _ := http.NewRequest(
+						"POST",
+						"http://example.com/api/hook?owner=diaspora&name=diaspora-client",
+						bytes.NewReader(payload),
+					)
+
+					repo, build, err := gitlab.Hook(req)
+
+					g.Assert(err == nil).IsTrue()
+					g.Assert(repo == nil).IsTrue()
+					g.Assert(build == nil).IsTrue()
 				})
 
 				g.It("Should parse legacy merge request hook", func() {
@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/drone/drone/model"
 	"github.com/drone/drone/remote/gitlab/client"
 )
 
@@ -15,10 +16,10 @@ const (
 	gravatarBase = "https://www.gravatar.com/avatar"
 )
 
-// NewClient is a helper function that returns a new GitHub
+// NewClient is a helper function that returns a new GitLab v4 api
 // client using the provided OAuth token.
 func NewClient(url, accessToken string, skipVerify bool) *client.Client {
-	client := client.New(url, "/api/v3", accessToken, skipVerify)
+	client := client.New(url, "/api/v4", accessToken, skipVerify)
 	return client
 }
 
@@ -29,7 +30,7 @@ func IsRead(proj *client.Project) bool {
 	var group = proj.Permissions.GroupAccess
 
 	switch {
-	case proj.Public:
+	case proj.Public, proj.Visibility == "public":
 		return true
 	case user != nil && user.AccessLevel >= 20:
 		return true
@@ -72,6 +73,21 @@ func IsAdmin(proj *client.Project) bool {
 	}
 }
 
+// GroupPerm is a helper function that returns the permissions granted by
+// the access level of a group member. Reporters are granted read access,
+// developers are granted write access and maintainers and owners are
+// granted admin access, in line with the project access levels.
+func GroupPerm(member *client.GroupMember) *model.Perm {
+	perm := new(model.Perm)
+	if member.State != "" && member.State != "active" {
+		return perm
+	}
+	perm.Pull = member.AccessLevel >= 20
+	perm.Push = member.AccessLevel >= 30
+	perm.Admin = member.AccessLevel >= 40
+	return perm
+}
+
 // GetKeyTitle is a helper function that generates a title for the
 // RSA public key based on the username and domain name.
 func GetKeyTitle(rawurl string) (string, error) {
@@ -82,8 +98,24 @@ func GetKeyTitle(rawurl string) (string, error) {
 	return fmt.Sprintf("drone@%s", uri.Host), nil
 }
 
+// matchingHook returns true if the hook url points to the same host as
+// the link.
+func matchingHook(hookURL, link string) bool {
+	a, err := url.Parse(hookURL)
+	if err != nil {
+		return false
+	}
+	b, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	return a.Host == b.Host
+}
+
+// ns returns the url encoded project id for the namespace and name. The
+// owner includes the parent groups of subgroup projects.
 func ns(owner, name string) string {
-	return fmt.Sprintf("%s%%2F%s", owner, name)
+	return fmt.Sprintf("%s%%2F%s", strings.Replace(owner, "/", "%2F", -1), name)
 }
 
 func GetUserAvatar(email string) string {
@@ -98,12 +130,15 @@ func GetUserAvatar(email string) string {
 	)
 }
 
+// ExtractFromPath returns the owner and name from the project path with
+// namespace. The owner of a subgroup project is the full group path, for
+// example group/subgroup.
 func ExtractFromPath(str string) (string, string, error) {
-	s := strings.Split(str, "/")
-	if len(s) < 2 {
+	i := strings.LastIndex(str, "/")
+	if i < 1 || i == len(str)-1 {
 		return "", "", fmt.Errorf("Minimum match not found")
 	}
-	return s[0], s[1], nil
+	return str[:i], str[i+1:], nil
 }
 
 func GetUserEmail(c *client.Client, defaultURL string) (*client.Client, error) {
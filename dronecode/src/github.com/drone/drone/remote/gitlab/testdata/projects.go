@@ -210,3 +210,25 @@ var project6Paylod = []byte(`
 	}
 }
 `)
+
+var projectHooksPayload = []byte(`
+[
+	{
+		"id": 1,
+		"url": "http://example.com/hook?access_token=token",
+		"push_events": true,
+		"tag_push_events": true,
+		"merge_requests_events": true
+	},
+	{
+		"id": 2,
+		"url": "http://ci.example.org/hook",
+		"push_events": true
+	}
+]
+`)
+
+var fileBasePayload = []byte(`pipeline:
+  build:
+    image: golang
+`)
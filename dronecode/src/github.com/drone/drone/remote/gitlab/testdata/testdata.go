@@ -3,6 +3,7 @@ package testdata
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 )
 
 // setup a mock server for testing purposes.
@@ -15,7 +16,7 @@ func NewServer() *httptest.Server {
 		//println(r.URL.Path + "  " + r.Method)
 		// evaluate the path to serve a dummy data file
 		switch r.URL.Path {
-		case "/api/v3/projects":
+		case "/api/v4/projects":
 			if r.URL.Query().Get("archived") == "false" {
 				w.Write(notArchivedProjectsPayload)
 			} else {
@@ -23,31 +24,50 @@ func NewServer() *httptest.Server {
 			}
 
 			return
-		case "/api/v3/projects/diaspora/diaspora-client":
+		case "/api/v4/projects/diaspora/diaspora-client":
 			w.Write(project4Paylod)
 			return
-		case "/api/v3/projects/brightbox/puppet":
+		case "/api/v4/projects/brightbox/puppet":
 			w.Write(project6Paylod)
 			return
-		case "/api/v3/projects/diaspora/diaspora-client/services/drone-ci":
+		case "/api/v4/projects/diaspora/diaspora-client/services/drone-ci":
+			w.WriteHeader(204)
+			return
+		case "/api/v4/projects/diaspora/diaspora-client/hooks":
 			switch r.Method {
-			case "PUT":
+			case "POST":
 				if r.FormValue("token") == "" {
-					w.WriteHeader(404)
+					w.WriteHeader(400)
 				} else {
 					w.WriteHeader(201)
 				}
-			case "DELETE":
-				w.WriteHeader(201)
+			case "GET":
+				w.Write(projectHooksPayload)
 			}
-
 			return
+		case "/api/v4/projects/diaspora/diaspora-client/hooks/1":
+			w.WriteHeader(204)
+			return
+		case "/api/v4/projects/diaspora/diaspora-client/repository/files/ci/base.yml/raw":
+			// the file path must be encoded as a single path segment.
+			if strings.Contains(r.URL.EscapedPath(), "/ci%2Fbase%2Eyml/") && r.FormValue("ref") == "abc" {
+				w.Write(fileBasePayload)
+				return
+			}
 		case "/oauth/token":
 			w.Write(accessTokenPayload)
 			return
-		case "/api/v3/user":
+		case "/api/v4/user":
 			w.Write(currentUserPayload)
 			return
+		case "/api/v4/groups/diaspora/members/all/1":
+			w.Write(groupMemberPayload)
+			return
+		case "/api/v4/groups/diaspora/core/members/all/1":
+			if strings.Contains(r.URL.EscapedPath(), "/diaspora%2Fcore/") {
+				w.Write(groupMemberPayload)
+				return
+			}
 		}
 
 		// else return a 404
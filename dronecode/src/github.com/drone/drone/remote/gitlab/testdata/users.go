@@ -22,3 +22,13 @@ var currentUserPayload = []byte(`
   "projects_limit": 100
 }
 `)
+
+var groupMemberPayload = []byte(`
+{
+  "id": 1,
+  "username": "john_smith",
+  "name": "John Smith",
+  "state": "active",
+  "access_level": 30
+}
+`)
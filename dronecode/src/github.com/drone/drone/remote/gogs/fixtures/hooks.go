@@ -110,7 +110,14 @@ var HookPullRequest = `{
     "head": {
       "label": "feature/changes",
       "ref": "feature/changes",
-      "sha": "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c"
+      "sha": "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c",
+      "repo": {
+        "id": 35129378,
+        "name": "hello-world",
+        "full_name": "spaceghost/hello-world",
+        "html_url": "http://gogs.golang.org/spaceghost/hello-world",
+        "clone_url": "http://gogs.golang.org/spaceghost/hello-world.git"
+      }
     }
   },
   "repository": {
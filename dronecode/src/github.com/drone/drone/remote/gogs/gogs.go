@@ -52,6 +52,11 @@ func New(opts Opts) (remote.Remote, error) {
 	}, nil
 }
 
+// Ping returns an error if the remote system is not reachable.
+func (c *client) Ping() error {
+	return remote.PingURL(c.URL, c.SkipVerify)
+}
+
 // Login authenticates an account with Gogs using basic authenticaiton. The
 // Gogs account details are returned when the user is successfully authenticated.
 func (c *client) Login(res http.ResponseWriter, req *http.Request) (*model.User, error) {
@@ -206,6 +211,16 @@ func (c *client) Status(u *model.User, r *model.Repo, b *model.Build, link strin
 	return nil
 }
 
+// BranchHead returns the sha of the head commit of the named branch.
+func (c *client) BranchHead(u *model.User, r *model.Repo, branch string) (string, error) {
+	client := c.newClientToken(u.Token)
+	data, err := client.GetRepoBranch(r.Owner, r.Name, branch)
+	if err != nil {
+		return "", err
+	}
+	return data.Commit.ID, nil
+}
+
 // Netrc returns a netrc file capable of authenticating Gogs requests and
 // cloning Gogs repositories. The netrc will use the global machine account
 // when configured.
@@ -255,6 +270,15 @@ func (c *client) Hook(r *http.Request) (*model.Repo, *model.Build, error) {
 	return parseHook(r)
 }
 
+// Verify verifies the hmac signature of the hook payload, sent by Gogs in
+// the X-Gogs-Signature header.
+func (c *client) Verify(r *http.Request, payload []byte, secret string) error {
+	if !validSignature(payload, r.Header.Get(hookSignature), secret) {
+		return remote.ErrSignature
+	}
+	return nil
+}
+
 // helper function to return the Gogs client
 func (c *client) newClient() *gogs.Client {
 	return c.newClientToken("")
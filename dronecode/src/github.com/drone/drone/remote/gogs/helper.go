@@ -1,6 +1,9 @@
 package gogs
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -143,6 +146,7 @@ func buildFromPullRequest(hook *pullRequestHook) *model.Build {
 		Avatar:  avatar,
 		Sender:  sender,
 		Title:   hook.PullRequest.Title,
+		Remote:  hook.PullRequest.Head.Repo.CloneURL,
 		Refspec: fmt.Sprintf("%s:%s",
 			hook.PullRequest.HeadBranch,
 			hook.PullRequest.BaseBranch,
@@ -219,3 +223,12 @@ func expandAvatar(repo, rawurl string) string {
 
 	return aurl.String()
 }
+
+// helper function returns true if the signature is the hex encoded hmac
+// sha256 of the payload.
+func validSignature(payload []byte, signature, secret string) bool {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
@@ -116,7 +116,7 @@ func Test_parse(t *testing.T) {
 			g.Assert(build.Message).Equal(hook.PullRequest.Title)
 			g.Assert(build.Avatar).Equal("http://1.gravatar.com/avatar/8c58a0be77ee441bb8f8595b7f1b4e87")
 			g.Assert(build.Author).Equal(hook.PullRequest.User.Username)
-
+			g.Assert(build.Remote).Equal("http://gogs.golang.org/spaceghost/hello-world.git")
 		})
 
 		g.It("Should return a Repo struct from a pull_request hook", func() {
@@ -246,5 +246,13 @@ func Test_parse(t *testing.T) {
 				g.Assert(got).Equal(url.After)
 			}
 		})
+
+		g.It("Should validate the hook signature", func() {
+			payload := []byte("The quick brown fox jumps over the lazy dog")
+			signature := "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"
+			g.Assert(validSignature(payload, signature, "key")).IsTrue()
+			g.Assert(validSignature(payload, signature, "invalid")).IsFalse()
+			g.Assert(validSignature(payload, "", "key")).IsFalse()
+		})
 	})
 }
@@ -9,6 +9,7 @@ import (
 
 const (
 	hookEvent       = "X-Gogs-Event"
+	hookSignature   = "X-Gogs-Signature"
 	hookPush        = "push"
 	hookCreated     = "create"
 	hookPullRequest = "pull_request"
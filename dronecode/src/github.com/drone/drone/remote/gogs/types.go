@@ -91,6 +91,7 @@ type pullRequestHook struct {
 				Name     string `json:"name"`
 				FullName string `json:"full_name"`
 				URL      string `json:"html_url"`
+				CloneURL string `json:"clone_url"`
 				Private  bool   `json:"private"`
 				Owner    struct {
 					ID       int64  `json:"id"`
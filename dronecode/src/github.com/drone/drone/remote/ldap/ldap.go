@@ -0,0 +1,444 @@
+package ldap
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/remote"
+	"github.com/drone/drone/shared/httputil"
+
+	"gopkg.in/ldap.v2"
+)
+
+// Opts defines configuration options.
+type Opts struct {
+	URL          string // LDAP server url, using the ldap or ldaps scheme.
+	StartTLS     bool   // Upgrade ldap connections using StartTLS.
+	SkipVerify   bool   // Skip ssl verification.
+	BindDN       string // Optional service account used to search users.
+	BindPassword string // Optional service account password.
+	BaseDN       string // Base distinguished name of the user search.
+	UserFilter   string // User search filter, for example (uid=%s).
+	EmailAttr    string // Attribute of the user email address.
+	GroupAttr    string // Attribute of the user groups, for example memberOf.
+	AdminGroup   string // Group of the system administrators.
+	LinkToken    bool   // Link the remote account with a personal access token.
+}
+
+// groupTTL is the duration the administrator group membership of a user is
+// cached before it is searched again.
+const groupTTL = 5 * time.Minute
+
+// ldapTimeout limits the duration of each request to the LDAP server.
+const ldapTimeout = 30 * time.Second
+
+// client embeds the remote used for repository operations, and
+// authenticates users with an LDAP directory or Active Directory.
+type client struct {
+	remote.Remote
+
+	opts Opts
+
+	sync.Mutex
+	groups map[string]groupAdmin
+}
+
+// groupAdmin is a cached administrator group membership.
+type groupAdmin struct {
+	admin   bool
+	expires time.Time
+}
+
+// New returns a Remote implementation that validates the login credentials
+// against the LDAP directory. The remote account of the user is then
+// authenticated with the login form of the remote, such as gogs, or linked
+// with a personal access token of the remote if LinkToken is set. The
+// directory groups of the user are used as the user teams, and members of
+// the administrator group are system administrators.
+func New(opts Opts, next remote.Remote) (remote.Remote, error) {
+	u, err := url.Parse(opts.URL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "ldap" && u.Scheme != "ldaps" {
+		return nil, fmt.Errorf("Invalid LDAP server url %s", opts.URL)
+	}
+	if opts.UserFilter == "" {
+		opts.UserFilter = "(uid=%s)"
+	}
+	if opts.EmailAttr == "" {
+		opts.EmailAttr = "mail"
+	}
+	if opts.GroupAttr == "" {
+		opts.GroupAttr = "memberOf"
+	}
+	return &client{
+		Remote: next,
+		opts:   opts,
+		groups: map[string]groupAdmin{},
+	}, nil
+}
+
+// Login authenticates the username and password of the login form with the
+// LDAP directory, and then with the remote.
+func (c *client) Login(res http.ResponseWriter, req *http.Request) (*model.User, error) {
+	var (
+		username = req.FormValue("username")
+		password = req.FormValue("password")
+	)
+
+	// if the username or password is empty we re-direct to the login screen.
+	if len(username) == 0 || len(password) == 0 {
+		http.Redirect(res, req, httputil.GetPrefix(req)+"/login/form", http.StatusSeeOther)
+		return nil, nil
+	}
+
+	entry, err := c.authenticate(username, password)
+	if err != nil {
+		return nil, err
+	}
+	var user *model.User
+	if c.opts.LinkToken {
+		user, err = c.link(username, req.FormValue("token"))
+	} else {
+		user, err = c.Remote.Login(res, req)
+	}
+	if err != nil || user == nil {
+		return user, err
+	}
+	if email := attrValue(entry, c.opts.EmailAttr); email != "" {
+		user.Email = email
+	}
+	user.GroupAdmin = c.isAdmin(entry)
+	c.cache(user.Login, user.GroupAdmin)
+	return user, nil
+}
+
+// LinkToken returns true if the login form requires a personal access token
+// of the remote account.
+func (c *client) LinkToken() bool {
+	return c.opts.LinkToken
+}
+
+// GroupAdmin returns true if the user is a member of the administrator
+// group. The membership is cached, so that it is not searched on every
+// request.
+func (c *client) GroupAdmin(u *model.User) (bool, error) {
+	c.Lock()
+	cached, ok := c.groups[u.Login]
+	c.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.admin, nil
+	}
+	entry, err := c.lookup(u.Login)
+	if err != nil {
+		return false, err
+	}
+	admin := c.isAdmin(entry)
+	c.cache(u.Login, admin)
+	return admin, nil
+}
+
+// Auth is not supported, since the access token of the remote does not
+// prove the directory credentials of the user.
+func (c *client) Auth(token, secret string) (string, error) {
+	return "", fmt.Errorf("Token login is not supported with LDAP authentication")
+}
+
+// Teams returns the directory groups of the user.
+func (c *client) Teams(u *model.User) ([]*model.Team, error) {
+	entry, err := c.lookup(u.Login)
+	if err != nil {
+		return nil, err
+	}
+	var teams []*model.Team
+	for _, group := range attrValues(entry, c.opts.GroupAttr) {
+		teams = append(teams, &model.Team{Login: groupName(group)})
+	}
+	return teams, nil
+}
+
+// TeamPerm returns the organization permissions of the user. Members of the
+// group with the organization name have read access. Otherwise the
+// permissions are fetched from the remote.
+func (c *client) TeamPerm(u *model.User, org string) (*model.Perm, error) {
+	entry, err := c.lookup(u.Login)
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range attrValues(entry, c.opts.GroupAttr) {
+		if strings.EqualFold(groupName(group), org) {
+			return &model.Perm{Pull: true}, nil
+		}
+	}
+	return c.Remote.TeamPerm(u, org)
+}
+
+// Refresh refreshes the oauth token of the user, if supported by the remote.
+func (c *client) Refresh(u *model.User) (bool, error) {
+	if refresher, ok := c.Remote.(remote.Refresher); ok {
+		return refresher.Refresh(u)
+	}
+	return false, nil
+}
+
+// Comment parses the pull request comment hook, if supported by the remote.
+func (c *client) Comment(r *http.Request) (*model.Comment, error) {
+	if commenter, ok := c.Remote.(remote.Commenter); ok {
+		return commenter.Comment(r)
+	}
+	return nil, nil
+}
+
+// Collaborator returns the repository permissions of the login, if
+// supported by the remote.
+func (c *client) Collaborator(u *model.User, r *model.Repo, login string) (*model.Perm, error) {
+	if commenter, ok := c.Remote.(remote.Commenter); ok {
+		return commenter.Collaborator(u, r, login)
+	}
+	return nil, fmt.Errorf("Collaborator permissions are not supported by the remote")
+}
+
+// BranchHead returns the head commit of the branch, if supported by the
+// remote.
+func (c *client) BranchHead(u *model.User, r *model.Repo, branch string) (string, error) {
+	if brancher, ok := c.Remote.(remote.Brancher); ok {
+		return brancher.BranchHead(u, r, branch)
+	}
+	return "", remote.ErrBranchHeadNotSupported
+}
+
+// Check reports the step state, if supported by the remote.
+func (c *client) Check(u *model.User, r *model.Repo, b *model.Build, p *model.Proc, link, logs string) error {
+	if checker, ok := c.Remote.(remote.Checker); ok {
+		return checker.Check(u, r, b, p, link, logs)
+	}
+	return nil
+}
+
+// Rerun parses the re-run request, if supported by the remote.
+func (c *client) Rerun(r *http.Request) (*model.Repo, int, error) {
+	if rerunner, ok := c.Remote.(remote.Rerunner); ok {
+		return rerunner.Rerun(r)
+	}
+	return nil, 0, fmt.Errorf("Re-running builds is not supported by the remote")
+}
+
+// Verify verifies the hook signature, if supported by the remote. Hooks of
+// remotes that do not sign hooks are accepted.
+func (c *client) Verify(r *http.Request, payload []byte, secret string) error {
+	if verifier, ok := c.Remote.(remote.Verifier); ok {
+		return verifier.Verify(r, payload, secret)
+	}
+	return nil
+}
+
+// Changes returns the changed files of the build, if supported by the
+// remote.
+func (c *client) Changes(u *model.User, r *model.Repo, b *model.Build) ([]string, error) {
+	if differ, ok := c.Remote.(remote.Differ); ok {
+		return differ.Changes(u, r, b)
+	}
+	return nil, fmt.Errorf("Changed files are not supported by the remote")
+}
+
+// Ping checks the remote is reachable, if supported by the remote.
+func (c *client) Ping() error {
+	if pinger, ok := c.Remote.(remote.Pinger); ok {
+		return pinger.Ping()
+	}
+	return nil
+}
+
+// link returns the remote account of the personal access token, which must
+// be the remote account of the directory user.
+func (c *client) link(username, token string) (*model.User, error) {
+	if token == "" {
+		return nil, fmt.Errorf("Missing access token of the remote account of %s", username)
+	}
+	login, err := c.Remote.Auth(token, "")
+	if err != nil {
+		return nil, fmt.Errorf("Invalid access token of the remote account of %s. %s", username, err)
+	}
+	if !strings.EqualFold(login, username) {
+		return nil, fmt.Errorf("Access token of %s does not match the LDAP user %s", login, username)
+	}
+	return &model.User{Login: login, Token: token}, nil
+}
+
+// cache stores the administrator group membership of the user.
+func (c *client) cache(login string, admin bool) {
+	c.Lock()
+	c.groups[login] = groupAdmin{admin: admin, expires: time.Now().Add(groupTTL)}
+	c.Unlock()
+}
+
+// authenticate returns the directory entry of the user if the password is
+// valid.
+func (c *client) authenticate(username, password string) (*ldap.Entry, error) {
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entry, err := c.search(conn, username)
+	if err != nil {
+		return nil, err
+	}
+	// servers treat a bind with an empty password as an unauthenticated
+	// bind, which succeeds for any user.
+	if password == "" {
+		return nil, fmt.Errorf("Invalid LDAP credentials for %s", username)
+	}
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, fmt.Errorf("Invalid LDAP credentials for %s", username)
+		}
+		return nil, err
+	}
+	return entry, nil
+}
+
+// lookup returns the directory entry of the user.
+func (c *client) lookup(username string) (*ldap.Entry, error) {
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return c.search(conn, username)
+}
+
+// search returns the directory entry of the user, using the service
+// account if configured, or an anonymous search otherwise.
+func (c *client) search(conn *ldap.Conn, username string) (*ldap.Entry, error) {
+	if c.opts.BindDN != "" {
+		if err := conn.Bind(c.opts.BindDN, c.opts.BindPassword); err != nil {
+			return nil, fmt.Errorf("Cannot bind the LDAP service account. %s", err)
+		}
+	}
+	filter := fmt.Sprintf(c.opts.UserFilter, ldap.EscapeFilter(username))
+	req := ldap.NewSearchRequest(
+		c.opts.BaseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		0, 0, false,
+		filter,
+		[]string{c.opts.EmailAttr, c.opts.GroupAttr},
+		nil,
+	)
+	res, err := conn.Search(req)
+	if err != nil {
+		return nil, err
+	}
+	switch len(res.Entries) {
+	case 0:
+		return nil, fmt.Errorf("Cannot find LDAP user %s", username)
+	case 1:
+		return res.Entries[0], nil
+	default:
+		return nil, fmt.Errorf("Found multiple LDAP users matching %s", username)
+	}
+}
+
+// dial connects to the LDAP server, using the ldap or ldaps scheme of the
+// server url.
+func (c *client) dial() (*ldap.Conn, error) {
+	u, err := url.Parse(c.opts.URL)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{
+		InsecureSkipVerify: c.opts.SkipVerify,
+		ServerName:         u.Hostname(),
+	}
+	host := u.Host
+	var conn *ldap.Conn
+	switch u.Scheme {
+	case "ldaps":
+		if u.Port() == "" {
+			host = net.JoinHostPort(host, "636")
+		}
+		conn, err = ldap.DialTLS("tcp", host, config)
+	default:
+		if u.Port() == "" {
+			host = net.JoinHostPort(host, "389")
+		}
+		conn, err = ldap.Dial("tcp", host)
+	}
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(ldapTimeout)
+	if c.opts.StartTLS && u.Scheme == "ldap" {
+		if err := conn.StartTLS(config); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// helper function returns the first value of the named attribute of the
+// entry. Attribute names are case insensitive.
+func attrValue(entry *ldap.Entry, name string) string {
+	if values := attrValues(entry, name); len(values) != 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// helper function returns the values of the named attribute of the entry.
+// Attribute names are case insensitive.
+func attrValues(entry *ldap.Entry, name string) []string {
+	for _, attr := range entry.Attributes {
+		if strings.EqualFold(attr.Name, name) {
+			return attr.Values
+		}
+	}
+	return nil
+}
+
+// helper function returns true if the user is a member of the administrator
+// group.
+func (c *client) isAdmin(entry *ldap.Entry) bool {
+	if c.opts.AdminGroup == "" {
+		return false
+	}
+	for _, group := range attrValues(entry, c.opts.GroupAttr) {
+		if strings.EqualFold(group, c.opts.AdminGroup) || strings.EqualFold(groupName(group), c.opts.AdminGroup) {
+			return true
+		}
+	}
+	return false
+}
+
+// helper function returns the common name of the group distinguished name,
+// for example admins for cn=admins,ou=groups,dc=example,dc=com. Group names
+// that are not distinguished names are returned unchanged.
+func groupName(dn string) string {
+	rdn := dn
+	for i := 0; i < len(dn); i++ {
+		if dn[i] == '\\' {
+			i++
+			continue
+		}
+		if dn[i] == ',' {
+			rdn = dn[:i]
+			break
+		}
+	}
+	i := strings.IndexByte(rdn, '=')
+	if i == -1 {
+		return dn
+	}
+	return strings.Replace(rdn[i+1:], "\\", "", -1)
+}
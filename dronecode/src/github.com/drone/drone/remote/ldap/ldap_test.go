@@ -0,0 +1,217 @@
+package ldap
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/remote"
+	"github.com/drone/drone/remote/mock"
+	"github.com/franela/goblin"
+
+	"gopkg.in/asn1-ber.v1"
+	"gopkg.in/ldap.v2"
+)
+
+func Test_ldap(t *testing.T) {
+	g := goblin.Goblin(t)
+	g.Describe("LDAP", func() {
+
+		g.It("Should require an ldap server url", func() {
+			_, err := New(Opts{URL: "http://ldap.example.com"}, nil)
+			g.Assert(err != nil).IsTrue()
+
+			r, err := New(Opts{URL: "ldaps://ldap.example.com"}, nil)
+			g.Assert(err == nil).IsTrue()
+			g.Assert(r.(*client).opts.UserFilter).Equal("(uid=%s)")
+			g.Assert(r.(*client).opts.GroupAttr).Equal("memberOf")
+		})
+
+		g.It("Should not support token login", func() {
+			r, _ := New(Opts{URL: "ldaps://ldap.example.com"}, nil)
+			_, err := r.Auth("token", "")
+			g.Assert(err != nil).IsTrue()
+		})
+
+		g.It("Should return the group name", func() {
+			g.Assert(groupName("cn=admins,ou=groups,dc=example,dc=com")).Equal("admins")
+			g.Assert(groupName("CN=Drone\\, Admins,OU=Groups,DC=example,DC=com")).Equal("Drone, Admins")
+			g.Assert(groupName("developers")).Equal("developers")
+		})
+
+		g.It("Should match the admin group", func() {
+			entry := ldap.NewEntry("uid=octocat,dc=example,dc=com", map[string][]string{
+				"memberOf": {"cn=octocat,ou=groups,dc=example,dc=com", "CN=Admins,ou=groups,dc=example,dc=com"},
+			})
+			c := &client{opts: Opts{GroupAttr: "memberOf", AdminGroup: "admins"}}
+			g.Assert(c.isAdmin(entry)).IsTrue()
+
+			c.opts.AdminGroup = "cn=admins,ou=groups,dc=example,dc=com"
+			g.Assert(c.isAdmin(entry)).IsTrue()
+
+			c.opts.AdminGroup = "operators"
+			g.Assert(c.isAdmin(entry)).IsFalse()
+
+			c.opts.AdminGroup = ""
+			g.Assert(c.isAdmin(entry)).IsFalse()
+		})
+
+		g.It("Should forward the optional interfaces of the remote", func() {
+			r, _ := New(Opts{URL: "ldaps://ldap.example.com"}, &fakeRemote{})
+			sha, err := r.(remote.Brancher).BranchHead(nil, nil, "master")
+			g.Assert(err == nil).IsTrue()
+			g.Assert(sha).Equal("abc")
+			g.Assert(r.(remote.Verifier).Verify(nil, nil, "secret")).Equal(remote.ErrSignature)
+		})
+
+		g.It("Should not forward optional interfaces the remote does not implement", func() {
+			r, _ := New(Opts{URL: "ldaps://ldap.example.com"}, new(mock.Remote))
+			_, err := r.(remote.Brancher).BranchHead(nil, nil, "master")
+			g.Assert(err).Equal(remote.ErrBranchHeadNotSupported)
+			g.Assert(r.(remote.Verifier).Verify(nil, nil, "secret") == nil).IsTrue()
+			refreshed, err := r.(remote.Refresher).Refresh(nil)
+			g.Assert(refreshed || err != nil).IsFalse()
+		})
+
+		g.It("Should link the remote account with an access token", func() {
+			next := new(mock.Remote)
+			next.On("Auth", "token", "").Return("Octocat", nil)
+			next.On("Auth", "other", "").Return("spaceghost", nil)
+			next.On("Auth", "invalid", "").Return("", errors.New("unauthorized"))
+			c := &client{Remote: next, opts: Opts{LinkToken: true}}
+
+			user, err := c.link("octocat", "token")
+			g.Assert(err == nil).IsTrue()
+			g.Assert(user.Login).Equal("Octocat")
+			g.Assert(user.Token).Equal("token")
+
+			_, err = c.link("octocat", "other")
+			g.Assert(err != nil).IsTrue()
+			_, err = c.link("octocat", "invalid")
+			g.Assert(err != nil).IsTrue()
+			_, err = c.link("octocat", "")
+			g.Assert(err != nil).IsTrue()
+		})
+
+		g.It("Should authenticate the user with the directory", func() {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			g.Assert(err == nil).IsTrue()
+			defer l.Close()
+			go serveLDAP(l)
+
+			r, _ := New(Opts{URL: "ldap://" + l.Addr().String(), BaseDN: "dc=example,dc=com"}, nil)
+			c := r.(*client)
+
+			entry, err := c.authenticate("octocat", "secret")
+			g.Assert(err == nil).IsTrue()
+			g.Assert(entry.DN).Equal("uid=octocat,dc=example,dc=com")
+			g.Assert(attrValue(entry, "MAIL")).Equal("octocat@example.com")
+			g.Assert(len(attrValues(entry, "memberOf"))).Equal(2)
+
+			_, err = c.authenticate("octocat", "wrong")
+			g.Assert(err.Error()).Equal("Invalid LDAP credentials for octocat")
+			_, err = c.authenticate("octocat", "")
+			g.Assert(err.Error()).Equal("Invalid LDAP credentials for octocat")
+		})
+
+		g.It("Should cache the admin group membership", func() {
+			r, _ := New(Opts{URL: "ldap://127.0.0.1:1"}, nil)
+			c := r.(*client)
+			user := &model.User{Login: "octocat"}
+
+			c.cache("octocat", true)
+			admin, err := c.GroupAdmin(user)
+			g.Assert(err == nil).IsTrue()
+			g.Assert(admin).IsTrue()
+
+			// expired memberships are searched again, and fail if the
+			// directory is not reachable.
+			c.groups["octocat"] = groupAdmin{admin: true, expires: time.Now().Add(-time.Second)}
+			_, err = c.GroupAdmin(user)
+			g.Assert(err != nil).IsTrue()
+		})
+	})
+}
+
+// fakeRemote is a remote that resolves branches and rejects hook signatures.
+type fakeRemote struct {
+	mock.Remote
+}
+
+func (r *fakeRemote) BranchHead(u *model.User, repo *model.Repo, branch string) (string, error) {
+	return "abc", nil
+}
+
+func (r *fakeRemote) Verify(req *http.Request, payload []byte, secret string) error {
+	return remote.ErrSignature
+}
+
+// serveLDAP is a fake ldap server that accepts any bind with the password
+// secret, and returns a single search entry.
+func serveLDAP(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			for {
+				packet, err := ber.ReadPacket(conn)
+				if err != nil || len(packet.Children) < 2 {
+					return
+				}
+				id := packet.Children[0].Value.(int64)
+				op := packet.Children[1]
+				switch op.Tag {
+				case ldap.ApplicationBindRequest:
+					code := ldap.LDAPResultSuccess
+					if op.Children[2].Data.String() != "secret" {
+						code = ldap.LDAPResultInvalidCredentials
+					}
+					conn.Write(ldapResult(id, ldap.ApplicationBindResponse, code).Bytes())
+				case ldap.ApplicationSearchRequest:
+					entry := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "")
+					entry.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "uid=octocat,dc=example,dc=com", ""))
+					attrs := ber.NewSequence("")
+					attrs.AppendChild(ldapAttr("mail", "octocat@example.com"))
+					attrs.AppendChild(ldapAttr("memberOf", "cn=octocat,dc=example,dc=com", "cn=admins,dc=example,dc=com"))
+					entry.AppendChild(attrs)
+					conn.Write(ldapMessage(id, entry).Bytes())
+					conn.Write(ldapResult(id, ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess).Bytes())
+				default:
+					return
+				}
+			}
+		}()
+	}
+}
+
+func ldapMessage(id int64, op *ber.Packet) *ber.Packet {
+	packet := ber.NewSequence("")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, ""))
+	packet.AppendChild(op)
+	return packet
+}
+
+func ldapResult(id int64, tag ber.Tag, code int) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, ""))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	return ldapMessage(id, op)
+}
+
+func ldapAttr(name string, values ...string) *ber.Packet {
+	attr := ber.NewSequence("")
+	attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, ""))
+	set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "")
+	for _, value := range values {
+		set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, ""))
+	}
+	attr.AppendChild(set)
+	return attr
+}
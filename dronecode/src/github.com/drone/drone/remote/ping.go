@@ -0,0 +1,29 @@
+package remote
+
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+)
+
+// PingURL returns an error if the server of the url is not reachable. Any
+// http response, including an error status, means the server is reachable.
+func PingURL(url string, skipVerify bool) error {
+	client := &http.Client{
+		Timeout: time.Second * 5,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: skipVerify},
+		},
+	}
+	req, err := http.NewRequest("HEAD", url, nil)
+	if err != nil {
+		return err
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	return nil
+}
@@ -0,0 +1,20 @@
+package remote
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPingURL(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(500)
+	}))
+	if err := PingURL(s.URL, false); err != nil {
+		t.Errorf("Want server reachable with an error status, got %s", err)
+	}
+	s.Close()
+	if err := PingURL(s.URL, false); err == nil {
+		t.Errorf("Want error pinging a server that is not reachable")
+	}
+}
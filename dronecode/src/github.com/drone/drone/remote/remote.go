@@ -3,6 +3,7 @@ package remote
 //go:generate mockery -name Remote -output mock -case=underscore
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
@@ -72,6 +73,82 @@ type Refresher interface {
 	Refresh(*model.User) (bool, error)
 }
 
+// Commenter parses pull request comment hooks and returns the permissions
+// of the comment author. It is an optional interface used to restart and
+// promote builds with pull request comment commands.
+type Commenter interface {
+	// Comment parses the pull request comment hook from the Request body. If
+	// the hook is not a new pull request comment a nil value is returned.
+	Comment(r *http.Request) (*model.Comment, error)
+
+	// Collaborator returns the permissions of the user login to the
+	// repository.
+	Collaborator(u *model.User, r *model.Repo, login string) (*model.Perm, error)
+}
+
+// Brancher resolves the head commit of a branch. It is an optional
+// interface used to start builds that are not triggered by a hook,
+// such as scheduled builds.
+type Brancher interface {
+	BranchHead(u *model.User, r *model.Repo, branch string) (string, error)
+}
+
+// Checker reports the state of an individual pipeline step to the remote
+// system. It is an optional interface used by remotes that support per-step
+// results, such as the GitHub Checks api.
+type Checker interface {
+	Check(u *model.User, r *model.Repo, b *model.Build, p *model.Proc, link, logs string) error
+}
+
+// Rerunner parses a request from the remote system to restart an existing
+// build, such as a GitHub check run re-run. It returns the repository and
+// build number, or a zero build number if the request should be ignored.
+type Rerunner interface {
+	Rerun(r *http.Request) (*model.Repo, int, error)
+}
+
+// Verifier verifies the signature of an incoming hook payload. It is an
+// optional interface used by remotes that sign hooks with the secret
+// registered when the repository is activated.
+type Verifier interface {
+	Verify(r *http.Request, payload []byte, secret string) error
+}
+
+// Differ lists the files changed by a push or pull request build. It is an
+// optional interface used to evaluate path conditions when the hook does
+// not include the changed files.
+type Differ interface {
+	Changes(u *model.User, r *model.Repo, b *model.Build) ([]string, error)
+}
+
+// Pinger verifies the remote system is reachable. It is an optional
+// interface used by the readiness health check.
+type Pinger interface {
+	Ping() error
+}
+
+// Grouper verifies the user is still a member of the administrator group
+// granted at login. It is an optional interface used to revoke group
+// administrator privileges without waiting for the user to login again.
+type Grouper interface {
+	GroupAdmin(u *model.User) (bool, error)
+}
+
+// TokenLinker links the remote account of the user with a personal access
+// token submitted in the login form. It is an optional interface used by
+// the login form to request the token.
+type TokenLinker interface {
+	LinkToken() bool
+}
+
+// ErrSignature is returned when the hook signature is missing or does not
+// match the payload.
+var ErrSignature = errors.New("remote: missing or invalid hook signature")
+
+// ErrBranchHeadNotSupported is returned when the remote system is not
+// capable of resolving the head commit of a branch.
+var ErrBranchHeadNotSupported = errors.New("remote: resolving branch head is not supported")
+
 // Login authenticates the session and returns the
 // remote user details.
 func Login(c context.Context, w http.ResponseWriter, r *http.Request) (*model.User, error) {
@@ -164,3 +241,12 @@ func Refresh(c context.Context, u *model.User) (bool, error) {
 	}
 	return refresher.Refresh(u)
 }
+
+// BranchHead returns the sha of the head commit of the named branch.
+func BranchHead(c context.Context, u *model.User, r *model.Repo, branch string) (string, error) {
+	brancher, ok := FromContext(c).(Brancher)
+	if !ok {
+		return "", ErrBranchHeadNotSupported
+	}
+	return brancher.BranchHead(u, r, branch)
+}
@@ -21,10 +21,11 @@ func Config(cli *cli.Context) gin.HandlerFunc {
 // helper function to create the configuration from the CLI context.
 func setupConfig(c *cli.Context) *model.Settings {
 	return &model.Settings{
-		Open:   c.Bool("open"),
-		Secret: c.String("agent-secret"),
-		Admins: sliceToMap2(c.StringSlice("admin")),
-		Orgs:   sliceToMap2(c.StringSlice("orgs")),
+		Open:      c.Bool("open"),
+		Secret:    c.String("agent-secret"),
+		PromToken: c.String("prometheus-auth-token"),
+		Admins:    sliceToMap2(c.StringSlice("admin")),
+		Orgs:      sliceToMap2(c.StringSlice("orgs")),
 	}
 }
 
@@ -0,0 +1,80 @@
+// Package logger provides structured request logging, where each request is
+// tagged with a request id that is included in the log entries written while
+// handling the request and echoed in the response.
+package logger
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/gin-gonic/gin"
+)
+
+// Header is the request and response header of the request id.
+const Header = "X-Request-ID"
+
+const (
+	keyID    = "request_id"
+	keyEntry = "logger"
+)
+
+// maxIDLen is the maximum length of a request id provided by the client.
+const maxIDLen = 64
+
+// Middleware returns a middleware function that tags the request with the
+// request id provided by the client, or a new request id, and logs the
+// completed request.
+func Middleware(logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		path := c.Request.URL.Path
+
+		id := c.Request.Header.Get(Header)
+		if id == "" || len(id) > maxIDLen {
+			id = newID()
+		}
+		entry := logger.WithField(keyID, id)
+		c.Set(keyID, id)
+		c.Set(keyEntry, entry)
+		c.Header(Header, id)
+
+		c.Next()
+
+		entry = entry.WithFields(logrus.Fields{
+			"status":     c.Writer.Status(),
+			"method":     c.Request.Method,
+			"path":       path,
+			"ip":         c.ClientIP(),
+			"latency":    time.Since(start),
+			"user-agent": c.Request.UserAgent(),
+		})
+		switch {
+		case len(c.Errors) != 0:
+			entry.Error(c.Errors.String())
+		case c.Writer.Status() >= 500:
+			entry.Error()
+		default:
+			entry.Info()
+		}
+	}
+}
+
+// FromContext returns the logger of the request, which includes the request
+// id in each entry, or the standard logger if the request is not tagged.
+func FromContext(c *gin.Context) *logrus.Entry {
+	if v, ok := c.Get(keyEntry); ok {
+		if entry, ok := v.(*logrus.Entry); ok {
+			return entry
+		}
+	}
+	return logrus.NewEntry(logrus.StandardLogger())
+}
+
+// helper function returns a random request id.
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,54 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/gin-gonic/gin"
+)
+
+func TestMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.Out = &buf
+	logger.Formatter = &logrus.JSONFormatter{}
+	logger.Level = logrus.InfoLevel
+
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.Use(Middleware(logger))
+	e.GET("/hook", func(c *gin.Context) {
+		FromContext(c).Info("processing hook")
+		c.String(200, "ok")
+	})
+
+	req, _ := http.NewRequest("GET", "/hook", nil)
+	req.Header.Set(Header, "6e2f1a")
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+
+	if got := w.Header().Get(Header); got != "6e2f1a" {
+		t.Errorf("Want request id echoed in response, got %q", got)
+	}
+	dec := json.NewDecoder(&buf)
+	for _, msg := range []string{"processing hook", ""} {
+		entry := map[string]interface{}{}
+		if err := dec.Decode(&entry); err != nil {
+			t.Fatal(err)
+		}
+		if entry["request_id"] != "6e2f1a" || entry["msg"] != msg {
+			t.Errorf("Want entry %q with request id, got %v", msg, entry)
+		}
+	}
+
+	req, _ = http.NewRequest("GET", "/hook", nil)
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	if got := w.Header().Get(Header); len(got) != 32 {
+		t.Errorf("Want generated request id, got %q", got)
+	}
+}
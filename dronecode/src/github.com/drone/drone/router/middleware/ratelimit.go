@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/drone/drone/router/middleware/session"
+	"github.com/drone/drone/shared/metrics"
+
+	"github.com/gin-gonic/gin"
+	"github.com/urfave/cli"
+)
+
+// rateLimitTTL is the duration after which the rate limit of an idle
+// client is removed.
+const rateLimitTTL = 10 * time.Minute
+
+// rateLimitClients is the maximum number of clients of each endpoint with
+// a rate limit. The rate limit of the least recently seen client is removed
+// once the maximum is exceeded.
+const rateLimitClients = 10000
+
+// RateLimit is a middleware function that limits the number of requests
+// per minute of each client to the hook, login, badge and api endpoints.
+// Clients are identified by their user, once the user of the request is
+// verified, or by their ip address for anonymous requests and requests with
+// an invalid token. The middleware must be used after the session user is
+// set. Requests exceeding the limit receive a 429 response.
+func RateLimit(cli *cli.Context) gin.HandlerFunc {
+	limiters := map[string]*limiter{}
+	for _, endpoint := range []string{"hook", "login", "badge", "api"} {
+		if limit := cli.Int("rate-limit-" + endpoint); limit > 0 {
+			limiters[endpoint] = newLimiter(limit, time.Minute)
+		}
+	}
+	return func(c *gin.Context) {
+		endpoint := rateLimitEndpoint(c.Request.URL.Path)
+		l, ok := limiters[endpoint]
+		if !ok {
+			return
+		}
+		if wait, ok := l.Allow(rateLimitKey(c), time.Now()); !ok {
+			metrics.RateLimited.WithLabelValues(endpoint).Inc()
+			c.Header("Retry-After", strconv.Itoa(int(wait/time.Second)+1))
+			c.String(429, "Rate limit exceeded")
+			c.Abort()
+		}
+	}
+}
+
+// helper function returns the rate limited endpoint of the request path,
+// or an empty string if the request path is not rate limited.
+func rateLimitEndpoint(path string) string {
+	switch {
+	case path == "/hook" || strings.HasPrefix(path, "/hook/") || path == "/api/hook":
+		return "hook"
+	case path == "/login" || path == "/authorize":
+		return "login"
+	case strings.HasPrefix(path, "/api/badges/"):
+		return "badge"
+	case strings.HasPrefix(path, "/api/"):
+		return "api"
+	default:
+		return ""
+	}
+}
+
+// helper function returns the verified user of the request, or the client
+// ip address for anonymous requests. The token of the request is not used,
+// since a client could send a new token with each request.
+func rateLimitKey(c *gin.Context) string {
+	if user := session.User(c); user != nil {
+		return "user:" + strconv.FormatInt(user.ID, 10)
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// limiter is a token bucket rate limiter for each client, allowing a
+// number of requests per interval.
+type limiter struct {
+	limit    float64
+	interval time.Duration
+
+	sync.Mutex
+	buckets map[string]*bucket
+	cleaned time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func newLimiter(limit int, interval time.Duration) *limiter {
+	return &limiter{
+		limit:    float64(limit),
+		interval: interval,
+		buckets:  map[string]*bucket{},
+	}
+}
+
+// Allow returns true if the client is allowed to make a request at the
+// time. Otherwise it returns the duration until the next request is
+// allowed.
+func (l *limiter) Allow(key string, now time.Time) (time.Duration, bool) {
+	l.Lock()
+	defer l.Unlock()
+
+	if now.Sub(l.cleaned) > rateLimitTTL {
+		l.cleaned = now
+		for k, b := range l.buckets {
+			if now.Sub(b.updated) > rateLimitTTL {
+				delete(l.buckets, k)
+			}
+		}
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= rateLimitClients {
+			l.evict()
+		}
+		b = &bucket{tokens: l.limit, updated: now}
+		l.buckets[key] = b
+	}
+	rate := l.limit / float64(l.interval)
+	b.tokens += float64(now.Sub(b.updated)) * rate
+	if b.tokens > l.limit {
+		b.tokens = l.limit
+	}
+	b.updated = now
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / rate), false
+	}
+	b.tokens--
+	return 0, true
+}
+
+// evict removes the bucket of the least recently seen client.
+func (l *limiter) evict() {
+	var oldest string
+	for k, b := range l.buckets {
+		if oldest == "" || b.updated.Before(l.buckets[oldest].updated) {
+			oldest = k
+		}
+	}
+	delete(l.buckets, oldest)
+}
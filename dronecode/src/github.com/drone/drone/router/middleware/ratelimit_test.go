@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/drone/drone/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestLimiter(t *testing.T) {
+	l := newLimiter(2, time.Minute)
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if _, ok := l.Allow("octocat", now); !ok {
+			t.Errorf("Want request %d allowed", i+1)
+		}
+	}
+	wait, ok := l.Allow("octocat", now)
+	if ok {
+		t.Errorf("Want request exceeding the limit denied")
+	}
+	if got, want := wait, 30*time.Second; got != want {
+		t.Errorf("Want wait %s, got %s", want, got)
+	}
+	if _, ok := l.Allow("spaceghost", now); !ok {
+		t.Errorf("Want request of other client allowed")
+	}
+	if _, ok := l.Allow("octocat", now.Add(wait)); !ok {
+		t.Errorf("Want request allowed after waiting")
+	}
+}
+
+func TestRateLimitEndpoint(t *testing.T) {
+	for path, want := range map[string]string{
+		"/hook":                             "hook",
+		"/api/hook":                         "hook",
+		"/hook/checks":                      "hook",
+		"/authorize":                        "login",
+		"/api/badges/octocat/hello/cc.xml":  "badge",
+		"/api/repos/octocat/hello/builds/1": "api",
+		"/static/app.js":                    "",
+	} {
+		if got := rateLimitEndpoint(path); got != want {
+			t.Errorf("Want path %s limited as %q, got %q", path, want, got)
+		}
+	}
+}
+
+func TestLimiterClients(t *testing.T) {
+	l := newLimiter(1, time.Minute)
+	now := time.Now()
+	for i := 0; i <= rateLimitClients; i++ {
+		l.Allow(strconv.Itoa(i), now.Add(time.Duration(i)))
+	}
+	if got := len(l.buckets); got != rateLimitClients {
+		t.Errorf("Want %d clients with a rate limit, got %d", rateLimitClients, got)
+	}
+	if _, ok := l.buckets["0"]; ok {
+		t.Errorf("Want the least recently seen client removed")
+	}
+}
+
+func TestRateLimitKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.GET("/api/user", func(c *gin.Context) {
+		// the session user is set once the token is verified.
+		if id := c.Request.Header.Get("X-Verified-User"); id != "" {
+			uid, _ := strconv.ParseInt(id, 10, 64)
+			c.Set("user", &model.User{ID: uid})
+		}
+		c.String(200, rateLimitKey(c))
+	})
+
+	tests := []struct {
+		header map[string]string
+		query  string
+		key    string
+	}{
+		{key: "ip:192.0.2.1"},
+		{header: map[string]string{"Authorization": "Bearer forged"}, key: "ip:192.0.2.1"},
+		{query: "?access_token=forged", key: "ip:192.0.2.1"},
+		{header: map[string]string{"Authorization": "Bearer valid", "X-Verified-User": "1"}, key: "user:1"},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest("GET", "/api/user"+test.query, nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		for k, v := range test.header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		if got := w.Body.String(); got != test.key {
+			t.Errorf("Want rate limit key %s, got %s", test.key, got)
+		}
+	}
+}
@@ -2,31 +2,40 @@ package middleware
 
 import (
 	"fmt"
+	"io/ioutil"
 
-	"github.com/Sirupsen/logrus"
 	"github.com/drone/drone/remote"
 	"github.com/drone/drone/remote/bitbucket"
 	"github.com/drone/drone/remote/bitbucketserver"
+	"github.com/drone/drone/remote/gitea"
 	"github.com/drone/drone/remote/github"
 	"github.com/drone/drone/remote/gitlab"
 	"github.com/drone/drone/remote/gogs"
+	"github.com/drone/drone/remote/ldap"
 	"github.com/gin-gonic/gin"
 	"github.com/urfave/cli"
 )
 
-// Remote is a middleware function that initializes the Remote and attaches to
-// the context of every http.Request.
-func Remote(c *cli.Context) gin.HandlerFunc {
-	v, err := setupRemote(c)
-	if err != nil {
-		logrus.Fatalln(err)
-	}
+// Remote is a middleware function that attaches the Remote to the
+// context of every http.Request.
+func Remote(v remote.Remote) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		remote.ToContext(c, v)
 	}
 }
 
-// helper function to setup the remote from the CLI arguments.
+// SetupRemote is a helper function to setup the remote from the CLI
+// arguments.
+func SetupRemote(c *cli.Context) (remote.Remote, error) {
+	r, err := setupRemote(c)
+	if err != nil || !c.Bool("ldap") {
+		return r, err
+	}
+	return setupLDAP(c, r)
+}
+
+// helper function to setup the version control system from the CLI
+// arguments.
 func setupRemote(c *cli.Context) (remote.Remote, error) {
 	switch {
 	case c.Bool("github"):
@@ -39,11 +48,33 @@ func setupRemote(c *cli.Context) (remote.Remote, error) {
 		return setupStash(c)
 	case c.Bool("gogs"):
 		return setupGogs(c)
+	case c.Bool("gitea"):
+		return setupGitea(c)
 	default:
 		return nil, fmt.Errorf("version control system not configured")
 	}
 }
 
+// helper function to setup LDAP authentication from the CLI arguments. The
+// login credentials are passed to the login form of gogs after they are
+// validated. Other remotes do not have form login, and the remote account
+// is linked with a personal access token submitted in the login form.
+func setupLDAP(c *cli.Context, r remote.Remote) (remote.Remote, error) {
+	return ldap.New(ldap.Opts{
+		URL:          c.String("ldap-server"),
+		StartTLS:     c.Bool("ldap-starttls"),
+		SkipVerify:   c.Bool("ldap-skip-verify"),
+		BindDN:       c.String("ldap-bind-dn"),
+		BindPassword: c.String("ldap-bind-password"),
+		BaseDN:       c.String("ldap-base-dn"),
+		UserFilter:   c.String("ldap-user-filter"),
+		EmailAttr:    c.String("ldap-email-attr"),
+		GroupAttr:    c.String("ldap-group-attr"),
+		AdminGroup:   c.String("ldap-admin-group"),
+		LinkToken:    !c.Bool("gogs"),
+	}, r)
+}
+
 // helper function to setup the Bitbucket remote from the CLI arguments.
 func setupBitbucket(c *cli.Context) (remote.Remote, error) {
 	return bitbucket.New(
@@ -63,6 +94,19 @@ func setupGogs(c *cli.Context) (remote.Remote, error) {
 	})
 }
 
+// helper function to setup the Gitea remote from the CLI arguments.
+func setupGitea(c *cli.Context) (remote.Remote, error) {
+	return gitea.New(gitea.Opts{
+		URL:         c.String("gitea-server"),
+		Client:      c.String("gitea-client"),
+		Secret:      c.String("gitea-secret"),
+		Username:    c.String("gitea-git-username"),
+		Password:    c.String("gitea-git-password"),
+		PrivateMode: c.Bool("gitea-private-mode"),
+		SkipVerify:  c.Bool("gitea-skip-verify"),
+	})
+}
+
 // helper function to setup the Stash remote from the CLI arguments.
 func setupStash(c *cli.Context) (remote.Remote, error) {
 	return bitbucketserver.New(bitbucketserver.Opts{
@@ -91,6 +135,14 @@ func setupGitlab(c *cli.Context) (remote.Remote, error) {
 
 // helper function to setup the GitHub remote from the CLI arguments.
 func setupGithub(c *cli.Context) (remote.Remote, error) {
+	var key []byte
+	if path := c.String("github-app-private-key"); path != "" {
+		var err error
+		key, err = ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return github.New(github.Opts{
 		URL:         c.String("github-server"),
 		Context:     c.String("github-context"),
@@ -102,5 +154,8 @@ func setupGithub(c *cli.Context) (remote.Remote, error) {
 		PrivateMode: c.Bool("github-private-mode"),
 		SkipVerify:  c.Bool("github-skip-verify"),
 		MergeRef:    c.BoolT("github-merge-ref"),
+		AppID:       c.Int64("github-app-id"),
+		AppKey:      key,
+		AppSecret:   c.String("github-app-secret"),
 	})
 }
@@ -107,15 +107,24 @@ func SetPerm() gin.HandlerFunc {
 			perm.Push = true
 			perm.Admin = true
 
+		// machine users do not have a remote account, and their
+		// permissions are stored in the database.
+		case user.Machine:
+			p, err := store.FromContext(c).PermFind(user, repo)
+			if err == nil {
+				perm = p.Perm()
+			}
+			if repo.IsPrivate == false {
+				perm.Pull = true
+			}
+
 		// otherwise if the user is authenticated we should
 		// check the remote system to get the users permissiosn.
 		default:
 			var err error
 			perm, err = cache.GetPerms(c, user, repo.Owner, repo.Name)
 			if err != nil {
-				perm.Pull = false
-				perm.Push = false
-				perm.Admin = false
+				perm = &model.Perm{}
 
 				// debug
 				log.Errorf("Error fetching permission for %s %s",
@@ -126,6 +135,14 @@ func SetPerm() gin.HandlerFunc {
 			if err != nil && repo.IsPrivate == false {
 				perm.Pull = true
 			}
+			// organization admins are granted admin access to all
+			// repositories of the organization.
+			if !perm.Admin && repo.Owner != user.Login {
+				team, err := cache.GetTeamPerms(c, user, repo.Owner)
+				if err == nil && team.Admin {
+					perm = &model.Perm{Pull: true, Push: true, Admin: true}
+				}
+			}
 		}
 
 		// all build logs are visible in public mode
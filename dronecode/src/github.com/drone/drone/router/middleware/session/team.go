@@ -39,9 +39,7 @@ func TeamPerm(c *gin.Context) *model.Perm {
 				log.Errorf("Error fetching team permission for %s %s",
 					user.Login, team)
 
-				perm.Admin = false
-				perm.Pull  = false
-				perm.Push  = false
+				perm = &model.Perm{}
 			}
 	}
 
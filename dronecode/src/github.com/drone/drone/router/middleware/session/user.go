@@ -1,12 +1,18 @@
 package session
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/drone/drone/model"
+	"github.com/drone/drone/remote"
 	"github.com/drone/drone/shared/token"
 	"github.com/drone/drone/store"
 
+	log "github.com/Sirupsen/logrus"
 	"github.com/gin-gonic/gin"
 )
 
@@ -34,20 +40,69 @@ func Token(c *gin.Context) *token.Token {
 	return u
 }
 
+// AccessToken returns the personal access token used to authenticate the
+// request, or nil if the request is not authenticated with an access token.
+func AccessToken(c *gin.Context) *model.AccessToken {
+	v, ok := c.Get("access_token")
+	if !ok {
+		return nil
+	}
+	t, ok := v.(*model.AccessToken)
+	if !ok {
+		return nil
+	}
+	return t
+}
+
+var errTokenExpired = errors.New("token is expired")
+
 func SetUser() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var user *model.User
+		var (
+			user   *model.User
+			access *model.AccessToken
+		)
 
 		t, err := token.ParseRequest(c.Request, func(t *token.Token) (string, error) {
 			var err error
+			if t.Kind == token.AccessToken {
+				id, _ := strconv.ParseInt(t.Text, 10, 64)
+				access, err = store.FromContext(c).AccessTokenFind(id)
+				if err != nil {
+					return "", err
+				}
+				if access.Expired(time.Now().Unix()) {
+					return "", errTokenExpired
+				}
+				user, err = store.GetUser(c, access.UserID)
+				return access.Hash, err
+			}
 			user, err = store.GetUserLogin(c, t.Text)
 			return user.Hash, err
 		})
 		if err == nil {
 			confv := c.MustGet("config")
 			if conf, ok := confv.(*model.Settings); ok {
+				user.GroupAdmin = checkGroupAdmin(c, user)
 				user.Admin = conf.IsAdmin(user)
 			}
+
+			// access tokens and legacy user tokens are limited to the
+			// granted scopes, and grant system administrator privileges
+			// with the admin scope only.
+			if scoped := scopedToken(t, access); scoped != nil {
+				if !scoped.HasScope(model.ScopeAdmin) {
+					user.Admin = false
+				}
+				if !scoped.HasScope(requiredScope(c.Request)) {
+					c.String(403, "Insufficient token scope")
+					c.Abort()
+					return
+				}
+			}
+			if t.Kind == token.AccessToken {
+				c.Set("access_token", access)
+			}
 			c.Set("user", user)
 
 			// if this is a session token (ie not the API token)
@@ -69,6 +124,61 @@ func SetUser() gin.HandlerFunc {
 	}
 }
 
+// checkGroupAdmin returns true if the user is still a member of the
+// administrator group granted at login. The membership is verified with
+// the remote if supported, and revoked if it cannot be verified.
+func checkGroupAdmin(c *gin.Context, user *model.User) bool {
+	if !user.GroupAdmin {
+		return false
+	}
+	grouper, ok := remote.FromContext(c).(remote.Grouper)
+	if !ok {
+		return true
+	}
+	admin, err := grouper.GroupAdmin(user)
+	if err != nil {
+		log.Warnf("Cannot verify the administrator group of %s. %s", user.Login, err)
+		return false
+	}
+	return admin
+}
+
+// scopedToken returns the token that limits the scopes of the request, or
+// nil if the request is not limited to scopes, such as web sessions. The
+// legacy user tokens are deprecated, and are limited to the legacy token
+// scopes.
+func scopedToken(t *token.Token, access *model.AccessToken) *model.AccessToken {
+	switch t.Kind {
+	case token.AccessToken:
+		return access
+	case token.UserToken:
+		return &model.AccessToken{Scopes: model.LegacyTokenScopes}
+	}
+	return nil
+}
+
+// requiredScope returns the access token scope required by the request.
+// Read requests require the read scope, changes to secrets require the
+// secrets:write scope and changes to builds, such as restarting, approving
+// or killing a build, require the build:restart scope. Any other change
+// requires the admin scope.
+func requiredScope(r *http.Request) string {
+	switch r.Method {
+	case "GET", "HEAD", "OPTIONS":
+		return model.ScopeRead
+	}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	for _, part := range parts {
+		if part == "secrets" {
+			return model.ScopeSecretsWrite
+		}
+	}
+	if len(parts) > 4 && parts[0] == "api" && parts[1] == "repos" && parts[4] == "builds" {
+		return model.ScopeBuildRestart
+	}
+	return model.ScopeAdmin
+}
+
 func MustAdmin() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		user := User(c)
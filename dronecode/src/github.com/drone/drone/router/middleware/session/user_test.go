@@ -0,0 +1,56 @@
+package session
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/shared/token"
+	"github.com/franela/goblin"
+)
+
+func TestRequiredScope(t *testing.T) {
+	g := goblin.Goblin(t)
+	g.Describe("requiredScope", func() {
+		tests := []struct {
+			method string
+			path   string
+			scope  string
+		}{
+			{"GET", "/api/repos/octocat/hello-world/secrets", model.ScopeRead},
+			{"POST", "/api/repos/octocat/hello-world/secrets", model.ScopeSecretsWrite},
+			{"DELETE", "/api/orgs/octocat/secrets/password", model.ScopeSecretsWrite},
+			{"POST", "/api/repos/octocat/hello-world/builds/1", model.ScopeBuildRestart},
+			{"DELETE", "/api/repos/octocat/hello-world/builds/1/2", model.ScopeBuildRestart},
+			{"PATCH", "/api/repos/octocat/hello-world", model.ScopeAdmin},
+			{"POST", "/api/user/tokens", model.ScopeAdmin},
+		}
+		for _, test := range tests {
+			test := test
+			g.It("Should require "+test.scope+" to "+test.method+" "+test.path, func() {
+				r, _ := http.NewRequest(test.method, test.path, nil)
+				g.Assert(requiredScope(r)).Equal(test.scope)
+			})
+		}
+	})
+}
+
+func TestScopedToken(t *testing.T) {
+	g := goblin.Goblin(t)
+	g.Describe("scopedToken", func() {
+		g.It("Should limit access tokens to the granted scopes", func() {
+			access := &model.AccessToken{Scopes: []string{model.ScopeAdmin}}
+			g.Assert(scopedToken(&token.Token{Kind: token.AccessToken}, access) == access).IsTrue()
+		})
+		g.It("Should limit legacy user tokens to the legacy scopes", func() {
+			scoped := scopedToken(&token.Token{Kind: token.UserToken}, nil)
+			g.Assert(scoped.HasScope(model.ScopeRead)).IsTrue()
+			g.Assert(scoped.HasScope(model.ScopeBuildRestart)).IsTrue()
+			g.Assert(scoped.HasScope(model.ScopeSecretsWrite)).IsTrue()
+			g.Assert(scoped.HasScope(model.ScopeAdmin)).IsFalse()
+		})
+		g.It("Should not limit web sessions", func() {
+			g.Assert(scopedToken(&token.Token{Kind: token.SessToken}, nil) == nil).IsTrue()
+		})
+	})
+}
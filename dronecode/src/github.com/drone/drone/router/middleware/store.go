@@ -15,3 +15,24 @@ func Store(cli *cli.Context, v store.Store) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// Replica is a middleware function that attaches the read replica Datastore
+// to the context of every http.Request, if a read replica is configured.
+func Replica(v store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if v != nil {
+			store.ReplicaToContext(c, v)
+		}
+		c.Next()
+	}
+}
+
+// UseReplica is a middleware function that replaces the Datastore of the
+// request with the read replica, if configured. It is used by read only
+// endpoints that tolerate replication lag.
+func UseReplica(c *gin.Context) {
+	if v := store.ReplicaFromContext(c); v != nil {
+		store.ToContext(c, v)
+	}
+	c.Next()
+}
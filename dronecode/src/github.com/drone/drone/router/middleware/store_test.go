@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/drone/drone/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// namedStore is a Store identified by name.
+type namedStore struct {
+	store.Store
+
+	name string
+}
+
+func TestUseReplica(t *testing.T) {
+	primary := &namedStore{name: "primary"}
+	replica := &namedStore{name: "replica"}
+
+	tests := []struct {
+		replica store.Store
+		use     bool
+		want    string
+	}{
+		{replica: replica, use: true, want: "replica"},
+		{replica: replica, use: false, want: "primary"},
+		{replica: nil, use: true, want: "primary"},
+	}
+	for _, test := range tests {
+		gin.SetMode(gin.TestMode)
+		e := gin.New()
+		e.Use(func(c *gin.Context) {
+			store.ToContext(c, primary)
+		})
+		e.Use(Replica(test.replica))
+		if test.use {
+			e.Use(UseReplica)
+		}
+		e.GET("/", func(c *gin.Context) {
+			c.String(200, store.FromContext(c).(*namedStore).name)
+		})
+
+		req, _ := http.NewRequest("GET", "/", nil)
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		if got := w.Body.String(); got != test.want {
+			t.Errorf("Want %s store, got %s", test.want, got)
+		}
+	}
+}
@@ -13,7 +13,7 @@ import (
 
 func Refresh(c *gin.Context) {
 	user := session.User(c)
-	if user == nil {
+	if user == nil || user.Machine {
 		c.Next()
 		return
 	}
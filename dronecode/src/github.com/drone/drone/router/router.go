@@ -6,6 +6,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/drone/drone/router/middleware"
 	"github.com/drone/drone/router/middleware/header"
 	"github.com/drone/drone/router/middleware/session"
 	"github.com/drone/drone/router/middleware/token"
@@ -17,8 +18,9 @@ import (
 	"github.com/drone/drone-ui/dist"
 )
 
-// Load loads the router
-func Load(middleware ...gin.HandlerFunc) http.Handler {
+// Load loads the router. The limit middleware is used once the session
+// user is set, so that requests are rate limited by the verified user.
+func Load(limit gin.HandlerFunc, mw ...gin.HandlerFunc) http.Handler {
 
 	e := gin.New()
 	e.Use(gin.Recovery())
@@ -36,24 +38,37 @@ func Load(middleware ...gin.HandlerFunc) http.Handler {
 	e.Use(header.NoCache)
 	e.Use(header.Options)
 	e.Use(header.Secure)
-	e.Use(middleware...)
+	e.Use(mw...)
 	e.Use(session.SetUser())
+	e.Use(limit)
 	e.Use(token.Refresh)
 
 	e.GET("/login", server.ShowLogin)
 	e.GET("/login/form", server.ShowLoginForm)
+	e.GET("/login/oidc", server.GetLoginOIDC)
 	e.GET("/logout", server.GetLogout)
+	e.GET("/healthz", server.GetHealthReady)
+	e.GET("/healthz/live", server.GetHealthLive)
+	e.GET("/healthz/ready", server.GetHealthReady)
 	e.NoRoute(server.ShowIndex)
 
 	user := e.Group("/api/user")
 	{
 		user.Use(session.MustUser())
 		user.GET("", server.GetSelf)
+		user.PATCH("", server.PatchSelf)
 		user.GET("/feed", server.GetFeed)
+		user.GET("/cc.xml", server.GetUserCC)
 		user.GET("/repos", server.GetRepos)
 		user.GET("/repos/remote", server.GetRemoteRepos)
+		user.GET("/repos/search", server.GetRepoSearch)
+		user.GET("/orgs", server.GetOrgs)
 		user.POST("/token", server.PostToken)
 		user.DELETE("/token", server.DeleteToken)
+		user.GET("/tokens", server.GetAccessTokens)
+		user.POST("/tokens", server.PostAccessToken)
+		user.DELETE("/tokens/:token", server.DeleteAccessToken)
+		user.POST("/sessions/revoke", server.PostSessionRevoke)
 	}
 
 	users := e.Group("/api/users")
@@ -64,6 +79,13 @@ func Load(middleware ...gin.HandlerFunc) http.Handler {
 		users.GET("/:login", server.GetUser)
 		users.PATCH("/:login", server.PatchUser)
 		users.DELETE("/:login", server.DeleteUser)
+		users.GET("/:login/tokens", server.GetUserTokens)
+		users.POST("/:login/tokens", server.PostUserToken)
+		users.DELETE("/:login/tokens/:token", server.DeleteUserToken)
+		users.POST("/:login/sessions/revoke", server.PostUserSessionRevoke)
+		users.GET("/:login/perms", server.GetUserPerms)
+		users.POST("/:login/perms/:owner/:name", server.PostUserPerm)
+		users.DELETE("/:login/perms/:owner/:name", server.DeleteUserPerm)
 	}
 
 	repos := e.Group("/api/repos/:owner/:name")
@@ -77,9 +99,14 @@ func Load(middleware ...gin.HandlerFunc) http.Handler {
 			repo.Use(session.MustPull)
 
 			repo.GET("", server.GetRepo)
-			repo.GET("/builds", server.GetBuilds)
+			repo.GET("/builds", middleware.UseReplica, server.GetBuilds)
 			repo.GET("/builds/:number", server.GetBuild)
-			repo.GET("/logs/:number/:ppid/:proc", server.GetBuildLogs)
+			repo.GET("/deployments", server.GetDeployments)
+			repo.GET("/environments", server.GetEnvironments)
+			repo.GET("/stats", server.GetRepoStats)
+			repo.GET("/logs/:number/:ppid/:proc", middleware.UseReplica, server.GetBuildLogs)
+			repo.GET("/files/:number", server.FileList)
+			repo.GET("/files/:number/:proc/*file", server.FileGet)
 			repo.POST("/sign", session.MustPush, server.Sign)
 
 			// requires push permissions
@@ -96,6 +123,16 @@ func Load(middleware ...gin.HandlerFunc) http.Handler {
 			repo.PATCH("/registry/:registry", session.MustPush, server.PatchRegistry)
 			repo.DELETE("/registry/:registry", session.MustPush, server.DeleteRegistry)
 
+			// requires push permissions
+			repo.GET("/export", session.MustPush, server.GetRepoExport)
+			repo.GET("/badge", session.MustPush, server.GetBadgeToken)
+			repo.POST("/import", session.MustRepoAdmin(), server.PostRepoImport)
+
+			repo.GET("/cron", session.MustPush, server.GetCronList)
+			repo.POST("/cron", session.MustPush, server.PostCron)
+			repo.GET("/cron/:cron", session.MustPush, server.GetCron)
+			repo.DELETE("/cron/:cron", session.MustPush, server.DeleteCron)
+
 			// requires push permissions
 			repo.PATCH("", session.MustPush, server.PatchRepo)
 			repo.DELETE("", session.MustRepoAdmin(), server.DeleteRepo)
@@ -105,18 +142,60 @@ func Load(middleware ...gin.HandlerFunc) http.Handler {
 			repo.POST("/builds/:number", session.MustPush, server.PostBuild)
 			repo.POST("/builds/:number/approve", session.MustPush, server.PostApproval)
 			repo.POST("/builds/:number/decline", session.MustPush, server.PostDecline)
+			repo.POST("/builds/:number/promote", session.MustPush, server.PostPromote)
+			repo.POST("/builds/:number/rollback", session.MustPush, server.PostRollback)
 			repo.DELETE("/builds/:number/:job", session.MustPush, server.DeleteBuild)
 		}
 	}
 
+	orgs := e.Group("/api/orgs/:team")
+	{
+		orgs.Use(session.MustTeamAdmin())
+		orgs.GET("/secrets", server.GetOrgSecretList)
+		orgs.POST("/secrets", server.PostOrgSecret)
+		orgs.GET("/secrets/:secret", server.GetOrgSecret)
+		orgs.PATCH("/secrets/:secret", server.PatchOrgSecret)
+		orgs.DELETE("/secrets/:secret", server.DeleteOrgSecret)
+		orgs.GET("/templates", server.GetTemplateList)
+		orgs.POST("/templates", server.PostTemplate)
+		orgs.GET("/templates/:template", server.GetTemplate)
+		orgs.PATCH("/templates/:template", server.PatchTemplate)
+		orgs.DELETE("/templates/:template", server.DeleteTemplate)
+	}
+
+	secrets := e.Group("/api/secrets")
+	{
+		secrets.Use(session.MustAdmin())
+		secrets.GET("", server.GetOrgSecretList)
+		secrets.POST("", server.PostOrgSecret)
+		secrets.GET("/:secret", server.GetOrgSecret)
+		secrets.PATCH("/:secret", server.PatchOrgSecret)
+		secrets.DELETE("/:secret", server.DeleteOrgSecret)
+	}
+
 	badges := e.Group("/api/badges/:owner/:name")
 	{
-		badges.GET("/status.svg", server.GetBadge)
-		badges.GET("/cc.xml", server.GetCC)
+		badges.GET("/status.svg", middleware.UseReplica, server.GetBadge)
+		badges.GET("/status.json", middleware.UseReplica, server.GetBadgeJSON)
+		badges.GET("/cc.xml", middleware.UseReplica, server.GetCC)
+	}
+
+	cache := e.Group("/api/cache/:owner/:name")
+	{
+		cache.GET("", server.GetCache)
+		cache.POST("", server.PostCache)
 	}
 
 	e.POST("/hook", server.PostHook)
 	e.POST("/api/hook", server.PostHook)
+	e.POST("/hook/checks", server.PostCheckHook)
+
+	hooks := e.Group("/api/hooks")
+	{
+		hooks.Use(session.MustAdmin())
+		hooks.GET("", server.GetHooks)
+		hooks.POST("/:hook/replay", server.PostHookReplay)
+	}
 
 	ws := e.Group("/ws")
 	{
@@ -131,6 +210,17 @@ func Load(middleware ...gin.HandlerFunc) http.Handler {
 		)
 	}
 
+	sse := e.Group("/stream")
+	{
+		sse.GET("/events", server.EventStreamSSE)
+		sse.GET("/logs/:owner/:name/:build/:number",
+			session.SetRepo(),
+			session.SetPerm(),
+			session.MustPull,
+			server.LogStreamSSE,
+		)
+	}
+
 	info := e.Group("/api/info")
 	{
 		info.GET("/queue",
@@ -139,6 +229,30 @@ func Load(middleware ...gin.HandlerFunc) http.Handler {
 		)
 	}
 
+	queue := e.Group("/api/queue")
+	{
+		queue.Use(session.MustAdmin())
+		queue.GET("", server.GetQueue)
+		queue.POST("/pause", server.PostQueuePause)
+		queue.POST("/resume", server.PostQueueResume)
+		queue.DELETE("/:id", server.DeleteQueueItem)
+	}
+
+	admin := e.Group("/api/admin")
+	{
+		admin.Use(session.MustAdmin())
+		admin.GET("/retention", server.GetRetention)
+		admin.POST("/gc", server.PostGC)
+		admin.GET("/webhooks", server.GetDeliveries)
+		admin.POST("/webhooks/:delivery", server.PostRedelivery)
+	}
+
+	audit := e.Group("/api/audit")
+	{
+		audit.Use(session.MustAdmin())
+		audit.GET("", server.GetAudit)
+	}
+
 	auth := e.Group("/authorize")
 	{
 		auth.GET("", server.GetLogin)
@@ -148,7 +262,7 @@ func Load(middleware ...gin.HandlerFunc) http.Handler {
 
 	builds := e.Group("/api/builds")
 	{
-		builds.Use(session.MustAdmin())
+		builds.Use(session.MustUser())
 		builds.GET("", server.GetBuildQueue)
 	}
 
@@ -165,12 +279,14 @@ func Load(middleware ...gin.HandlerFunc) http.Handler {
 		debugger.GET("/pprof/symbol", debug.SymbolHandler())
 		debugger.POST("/pprof/symbol", debug.SymbolHandler())
 		debugger.GET("/pprof/trace", debug.TraceHandler())
+		debugger.GET("/log/level", server.GetLogLevel)
+		debugger.POST("/log/level", server.PostLogLevel)
 	}
 
 	monitor := e.Group("/metrics")
 	{
 		monitor.GET("",
-			session.MustAdmin(),
+			metrics.MustAuth(),
 			metrics.PromHandler(),
 		)
 	}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/router/middleware/session"
+	"github.com/drone/drone/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAudit returns the audit log, filtered by the user, repo, after and
+// before query parameters. Times are unix timestamps.
+func GetAudit(c *gin.Context) {
+	filter := &model.AuditFilter{
+		User:  c.Query("user"),
+		Repo:  c.Query("repo"),
+		Limit: 100,
+	}
+	var err error
+	if v := c.Query("after"); v != "" {
+		if filter.After, err = strconv.ParseInt(v, 10, 64); err != nil {
+			c.String(400, "Error parsing after parameter. %s", err)
+			return
+		}
+	}
+	if v := c.Query("before"); v != "" {
+		if filter.Before, err = strconv.ParseInt(v, 10, 64); err != nil {
+			c.String(400, "Error parsing before parameter. %s", err)
+			return
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			c.String(400, "Error parsing limit parameter. %s", err)
+			return
+		}
+	}
+	list, err := store.FromContext(c).AuditList(filter)
+	if err != nil {
+		c.String(500, "Error getting audit log. %s", err)
+		return
+	}
+	c.JSON(200, list)
+}
+
+// recordAudit appends the action of the authenticated user to the audit
+// log. Failures are logged and do not fail the request.
+func recordAudit(c *gin.Context, action, repo, target string) {
+	var login string
+	if user := session.User(c); user != nil {
+		login = user.Login
+	}
+	recordAuditUser(c, login, action, repo, target)
+}
+
+// recordAuditUser records the action of the user login in the audit log,
+// for actions that are not requested by the authenticated user, such as
+// actions requested by a hook.
+func recordAuditUser(c *gin.Context, login, action, repo, target string) {
+	audit := &model.Audit{
+		Action:  action,
+		User:    login,
+		Repo:    repo,
+		Target:  target,
+		Created: time.Now().Unix(),
+	}
+	if err := store.FromContext(c).AuditCreate(audit); err != nil {
+		logrus.Errorf("Error recording audit action %s by %s. %s", action, audit.User, err)
+	}
+}
@@ -2,73 +2,166 @@ package server
 
 import (
 	"fmt"
+	"strings"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/gin-gonic/gin"
 
+	"github.com/drone/drone/cache"
 	"github.com/drone/drone/model"
+	"github.com/drone/drone/router/middleware/session"
 	"github.com/drone/drone/shared/httputil"
+	"github.com/drone/drone/shared/token"
 	"github.com/drone/drone/store"
 )
 
+// badge defines the message, color and message width of a badge.
+type badge struct {
+	message string
+	color   string
+	shields string
+	width   int
+}
+
 var (
-	badgeSuccess = `<svg xmlns="http://www.w3.org/2000/svg" width="91" height="20"><linearGradient id="a" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient><rect rx="3" width="91" height="20" fill="#555"/><rect rx="3" x="37" width="54" height="20" fill="#4c1"/><path fill="#4c1" d="M37 0h4v20h-4z"/><rect rx="3" width="91" height="20" fill="url(#a)"/><g fill="#fff" text-anchor="middle" font-family="DejaVu Sans,Verdana,Geneva,sans-serif" font-size="11"><text x="19.5" y="15" fill="#010101" fill-opacity=".3">build</text><text x="19.5" y="14">build</text><text x="63" y="15" fill="#010101" fill-opacity=".3">success</text><text x="63" y="14">success</text></g></svg>`
-	badgeFailure = `<svg xmlns="http://www.w3.org/2000/svg" width="83" height="20"><linearGradient id="a" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient><rect rx="3" width="83" height="20" fill="#555"/><rect rx="3" x="37" width="46" height="20" fill="#e05d44"/><path fill="#e05d44" d="M37 0h4v20h-4z"/><rect rx="3" width="83" height="20" fill="url(#a)"/><g fill="#fff" text-anchor="middle" font-family="DejaVu Sans,Verdana,Geneva,sans-serif" font-size="11"><text x="19.5" y="15" fill="#010101" fill-opacity=".3">build</text><text x="19.5" y="14">build</text><text x="59" y="15" fill="#010101" fill-opacity=".3">failure</text><text x="59" y="14">failure</text></g></svg>`
-	badgeStarted = `<svg xmlns="http://www.w3.org/2000/svg" width="87" height="20"><linearGradient id="a" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient><rect rx="3" width="87" height="20" fill="#555"/><rect rx="3" x="37" width="50" height="20" fill="#dfb317"/><path fill="#dfb317" d="M37 0h4v20h-4z"/><rect rx="3" width="87" height="20" fill="url(#a)"/><g fill="#fff" text-anchor="middle" font-family="DejaVu Sans,Verdana,Geneva,sans-serif" font-size="11"><text x="19.5" y="15" fill="#010101" fill-opacity=".3">build</text><text x="19.5" y="14">build</text><text x="61" y="15" fill="#010101" fill-opacity=".3">started</text><text x="61" y="14">started</text></g></svg>`
-	badgeError   = `<svg xmlns="http://www.w3.org/2000/svg" width="76" height="20"><linearGradient id="a" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient><rect rx="3" width="76" height="20" fill="#555"/><rect rx="3" x="37" width="39" height="20" fill="#9f9f9f"/><path fill="#9f9f9f" d="M37 0h4v20h-4z"/><rect rx="3" width="76" height="20" fill="url(#a)"/><g fill="#fff" text-anchor="middle" font-family="DejaVu Sans,Verdana,Geneva,sans-serif" font-size="11"><text x="19.5" y="15" fill="#010101" fill-opacity=".3">build</text><text x="19.5" y="14">build</text><text x="55.5" y="15" fill="#010101" fill-opacity=".3">error</text><text x="55.5" y="14">error</text></g></svg>`
-	badgeNone    = `<svg xmlns="http://www.w3.org/2000/svg" width="75" height="20"><linearGradient id="a" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient><rect rx="3" width="75" height="20" fill="#555"/><rect rx="3" x="37" width="38" height="20" fill="#9f9f9f"/><path fill="#9f9f9f" d="M37 0h4v20h-4z"/><rect rx="3" width="75" height="20" fill="url(#a)"/><g fill="#fff" text-anchor="middle" font-family="DejaVu Sans,Verdana,Geneva,sans-serif" font-size="11"><text x="19.5" y="15" fill="#010101" fill-opacity=".3">build</text><text x="19.5" y="14">build</text><text x="55" y="15" fill="#010101" fill-opacity=".3">none</text><text x="55" y="14">none</text></g></svg>`
+	badgeSuccess = badge{"success", "#4c1", "brightgreen", 54}
+	badgeFailure = badge{"failure", "#e05d44", "red", 46}
+	badgeStarted = badge{"started", "#dfb317", "yellow", 50}
+	badgeError   = badge{"error", "#9f9f9f", "lightgrey", 39}
+	badgeNone    = badge{"none", "#9f9f9f", "lightgrey", 38}
+)
+
+const (
+	badgeFlat        = `<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20"><linearGradient id="a" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient><rect rx="3" width="%[1]d" height="20" fill="#555"/><rect rx="3" x="37" width="%[2]d" height="20" fill="%[3]s"/><path fill="%[3]s" d="M37 0h4v20h-4z"/><rect rx="3" width="%[1]d" height="20" fill="url(#a)"/><g fill="#fff" text-anchor="middle" font-family="DejaVu Sans,Verdana,Geneva,sans-serif" font-size="11"><text x="19.5" y="15" fill="#010101" fill-opacity=".3">build</text><text x="19.5" y="14">build</text><text x="%[4]g" y="15" fill="#010101" fill-opacity=".3">%[5]s</text><text x="%[4]g" y="14">%[5]s</text></g></svg>`
+	badgeFlatSquare  = `<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20"><rect width="37" height="20" fill="#555"/><rect x="37" width="%[2]d" height="20" fill="%[3]s"/><g fill="#fff" text-anchor="middle" font-family="DejaVu Sans,Verdana,Geneva,sans-serif" font-size="11"><text x="19.5" y="14">build</text><text x="%[4]g" y="14">%[5]s</text></g></svg>`
+	badgeForTheBadge = `<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="28"><rect width="63" height="28" fill="#555"/><rect x="63" width="%[2]d" height="28" fill="%[3]s"/><g fill="#fff" text-anchor="middle" font-family="DejaVu Sans,Verdana,Geneva,sans-serif" font-size="10" font-weight="bold" letter-spacing="1"><text x="31.5" y="18">BUILD</text><text x="%[4]g" y="18">%[5]s</text></g></svg>`
 )
 
+// svg returns the svg image of the badge in the style, which is flat,
+// flat-square or for-the-badge.
+func (b badge) svg(style string) string {
+	switch style {
+	case "flat-square":
+		return fmt.Sprintf(badgeFlatSquare, 37+b.width, b.width, b.color, 37+float64(b.width)/2, b.message)
+	case "for-the-badge":
+		width := 9*len(b.message) + 18
+		return fmt.Sprintf(badgeForTheBadge, 63+width, width, b.color, 63+float64(width)/2, strings.ToUpper(b.message))
+	default:
+		return fmt.Sprintf(badgeFlat, 37+b.width, b.width, b.color, 37+float64(b.width)/2-1, b.message)
+	}
+}
+
+// GetBadge returns the svg status badge of the latest build of the branch.
 func GetBadge(c *gin.Context) {
+	repo, ok := badgeRepo(c)
+	if !ok {
+		return
+	}
+
+	// an SVG response is always served, even when error, so
+	// we can go ahead and set the content type appropriately.
+	c.Writer.Header().Set("Content-Type", "image/svg+xml")
+	c.String(200, badgeStatus(c, repo).svg(c.Query("style")))
+}
+
+// GetBadgeJSON returns the status badge of the latest build of the branch
+// in the shields.io endpoint format.
+func GetBadgeJSON(c *gin.Context) {
+	repo, ok := badgeRepo(c)
+	if !ok {
+		return
+	}
+	b := badgeStatus(c, repo)
+	c.JSON(200, gin.H{
+		"schemaVersion": 1,
+		"label":         "build",
+		"message":       b.message,
+		"color":         b.shields,
+	})
+}
+
+// GetBadgeToken returns the badge token of the repository, which grants
+// access to the badges of a private repository. The badge token is signed
+// with a key of its own, since it is published in the readme.
+func GetBadgeToken(c *gin.Context) {
+	repo := session.Repo(c)
+	t := token.New(token.BadgeToken, repo.FullName)
+	tokenstr, err := t.Sign(token.KindSecret(repo.Hash, token.BadgeToken))
+	if err != nil {
+		c.String(500, "Error creating badge token. %s", err)
+		return
+	}
+	c.String(200, tokenstr)
+}
+
+// helper function returns the repository of the badge. The badges of a
+// private repository require the badge token of the repository.
+func badgeRepo(c *gin.Context) (*model.Repo, bool) {
 	repo, err := store.GetRepoOwnerName(c,
 		c.Param("owner"),
 		c.Param("name"),
 	)
 	if err != nil {
 		c.AbortWithStatus(404)
-		return
+		return nil, false
 	}
+	if !repo.IsPrivate {
+		return repo, true
+	}
+	parsed, err := token.Parse(c.Query("token"), func(t *token.Token) (string, error) {
+		return token.KindSecret(repo.Hash, token.BadgeToken), nil
+	})
+	if err != nil || parsed.Kind != token.BadgeToken || parsed.Text != repo.FullName {
+		c.AbortWithStatus(404)
+		return nil, false
+	}
+	return repo, true
+}
 
-	// an SVG response is always served, even when error, so
-	// we can go ahead and set the content type appropriately.
-	c.Writer.Header().Set("Content-Type", "image/svg+xml")
-
-	// if no commit was found then display
-	// the 'none' badge, instead of throwing
-	// an error response
+// helper function returns the badge of the latest build matching the
+// branch and event query parameters. Push builds of the default branch are
+// matched by default, and builds of other events match all branches unless
+// a branch is provided.
+func badgeStatus(c *gin.Context, repo *model.Repo) badge {
+	event := c.DefaultQuery("event", model.EventPush)
 	branch := c.Query("branch")
-	if len(branch) == 0 {
+	if len(branch) == 0 && event == model.EventPush {
 		branch = repo.Branch
 	}
 
-	build, err := store.GetBuildLast(c, repo, branch)
+	// if no build was found then display
+	// the 'none' badge, instead of throwing
+	// an error response
+	builds, err := store.FromContext(c).GetBuildListFilter(repo, &model.BuildFilter{
+		Branch: branch,
+		Event:  event,
+		Limit:  1,
+	})
 	if err != nil {
 		log.Warning(err)
-		c.String(200, badgeNone)
-		return
+		return badgeNone
+	}
+	if len(builds) == 0 {
+		return badgeNone
 	}
 
-	switch build.Status {
+	switch builds[0].Status {
 	case model.StatusSuccess:
-		c.String(200, badgeSuccess)
+		return badgeSuccess
 	case model.StatusFailure:
-		c.String(200, badgeFailure)
+		return badgeFailure
 	case model.StatusError, model.StatusKilled:
-		c.String(200, badgeError)
+		return badgeError
 	case model.StatusPending, model.StatusRunning:
-		c.String(200, badgeStarted)
+		return badgeStarted
 	default:
-		c.String(200, badgeNone)
+		return badgeNone
 	}
 }
 
 func GetCC(c *gin.Context) {
-	repo, err := store.GetRepoOwnerName(c,
-		c.Param("owner"),
-		c.Param("name"),
-	)
-	if err != nil {
-		c.AbortWithStatus(404)
+	repo, ok := badgeRepo(c)
+	if !ok {
 		return
 	}
 
@@ -82,3 +175,20 @@ func GetCC(c *gin.Context) {
 	cc := model.NewCC(repo, builds[0], url)
 	c.XML(200, cc)
 }
+
+// GetUserCC returns the cctray projects of the latest builds of the
+// repositories of the authenticated user, so that build monitors can show
+// all repositories with a single user token.
+func GetUserCC(c *gin.Context) {
+	repos, err := cache.GetRepos(c, session.User(c))
+	if err != nil {
+		c.String(500, "Error fetching repository list. %s", err)
+		return
+	}
+	feed, err := store.GetUserFeed(c, repos, true)
+	if err != nil {
+		c.String(500, "Error fetching feed. %s", err)
+		return
+	}
+	c.XML(200, model.NewCCFeed(feed, httputil.GetURL(c.Request)))
+}
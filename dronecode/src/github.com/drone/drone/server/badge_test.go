@@ -0,0 +1,109 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/shared/token"
+	"github.com/drone/drone/store"
+	"github.com/drone/drone/store/datastore"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBadgeSVG(t *testing.T) {
+	tests := []struct {
+		style string
+		want  string
+	}{
+		{style: "", want: `<text x="19.5" y="14">build</text>`},
+		{style: "flat-square", want: `<rect width="37" height="20" fill="#555"/>`},
+		{style: "for-the-badge", want: `>SUCCESS</text>`},
+	}
+	for _, test := range tests {
+		if got := badgeSuccess.svg(test.style); !strings.Contains(got, test.want) {
+			t.Errorf("Want %q badge containing %s, got %s", test.style, test.want, got)
+		}
+	}
+}
+
+func TestGetBadgeJSON(t *testing.T) {
+	s := datastore.New("sqlite3", ":memory:")
+	public := &model.Repo{UserID: 1, Owner: "octocat", Name: "hello-world", FullName: "octocat/hello-world", Branch: "master"}
+	private := &model.Repo{UserID: 1, Owner: "octocat", Name: "secret", FullName: "octocat/secret", Branch: "master", IsPrivate: true, Hash: "a1b2c3d4"}
+	for _, repo := range []*model.Repo{public, private} {
+		if err := s.CreateRepo(repo); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, build := range []*model.Build{
+		{RepoID: public.ID, Event: model.EventPush, Branch: "master", Status: model.StatusSuccess},
+		{RepoID: public.ID, Event: model.EventPush, Branch: "develop", Status: model.StatusFailure},
+		{RepoID: public.ID, Event: model.EventTag, Branch: "v1.0", Status: model.StatusRunning},
+		{RepoID: private.ID, Event: model.EventPush, Branch: "master", Status: model.StatusKilled},
+	} {
+		if err := s.CreateBuild(build); err != nil {
+			t.Fatal(err)
+		}
+	}
+	badgeToken, err := token.New(token.BadgeToken, private.FullName).Sign(token.KindSecret(private.Hash, token.BadgeToken))
+	if err != nil {
+		t.Fatal(err)
+	}
+	repoToken, err := token.New(token.BadgeToken, private.FullName).Sign(private.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherToken, err := token.New(token.AccessToken, private.FullName).Sign(private.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.GET("/api/badges/:owner/:name/status.json", func(c *gin.Context) {
+		store.ToContext(c, s)
+		GetBadgeJSON(c)
+	})
+
+	tests := []struct {
+		repo    string
+		query   string
+		status  int
+		message string
+	}{
+		{repo: "octocat/hello-world", status: 200, message: "success"},
+		{repo: "octocat/hello-world", query: "branch=develop", status: 200, message: "failure"},
+		{repo: "octocat/hello-world", query: "event=tag", status: 200, message: "started"},
+		{repo: "octocat/hello-world", query: "event=deployment", status: 200, message: "none"},
+		{repo: "octocat/secret", status: 404},
+		{repo: "octocat/secret", query: "token=" + otherToken, status: 404},
+		{repo: "octocat/secret", query: "token=" + repoToken, status: 404},
+		{repo: "octocat/secret", query: "token=" + badgeToken, status: 200, message: "error"},
+		{repo: "octocat/linguist", status: 404},
+	}
+	for _, test := range tests {
+		url := "/api/badges/" + test.repo + "/status.json?" + test.query
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		if w.Code != test.status {
+			t.Errorf("Want status %d for badge %s, got %d", test.status, url, w.Code)
+			continue
+		}
+		if test.status != 200 {
+			continue
+		}
+		out := map[string]interface{}{}
+		if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+			t.Fatal(err)
+		}
+		if out["message"] != test.message {
+			t.Errorf("Want badge %s message %s, got %v", url, test.message, out["message"])
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/store"
+)
+
+// procUpdates coalesces the proc updates of the Update rpc. It is nil, and
+// updates are written immediately, unless batching is enabled.
+var procUpdates *procBatch
+
+// BatchProcUpdates enables batching of the proc updates of the Update rpc.
+// Updates of the same proc are coalesced, and the pending updates are
+// written to the store in a single batch after the interval, which reduces
+// the database writes of pipelines with many short steps.
+func BatchProcUpdates(s store.Store, interval time.Duration) {
+	procUpdates = &procBatch{
+		store:    s,
+		interval: interval,
+		pending:  map[int64]*model.Proc{},
+	}
+}
+
+// FlushProcUpdates writes the pending proc updates to the store.
+func FlushProcUpdates() {
+	procUpdates.flush()
+}
+
+type procBatch struct {
+	store    store.Store
+	interval time.Duration
+
+	// flushing serializes the flushes, so that an older batch cannot
+	// overwrite the updates of a newer batch.
+	flushing sync.Mutex
+
+	sync.Mutex
+	pending map[int64]*model.Proc
+	timer   *time.Timer
+}
+
+// update queues the proc update, or writes it immediately if batching is
+// disabled.
+func (b *procBatch) update(s store.Store, proc *model.Proc) error {
+	if b == nil {
+		return s.ProcUpdate(proc)
+	}
+	b.Lock()
+	defer b.Unlock()
+	p := *proc
+	b.pending[proc.ID] = &p
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.interval, b.flush)
+	}
+	return nil
+}
+
+// get returns a copy of the pending update of the proc, or the proc if no
+// update is pending.
+func (b *procBatch) get(proc *model.Proc) *model.Proc {
+	if b == nil {
+		return proc
+	}
+	b.Lock()
+	defer b.Unlock()
+	if pending, ok := b.pending[proc.ID]; ok {
+		p := *pending
+		return &p
+	}
+	return proc
+}
+
+// apply replaces the procs of the list with their pending updates.
+func (b *procBatch) apply(procs []*model.Proc) {
+	for i, proc := range procs {
+		procs[i] = b.get(proc)
+	}
+}
+
+// flush writes the pending updates to the store.
+func (b *procBatch) flush() {
+	if b == nil {
+		return
+	}
+	b.flushing.Lock()
+	defer b.flushing.Unlock()
+
+	b.Lock()
+	var procs []*model.Proc
+	for _, proc := range b.pending {
+		procs = append(procs, proc)
+	}
+	b.pending = map[int64]*model.Proc{}
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	b.Unlock()
+
+	if len(procs) == 0 {
+		return
+	}
+	if err := b.store.ProcUpdateBatch(procs); err != nil {
+		logrus.Errorf("cannot update %d procs: %s", len(procs), err)
+	}
+}
@@ -2,17 +2,16 @@ package server
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Sirupsen/logrus"
-	"github.com/cncd/pipeline/pipeline/rpc"
-	"github.com/cncd/pubsub"
 	"github.com/cncd/queue"
+	"github.com/drone/drone/cache"
 	"github.com/drone/drone/remote"
 	"github.com/drone/drone/shared/httputil"
 	"github.com/drone/drone/store"
@@ -22,16 +21,111 @@ import (
 	"github.com/drone/drone/router/middleware/session"
 )
 
+// maxBuildsPerPage is the maximum number of builds per page of the build
+// list.
+const maxBuildsPerPage = 100
+
+// GetBuilds gets a page of builds of the repository, filtered by the
+// branch, event and status query parameters, and writes to the response in
+// json format. The before and after parameters select the builds with a
+// lower or higher build number, for keyset pagination, and the total count
+// of builds matching the filter is written to the X-Total-Count header. The
+// link of the next page is written to the Link header, which uses keyset
+// pagination instead of the slower page parameter.
 func GetBuilds(c *gin.Context) {
 	repo := session.Repo(c)
-	builds, err := store.GetBuildList(c, repo)
+	filter, err := buildFilter(c)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Error parsing parameters. %s", err)
+		return
+	}
+	builds, err := store.FromContext(c).GetBuildListFilter(repo, filter)
+	if err != nil {
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	count, err := store.FromContext(c).GetBuildCount(repo, filter)
 	if err != nil {
 		c.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
+	c.Header("X-Total-Count", strconv.Itoa(count))
+	if len(builds) == filter.Limit {
+		last := int64(builds[len(builds)-1].Number)
+		if filter.Ascending {
+			nextLink(c, "after", last)
+		} else {
+			nextLink(c, "before", last)
+		}
+	}
 	c.JSON(http.StatusOK, builds)
 }
 
+// helper function writes the link of the next page to the Link header,
+// replacing the page parameter of the request with the keyset parameter.
+func nextLink(c *gin.Context, param string, value int64) {
+	q := c.Request.URL.Query()
+	q.Del("page")
+	q.Set(param, strconv.FormatInt(value, 10))
+	u := *c.Request.URL
+	u.RawQuery = q.Encode()
+	c.Header("Link", fmt.Sprintf("<%s>; rel=\"next\"", u.RequestURI()))
+}
+
+// helper function returns the build filter of the query parameters.
+func buildFilter(c *gin.Context) (*model.BuildFilter, error) {
+	filter := &model.BuildFilter{
+		Branch:    c.Query("branch"),
+		Event:     c.Query("event"),
+		Status:    c.Query("status"),
+		Ascending: c.Query("sort") == "asc",
+		Limit:     50,
+	}
+	switch c.Query("sort") {
+	case "", "asc", "desc":
+	default:
+		return nil, fmt.Errorf("invalid sort order %q", c.Query("sort"))
+	}
+	for _, label := range c.Request.URL.Query()["label"] {
+		parts := strings.SplitN(label, ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid label %q, expected key:value", label)
+		}
+		if filter.Labels == nil {
+			filter.Labels = map[string]string{}
+		}
+		filter.Labels[parts[0]] = parts[1]
+	}
+	page := 1
+	for _, param := range []struct {
+		name  string
+		value *int
+	}{
+		{"page", &page},
+		{"per_page", &filter.Limit},
+		{"before", &filter.Before},
+		{"after", &filter.After},
+	} {
+		v := c.Query(param.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid %s parameter %q", param.name, v)
+		}
+		*param.value = n
+	}
+	if page < 1 {
+		page = 1
+	}
+	if filter.Limit < 1 || filter.Limit > maxBuildsPerPage {
+		filter.Limit = maxBuildsPerPage
+	}
+	filter.Offset = (page - 1) * filter.Limit
+	return filter, nil
+}
+
 func GetBuild(c *gin.Context) {
 	if c.Param("number") == "latest" {
 		GetBuildLast(c)
@@ -52,6 +146,9 @@ func GetBuild(c *gin.Context) {
 	}
 	procs, _ := store.FromContext(c).ProcList(build)
 	build.Procs = model.Tree(procs)
+	if build.Status == model.StatusPending {
+		build.Reason = pendingReason(c, build)
+	}
 
 	c.JSON(http.StatusOK, build)
 }
@@ -92,7 +189,9 @@ func GetBuildLogs(c *gin.Context) {
 		return
 	}
 
-	rc, err := store.FromContext(c).LogFind(proc)
+	// the tail parameter limits the logs to the trailing lines.
+	tail, _ := strconv.Atoi(c.Query("tail"))
+	rc, err := store.FromContext(c).LogTail(proc, tail)
 	if err != nil {
 		c.AbortWithError(404, err)
 		return
@@ -139,9 +238,53 @@ func DeleteBuild(c *gin.Context) {
 	store.FromContext(c).ProcUpdate(proc)
 
 	Config.Services.Queue.Error(context.Background(), fmt.Sprint(proc.ID), queue.ErrCancel)
+	recordAudit(c, model.AuditBuildKill, repo.FullName, fmt.Sprintf("%d.%d", build.Number, proc.PID))
 	c.String(204, "")
 }
 
+// cancelBuild cancels the pending and running pipelines of the build and
+// marks the build as killed.
+func cancelBuild(s store.Store, build *model.Build) error {
+	// the pending step updates are written first, so that they do not
+	// overwrite the killed steps.
+	procUpdates.flush()
+	procs, err := s.ProcList(build)
+	if err != nil {
+		return err
+	}
+	dropBuildItems(s, build)
+
+	now := time.Now().Unix()
+	for _, proc := range procs {
+		if !proc.Running() {
+			continue
+		}
+		if proc.PPID == 0 {
+			id := fmt.Sprint(proc.ID)
+			if err := Config.Services.Queue.Evict(context.Background(), id); err != nil {
+				Config.Services.Queue.Error(context.Background(), id, queue.ErrCancel)
+			}
+		}
+		if proc.State == model.StatusPending {
+			proc.State = model.StatusSkipped
+		} else {
+			proc.State = model.StatusKilled
+			proc.ExitCode = 137
+			proc.Stopped = now
+		}
+		if err := s.ProcUpdate(proc); err != nil {
+			return err
+		}
+	}
+
+	build.Status = model.StatusKilled
+	build.Finished = now
+	if build.Started == 0 {
+		build.Started = now
+	}
+	return s.UpdateBuild(build)
+}
+
 func PostApproval(c *gin.Context) {
 	var (
 		remote_ = remote.FromContext(c)
@@ -158,7 +301,7 @@ func PostApproval(c *gin.Context) {
 		return
 	}
 	if build.Status != model.StatusBlocked {
-		c.String(500, "cannot decline a build with status %s", build.Status)
+		c.String(500, "cannot approve a build with status %s", build.Status)
 		return
 	}
 	build.Status = model.StatusPending
@@ -174,7 +317,7 @@ func PostApproval(c *gin.Context) {
 	//
 
 	// fetch the build file from the database
-	conf, err := Config.Storage.Config.ConfigLoad(build.ConfigID)
+	conf, err := loadConfig(remote_, user, repo, build)
 	if err != nil {
 		logrus.Errorf("failure to get build config for %s. %s", repo.FullName, err)
 		c.AbortWithError(404, err)
@@ -191,13 +334,15 @@ func PostApproval(c *gin.Context) {
 		c.String(500, "error updating build. %s", uerr)
 		return
 	}
+	recordAudit(c, model.AuditBuildApprove, repo.FullName, fmt.Sprint(build.Number))
 
 	c.JSON(200, build)
 
 	// get the previous build so that we can send
 	// on status change notifications
 	last, _ := store.GetBuildLastBefore(c, repo, build.Branch, build.ID)
-	secs, err := Config.Services.Secrets.SecretList(repo)
+	changedFiles(remote_, user, repo, build)
+	secs, err := buildSecrets(repo)
 	if err != nil {
 		logrus.Debugf("Error getting secrets for %s#%d. %s", repo.FullName, build.Number, err)
 	}
@@ -234,73 +379,32 @@ func PostApproval(c *gin.Context) {
 		return
 	}
 
-	var pcounter = len(items)
-	for _, item := range items {
-		build.Procs = append(build.Procs, item.Proc)
-		item.Proc.BuildID = build.ID
-
-		for _, stage := range item.Config.Stages {
-			var gid int
-			for _, step := range stage.Steps {
-				pcounter++
-				if gid == 0 {
-					gid = pcounter
-				}
-				proc := &model.Proc{
-					BuildID: build.ID,
-					Name:    step.Alias,
-					PID:     pcounter,
-					PPID:    item.Proc.PID,
-					PGID:    gid,
-					State:   model.StatusPending,
-				}
-				build.Procs = append(build.Procs, proc)
-			}
-		}
-	}
+	createProcs(build, items)
 	store.FromContext(c).ProcCreate(build.Procs)
+	store.UpdateBuild(c, build)
 
-	//
-	// publish topic
-	//
-	buildCopy := *build
-	buildCopy.Procs = model.Tree(buildCopy.Procs)
-	message := pubsub.Message{
-		Labels: map[string]string{
-			"repo":    repo.FullName,
-			"private": strconv.FormatBool(repo.IsPrivate),
-		},
-	}
-	message.Data, _ = json.Marshal(model.Event{
-		Type:  model.Enqueued,
-		Repo:  *repo,
-		Build: buildCopy,
-	})
-	// TODO remove global reference
-	Config.Services.Pubsub.Publish(c, "topic/events", message)
-
-	//
-	// end publish topic
-	//
+	publishBuild(c, repo, build)
 
-	for _, item := range items {
-		task := new(queue.Task)
-		task.ID = fmt.Sprint(item.Proc.ID)
-		task.Labels = map[string]string{}
-		task.Labels["platform"] = item.Platform
-		for k, v := range item.Labels {
-			task.Labels[k] = v
-		}
-
-		task.Data, _ = json.Marshal(rpc.Pipeline{
-			ID:      fmt.Sprint(item.Proc.ID),
-			Config:  item.Config,
-			Timeout: b.Repo.Timeout,
-		})
+	queueBuild(store.FromContext(c), repo, items)
+}
 
-		Config.Services.Logs.Open(context.Background(), task.ID)
-		Config.Services.Queue.Push(context.Background(), task)
+// loadConfig returns the stored pipeline configuration of the build. The
+// configuration of a pull request from a fork that was deferred until the
+// build is approved is fetched, evaluated and stored.
+func loadConfig(r remote.Remote, user *model.User, repo *model.Repo, build *model.Build) (*model.Config, error) {
+	if build.ConfigID != 0 {
+		return Config.Storage.Config.ConfigLoad(build.ConfigID)
 	}
+	data, err := fetchConfig(r, user, repo, build, true)
+	if err != nil {
+		return nil, err
+	}
+	conf, err := persistConfig(repo, data)
+	if err != nil {
+		return nil, err
+	}
+	build.ConfigID = conf.ID
+	return conf, nil
 }
 
 func PostDecline(c *gin.Context) {
@@ -331,6 +435,7 @@ func PostDecline(c *gin.Context) {
 		c.String(500, "error updating build. %s", err)
 		return
 	}
+	recordAudit(c, model.AuditBuildDecline, repo.FullName, fmt.Sprint(build.Number))
 
 	uri := fmt.Sprintf("%s/%s/%d", httputil.GetURL(c.Request), repo.FullName, build.Number)
 	err = remote_.Status(user, repo, build, uri)
@@ -341,12 +446,66 @@ func PostDecline(c *gin.Context) {
 	c.JSON(200, build)
 }
 
+// maxFeedLimit is the maximum number of builds of the build feed.
+const maxFeedLimit = 1000
+
+// GetBuildQueue gets the builds of all repositories visible to the user,
+// filtered by the status, branch and event query parameters, and writes to
+// the response in json format. The status is a comma separated list, and
+// defaults to the pending and running builds. Administrators see the builds
+// of all repositories. The before parameter selects the builds with a lower
+// build id, for keyset pagination.
 func GetBuildQueue(c *gin.Context) {
-	out, err := store.GetBuildQueue(c)
+	user := session.User(c)
+	filter := &model.FeedFilter{
+		Status: []string{model.StatusPending, model.StatusRunning},
+		Branch: c.Query("branch"),
+		Event:  c.Query("event"),
+		Limit:  100,
+	}
+	if v := c.Query("status"); v != "" {
+		filter.Status = strings.Split(v, ",")
+	}
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			c.String(400, "Error parsing limit parameter %q", v)
+			return
+		}
+		filter.Limit = limit
+	}
+	if v := c.Query("before"); v != "" {
+		before, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || before < 1 {
+			c.String(400, "Error parsing before parameter %q", v)
+			return
+		}
+		filter.Before = before
+	}
+	if filter.Limit > maxFeedLimit {
+		filter.Limit = maxFeedLimit
+	}
+
+	var repos []*model.RepoLite
+	if !user.Admin {
+		var err error
+		repos, err = cache.GetRepos(c, user)
+		if err != nil {
+			c.String(500, "Error fetching repository list. %s", err)
+			return
+		}
+		if repos == nil {
+			repos = []*model.RepoLite{}
+		}
+	}
+	out, err := store.FromContext(c).GetBuildFeed(repos, filter)
 	if err != nil {
-		c.String(500, "Error getting build queue. %s", err)
+		c.String(500, "Error getting build feed. %s", err)
 		return
 	}
+	if len(out) == filter.Limit {
+		nextLink(c, "before", out[len(out)-1].ID)
+	}
 	c.JSON(200, out)
 }
 
@@ -357,6 +516,31 @@ func GetBuildQueue(c *gin.Context) {
 //
 //
 
+// buildParams returns the request query parameters as build parameters,
+// excluding the reserved parameters. Build parameters are injected into
+// the pipeline environment, and therefore only string literals are
+// accepted.
+func buildParams(c *gin.Context, reserved ...string) map[string]string {
+	params := map[string]string{}
+	for key, val := range c.Request.URL.Query() {
+		if key == "access_token" || isReserved(reserved, key) {
+			continue
+		}
+		params[key] = val[0]
+	}
+	return params
+}
+
+// helper function returns true if the key is reserved.
+func isReserved(reserved []string, key string) bool {
+	for _, r := range reserved {
+		if r == key {
+			return true
+		}
+	}
+	return false
+}
+
 func PostBuild(c *gin.Context) {
 
 	remote_ := remote.FromContext(c)
@@ -394,7 +578,7 @@ func PostBuild(c *gin.Context) {
 	}
 
 	// fetch the .drone.yml file from the database
-	conf, err := Config.Storage.Config.ConfigLoad(build.ConfigID)
+	conf, err := loadConfig(remote_, user, repo, build)
 	if err != nil {
 		logrus.Errorf("failure to get build config for %s. %s", repo.FullName, err)
 		c.AbortWithError(404, err)
@@ -414,9 +598,41 @@ func PostBuild(c *gin.Context) {
 		return
 	}
 
+	// system administrators are not limited by the build rate limit. The
+	// re-run hook of the remote has no session user.
+	if actor := session.User(c); actor == nil || !actor.Admin {
+		if err := checkRateLimit(store.FromContext(c), user, repo); err != nil {
+			c.String(429, err.Error())
+			return
+		}
+	}
+
+	// restart parameters are injected as environment variables, and
+	// replace the parameters of the previous run.
+	if params := buildParams(c, "fork", "event", "deploy_to"); len(params) != 0 {
+		build.Params = params
+	}
+
+	// forking the build may change the event and the deploy target.
+	forkit, _ := strconv.ParseBool(fork)
+	event, target := build.Event, build.Deploy
+	if forkit {
+		event = c.DefaultQuery("event", build.Event)
+		target = c.DefaultQuery("deploy_to", build.Deploy)
+	}
+	if err := checkForkEvent(build, event); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// deployments must be allowed by the deploy rules of the repository.
+	if (event == model.EventDeploy || event == model.EventRollback) && !allowDeploy(c, repo, build, target) {
+		return
+	}
+
 	// forking the build creates a duplicate of the build
 	// and then executes. This retains prior build history.
-	if forkit, _ := strconv.ParseBool(fork); forkit {
+	if forkit {
 		build.ID = 0
 		build.Number = 0
 		build.Parent = num
@@ -431,14 +647,8 @@ func PostBuild(c *gin.Context) {
 			return
 		}
 
-		event := c.DefaultQuery("event", build.Event)
-		if event == model.EventPush ||
-			event == model.EventPull ||
-			event == model.EventTag ||
-			event == model.EventDeploy {
-			build.Event = event
-		}
-		build.Deploy = c.DefaultQuery("deploy_to", build.Deploy)
+		build.Event = event
+		build.Deploy = target
 	} else {
 		// todo move this to database tier
 		// and wrap inside a transaction
@@ -453,6 +663,7 @@ func PostBuild(c *gin.Context) {
 			c.AbortWithStatus(500)
 			return
 		}
+		dropBuildItems(store.FromContext(c), build)
 
 		err = store.UpdateBuild(c, build)
 		if err != nil {
@@ -461,22 +672,11 @@ func PostBuild(c *gin.Context) {
 		}
 	}
 
-	// Read query string parameters into buildParams, exclude reserved params
-	var buildParams = map[string]string{}
-	for key, val := range c.Request.URL.Query() {
-		switch key {
-		case "fork", "event", "deploy_to":
-		default:
-			// We only accept string literals, because build parameters will be
-			// injected as environment variables
-			buildParams[key] = val[0]
-		}
-	}
-
 	// get the previous build so that we can send
 	// on status change notifications
 	last, _ := store.GetBuildLastBefore(c, repo, build.Branch, build.ID)
-	secs, err := Config.Services.Secrets.SecretList(repo)
+	changedFiles(remote_, user, repo, build)
+	secs, err := buildSecrets(repo)
 	if err != nil {
 		logrus.Debugf("Error getting secrets for %s#%d. %s", repo.FullName, build.Number, err)
 	}
@@ -495,7 +695,6 @@ func PostBuild(c *gin.Context) {
 		Link:  httputil.GetURL(c.Request),
 		Yaml:  conf.Data,
 	}
-	// TODO inject environment varibles !!!!!! buildParams
 	items, err := b.Build()
 	if err != nil {
 		build.Status = model.StatusError
@@ -506,30 +705,7 @@ func PostBuild(c *gin.Context) {
 		return
 	}
 
-	var pcounter = len(items)
-	for _, item := range items {
-		build.Procs = append(build.Procs, item.Proc)
-		item.Proc.BuildID = build.ID
-
-		for _, stage := range item.Config.Stages {
-			var gid int
-			for _, step := range stage.Steps {
-				pcounter++
-				if gid == 0 {
-					gid = pcounter
-				}
-				proc := &model.Proc{
-					BuildID: build.ID,
-					Name:    step.Alias,
-					PID:     pcounter,
-					PPID:    item.Proc.PID,
-					PGID:    gid,
-					State:   model.StatusPending,
-				}
-				build.Procs = append(build.Procs, proc)
-			}
-		}
-	}
+	createProcs(build, items)
 
 	err = store.FromContext(c).ProcCreate(build.Procs)
 	if err != nil {
@@ -541,47 +717,32 @@ func PostBuild(c *gin.Context) {
 		c.JSON(500, build)
 		return
 	}
+	if err := store.UpdateBuild(c, build); err != nil {
+		logrus.Errorf("cannot restart %s#%d: %s", repo.FullName, build.Number, err)
+	}
 
+	recordAudit(c, model.AuditBuildRestart, repo.FullName, fmt.Sprint(build.Number))
 	c.JSON(202, build)
 
-	//
-	// publish topic
-	//
-	buildCopy := *build
-	buildCopy.Procs = model.Tree(buildCopy.Procs)
-	message := pubsub.Message{
-		Labels: map[string]string{
-			"repo":    repo.FullName,
-			"private": strconv.FormatBool(repo.IsPrivate),
-		},
-	}
-	message.Data, _ = json.Marshal(model.Event{
-		Type:  model.Enqueued,
-		Repo:  *repo,
-		Build: buildCopy,
-	})
-	// TODO remove global reference
-	Config.Services.Pubsub.Publish(c, "topic/events", message)
-	//
-	// end publish topic
-	//
-
-	for _, item := range items {
-		task := new(queue.Task)
-		task.ID = fmt.Sprint(item.Proc.ID)
-		task.Labels = map[string]string{}
-		task.Labels["platform"] = item.Platform
-		for k, v := range item.Labels {
-			task.Labels[k] = v
-		}
+	publishBuild(c, repo, build)
 
-		task.Data, _ = json.Marshal(rpc.Pipeline{
-			ID:      fmt.Sprint(item.Proc.ID),
-			Config:  item.Config,
-			Timeout: b.Repo.Timeout,
-		})
+	queueBuild(store.FromContext(c), repo, items)
+}
 
-		Config.Services.Logs.Open(context.Background(), task.ID)
-		Config.Services.Queue.Push(context.Background(), task)
+// checkForkEvent returns an error if the build cannot be forked as a build
+// of the event. Only a successful build can be rolled back.
+func checkForkEvent(build *model.Build, event string) error {
+	if event == build.Event {
+		return nil
+	}
+	switch event {
+	case model.EventPush, model.EventPull, model.EventTag, model.EventDeploy:
+		return nil
+	case model.EventRollback:
+		if build.Status != model.StatusSuccess {
+			return fmt.Errorf("Error rolling back. Build %d was not successful", build.Number)
+		}
+		return nil
 	}
+	return fmt.Errorf("Error forking build. Invalid event %q", event)
 }
@@ -0,0 +1,30 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/drone/drone/model"
+)
+
+func TestCheckForkEvent(t *testing.T) {
+	tests := []struct {
+		status string
+		event  string
+		valid  bool
+	}{
+		{model.StatusFailure, model.EventPush, true},
+		{model.StatusFailure, model.EventPull, true},
+		{model.StatusFailure, model.EventTag, true},
+		{model.StatusFailure, model.EventDeploy, true},
+		{model.StatusSuccess, model.EventRollback, true},
+		{model.StatusFailure, model.EventRollback, false},
+		{model.StatusSuccess, "deploy", false},
+		{model.StatusSuccess, model.EventCron, true}, // the event of the build
+	}
+	for _, test := range tests {
+		build := &model.Build{Number: 1, Status: test.status, Event: model.EventCron}
+		if err := checkForkEvent(build, test.event); (err == nil) != test.valid {
+			t.Errorf("Want fork of %s build as %s valid %v, got %v", test.status, test.event, test.valid, err)
+		}
+	}
+}
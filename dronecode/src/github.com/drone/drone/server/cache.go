@@ -0,0 +1,154 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/cncd/pipeline/pipeline/frontend/yaml/compiler"
+	"github.com/gin-gonic/gin"
+
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/shared/token"
+	"github.com/drone/drone/store"
+)
+
+// cacheKey matches the valid pipeline cache keys, which are used as file
+// names by the cache steps.
+var cacheKey = regexp.MustCompile(`^[A-Za-z0-9._-]{1,250}$`)
+
+// GetCache writes the pipeline cache archive of the cache key to the
+// response, or the most recent archive matching one of the restore keys.
+func GetCache(c *gin.Context) {
+	repo, ok := cacheRepo(c, token.CacheToken, token.CacheRead)
+	if !ok {
+		return
+	}
+	s := store.FromContext(c)
+	cache, err := s.CacheFind(repo, c.Query("key"))
+	if err != nil {
+		for _, prefix := range c.Request.URL.Query()["restore"] {
+			if prefix == "" {
+				continue
+			}
+			if cache, err = s.CacheFindPrefix(repo, prefix); err == nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		c.String(404, "Cache not found")
+		return
+	}
+	rc, err := s.CacheRead(cache)
+	if err != nil {
+		c.String(500, "Error reading cache %q. %s", cache.Key, err)
+		return
+	}
+	defer rc.Close()
+
+	c.Header("Content-Type", "application/x-tar")
+	c.Header("Content-Length", strconv.FormatInt(cache.Size, 10))
+	c.Header("X-Cache-Key", cache.Key)
+	c.Status(200)
+	io.Copy(c.Writer, rc)
+}
+
+// PostCache saves the pipeline cache archive of the cache key. The cache
+// is saved once, and the archive is ignored if the cache key exists.
+func PostCache(c *gin.Context) {
+	repo, ok := cacheRepo(c, token.CacheToken)
+	if !ok {
+		return
+	}
+	key := c.Query("key")
+	if !cacheKey.MatchString(key) {
+		c.String(400, "Invalid cache key %q", key)
+		return
+	}
+	s := store.FromContext(c)
+	if _, err := s.CacheFind(repo, key); err == nil {
+		c.String(204, "")
+		return
+	}
+
+	limit := Config.Pipeline.CacheSize
+	data, err := ioutil.ReadAll(io.LimitReader(c.Request.Body, limit+1))
+	if err != nil {
+		c.String(400, "Error reading cache. %s", err)
+		return
+	}
+	if int64(len(data)) > limit {
+		c.String(http.StatusRequestEntityTooLarge, "Cache exceeds the size limit of %d bytes", limit)
+		return
+	}
+	cache := &model.Cache{
+		RepoID:  repo.ID,
+		Key:     key,
+		Size:    int64(len(data)),
+		Created: time.Now().Unix(),
+	}
+	if err := s.CacheCreate(cache, bytes.NewReader(data)); err != nil {
+		c.String(500, "Error saving cache %q. %s", key, err)
+		return
+	}
+	c.JSON(200, cache)
+}
+
+// helper function returns the repository of the cache, which requires a
+// cache token of the repository of one of the kinds.
+func cacheRepo(c *gin.Context, kinds ...string) (*model.Repo, bool) {
+	repo, err := store.GetRepoOwnerName(c,
+		c.Param("owner"),
+		c.Param("name"),
+	)
+	if err != nil {
+		c.AbortWithStatus(404)
+		return nil, false
+	}
+	parsed, err := token.ParseRequest(c.Request, func(t *token.Token) (string, error) {
+		return token.KindSecret(repo.Hash, t.Kind), nil
+	})
+	if err != nil || parsed.Text != repo.FullName {
+		c.AbortWithStatus(401)
+		return nil, false
+	}
+	for _, kind := range kinds {
+		if parsed.Kind == kind {
+			return repo, true
+		}
+	}
+	c.AbortWithStatus(403)
+	return nil, false
+}
+
+// helper function returns the storage of the pipeline cache of the
+// repository. The cache is uploaded to the server with a cache token of
+// the repository that expires after the build timeout, when the cache is
+// stored on the server. Pull requests, which may run untrusted code, get
+// a read-only token so that they cannot overwrite the cache restored by
+// the builds of the repository.
+func cacheConfig(repo *model.Repo, build *model.Build) compiler.Cache {
+	cache := compiler.Cache{Image: Config.Pipeline.CacheImage}
+	if !Config.Pipeline.CacheServer {
+		return cache
+	}
+	kind := token.CacheToken
+	if build.Event == model.EventPull {
+		kind = token.CacheRead
+	}
+	exp := time.Now().Add(time.Duration(repo.Timeout)*time.Minute + time.Hour).Unix()
+	tokenstr, err := token.New(kind, repo.FullName).SignExpires(token.KindSecret(repo.Hash, kind), exp)
+	if err != nil {
+		logrus.Errorf("cannot create the cache token of %s. %s", repo.FullName, err)
+		return cache
+	}
+	cache.URL = Config.Server.Host + "/api/cache/" + repo.FullName
+	cache.Token = tokenstr
+	return cache
+}
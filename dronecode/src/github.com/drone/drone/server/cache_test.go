@@ -0,0 +1,66 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/shared/token"
+	"github.com/drone/drone/store"
+	"github.com/drone/drone/store/datastore"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCacheTokens(t *testing.T) {
+	defer func(server bool, size int64) {
+		Config.Pipeline.CacheServer = server
+		Config.Pipeline.CacheSize = size
+	}(Config.Pipeline.CacheServer, Config.Pipeline.CacheSize)
+	Config.Pipeline.CacheServer = true
+	Config.Pipeline.CacheSize = 1024
+
+	s := datastore.New("sqlite3", ":memory:")
+	repo := &model.Repo{UserID: 1, FullName: "octocat/hello-world", Owner: "octocat", Name: "hello-world", Hash: "secret"}
+	if err := s.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.Use(func(c *gin.Context) { store.ToContext(c, s) })
+	e.GET("/api/cache/:owner/:name", GetCache)
+	e.POST("/api/cache/:owner/:name", PostCache)
+
+	push := cacheConfig(repo, &model.Build{Event: model.EventPush}).Token
+	pull := cacheConfig(repo, &model.Build{Event: model.EventPull}).Token
+	hook, _ := token.New(token.HookToken, repo.FullName).Sign(repo.Hash)
+	forged, _ := token.New(token.CacheToken, repo.FullName).Sign(repo.Hash)
+
+	tests := []struct {
+		method string
+		key    string
+		token  string
+		status int
+	}{
+		{method: "POST", key: "pull", token: pull, status: 403},
+		{method: "POST", key: "hook", token: hook, status: 401},
+		{method: "POST", key: "forged", token: forged, status: 401},
+		{method: "POST", key: "push", token: push, status: 200},
+		{method: "GET", key: "push", token: pull, status: 200},
+		{method: "GET", key: "push", token: push, status: 200},
+		{method: "GET", key: "push", token: hook, status: 401},
+		{method: "GET", key: "pull", token: pull, status: 404},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest(test.method, "/api/cache/octocat/hello-world?key="+test.key, strings.NewReader("data"))
+		req.Header.Set("Authorization", "Bearer "+test.token)
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		if w.Code != test.status {
+			t.Errorf("Want status %d for %s of cache key %s, got %d", test.status, test.method, test.key, w.Code)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package server
+
+import (
+	"github.com/Sirupsen/logrus"
+	"github.com/drone/drone/model"
+)
+
+// notifyChat posts the result of the build to the chat service, if system
+// chat notifications are enabled.
+func notifyChat(repo *model.Repo, build *model.Build, link string) {
+	if Config.Services.Chat == nil {
+		return
+	}
+	if err := Config.Services.Chat.ChatBuild(repo, build, link); err != nil {
+		logrus.Errorf("Error posting chat notification for %s#%d. %s", repo.FullName, build.Number, err)
+	}
+}
+
+// notifyChatApproval posts an approval request for the blocked build to the
+// chat service, if system chat notifications are enabled.
+func notifyChatApproval(repo *model.Repo, build *model.Build, link string) {
+	if Config.Services.Chat == nil {
+		return
+	}
+	if err := Config.Services.Chat.ChatApproval(repo, build, link); err != nil {
+		logrus.Errorf("Error posting chat approval request for %s#%d. %s", repo.FullName, build.Number, err)
+	}
+}
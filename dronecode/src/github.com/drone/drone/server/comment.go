@@ -0,0 +1,148 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/remote"
+	"github.com/drone/drone/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// comment commands that can be used in a pull request comment.
+const (
+	commandRebuild = "rebuild"
+	commandDeploy  = "deploy"
+)
+
+// commentCommand defines a pull request comment command, such as
+// "/drone rebuild" or "/drone deploy staging".
+type commentCommand struct {
+	Name   string
+	Target string
+}
+
+// parseCommand returns the first command in the comment body, or nil if
+// the comment does not contain a command.
+func parseCommand(body string) *commentCommand {
+	for _, line := range strings.Split(body, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "/drone" {
+			continue
+		}
+		switch {
+		case fields[1] == commandRebuild:
+			return &commentCommand{Name: commandRebuild}
+		case fields[1] == commandDeploy && len(fields) > 2:
+			return &commentCommand{Name: commandDeploy, Target: fields[2]}
+		}
+	}
+	return nil
+}
+
+// postComment restarts or promotes the latest build of the pull request
+// with the comment command. The comment author must have push access to the
+// repository, and comment commands must be enabled for the repository.
+func postComment(c *gin.Context, hook *model.Hook, comment *model.Comment) {
+	cmd := parseCommand(comment.Body)
+	if cmd == nil {
+		c.Writer.WriteHeader(204)
+		return
+	}
+
+	repo, err := store.GetRepoOwnerName(c, comment.Repo.Owner, comment.Repo.Name)
+	if err != nil {
+		logrus.Errorf("failure to find repo %s from comment. %s", comment.Repo.FullName, err)
+		c.AbortWithError(404, err)
+		return
+	}
+	hook.Repo = repo.FullName
+
+	if !verifyHook(c, hook, repo) {
+		return
+	}
+	if !repo.AllowComments {
+		logrus.Infof("ignoring comment. repo %s is disabled for comment commands.", repo.FullName)
+		c.Writer.WriteHeader(204)
+		return
+	}
+
+	user, err := store.GetUser(c, repo.UserID)
+	if err != nil {
+		logrus.Errorf("failure to find repo owner %s. %s", repo.FullName, err)
+		c.AbortWithError(500, err)
+		return
+	}
+	r := remote.FromContext(c)
+	commenter, ok := r.(remote.Commenter)
+	if !ok {
+		logrus.Infof("ignoring comment. remote does not support comment commands.")
+		c.Writer.WriteHeader(204)
+		return
+	}
+	if refresher, ok := r.(remote.Refresher); ok {
+		ok, _ := refresher.Refresh(user)
+		if ok {
+			store.UpdateUser(c, user)
+		}
+	}
+
+	perm, err := commenter.Collaborator(user, repo, comment.Author)
+	if err != nil {
+		logrus.Errorf("failure to get permissions of %s for %s. %s", comment.Author, repo.FullName, err)
+		c.AbortWithError(500, err)
+		return
+	}
+	if !perm.Push {
+		logrus.Infof("ignoring comment. %s does not have push access to %s.", comment.Author, repo.FullName)
+		c.String(403, "Insufficient privileges")
+		return
+	}
+
+	parent, err := pullRequestBuild(c, repo, comment.Number)
+	if err != nil {
+		logrus.Errorf("failure to find build for %s pull request %d. %s", repo.FullName, comment.Number, err)
+		c.AbortWithError(404, err)
+		return
+	}
+
+	var (
+		build  *model.Build
+		action = model.AuditBuildRestart
+		target = fmt.Sprint(parent.Number)
+	)
+	switch cmd.Name {
+	case commandRebuild:
+		build = copyBuild(parent, model.EventPull, "", comment.Author)
+	case commandDeploy:
+		if err := model.CheckDeploy(repo.DeployRules, cmd.Target, comment.Author, parent.Branch); err != nil {
+			logrus.Infof("ignoring comment. %s", err)
+			recordAuditUser(c, comment.Author, model.AuditDeployDenied, repo.FullName, fmt.Sprintf("%d:%s", parent.Number, cmd.Target))
+			c.String(403, err.Error())
+			return
+		}
+		build = copyBuild(parent, model.EventDeploy, cmd.Target, comment.Author)
+		action = model.AuditBuildPromote
+		target = fmt.Sprintf("%d:%s", parent.Number, cmd.Target)
+	}
+
+	if err := startBuild(store.FromContext(c), r, user, repo, build); err != nil {
+		logrus.Errorf("failure to start build for %s pull request %d. %s", repo.FullName, comment.Number, err)
+		c.AbortWithError(500, err)
+		return
+	}
+	recordAuditUser(c, comment.Author, action, repo.FullName, target)
+	c.JSON(200, build)
+}
+
+// helper function returns the latest build of the pull request.
+func pullRequestBuild(c *gin.Context, repo *model.Repo, number int) (*model.Build, error) {
+	build, err := store.FromContext(c).GetBuildRef(repo, fmt.Sprintf("refs/pull/%d/head", number))
+	if err != nil {
+		build, err = store.FromContext(c).GetBuildRef(repo, fmt.Sprintf("refs/pull/%d/merge", number))
+	}
+	return build, err
+}
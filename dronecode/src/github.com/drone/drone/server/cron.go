@@ -0,0 +1,259 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/remote"
+	"github.com/drone/drone/router/middleware/session"
+	"github.com/drone/drone/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCron gets the named cron job from the database and writes
+// to the response in json format.
+func GetCron(c *gin.Context) {
+	var (
+		repo = session.Repo(c)
+		name = c.Param("cron")
+	)
+	cron, err := store.FromContext(c).CronFind(repo, name)
+	if err != nil {
+		c.String(404, "Error getting cron %q. %s", name, err)
+		return
+	}
+	c.JSON(200, cron)
+}
+
+// PostCron persists the cron job to the database.
+func PostCron(c *gin.Context) {
+	repo := session.Repo(c)
+
+	if _, ok := remote.FromContext(c).(remote.Brancher); !ok {
+		c.String(400, "Error inserting cron. Cron jobs are not supported by the remote")
+		return
+	}
+
+	in := new(model.Cron)
+	if err := c.Bind(in); err != nil {
+		c.String(http.StatusBadRequest, "Error parsing request. %s", err)
+		return
+	}
+	cron := &model.Cron{
+		RepoID:   repo.ID,
+		Name:     in.Name,
+		Schedule: in.Schedule,
+		Branch:   in.Branch,
+		Created:  time.Now().Unix(),
+	}
+	if err := cron.Validate(); err != nil {
+		c.String(400, "Error inserting cron. %s", err)
+		return
+	}
+	if err := cron.SetNext(time.Now()); err != nil {
+		c.String(400, "Error inserting cron. %s", err)
+		return
+	}
+	if err := store.FromContext(c).CronCreate(cron); err != nil {
+		c.String(500, "Error inserting cron %q. %s", in.Name, err)
+		return
+	}
+	c.JSON(200, cron)
+}
+
+// GetCronList gets the cron job list from the database and writes
+// to the response in json format.
+func GetCronList(c *gin.Context) {
+	repo := session.Repo(c)
+	list, err := store.FromContext(c).CronList(repo)
+	if err != nil {
+		c.String(500, "Error getting cron list. %s", err)
+		return
+	}
+	c.JSON(200, list)
+}
+
+// DeleteCron deletes the named cron job from the database.
+func DeleteCron(c *gin.Context) {
+	var (
+		repo = session.Repo(c)
+		name = c.Param("cron")
+	)
+	cron, err := store.FromContext(c).CronFind(repo, name)
+	if err != nil {
+		c.String(404, "Error getting cron %q. %s", name, err)
+		return
+	}
+	if err := store.FromContext(c).CronDelete(cron); err != nil {
+		c.String(500, "Error deleting cron %q. %s", name, err)
+		return
+	}
+	c.String(204, "")
+}
+
+// CronScheduler periodically starts builds for the cron jobs that are
+// due, until the context is cancelled.
+func CronScheduler(ctx context.Context, s store.Store, r remote.Remote, interval time.Duration) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		now := time.Now()
+		crons, err := s.CronListDue(now.Unix())
+		if err != nil {
+			logrus.Errorf("cron: cannot list scheduled builds. %s", err)
+			continue
+		}
+		for _, cron := range crons {
+			cron.Prev = cron.Next
+			if err := cron.SetNext(now); err != nil {
+				// the cron job is stored without a next execution
+				// time, so that it is skipped until it is updated.
+				logrus.Errorf("cron: invalid schedule %q. %s", cron.Schedule, err)
+				s.CronUpdate(cron)
+				continue
+			}
+			if err := s.CronUpdate(cron); err != nil {
+				logrus.Errorf("cron: cannot update cron %q. %s", cron.Name, err)
+				continue
+			}
+			if err := runCron(s, r, cron); err != nil {
+				logrus.Errorf("cron: cannot start build for cron %q. %s", cron.Name, err)
+			}
+		}
+	}
+}
+
+// runCron starts a build for the cron job, at the head commit of the
+// cron branch.
+func runCron(s store.Store, r remote.Remote, cron *model.Cron) error {
+	repo, err := s.GetRepo(cron.RepoID)
+	if err != nil {
+		return err
+	}
+	user, err := s.GetUser(repo.UserID)
+	if err != nil {
+		return err
+	}
+
+	// if the remote has a refresh token, the current access token
+	// may be stale. Therefore, we should refresh prior to dispatching
+	// the build.
+	if refresher, ok := r.(remote.Refresher); ok {
+		ok, _ := refresher.Refresh(user)
+		if ok {
+			s.UpdateUser(user)
+		}
+	}
+
+	brancher, ok := r.(remote.Brancher)
+	if !ok {
+		return remote.ErrBranchHeadNotSupported
+	}
+	branch := cron.Branch
+	if branch == "" {
+		branch = repo.Branch
+	}
+	sha, err := brancher.BranchHead(user, repo, branch)
+	if err != nil {
+		return err
+	}
+
+	build := &model.Build{
+		RepoID:    repo.ID,
+		Event:     model.EventCron,
+		Commit:    sha,
+		Branch:    branch,
+		Ref:       "refs/heads/" + branch,
+		Link:      repo.Link,
+		Message:   fmt.Sprintf("scheduled build %s", cron.Name),
+		Timestamp: time.Now().Unix(),
+		Sender:    cron.Name,
+		Author:    user.Login,
+		Avatar:    user.Avatar,
+		Email:     user.Email,
+		Status:    model.StatusPending,
+	}
+	return startBuild(s, r, user, repo, build)
+}
+
+// startBuild creates the build, using the yaml configuration of the build
+// commit, and queues the build pipelines.
+func startBuild(s store.Store, r remote.Remote, user *model.User, repo *model.Repo, build *model.Build) error {
+	confb, err := fetchConfig(r, user, repo, build, true)
+	if err != nil {
+		return err
+	}
+	conf, err := persistConfig(repo, confb)
+	if err != nil {
+		return err
+	}
+	build.ConfigID = conf.ID
+	build.Signed, build.Verified = verifyConfig(r, user, repo, build)
+
+	netrc, err := r.Netrc(user, repo)
+	if err != nil {
+		return err
+	}
+
+	secs, err := buildSecrets(repo)
+	if err != nil {
+		logrus.Debugf("Error getting secrets for %s#%d. %s", repo.FullName, build.Number, err)
+	}
+	regs, err := Config.Services.Registries.RegistryList(repo)
+	if err != nil {
+		logrus.Debugf("Error getting registry credentials for %s#%d. %s", repo.FullName, build.Number, err)
+	}
+
+	if err := s.CreateBuild(build); err != nil {
+		return err
+	}
+
+	last, _ := s.GetBuildLastBefore(repo, build.Branch, build.ID)
+
+	defer func() {
+		uri := fmt.Sprintf("%s/%s/%d", Config.Server.Host, repo.FullName, build.Number)
+		if err := r.Status(user, repo, build, uri); err != nil {
+			logrus.Errorf("error setting commit status for %s/%d", repo.FullName, build.Number)
+		}
+	}()
+
+	b := builder{
+		Repo:  repo,
+		Curr:  build,
+		Last:  last,
+		Netrc: netrc,
+		Secs:  secs,
+		Regs:  regs,
+		Link:  Config.Server.Host,
+		Yaml:  conf.Data,
+	}
+	items, err := b.Build()
+	if err != nil {
+		build.Status = model.StatusError
+		build.Started = time.Now().Unix()
+		build.Finished = build.Started
+		build.Error = err.Error()
+		return s.UpdateBuild(build)
+	}
+
+	createProcs(build, items)
+	if err := s.ProcCreate(build.Procs); err != nil {
+		logrus.Errorf("error persisting procs %s/%d: %s", repo.FullName, build.Number, err)
+	}
+	if err := s.UpdateBuild(build); err != nil {
+		logrus.Errorf("error persisting build labels %s/%d: %s", repo.FullName, build.Number, err)
+	}
+
+	publishBuild(context.Background(), repo, build)
+	queueBuild(s, repo, items)
+	return nil
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/remote"
+	"github.com/drone/drone/router/middleware/session"
+	"github.com/drone/drone/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PostPromote creates a deployment of the build to the target
+// environment. The query parameters other than the target are recorded as
+// build parameters.
+func PostPromote(c *gin.Context) {
+	deployBuild(c, model.EventDeploy)
+}
+
+// PostRollback re-runs the deployment pipelines of an earlier successful
+// build against the target environment, which defaults to the environment
+// the build was deployed to.
+func PostRollback(c *gin.Context) {
+	deployBuild(c, model.EventRollback)
+}
+
+// helper function creates a deployment or rollback build of the build
+// number to the target environment.
+func deployBuild(c *gin.Context, event string) {
+	var (
+		repo   = session.Repo(c)
+		sender = session.User(c)
+		target = c.Query("target")
+	)
+	num, err := strconv.Atoi(c.Param("number"))
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	parent, err := store.GetBuildNumber(c, repo, num)
+	if err != nil {
+		c.String(404, "Error getting build %d. %s", num, err)
+		return
+	}
+	if event == model.EventRollback {
+		if parent.Status != model.StatusSuccess {
+			c.String(http.StatusBadRequest, "Error rolling back. Build %d was not successful", num)
+			return
+		}
+		if target == "" {
+			target = parent.Deploy
+		}
+	}
+	if target == "" {
+		c.String(http.StatusBadRequest, "Error deploying build. Please specify the target environment")
+		return
+	}
+	if !allowDeploy(c, repo, parent, target) {
+		return
+	}
+	user, err := store.GetUser(c, repo.UserID)
+	if err != nil {
+		logrus.Errorf("failure to find repo owner %s. %s", repo.FullName, err)
+		c.AbortWithError(500, err)
+		return
+	}
+
+	r := remote.FromContext(c)
+	if refresher, ok := r.(remote.Refresher); ok {
+		ok, _ := refresher.Refresh(user)
+		if ok {
+			store.UpdateUser(c, user)
+		}
+	}
+
+	build := copyBuild(parent, event, target, sender.Login)
+	build.Params = buildParams(c, "target")
+	if err := startBuild(store.FromContext(c), r, user, repo, build); err != nil {
+		c.String(500, "Error deploying build %d. %s", num, err)
+		return
+	}
+	action := model.AuditBuildPromote
+	if event == model.EventRollback {
+		action = model.AuditBuildRollback
+	}
+	recordAudit(c, action, repo.FullName, fmt.Sprintf("%d:%s", num, target))
+	c.JSON(200, build)
+}
+
+// copyBuild returns a pending build of the same commit as the parent build,
+// with the event and target environment. The build uses the configuration
+// of the parent build, and inherits its signature verification.
+func copyBuild(parent *model.Build, event, target, sender string) *model.Build {
+	return &model.Build{
+		RepoID:    parent.RepoID,
+		Parent:    parent.Number,
+		Event:     event,
+		Deploy:    target,
+		Commit:    parent.Commit,
+		Branch:    parent.Branch,
+		Ref:       parent.Ref,
+		Refspec:   parent.Refspec,
+		Remote:    parent.Remote,
+		Title:     parent.Title,
+		Message:   parent.Message,
+		Link:      parent.Link,
+		Timestamp: time.Now().Unix(),
+		Sender:    sender,
+		Author:    parent.Author,
+		Avatar:    parent.Avatar,
+		Email:     parent.Email,
+		Signed:    parent.Signed,
+		Verified:  parent.Verified,
+		Status:    model.StatusPending,
+	}
+}
+
+// allowDeploy returns true if the deploy rules of the repository allow the
+// user to deploy the build to the target environment. Otherwise the denied
+// deployment is recorded in the audit log and a 403 is written to the
+// response. Requests without a session user, such as the re-run hook of the
+// remote, are only allowed by rules that do not restrict the users.
+func allowDeploy(c *gin.Context, repo *model.Repo, build *model.Build, target string) bool {
+	var login string
+	if user := session.User(c); user != nil {
+		login = user.Login
+	}
+	err := model.CheckDeploy(repo.DeployRules, target, login, build.Branch)
+	if err == nil {
+		return true
+	}
+	recordAudit(c, model.AuditDeployDenied, repo.FullName, fmt.Sprintf("%d:%s", build.Number, target))
+	c.String(403, err.Error())
+	return false
+}
+
+// GetDeployments gets the deployments and rollbacks of the repository,
+// optionally filtered by the target environment, and writes to the
+// response in json format.
+func GetDeployments(c *gin.Context) {
+	repo := session.Repo(c)
+	builds, err := store.FromContext(c).GetDeployList(repo, c.Query("target"))
+	if err != nil {
+		c.String(500, "Error getting deployments. %s", err)
+		return
+	}
+	c.JSON(200, builds)
+}
+
+// GetEnvironments gets the latest deployment or rollback of each
+// environment of the repository and writes to the response in json format.
+func GetEnvironments(c *gin.Context) {
+	repo := session.Repo(c)
+	builds, err := store.FromContext(c).GetDeployLatest(repo)
+	if err != nil {
+		c.String(500, "Error getting environments. %s", err)
+		return
+	}
+	c.JSON(200, builds)
+}
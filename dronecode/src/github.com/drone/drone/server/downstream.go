@@ -0,0 +1,218 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/remote"
+	"github.com/drone/drone/store"
+)
+
+// maxDownstreamDepth limits the number of repositories in a chain of
+// downstream builds.
+const maxDownstreamDepth = 10
+
+// downstream defines a repository that is built when a build of the
+// upstream repository succeeds, in owner/name or owner/name@branch format.
+type downstream struct {
+	Owner  string
+	Name   string
+	Branch string
+}
+
+// parseDownstream parses the downstream repository.
+func parseDownstream(s string) (*downstream, error) {
+	d := new(downstream)
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, "@"); i != -1 {
+		s, d.Branch = s[:i], s[i+1:]
+	}
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("Invalid downstream repository %q", s)
+	}
+	d.Owner, d.Name = parts[0], parts[1]
+	return d, nil
+}
+
+// FullName returns the full name of the downstream repository.
+func (d *downstream) FullName() string {
+	return d.Owner + "/" + d.Name
+}
+
+// validateDownstream returns an error if a downstream repository is invalid,
+// or if the downstream repositories of the repository settings would
+// trigger a build of the repository itself.
+func validateDownstream(s store.Store, repo *model.Repo, list []string) error {
+	visited := map[string]bool{}
+	var visit func(name string, list []string) error
+	visit = func(name string, list []string) error {
+		for _, item := range list {
+			d, err := parseDownstream(item)
+			if err != nil {
+				return err
+			}
+			if d.FullName() == repo.FullName {
+				return fmt.Errorf("Downstream repository %s of %s creates a cycle", repo.FullName, name)
+			}
+			if visited[d.FullName()] {
+				continue
+			}
+			visited[d.FullName()] = true
+			next, err := s.GetRepoName(d.FullName())
+			if err != nil {
+				continue
+			}
+			if err := visit(next.FullName, next.Downstream); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return visit(repo.FullName, list)
+}
+
+// downstreamList returns the downstream repositories of the repository
+// settings and of the yaml configuration of the build.
+func downstreamList(repo *model.Repo, build *model.Build) []string {
+	list := append([]string{}, repo.Downstream...)
+	conf, err := Config.Storage.Config.ConfigLoad(build.ConfigID)
+	if err != nil {
+		return list
+	}
+	for _, doc := range splitDocuments(conf.Data) {
+		header, err := parseHeader(doc)
+		if err == nil {
+			list = append(list, header.Downstream...)
+		}
+	}
+	return list
+}
+
+// triggerDownstream starts a build of each downstream repository of the
+// successful build, at the head commit of the downstream branch. The
+// upstream repository and build are added to the downstream build
+// environment. Repositories that already built in the chain of upstream
+// builds are skipped, so that a cycle does not trigger builds forever.
+func triggerDownstream(s store.Store, r remote.Remote, repo *model.Repo, build *model.Build) {
+	list := downstreamList(repo, build)
+	if len(list) == 0 {
+		return
+	}
+
+	var chain []string
+	if build.Upstream != "" {
+		chain = strings.Split(build.Upstream, ",")
+	}
+	chain = append(chain, repo.FullName)
+	if len(chain) >= maxDownstreamDepth {
+		logrus.Errorf("downstream: chain of %s#%d exceeds %d repositories", repo.FullName, build.Number, maxDownstreamDepth)
+		return
+	}
+
+	user, err := s.GetUser(repo.UserID)
+	if err != nil {
+		logrus.Errorf("downstream: cannot get the owner of %s. %s", repo.FullName, err)
+		return
+	}
+
+	triggered := map[string]bool{}
+	for _, item := range list {
+		d, err := parseDownstream(item)
+		if err != nil {
+			logrus.Errorf("downstream: %s", err)
+			continue
+		}
+		if triggered[d.FullName()] {
+			continue
+		}
+		triggered[d.FullName()] = true
+		if inChain(chain, d.FullName()) {
+			logrus.Errorf("downstream: skipping %s, which is upstream of %s#%d", d.FullName(), repo.FullName, build.Number)
+			continue
+		}
+		if err := runDownstream(s, r, user, repo, build, chain, d); err != nil {
+			logrus.Errorf("downstream: cannot start build of %s for %s#%d. %s", d.FullName(), repo.FullName, build.Number, err)
+		}
+	}
+}
+
+// runDownstream starts a build of the downstream repository. The owner of
+// the upstream repository must have push access to the downstream
+// repository.
+func runDownstream(s store.Store, r remote.Remote, upstreamUser *model.User, upstream *model.Repo, upstreamBuild *model.Build, chain []string, d *downstream) error {
+	repo, err := s.GetRepoName(d.FullName())
+	if err != nil {
+		return fmt.Errorf("repository is not active")
+	}
+	perm, err := r.Perm(upstreamUser, d.Owner, d.Name)
+	if err != nil {
+		return err
+	}
+	if !perm.Push {
+		return fmt.Errorf("%s does not have push access", upstreamUser.Login)
+	}
+
+	user, err := s.GetUser(repo.UserID)
+	if err != nil {
+		return err
+	}
+	if refresher, ok := r.(remote.Refresher); ok {
+		ok, _ := refresher.Refresh(user)
+		if ok {
+			s.UpdateUser(user)
+		}
+	}
+
+	brancher, ok := r.(remote.Brancher)
+	if !ok {
+		return remote.ErrBranchHeadNotSupported
+	}
+	branch := d.Branch
+	if branch == "" {
+		branch = repo.Branch
+	}
+	sha, err := brancher.BranchHead(user, repo, branch)
+	if err != nil {
+		return err
+	}
+
+	build := &model.Build{
+		RepoID:    repo.ID,
+		Event:     model.EventPush,
+		Commit:    sha,
+		Branch:    branch,
+		Ref:       "refs/heads/" + branch,
+		Link:      repo.Link,
+		Message:   fmt.Sprintf("triggered by %s#%d", upstream.FullName, upstreamBuild.Number),
+		Timestamp: time.Now().Unix(),
+		Sender:    upstreamBuild.Sender,
+		Author:    user.Login,
+		Avatar:    user.Avatar,
+		Email:     user.Email,
+		Status:    model.StatusPending,
+		Upstream:  strings.Join(chain, ","),
+	}
+	build.Params = map[string]string{
+		"DRONE_UPSTREAM_REPO":   upstream.FullName,
+		"DRONE_UPSTREAM_BUILD":  strconv.Itoa(upstreamBuild.Number),
+		"DRONE_UPSTREAM_COMMIT": upstreamBuild.Commit,
+		"DRONE_UPSTREAM_BRANCH": upstreamBuild.Branch,
+		"DRONE_UPSTREAM_CHAIN":  build.Upstream,
+	}
+	return startBuild(s, r, user, repo, build)
+}
+
+// helper function returns true if the repository is in the chain.
+func inChain(chain []string, name string) bool {
+	for _, item := range chain {
+		if item == name {
+			return true
+		}
+	}
+	return false
+}
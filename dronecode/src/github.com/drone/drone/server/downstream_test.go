@@ -0,0 +1,131 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/remote"
+	"github.com/drone/drone/store/datastore"
+)
+
+// permRemote records the repositories of the permission checks, and denies
+// push access, so that downstream builds are not started.
+type permRemote struct {
+	remote.Remote
+
+	checked []string
+}
+
+func (r *permRemote) Perm(u *model.User, owner, name string) (*model.Perm, error) {
+	r.checked = append(r.checked, owner+"/"+name)
+	return &model.Perm{Pull: true}, nil
+}
+
+func TestParseDownstream(t *testing.T) {
+	tests := []struct {
+		in   string
+		want downstream
+		err  bool
+	}{
+		{in: "octocat/hello-world", want: downstream{Owner: "octocat", Name: "hello-world"}},
+		{in: " octocat/hello-world@develop ", want: downstream{Owner: "octocat", Name: "hello-world", Branch: "develop"}},
+		{in: "octocat", err: true},
+		{in: "octocat/", err: true},
+		{in: "octocat/hello-world/docs", err: true},
+	}
+	for _, test := range tests {
+		got, err := parseDownstream(test.in)
+		if test.err {
+			if err == nil {
+				t.Errorf("Want error parsing downstream %q", test.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Want downstream %q parsed, got %s", test.in, err)
+			continue
+		}
+		if *got != test.want {
+			t.Errorf("Want downstream %+v, got %+v", test.want, *got)
+		}
+	}
+}
+
+func TestValidateDownstream(t *testing.T) {
+	s := datastore.New("sqlite3", ":memory:")
+	for _, repo := range []*model.Repo{
+		{UserID: 1, Owner: "octocat", Name: "b", FullName: "octocat/b", Downstream: []string{"octocat/c"}},
+		{UserID: 1, Owner: "octocat", Name: "c", FullName: "octocat/c", Downstream: []string{"octocat/a@master"}},
+		{UserID: 1, Owner: "octocat", Name: "d", FullName: "octocat/d", Downstream: []string{"octocat/d"}},
+	} {
+		if err := s.CreateRepo(repo); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo := &model.Repo{FullName: "octocat/a"}
+
+	tests := []struct {
+		list  []string
+		cycle bool
+	}{
+		{list: []string{"octocat/d"}},
+		{list: []string{"octocat/e"}},
+		{list: []string{"octocat/b"}, cycle: true},
+		{list: []string{"octocat/a"}, cycle: true},
+	}
+	for _, test := range tests {
+		err := validateDownstream(s, repo, test.list)
+		if test.cycle && (err == nil || !strings.Contains(err.Error(), "cycle")) {
+			t.Errorf("Want cycle error for downstream %v, got %v", test.list, err)
+		}
+		if !test.cycle && err != nil {
+			t.Errorf("Want downstream %v valid, got %s", test.list, err)
+		}
+	}
+	if err := validateDownstream(s, repo, []string{"octocat"}); err == nil {
+		t.Errorf("Want error for an invalid downstream repository")
+	}
+}
+
+func TestTriggerDownstream(t *testing.T) {
+	defer func(c model.ConfigStore) {
+		Config.Storage.Config = c
+	}(Config.Storage.Config)
+
+	s := datastore.New("sqlite3", ":memory:")
+	Config.Storage.Config = s
+	user := &model.User{Login: "octocat", Token: "cfcd2084"}
+	if err := s.CreateUser(user); err != nil {
+		t.Fatal(err)
+	}
+	repo := &model.Repo{
+		UserID:     user.ID,
+		FullName:   "octocat/c",
+		Downstream: []string{"octocat/a", "octocat/d", "octocat/d@develop", "octocat"},
+	}
+
+	r := new(permRemote)
+	triggerDownstream(s, r, repo, &model.Build{Number: 1, Upstream: "octocat/a,octocat/b"})
+	if got := strings.Join(r.checked, ","); got != "" {
+		t.Errorf("Want no permission checks for inactive repositories, got %s", got)
+	}
+
+	if err := s.CreateRepo(&model.Repo{UserID: user.ID, Owner: "octocat", Name: "a", FullName: "octocat/a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateRepo(&model.Repo{UserID: user.ID, Owner: "octocat", Name: "d", FullName: "octocat/d"}); err != nil {
+		t.Fatal(err)
+	}
+	triggerDownstream(s, r, repo, &model.Build{Number: 1, Upstream: "octocat/a,octocat/b"})
+	if got := strings.Join(r.checked, ","); got != "octocat/d" {
+		t.Errorf("Want downstream octocat/d triggered once, skipping the upstream repositories, got %s", got)
+	}
+
+	r.checked = nil
+	chain := strings.TrimSuffix(strings.Repeat("octocat/x,", maxDownstreamDepth-1), ",")
+	triggerDownstream(s, r, repo, &model.Build{Number: 1, Upstream: chain})
+	if len(r.checked) != 0 {
+		t.Errorf("Want no downstream builds when the chain exceeds the depth, got %v", r.checked)
+	}
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"context"
+	"sync"
+)
+
+var (
+	draining  = make(chan struct{})
+	drainOnce sync.Once
+)
+
+// Drain prepares the server for shutdown. Hooks and agent connections are
+// rejected, the readiness check fails, and connected agents are
+// disconnected, which cancels their pending requests for work. Agents
+// reconnect to another server, and running pipelines are re-queued if the
+// agent does not report back before the lease expires.
+func Drain() {
+	drainOnce.Do(func() {
+		close(draining)
+	})
+}
+
+// helper function returns true if the server is draining.
+func isDraining() bool {
+	select {
+	case <-draining:
+		return true
+	default:
+		return false
+	}
+}
+
+// helper function returns a context that is cancelled when the server
+// starts draining.
+func drainContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-draining:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
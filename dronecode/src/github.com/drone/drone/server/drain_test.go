@@ -0,0 +1,54 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDrain(t *testing.T) {
+	defer func() {
+		draining = make(chan struct{})
+		drainOnce = sync.Once{}
+	}()
+
+	ctx, cancel := drainContext()
+	defer cancel()
+	if isDraining() {
+		t.Errorf("Want server not draining")
+	}
+
+	Drain()
+	Drain() // draining twice is a no-op
+	if !isDraining() {
+		t.Errorf("Want server draining")
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Errorf("Want context cancelled when the server drains")
+	}
+
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.GET("/readyz", GetHealthReady)
+	e.POST("/hook", PostHook)
+	e.GET("/ws/broker", RPCHandler)
+
+	for _, test := range []struct{ method, path string }{
+		{"GET", "/readyz"},
+		{"POST", "/hook"},
+		{"GET", "/ws/broker"},
+	} {
+		req, _ := http.NewRequest(test.method, test.path, nil)
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		if w.Code != 503 {
+			t.Errorf("Want status 503 for %s while draining, got %d", test.path, w.Code)
+		}
+	}
+}
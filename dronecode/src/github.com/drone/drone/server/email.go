@@ -0,0 +1,100 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/store"
+)
+
+// notifyBuild sends an email notification if a push to the default branch
+// of the repository failed, or fixed a failed build. The author of the
+// build and the recipients of the repository are notified.
+func notifyBuild(s store.Store, repo *model.Repo, build *model.Build, link string) {
+	if Config.Services.Email == nil || !repo.EmailNotify {
+		return
+	}
+	if build.Event != model.EventPush || build.Branch != repo.Branch {
+		return
+	}
+
+	var verb string
+	switch {
+	case failed(build.Status):
+		verb = "failed"
+	case build.Status == model.StatusSuccess:
+		last, err := s.GetBuildLastBefore(repo, build.Branch, build.ID)
+		if err != nil || !failed(last.Status) {
+			return
+		}
+		verb = "fixed"
+	default:
+		return
+	}
+
+	to := recipients(s, repo, build.Author)
+	sendEmail(&model.Email{
+		To:      to,
+		Subject: fmt.Sprintf("[%s] Build #%d %s (%s - %.8s)", repo.FullName, build.Number, verb, build.Branch, build.Commit),
+		Body: fmt.Sprintf("Build #%d of %s %s.\n\n%s\n\nAuthor: %s\nCommit: %s\n\n%s\n",
+			build.Number, repo.FullName, verb, build.Message, build.Author, build.Commit, link),
+	})
+}
+
+// notifyBlocked sends an email notification that the gated build of the
+// repository is waiting for approval. The owner of the repository and the
+// recipients of the repository are notified.
+func notifyBlocked(s store.Store, repo *model.Repo, build *model.Build, link string) {
+	if Config.Services.Email == nil || !repo.EmailNotify {
+		return
+	}
+	var owner string
+	if user, err := s.GetUser(repo.UserID); err == nil {
+		owner = user.Login
+	}
+	to := recipients(s, repo, owner)
+	sendEmail(&model.Email{
+		To:      to,
+		Subject: fmt.Sprintf("[%s] Build #%d is waiting for approval", repo.FullName, build.Number),
+		Body: fmt.Sprintf("Build #%d of %s by %s is waiting for approval.\n\n%s\n\n%s\n",
+			build.Number, repo.FullName, build.Sender, build.Message, link),
+	})
+}
+
+// helper function returns the email recipients of the repository, and the
+// email address of the user if the user receives email notifications.
+func recipients(s store.Store, repo *model.Repo, login string) []string {
+	to := append([]string{}, repo.EmailTo...)
+	if login == "" {
+		return to
+	}
+	user, err := s.GetUserLogin(login)
+	if err != nil || !user.EmailNotify || user.Email == "" {
+		return to
+	}
+	for _, addr := range to {
+		if addr == user.Email {
+			return to
+		}
+	}
+	return append(to, user.Email)
+}
+
+// helper function returns true if the build status is a failed status.
+func failed(status string) bool {
+	return status == model.StatusFailure ||
+		status == model.StatusError ||
+		status == model.StatusKilled
+}
+
+// sendEmail sends the email notification, if email notifications are
+// enabled.
+func sendEmail(email *model.Email) {
+	if Config.Services.Email == nil || len(email.To) == 0 {
+		return
+	}
+	if err := Config.Services.Email.EmailSend(email); err != nil {
+		logrus.Errorf("Error sending email %q. %s", email.Subject, err)
+	}
+}
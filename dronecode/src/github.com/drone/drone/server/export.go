@@ -0,0 +1,239 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/remote"
+	"github.com/drone/drone/router/middleware/session"
+	"github.com/drone/drone/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetRepoExport exports the settings, secrets, registries and cron jobs of
+// the repository and writes to the response in json format. Secret values
+// and registry passwords are not exported.
+func GetRepoExport(c *gin.Context) {
+	repo := session.Repo(c)
+	out, err := exportRepo(store.FromContext(c), repo)
+	if err != nil {
+		c.String(500, "Error exporting repository. %s", err)
+		return
+	}
+	c.JSON(200, out)
+}
+
+// PostRepoImport imports the exported settings, secrets, registries and
+// cron jobs into the repository. Existing secrets, registries and cron jobs
+// with the same name are updated. Secrets without a value and registries
+// without a password are only imported if they already exist.
+func PostRepoImport(c *gin.Context) {
+	var (
+		repo = session.Repo(c)
+		s    = store.FromContext(c)
+	)
+	in := new(model.RepoExport)
+	if err := c.Bind(in); err != nil {
+		c.String(http.StatusBadRequest, "Error parsing export. %s", err)
+		return
+	}
+	if _, ok := remote.FromContext(c).(remote.Brancher); !ok && len(in.Crons) != 0 {
+		c.String(400, "Error importing crons. Cron jobs are not supported by the remote")
+		return
+	}
+	for _, cron := range in.Crons {
+		if err := cron.Validate(); err != nil {
+			c.String(400, "Error importing cron %q. %s", cron.Name, err)
+			return
+		}
+	}
+	if in.Settings != nil {
+		if !patchRepo(c, repo, in.Settings) {
+			return
+		}
+		if err := s.UpdateRepo(repo); err != nil {
+			c.String(500, "Error importing settings. %s", err)
+			return
+		}
+		recordAudit(c, model.AuditRepoUpdate, repo.FullName, "")
+	}
+
+	for _, in := range in.Secrets {
+		ok, err := importSecret(repo, in)
+		if err != nil {
+			c.String(500, "Error importing secret %q. %s", in.Name, err)
+			return
+		}
+		if ok {
+			recordAudit(c, model.AuditSecretUpdate, repo.FullName, in.Name)
+		}
+	}
+	for _, in := range in.Registries {
+		if err := importRegistry(repo, in); err != nil {
+			c.String(500, "Error importing registry %q. %s", in.Address, err)
+			return
+		}
+	}
+	for _, in := range in.Crons {
+		if err := importCron(s, repo, in); err != nil {
+			c.String(500, "Error importing cron %q. %s", in.Name, err)
+			return
+		}
+	}
+
+	out, err := exportRepo(s, repo)
+	if err != nil {
+		c.String(500, "Error exporting repository. %s", err)
+		return
+	}
+	c.JSON(200, out)
+}
+
+// helper function returns the export of the repository.
+func exportRepo(s store.Store, repo *model.Repo) (*model.RepoExport, error) {
+	out := &model.RepoExport{
+		Settings:   exportSettings(repo),
+		Secrets:    []*model.Secret{},
+		Registries: []*model.Registry{},
+	}
+	secrets, err := Config.Services.Secrets.SecretList(repo)
+	if err != nil {
+		return nil, err
+	}
+	for _, secret := range secrets {
+		out.Secrets = append(out.Secrets, secret.Copy())
+	}
+	registries, err := Config.Services.Registries.RegistryList(repo)
+	if err != nil {
+		return nil, err
+	}
+	for _, registry := range registries {
+		out.Registries = append(out.Registries, registry.Copy())
+	}
+	out.Crons, err = s.CronList(repo)
+	return out, err
+}
+
+// helper function returns the repository settings as a patch. Settings
+// that require system administrator privileges are only exported if they
+// are set, so that the export can be imported by repository administrators.
+func exportSettings(repo *model.Repo) *model.RepoPatch {
+	patch := &model.RepoPatch{
+		Config:        &repo.Config,
+		IsGated:       &repo.IsGated,
+		RequireSigned: &repo.RequireSigned,
+		AllowPull:     &repo.AllowPull,
+		AllowPush:     &repo.AllowPush,
+		AllowDeploy:   &repo.AllowDeploy,
+		AllowTag:      &repo.AllowTag,
+		AllowComments: &repo.AllowComments,
+		CancelPulls:   &repo.CancelPulls,
+		CancelPush:    &repo.CancelPush,
+		EmailNotify:   &repo.EmailNotify,
+		EmailTo:       &repo.EmailTo,
+		SkipPatterns:  &repo.SkipPatterns,
+		DeployRules:   &repo.DeployRules,
+		Downstream:    &repo.Downstream,
+	}
+	if repo.IsTrusted {
+		patch.IsTrusted = &repo.IsTrusted
+	}
+	if repo.Timeout != 0 {
+		patch.Timeout = &repo.Timeout
+	}
+	if repo.Throttle != 0 {
+		patch.Throttle = &repo.Throttle
+	}
+	if repo.RateLimit != 0 {
+		patch.RateLimit = &repo.RateLimit
+	}
+	if repo.Priority != 0 {
+		patch.Priority = &repo.Priority
+	}
+	return patch
+}
+
+// helper function creates or updates the imported secret, and returns
+// false if the secret is skipped.
+func importSecret(repo *model.Repo, in *model.Secret) (bool, error) {
+	secret, err := Config.Services.Secrets.SecretFind(repo, in.Name)
+	if err != nil {
+		if in.Value == "" {
+			logrus.Infof("skipping import of secret %q to %s without a value", in.Name, repo.FullName)
+			return false, nil
+		}
+		secret = &model.Secret{
+			RepoID:   repo.ID,
+			Name:     in.Name,
+			Value:    in.Value,
+			Events:   in.Events,
+			Images:   in.Images,
+			Branches: in.Branches,
+		}
+		if err := secret.Validate(); err != nil {
+			return false, err
+		}
+		return true, Config.Services.Secrets.SecretCreate(repo, secret)
+	}
+	if in.Value != "" {
+		secret.Value = in.Value
+	}
+	secret.Events = in.Events
+	secret.Images = in.Images
+	secret.Branches = in.Branches
+	return true, Config.Services.Secrets.SecretUpdate(repo, secret)
+}
+
+// helper function creates or updates the imported registry.
+func importRegistry(repo *model.Repo, in *model.Registry) error {
+	registry, err := Config.Services.Registries.RegistryFind(repo, in.Address)
+	if err != nil {
+		if in.Password == "" {
+			logrus.Infof("skipping import of registry %q to %s without a password", in.Address, repo.FullName)
+			return nil
+		}
+		registry = &model.Registry{
+			RepoID:   repo.ID,
+			Address:  in.Address,
+			Username: in.Username,
+			Password: in.Password,
+			Email:    in.Email,
+			Token:    in.Token,
+		}
+		if err := registry.Validate(); err != nil {
+			return err
+		}
+		return Config.Services.Registries.RegistryCreate(repo, registry)
+	}
+	if in.Password != "" {
+		registry.Password = in.Password
+	}
+	registry.Username = in.Username
+	registry.Email = in.Email
+	registry.Token = in.Token
+	return Config.Services.Registries.RegistryUpdate(repo, registry)
+}
+
+// helper function creates or updates the imported cron job.
+func importCron(s store.Store, repo *model.Repo, in *model.Cron) error {
+	cron, err := s.CronFind(repo, in.Name)
+	if err != nil {
+		cron = &model.Cron{
+			RepoID:  repo.ID,
+			Name:    in.Name,
+			Created: time.Now().Unix(),
+		}
+	}
+	cron.Schedule = in.Schedule
+	cron.Branch = in.Branch
+	if err := cron.SetNext(time.Now()); err != nil {
+		return err
+	}
+	if cron.ID == 0 {
+		return s.CronCreate(cron)
+	}
+	return s.CronUpdate(cron)
+}
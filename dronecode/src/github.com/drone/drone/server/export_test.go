@@ -0,0 +1,92 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/plugins/registry"
+	"github.com/drone/drone/plugins/secrets"
+	"github.com/drone/drone/store/datastore"
+)
+
+func TestExportImportRepo(t *testing.T) {
+	defer func(s model.SecretService, r model.RegistryService) {
+		Config.Services.Secrets, Config.Services.Registries = s, r
+	}(Config.Services.Secrets, Config.Services.Registries)
+
+	s := datastore.New("sqlite3", ":memory:")
+	Config.Services.Secrets = secrets.New(s)
+	Config.Services.Registries = registry.New(s)
+
+	from := &model.Repo{ID: 1, FullName: "octocat/hello-world", Config: ".drone.yml", Timeout: 60}
+	to := &model.Repo{ID: 2, FullName: "octocat/spoon-knife"}
+	Config.Services.Secrets.SecretCreate(from, &model.Secret{RepoID: from.ID, Name: "token", Value: "cfcd2084", Events: []string{"push"}})
+	Config.Services.Registries.RegistryCreate(from, &model.Registry{RepoID: from.ID, Address: "index.docker.io", Username: "octocat", Password: "correct-horse"})
+	s.CronCreate(&model.Cron{RepoID: from.ID, Name: "nightly", Schedule: "@daily", Branch: "master"})
+
+	out, err := exportRepo(s, from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Secrets) != 1 || out.Secrets[0].Value != "" {
+		t.Errorf("Want the secret exported without the value, got %v", out.Secrets)
+	}
+	if len(out.Registries) != 1 || out.Registries[0].Password != "" {
+		t.Errorf("Want the registry exported without the password, got %v", out.Registries)
+	}
+	if len(out.Crons) != 1 || out.Crons[0].Schedule != "@daily" {
+		t.Errorf("Want the cron job exported, got %v", out.Crons)
+	}
+	if out.Settings.Timeout == nil || *out.Settings.Timeout != 60 || out.Settings.IsTrusted != nil {
+		t.Errorf("Want only the administrator settings that are set exported")
+	}
+
+	// secrets and registries without a value are skipped unless they
+	// exist in the repository.
+	for _, in := range out.Secrets {
+		if ok, err := importSecret(to, in); ok || err != nil {
+			t.Errorf("Want secret without a value skipped, got %v %v", ok, err)
+		}
+	}
+	for _, in := range out.Registries {
+		if err := importRegistry(to, in); err != nil {
+			t.Error(err)
+		}
+	}
+	for _, in := range out.Crons {
+		if err := importCron(s, to, in); err != nil {
+			t.Error(err)
+		}
+	}
+	imported, err := exportRepo(s, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imported.Secrets) != 0 || len(imported.Registries) != 0 {
+		t.Errorf("Want no secrets and registries imported without values, got %d and %d", len(imported.Secrets), len(imported.Registries))
+	}
+	if len(imported.Crons) != 1 || imported.Crons[0].RepoID != to.ID || imported.Crons[0].Next == 0 {
+		t.Errorf("Want the cron job imported and scheduled, got %v", imported.Crons)
+	}
+
+	// the existing secret is updated, and keeps its value.
+	Config.Services.Secrets.SecretCreate(to, &model.Secret{RepoID: to.ID, Name: "token", Value: "a1b2c3d4"})
+	if ok, err := importSecret(to, out.Secrets[0]); !ok || err != nil {
+		t.Errorf("Want existing secret updated, got %v %v", ok, err)
+	}
+	secret, err := Config.Services.Secrets.SecretFind(to, "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.Value != "a1b2c3d4" || len(secret.Events) != 1 {
+		t.Errorf("Want secret events updated and the value kept, got %v with value %s", secret.Events, secret.Value)
+	}
+
+	// importing the cron job again updates the existing cron job.
+	if err := importCron(s, to, out.Crons[0]); err != nil {
+		t.Error(err)
+	}
+	if crons, _ := s.CronList(to); len(crons) != 1 {
+		t.Errorf("Want the cron job updated, got %d cron jobs", len(crons))
+	}
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/drone/drone/router/middleware/session"
+	"github.com/drone/drone/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FileList returns the artifacts uploaded by the build steps.
+func FileList(c *gin.Context) {
+	num, err := strconv.Atoi(c.Param("number"))
+	if err != nil {
+		c.String(400, "Error parsing build number. %s", err)
+		return
+	}
+	build, err := store.GetBuildNumber(c, session.Repo(c), num)
+	if err != nil {
+		c.String(404, "Cannot find build. %s", err)
+		return
+	}
+	files, err := store.FromContext(c).FileList(build)
+	if err != nil {
+		c.String(500, "Error getting artifact list. %s", err)
+		return
+	}
+	c.JSON(200, files)
+}
+
+// FileGet downloads the artifact uploaded by the build step.
+func FileGet(c *gin.Context) {
+	var (
+		repo = session.Repo(c)
+		name = strings.TrimPrefix(c.Param("file"), "/")
+	)
+	num, err := strconv.Atoi(c.Param("number"))
+	if err != nil {
+		c.String(400, "Error parsing build number. %s", err)
+		return
+	}
+	pid, err := strconv.Atoi(c.Param("proc"))
+	if err != nil {
+		c.String(400, "Error parsing step number. %s", err)
+		return
+	}
+	build, err := store.GetBuildNumber(c, repo, num)
+	if err != nil {
+		c.String(404, "Cannot find build. %s", err)
+		return
+	}
+	proc, err := store.FromContext(c).ProcFind(build, pid)
+	if err != nil {
+		c.String(404, "Cannot find step. %s", err)
+		return
+	}
+	file, err := store.FromContext(c).FileFind(proc, name)
+	if err != nil {
+		c.String(404, "Cannot find artifact. %s", err)
+		return
+	}
+	rc, err := store.FromContext(c).FileRead(proc, file.Name)
+	if err != nil {
+		c.String(404, "Cannot read artifact. %s", err)
+		return
+	}
+	defer rc.Close()
+
+	c.Header("Content-Type", file.Mime)
+	c.Header("Content-Length", strconv.Itoa(file.Size))
+	if c.Query("raw") != "true" {
+		c.Header("Content-Disposition", "attachment; filename=\""+strings.Replace(name, "\"", "", -1)+"\"")
+	}
+	io.Copy(c.Writer, rc)
+}
+
+// pruneBatch is the number of expired artifacts deleted at a time.
+const pruneBatch = 100
+
+// ArtifactPruner periodically deletes the artifacts uploaded before the
+// retention period, until the context is cancelled.
+func ArtifactPruner(ctx context.Context, s store.Store, retention, interval time.Duration) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+		if err := pruneArtifacts(s, time.Now().Add(-retention)); err != nil {
+			logrus.Errorf("artifacts: cannot prune expired artifacts. %s", err)
+		}
+	}
+}
+
+// pruneArtifacts deletes the artifacts uploaded before the time.
+func pruneArtifacts(s store.Store, before time.Time) error {
+	for {
+		files, err := s.FileListBefore(before.Unix(), pruneBatch)
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			if err := s.FileDelete(file); err != nil {
+				return err
+			}
+		}
+		if len(files) < pruneBatch {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/drone/drone/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// gcResult reports the number of records deleted by the garbage collector.
+type gcResult struct {
+	Logs   int64 `json:"logs"`
+	Builds int64 `json:"builds"`
+	Hooks  int64 `json:"hooks"`
+}
+
+// GetRetention returns the retention policy of the server.
+func GetRetention(c *gin.Context) {
+	c.JSON(200, gin.H{
+		"logs":   Config.Retention.Logs.String(),
+		"builds": Config.Retention.Builds,
+		"hooks":  Config.Retention.Hooks.String(),
+	})
+}
+
+// PostGC deletes the logs, builds and hooks that are expired by the
+// retention policy, and returns the number of deleted records.
+func PostGC(c *gin.Context) {
+	result, err := collect(store.FromContext(c), time.Now())
+	if err != nil {
+		c.String(500, "Error collecting expired records. %s", err)
+		return
+	}
+	c.JSON(200, result)
+}
+
+// GarbageCollector periodically deletes the logs, builds and hooks that are
+// expired by the retention policy, until the context is cancelled.
+func GarbageCollector(ctx context.Context, s store.Store, interval time.Duration) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+		result, err := collect(s, time.Now())
+		if err != nil {
+			logrus.Errorf("gc: cannot collect expired records. %s", err)
+			continue
+		}
+		logrus.Debugf("gc: deleted %d logs, %d builds and %d hooks",
+			result.Logs, result.Builds, result.Hooks)
+	}
+}
+
+// helper function deletes the records that are expired at the time. A zero
+// retention disables the policy.
+func collect(s store.Store, now time.Time) (*gcResult, error) {
+	var (
+		result = new(gcResult)
+		err    error
+	)
+	if retention := Config.Retention.Builds; retention > 0 {
+		if result.Builds, err = s.BuildPrune(retention); err != nil {
+			return nil, err
+		}
+	}
+	if retention := Config.Retention.Logs; retention > 0 {
+		if result.Logs, err = s.LogPrune(now.Add(-retention).Unix()); err != nil {
+			return nil, err
+		}
+	}
+	if retention := Config.Retention.Hooks; retention > 0 {
+		if result.Hooks, err = s.HookPrune(now.Add(-retention).Unix()); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
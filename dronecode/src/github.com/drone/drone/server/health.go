@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/drone/drone/remote"
+	"github.com/drone/drone/store"
+)
+
+// healthTimeout is the maximum duration of each dependency check.
+const healthTimeout = time.Second * 5
+
+// remoteCacheTTL is the duration the result of the remote check is reused,
+// so that frequent probes do not count against the remote rate limit.
+const remoteCacheTTL = time.Second * 30
+
+type healthCheck struct {
+	Status  string      `json:"status"`
+	Latency string      `json:"latency,omitempty"`
+	Error   string      `json:"error,omitempty"`
+	Detail  interface{} `json:"detail,omitempty"`
+}
+
+type healthStatus struct {
+	Status string                  `json:"status"`
+	Checks map[string]*healthCheck `json:"checks,omitempty"`
+}
+
+var remoteHealth struct {
+	sync.Mutex
+	check   *healthCheck
+	checked time.Time
+}
+
+// GetHealthLive returns 200 if the server process is running and serving
+// requests. It does not check dependencies, so that a failing dependency
+// does not cause the server to be restarted.
+func GetHealthLive(c *gin.Context) {
+	c.JSON(200, &healthStatus{Status: "ok"})
+}
+
+// GetHealthReady returns 200 if the database and remote system are
+// reachable and the server is ready to receive traffic, or 503 if any of
+// the dependency checks fail or the server is shutting down. The queue
+// state is included for reference.
+func GetHealthReady(c *gin.Context) {
+	if isDraining() {
+		c.JSON(503, &healthStatus{Status: "draining"})
+		return
+	}
+	out := &healthStatus{
+		Status: "ok",
+		Checks: map[string]*healthCheck{
+			"database": runCheck(store.FromContext(c).Ping),
+			"remote":   checkRemote(remote.FromContext(c)),
+			"queue":    checkQueue(c),
+		},
+	}
+	code := 200
+	for _, check := range out.Checks {
+		if check.Status == "fail" {
+			out.Status = "fail"
+			code = 503
+		}
+	}
+	c.JSON(code, out)
+}
+
+// helper function checks the remote system is reachable, if the remote
+// supports the check. The result is cached.
+func checkRemote(r remote.Remote) *healthCheck {
+	pinger, ok := r.(remote.Pinger)
+	if !ok {
+		return &healthCheck{Status: "ok", Detail: "not supported"}
+	}
+	remoteHealth.Lock()
+	defer remoteHealth.Unlock()
+	if remoteHealth.check == nil || time.Since(remoteHealth.checked) > remoteCacheTTL {
+		remoteHealth.check = runCheck(pinger.Ping)
+		remoteHealth.checked = time.Now()
+	}
+	return remoteHealth.check
+}
+
+// helper function returns the queue state. A paused queue is reported but
+// does not fail the check, since the server continues to accept builds.
+func checkQueue(c context.Context) *healthCheck {
+	if Config.Services.Queue == nil {
+		return &healthCheck{Status: "fail", Error: "queue is not configured"}
+	}
+	info := Config.Services.Queue.Info(c)
+	return &healthCheck{
+		Status: "ok",
+		Detail: map[string]interface{}{
+			"paused":  info.Paused,
+			"workers": info.Stats.Workers,
+			"pending": info.Stats.Pending,
+			"running": info.Stats.Running,
+		},
+	}
+}
+
+// helper function runs the check with a timeout and records the latency.
+func runCheck(fn func() error) *healthCheck {
+	start := time.Now()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- fn()
+	}()
+
+	var err error
+	select {
+	case err = <-errc:
+	case <-time.After(healthTimeout):
+		err = fmt.Errorf("check timed out after %s", healthTimeout)
+	}
+	check := &healthCheck{
+		Status:  "ok",
+		Latency: time.Since(start).String(),
+	}
+	if err != nil {
+		check.Status = "fail"
+		check.Error = err.Error()
+	}
+	return check
+}
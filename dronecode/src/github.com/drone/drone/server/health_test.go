@@ -0,0 +1,118 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cncd/queue"
+	"github.com/drone/drone/remote"
+	"github.com/drone/drone/store"
+	"github.com/drone/drone/store/datastore"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pingRemote fakes a remote system that counts the pings and returns err.
+type pingRemote struct {
+	remote.Remote
+
+	err   error
+	pings int
+}
+
+func (r *pingRemote) Ping() error {
+	r.pings++
+	return r.err
+}
+
+func TestGetHealthLive(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.GET("/healthz", GetHealthLive)
+
+	req, _ := http.NewRequest("GET", "/healthz", nil)
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	if w.Code != 200 {
+		t.Errorf("Want status 200, got %d", w.Code)
+	}
+}
+
+func TestGetHealthReady(t *testing.T) {
+	defer func(q queue.Queue) {
+		Config.Services.Queue = q
+	}(Config.Services.Queue)
+	Config.Services.Queue = queue.New()
+	defer resetRemoteHealth()
+
+	s := datastore.New("sqlite3", ":memory:")
+	var r remote.Remote
+
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.GET("/readyz", func(c *gin.Context) {
+		store.ToContext(c, s)
+		remote.ToContext(c, r)
+		GetHealthReady(c)
+	})
+	ready := func() (int, *healthStatus) {
+		req, _ := http.NewRequest("GET", "/readyz", nil)
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		out := new(healthStatus)
+		json.Unmarshal(w.Body.Bytes(), out)
+		return w.Code, out
+	}
+
+	r = &includeRemote{}
+	resetRemoteHealth()
+	if code, out := ready(); code != 200 || out.Checks["remote"].Detail != "not supported" {
+		t.Errorf("Want ready when the remote check is not supported, got %d %+v", code, out.Checks["remote"])
+	}
+
+	pinger := &pingRemote{}
+	r = pinger
+	resetRemoteHealth()
+	if code, out := ready(); code != 200 || out.Status != "ok" || out.Checks["database"].Status != "ok" {
+		t.Errorf("Want ready, got %d %+v", code, out)
+	}
+	ready()
+	if pinger.pings != 1 {
+		t.Errorf("Want the remote check cached, got %d pings", pinger.pings)
+	}
+
+	r = &pingRemote{err: errors.New("connection refused")}
+	resetRemoteHealth()
+	code, out := ready()
+	if code != 503 || out.Status != "fail" || out.Checks["remote"].Error != "connection refused" {
+		t.Errorf("Want not ready when the remote is unreachable, got %d %+v", code, out.Checks["remote"])
+	}
+
+	Config.Services.Queue = nil
+	resetRemoteHealth()
+	if code, _ := ready(); code != 503 {
+		t.Errorf("Want not ready without a queue, got %d", code)
+	}
+}
+
+func TestRunCheck(t *testing.T) {
+	if check := runCheck(func() error { return nil }); check.Status != "ok" || check.Latency == "" {
+		t.Errorf("Want successful check with latency, got %+v", check)
+	}
+	check := runCheck(func() error { return errors.New("database is locked") })
+	if check.Status != "fail" || check.Error != "database is locked" {
+		t.Errorf("Want failed check, got %+v", check)
+	}
+}
+
+// helper function clears the cached result of the remote check.
+func resetRemoteHealth() {
+	remoteHealth.Lock()
+	remoteHealth.check = nil
+	remoteHealth.checked = time.Time{}
+	remoteHealth.Unlock()
+}
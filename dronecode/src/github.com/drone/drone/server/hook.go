@@ -4,18 +4,25 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"math/rand"
-	"regexp"
+	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/drone/drone/model"
+	"github.com/drone/drone/plugins/config"
 	"github.com/drone/drone/remote"
+	"github.com/drone/drone/router/middleware/logger"
 	"github.com/drone/drone/shared/httputil"
+	"github.com/drone/drone/shared/metrics"
 	"github.com/drone/drone/shared/token"
 	"github.com/drone/drone/store"
 	"github.com/drone/envsubst"
@@ -38,7 +45,18 @@ import (
 // experimental code. Please pardon our appearance during renovations.
 //
 
-var skipRe = regexp.MustCompile(`\[(?i:ci *skip|skip *ci)\]`)
+// default paths of the yaml, jsonnet and starlark pipeline configuration.
+const (
+	defaultConfigPath   = ".drone.yml"
+	defaultJsonnetPath  = ".drone.jsonnet"
+	defaultStarlarkPath = ".drone.star"
+)
+
+var (
+	errJsonnetDisabled  = errors.New("Jsonnet configuration is not enabled on the server")
+	errStarlarkDisabled = errors.New("Starlark configuration is not enabled on the server")
+	errConfigDeferred   = errors.New("Configuration of pull requests from forks is evaluated after approval")
+)
 
 func init() {
 	rand.Seed(time.Now().UnixNano())
@@ -50,58 +68,227 @@ func GetQueueInfo(c *gin.Context) {
 	)
 }
 
-func PostHook(c *gin.Context) {
-	remote_ := remote.FromContext(c)
-
-	tmprepo, build, err := remote_.Hook(c.Request)
+// PostCheckHook handles requests from the remote system to re-run a build,
+// such as the GitHub Checks api re-run action, and restarts the build.
+func PostCheckHook(c *gin.Context) {
+	log := logger.FromContext(c)
+	if isDraining() {
+		c.String(503, "Server is shutting down")
+		return
+	}
+	rerunner, ok := remote.FromContext(c).(remote.Rerunner)
+	if !ok {
+		c.String(404, "Re-running builds is not supported by the remote")
+		return
+	}
+	tmprepo, num, err := rerunner.Rerun(c.Request)
 	if err != nil {
-		logrus.Errorf("failure to parse hook. %s", err)
+		log.Errorf("failure to parse re-run hook. %s", err)
 		c.AbortWithError(400, err)
 		return
 	}
-	if build == nil {
+	if num == 0 {
 		c.Writer.WriteHeader(200)
 		return
 	}
-	if tmprepo == nil {
-		logrus.Errorf("failure to ascertain repo from hook.")
-		c.Writer.WriteHeader(400)
+
+	repo, err := store.GetRepoOwnerName(c, tmprepo.Owner, tmprepo.Name)
+	if err != nil {
+		log.Errorf("failure to find repo %s/%s from re-run hook. %s", tmprepo.Owner, tmprepo.Name, err)
+		c.AbortWithError(404, err)
 		return
 	}
 
-	// skip the build if any case-insensitive combination of the words "skip" and "ci"
-	// wrapped in square brackets appear in the commit message
-	skipMatch := skipRe.FindString(build.Message)
-	if len(skipMatch) > 0 {
-		logrus.Infof("ignoring hook. %s found in %s", skipMatch, build.Commit)
-		c.Writer.WriteHeader(204)
+	// the re-run hook is verified by the remote, so the build is restarted
+	// the same way as a user initiated restart.
+	c.Set("repo", repo)
+	c.Params = append(c.Params, gin.Param{Key: "number", Value: strconv.Itoa(num)})
+	PostBuild(c)
+}
+
+// PostHook handles hooks received from the remote system. The hook is
+// persisted with its processing status, so that hooks that failed to
+// process can be replayed.
+func PostHook(c *gin.Context) {
+	log := logger.FromContext(c)
+	if isDraining() {
+		c.String(503, "Server is shutting down")
+		return
+	}
+	payload, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		log.Errorf("failure to read hook. %s", err)
+		c.AbortWithError(400, err)
 		return
 	}
+	hook := &model.Hook{
+		Query:   c.Request.URL.RawQuery,
+		Headers: c.Request.Header,
+		Payload: string(payload),
+		Created: time.Now().Unix(),
+	}
+	if err := store.FromContext(c).HookCreate(hook); err != nil {
+		log.Errorf("failure to persist hook. %s", err)
+	}
+	processHook(c, hook)
+}
 
-	repo, err := store.GetRepoOwnerName(c, tmprepo.Owner, tmprepo.Name)
+// GetHooks returns the most recent hooks that failed to process.
+func GetHooks(c *gin.Context) {
+	hooks, err := store.FromContext(c).HookListFailed(100)
 	if err != nil {
-		logrus.Errorf("failure to find repo %s/%s from hook. %s", tmprepo.Owner, tmprepo.Name, err)
-		c.AbortWithError(404, err)
+		c.String(500, "Error getting hook list. %s", err)
+		return
+	}
+	c.JSON(200, hooks)
+}
+
+// PostHookReplay replays the persisted hook, using the original headers
+// and payload, and updates the hook processing status.
+func PostHookReplay(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("hook"), 10, 64)
+	if err != nil {
+		c.String(400, "Error parsing hook id. %s", err)
+		return
+	}
+	hook, err := store.FromContext(c).HookFind(id)
+	if err != nil {
+		c.String(404, "Error getting hook %d. %s", id, err)
+		return
+	}
+	req, err := http.NewRequest("POST", "/hook?"+hook.Query, nil)
+	if err != nil {
+		c.String(500, "Error creating hook request. %s", err)
+		return
+	}
+	req.Header = hook.Headers
+	req.Host = c.Request.Host
+	req.TLS = c.Request.TLS
+	c.Request = req
+
+	processHook(c, hook)
+}
+
+// processHook processes the hook and persists the processing status.
+func processHook(c *gin.Context, hook *model.Hook) {
+	log := logger.FromContext(c)
+	c.Request.Body = ioutil.NopCloser(strings.NewReader(hook.Payload))
+
+	span := Config.Services.Tracer.Start("hook", "")
+	c.Set("trace", span)
+	postHook(c, hook)
+	span.Tag("http.status_code", strconv.Itoa(c.Writer.Status()))
+	span.Finish()
+
+	hook.Code = c.Writer.Status()
+	hook.Status = model.StatusSuccess
+	hook.Error = ""
+	if hook.Code >= 400 {
+		hook.Status = model.StatusFailure
+		hook.Error = http.StatusText(hook.Code)
+		if err := c.Errors.Last(); err != nil {
+			hook.Error = err.Error()
+		}
+	}
+	hook.Updated = time.Now().Unix()
+	if hook.ID == 0 {
 		return
 	}
+	if err := store.FromContext(c).HookUpdate(hook); err != nil {
+		log.Errorf("failure to update hook %d. %s", hook.ID, err)
+	}
+}
+
+// verifyHook verifies the hook signature, if the remote signs hooks, and the
+// token of the hook url. The signature is not verified if unsigned hooks are
+// accepted by the server, while the hooks are repaired. It returns false and
+// writes the error to the response if the hook is not authorized.
+func verifyHook(c *gin.Context, hook *model.Hook, repo *model.Repo) bool {
+	log := logger.FromContext(c)
+	if verifier, ok := remote.FromContext(c).(remote.Verifier); ok && !Config.Server.HookUnsigned {
+		if err := verifier.Verify(c.Request, []byte(hook.Payload), repo.Hash); err != nil {
+			log.Errorf("failure to verify hook signature for %s. %s", repo.FullName, err)
+			c.AbortWithStatus(403)
+			return false
+		}
+	}
 
 	// get the token and verify the hook is authorized
 	parsed, err := token.ParseRequest(c.Request, func(t *token.Token) (string, error) {
 		return repo.Hash, nil
 	})
 	if err != nil {
-		logrus.Errorf("failure to parse token from hook for %s. %s", repo.FullName, err)
+		log.Errorf("failure to parse token from hook for %s. %s", repo.FullName, err)
 		c.AbortWithError(400, err)
-		return
+		return false
+	}
+	if parsed.Kind != token.HookToken {
+		log.Errorf("failure to verify token from hook for %s. Expected a hook token, got %s", repo.FullName, parsed.Kind)
+		c.AbortWithStatus(403)
+		return false
 	}
 	if parsed.Text != repo.FullName {
-		logrus.Errorf("failure to verify token from hook. Expected %s, got %s", repo.FullName, parsed.Text)
+		log.Errorf("failure to verify token from hook. Expected %s, got %s", repo.FullName, parsed.Text)
 		c.AbortWithStatus(403)
+		return false
+	}
+	return true
+}
+
+func postHook(c *gin.Context, hook *model.Hook) {
+	log := logger.FromContext(c)
+	defer func(start time.Time) {
+		metrics.HookDuration.Observe(time.Since(start).Seconds())
+	}(time.Now())
+
+	remote_ := remote.FromContext(c)
+
+	// pull request comments are processed as comment commands, if the
+	// remote supports comment hooks.
+	if commenter, ok := remote_.(remote.Commenter); ok {
+		comment, err := commenter.Comment(c.Request)
+		if err != nil {
+			log.Errorf("failure to parse comment hook. %s", err)
+			c.AbortWithError(400, err)
+			return
+		}
+		if comment != nil {
+			postComment(c, hook, comment)
+			return
+		}
+		c.Request.Body = ioutil.NopCloser(strings.NewReader(hook.Payload))
+	}
+
+	tmprepo, build, err := remote_.Hook(c.Request)
+	if err != nil {
+		log.Errorf("failure to parse hook. %s", err)
+		c.AbortWithError(400, err)
+		return
+	}
+	if build == nil {
+		c.Writer.WriteHeader(200)
+		return
+	}
+	if tmprepo == nil {
+		log.Errorf("failure to ascertain repo from hook.")
+		c.Writer.WriteHeader(400)
+		return
+	}
+	hook.Repo = tmprepo.FullName
+
+	repo, err := store.GetRepoOwnerName(c, tmprepo.Owner, tmprepo.Name)
+	if err != nil {
+		log.Errorf("failure to find repo %s/%s from hook. %s", tmprepo.Owner, tmprepo.Name, err)
+		c.AbortWithError(404, err)
+		return
+	}
+
+	if !verifyHook(c, hook, repo) {
 		return
 	}
 
 	if repo.UserID == 0 {
-		logrus.Warnf("ignoring hook. repo %s has no owner.", repo.FullName)
+		log.Warnf("ignoring hook. repo %s has no owner.", repo.FullName)
 		c.Writer.WriteHeader(204)
 		return
 	}
@@ -114,18 +301,50 @@ func PostHook(c *gin.Context) {
 	}
 
 	if skipped {
-		logrus.Infof("ignoring hook. repo %s is disabled for %s events.", repo.FullName, build.Event)
+		log.Infof("ignoring hook. repo %s is disabled for %s events.", repo.FullName, build.Event)
 		c.Writer.WriteHeader(204)
 		return
 	}
 
+	if build.Event == model.EventDeploy {
+		if err := model.CheckDeploy(repo.DeployRules, build.Deploy, build.Sender, build.Branch); err != nil {
+			log.Infof("ignoring hook. %s", err)
+			recordAuditUser(c, build.Sender, model.AuditDeployDenied, repo.FullName, build.Deploy)
+			c.String(403, err.Error())
+			return
+		}
+	}
+
+	// the build is recorded as skipped if the commit message contains
+	// [ci skip], or matches any of the repository skip patterns.
+	directives := model.ParseDirectives(build.Message, repo.SkipPatterns)
+	if directives.Skip != "" {
+		log.Infof("skipping build. %s found in %s", directives.Skip, build.Commit)
+		build.RepoID = repo.ID
+		build.Trim()
+		skipBuild(build, fmt.Sprintf("Skipped by %s in the commit message", directives.Skip))
+		if err := store.CreateBuild(c, build); err != nil {
+			log.Errorf("failure to save skipped commit for %s. %s", repo.FullName, err)
+			c.AbortWithError(500, err)
+			return
+		}
+		c.JSON(200, build)
+		return
+	}
+
 	user, err := store.GetUser(c, repo.UserID)
 	if err != nil {
-		logrus.Errorf("failure to find repo owner %s. %s", repo.FullName, err)
+		log.Errorf("failure to find repo owner %s. %s", repo.FullName, err)
 		c.AbortWithError(500, err)
 		return
 	}
 
+	if err := checkRateLimit(store.FromContext(c), user, repo); err != nil {
+		log.Infof("ignoring hook. %s", err)
+		c.String(429, err.Error())
+		return
+	}
+
 	// if the remote has a refresh token, the current access token
 	// may be stale. Therefore, we should refresh prior to dispatching
 	// the build.
@@ -136,29 +355,27 @@ func PostHook(c *gin.Context) {
 		}
 	}
 
-	// fetch the build file from the database
-	confb, err := remote_.File(user, repo, build, repo.Config)
-	if err != nil {
-		logrus.Errorf("failure to get build config for %s. %s", repo.FullName, err)
+	// fetch the build file from the database. The jsonnet and starlark
+	// configurations of pull requests from forks are evaluated after the
+	// build is approved.
+	confb, err := fetchConfig(remote_, user, repo, build, false)
+	deferred := err == errConfigDeferred
+	if err != nil && !deferred {
+		log.Errorf("failure to get build config for %s. %s", repo.FullName, err)
 		c.AbortWithError(404, err)
 		return
 	}
-	sha := shasum(confb)
-	conf, err := Config.Storage.Config.ConfigFind(repo, sha)
-	if err != nil {
-		conf = &model.Config{
-			RepoID: repo.ID,
-			Data:   string(confb),
-			Hash:   sha,
-		}
-		err = Config.Storage.Config.ConfigCreate(conf)
+	conf := new(model.Config)
+	if !deferred {
+		conf, err = persistConfig(repo, confb)
 		if err != nil {
-			logrus.Errorf("failure to persist config for %s. %s", repo.FullName, err)
+			log.Errorf("failure to persist config for %s. %s", repo.FullName, err)
 			c.AbortWithError(500, err)
 			return
 		}
 	}
 	build.ConfigID = conf.ID
+	build.Signed, build.Verified = verifyConfig(remote_, user, repo, build)
 
 	netrc, err := remote_.Netrc(user, repo)
 	if err != nil {
@@ -167,32 +384,44 @@ func PostHook(c *gin.Context) {
 	}
 
 	// verify the branches can be built vs skipped
-	branches, err := yaml.ParseString(conf.Data)
-	if err == nil {
-		if !branches.Branches.Match(build.Branch) && build.Event != model.EventTag && build.Event != model.EventDeploy {
-			c.String(200, "Branch does not match restrictions defined in yaml")
-			return
-		}
+	if !deferred && !matchBranches(conf.Data, build.Branch) && build.Event != model.EventTag && build.Event != model.EventDeploy {
+		c.String(200, "Branch does not match restrictions defined in yaml")
+		return
+	}
+
+	// verify the changed files can be built vs skipped
+	changedFiles(remote_, user, repo, build)
+	if !deferred && !matchPaths(conf.Data, build.Changed) {
+		c.String(200, "Changed files do not match the paths defined in yaml")
+		return
 	}
 
-	secs, err := Config.Services.Secrets.SecretList(repo)
+	secs, err := buildSecrets(repo)
 	if err != nil {
-		logrus.Debugf("Error getting secrets for %s#%d. %s", repo.FullName, build.Number, err)
+		log.Debugf("Error getting secrets for %s#%d. %s", repo.FullName, build.Number, err)
 	}
 
 	regs, err := Config.Services.Registries.RegistryList(repo)
 	if err != nil {
-		logrus.Debugf("Error getting registry credentials for %s#%d. %s", repo.FullName, build.Number, err)
+		log.Debugf("Error getting registry credentials for %s#%d. %s", repo.FullName, build.Number, err)
 	}
 
 	// update some build fields
 	build.RepoID = repo.ID
-	build.Verified = true
 	build.Status = model.StatusPending
 
-	if repo.IsGated {
+	// unsigned yaml changes require approval before they are queued, if
+	// the repository requires signed yaml. Pull requests from forks are
+	// gated below, and their secrets are stripped instead.
+	if repo.RequireSigned && !build.Verified && !isFork(repo, build) {
+		build.Status = model.StatusBlocked
+	}
+
+	// pull requests from forks require approval before they are queued,
+	// unless the sender is allowed, to prevent secret exfiltration.
+	if repo.IsGated || isFork(repo, build) {
 		allowed, _ := Config.Services.Senders.SenderAllowed(user, repo, build, conf)
-		if !allowed {
+		if !allowed || deferred {
 			build.Status = model.StatusBlocked
 		}
 	}
@@ -200,14 +429,19 @@ func PostHook(c *gin.Context) {
 	build.Trim()
 	err = store.CreateBuild(c, build, build.Procs...)
 	if err != nil {
-		logrus.Errorf("failure to save commit for %s. %s", repo.FullName, err)
+		log.Errorf("failure to save commit for %s. %s", repo.FullName, err)
 		c.AbortWithError(500, err)
 		return
 	}
 
+	cancelPrevious(store.FromContext(c), repo, build)
+
 	c.JSON(200, build)
 
 	if build.Status == model.StatusBlocked {
+		link := fmt.Sprintf("%s/%s/%d", httputil.GetURL(c.Request), repo.FullName, build.Number)
+		go notifyBlocked(store.FromContext(c), repo, build, link)
+		go notifyChatApproval(repo, build, link)
 		return
 	}
 
@@ -223,19 +457,20 @@ func PostHook(c *gin.Context) {
 		uri := fmt.Sprintf("%s/%s/%d", httputil.GetURL(c.Request), repo.FullName, build.Number)
 		err = remote_.Status(user, repo, build, uri)
 		if err != nil {
-			logrus.Errorf("error setting commit status for %s/%d", repo.FullName, build.Number)
+			log.Errorf("error setting commit status for %s/%d", repo.FullName, build.Number)
 		}
 	}()
 
 	b := builder{
-		Repo:  repo,
-		Curr:  build,
-		Last:  last,
-		Netrc: netrc,
-		Secs:  secs,
-		Regs:  regs,
-		Link:  httputil.GetURL(c.Request),
-		Yaml:  conf.Data,
+		Repo:       repo,
+		Curr:       build,
+		Last:       last,
+		Netrc:      netrc,
+		Secs:       secs,
+		Regs:       regs,
+		Link:       httputil.GetURL(c.Request),
+		Yaml:       conf.Data,
+		Directives: directives,
 	}
 	items, err := b.Build()
 	if err != nil {
@@ -246,12 +481,120 @@ func PostHook(c *gin.Context) {
 		store.UpdateBuild(c, build)
 		return
 	}
+	if len(items) == 0 && len(directives.Only) != 0 {
+		skipBuild(build, "Skipped because no pipeline matches the build directives in the commit message")
+		store.UpdateBuild(c, build)
+		return
+	}
+
+	createProcs(build, items)
+	err = store.FromContext(c).ProcCreate(build.Procs)
+	if err != nil {
+		log.Errorf("error persisting procs %s/%d: %s", repo.FullName, build.Number, err)
+	}
+	if err := store.UpdateBuild(c, build); err != nil {
+		log.Errorf("error persisting build labels %s/%d: %s", repo.FullName, build.Number, err)
+	}
+
+	publishBuild(c, repo, build)
+
+	span := traceSpan(c)
+	span.Tag("repo", repo.FullName)
+	span.Tag("build", strconv.Itoa(build.Number))
+	for _, item := range items {
+		item.Trace = span.Context()
+	}
+	queueBuild(store.FromContext(c), repo, items)
+}
+
+// skipBuild marks the build as skipped, with the reason the build is
+// skipped as the build error.
+func skipBuild(build *model.Build, reason string) {
+	build.Status = model.StatusSkipped
+	build.Started = time.Now().Unix()
+	build.Finished = build.Started
+	build.Error = reason
+}
+
+// cancelPrevious cancels the pending and running builds for the same branch,
+// or the same pull request, when the repository is configured to cancel
+// redundant builds.
+func cancelPrevious(s store.Store, repo *model.Repo, build *model.Build) {
+	switch {
+	case build.Event == model.EventPush && repo.CancelPush:
+	case build.Event == model.EventPull && repo.CancelPulls:
+	default:
+		return
+	}
+	builds, err := s.GetBuildActive(repo)
+	if err != nil {
+		logrus.Errorf("failure to list active builds for %s. %s", repo.FullName, err)
+		return
+	}
+	for _, prev := range builds {
+		if prev.Number >= build.Number || prev.Event != build.Event {
+			continue
+		}
+		if build.Event == model.EventPush && prev.Branch != build.Branch {
+			continue
+		}
+		if build.Event == model.EventPull && prev.Ref != build.Ref {
+			continue
+		}
+		logrus.Infof("cancelling build %s#%d superseded by build #%d", repo.FullName, prev.Number, build.Number)
+		if err := cancelBuild(s, prev); err != nil {
+			logrus.Errorf("failure to cancel build %s#%d. %s", repo.FullName, prev.Number, err)
+		}
+	}
+}
+
+// isFork returns true if the build is a pull request that originates
+// from a fork of the repository. The full path of the pull request clone
+// url is compared with the full path of the repository clone url, since
+// the full name of repositories in subgroups has more than two parts. A
+// pull request without a clone url is treated as a fork, since the origin
+// of the pull request is unknown.
+func isFork(repo *model.Repo, build *model.Build) bool {
+	if build.Event != model.EventPull {
+		return false
+	}
+	if build.Remote == "" {
+		return true
+	}
+	name := repo.FullName
+	if repo.Clone != "" {
+		name = clonePath(repo.Clone)
+	}
+	return !strings.EqualFold(clonePath(build.Remote), name)
+}
+
+// clonePath returns the path of the clone url, without the .git suffix.
+func clonePath(link string) string {
+	link = strings.TrimSuffix(strings.TrimSuffix(link, "/"), ".git")
+	if u, err := url.Parse(link); err == nil && u.Host != "" {
+		return strings.Trim(u.Path, "/")
+	}
+	// scp-like ssh urls, such as git@github.com:octocat/hello-world
+	if i := strings.Index(link, ":"); i != -1 {
+		return strings.Trim(link[i+1:], "/")
+	}
+	return strings.Trim(link, "/")
+}
 
+// createProcs appends the procs for each build pipeline, and the steps
+// of each pipeline, to the build. The labels of the pipelines and steps are
+// stored on the procs, and the labels of the pipelines on the build.
+func createProcs(build *model.Build, items []*buildItem) {
 	var pcounter = len(items)
 
+	build.Labels = map[string]string{}
 	for _, item := range items {
 		build.Procs = append(build.Procs, item.Proc)
 		item.Proc.BuildID = build.ID
+		item.Proc.Labels = item.Labels
+		for k, v := range item.Labels {
+			build.Labels[k] = v
+		}
 
 		for _, stage := range item.Config.Stages {
 			var gid int
@@ -267,19 +610,16 @@ func PostHook(c *gin.Context) {
 					PPID:    item.Proc.PID,
 					PGID:    gid,
 					State:   model.StatusPending,
+					Labels:  step.Labels,
 				}
 				build.Procs = append(build.Procs, proc)
 			}
 		}
 	}
-	err = store.FromContext(c).ProcCreate(build.Procs)
-	if err != nil {
-		logrus.Errorf("error persisting procs %s/%d: %s", repo.FullName, build.Number, err)
-	}
+}
 
-	//
-	// publish topic
-	//
+// publishBuild publishes the enqueued build to the event stream.
+func publishBuild(c context.Context, repo *model.Repo, build *model.Build) {
 	message := pubsub.Message{
 		Labels: map[string]string{
 			"repo":    repo.FullName,
@@ -295,32 +635,68 @@ func PostHook(c *gin.Context) {
 	})
 	// TODO remove global reference
 	Config.Services.Pubsub.Publish(c, "topic/events", message)
-	//
-	// end publish topic
-	//
 
+	sendWebhook(&model.WebhookPayload{
+		Event: model.WebhookBuildCreated,
+		Repo:  repo,
+		Build: &buildCopy,
+	})
+}
+
+// queueBuild pushes the build pipelines to the queue. Pipelines that
+// depend on other pipelines are held until their dependencies complete.
+func queueBuild(s store.Store, repo *model.Repo, items []*buildItem) {
 	for _, item := range items {
-		task := new(queue.Task)
-		task.ID = fmt.Sprint(item.Proc.ID)
-		task.Labels = map[string]string{}
-		task.Labels["platform"] = item.Platform
-		for k, v := range item.Labels {
-			task.Labels[k] = v
+		if len(item.DependsOn) != 0 {
+			holdBuildItem(s, repo, item)
+			continue
 		}
+		pushTask(newBuildTask(repo, item))
+	}
+}
 
-		task.Data, _ = json.Marshal(rpc.Pipeline{
-			ID:      fmt.Sprint(item.Proc.ID),
-			Config:  item.Config,
-			Timeout: b.Repo.Timeout,
-		})
-
-		Config.Services.Logs.Open(context.Background(), task.ID)
-		Config.Services.Queue.Push(context.Background(), task)
+// newBuildTask returns the queue task of the build pipeline.
+func newBuildTask(repo *model.Repo, item *buildItem) *queue.Task {
+	task := new(queue.Task)
+	task.ID = fmt.Sprint(item.Proc.ID)
+	task.Priority = item.Priority
+	task.Labels = map[string]string{}
+	task.Labels["platform"] = item.Platform
+	for k, v := range item.Labels {
+		task.Labels[k] = v
 	}
+	for k, v := range item.Node {
+		task.Labels[nodeLabelPrefix+k] = v
+	}
+	task.Labels["repo"] = repo.FullName
+	task.Labels["org"] = repo.Owner
+	task.Labels["build"] = fmt.Sprint(item.Proc.BuildID)
+	if repo.Throttle > 0 {
+		task.Labels["throttle"] = strconv.Itoa(repo.Throttle)
+	}
+
+	task.Data, _ = json.Marshal(rpc.Pipeline{
+		ID:      fmt.Sprint(item.Proc.ID),
+		Config:  item.Config,
+		Timeout: repo.Timeout,
+		Trace:   item.Trace,
+	})
+	return task
+}
+
+// pushTask opens the log stream of the task and pushes the task to the
+// queue.
+func pushTask(task *queue.Task) {
+	Config.Services.Logs.Open(context.Background(), task.ID)
+	Config.Services.Queue.Push(context.Background(), task)
 }
 
 // return the metadata from the cli context.
 func metadataFromStruct(repo *model.Repo, build, last *model.Build, proc *model.Proc, link string) frontend.Metadata {
+	var host string
+	if uri, err := url.Parse(link); err == nil {
+		host = uri.Host
+	}
 	return frontend.Metadata{
 		Repo: frontend.Repo{
 			Name:    repo.FullName,
@@ -349,6 +725,7 @@ func metadataFromStruct(repo *model.Repo, build, last *model.Build, proc *model.
 					Email:  build.Email,
 					Avatar: build.Avatar,
 				},
+				Changed: build.Changed,
 			},
 		},
 		Prev: frontend.Build{
@@ -379,6 +756,7 @@ func metadataFromStruct(repo *model.Repo, build, last *model.Build, proc *model.
 		},
 		Sys: frontend.System{
 			Name: "drone",
+			Host: host,
 			Link: link,
 			Arch: "linux/amd64",
 		},
@@ -394,18 +772,89 @@ type builder struct {
 	Regs  []*model.Registry
 	Link  string
 	Yaml  string
+
+	// Directives restricts the pipelines of the build to the pipelines
+	// named in the commit message, if not nil.
+	Directives *model.Directives
 }
 
 type buildItem struct {
-	Proc     *model.Proc
-	Platform string
-	Labels   map[string]string
-	Config   *backend.Config
+	Proc      *model.Proc
+	Platform  string
+	Labels    map[string]string
+	Node      map[string]string
+	DependsOn []string
+	Priority  int
+	Config    *backend.Config
+	Trace     string
 }
 
+// Build compiles the build pipelines. The yaml file may contain multiple
+// pipelines, one per yaml document, and each pipeline is expanded into a
+// pipeline per matrix axis.
 func (b *builder) Build() ([]*buildItem, error) {
+	docs := splitDocuments(b.Yaml)
+	if len(docs) == 0 {
+		docs = append(docs, b.Yaml)
+	}
+
+	var headers []*pipelineHeader
+	for _, doc := range docs {
+		header, err := parseHeader(doc)
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, header)
+	}
+	if err := validateDependencies(headers); err != nil {
+		return nil, err
+	}
+
+	// pipelines are excluded if the changed files do not match the path
+	// restrictions, or the commit message directives, and dependencies on
+	// excluded pipelines are ignored.
+	included := map[string]bool{}
+	for _, header := range headers {
+		if b.includes(header) {
+			included[header.Name] = true
+		}
+	}
+
+	var items []*buildItem
+	for i, doc := range docs {
+		header := headers[i]
+		if !b.includes(header) {
+			continue
+		}
+		var deps []string
+		for _, dep := range header.DependsOn {
+			if included[dep] {
+				deps = append(deps, dep)
+			}
+		}
+		header.DependsOn = deps
+
+		docItems, err := b.buildPipeline(doc, header, len(items))
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, docItems...)
+	}
+	return items, nil
+}
+
+// helper function returns true if the pipeline is included in the build.
+func (b *builder) includes(header *pipelineHeader) bool {
+	if b.Directives != nil && !b.Directives.Includes(header.Name) {
+		return false
+	}
+	return header.Paths.MatchPaths(b.Curr.Changed)
+}
 
-	axes, err := matrix.ParseString(b.Yaml)
+// buildPipeline compiles the pipeline for each matrix axis of the yaml
+// document. The process ids are numbered after the offset.
+func (b *builder) buildPipeline(doc string, header *pipelineHeader, offset int) ([]*buildItem, error) {
+	axes, err := matrix.ParseString(doc)
 	if err != nil {
 		return nil, err
 	}
@@ -417,8 +866,9 @@ func (b *builder) Build() ([]*buildItem, error) {
 	for i, axis := range axes {
 		proc := &model.Proc{
 			BuildID: b.Curr.ID,
-			PID:     i + 1,
-			PGID:    i + 1,
+			PID:     offset + i + 1,
+			PGID:    offset + i + 1,
+			Name:    header.Name,
 			State:   model.StatusPending,
 			Environ: axis,
 		}
@@ -428,13 +878,17 @@ func (b *builder) Build() ([]*buildItem, error) {
 		for k, v := range metadata.EnvironDrone() {
 			environ[k] = v
 		}
+		for k, v := range b.Curr.Params {
+			environ[k] = v
+		}
 		for k, v := range axis {
 			environ[k] = v
 		}
 
 		var secrets []compiler.Secret
+		strip := stripSecrets(b.Repo, b.Curr)
 		for _, sec := range b.Secs {
-			if !sec.Match(b.Curr.Event) {
+			if !sec.Match(b.Curr.Event) || !sec.MatchBranch(b.Curr.Branch) || (strip && !sec.SkipVerify) {
 				continue
 			}
 			secrets = append(secrets, compiler.Secret{
@@ -444,7 +898,7 @@ func (b *builder) Build() ([]*buildItem, error) {
 			})
 		}
 
-		y := b.Yaml
+		y := doc
 		s, err := envsubst.Eval(y, func(name string) string {
 			return environ[name]
 		})
@@ -457,18 +911,34 @@ func (b *builder) Build() ([]*buildItem, error) {
 		if err != nil {
 			return nil, err
 		}
-		metadata.Sys.Arch = parsed.Platform
+		metadata.Sys.Arch = parsed.Platform.String()
 		if metadata.Sys.Arch == "" {
 			metadata.Sys.Arch = "linux/amd64"
 		}
 
 		lerr := linter.New(
 			linter.WithTrusted(b.Repo.IsTrusted),
+			linter.WithHostPaths(Config.Pipeline.VolumePaths...),
+			linter.WithNamedVolumes(Config.Pipeline.VolumeNames...),
+			linter.WithMaxResources(Config.Pipeline.MaxCPU, Config.Pipeline.MaxMemory),
 		).Lint(parsed)
 		if lerr != nil {
 			return nil, lerr
 		}
 
+		if Config.Services.Validator != nil {
+			if err := Config.Services.Validator.ConfigValidate(b.Repo, b.Curr, []byte(y)); err != nil {
+				return nil, err
+			}
+		}
+
+		if Config.Services.Resolver != nil && !strip {
+			secrets, err = resolveSecrets(b.Repo, b.Curr, parsed, secrets)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		var registries []compiler.Registry
 		for _, reg := range b.Regs {
 			registries = append(registries, compiler.Registry{
@@ -484,6 +954,7 @@ func (b *builder) Build() ([]*buildItem, error) {
 			compiler.WithEscalated(Config.Pipeline.Privileged...),
 			compiler.WithVolumes(Config.Pipeline.Volumes...),
 			compiler.WithNetworks(Config.Pipeline.Networks...),
+			compiler.WithCache(cacheConfig(b.Repo, b.Curr)),
 			compiler.WithLocal(false),
 			compiler.WithOption(
 				compiler.WithNetrc(
@@ -522,10 +993,13 @@ func (b *builder) Build() ([]*buildItem, error) {
 		// }
 
 		item := &buildItem{
-			Proc:     proc,
-			Config:   ir,
-			Labels:   parsed.Labels,
-			Platform: metadata.Sys.Arch,
+			Proc:      proc,
+			Config:    ir,
+			Labels:    parsed.Labels,
+			Node:      parsed.Node,
+			DependsOn: header.DependsOn,
+			Priority:  buildPriority(b.Repo, b.Curr),
+			Platform:  metadata.Sys.Arch,
 		}
 		if item.Labels == nil {
 			item.Labels = map[string]string{}
@@ -536,6 +1010,160 @@ func (b *builder) Build() ([]*buildItem, error) {
 	return items, nil
 }
 
+// resolveSecrets resolves the secrets requested by each pipeline step that
+// are not stored by the server using the secret extension. The resolved
+// secrets are only exposed to the images for which they were resolved.
+func resolveSecrets(repo *model.Repo, build *model.Build, parsed *yaml.Config, secrets []compiler.Secret) ([]compiler.Secret, error) {
+	stored := map[string]bool{}
+	for _, secret := range secrets {
+		stored[strings.ToLower(secret.Name)] = true
+	}
+	resolved := map[string]int{}
+
+	var containers []*yaml.Container
+	containers = append(containers, parsed.Pipeline.Containers...)
+	containers = append(containers, parsed.Finally.Containers...)
+	containers = append(containers, parsed.Services.Containers...)
+	for _, container := range containers {
+		for _, requested := range container.Secrets.Secrets {
+			name := strings.ToLower(requested.Source)
+			if stored[name] {
+				continue
+			}
+			secret, err := Config.Services.Resolver.SecretResolve(repo, build, requested.Source, container.Image)
+			if err != nil {
+				return nil, fmt.Errorf("Error resolving secret %q. %s", requested.Source, err)
+			}
+			if secret == nil {
+				continue
+			}
+			// the compiler exposes a single value for each secret name,
+			// so a different value resolved for another image is ignored.
+			if i, ok := resolved[name]; ok {
+				if secrets[i].Value == secret.Value {
+					secrets[i].Match = append(secrets[i].Match, container.Image)
+				}
+				continue
+			}
+			resolved[name] = len(secrets)
+			secrets = append(secrets, compiler.Secret{
+				Name:  requested.Source,
+				Value: secret.Value,
+				Match: []string{container.Image},
+			})
+		}
+	}
+	return secrets, nil
+}
+
+// fetchConfig fetches the pipeline configuration from the repository and
+// resolves it using the configuration service, which may generate the
+// configuration when the repository does not contain one. The jsonnet and
+// starlark configurations of pull requests from forks are not evaluated
+// until the build is approved, and errConfigDeferred is returned instead.
+func fetchConfig(r remote.Remote, user *model.User, repo *model.Repo, build *model.Build, approved bool) ([]byte, error) {
+	path, data, ferr := fetchConfigFile(r, user, repo, build)
+	if ferr == nil && !approved && evaluated(path) && isFork(repo, build) {
+		return nil, errConfigDeferred
+	}
+	if ferr == nil {
+		var err error
+		data, err = evalConfig(path, repo, build, data)
+		if err != nil {
+			return nil, err
+		}
+		data, err = expandTemplate(repo, data)
+		if err != nil {
+			return nil, err
+		}
+		data, err = expandIncludes(r, user, repo, build, data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if ferr != nil {
+		data = nil
+	}
+	out, err := Config.Services.Configs.ConfigResolve(repo, build, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 && ferr != nil {
+		return nil, ferr
+	}
+	return out, nil
+}
+
+// persistConfig stores the pipeline configuration, unless a configuration
+// with the same contents is already stored for the repository.
+func persistConfig(repo *model.Repo, data []byte) (*model.Config, error) {
+	sha := shasum(data)
+	conf, err := Config.Storage.Config.ConfigFind(repo, sha)
+	if err == nil {
+		return conf, nil
+	}
+	conf = &model.Config{
+		RepoID: repo.ID,
+		Data:   string(data),
+		Hash:   sha,
+	}
+	return conf, Config.Storage.Config.ConfigCreate(conf)
+}
+
+// fetchConfigFile returns the path and contents of the configuration file
+// of the repository. The jsonnet or starlark configuration is used if the
+// repository uses the default configuration path, and the yaml
+// configuration does not exist.
+func fetchConfigFile(r remote.Remote, user *model.User, repo *model.Repo, build *model.Build) (string, []byte, error) {
+	path := repo.Config
+	data, err := r.File(user, repo, build, path)
+	if err != nil && path == defaultConfigPath {
+		for _, alt := range []string{defaultJsonnetPath, defaultStarlarkPath} {
+			if !evalEnabled(alt) {
+				continue
+			}
+			if adata, aerr := r.File(user, repo, build, alt); aerr == nil {
+				return alt, adata, nil
+			}
+		}
+	}
+	return path, data, err
+}
+
+// evalConfig evaluates the jsonnet and starlark configurations, and returns
+// the generated yaml configuration. Other configurations are returned as-is.
+func evalConfig(path string, repo *model.Repo, build *model.Build, data []byte) ([]byte, error) {
+	switch {
+	case strings.HasSuffix(path, ".jsonnet") && !evalEnabled(path):
+		return nil, errJsonnetDisabled
+	case strings.HasSuffix(path, ".jsonnet"):
+		return config.EvalJsonnet(repo, build, data)
+	case strings.HasSuffix(path, ".star") && !evalEnabled(path):
+		return nil, errStarlarkDisabled
+	case strings.HasSuffix(path, ".star"):
+		return config.EvalStarlark(repo, build, data)
+	}
+	return data, nil
+}
+
+// evaluated returns true if the configuration of the path is evaluated to
+// generate the yaml configuration.
+func evaluated(path string) bool {
+	return strings.HasSuffix(path, ".jsonnet") || strings.HasSuffix(path, ".star")
+}
+
+// evalEnabled returns true if the configuration format of the path is
+// enabled on the server.
+func evalEnabled(path string) bool {
+	switch {
+	case strings.HasSuffix(path, ".jsonnet"):
+		return Config.Pipeline.Jsonnet
+	case strings.HasSuffix(path, ".star"):
+		return Config.Pipeline.Starlark
+	}
+	return false
+}
+
 func shasum(raw []byte) string {
 	sum := sha256.Sum256(raw)
 	return fmt.Sprintf("%x", sum)
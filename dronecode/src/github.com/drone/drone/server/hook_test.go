@@ -0,0 +1,196 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/plugins/config"
+	"github.com/drone/drone/remote"
+	"github.com/drone/drone/shared/token"
+	"github.com/drone/drone/store/datastore"
+
+	"github.com/gin-gonic/gin"
+)
+
+// verifierRemote is a remote that rejects the signature of every hook.
+type verifierRemote struct {
+	remote.Remote
+}
+
+func (r *verifierRemote) Verify(req *http.Request, payload []byte, secret string) error {
+	return remote.ErrSignature
+}
+
+func TestVerifyHook(t *testing.T) {
+	defer func(unsigned bool) {
+		Config.Server.HookUnsigned = unsigned
+	}(Config.Server.HookUnsigned)
+
+	repo := &model.Repo{FullName: "octocat/hello-world", Hash: "secret"}
+	sig, err := token.New(token.HookToken, repo.FullName).Sign(repo.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.POST("/hook", func(c *gin.Context) {
+		remote.ToContext(c, new(verifierRemote))
+		if verifyHook(c, new(model.Hook), repo) {
+			c.String(200, "ok")
+		}
+	})
+
+	tests := []struct {
+		signed   bool
+		unsigned bool
+		status   int
+	}{
+		{signed: false, status: 403},
+		{signed: true, status: 403},
+		{signed: false, unsigned: true, status: 200},
+	}
+	for _, test := range tests {
+		repo.HookSigned = test.signed
+		Config.Server.HookUnsigned = test.unsigned
+		req, _ := http.NewRequest("POST", "/hook?access_token="+sig, nil)
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		if w.Code != test.status {
+			t.Errorf("Want status %d verifying hook signed %v, unsigned hooks accepted %v, got %d", test.status, test.signed, test.unsigned, w.Code)
+		}
+	}
+}
+
+func TestVerifyHookKind(t *testing.T) {
+	repo := &model.Repo{FullName: "octocat/hello-world", Hash: "secret"}
+
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.POST("/hook", func(c *gin.Context) {
+		remote.ToContext(c, struct{ remote.Remote }{})
+		if verifyHook(c, new(model.Hook), repo) {
+			c.String(200, "ok")
+		}
+	})
+
+	tests := []struct {
+		kind   string
+		status int
+	}{
+		{kind: token.HookToken, status: 200},
+		{kind: token.CacheToken, status: 403},
+		{kind: token.BadgeToken, status: 403},
+	}
+	for _, test := range tests {
+		sig, err := token.New(test.kind, repo.FullName).Sign(repo.Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest("POST", "/hook?access_token="+sig, nil)
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		if w.Code != test.status {
+			t.Errorf("Want status %d for a %s token, got %d", test.status, test.kind, w.Code)
+		}
+	}
+}
+
+func TestIsFork(t *testing.T) {
+	tests := []struct {
+		repo   model.Repo
+		remote string
+		fork   bool
+	}{
+		{model.Repo{FullName: "octocat/hello-world"}, "https://github.com/octocat/hello-world.git", false},
+		{model.Repo{FullName: "octocat/hello-world"}, "https://github.com/OctoCat/Hello-World", false},
+		{model.Repo{FullName: "octocat/hello-world"}, "git@github.com:octocat/hello-world.git", false},
+		{model.Repo{FullName: "octocat/hello-world"}, "https://github.com/spaceghost/hello-world.git", true},
+		{model.Repo{FullName: "diaspora/core/client"}, "https://gitlab.com/diaspora/core/client.git", false},
+		{model.Repo{FullName: "diaspora/core/client"}, "https://gitlab.com/spaceghost/core/client.git", true},
+		{model.Repo{FullName: "core/client"}, "https://gitlab.com/diaspora/core/client.git", true},
+		{
+			model.Repo{FullName: "diaspora/client", Clone: "https://example.com/gitlab/diaspora/client.git"},
+			"https://example.com/gitlab/diaspora/client.git",
+			false,
+		},
+		{
+			model.Repo{FullName: "diaspora/client", Clone: "https://example.com/gitlab/diaspora/client.git"},
+			"https://example.com/gitlab/spaceghost/diaspora/client.git",
+			true,
+		},
+	}
+	for _, test := range tests {
+		build := &model.Build{Event: model.EventPull, Remote: test.remote}
+		if got := isFork(&test.repo, build); got != test.fork {
+			t.Errorf("Want pull request from %s to %s fork %v, got %v", test.remote, test.repo.FullName, test.fork, got)
+		}
+	}
+	if !isFork(&model.Repo{FullName: "octocat/hello-world"}, &model.Build{Event: model.EventPull}) {
+		t.Errorf("Want pull requests from an unknown repository treated as a fork")
+	}
+	if isFork(&model.Repo{FullName: "octocat/hello-world"}, &model.Build{Event: model.EventPush, Remote: "https://github.com/spaceghost/hello-world.git"}) {
+		t.Errorf("Want push builds not from a fork")
+	}
+}
+
+func TestFetchConfigDeferred(t *testing.T) {
+	defer func(jsonnet bool, configs model.ConfigService, store model.ConfigStore) {
+		Config.Pipeline.Jsonnet = jsonnet
+		Config.Services.Configs = configs
+		Config.Storage.Config = store
+	}(Config.Pipeline.Jsonnet, Config.Services.Configs, Config.Storage.Config)
+
+	Config.Pipeline.Jsonnet = true
+	Config.Services.Configs = config.New()
+	Config.Storage.Config = datastore.New("sqlite3", ":memory:")
+
+	r := &includeRemote{files: map[string]string{
+		"octocat/hello-world@abc:.drone.jsonnet": `{pipeline: {build: {image: "golang"}}}`,
+	}}
+	repo := &model.Repo{ID: 1, FullName: "octocat/hello-world", Config: ".drone.yml"}
+	want := "pipeline:\n  build:\n    image: golang\n"
+
+	push := &model.Build{Commit: "abc", Event: model.EventPush}
+	out, err := fetchConfig(r, new(model.User), repo, push, false)
+	if err != nil || string(out) != want {
+		t.Errorf("Want the jsonnet of push builds evaluated, got %q, %v", out, err)
+	}
+
+	fork := &model.Build{Commit: "abc", Event: model.EventPull, Remote: "https://github.com/spaceghost/hello-world.git"}
+	if _, err := fetchConfig(r, new(model.User), repo, fork, false); err != errConfigDeferred {
+		t.Errorf("Want the jsonnet of pull requests from forks deferred, got %v", err)
+	}
+
+	conf, err := loadConfig(r, new(model.User), repo, fork)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Data != want {
+		t.Errorf("Want the jsonnet evaluated after approval, got %q", conf.Data)
+	}
+	if fork.ConfigID == 0 || fork.ConfigID != conf.ID {
+		t.Errorf("Want the evaluated configuration stored with the build, got config %d", fork.ConfigID)
+	}
+}
+
+func TestMetadataHost(t *testing.T) {
+	repo := &model.Repo{FullName: "octocat/hello-world"}
+	build := &model.Build{}
+	tests := []struct {
+		link string
+		host string
+	}{
+		{link: "https://ci.example.com", host: "ci.example.com"},
+		{link: "http://localhost:8000/drone", host: "localhost:8000"},
+		{link: "", host: ""},
+	}
+	for _, test := range tests {
+		metadata := metadataFromStruct(repo, build, &model.Build{}, &model.Proc{}, test.link)
+		if metadata.Sys.Host != test.host {
+			t.Errorf("Want instance %q for link %q, got %q", test.host, test.link, metadata.Sys.Host)
+		}
+	}
+}
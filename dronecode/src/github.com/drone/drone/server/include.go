@@ -0,0 +1,267 @@
+package server
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/remote"
+
+	"gopkg.in/yaml.v2"
+)
+
+// maxIncludeDepth limits the nesting of included yaml fragments, and
+// maxIncludes limits the number of fragments included by a configuration.
+const (
+	maxIncludeDepth = 5
+	maxIncludes     = 50
+)
+
+// include defines a yaml fragment included by a pipeline configuration. The
+// fragment is a file of the same repository, or of a config repository
+// allowed by the server configuration, pinned to the ref.
+type include struct {
+	Repo string `yaml:"repo"`
+	Ref  string `yaml:"ref"`
+	File string `yaml:"file"`
+}
+
+// UnmarshalYAML implements the Unmarshaller interface. The include may be
+// the path of a file of the same repository.
+func (i *include) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var file string
+	if err := unmarshal(&file); err == nil {
+		i.File = file
+		return nil
+	}
+	type plain include
+	return unmarshal((*plain)(i))
+}
+
+// includeSource defines the repository and ref of a yaml document. The
+// build is set if the document is from the build commit.
+type includeSource struct {
+	repo  *model.Repo
+	ref   string
+	build *model.Build
+}
+
+// helper function returns the key of the file of the source, which is
+// used to detect include cycles.
+func (s *includeSource) key(file string) string {
+	return fmt.Sprintf("%s@%s:%s", s.repo.FullName, s.ref, file)
+}
+
+type includer struct {
+	remote remote.Remote
+	user   *model.User
+	repos  []string // name patterns of the allowed config repositories
+	fork   bool     // build is a pull request from a fork
+	count  int
+}
+
+// expandIncludes inlines the yaml fragments included by the documents of
+// the pipeline configuration. The document is merged into the included
+// fragments, such that the document extends or overrides the steps and
+// settings of the fragments. Documents without includes are unchanged.
+func expandIncludes(r remote.Remote, user *model.User, repo *model.Repo, build *model.Build, data []byte) ([]byte, error) {
+	docs := splitDocuments(string(data))
+	inc := &includer{
+		remote: r,
+		user:   user,
+		repos:  Config.Pipeline.IncludeRepos,
+		fork:   isFork(repo, build),
+	}
+	src := &includeSource{repo: repo, ref: build.Commit, build: build}
+
+	var changed bool
+	for i, doc := range docs {
+		parsed := yaml.MapSlice{}
+		if err := yaml.Unmarshal([]byte(doc), &parsed); err != nil || !hasInclude(parsed) {
+			continue
+		}
+		parsed, err := inc.resolve(src, parsed, []string{src.key(repo.Config)})
+		if err != nil {
+			return nil, err
+		}
+		out, err := yaml.Marshal(parsed)
+		if err != nil {
+			return nil, err
+		}
+		docs[i], changed = string(out), true
+	}
+	if !changed {
+		return data, nil
+	}
+	return []byte(strings.Join(docs, "\n---\n")), nil
+}
+
+// usesIncludes returns true if a document of the pipeline configuration
+// includes yaml fragments.
+func usesIncludes(data []byte) bool {
+	for _, doc := range splitDocuments(string(data)) {
+		parsed := yaml.MapSlice{}
+		if err := yaml.Unmarshal([]byte(doc), &parsed); err == nil && hasInclude(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// helper function inlines the fragments included by the yaml document, and
+// the fragments included by the fragments.
+func (inc *includer) resolve(src *includeSource, doc yaml.MapSlice, chain []string) (yaml.MapSlice, error) {
+	var list []*include
+	var rest yaml.MapSlice
+	for _, item := range doc {
+		if item.Key != "include" {
+			rest = append(rest, item)
+			continue
+		}
+		var err error
+		if list, err = parseIncludes(item.Value); err != nil {
+			return nil, err
+		}
+	}
+	if len(list) != 0 && len(chain) > maxIncludeDepth {
+		return nil, fmt.Errorf("Include of %s exceeds the depth of %d includes", chain[len(chain)-1], maxIncludeDepth)
+	}
+
+	var base yaml.MapSlice
+	for _, i := range list {
+		next, err := inc.source(src, i)
+		if err != nil {
+			return nil, err
+		}
+		file := path.Clean(strings.TrimPrefix(i.File, "/"))
+		key := next.key(file)
+		for _, prev := range chain {
+			if prev == key {
+				return nil, fmt.Errorf("Include of %s creates a cycle", key)
+			}
+		}
+		if inc.count++; inc.count > maxIncludes {
+			return nil, fmt.Errorf("Configuration exceeds the limit of %d includes", maxIncludes)
+		}
+
+		var data []byte
+		if next.build != nil {
+			data, err = inc.remote.File(inc.user, next.repo, next.build, file)
+		} else {
+			data, err = inc.remote.FileRef(inc.user, next.repo, next.ref, file)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Error getting include %s. %s", key, err)
+		}
+		fragment := yaml.MapSlice{}
+		if err := yaml.Unmarshal(data, &fragment); err != nil {
+			return nil, fmt.Errorf("Error parsing include %s. %s", key, err)
+		}
+		fragment, err = inc.resolve(next, fragment, append(chain, key))
+		if err != nil {
+			return nil, err
+		}
+		base = mergeYaml(base, fragment)
+	}
+	return mergeYaml(base, rest), nil
+}
+
+// helper function returns the source of the included fragment. Fragments
+// of other repositories must be pinned to a ref, and are fetched with the
+// credentials of the repository owner. Since the fragments are stored with
+// the build configuration, other repositories must be config repositories
+// allowed by the server, and cannot be included by pull requests from
+// forks.
+func (inc *includer) source(src *includeSource, i *include) (*includeSource, error) {
+	if i.File == "" {
+		return nil, fmt.Errorf("Invalid include, expected a file")
+	}
+	if i.Repo == "" || i.Repo == src.repo.FullName {
+		return src, nil
+	}
+	parts := strings.Split(i.Repo, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("Invalid include repository %q", i.Repo)
+	}
+	if i.Ref == "" {
+		return nil, fmt.Errorf("Invalid include of %s, expected a ref", i.Repo)
+	}
+	if inc.fork {
+		return nil, fmt.Errorf("Include of %s is not allowed for pull requests from forks", i.Repo)
+	}
+	if !inc.allowRepo(i.Repo) {
+		return nil, fmt.Errorf("Include of %s is not allowed, expected a config repository", i.Repo)
+	}
+	repo, err := inc.remote.Repo(inc.user, parts[0], parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("Error getting include repository %s. %s", i.Repo, err)
+	}
+	return &includeSource{repo: repo, ref: i.Ref}, nil
+}
+
+// helper function returns true if the repository matches a name pattern of
+// the allowed config repositories.
+func (inc *includer) allowRepo(name string) bool {
+	for _, pattern := range inc.repos {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// helper function parses the include, or list of includes.
+func parseIncludes(v interface{}) ([]*include, error) {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var list []*include
+	if err := yaml.Unmarshal(data, &list); err == nil {
+		return list, nil
+	}
+	i := new(include)
+	if err := yaml.Unmarshal(data, i); err != nil {
+		return nil, fmt.Errorf("Invalid include. %s", err)
+	}
+	return []*include{i}, nil
+}
+
+// helper function returns true if the yaml document includes fragments.
+func hasInclude(doc yaml.MapSlice) bool {
+	for _, item := range doc {
+		if item.Key == "include" {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeYaml merges the yaml mapping into the base mapping. Nested mappings
+// are merged, and other values replace the base values. The order of the
+// base keys is preserved, followed by the new keys.
+func mergeYaml(base, over yaml.MapSlice) yaml.MapSlice {
+	out := append(yaml.MapSlice{}, base...)
+	for _, item := range over {
+		i := -1
+		for j := range out {
+			if out[j].Key == item.Key {
+				i = j
+				break
+			}
+		}
+		if i == -1 {
+			out = append(out, item)
+			continue
+		}
+		prev, ok1 := out[i].Value.(yaml.MapSlice)
+		next, ok2 := item.Value.(yaml.MapSlice)
+		if ok1 && ok2 {
+			out[i].Value = mergeYaml(prev, next)
+		} else {
+			out[i].Value = item.Value
+		}
+	}
+	return out
+}
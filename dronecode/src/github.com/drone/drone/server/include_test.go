@@ -0,0 +1,206 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/remote"
+
+	"gopkg.in/yaml.v2"
+)
+
+// includeRemote serves the files of the build commit, and the files of
+// other repositories by full name, ref and path.
+type includeRemote struct {
+	remote.Remote
+
+	files map[string]string
+}
+
+func (r *includeRemote) File(u *model.User, repo *model.Repo, b *model.Build, f string) ([]byte, error) {
+	return r.FileRef(u, repo, b.Commit, f)
+}
+
+func (r *includeRemote) FileRef(u *model.User, repo *model.Repo, ref, f string) ([]byte, error) {
+	data, ok := r.files[repo.FullName+"@"+ref+":"+f]
+	if !ok {
+		return nil, fmt.Errorf("file not found")
+	}
+	return []byte(data), nil
+}
+
+func (r *includeRemote) Repo(u *model.User, owner, name string) (*model.Repo, error) {
+	return &model.Repo{Owner: owner, Name: name, FullName: owner + "/" + name}, nil
+}
+
+func testIncludes(files map[string]string, data string) (string, error) {
+	repo := &model.Repo{Owner: "octocat", Name: "hello-world", FullName: "octocat/hello-world", Config: ".drone.yml"}
+	build := &model.Build{Commit: "abc", Event: model.EventPush}
+	out, err := expandIncludes(&includeRemote{files: files}, new(model.User), repo, build, []byte(data))
+	return string(out), err
+}
+
+func TestExpandIncludesMerge(t *testing.T) {
+	files := map[string]string{
+		"octocat/hello-world@abc:ci/base.yml": "pipeline:\n  build:\n    image: golang\n    commands: [go build]\n  test:\n    image: golang\n",
+	}
+	out, err := testIncludes(files, "include: ci/base.yml\npipeline:\n  test:\n    image: golang:1.9\n  lint:\n    image: golint\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "pipeline:\n  build:\n    image: golang\n    commands:\n    - go build\n  test:\n    image: golang:1.9\n  lint:\n    image: golint\n"
+	if out != want {
+		t.Errorf("Want merged yaml %q, got %q", want, out)
+	}
+}
+
+func TestExpandIncludesUnchanged(t *testing.T) {
+	data := "pipeline:\n  build:\n    image: golang\n"
+	out, err := testIncludes(nil, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != data {
+		t.Errorf("Want unchanged yaml %q, got %q", data, out)
+	}
+}
+
+func TestExpandIncludesOrder(t *testing.T) {
+	files := map[string]string{
+		"octocat/hello-world@abc:a.yml": "pipeline:\n  build:\n    image: a\n",
+		"octocat/hello-world@abc:b.yml": "pipeline:\n  build:\n    image: b\n",
+	}
+	out, err := testIncludes(files, "include: [a.yml, b.yml]\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "pipeline:\n  build:\n    image: b\n"; out != want {
+		t.Errorf("Want later include to override %q, got %q", want, out)
+	}
+}
+
+func TestExpandIncludesCycle(t *testing.T) {
+	files := map[string]string{
+		"octocat/hello-world@abc:a.yml": "include: b.yml\n",
+		"octocat/hello-world@abc:b.yml": "include: /a.yml\n",
+	}
+	_, err := testIncludes(files, "include: a.yml\n")
+	if err == nil || !strings.Contains(err.Error(), "creates a cycle") {
+		t.Errorf("Want cycle error, got %v", err)
+	}
+
+	files = map[string]string{
+		"octocat/hello-world@abc:a.yml": "include: .drone.yml\n",
+	}
+	_, err = testIncludes(files, "include: a.yml\n")
+	if err == nil || !strings.Contains(err.Error(), "creates a cycle") {
+		t.Errorf("Want cycle error including the configuration, got %v", err)
+	}
+}
+
+func TestExpandIncludesDepth(t *testing.T) {
+	files := map[string]string{}
+	for i := 0; i < maxIncludeDepth+1; i++ {
+		files[fmt.Sprintf("octocat/hello-world@abc:%d.yml", i)] = fmt.Sprintf("include: %d.yml\n", i+1)
+	}
+	files[fmt.Sprintf("octocat/hello-world@abc:%d.yml", maxIncludeDepth+1)] = "pipeline: {}\n"
+	_, err := testIncludes(files, "include: 0.yml\n")
+	if err == nil || !strings.Contains(err.Error(), "exceeds the depth") {
+		t.Errorf("Want depth error, got %v", err)
+	}
+
+	delete(files, fmt.Sprintf("octocat/hello-world@abc:%d.yml", maxIncludeDepth-1))
+	files[fmt.Sprintf("octocat/hello-world@abc:%d.yml", maxIncludeDepth-1)] = "pipeline: {}\n"
+	if _, err := testIncludes(files, "include: 0.yml\n"); err != nil {
+		t.Errorf("Want includes within the depth limit, got %v", err)
+	}
+}
+
+func TestExpandIncludesCount(t *testing.T) {
+	files := map[string]string{}
+	var list []string
+	for i := 0; i < maxIncludes+1; i++ {
+		name := fmt.Sprintf("%d.yml", i)
+		files["octocat/hello-world@abc:"+name] = "pipeline: {}\n"
+		list = append(list, name)
+	}
+	_, err := testIncludes(files, "include: ["+strings.Join(list, ", ")+"]\n")
+	if err == nil || !strings.Contains(err.Error(), "exceeds the limit") {
+		t.Errorf("Want count error, got %v", err)
+	}
+	_, err = testIncludes(files, "include: ["+strings.Join(list[:maxIncludes], ", ")+"]\n")
+	if err != nil {
+		t.Errorf("Want includes within the count limit, got %v", err)
+	}
+}
+
+func TestParseIncludes(t *testing.T) {
+	tests := []struct {
+		data string
+		want []include
+	}{
+		{"ci/base.yml", []include{{File: "ci/base.yml"}}},
+		{"{repo: octocat/config, ref: v1, file: base.yml}", []include{{Repo: "octocat/config", Ref: "v1", File: "base.yml"}}},
+		{"[a.yml, {file: b.yml}]", []include{{File: "a.yml"}, {File: "b.yml"}}},
+	}
+	for _, test := range tests {
+		list, err := parseIncludes(mustUnmarshalYaml(t, test.data))
+		if err != nil {
+			t.Errorf("Want include %s parsed, got %s", test.data, err)
+			continue
+		}
+		if len(list) != len(test.want) {
+			t.Errorf("Want %d includes of %s, got %d", len(test.want), test.data, len(list))
+			continue
+		}
+		for i, got := range list {
+			if *got != test.want[i] {
+				t.Errorf("Want include %v of %s, got %v", test.want[i], test.data, *got)
+			}
+		}
+	}
+	if _, err := parseIncludes(mustUnmarshalYaml(t, "{file: [a.yml]}")); err == nil {
+		t.Errorf("Want invalid include error")
+	}
+}
+
+func TestExpandIncludesRepos(t *testing.T) {
+	defer func(repos []string) {
+		Config.Pipeline.IncludeRepos = repos
+	}(Config.Pipeline.IncludeRepos)
+
+	files := map[string]string{
+		"octocat/config@v1:base.yml": "pipeline:\n  build:\n    image: golang\n",
+	}
+	data := "include: {repo: octocat/config, ref: v1, file: base.yml}\n"
+
+	Config.Pipeline.IncludeRepos = nil
+	if _, err := testIncludes(files, data); err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Errorf("Want error including a repository that is not allowed, got %v", err)
+	}
+
+	Config.Pipeline.IncludeRepos = []string{"octocat/*"}
+	if _, err := testIncludes(files, data); err != nil {
+		t.Errorf("Want include of an allowed repository, got %v", err)
+	}
+	if _, err := testIncludes(files, "include: {repo: octocat/config, file: base.yml}\n"); err == nil {
+		t.Errorf("Want error including a repository without a ref")
+	}
+
+	repo := &model.Repo{Owner: "octocat", Name: "hello-world", FullName: "octocat/hello-world", Config: ".drone.yml"}
+	build := &model.Build{Commit: "abc", Event: model.EventPull, Remote: "https://github.com/spaceghost/hello-world.git"}
+	_, err := expandIncludes(&includeRemote{files: files}, new(model.User), repo, build, []byte(data))
+	if err == nil || !strings.Contains(err.Error(), "forks") {
+		t.Errorf("Want error including a repository from a fork, got %v", err)
+	}
+}
+
+func mustUnmarshalYaml(t *testing.T, data string) interface{} {
+	var v interface{}
+	if err := yaml.Unmarshal([]byte(data), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
@@ -8,7 +8,6 @@ import (
 	"github.com/drone/drone/model"
 	"github.com/drone/drone/remote"
 	"github.com/drone/drone/shared/httputil"
-	"github.com/drone/drone/shared/token"
 	"github.com/drone/drone/store"
 	"github.com/gorilla/securecookie"
 
@@ -22,10 +21,20 @@ func GetLogin(c *gin.Context) {
 	// cannot, however, remember why, so need to revisit this line.
 	c.Writer.Header().Del("Content-Type")
 
+	// users authenticate with the openid connect provider before the
+	// remote login, if configured.
+	var identity *oidcIdentity
+	if Config.OIDC.Provider != nil {
+		if identity = getIdentity(c.Request); identity == nil {
+			c.Redirect(303, httputil.GetPrefix(c.Request)+"/login/oidc")
+			return
+		}
+	}
+
 	tmpuser, err := remote.Login(c, c.Writer, c.Request)
 	if err != nil {
 		logrus.Errorf("cannot authenticate user. %s", err)
-		c.Redirect(303, "/login?error=oauth_error")
+		c.Redirect(303, httputil.GetPrefix(c.Request)+"/login?error=oauth_error")
 		return
 	}
 	// this will happen when the user is redirected by the remote provider as
@@ -33,6 +42,16 @@ func GetLogin(c *gin.Context) {
 	if tmpuser == nil {
 		return
 	}
+	// the openid connect identity is linked to the remote account.
+	if identity != nil {
+		if err := checkIdentity(store.FromContext(c), tmpuser.Login, identity); err != nil {
+			logrus.Errorf("cannot link openid connect identity. %s", err)
+			c.Redirect(303, httputil.GetPrefix(c.Request)+"/login?error=access_denied")
+			return
+		}
+		tmpuser.Subject = identity.Subject
+		tmpuser.GroupAdmin = tmpuser.GroupAdmin || identity.Admin
+	}
 	config := ToConfig(c)
 
 	// get the user from the database
@@ -42,7 +61,7 @@ func GetLogin(c *gin.Context) {
 		// if self-registration is disabled we should return a not authorized error
 		if !config.Open && !config.IsAdmin(tmpuser) {
 			logrus.Errorf("cannot register %s. registration closed", tmpuser.Login)
-			c.Redirect(303, "/login?error=access_denied")
+			c.Redirect(303, httputil.GetPrefix(c.Request)+"/login?error=access_denied")
 			return
 		}
 
@@ -52,18 +71,21 @@ func GetLogin(c *gin.Context) {
 			teams, terr := remote.Teams(c, tmpuser)
 			if terr != nil || config.IsMember(teams) == false {
 				logrus.Errorf("cannot verify team membership for %s.", u.Login)
-				c.Redirect(303, "/login?error=access_denied")
+				c.Redirect(303, httputil.GetPrefix(c.Request)+"/login?error=access_denied")
 				return
 			}
 		}
 
 		// create the user account
 		u = &model.User{
-			Login:  tmpuser.Login,
-			Token:  tmpuser.Token,
-			Secret: tmpuser.Secret,
-			Email:  tmpuser.Email,
-			Avatar: tmpuser.Avatar,
+			Login:       tmpuser.Login,
+			Token:       tmpuser.Token,
+			Secret:      tmpuser.Secret,
+			Email:       tmpuser.Email,
+			Avatar:      tmpuser.Avatar,
+			GroupAdmin:  tmpuser.GroupAdmin,
+			Subject:     tmpuser.Subject,
+			EmailNotify: true,
 			Hash: base32.StdEncoding.EncodeToString(
 				securecookie.GenerateRandomKey(32),
 			),
@@ -72,16 +94,27 @@ func GetLogin(c *gin.Context) {
 		// insert the user into the database
 		if err := store.CreateUser(c, u); err != nil {
 			logrus.Errorf("cannot insert %s. %s", u.Login, err)
-			c.Redirect(303, "/login?error=internal_error")
+			c.Redirect(303, httputil.GetPrefix(c.Request)+"/login?error=internal_error")
 			return
 		}
 	}
 
+	// machine users cannot login with the remote system.
+	if u.Machine {
+		logrus.Errorf("cannot login %s. machine users authenticate with access tokens", u.Login)
+		c.Redirect(303, httputil.GetPrefix(c.Request)+"/login?error=access_denied")
+		return
+	}
+
 	// update the user meta data and authorization data.
 	u.Token = tmpuser.Token
 	u.Secret = tmpuser.Secret
 	u.Email = tmpuser.Email
 	u.Avatar = tmpuser.Avatar
+	u.GroupAdmin = tmpuser.GroupAdmin
+	if tmpuser.Subject != "" {
+		u.Subject = tmpuser.Subject
+	}
 
 	// if self-registration is enabled for whitelisted organizations we need to
 	// check the user's organization membership.
@@ -89,38 +122,50 @@ func GetLogin(c *gin.Context) {
 		teams, terr := remote.Teams(c, u)
 		if terr != nil || config.IsMember(teams) == false {
 			logrus.Errorf("cannot verify team membership for %s.", u.Login)
-			c.Redirect(303, "/login?error=access_denied")
+			c.Redirect(303, httputil.GetPrefix(c.Request)+"/login?error=access_denied")
 			return
 		}
 	}
 
 	if err := store.UpdateUser(c, u); err != nil {
 		logrus.Errorf("cannot update %s. %s", u.Login, err)
-		c.Redirect(303, "/login?error=internal_error")
+		c.Redirect(303, httputil.GetPrefix(c.Request)+"/login?error=internal_error")
 		return
 	}
 
-	exp := time.Now().Add(time.Hour * 72).Unix()
-	token := token.New(token.SessToken, u.Login)
-	tokenstr, err := token.SignExpires(u.Hash, exp)
+	tokenstr, _, err := sessionToken(u)
 	if err != nil {
 		logrus.Errorf("cannot create token for %s. %s", u.Login, err)
-		c.Redirect(303, "/login?error=internal_error")
+		c.Redirect(303, httputil.GetPrefix(c.Request)+"/login?error=internal_error")
 		return
 	}
 
+	sendWebhook(&model.WebhookPayload{
+		Event: model.WebhookUserLogin,
+		User:  u,
+	})
+
+	httputil.DelCookie(c.Writer, c.Request, "oidc_identity")
 	httputil.SetCookie(c.Writer, c.Request, "user_sess", tokenstr)
-	c.Redirect(303, "/")
+	c.Redirect(303, httputil.GetPrefix(c.Request)+"/")
 
 }
 
 func GetLogout(c *gin.Context) {
 	httputil.DelCookie(c.Writer, c.Request, "user_sess")
 	httputil.DelCookie(c.Writer, c.Request, "user_last")
-	c.Redirect(303, "/")
+	c.Redirect(303, httputil.GetPrefix(c.Request)+"/")
 }
 
+// GetLoginToken exchanges the access token of the remote for a session
+// token. Token login is disabled when users authenticate with an OpenID
+// Connect provider, since the remote token does not prove the identity.
 func GetLoginToken(c *gin.Context) {
+	if Config.OIDC.Provider != nil {
+		c.String(http.StatusForbidden, "Token login is disabled when OpenID Connect is enabled")
+		return
+	}
+
 	in := &tokenPayload{}
 	err := c.Bind(in)
 	if err != nil {
@@ -139,10 +184,12 @@ func GetLoginToken(c *gin.Context) {
 		c.AbortWithError(http.StatusNotFound, err)
 		return
 	}
+	if user.Machine {
+		c.String(http.StatusForbidden, "Machine users authenticate with access tokens")
+		return
+	}
 
-	exp := time.Now().Add(time.Hour * 72).Unix()
-	token := token.New(token.SessToken, user.Login)
-	tokenstr, err := token.SignExpires(user.Hash, exp)
+	tokenstr, exp, err := sessionToken(user)
 	if err != nil {
 		c.AbortWithError(http.StatusInternalServerError, err)
 		return
@@ -0,0 +1,33 @@
+package server
+
+import (
+	"github.com/Sirupsen/logrus"
+	"github.com/gin-gonic/gin"
+
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/router/middleware/logger"
+	"github.com/drone/drone/router/middleware/session"
+)
+
+// GetLogLevel returns the logging level of the server.
+func GetLogLevel(c *gin.Context) {
+	c.JSON(200, &model.LogLevel{Level: logrus.GetLevel().String()})
+}
+
+// PostLogLevel changes the logging level of the server at runtime. The
+// level is reset to the configured level when the server restarts.
+func PostLogLevel(c *gin.Context) {
+	in := new(model.LogLevel)
+	if err := c.Bind(in); err != nil {
+		c.String(400, "Error parsing request body. %s", err)
+		return
+	}
+	level, err := logrus.ParseLevel(in.Level)
+	if err != nil {
+		c.String(400, "Error parsing log level. %s", err)
+		return
+	}
+	logrus.SetLevel(level)
+	logger.FromContext(c).Warnf("log level changed to %s by %s", level, session.User(c).Login)
+	c.JSON(200, &model.LogLevel{Level: level.String()})
+}
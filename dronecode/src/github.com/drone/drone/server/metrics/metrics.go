@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/store"
+
+	"github.com/cncd/queue"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collect registers gauges that report the current state of the queue and
+// the pending and running builds in the store.
+func Collect(q queue.Queue, s store.Store) {
+	info := func() queue.InfoT {
+		return q.Info(context.Background())
+	}
+	builds := func(status string) float64 {
+		feed, err := s.GetBuildQueue()
+		if err != nil {
+			return 0
+		}
+		var count int
+		for _, item := range feed {
+			if item.Status == status {
+				count++
+			}
+		}
+		return float64(count)
+	}
+
+	prometheus.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "drone_pending_jobs",
+			Help: "Number of pending jobs in the queue.",
+		}, func() float64 {
+			return float64(info().Stats.Pending)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "drone_running_jobs",
+			Help: "Number of running jobs in the queue.",
+		}, func() float64 {
+			return float64(info().Stats.Running)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "drone_waiting_workers",
+			Help: "Number of workers waiting for a job.",
+		}, func() float64 {
+			return float64(info().Stats.Workers)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "drone_pending_builds",
+			Help: "Number of pending builds.",
+		}, func() float64 {
+			return builds(model.StatusPending)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "drone_running_builds",
+			Help: "Number of running builds.",
+		}, func() float64 {
+			return builds(model.StatusRunning)
+		}),
+	)
+}
@@ -1,8 +1,12 @@
 package metrics
 
 import (
-	"github.com/gin-gonic/gin"
+	"crypto/subtle"
+
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/router/middleware/session"
 
+	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
@@ -12,3 +16,20 @@ func PromHandler() gin.HandlerFunc {
 		promhttp.Handler().ServeHTTP(c.Writer, c.Request)
 	}
 }
+
+// MustAuth authorizes requests bearing the prometheus auth token. If the
+// token is not configured, or not provided, an admin session is required.
+func MustAuth() gin.HandlerFunc {
+	admin := session.MustAdmin()
+	return func(c *gin.Context) {
+		conf, _ := c.MustGet("config").(*model.Settings)
+		if conf != nil && conf.PromToken != "" {
+			header := c.Request.Header.Get("Authorization")
+			if subtle.ConstantTimeCompare([]byte(header), []byte("Bearer "+conf.PromToken)) == 1 {
+				c.Next()
+				return
+			}
+		}
+		admin(c)
+	}
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"encoding/base32"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/drone/drone/shared/httputil"
+	"github.com/drone/drone/shared/token"
+	"github.com/drone/drone/store"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/securecookie"
+)
+
+// oidcIdentity defines the OpenID Connect identity of the user, which is
+// kept in a signed cookie while the user is linked to the remote account.
+type oidcIdentity struct {
+	Subject string `json:"sub"`
+	Admin   bool   `json:"admin"`
+}
+
+// GetLoginOIDC authenticates the user with the OpenID Connect provider, and
+// then redirects to the remote login to link the identity to the remote
+// account.
+func GetLoginOIDC(c *gin.Context) {
+	provider := Config.OIDC.Provider
+	if provider == nil {
+		c.String(404, "OpenID Connect is not configured")
+		return
+	}
+	var (
+		prefix   = httputil.GetPrefix(c.Request)
+		redirect = httputil.GetURL(c.Request) + "/login/oidc"
+	)
+
+	if err := c.Query("error"); err != "" {
+		logrus.Errorf("cannot authenticate user with openid connect. %s %s", err, c.Query("error_description"))
+		c.Redirect(303, prefix+"/login?error=oauth_error")
+		return
+	}
+
+	code := c.Query("code")
+	if code == "" {
+		state := base32.StdEncoding.EncodeToString(securecookie.GenerateRandomKey(32))
+		link, err := provider.AuthCodeURL(redirect, state, state)
+		if err != nil {
+			logrus.Errorf("cannot get openid connect login url. %s", err)
+			c.Redirect(303, prefix+"/login?error=oauth_error")
+			return
+		}
+		httputil.SetCookie(c.Writer, c.Request, "oidc_state", state)
+		c.Redirect(303, link)
+		return
+	}
+
+	state := httputil.GetCookie(c.Request, "oidc_state")
+	httputil.DelCookie(c.Writer, c.Request, "oidc_state")
+	if state == "" || state != c.Query("state") {
+		logrus.Errorf("cannot authenticate user with openid connect. invalid state")
+		c.Redirect(303, prefix+"/login?error=oauth_error")
+		return
+	}
+	claims, err := provider.Exchange(redirect, code, state)
+	if err != nil {
+		logrus.Errorf("cannot authenticate user with openid connect. %s", err)
+		c.Redirect(303, prefix+"/login?error=oauth_error")
+		return
+	}
+
+	identity := &oidcIdentity{Subject: claims.Subject}
+	for _, group := range claims.Groups {
+		if Config.OIDC.AdminGroup != "" && group == Config.OIDC.AdminGroup {
+			identity.Admin = true
+		}
+	}
+	data, _ := json.Marshal(identity)
+	exp := time.Now().Add(10 * time.Minute).Unix()
+	tokenstr, err := token.New(token.OIDCToken, string(data)).SignExpires(provider.ClientSecret, exp)
+	if err != nil {
+		logrus.Errorf("cannot create openid connect token for %s. %s", claims.Subject, err)
+		c.Redirect(303, prefix+"/login?error=internal_error")
+		return
+	}
+	httputil.SetCookie(c.Writer, c.Request, "oidc_identity", tokenstr)
+	c.Redirect(303, prefix+"/authorize")
+}
+
+// helper function returns the OpenID Connect identity of the signed cookie,
+// or nil if the cookie is missing, invalid or expired.
+func getIdentity(r *http.Request) *oidcIdentity {
+	raw := httputil.GetCookie(r, "oidc_identity")
+	if raw == "" {
+		return nil
+	}
+	parsed, err := token.Parse(raw, func(t *token.Token) (string, error) {
+		return Config.OIDC.Provider.ClientSecret, nil
+	})
+	if err != nil || parsed.Kind != token.OIDCToken {
+		return nil
+	}
+	identity := new(oidcIdentity)
+	if err := json.Unmarshal([]byte(parsed.Text), identity); err != nil || identity.Subject == "" {
+		return nil
+	}
+	return identity
+}
+
+// helper function returns an error if the OpenID Connect identity is linked
+// to another user account, or if the user account is linked to another
+// identity.
+func checkIdentity(s store.Store, login string, identity *oidcIdentity) error {
+	other, err := s.GetUserSubject(identity.Subject)
+	if err == nil && other.Login != login {
+		return fmt.Errorf("identity %s is linked to %s", identity.Subject, other.Login)
+	}
+	user, err := s.GetUserLogin(login)
+	if err == nil && user.Subject != "" && user.Subject != identity.Subject {
+		return fmt.Errorf("%s is linked to another identity", login)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/drone/drone/model"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/gin-gonic/gin"
+)
+
+// GetOrgSecret gets the named organization secret from the database and
+// writes to the response in json format. Global secrets are served from
+// routes without an organization parameter.
+func GetOrgSecret(c *gin.Context) {
+	var (
+		owner = c.Param("team")
+		name  = c.Param("secret")
+	)
+	secret, err := Config.Storage.OrgSecrets.OrgSecretFind(owner, name)
+	if err != nil {
+		c.String(404, "Error getting secret %q. %s", name, err)
+		return
+	}
+	c.JSON(200, secret.Copy())
+}
+
+// PostOrgSecret persists the organization secret to the database.
+func PostOrgSecret(c *gin.Context) {
+	owner := c.Param("team")
+
+	in := new(model.OrgSecret)
+	if err := c.Bind(in); err != nil {
+		c.String(http.StatusBadRequest, "Error parsing secret. %s", err)
+		return
+	}
+	secret := &model.OrgSecret{
+		Owner:    owner,
+		Name:     in.Name,
+		Value:    in.Value,
+		Events:   in.Events,
+		Images:   in.Images,
+		Branches: in.Branches,
+	}
+	if err := secret.Validate(); err != nil {
+		c.String(400, "Error inserting secret. %s", err)
+		return
+	}
+	if err := Config.Storage.OrgSecrets.OrgSecretCreate(secret); err != nil {
+		c.String(500, "Error inserting secret %q. %s", in.Name, err)
+		return
+	}
+	recordAudit(c, model.AuditSecretCreate, "", owner+"/"+secret.Name)
+	c.JSON(200, secret.Copy())
+}
+
+// PatchOrgSecret updates the organization secret in the database.
+func PatchOrgSecret(c *gin.Context) {
+	var (
+		owner = c.Param("team")
+		name  = c.Param("secret")
+	)
+
+	in := new(model.OrgSecret)
+	if err := c.Bind(in); err != nil {
+		c.String(http.StatusBadRequest, "Error parsing secret. %s", err)
+		return
+	}
+
+	secret, err := Config.Storage.OrgSecrets.OrgSecretFind(owner, name)
+	if err != nil {
+		c.String(404, "Error getting secret %q. %s", name, err)
+		return
+	}
+	if in.Value != "" {
+		secret.Value = in.Value
+	}
+	if len(in.Events) != 0 {
+		secret.Events = in.Events
+	}
+	if len(in.Images) != 0 {
+		secret.Images = in.Images
+	}
+	if len(in.Branches) != 0 {
+		secret.Branches = in.Branches
+	}
+
+	if err := secret.Validate(); err != nil {
+		c.String(400, "Error updating secret. %s", err)
+		return
+	}
+	if err := Config.Storage.OrgSecrets.OrgSecretUpdate(secret); err != nil {
+		c.String(500, "Error updating secret %q. %s", name, err)
+		return
+	}
+	recordAudit(c, model.AuditSecretUpdate, "", owner+"/"+name)
+	c.JSON(200, secret.Copy())
+}
+
+// GetOrgSecretList gets the organization secret list from the database and
+// writes to the response in json format.
+func GetOrgSecretList(c *gin.Context) {
+	list, err := Config.Storage.OrgSecrets.OrgSecretList(c.Param("team"))
+	if err != nil {
+		c.String(500, "Error getting secret list. %s", err)
+		return
+	}
+	// copy the secret detail to remove the sensitive
+	// password and token fields.
+	for i, secret := range list {
+		list[i] = secret.Copy()
+	}
+	c.JSON(200, list)
+}
+
+// DeleteOrgSecret deletes the named organization secret from the database.
+func DeleteOrgSecret(c *gin.Context) {
+	var (
+		owner = c.Param("team")
+		name  = c.Param("secret")
+	)
+	secret, err := Config.Storage.OrgSecrets.OrgSecretFind(owner, name)
+	if err != nil {
+		c.String(404, "Error getting secret %q. %s", name, err)
+		return
+	}
+	if err := Config.Storage.OrgSecrets.OrgSecretDelete(secret); err != nil {
+		c.String(500, "Error deleting secret %q. %s", name, err)
+		return
+	}
+	recordAudit(c, model.AuditSecretDelete, "", owner+"/"+name)
+	c.String(204, "")
+}
+
+// buildSecrets returns the global, organization and repository secrets
+// available to builds for the repository. Repository secrets take
+// precedence over organization secrets, and organization secrets over
+// global secrets, with the same name. The repository secrets are returned
+// if the organization secrets cannot be listed.
+func buildSecrets(repo *model.Repo) ([]*model.Secret, error) {
+	var secrets []*model.Secret
+	names := map[string]int{}
+	add := func(secret *model.Secret) {
+		if i, ok := names[secret.Name]; ok {
+			secrets[i] = secret
+			return
+		}
+		names[secret.Name] = len(secrets)
+		secrets = append(secrets, secret)
+	}
+
+	for _, owner := range []string{"", repo.Owner} {
+		list, err := Config.Storage.OrgSecrets.OrgSecretList(owner)
+		if err != nil {
+			logrus.Errorf("error getting the organization secrets of %s. %s", repo.FullName, err)
+			continue
+		}
+		for _, secret := range list {
+			add(secret.Secret())
+		}
+	}
+	list, err := Config.Services.Secrets.SecretList(repo)
+	if err != nil {
+		return nil, err
+	}
+	for _, secret := range list {
+		add(secret)
+	}
+	return secrets, nil
+}
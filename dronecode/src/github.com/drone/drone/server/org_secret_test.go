@@ -0,0 +1,49 @@
+package server
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/drone/drone/model"
+)
+
+// orgSecretStore is an organization secret store that cannot list the
+// secrets of the organization.
+type orgSecretStore struct {
+	model.OrgSecretStore
+}
+
+func (s *orgSecretStore) OrgSecretList(owner string) ([]*model.OrgSecret, error) {
+	if owner != "" {
+		return nil, errors.New("database unavailable")
+	}
+	return []*model.OrgSecret{
+		{Name: "password", Value: "global"},
+		{Name: "token", Value: "global"},
+	}, nil
+}
+
+// repoSecretService is a secret service with a single repository secret.
+type repoSecretService struct {
+	model.SecretService
+}
+
+func (s *repoSecretService) SecretList(repo *model.Repo) ([]*model.Secret, error) {
+	return []*model.Secret{{Name: "password", Value: "repo"}}, nil
+}
+
+func TestBuildSecrets(t *testing.T) {
+	defer func(orgs model.OrgSecretStore, secrets model.SecretService) {
+		Config.Storage.OrgSecrets, Config.Services.Secrets = orgs, secrets
+	}(Config.Storage.OrgSecrets, Config.Services.Secrets)
+	Config.Storage.OrgSecrets = new(orgSecretStore)
+	Config.Services.Secrets = new(repoSecretService)
+
+	secrets, err := buildSecrets(&model.Repo{Owner: "octocat", FullName: "octocat/hello-world"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secrets) != 2 || secrets[0].Value != "repo" || secrets[1].Value != "global" {
+		t.Errorf("Want the repository and global secrets when the organization secrets fail, got %v", secrets)
+	}
+}
@@ -3,7 +3,9 @@ package server
 import (
 	"github.com/gin-gonic/gin"
 
+	"github.com/drone/drone/remote"
 	"github.com/drone/drone/router/middleware/session"
+	"github.com/drone/drone/shared/httputil"
 	"github.com/drone/drone/shared/token"
 )
 
@@ -22,6 +24,7 @@ func ShowIndex(c *gin.Context) {
 	c.HTML(200, "index.html", gin.H{
 		"user": user,
 		"csrf": csrf,
+		"root": httputil.GetPrefix(c.Request),
 	})
 }
 
@@ -29,14 +32,22 @@ func ShowIndex(c *gin.Context) {
 // initiliaze the oauth flow
 func ShowLogin(c *gin.Context) {
 	if err := c.Query("error"); err != "" {
-		c.HTML(500, "error.html", gin.H{"error": err})
+		c.HTML(500, "error.html", gin.H{
+			"error": err,
+			"root":  httputil.GetPrefix(c.Request),
+		})
 		return
 	}
-	c.Redirect(303, "/authorize")
+	c.Redirect(303, httputil.GetPrefix(c.Request)+"/authorize")
 }
 
 // ShowLoginForm displays a login form for systems like Gogs that do not
-// yet support oauth workflows.
+// yet support oauth workflows. The form requests a personal access token
+// if the remote links accounts with a token.
 func ShowLoginForm(c *gin.Context) {
-	c.HTML(200, "login.html", gin.H{})
+	linker, ok := remote.FromContext(c).(remote.TokenLinker)
+	c.HTML(200, "login.html", gin.H{
+		"root":  httputil.GetPrefix(c.Request),
+		"token": ok && linker.LinkToken(),
+	})
 }
@@ -0,0 +1,271 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/cncd/queue"
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/remote"
+	"github.com/drone/drone/store"
+
+	pipeline "github.com/cncd/pipeline/pipeline/frontend/yaml"
+	"gopkg.in/yaml.v2"
+)
+
+// pipelineHeader defines the pipeline name and the pipelines it depends
+// on, declared at the top of each document in a multi-pipeline yaml file.
+type pipelineHeader struct {
+	Name       string              `yaml:"name"`
+	DependsOn  []string            `yaml:"depends_on"`
+	Paths      pipeline.Constraint `yaml:"paths"`
+	Downstream []string            `yaml:"downstream"`
+}
+
+// splitDocuments splits the yaml configuration into the yaml documents
+// separated by a line containing only the document separator. Empty
+// documents are ignored.
+func splitDocuments(data string) []string {
+	var docs []string
+	var doc []string
+	flush := func() {
+		if s := strings.Join(doc, "\n"); strings.TrimSpace(s) != "" {
+			docs = append(docs, s)
+		}
+		doc = nil
+	}
+	for _, line := range strings.Split(data, "\n") {
+		if strings.TrimRight(line, " \t\r") == "---" {
+			flush()
+			continue
+		}
+		doc = append(doc, line)
+	}
+	flush()
+	return docs
+}
+
+// parseHeader parses the pipeline name and dependencies from the yaml
+// document.
+func parseHeader(doc string) (*pipelineHeader, error) {
+	header := new(pipelineHeader)
+	err := yaml.Unmarshal([]byte(doc), header)
+	return header, err
+}
+
+// matchBranches returns true if the branch matches the branch restrictions
+// of any pipeline in the yaml configuration. Pipelines that cannot be
+// parsed match any branch, so that the parse error is reported when the
+// build is compiled.
+func matchBranches(data, branch string) bool {
+	docs := splitDocuments(data)
+	if len(docs) == 0 {
+		return true
+	}
+	for _, doc := range docs {
+		parsed, err := pipeline.ParseString(doc)
+		if err != nil || parsed.Branches.Match(branch) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPaths returns true if the changed files match the path restrictions
+// of any pipeline in the yaml configuration.
+func matchPaths(data string, changed []string) bool {
+	docs := splitDocuments(data)
+	if len(docs) == 0 {
+		return true
+	}
+	for _, doc := range docs {
+		header, err := parseHeader(doc)
+		if err != nil || header.Paths.MatchPaths(changed) {
+			return true
+		}
+	}
+	return false
+}
+
+// changedFiles fetches the files changed by the build from the remote,
+// if the changed files were not included in the hook. Path restrictions
+// match every build when the changed files are unknown.
+func changedFiles(r remote.Remote, user *model.User, repo *model.Repo, build *model.Build) {
+	if build.Changed != nil || (build.Event != model.EventPush && build.Event != model.EventPull) {
+		return
+	}
+	differ, ok := r.(remote.Differ)
+	if !ok {
+		return
+	}
+	changed, err := differ.Changes(user, repo, build)
+	if err != nil {
+		logrus.Debugf("Error getting changed files for %s %s. %s", repo.FullName, build.Commit, err)
+		return
+	}
+	build.Changed = changed
+}
+
+// validateDependencies returns an error if a pipeline depends on a
+// pipeline that is not defined, or if the dependencies contain a cycle.
+func validateDependencies(headers []*pipelineHeader) error {
+	deps := map[string][]string{}
+	for _, header := range headers {
+		deps[header.Name] = append(deps[header.Name], header.DependsOn...)
+	}
+	for _, header := range headers {
+		for _, dep := range header.DependsOn {
+			if _, ok := deps[dep]; !ok || dep == "" {
+				return fmt.Errorf("Pipeline %q depends on unknown pipeline %q", header.Name, dep)
+			}
+		}
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("Pipeline %q has a circular dependency", name)
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		for _, dep := range deps[name] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+	for name := range deps {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// holdBuildItem holds the pipeline until the pipelines it depends on have
+// completed. The held pipeline is stored in the database, so that it is
+// queued after a restart, and by any server when running multiple servers.
+func holdBuildItem(s store.Store, repo *model.Repo, item *buildItem) {
+	task, _ := json.Marshal(newBuildTask(repo, item))
+	hold := &model.Hold{
+		BuildID:   item.Proc.BuildID,
+		ProcID:    item.Proc.ID,
+		DependsOn: item.DependsOn,
+		Task:      task,
+	}
+	if err := s.HoldCreate(hold); err != nil {
+		logrus.Errorf("cannot hold proc_id %d: %s", item.Proc.ID, err)
+	}
+}
+
+// releaseBuildItems queues the held pipelines of the build whose
+// dependencies have completed successfully, and returns the held
+// pipelines that are skipped because a dependency did not succeed. A held
+// pipeline is released by the server that deletes it, so that it is not
+// queued twice.
+func releaseBuildItems(s store.Store, build *model.Build, procs []*model.Proc) []*model.Proc {
+	holds, err := s.HoldList(build)
+	if err != nil {
+		logrus.Errorf("cannot list held pipelines of build %d: %s", build.ID, err)
+		return nil
+	}
+
+	var (
+		skipped []*model.Proc
+		queued  []*model.Hold
+	)
+	for {
+		// the state of each named pipeline is the state of the matrix
+		// procs with the pipeline name.
+		done := map[string]bool{}
+		failed := map[string]bool{}
+		for _, p := range procs {
+			if p.PPID != 0 {
+				continue
+			}
+			if _, ok := done[p.Name]; !ok {
+				done[p.Name] = true
+			}
+			if p.Running() {
+				done[p.Name] = false
+			}
+			if p.State != model.StatusSuccess {
+				failed[p.Name] = true
+			}
+		}
+
+		var (
+			pending []*model.Hold
+			changed bool
+		)
+		for _, hold := range holds {
+			ready, skip := true, false
+			for _, dep := range hold.DependsOn {
+				if !done[dep] {
+					ready = false
+				} else if failed[dep] {
+					skip = true
+				}
+			}
+			if !ready && !skip {
+				pending = append(pending, hold)
+				continue
+			}
+			if ok, err := s.HoldDelete(hold); err != nil || !ok {
+				continue
+			}
+			if !skip {
+				queued = append(queued, hold)
+				continue
+			}
+			pid := -1
+			for _, p := range procs {
+				if p.ID == hold.ProcID {
+					pid = p.PID
+				}
+			}
+			for _, p := range procs {
+				if p.ID == hold.ProcID || p.PPID == pid {
+					p.State = model.StatusSkipped
+					skipped = append(skipped, p)
+				}
+			}
+			changed = true
+		}
+		holds = pending
+		// skipping a pipeline may complete the dependencies of other
+		// held pipelines, which are evaluated again.
+		if !changed {
+			break
+		}
+	}
+
+	for _, hold := range queued {
+		task := new(queue.Task)
+		if err := json.Unmarshal(hold.Task, task); err != nil {
+			logrus.Errorf("cannot queue held proc_id %d: %s", hold.ProcID, err)
+			continue
+		}
+		pushTask(task)
+	}
+	return skipped
+}
+
+// dropBuildItems removes the held pipelines of the build, for example
+// when the build is cancelled.
+func dropBuildItems(s store.Store, build *model.Build) {
+	if err := s.HoldClear(build); err != nil {
+		logrus.Errorf("cannot remove held pipelines of build %d: %s", build.ID, err)
+	}
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cncd/logging"
+	"github.com/cncd/queue"
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/store/datastore"
+)
+
+func TestReleaseBuildItems(t *testing.T) {
+	defer func(q queue.Queue, l logging.Log) {
+		Config.Services.Queue, Config.Services.Logs = q, l
+	}(Config.Services.Queue, Config.Services.Logs)
+	Config.Services.Queue = queue.New()
+	Config.Services.Logs = logging.New()
+
+	s := datastore.New("sqlite3", ":memory:")
+
+	repo := &model.Repo{FullName: "octocat/hello-world", Owner: "octocat"}
+	build := &model.Build{ID: 1}
+	procs := []*model.Proc{
+		{ID: 1, BuildID: 1, PID: 1, Name: "backend", State: model.StatusRunning},
+		{ID: 2, BuildID: 1, PID: 2, Name: "frontend", State: model.StatusRunning},
+		{ID: 3, BuildID: 1, PID: 3, Name: "deploy", State: model.StatusPending},
+		{ID: 4, BuildID: 1, PID: 4, PPID: 3, Name: "step", State: model.StatusPending},
+		{ID: 5, BuildID: 1, PID: 5, Name: "notify", State: model.StatusPending},
+	}
+	holdBuildItem(s, repo, &buildItem{Proc: procs[2], DependsOn: []string{"backend"}})
+	holdBuildItem(s, repo, &buildItem{Proc: procs[4], DependsOn: []string{"frontend", "deploy"}})
+
+	if skipped := releaseBuildItems(s, build, procs); len(skipped) != 0 {
+		t.Errorf("Want no skipped pipelines while the dependencies run, got %d", len(skipped))
+	}
+
+	procs[0].State = model.StatusSuccess
+	releaseBuildItems(s, build, procs)
+	if got := len(Config.Services.Queue.Info(context.Background()).Pending); got != 1 {
+		t.Errorf("Want the pipeline queued once its dependency succeeds, got %d pending", got)
+	}
+	// the released pipeline is removed from the database, and is not
+	// queued again.
+	releaseBuildItems(s, build, procs)
+	if got := len(Config.Services.Queue.Info(context.Background()).Pending); got != 1 {
+		t.Errorf("Want the pipeline queued once, got %d pending", got)
+	}
+
+	procs[1].State = model.StatusFailure
+	skipped := releaseBuildItems(s, build, procs)
+	if len(skipped) != 1 || skipped[0].ID != 5 || procs[4].State != model.StatusSkipped {
+		t.Errorf("Want the pipeline skipped when a dependency fails, got %v", skipped)
+	}
+	if list, _ := s.HoldList(build); len(list) != 0 {
+		t.Errorf("Want no held pipelines, got %d", len(list))
+	}
+}
+
+func TestDropBuildItems(t *testing.T) {
+	s := datastore.New("sqlite3", ":memory:")
+
+	build := &model.Build{ID: 1}
+	proc := &model.Proc{ID: 1, BuildID: 1, PID: 1, Name: "deploy", State: model.StatusPending}
+	holdBuildItem(s, new(model.Repo), &buildItem{Proc: proc, DependsOn: []string{"build"}})
+	dropBuildItems(s, build)
+	if list, _ := s.HoldList(build); len(list) != 0 {
+		t.Errorf("Want held pipelines removed, got %d", len(list))
+	}
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/drone/drone/model"
+)
+
+// buildPriority returns the queue priority of the build, which is the
+// repository priority plus the priority of the first rule that matches the
+// build event and branch. Rules are declared as event=priority or, to match
+// a branch glob pattern, event:branch=priority.
+func buildPriority(repo *model.Repo, build *model.Build) int {
+	for _, rule := range Config.Pipeline.Priority {
+		i := strings.LastIndex(rule, "=")
+		if i == -1 {
+			continue
+		}
+		priority, err := strconv.Atoi(strings.TrimSpace(rule[i+1:]))
+		if err != nil {
+			continue
+		}
+		event, branch := strings.TrimSpace(rule[:i]), ""
+		if j := strings.Index(event, ":"); j != -1 {
+			event, branch = event[:j], event[j+1:]
+		}
+		if event != build.Event {
+			continue
+		}
+		if match, _ := filepath.Match(branch, build.Branch); branch != "" && !match {
+			continue
+		}
+		return repo.Priority + priority
+	}
+	return repo.Priority
+}
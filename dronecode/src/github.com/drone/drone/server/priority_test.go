@@ -0,0 +1,42 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/drone/drone/model"
+)
+
+func TestBuildPriority(t *testing.T) {
+	defer func(rules []string) {
+		Config.Pipeline.Priority = rules
+	}(Config.Pipeline.Priority)
+	Config.Pipeline.Priority = []string{
+		"push:release/*=20",
+		"push=10",
+		"pull_request=-5",
+		"tag",
+		"deployment=high",
+	}
+
+	tests := []struct {
+		event    string
+		branch   string
+		priority int
+		want     int
+	}{
+		{event: model.EventPush, branch: "master", want: 10},
+		{event: model.EventPush, branch: "release/1.0", want: 20},
+		{event: model.EventPull, branch: "master", want: -5},
+		{event: model.EventTag, want: 0},
+		{event: model.EventDeploy, want: 0},
+		{event: model.EventPush, branch: "master", priority: 5, want: 15},
+		{event: model.EventTag, priority: 5, want: 5},
+	}
+	for _, test := range tests {
+		repo := &model.Repo{Priority: test.priority}
+		build := &model.Build{Event: test.event, Branch: test.branch}
+		if got := buildPriority(repo, build); got != test.want {
+			t.Errorf("Want %s build of branch %q priority %d, got %d", test.event, test.branch, test.want, got)
+		}
+	}
+}
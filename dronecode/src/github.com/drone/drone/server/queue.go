@@ -0,0 +1,149 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/cncd/queue"
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetQueue returns the pending and running pipelines in the build queue.
+func GetQueue(c *gin.Context) {
+	info := Config.Services.Queue.Info(c)
+	builds := map[int64]*model.Build{}
+
+	item := func(task *queue.Task) *model.QueueItem {
+		out := &model.QueueItem{
+			ID:       task.ID,
+			Repo:     task.Labels["repo"],
+			Labels:   task.Labels,
+			Priority: task.Priority,
+		}
+		id, _ := strconv.ParseInt(task.ID, 10, 64)
+		proc, err := store.FromContext(c).ProcLoad(id)
+		if err != nil {
+			return out
+		}
+		build, ok := builds[proc.BuildID]
+		if !ok {
+			build, err = store.FromContext(c).GetBuild(proc.BuildID)
+			if err != nil {
+				return out
+			}
+			builds[proc.BuildID] = build
+		}
+		out.Build = build.Number
+		out.Pipeline = proc.Name
+		out.Agent = proc.Machine
+
+		started := time.Now().Unix()
+		if proc.Started != 0 {
+			started = proc.Started
+		}
+		if build.Enqueued != 0 && started > build.Enqueued {
+			out.Waiting = started - build.Enqueued
+		}
+		return out
+	}
+
+	out := &model.QueueInfo{
+		Paused:  info.Paused,
+		Pending: []*model.QueueItem{},
+		Running: []*model.QueueItem{},
+	}
+	for _, task := range info.Pending {
+		out.Pending = append(out.Pending, item(task))
+	}
+	for _, task := range info.Running {
+		out.Running = append(out.Running, item(task))
+	}
+	c.JSON(200, out)
+}
+
+// PostQueuePause pauses the build queue. Running pipelines complete, but
+// pending pipelines are not dispatched to agents until the queue is resumed.
+func PostQueuePause(c *gin.Context) {
+	Config.Services.Queue.Pause()
+	c.String(204, "")
+}
+
+// PostQueueResume resumes the build queue.
+func PostQueueResume(c *gin.Context) {
+	Config.Services.Queue.Resume()
+	c.String(204, "")
+}
+
+// DeleteQueueItem evicts the pending pipeline from the build queue and
+// marks the pipeline as killed.
+func DeleteQueueItem(c *gin.Context) {
+	id := c.Param("id")
+	if err := Config.Services.Queue.Evict(c, id); err != nil {
+		c.String(404, "Cannot evict the queue item. %s", err)
+		return
+	}
+
+	procID, _ := strconv.ParseInt(id, 10, 64)
+	if err := evictProc(store.FromContext(c), procID); err != nil {
+		c.String(500, "Error updating the evicted pipeline. %s", err)
+		return
+	}
+	c.String(204, "")
+}
+
+// evictProc marks the evicted pipeline as killed, skips the pipelines that
+// depend on it, and completes the build if no pipelines remain.
+func evictProc(s store.Store, id int64) error {
+	proc, err := s.ProcLoad(id)
+	if err != nil {
+		return err
+	}
+	build, err := s.GetBuild(proc.BuildID)
+	if err != nil {
+		return err
+	}
+	procUpdates.flush()
+	procs, err := s.ProcList(build)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	for _, p := range procs {
+		if p.ID != proc.ID && p.PPID != proc.PID {
+			continue
+		}
+		p.State = model.StatusKilled
+		p.Stopped = now
+		if p.Started == 0 {
+			p.Started = now
+		}
+		if err := s.ProcUpdate(p); err != nil {
+			return err
+		}
+	}
+	for _, p := range releaseBuildItems(s, build, procs) {
+		if err := s.ProcUpdate(p); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range procs {
+		if p.PPID == 0 && p.Running() {
+			return nil
+		}
+	}
+	build.Status = model.StatusKilled
+	build.Finished = now
+	if build.Started == 0 {
+		build.Started = now
+	}
+	if err := s.UpdateBuild(build); err != nil {
+		return fmt.Errorf("cannot update build %d. %s", build.ID, err)
+	}
+	return nil
+}
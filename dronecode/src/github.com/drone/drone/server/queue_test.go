@@ -0,0 +1,100 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cncd/queue"
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/store"
+	"github.com/drone/drone/store/datastore"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetQueue(t *testing.T) {
+	defer func(q queue.Queue) {
+		Config.Services.Queue = q
+	}(Config.Services.Queue)
+	Config.Services.Queue = queue.New()
+
+	s := datastore.New("sqlite3", ":memory:")
+	build := &model.Build{RepoID: 1, Status: model.StatusPending}
+	proc := &model.Proc{PID: 1, PGID: 1, Name: "backend", State: model.StatusPending}
+	if err := s.CreateBuild(build, proc); err != nil {
+		t.Fatal(err)
+	}
+	Config.Services.Queue.Push(context.Background(), &queue.Task{
+		ID:       "1",
+		Priority: 10,
+		Labels:   map[string]string{"repo": "octocat/hello-world"},
+	})
+	Config.Services.Queue.Pause()
+
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.GET("/api/queue", func(c *gin.Context) {
+		store.ToContext(c, s)
+		GetQueue(c)
+	})
+	req, _ := http.NewRequest("GET", "/api/queue", nil)
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+
+	out := new(model.QueueInfo)
+	if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+		t.Fatal(err)
+	}
+	if !out.Paused {
+		t.Errorf("Want paused queue")
+	}
+	if len(out.Pending) != 1 {
+		t.Fatalf("Want one pending pipeline, got %d", len(out.Pending))
+	}
+	item := out.Pending[0]
+	if item.Repo != "octocat/hello-world" || item.Build != build.Number || item.Pipeline != "backend" || item.Priority != 10 {
+		t.Errorf("Want pending pipeline backend of build octocat/hello-world#%d, got %+v", build.Number, item)
+	}
+}
+
+func TestEvictProc(t *testing.T) {
+	s := datastore.New("sqlite3", ":memory:")
+	build := &model.Build{RepoID: 1, Number: 1, Status: model.StatusRunning}
+	procs := []*model.Proc{
+		{PID: 1, PGID: 1, Name: "backend", State: model.StatusPending},
+		{PID: 2, PGID: 1, PPID: 1, Name: "build", State: model.StatusPending},
+		{PID: 3, PGID: 3, Name: "frontend", State: model.StatusRunning},
+	}
+	if err := s.CreateBuild(build, procs...); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := evictProc(s, procs[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	list, _ := s.ProcList(build)
+	for _, p := range list {
+		want := model.StatusKilled
+		if p.PID == 3 {
+			want = model.StatusRunning
+		}
+		if p.State != want {
+			t.Errorf("Want proc %s %s, got %s", p.Name, want, p.State)
+		}
+	}
+	if got, _ := s.GetBuild(build.ID); got.Status != model.StatusRunning {
+		t.Errorf("Want build running while a pipeline runs, got %s", got.Status)
+	}
+
+	procs[2].State = model.StatusPending
+	s.ProcUpdate(procs[2])
+	if err := evictProc(s, procs[2].ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.GetBuild(build.ID); got.Status != model.StatusKilled || got.Finished == 0 {
+		t.Errorf("Want build killed once no pipelines run, got %s", got.Status)
+	}
+}
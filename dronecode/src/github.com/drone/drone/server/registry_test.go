@@ -0,0 +1,40 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/drone/drone/model"
+)
+
+func TestBuildRegistries(t *testing.T) {
+	b := &builder{
+		Repo:  &model.Repo{Owner: "octocat", Name: "hello-world", FullName: "octocat/hello-world"},
+		Curr:  &model.Build{Event: model.EventPush, Branch: "master"},
+		Last:  new(model.Build),
+		Netrc: new(model.Netrc),
+		Regs: []*model.Registry{
+			{Address: "registry.example.com", Username: "octocat", Password: "correct-horse-battery-staple"},
+		},
+		Yaml: "pipeline:\n  build:\n    image: registry.example.com/octocat/golang\n  test:\n    image: golang\n",
+	}
+	items, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("Want one pipeline, got %d", len(items))
+	}
+
+	auths := map[string]string{}
+	for _, stage := range items[0].Config.Stages {
+		for _, step := range stage.Steps {
+			auths[step.Image] = step.AuthConfig.Password
+		}
+	}
+	if got := auths["registry.example.com/octocat/golang:latest"]; got != "correct-horse-battery-staple" {
+		t.Errorf("Want registry credentials for the private image, got %q", got)
+	}
+	if got := auths["golang:latest"]; got != "" {
+		t.Errorf("Want no registry credentials for the public image, got %q", got)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/base32"
 	"fmt"
 	"net/http"
+	"net/mail"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/securecookie"
@@ -55,7 +56,7 @@ func PostRepo(c *gin.Context) {
 	r.UserID = user.ID
 	r.AllowPush = true
 	r.AllowPull = true
-	r.Config = ".drone.yml"
+	r.Config = defaultConfigPath
 	r.Timeout = 60 // 1 hour default build time
 	r.Hash = base32.StdEncoding.EncodeToString(
 		securecookie.GenerateRandomKey(32),
@@ -82,6 +83,7 @@ func PostRepo(c *gin.Context) {
 		c.String(500, err.Error())
 		return
 	}
+	r.HookSigned = true
 
 	// persist the repository
 	err = store.CreateRepo(c, r)
@@ -89,13 +91,18 @@ func PostRepo(c *gin.Context) {
 		c.String(500, err.Error())
 		return
 	}
+	recordAudit(c, model.AuditRepoActivate, r.FullName, "")
+	sendWebhook(&model.WebhookPayload{
+		Event: model.WebhookRepoActivated,
+		Repo:  r,
+		User:  user,
+	})
 
 	c.JSON(200, r)
 }
 
 func PatchRepo(c *gin.Context) {
 	repo := session.Repo(c)
-	user := session.User(c)
 
 	in := new(model.RepoPatch)
 	if err := c.Bind(in); err != nil {
@@ -103,11 +110,31 @@ func PatchRepo(c *gin.Context) {
 		return
 	}
 
-	if (in.IsTrusted != nil || in.Timeout != nil) && !user.Admin {
-		c.String(403, "Insufficient privileges")
+	if !patchRepo(c, repo, in) {
 		return
 	}
 
+	err := store.UpdateRepo(c, repo)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	recordAudit(c, model.AuditRepoUpdate, repo.FullName, "")
+
+	c.JSON(http.StatusOK, repo)
+}
+
+// patchRepo applies the patch to the repository settings. It returns false
+// and writes the error to the response if the patch is invalid, or if the
+// user has insufficient privileges.
+func patchRepo(c *gin.Context, repo *model.Repo, in *model.RepoPatch) bool {
+	user := session.User(c)
+
+	if (in.IsTrusted != nil || in.Timeout != nil || in.Throttle != nil || in.RateLimit != nil || in.Priority != nil) && !user.Admin {
+		c.String(403, "Insufficient privileges")
+		return false
+	}
+
 	if in.AllowPush != nil {
 		repo.AllowPush = *in.AllowPush
 	}
@@ -120,26 +147,83 @@ func PatchRepo(c *gin.Context) {
 	if in.AllowTag != nil {
 		repo.AllowTag = *in.AllowTag
 	}
+	if in.AllowComments != nil {
+		repo.AllowComments = *in.AllowComments
+	}
 	if in.IsGated != nil {
 		repo.IsGated = *in.IsGated
 	}
+	if in.CancelPulls != nil {
+		repo.CancelPulls = *in.CancelPulls
+	}
+	if in.CancelPush != nil {
+		repo.CancelPush = *in.CancelPush
+	}
 	if in.IsTrusted != nil {
 		repo.IsTrusted = *in.IsTrusted
 	}
 	if in.Timeout != nil {
 		repo.Timeout = *in.Timeout
 	}
+	if in.Throttle != nil {
+		repo.Throttle = *in.Throttle
+	}
+	if in.RateLimit != nil {
+		repo.RateLimit = *in.RateLimit
+	}
+	if in.Priority != nil {
+		repo.Priority = *in.Priority
+	}
 	if in.Config != nil {
 		repo.Config = *in.Config
 	}
-
-	err := store.UpdateRepo(c, repo)
-	if err != nil {
-		c.AbortWithError(http.StatusInternalServerError, err)
-		return
+	if in.EmailNotify != nil {
+		repo.EmailNotify = *in.EmailNotify
 	}
-
-	c.JSON(http.StatusOK, repo)
+	if in.EmailTo != nil {
+		for _, addr := range *in.EmailTo {
+			if _, err := mail.ParseAddress(addr); err != nil {
+				c.String(400, "Invalid email recipient %q. %s", addr, err)
+				return false
+			}
+		}
+		repo.EmailTo = *in.EmailTo
+	}
+	if in.RequireSigned != nil {
+		if !session.Perm(c).Admin {
+			c.String(403, "Insufficient privileges")
+			return false
+		}
+		repo.RequireSigned = *in.RequireSigned
+	}
+	if in.DeployRules != nil {
+		if !session.Perm(c).Admin {
+			c.String(403, "Insufficient privileges")
+			return false
+		}
+		for _, rule := range *in.DeployRules {
+			if err := rule.Validate(); err != nil {
+				c.String(400, err.Error())
+				return false
+			}
+		}
+		repo.DeployRules = *in.DeployRules
+	}
+	if in.SkipPatterns != nil {
+		if err := model.ValidateSkipPatterns(*in.SkipPatterns); err != nil {
+			c.String(400, "Invalid skip pattern. %s", err)
+			return false
+		}
+		repo.SkipPatterns = *in.SkipPatterns
+	}
+	if in.Downstream != nil {
+		if err := validateDownstream(store.FromContext(c), repo, *in.Downstream); err != nil {
+			c.String(400, err.Error())
+			return false
+		}
+		repo.Downstream = *in.Downstream
+	}
+	return true
 }
 
 func ChownRepo(c *gin.Context) {
@@ -171,6 +255,7 @@ func DeleteRepo(c *gin.Context) {
 	}
 
 	remote.Deactivate(user, repo, httputil.GetURL(c.Request))
+	recordAudit(c, model.AuditRepoDeactivate, repo.FullName, "")
 	c.Writer.WriteHeader(http.StatusOK)
 }
 
@@ -201,5 +286,14 @@ func RepairRepo(c *gin.Context) {
 		c.String(500, err.Error())
 		return
 	}
+
+	// the repaired hook is registered with the hook secret.
+	if !repo.HookSigned {
+		repo.HookSigned = true
+		if err := store.UpdateRepo(c, repo); err != nil {
+			c.String(500, err.Error())
+			return
+		}
+	}
 	c.Writer.WriteHeader(http.StatusOK)
 }
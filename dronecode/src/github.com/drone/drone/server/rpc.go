@@ -5,11 +5,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/cncd/logging"
+	"github.com/cncd/pipeline/pipeline/frontend/yaml"
 	"github.com/cncd/pipeline/pipeline/rpc"
 	"github.com/cncd/pubsub"
 	"github.com/cncd/queue"
@@ -18,6 +20,10 @@ import (
 
 	"github.com/drone/drone/model"
 	"github.com/drone/drone/remote"
+	"github.com/drone/drone/router/middleware/logger"
+	"github.com/drone/drone/shared/metrics"
+	"github.com/drone/drone/shared/oidc"
+	"github.com/drone/drone/shared/trace"
 	"github.com/drone/drone/store"
 	"github.com/drone/drone/version"
 )
@@ -35,15 +41,25 @@ var Config = struct {
 		Senders    model.SenderService
 		Secrets    model.SecretService
 		Registries model.RegistryService
+		Configs    model.ConfigService
+		Resolver   model.SecretResolver
+		Validator  model.ValidateService
+		Webhooks   model.WebhookService
+		Email      model.EmailService
+		Chat       model.ChatService
+		Uploads    model.UploadService
+		Tracer     *trace.Tracer
 	}
 	Storage struct {
 		// Users  model.UserStore
 		// Repos  model.RepoStore
 		// Builds model.BuildStore
 		// Logs   model.LogStore
-		Config model.ConfigStore
-		Files  model.FileStore
-		Procs  model.ProcStore
+		Config     model.ConfigStore
+		Files      model.FileStore
+		Procs      model.ProcStore
+		OrgSecrets model.OrgSecretStore
+		Templates  model.TemplateStore
 		// Registries model.RegistryStore
 		// Secrets model.SecretStore
 	}
@@ -53,14 +69,43 @@ var Config = struct {
 		Host string
 		Port string
 		Pass string
+		// HookUnsigned disables the verification of the hook signatures,
+		// while the hooks registered without the hook secret are repaired.
+		HookUnsigned bool
 		// Open bool
 		// Orgs map[string]struct{}
 		// Admins map[string]struct{}
 	}
 	Pipeline struct {
-		Volumes    []string
-		Networks   []string
-		Privileged []string
+		Volumes      []string
+		VolumePaths  []string
+		VolumeNames  []string
+		CacheServer  bool
+		CacheImage   string
+		CacheSize    int64
+		Jsonnet      bool
+		Starlark     bool
+		IncludeRepos []string
+		MaxCPU       yaml.CPU
+		MaxMemory    yaml.Memory
+		Networks     []string
+		Privileged   []string
+		Throttle     int
+		RateLimit    int
+		Priority     []string
+	}
+	Retention struct {
+		Logs   time.Duration
+		Builds int
+		Hooks  time.Duration
+	}
+	Session struct {
+		Expires      time.Duration
+		TokenExpires time.Duration
+	}
+	OIDC struct {
+		Provider   *oidc.Provider
+		AdminGroup string
 	}
 }{}
 
@@ -85,9 +130,13 @@ var Config = struct {
 // }
 
 func RPCHandler(c *gin.Context) {
+	if isDraining() {
+		c.String(503, "Unable to connect agent. Server is shutting down")
+		return
+	}
 
 	if secret := c.Request.Header.Get("Authorization"); secret != "Bearer "+Config.Server.Pass {
-		log.Printf("Unable to connect agent. Invalid authorization token %q does not match %q", secret, Config.Server.Pass)
+		logger.FromContext(c).Warnf("Unable to connect agent %s. Invalid authorization token", c.ClientIP())
 		c.String(401, "Unable to connect agent. Invalid authorization token")
 		return
 	}
@@ -95,9 +144,14 @@ func RPCHandler(c *gin.Context) {
 	agent := semver.New(
 		c.Request.Header.Get("X-Drone-Version"),
 	)
-	logrus.Debugf("agent connected: ip address %s: version %s", c.ClientIP(), agent)
+	log := logger.FromContext(c).WithFields(logrus.Fields{
+		"agent":   c.Request.Header.Get("X-Drone-Hostname"),
+		"ip":      c.ClientIP(),
+		"version": agent.String(),
+	})
+	log.Debugf("agent connected")
 	if agent.LessThan(version.Version) {
-		logrus.Warnf("Version mismatch. Agent version %s < Server version %s", agent, version.Version)
+		log.Warnf("Version mismatch. Agent version %s < Server version %s", agent, version.Version)
 		c.String(409, "Version mismatch. Agent version %s < Server version %s", agent, version.Version)
 		return
 	}
@@ -109,8 +163,18 @@ func RPCHandler(c *gin.Context) {
 		pubsub: Config.Services.Pubsub,
 		logger: Config.Services.Logs,
 		host:   Config.Server.Host,
+		agent:  c.Request.Header.Get("X-Drone-Hostname"),
+		log:    log,
 	}
-	rpc.NewServer(&peer).ServeHTTP(c.Writer, c.Request)
+	if peer.agent == "" {
+		peer.agent = c.ClientIP()
+	}
+	metrics.Agents.Inc()
+	defer metrics.Agents.Dec()
+
+	ctx, cancel := drainContext()
+	defer cancel()
+	rpc.NewServer(&peer).Serve(ctx, c.Writer, c.Request)
 }
 
 type RPC struct {
@@ -120,6 +184,8 @@ type RPC struct {
 	logger logging.Log
 	store  store.Store
 	host   string
+	agent  string
+	log    *logrus.Entry
 }
 
 // Next implements the rpc.Next function
@@ -130,6 +196,12 @@ func (s *RPC) Next(c context.Context, filter rpc.Filter) (*rpc.Pipeline, error)
 				return false
 			}
 		}
+		if !matchNode(task, filter.Node) {
+			return false
+		}
+		if filter.Weight != 0 && taskWeight(task, filter) > filter.Weight {
+			return false
+		}
 		return true
 	}
 	task, err := s.queue.Poll(c, fn)
@@ -140,9 +212,88 @@ func (s *RPC) Next(c context.Context, filter rpc.Filter) (*rpc.Pipeline, error)
 	}
 	pipeline := new(rpc.Pipeline)
 	err = json.Unmarshal(task.Data, pipeline)
+	pipeline.Weight = taskWeight(task, filter)
+	if err == nil {
+		pipeline.Uploads = s.uploadURLs(pipeline)
+	}
+	traceQueue(s.store, task, pipeline)
 	return pipeline, err
 }
 
+// uploadURLs returns the pre-signed upload urls of the step logs, keyed by
+// step alias, if the agent uploads the logs directly to blob storage. The
+// logs of the steps without an upload url are uploaded through the server.
+func (s *RPC) uploadURLs(pipeline *rpc.Pipeline) map[string]*rpc.Upload {
+	if Config.Services.Uploads == nil || pipeline.Config == nil {
+		return nil
+	}
+	log := s.log.WithFields(logrus.Fields{"rpc": "next", "proc": pipeline.ID})
+	procID, err := strconv.ParseInt(pipeline.ID, 10, 64)
+	if err != nil {
+		return nil
+	}
+	pproc, err := s.store.ProcLoad(procID)
+	if err != nil {
+		log.Errorf("cannot find proc with id %d: %s", procID, err)
+		return nil
+	}
+	build, err := s.store.GetBuild(pproc.BuildID)
+	if err != nil {
+		log.Errorf("cannot find build with id %d: %s", pproc.BuildID, err)
+		return nil
+	}
+
+	uploads := map[string]*rpc.Upload{}
+	for _, stage := range pipeline.Config.Stages {
+		for _, step := range stage.Steps {
+			proc, err := s.store.ProcChild(build, pproc.PID, step.Alias)
+			if err != nil {
+				continue
+			}
+			link, err := Config.Services.Uploads.UploadLogs(proc)
+			if err != nil {
+				log.Errorf("cannot pre-sign the log upload of step %s: %s", step.Alias, err)
+				continue
+			}
+			uploads[step.Alias] = &rpc.Upload{Logs: link}
+		}
+	}
+	return uploads
+}
+
+// nodeLabelPrefix is the prefix of the task labels of the node selectors
+// declared in the yaml, which must match the node labels of the agent.
+const nodeLabelPrefix = "node."
+
+// matchNode returns true if the agent node labels match every node selector
+// of the task.
+func matchNode(task *queue.Task, node map[string]string) bool {
+	for k, v := range task.Labels {
+		if !strings.HasPrefix(k, nodeLabelPrefix) {
+			continue
+		}
+		if node[strings.TrimPrefix(k, nodeLabelPrefix)] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// taskWeight returns the number of agent slots occupied by the task,
+// as declared by the weight label in the yaml. The default is 1. The
+// weight is limited to the capacity of the agent, so a task that is
+// heavier than the agent runs once every slot of the agent is free.
+func taskWeight(task *queue.Task, filter rpc.Filter) int {
+	weight, err := strconv.Atoi(task.Labels["weight"])
+	if err != nil || weight < 1 {
+		return 1
+	}
+	if filter.Capacity != 0 && weight > filter.Capacity {
+		return filter.Capacity
+	}
+	return weight
+}
+
 // Wait implements the rpc.Wait function
 func (s *RPC) Wait(c context.Context, id string) error {
 	return s.queue.Wait(c, id)
@@ -155,6 +306,7 @@ func (s *RPC) Extend(c context.Context, id string) error {
 
 // Update implements the rpc.Update function
 func (s *RPC) Update(c context.Context, id string, state rpc.State) error {
+	log := s.log.WithFields(logrus.Fields{"rpc": "update", "proc": id})
 	procID, err := strconv.ParseInt(id, 10, 64)
 	if err != nil {
 		return err
@@ -162,25 +314,26 @@ func (s *RPC) Update(c context.Context, id string, state rpc.State) error {
 
 	pproc, err := s.store.ProcLoad(procID)
 	if err != nil {
-		log.Printf("error: rpc.update: cannot find pproc with id %d: %s", procID, err)
+		log.Errorf("cannot find pproc with id %d: %s", procID, err)
 		return err
 	}
 
 	build, err := s.store.GetBuild(pproc.BuildID)
 	if err != nil {
-		log.Printf("error: cannot find build with id %d: %s", pproc.BuildID, err)
+		log.Errorf("cannot find build with id %d: %s", pproc.BuildID, err)
 		return err
 	}
 
-	proc, err := s.store.ProcChild(build, pproc.PID, state.Proc)
+	proc, err := s.procChild(build, pproc, state.Proc)
 	if err != nil {
-		log.Printf("error: cannot find proc with name %s: %s", state.Proc, err)
+		log.Errorf("cannot find proc with name %s: %s", state.Proc, err)
 		return err
 	}
+	proc = procUpdates.get(proc)
 
 	repo, err := s.store.GetRepo(build.RepoID)
 	if err != nil {
-		log.Printf("error: cannot find repo with id %d: %s", build.RepoID, err)
+		log.Errorf("cannot find repo with id %d: %s", build.RepoID, err)
 		return err
 	}
 
@@ -191,17 +344,24 @@ func (s *RPC) Update(c context.Context, id string, state rpc.State) error {
 		proc.State = model.StatusSuccess
 		if state.ExitCode != 0 || state.Error != "" {
 			proc.State = model.StatusFailure
+			if state.AllowFailure {
+				proc.State = model.StatusFailureAllowed
+			}
 		}
 	} else {
 		proc.Started = state.Started
 		proc.State = model.StatusRunning
 	}
 
-	if err := s.store.ProcUpdate(proc); err != nil {
-		log.Printf("error: rpc.update: cannot update proc: %s", err)
+	if err := procUpdates.update(s.store, proc); err != nil {
+		log.Errorf("cannot update proc: %s", err)
+	}
+	if !isAgentStep(proc.Name) {
+		s.updateCheck(repo, build, proc)
 	}
 
 	build.Procs, _ = s.store.ProcList(build)
+	procUpdates.apply(build.Procs)
 	build.Procs = model.Tree(build.Procs)
 	message := pubsub.Message{
 		Labels: map[string]string{
@@ -218,8 +378,105 @@ func (s *RPC) Update(c context.Context, id string, state rpc.State) error {
 	return nil
 }
 
+// procChild returns the named child process. Steps that are added to
+// the pipeline by the agent, and are therefore not known to the server
+// (e.g. agent hooks), are created on first use.
+func (s *RPC) procChild(build *model.Build, pproc *model.Proc, name string) (*model.Proc, error) {
+	proc, err := s.store.ProcChild(build, pproc.PID, name)
+	if err == nil || !isAgentStep(name) {
+		return proc, err
+	}
+	// the pid is allocated from the procs of the build, and the unique
+	// index of the build pid rejects a pid allocated concurrently, in
+	// which case the pid is allocated again.
+	for i := 0; i < maxProcChildAttempts; i++ {
+		procs, err := s.store.ProcList(build)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range procs {
+			if p.PPID == pproc.PID && p.Name == name {
+				return p, nil
+			}
+		}
+		proc = &model.Proc{
+			BuildID: build.ID,
+			PPID:    pproc.PID,
+			PGID:    pproc.PGID,
+			Name:    name,
+			State:   model.StatusPending,
+		}
+		for _, p := range procs {
+			if p.PID > proc.PID {
+				proc.PID = p.PID
+			}
+		}
+		proc.PID++
+		if err = s.store.ProcCreate([]*model.Proc{proc}); err == nil {
+			return proc, nil
+		}
+		s.log.Debugf("cannot create proc %s with pid %d, retrying: %s", name, proc.PID, err)
+	}
+	return nil, fmt.Errorf("cannot allocate a pid for proc %s", name)
+}
+
+// maxProcChildAttempts is the number of attempts to allocate the pid of a
+// proc added to the pipeline by the agent.
+const maxProcChildAttempts = 5
+
+// isAgentStep returns true if the named step was added to the
+// pipeline by the agent.
+func isAgentStep(name string) bool {
+	return name == rpc.HookPre || name == rpc.HookPost
+}
+
+// maxCheckLines is the maximum number of trailing log lines included in the
+// step results reported to the remote system.
+const maxCheckLines = 50
+
+// updateCheck reports the step state to the remote system, if the remote
+// system supports per-step results.
+func (s *RPC) updateCheck(repo *model.Repo, build *model.Build, proc *model.Proc) {
+	checker, ok := s.remote.(remote.Checker)
+	if !ok {
+		return
+	}
+	user, err := s.store.GetUser(repo.UserID)
+	if err != nil {
+		return
+	}
+	var logs string
+	if proc.Stopped != 0 {
+		logs = s.logExcerpt(proc)
+	}
+	uri := fmt.Sprintf("%s/%s/%d", s.host, repo.FullName, build.Number)
+	if err := checker.Check(user, repo, build, proc, uri, logs); err != nil {
+		s.log.Errorf("error setting check run for %s/%d step %s. %s", repo.FullName, build.Number, proc.Name, err)
+	}
+}
+
+// logExcerpt returns the trailing lines of the step logs.
+func (s *RPC) logExcerpt(proc *model.Proc) string {
+	rc, err := s.store.LogTail(proc, maxCheckLines)
+	if err != nil {
+		return ""
+	}
+	defer rc.Close()
+
+	var lines []*rpc.Line
+	if err := json.NewDecoder(rc).Decode(&lines); err != nil {
+		return ""
+	}
+	var out []string
+	for _, line := range lines {
+		out = append(out, strings.TrimRight(line.Out, "\r\n"))
+	}
+	return strings.Join(out, "\n")
+}
+
 // Upload implements the rpc.Upload function
 func (s *RPC) Upload(c context.Context, id string, file *rpc.File) error {
+	log := s.log.WithFields(logrus.Fields{"rpc": "upload", "proc": id})
 	procID, err := strconv.ParseInt(id, 10, 64)
 	if err != nil {
 		return err
@@ -227,19 +484,19 @@ func (s *RPC) Upload(c context.Context, id string, file *rpc.File) error {
 
 	pproc, err := s.store.ProcLoad(procID)
 	if err != nil {
-		log.Printf("error: cannot find parent proc with id %d: %s", procID, err)
+		log.Errorf("cannot find parent proc with id %d: %s", procID, err)
 		return err
 	}
 
 	build, err := s.store.GetBuild(pproc.BuildID)
 	if err != nil {
-		log.Printf("error: cannot find build with id %d: %s", pproc.BuildID, err)
+		log.Errorf("cannot find build with id %d: %s", pproc.BuildID, err)
 		return err
 	}
 
-	proc, err := s.store.ProcChild(build, pproc.PID, file.Proc)
+	proc, err := s.procChild(build, pproc, file.Proc)
 	if err != nil {
-		log.Printf("error: cannot find child proc with name %s: %s", file.Proc, err)
+		log.Errorf("cannot find child proc with name %s: %s", file.Proc, err)
 		return err
 	}
 
@@ -264,6 +521,7 @@ func (s *RPC) Upload(c context.Context, id string, file *rpc.File) error {
 
 // Init implements the rpc.Init function
 func (s *RPC) Init(c context.Context, id string, state rpc.State) error {
+	log := s.log.WithFields(logrus.Fields{"rpc": "init", "proc": id})
 	procID, err := strconv.ParseInt(id, 10, 64)
 	if err != nil {
 		return err
@@ -271,19 +529,19 @@ func (s *RPC) Init(c context.Context, id string, state rpc.State) error {
 
 	proc, err := s.store.ProcLoad(procID)
 	if err != nil {
-		log.Printf("error: cannot find proc with id %d: %s", procID, err)
+		log.Errorf("cannot find proc with id %d: %s", procID, err)
 		return err
 	}
 
 	build, err := s.store.GetBuild(proc.BuildID)
 	if err != nil {
-		log.Printf("error: cannot find build with id %d: %s", proc.BuildID, err)
+		log.Errorf("cannot find build with id %d: %s", proc.BuildID, err)
 		return err
 	}
 
 	repo, err := s.store.GetRepo(build.RepoID)
 	if err != nil {
-		log.Printf("error: cannot find repo with id %d: %s", build.RepoID, err)
+		log.Errorf("cannot find repo with id %d: %s", build.RepoID, err)
 		return err
 	}
 
@@ -291,8 +549,13 @@ func (s *RPC) Init(c context.Context, id string, state rpc.State) error {
 		build.Status = model.StatusRunning
 		build.Started = state.Started
 		if err := s.store.UpdateBuild(build); err != nil {
-			log.Printf("error: init: cannot update build_id %d state: %s", build.ID, err)
+			log.Errorf("cannot update build_id %d state: %s", build.ID, err)
 		}
+		sendWebhook(&model.WebhookPayload{
+			Event: model.WebhookBuildStarted,
+			Repo:  repo,
+			Build: build,
+		})
 	}
 
 	defer func() {
@@ -312,31 +575,37 @@ func (s *RPC) Init(c context.Context, id string, state rpc.State) error {
 
 	proc.Started = state.Started
 	proc.State = model.StatusRunning
+	proc.Machine = s.agent
 	return s.store.ProcUpdate(proc)
 }
 
 // Done implements the rpc.Done function
 func (s *RPC) Done(c context.Context, id string, state rpc.State) error {
+	log := s.log.WithFields(logrus.Fields{"rpc": "done", "proc": id})
 	procID, err := strconv.ParseInt(id, 10, 64)
 	if err != nil {
 		return err
 	}
 
+	// the pending step updates are written before the build status is
+	// computed from the steps.
+	procUpdates.flush()
+
 	proc, err := s.store.ProcLoad(procID)
 	if err != nil {
-		log.Printf("error: cannot find proc with id %d: %s", procID, err)
+		log.Errorf("cannot find proc with id %d: %s", procID, err)
 		return err
 	}
 
 	build, err := s.store.GetBuild(proc.BuildID)
 	if err != nil {
-		log.Printf("error: cannot find build with id %d: %s", proc.BuildID, err)
+		log.Errorf("cannot find build with id %d: %s", proc.BuildID, err)
 		return err
 	}
 
 	repo, err := s.store.GetRepo(build.RepoID)
 	if err != nil {
-		log.Printf("error: cannot find repo with id %d: %s", build.RepoID, err)
+		log.Errorf("cannot find repo with id %d: %s", build.RepoID, err)
 		return err
 	}
 
@@ -348,11 +617,11 @@ func (s *RPC) Done(c context.Context, id string, state rpc.State) error {
 		proc.State = model.StatusFailure
 	}
 	if err := s.store.ProcUpdate(proc); err != nil {
-		log.Printf("error: done: cannot update proc_id %d state: %s", procID, err)
+		log.Errorf("cannot update proc_id %d state: %s", procID, err)
 	}
 
 	if err := s.queue.Done(c, id); err != nil {
-		log.Printf("error: done: cannot ack proc_id %d: %s", procID, err)
+		log.Errorf("cannot ack proc_id %d: %s", procID, err)
 	}
 
 	// TODO handle this error
@@ -365,7 +634,17 @@ func (s *RPC) Done(c context.Context, id string, state rpc.State) error {
 				p.Stopped = proc.Stopped
 			}
 			if err := s.store.ProcUpdate(p); err != nil {
-				log.Printf("error: done: cannot update proc_id %d child state: %s", p.ID, err)
+				log.Errorf("cannot update proc_id %d child state: %s", p.ID, err)
+			}
+		}
+	}
+
+	// queue the pipelines that depend on the completed pipeline, or skip
+	// them if a dependency did not succeed.
+	if proc.PPID == 0 {
+		for _, p := range releaseBuildItems(s.store, build, procs) {
+			if err := s.store.ProcUpdate(p); err != nil {
+				log.Errorf("cannot update proc_id %d dependent state: %s", p.ID, err)
 			}
 		}
 	}
@@ -386,8 +665,20 @@ func (s *RPC) Done(c context.Context, id string, state rpc.State) error {
 		build.Status = status
 		build.Finished = proc.Stopped
 		if err := s.store.UpdateBuild(build); err != nil {
-			log.Printf("error: done: cannot update build_id %d final state: %s", build.ID, err)
+			log.Errorf("cannot update build_id %d final state: %s", build.ID, err)
+		}
+		metrics.Builds.WithLabelValues(build.Status).Inc()
+		sendWebhook(&model.WebhookPayload{
+			Event: model.WebhookBuildFinished,
+			Repo:  repo,
+			Build: build,
+		})
+		if build.Status == model.StatusSuccess && build.Event != model.EventPull {
+			go triggerDownstream(s.store, s.remote, repo, build)
 		}
+		link := fmt.Sprintf("%s/%s/%d", s.host, repo.FullName, build.Number)
+		go notifyBuild(s.store, repo, build, link)
+		go notifyChat(repo, build, link)
 
 		// update the status
 		user, err := s.store.GetUser(repo.UserID)
@@ -401,13 +692,13 @@ func (s *RPC) Done(c context.Context, id string, state rpc.State) error {
 			uri := fmt.Sprintf("%s/%s/%d", s.host, repo.FullName, build.Number)
 			err = s.remote.Status(user, repo, build, uri)
 			if err != nil {
-				logrus.Errorf("error setting commit status for %s/%d", repo.FullName, build.Number)
+				log.Errorf("error setting commit status for %s/%d", repo.FullName, build.Number)
 			}
 		}
 	}
 
 	if err := s.logger.Close(c, id); err != nil {
-		log.Printf("error: done: cannot close build_id %d logger: %s", proc.ID, err)
+		log.Errorf("cannot close build_id %d logger: %s", proc.ID, err)
 	}
 
 	build.Procs = model.Tree(procs)
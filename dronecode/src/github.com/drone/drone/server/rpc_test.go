@@ -0,0 +1,208 @@
+package server
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/cncd/pipeline/pipeline/backend"
+	"github.com/cncd/pipeline/pipeline/rpc"
+	"github.com/cncd/pubsub"
+	"github.com/cncd/queue"
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/store"
+	"github.com/drone/drone/store/datastore"
+)
+
+// staleStore is a store that omits the last proc from the first proc list,
+// as if the proc was created concurrently.
+type staleStore struct {
+	store.Store
+
+	stale bool
+}
+
+func (s *staleStore) ProcList(build *model.Build) ([]*model.Proc, error) {
+	procs, err := s.Store.ProcList(build)
+	if err != nil || s.stale {
+		return procs, err
+	}
+	s.stale = true
+	return procs[:len(procs)-1], nil
+}
+
+func TestProcChild(t *testing.T) {
+	s := &staleStore{Store: datastore.New("sqlite3", ":memory:")}
+	build := &model.Build{ID: 1}
+	pproc := &model.Proc{BuildID: 1, PID: 1, PGID: 1, Name: "build"}
+	procs := []*model.Proc{
+		pproc,
+		{BuildID: 1, PID: 2, PPID: 1, PGID: 1, Name: "test"},
+	}
+	if err := s.ProcCreate(procs); err != nil {
+		t.Fatal(err)
+	}
+
+	peer := &RPC{store: s, log: logrus.NewEntry(logrus.StandardLogger())}
+	proc, err := peer.procChild(build, pproc, rpc.HookPre)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proc.PID != 3 || proc.PPID != 1 || proc.ID == 0 {
+		t.Errorf("Want the proc created with the next free pid, got pid %d", proc.PID)
+	}
+
+	again, err := peer.procChild(build, pproc, rpc.HookPre)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != proc.ID {
+		t.Errorf("Want the existing proc %d, got %d", proc.ID, again.ID)
+	}
+
+	if _, err := peer.procChild(build, pproc, "deploy"); err == nil {
+		t.Errorf("Want error finding a proc that is not added by the agent")
+	}
+}
+
+// presignUploads is an upload service that returns the url of the proc.
+type presignUploads struct{}
+
+func (presignUploads) UploadLogs(proc *model.Proc) (string, error) {
+	return "https://bucket.s3.amazonaws.com/" + strconv.FormatInt(proc.ID, 10), nil
+}
+
+func TestUploadURLs(t *testing.T) {
+	defer func(u model.UploadService) {
+		Config.Services.Uploads = u
+	}(Config.Services.Uploads)
+
+	s := datastore.New("sqlite3", ":memory:")
+	pproc := &model.Proc{PID: 1, PGID: 1, Name: "build"}
+	proc := &model.Proc{PID: 2, PPID: 1, PGID: 1, Name: "test"}
+	if err := s.CreateBuild(&model.Build{RepoID: 1}, pproc, proc); err != nil {
+		t.Fatal(err)
+	}
+	pipeline := &rpc.Pipeline{
+		ID: strconv.FormatInt(pproc.ID, 10),
+		Config: &backend.Config{Stages: []*backend.Stage{
+			{Steps: []*backend.Step{{Alias: "clone"}}},
+			{Steps: []*backend.Step{{Alias: "test"}}},
+		}},
+	}
+	peer := &RPC{store: s, log: logrus.NewEntry(logrus.StandardLogger())}
+
+	Config.Services.Uploads = nil
+	if uploads := peer.uploadURLs(pipeline); uploads != nil {
+		t.Errorf("Want no upload urls without an upload service, got %v", uploads)
+	}
+
+	Config.Services.Uploads = presignUploads{}
+	uploads := peer.uploadURLs(pipeline)
+	if len(uploads) != 1 || uploads["test"] == nil {
+		t.Fatalf("Want the upload url of the test step, got %v", uploads)
+	}
+	if got, want := uploads["test"].Logs, "https://bucket.s3.amazonaws.com/"+strconv.FormatInt(proc.ID, 10); got != want {
+		t.Errorf("Want log upload url %s, got %s", want, got)
+	}
+}
+
+func TestUpdateAllowFailure(t *testing.T) {
+	s := datastore.New("sqlite3", ":memory:")
+	repo := &model.Repo{UserID: 1, FullName: "octocat/hello-world", Owner: "octocat", Name: "hello-world"}
+	if err := s.CreateRepo(repo); err != nil {
+		t.Fatal(err)
+	}
+	build := &model.Build{RepoID: repo.ID}
+	if err := s.CreateBuild(build); err != nil {
+		t.Fatal(err)
+	}
+	pproc := &model.Proc{BuildID: build.ID, PID: 1, PGID: 1, Name: "build"}
+	procs := []*model.Proc{
+		pproc,
+		{BuildID: build.ID, PID: 2, PPID: 1, PGID: 1, Name: "lint"},
+		{BuildID: build.ID, PID: 3, PPID: 1, PGID: 1, Name: "test"},
+	}
+	if err := s.ProcCreate(procs); err != nil {
+		t.Fatal(err)
+	}
+
+	peer := &RPC{store: s, pubsub: pubsub.New(), log: logrus.NewEntry(logrus.StandardLogger())}
+	id := strconv.FormatInt(pproc.ID, 10)
+	tests := []struct {
+		state rpc.State
+		want  string
+	}{
+		{state: rpc.State{Proc: "lint", Exited: true, ExitCode: 1, AllowFailure: true}, want: model.StatusFailureAllowed},
+		{state: rpc.State{Proc: "test", Exited: true, ExitCode: 1}, want: model.StatusFailure},
+	}
+	for _, test := range tests {
+		if err := peer.Update(context.Background(), id, test.state); err != nil {
+			t.Fatal(err)
+		}
+		proc, err := s.ProcChild(build, pproc.PID, test.state.Proc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if proc.State != test.want {
+			t.Errorf("Want proc %s state %s, got %s", test.state.Proc, test.want, proc.State)
+		}
+	}
+}
+
+func TestTaskWeight(t *testing.T) {
+	tests := []struct {
+		label    string
+		capacity int
+		want     int
+	}{
+		{"", 0, 1},
+		{"invalid", 4, 1},
+		{"-2", 4, 1},
+		{"3", 0, 3},
+		{"3", 4, 3},
+		{"8", 4, 4},
+	}
+	for _, test := range tests {
+		task := &queue.Task{Labels: map[string]string{"weight": test.label}}
+		if got := taskWeight(task, rpc.Filter{Capacity: test.capacity}); got != test.want {
+			t.Errorf("Want weight %q with capacity %d to be %d, got %d", test.label, test.capacity, test.want, got)
+		}
+	}
+}
+
+func TestMatchNode(t *testing.T) {
+	item := &buildItem{
+		Proc:     &model.Proc{ID: 1, BuildID: 1},
+		Platform: "linux/amd64",
+		Labels:   map[string]string{"team": "ml"},
+		Node:     map[string]string{"gpu": "true", "zone": "eu-west-1"},
+	}
+	task := newBuildTask(&model.Repo{FullName: "octocat/hello-world", Owner: "octocat"}, item)
+	if task.Labels["node.gpu"] != "true" || task.Labels["node.zone"] != "eu-west-1" || task.Labels["team"] != "ml" {
+		t.Errorf("Want the node selectors added to the task labels, got %v", task.Labels)
+	}
+
+	tests := []struct {
+		node  map[string]string
+		match bool
+	}{
+		{map[string]string{"gpu": "true", "zone": "eu-west-1"}, true},
+		{map[string]string{"gpu": "true", "zone": "eu-west-1", "disk": "ssd"}, true},
+		{map[string]string{"gpu": "true"}, false},
+		{map[string]string{"gpu": "false", "zone": "eu-west-1"}, false},
+		{nil, false},
+	}
+	for _, test := range tests {
+		if got := matchNode(task, test.node); got != test.match {
+			t.Errorf("Want agent node %v match %v, got %v", test.node, test.match, got)
+		}
+	}
+
+	// tasks without node selectors match every agent.
+	task = newBuildTask(&model.Repo{FullName: "octocat/hello-world"}, &buildItem{Proc: new(model.Proc)})
+	if !matchNode(task, nil) || !matchNode(task, map[string]string{"gpu": "true"}) {
+		t.Errorf("Want a task without node selectors to match every agent")
+	}
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/drone/drone/cache"
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/router/middleware/session"
+	"github.com/drone/drone/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxReposPerPage is the maximum number of repositories per page of the
+// repository search.
+const maxReposPerPage = 100
+
+// activeBatchSize is the number of repositories looked up in the database
+// in a single query, which is limited by the maximum number of sql
+// parameters.
+const activeBatchSize = 500
+
+// GetRepoSearch searches the repositories of the user in the remote system
+// by name and organization, and writes a page of the matching repositories
+// to the response in json format. Active repositories include the
+// repository settings, and the active parameter filters the results by
+// activation status. The total count of matching repositories is written
+// to the X-Total-Count header.
+func GetRepoSearch(c *gin.Context) {
+	var (
+		query = strings.ToLower(c.Query("q"))
+		org   = c.Query("org")
+	)
+	remote, err := cache.GetRepos(c, session.User(c))
+	if err != nil {
+		c.String(500, "Error fetching repository list. %s", err)
+		return
+	}
+
+	var matched []*model.RepoLite
+	for _, repo := range remote {
+		if org != "" && !strings.EqualFold(repo.Owner, org) {
+			continue
+		}
+		if query != "" && !strings.Contains(strings.ToLower(repo.FullName), query) {
+			continue
+		}
+		matched = append(matched, repo)
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].FullName < matched[j].FullName
+	})
+
+	active, err := activeRepos(store.FromContext(c), matched)
+	if err != nil {
+		c.String(500, "Error fetching repository list. %s", err)
+		return
+	}
+
+	repos := []*model.Repo{}
+	for _, repo := range matched {
+		r, ok := active[repo.FullName]
+		if v := c.Query("active"); v != "" && strconv.FormatBool(ok) != v {
+			continue
+		}
+		if !ok {
+			r = &model.Repo{
+				Avatar:   repo.Avatar,
+				FullName: repo.FullName,
+				Owner:    repo.Owner,
+				Name:     repo.Name,
+			}
+		}
+		repos = append(repos, r)
+	}
+
+	page, _ := strconv.Atoi(c.Query("page"))
+	if page < 1 {
+		page = 1
+	}
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "50"))
+	if perPage < 1 || perPage > maxReposPerPage {
+		perPage = maxReposPerPage
+	}
+	c.Header("X-Total-Count", strconv.Itoa(len(repos)))
+
+	start := (page - 1) * perPage
+	if start > len(repos) {
+		start = len(repos)
+	}
+	end := start + perPage
+	if end > len(repos) {
+		end = len(repos)
+	}
+	c.JSON(http.StatusOK, repos[start:end])
+}
+
+// GetOrgs gets the organizations of the repositories of the user in the
+// remote system, with the number of repositories and active repositories,
+// and writes to the response in json format.
+func GetOrgs(c *gin.Context) {
+	remote, err := cache.GetRepos(c, session.User(c))
+	if err != nil {
+		c.String(500, "Error fetching repository list. %s", err)
+		return
+	}
+	active, err := activeRepos(store.FromContext(c), remote)
+	if err != nil {
+		c.String(500, "Error fetching repository list. %s", err)
+		return
+	}
+
+	orgs := []*model.Org{}
+	index := map[string]*model.Org{}
+	for _, repo := range remote {
+		org, ok := index[repo.Owner]
+		if !ok {
+			org = &model.Org{Name: repo.Owner, Avatar: repo.Avatar}
+			index[repo.Owner] = org
+			orgs = append(orgs, org)
+		}
+		org.Repos++
+		if _, ok := active[repo.FullName]; ok {
+			org.Active++
+		}
+	}
+	sort.Slice(orgs, func(i, j int) bool {
+		return orgs[i].Name < orgs[j].Name
+	})
+	c.JSON(http.StatusOK, orgs)
+}
+
+// helper function returns the active repositories of the list, by full
+// name.
+func activeRepos(s store.Store, listof []*model.RepoLite) (map[string]*model.Repo, error) {
+	active := map[string]*model.Repo{}
+	for i := 0; i < len(listof); i += activeBatchSize {
+		end := i + activeBatchSize
+		if end > len(listof) {
+			end = len(listof)
+		}
+		repos, err := s.GetRepoListOf(listof[i:end])
+		if err != nil {
+			return nil, err
+		}
+		for _, repo := range repos {
+			active[repo.FullName] = repo
+		}
+	}
+	return active, nil
+}
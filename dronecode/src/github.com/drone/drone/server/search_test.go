@@ -0,0 +1,127 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/drone/drone/cache"
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/remote"
+	"github.com/drone/drone/store"
+	"github.com/drone/drone/store/datastore"
+
+	"github.com/gin-gonic/gin"
+)
+
+// reposRemote lists the repositories of the user.
+type reposRemote struct {
+	remote.Remote
+
+	repos []*model.RepoLite
+}
+
+func (r *reposRemote) Repos(u *model.User) ([]*model.RepoLite, error) {
+	return r.repos, nil
+}
+
+func TestGetRepoSearch(t *testing.T) {
+	e := testSearchRouter(t)
+
+	tests := []struct {
+		query string
+		want  []string
+		total string
+	}{
+		{query: "", total: "4", want: []string{"octocat/hello-world", "octocat/spoon-knife", "spaceghost/hello-world", "spaceghost/linguist"}},
+		{query: "q=HELLO", total: "2", want: []string{"octocat/hello-world", "spaceghost/hello-world"}},
+		{query: "org=octocat", total: "2", want: []string{"octocat/hello-world", "octocat/spoon-knife"}},
+		{query: "active=true", total: "1", want: []string{"octocat/hello-world"}},
+		{query: "active=false&org=spaceghost", total: "2", want: []string{"spaceghost/hello-world", "spaceghost/linguist"}},
+		{query: "per_page=3&page=2", total: "4", want: []string{"spaceghost/linguist"}},
+		{query: "per_page=3&page=3", total: "4", want: []string{}},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest("GET", "/api/user/repos/search?"+test.query, nil)
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+
+		var repos []*model.Repo
+		if err := json.Unmarshal(w.Body.Bytes(), &repos); err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, repo := range repos {
+			got = append(got, repo.FullName)
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("Want search %q results %v, got %v", test.query, test.want, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("Want search %q results %v, got %v", test.query, test.want, got)
+				break
+			}
+		}
+		if total := w.Header().Get("X-Total-Count"); total != test.total {
+			t.Errorf("Want search %q total count %s, got %s", test.query, test.total, total)
+		}
+	}
+}
+
+func TestGetOrgs(t *testing.T) {
+	e := testSearchRouter(t)
+
+	req, _ := http.NewRequest("GET", "/api/user/orgs", nil)
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+
+	var orgs []*model.Org
+	if err := json.Unmarshal(w.Body.Bytes(), &orgs); err != nil {
+		t.Fatal(err)
+	}
+	want := []model.Org{
+		{Name: "octocat", Repos: 2, Active: 1},
+		{Name: "spaceghost", Repos: 2, Active: 0},
+	}
+	if len(orgs) != len(want) {
+		t.Fatalf("Want %d organizations, got %d", len(want), len(orgs))
+	}
+	for i, org := range orgs {
+		if *org != want[i] {
+			t.Errorf("Want organization %+v, got %+v", want[i], *org)
+		}
+	}
+}
+
+// helper function returns a router serving the search endpoints for a
+// user with four repositories, of which octocat/hello-world is active.
+func testSearchRouter(t *testing.T) http.Handler {
+	s := datastore.New("sqlite3", ":memory:")
+	if err := s.CreateRepo(&model.Repo{UserID: 1, Owner: "octocat", Name: "hello-world", FullName: "octocat/hello-world"}); err != nil {
+		t.Fatal(err)
+	}
+	r := &reposRemote{
+		repos: []*model.RepoLite{
+			{Owner: "spaceghost", Name: "linguist", FullName: "spaceghost/linguist"},
+			{Owner: "octocat", Name: "spoon-knife", FullName: "octocat/spoon-knife"},
+			{Owner: "octocat", Name: "hello-world", FullName: "octocat/hello-world"},
+			{Owner: "spaceghost", Name: "hello-world", FullName: "spaceghost/hello-world"},
+		},
+	}
+	c := cache.Default()
+
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.Use(func(ctx *gin.Context) {
+		store.ToContext(ctx, s)
+		remote.ToContext(ctx, r)
+		cache.ToContext(ctx, c)
+		ctx.Set("user", &model.User{ID: 1, Login: "octocat"})
+	})
+	e.GET("/api/user/repos/search", GetRepoSearch)
+	e.GET("/api/user/orgs", GetOrgs)
+	return e
+}
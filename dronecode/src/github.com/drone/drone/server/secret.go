@@ -34,11 +34,12 @@ func PostSecret(c *gin.Context) {
 		return
 	}
 	secret := &model.Secret{
-		RepoID: repo.ID,
-		Name:   in.Name,
-		Value:  in.Value,
-		Events: in.Events,
-		Images: in.Images,
+		RepoID:   repo.ID,
+		Name:     in.Name,
+		Value:    in.Value,
+		Events:   in.Events,
+		Images:   in.Images,
+		Branches: in.Branches,
 	}
 	if err := secret.Validate(); err != nil {
 		c.String(400, "Error inserting secret. %s", err)
@@ -48,6 +49,7 @@ func PostSecret(c *gin.Context) {
 		c.String(500, "Error inserting secret %q. %s", in.Name, err)
 		return
 	}
+	recordAudit(c, model.AuditSecretCreate, repo.FullName, secret.Name)
 	c.JSON(200, secret.Copy())
 }
 
@@ -79,6 +81,9 @@ func PatchSecret(c *gin.Context) {
 	if len(in.Images) != 0 {
 		secret.Images = in.Images
 	}
+	if len(in.Branches) != 0 {
+		secret.Branches = in.Branches
+	}
 
 	if err := secret.Validate(); err != nil {
 		c.String(400, "Error updating secret. %s", err)
@@ -88,6 +93,7 @@ func PatchSecret(c *gin.Context) {
 		c.String(500, "Error updating secret %q. %s", in.Name, err)
 		return
 	}
+	recordAudit(c, model.AuditSecretUpdate, repo.FullName, secret.Name)
 	c.JSON(200, secret.Copy())
 }
 
@@ -118,5 +124,6 @@ func DeleteSecret(c *gin.Context) {
 		c.String(500, "Error deleting secret %q. %s", name, err)
 		return
 	}
+	recordAudit(c, model.AuditSecretDelete, repo.FullName, name)
 	c.String(204, "")
 }
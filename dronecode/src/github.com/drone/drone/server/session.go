@@ -0,0 +1,79 @@
+package server
+
+import (
+	"encoding/base32"
+	"time"
+
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/router/middleware/session"
+	"github.com/drone/drone/shared/httputil"
+	"github.com/drone/drone/shared/token"
+	"github.com/drone/drone/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/securecookie"
+)
+
+// PostSessionRevoke revokes the sessions and user tokens of the
+// authenticated user, including the current session.
+func PostSessionRevoke(c *gin.Context) {
+	user := session.User(c)
+	if err := revokeSessions(store.FromContext(c), user); err != nil {
+		c.String(500, "Error revoking sessions. %s", err)
+		return
+	}
+	recordAudit(c, model.AuditSessionRevoke, "", user.Login)
+	httputil.DelCookie(c.Writer, c.Request, "user_sess")
+	c.String(204, "")
+}
+
+// PostUserSessionRevoke revokes the sessions and user tokens of the user,
+// which forces the user to login again.
+func PostUserSessionRevoke(c *gin.Context) {
+	user, err := store.GetUserLogin(c, c.Param("login"))
+	if err != nil {
+		c.String(404, "Cannot find user. %s", err)
+		return
+	}
+	if err := revokeSessions(store.FromContext(c), user); err != nil {
+		c.String(500, "Error revoking sessions. %s", err)
+		return
+	}
+	recordAudit(c, model.AuditSessionRevoke, "", user.Login)
+	c.String(204, "")
+}
+
+// helper function revokes the sessions, user tokens and csrf tokens of the
+// user, which are signed with the user hash, by generating a new hash.
+// Personal access tokens are signed with their own hash, and are revoked
+// individually.
+func revokeSessions(s store.Store, user *model.User) error {
+	user.Hash = base32.StdEncoding.EncodeToString(
+		securecookie.GenerateRandomKey(32),
+	)
+	return s.UpdateUser(user)
+}
+
+// helper function returns a session token of the user, and the expiry of
+// the session. Sessions expire after 72 hours unless configured otherwise.
+func sessionToken(user *model.User) (string, int64, error) {
+	expires := Config.Session.Expires
+	if expires <= 0 {
+		expires = time.Hour * 72
+	}
+	exp := time.Now().Add(expires).Unix()
+	tokenstr, err := token.New(token.SessToken, user.Login).SignExpires(user.Hash, exp)
+	return tokenstr, exp, err
+}
+
+// helper function returns a user token of the user, which expires after the
+// configured token lifetime, or never if the lifetime is zero. User tokens
+// are deprecated in favor of access tokens, and are limited to the legacy
+// token scopes, see model.LegacyTokenScopes.
+func userToken(user *model.User) (string, error) {
+	var exp int64
+	if Config.Session.TokenExpires > 0 {
+		exp = time.Now().Add(Config.Session.TokenExpires).Unix()
+	}
+	return token.New(token.UserToken, user.Login).SignExpires(user.Hash, exp)
+}
@@ -1,8 +1,12 @@
 package server
 
 import (
+	"bytes"
 	"io/ioutil"
+	"strings"
 
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/remote"
 	"github.com/drone/drone/router/middleware/session"
 
 	"github.com/gin-gonic/gin"
@@ -38,3 +42,36 @@ func Sign(c *gin.Context) {
 
 	c.String(200, out)
 }
+
+// verifyConfig returns true if the yaml configuration of the build has a
+// signature file, and true if the signature file is a valid signature of
+// the configuration file, signed with the repository secret. The signature
+// is verified against the file of the repository, before the configuration
+// is evaluated or expanded. Configurations that include files are not
+// verified, since the included files are not covered by the signature.
+func verifyConfig(r remote.Remote, user *model.User, repo *model.Repo, build *model.Build) (signed, verified bool) {
+	path, data, err := fetchConfigFile(r, user, repo, build)
+	if err != nil {
+		return false, false
+	}
+	sig, err := r.File(user, repo, build, path+".sig")
+	if err != nil || len(sig) == 0 {
+		return false, false
+	}
+	obj, err := jose.ParseSigned(strings.TrimSpace(string(sig)))
+	if err != nil {
+		return true, false
+	}
+	payload, err := obj.Verify([]byte(repo.Hash))
+	if err != nil {
+		return true, false
+	}
+	return true, bytes.Equal(payload, data) && !usesIncludes(data)
+}
+
+// stripSecrets returns true if the secrets must not be exposed to the
+// build, because the repository requires signed yaml and the yaml of the
+// pull request from a fork is not signed.
+func stripSecrets(repo *model.Repo, build *model.Build) bool {
+	return repo.RequireSigned && !build.Verified && isFork(repo, build)
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/drone/drone/model"
+
+	"github.com/square/go-jose"
+)
+
+func TestVerifyConfig(t *testing.T) {
+	repo := &model.Repo{FullName: "octocat/hello-world", Config: ".drone.yml", Hash: "secret"}
+	build := &model.Build{Commit: "abc", Event: model.EventPush}
+	config := "pipeline:\n  build:\n    image: golang\n"
+
+	tests := []struct {
+		files    map[string]string
+		signed   bool
+		verified bool
+	}{
+		{
+			files: map[string]string{"octocat/hello-world@abc:.drone.yml": config},
+		},
+		{
+			files: map[string]string{
+				"octocat/hello-world@abc:.drone.yml":     config,
+				"octocat/hello-world@abc:.drone.yml.sig": testSign(t, repo.Hash, config) + "\n",
+			},
+			signed:   true,
+			verified: true,
+		},
+		{
+			files: map[string]string{
+				"octocat/hello-world@abc:.drone.yml":     config + "  test:\n    image: golang\n",
+				"octocat/hello-world@abc:.drone.yml.sig": testSign(t, repo.Hash, config),
+			},
+			signed: true,
+		},
+		{
+			files: map[string]string{
+				"octocat/hello-world@abc:.drone.yml":     config,
+				"octocat/hello-world@abc:.drone.yml.sig": testSign(t, "other", config),
+			},
+			signed: true,
+		},
+		{
+			files: map[string]string{
+				"octocat/hello-world@abc:.drone.yml":     "include: ci/base.yml\n",
+				"octocat/hello-world@abc:.drone.yml.sig": testSign(t, repo.Hash, "include: ci/base.yml\n"),
+			},
+			signed: true,
+		},
+	}
+	for i, test := range tests {
+		signed, verified := verifyConfig(&includeRemote{files: test.files}, new(model.User), repo, build)
+		if signed != test.signed || verified != test.verified {
+			t.Errorf("Want config %d signed %v and verified %v, got %v and %v", i, test.signed, test.verified, signed, verified)
+		}
+	}
+}
+
+func testSign(t *testing.T, secret, data string) string {
+	signer, err := jose.NewSigner(jose.HS256, []byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := signer.Sign([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := signed.CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
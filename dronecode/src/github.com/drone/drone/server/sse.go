@@ -0,0 +1,198 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cncd/logging"
+	"github.com/cncd/pubsub"
+	"github.com/drone/drone/cache"
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/router/middleware/session"
+	"github.com/drone/drone/store"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/gin-gonic/gin"
+)
+
+// EventStreamSSE streams the build events of the repositories visible to
+// the authenticated user as server-sent events. Unauthenticated requests
+// receive the events of public repositories.
+func EventStreamSSE(c *gin.Context) {
+	user := session.User(c)
+	repo := map[string]bool{}
+	if user != nil {
+		repo = userRepoMap(c, user)
+	}
+
+	flusher, ok := startEventStream(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	eventc := make(chan []byte, 10)
+	go func() {
+		// TODO remove this from global config
+		Config.Services.Pubsub.Subscribe(ctx, "topic/events", func(m pubsub.Message) {
+			name := m.Labels["repo"]
+			priv := m.Labels["private"]
+			if repo[name] || priv == "false" {
+				select {
+				case <-ctx.Done():
+				case eventc <- m.Data:
+				}
+			}
+		})
+		cancel()
+	}()
+
+	streamEvents(ctx, c, flusher, "message", eventc)
+}
+
+// LogStreamSSE streams the logs of the running build step as server-sent
+// events. Each event contains a log line, and an eof event is sent when
+// the step completes.
+func LogStreamSSE(c *gin.Context) {
+	repo := session.Repo(c)
+	buildn, _ := strconv.Atoi(c.Param("build"))
+	jobn, _ := strconv.Atoi(c.Param("number"))
+
+	build, err := store.GetBuildNumber(c, repo, buildn)
+	if err != nil {
+		c.String(404, "Cannot find build. %s", err)
+		return
+	}
+	proc, err := store.FromContext(c).ProcFind(build, jobn)
+	if err != nil {
+		c.String(404, "Cannot find proc. %s", err)
+		return
+	}
+	if proc.State != model.StatusRunning {
+		c.String(404, "Cannot stream the logs of a proc that is not running.")
+		return
+	}
+
+	flusher, ok := startEventStream(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logc := make(chan []byte, 10)
+	go func() {
+		// TODO remove global variable
+		Config.Services.Logs.Tail(ctx, fmt.Sprint(proc.ID), func(entries ...*logging.Entry) {
+			for _, entry := range entries {
+				select {
+				case <-ctx.Done():
+					return
+				case logc <- entry.Data:
+				}
+			}
+		})
+		cancel()
+	}()
+
+	streamEvents(ctx, c, flusher, "line", logc)
+	writeEvent(c.Writer, "eof", nil)
+	flusher.Flush()
+}
+
+// helper function writes the server-sent event headers, and returns false
+// if the response writer does not support streaming.
+func startEventStream(c *gin.Context) (http.Flusher, bool) {
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		c.String(500, "Streaming is not supported.")
+		return nil, false
+	}
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Writer.WriteHeader(200)
+	flusher.Flush()
+	return flusher, true
+}
+
+// helper function writes the messages as server-sent events, and a comment
+// each ping period to keep the connection alive, until the context is
+// cancelled or the client disconnects.
+func streamEvents(ctx context.Context, c *gin.Context, flusher http.Flusher, event string, messages <-chan []byte) {
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+
+	closed := c.Writer.CloseNotify()
+	for {
+		select {
+		case <-ctx.Done():
+			// drain the messages received before the stream ended.
+			for {
+				select {
+				case data := <-messages:
+					writeEvent(c.Writer, event, data)
+				default:
+					flusher.Flush()
+					return
+				}
+			}
+		case <-closed:
+			logrus.Debugf("Event stream closed by client")
+			return
+		case data := <-messages:
+			writeEvent(c.Writer, event, data)
+			flusher.Flush()
+		case <-ticker.C:
+			io.WriteString(c.Writer, ": ping\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// helper function writes a server-sent event. Each line of the data is
+// written as a separate data field.
+func writeEvent(w io.Writer, event string, data []byte) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "event: %s\n", event)
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		buf.WriteString("data: ")
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+	w.Write(buf.Bytes())
+}
+
+// helper function returns the repositories the user can view, indexed by
+// full name. The repositories of machine users are the repositories they
+// are granted permissions to.
+func userRepoMap(c *gin.Context, user *model.User) map[string]bool {
+	repo := map[string]bool{}
+	if !user.Machine {
+		repo, _ = cache.GetRepoMap(c, user)
+		if repo == nil {
+			repo = map[string]bool{}
+		}
+		return repo
+	}
+	perms, err := store.FromContext(c).PermList(user)
+	if err != nil {
+		return repo
+	}
+	for _, perm := range perms {
+		if r, err := store.GetRepo(c, perm.RepoID); err == nil && perm.Perm().Pull {
+			repo[r.FullName] = true
+		}
+	}
+	return repo
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cncd/logging"
+	"github.com/cncd/pubsub"
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/store"
+	"github.com/drone/drone/store/datastore"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sentPubsub delivers the messages to the subscriber and returns, which
+// ends the event stream.
+type sentPubsub struct {
+	pubsub.Publisher
+
+	messages []pubsub.Message
+}
+
+func (p *sentPubsub) Subscribe(c context.Context, topic string, receiver pubsub.Receiver) error {
+	for _, m := range p.messages {
+		receiver(m)
+	}
+	return nil
+}
+
+// sentLog delivers the log entries to the handler and returns, as if the
+// log was closed.
+type sentLog struct {
+	logging.Log
+
+	entries []*logging.Entry
+}
+
+func (l *sentLog) Tail(c context.Context, path string, handler logging.Handler) error {
+	handler(l.entries...)
+	return nil
+}
+
+func TestWriteEvent(t *testing.T) {
+	var buf bytes.Buffer
+	writeEvent(&buf, "message", []byte("{\n\"id\": 1\n}"))
+	if got, want := buf.String(), "event: message\ndata: {\ndata: \"id\": 1\ndata: }\n\n"; got != want {
+		t.Errorf("Want event %q, got %q", want, got)
+	}
+}
+
+func TestEventStreamSSE(t *testing.T) {
+	defer func(p pubsub.Publisher) {
+		Config.Services.Pubsub = p
+	}(Config.Services.Pubsub)
+	Config.Services.Pubsub = &sentPubsub{
+		messages: []pubsub.Message{
+			{Data: []byte("private"), Labels: map[string]string{"repo": "octocat/secret", "private": "true"}},
+			{Data: []byte("public"), Labels: map[string]string{"repo": "octocat/hello-world", "private": "false"}},
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.GET("/stream/events", EventStreamSSE)
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	body, header := testEventStream(t, server.URL+"/stream/events")
+	if got := header.Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Want event stream content type, got %s", got)
+	}
+	if want := "event: message\ndata: public\n\n"; body != want {
+		t.Errorf("Want the events of public repositories %q, got %q", want, body)
+	}
+}
+
+func TestLogStreamSSE(t *testing.T) {
+	defer func(l logging.Log) {
+		Config.Services.Logs = l
+	}(Config.Services.Logs)
+	Config.Services.Logs = &sentLog{
+		entries: []*logging.Entry{
+			{Data: []byte(`{"out":"go build"}`)},
+			{Data: []byte(`{"out":"go test"}`)},
+		},
+	}
+
+	s := datastore.New("sqlite3", ":memory:")
+	repo := &model.Repo{ID: 1, FullName: "octocat/hello-world"}
+	build := &model.Build{RepoID: repo.ID, Status: model.StatusRunning}
+	procs := []*model.Proc{
+		{PID: 1, PGID: 1, Name: "backend", State: model.StatusRunning},
+		{PID: 2, PGID: 2, Name: "frontend", State: model.StatusSuccess},
+	}
+	if err := s.CreateBuild(build, procs...); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.GET("/stream/logs/:build/:number", func(c *gin.Context) {
+		store.ToContext(c, s)
+		c.Set("repo", repo)
+		LogStreamSSE(c)
+	})
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	body, _ := testEventStream(t, server.URL+"/stream/logs/1/1")
+	want := "event: line\ndata: {\"out\":\"go build\"}\n\nevent: line\ndata: {\"out\":\"go test\"}\n\nevent: eof\ndata: \n\n"
+	if body != want {
+		t.Errorf("Want log events %q, got %q", want, body)
+	}
+
+	res, err := http.Get(server.URL + "/stream/logs/1/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != 404 {
+		t.Errorf("Want status 404 streaming the logs of a completed proc, got %d", res.StatusCode)
+	}
+}
+
+// helper function returns the body and headers of the event stream.
+func testEventStream(t *testing.T, url string) (string, http.Header) {
+	res, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body), res.Header
+}
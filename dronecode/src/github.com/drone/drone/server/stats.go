@@ -0,0 +1,110 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/router/middleware/session"
+	"github.com/drone/drone/store"
+)
+
+// defaultWindows are the windows of the repository statistics if no window
+// is requested.
+var defaultWindows = []string{"24h", "7d", "30d"}
+
+// GetRepoStats returns the build statistics of the repository for each
+// requested window, formatted as json or as OpenMetrics if requested with
+// the format query parameter or the accept header.
+func GetRepoStats(c *gin.Context) {
+	repo := session.Repo(c)
+
+	windows := c.Request.URL.Query()["window"]
+	if len(windows) == 0 {
+		windows = defaultWindows
+	}
+
+	var stats []*model.RepoStats
+	for _, window := range windows {
+		d, err := parseWindow(window)
+		if err != nil {
+			c.String(400, "Error parsing window %q. %s", window, err)
+			return
+		}
+		since := time.Now().Add(-d).Unix()
+		times, err := store.FromContext(c).GetBuildTimes(repo, since)
+		if err != nil {
+			c.String(500, "Error getting build statistics. %s", err)
+			return
+		}
+		stats = append(stats, model.NewRepoStats(window, times))
+	}
+
+	if c.Query("format") == "openmetrics" ||
+		strings.Contains(c.Request.Header.Get("Accept"), "application/openmetrics-text") {
+		c.Data(200, "application/openmetrics-text; version=1.0.0; charset=utf-8", openMetrics(repo, stats))
+		return
+	}
+	c.JSON(200, stats)
+}
+
+// helper function parses the window duration, which is a duration such as
+// 24h, or a number of days such as 7d.
+func parseWindow(window string) (time.Duration, error) {
+	var d time.Duration
+	if strings.HasSuffix(window, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(window, "d"))
+		if err != nil {
+			return 0, err
+		}
+		d = time.Duration(days) * time.Hour * 24
+	} else {
+		var err error
+		if d, err = time.ParseDuration(window); err != nil {
+			return 0, err
+		}
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("window must be positive")
+	}
+	return d, nil
+}
+
+// helper function returns the repository statistics in the OpenMetrics
+// text format.
+func openMetrics(repo *model.Repo, stats []*model.RepoStats) []byte {
+	var buf bytes.Buffer
+	gauge := func(name, help string, value func(*model.RepoStats) float64) {
+		fmt.Fprintf(&buf, "# HELP drone_repo_%s %s\n", name, help)
+		fmt.Fprintf(&buf, "# TYPE drone_repo_%s gauge\n", name)
+		for _, s := range stats {
+			fmt.Fprintf(&buf, "drone_repo_%s{repo=%q,window=%q} %g\n", name, repo.FullName, s.Window, value(s))
+		}
+	}
+	summary := func(name, help string, value func(*model.RepoStats) model.Durations) {
+		fmt.Fprintf(&buf, "# HELP drone_repo_%s_seconds %s\n", name, help)
+		fmt.Fprintf(&buf, "# TYPE drone_repo_%s_seconds gauge\n", name)
+		for _, s := range stats {
+			d := value(s)
+			for _, q := range []struct {
+				stat  string
+				value float64
+			}{{"avg", d.Avg}, {"p50", d.P50}, {"p90", d.P90}, {"p95", d.P95}, {"p99", d.P99}} {
+				fmt.Fprintf(&buf, "drone_repo_%s_seconds{repo=%q,window=%q,stat=%q} %g\n", name, repo.FullName, s.Window, q.stat, q.value)
+			}
+		}
+	}
+	gauge("builds", "Number of completed builds.", func(s *model.RepoStats) float64 { return float64(s.Builds) })
+	gauge("builds_success", "Number of successful builds.", func(s *model.RepoStats) float64 { return float64(s.Success) })
+	gauge("builds_failure", "Number of failed builds.", func(s *model.RepoStats) float64 { return float64(s.Failure) })
+	gauge("success_ratio", "Ratio of successful builds.", func(s *model.RepoStats) float64 { return s.SuccessRate })
+	summary("build_duration", "Duration of completed builds.", func(s *model.RepoStats) model.Durations { return s.Duration })
+	summary("queue_wait", "Time builds waited in the queue.", func(s *model.RepoStats) model.Durations { return s.Wait })
+	buf.WriteString("# EOF\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/remote"
+	"github.com/drone/drone/shared/token"
+	"github.com/drone/drone/store"
+)
+
+// RepoSyncer periodically refreshes the metadata of the active repositories
+// from the remote, until the context is cancelled.
+func RepoSyncer(ctx context.Context, s store.Store, r remote.Remote, interval time.Duration) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+		repos, err := s.GetRepoList()
+		if err != nil {
+			logrus.Errorf("sync: cannot list repositories. %s", err)
+			continue
+		}
+		for _, repo := range repos {
+			if ctx.Err() != nil {
+				return
+			}
+			if err := syncRepo(s, r, repo); err != nil {
+				logrus.Errorf("sync: cannot sync %s. %s", repo.FullName, err)
+			}
+		}
+	}
+}
+
+// syncRepo refreshes the repository metadata from the remote using the
+// credentials of the repository owner. If the repository was renamed or
+// transferred, the stored full name is updated and the hook is registered
+// again, since the hook token is signed with the full name.
+func syncRepo(s store.Store, r remote.Remote, repo *model.Repo) error {
+	user, err := s.GetUser(repo.UserID)
+	if err != nil {
+		return fmt.Errorf("cannot get the repository owner. %s", err)
+	}
+	if refresher, ok := r.(remote.Refresher); ok {
+		ok, _ := refresher.Refresh(user)
+		if ok {
+			s.UpdateUser(user)
+		}
+	}
+
+	from, err := r.Repo(user, repo.Owner, repo.Name)
+	if err != nil {
+		return err
+	}
+	perm, err := r.Perm(user, from.Owner, from.Name)
+	if err != nil {
+		return err
+	}
+	if !perm.Admin {
+		logrus.Warnf("sync: %s is no longer an admin of %s", user.Login, from.FullName)
+	}
+
+	renamed := from.FullName != repo.FullName
+	if !renamed && !repoChanged(repo, from) {
+		return nil
+	}
+	prev := repo.FullName
+	repo.Owner = from.Owner
+	repo.Name = from.Name
+	repo.FullName = from.FullName
+	repo.Avatar = from.Avatar
+	repo.Link = from.Link
+	repo.Clone = from.Clone
+	repo.Branch = from.Branch
+	repo.IsPrivate = from.IsPrivate
+	if renamed && !perm.Admin {
+		return fmt.Errorf("cannot register the hook of %s without admin access", repo.FullName)
+	}
+	if err := s.UpdateRepo(repo); err != nil {
+		return err
+	}
+	if !renamed {
+		return nil
+	}
+
+	logrus.Infof("sync: %s was renamed to %s", prev, repo.FullName)
+	t := token.New(token.HookToken, repo.FullName)
+	sig, err := t.Sign(repo.Hash)
+	if err != nil {
+		return err
+	}
+	link := fmt.Sprintf("%s/hook?access_token=%s", Config.Server.Host, sig)
+	r.Deactivate(user, repo, Config.Server.Host)
+	if err := r.Activate(user, repo, link); err != nil {
+		return fmt.Errorf("cannot register the hook. %s", err)
+	}
+	if !repo.HookSigned {
+		repo.HookSigned = true
+		if err := s.UpdateRepo(repo); err != nil {
+			return err
+		}
+	}
+	s.AuditCreate(&model.Audit{
+		Action:  model.AuditRepoRename,
+		User:    user.Login,
+		Repo:    repo.FullName,
+		Target:  prev,
+		Created: time.Now().Unix(),
+	})
+	return nil
+}
+
+// helper function returns true if the remote metadata of the repository
+// differs from the stored repository.
+func repoChanged(repo, from *model.Repo) bool {
+	return repo.Avatar != from.Avatar ||
+		repo.Link != from.Link ||
+		repo.Clone != from.Clone ||
+		repo.Branch != from.Branch ||
+		repo.IsPrivate != from.IsPrivate
+}
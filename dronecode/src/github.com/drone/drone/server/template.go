@@ -0,0 +1,148 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/drone/drone/model"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v2"
+)
+
+// GetTemplate gets the named template of the namespace from the database
+// and writes to the response in json format.
+func GetTemplate(c *gin.Context) {
+	var (
+		namespace = c.Param("team")
+		name      = c.Param("template")
+	)
+	template, err := Config.Storage.Templates.TemplateFind(namespace, name)
+	if err != nil {
+		c.String(404, "Error getting template %q. %s", name, err)
+		return
+	}
+	c.JSON(200, template)
+}
+
+// PostTemplate persists the template of the namespace to the database.
+func PostTemplate(c *gin.Context) {
+	namespace := c.Param("team")
+
+	in := new(model.Template)
+	if err := c.Bind(in); err != nil {
+		c.String(http.StatusBadRequest, "Error parsing template. %s", err)
+		return
+	}
+	template := &model.Template{
+		Namespace: namespace,
+		Name:      in.Name,
+		Data:      in.Data,
+		Created:   time.Now().Unix(),
+	}
+	template.Updated = template.Created
+	if err := template.Validate(); err != nil {
+		c.String(400, "Error inserting template. %s", err)
+		return
+	}
+	if err := Config.Storage.Templates.TemplateCreate(template); err != nil {
+		c.String(500, "Error inserting template %q. %s", in.Name, err)
+		return
+	}
+	recordAudit(c, model.AuditTemplateCreate, "", namespace+"/"+template.Name)
+	c.JSON(200, template)
+}
+
+// PatchTemplate updates the template of the namespace in the database.
+func PatchTemplate(c *gin.Context) {
+	var (
+		namespace = c.Param("team")
+		name      = c.Param("template")
+	)
+
+	in := new(model.Template)
+	if err := c.Bind(in); err != nil {
+		c.String(http.StatusBadRequest, "Error parsing template. %s", err)
+		return
+	}
+
+	template, err := Config.Storage.Templates.TemplateFind(namespace, name)
+	if err != nil {
+		c.String(404, "Error getting template %q. %s", name, err)
+		return
+	}
+	if in.Data != "" {
+		template.Data = in.Data
+	}
+	template.Updated = time.Now().Unix()
+
+	if err := template.Validate(); err != nil {
+		c.String(400, "Error updating template. %s", err)
+		return
+	}
+	if err := Config.Storage.Templates.TemplateUpdate(template); err != nil {
+		c.String(500, "Error updating template %q. %s", name, err)
+		return
+	}
+	recordAudit(c, model.AuditTemplateUpdate, "", namespace+"/"+name)
+	c.JSON(200, template)
+}
+
+// GetTemplateList gets the template list of the namespace from the database
+// and writes to the response in json format.
+func GetTemplateList(c *gin.Context) {
+	list, err := Config.Storage.Templates.TemplateList(c.Param("team"))
+	if err != nil {
+		c.String(500, "Error getting template list. %s", err)
+		return
+	}
+	c.JSON(200, list)
+}
+
+// DeleteTemplate deletes the named template of the namespace from the
+// database.
+func DeleteTemplate(c *gin.Context) {
+	var (
+		namespace = c.Param("team")
+		name      = c.Param("template")
+	)
+	template, err := Config.Storage.Templates.TemplateFind(namespace, name)
+	if err != nil {
+		c.String(404, "Error getting template %q. %s", name, err)
+		return
+	}
+	if err := Config.Storage.Templates.TemplateDelete(template); err != nil {
+		c.String(500, "Error deleting template %q. %s", name, err)
+		return
+	}
+	recordAudit(c, model.AuditTemplateDelete, "", namespace+"/"+name)
+	c.String(204, "")
+}
+
+// templateConfig defines a pipeline configuration that loads a template of
+// the repository namespace, with the template input parameters.
+type templateConfig struct {
+	Kind string      `yaml:"kind"`
+	Load string      `yaml:"load"`
+	Data interface{} `yaml:"data"`
+}
+
+// expandTemplate returns the pipeline configuration rendered from the
+// template, if the configuration is a template configuration, and
+// otherwise returns the configuration as-is.
+func expandTemplate(repo *model.Repo, data []byte) ([]byte, error) {
+	conf := new(templateConfig)
+	if err := yaml.Unmarshal(data, conf); err != nil || conf.Kind != "template" {
+		return data, nil
+	}
+	template, err := Config.Storage.Templates.TemplateFind(repo.Owner, conf.Load)
+	if err != nil {
+		return nil, fmt.Errorf("Error getting template %q. %s", conf.Load, err)
+	}
+	out, err := template.Render(conf.Data)
+	if err != nil {
+		return nil, fmt.Errorf("Error rendering template %q. %s", conf.Load, err)
+	}
+	return []byte(out), nil
+}
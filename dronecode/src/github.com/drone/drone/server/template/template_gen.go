@@ -48,8 +48,8 @@ var error = `<!DOCTYPE html>
   <link href="https://fonts.googleapis.com/css?family=Roboto" rel="stylesheet"/>
   <link href="https://fonts.googleapis.com/css?family=Roboto+Mono" rel="stylesheet"/>
   <link href="https://fonts.googleapis.com/icon?family=Material+Icons" rel="stylesheet"/>
-  <link href="/static/favicon.ico" rel="icon" type="image/x-icon"/>
-  <link rel="stylesheet" href="/static/app.css" />
+  <link href="{{ .root }}/static/favicon.ico" rel="icon" type="image/x-icon"/>
+  <link rel="stylesheet" href="{{ .root }}/static/app.css" />
   <title>error | drone</title>
 </head>
 <body>
@@ -69,8 +69,8 @@ var index = `<!DOCTYPE html>
   <link href="https://fonts.googleapis.com/css?family=Roboto" rel="stylesheet"/>
   <link href="https://fonts.googleapis.com/css?family=Roboto+Mono" rel="stylesheet"/>
   <link href="https://fonts.googleapis.com/icon?family=Material+Icons" rel="stylesheet"/>
-  <link href="/static/app.css" rel="stylesheet"/>
-  <link href="/static/favicon.ico" rel="icon" type="image/x-icon"/>
+  <link href="{{ .root }}/static/app.css" rel="stylesheet"/>
+  <link href="{{ .root }}/static/favicon.ico" rel="icon" type="image/x-icon"/>
 </head>
 <body>
 <div id="app"></div>
@@ -78,7 +78,7 @@ var index = `<!DOCTYPE html>
   window.STATE_FROM_SERVER={{ . | json }};
 </script>
 <script src="https://code.getmdl.io/1.1.3/material.min.js"></script>
-<script src="/static/app.js"></script>
+<script src="{{ .root }}/static/app.js"></script>
 </body>
 </html>
 `
@@ -93,15 +93,15 @@ var login = `<!DOCTYPE html>
   <link href="https://fonts.googleapis.com/css?family=Roboto" rel="stylesheet"/>
   <link href="https://fonts.googleapis.com/css?family=Roboto+Mono" rel="stylesheet"/>
   <link href="https://fonts.googleapis.com/icon?family=Material+Icons" rel="stylesheet"/>
-  <link href="/static/favicon.ico" rel="icon" type="image/x-icon"/>
-  <link rel="stylesheet" href="/static/app.css" />
+  <link href="{{ .root }}/static/favicon.ico" rel="icon" type="image/x-icon"/>
+  <link rel="stylesheet" href="{{ .root }}/static/app.css" />
   <title>login | drone</title>
 </head>
 <body>
   <div class="mdl-grid">
     <div class="mdl-layout-spacer"></div>
     <div class="mdl-card">
-      <form action="/authorize" method="post">
+      <form action="{{ .root }}/authorize" method="post">
         <div class="mdl-textfield mdl-js-textfield">
           <input class="mdl-textfield__input" type="text" id="username" name="username" />
           <label class="mdl-textfield__label" for="username">Username</label>
@@ -110,6 +110,12 @@ var login = `<!DOCTYPE html>
           <input class="mdl-textfield__input" type="password" id="userpass" name="password" />
           <label class="mdl-textfield__label" for="userpass">Password</label>
         </div>
+        {{ if .token }}
+        <div class="mdl-textfield mdl-js-textfield">
+          <input class="mdl-textfield__input" type="password" id="usertoken" name="token" />
+          <label class="mdl-textfield__label" for="usertoken">Access Token</label>
+        </div>
+        {{ end }}
         <div class="mdl-dialog__actions">
           <input type="submit" class="mdl-button mdl-button--colored mdl-js-button" value="Login" />
         </div>
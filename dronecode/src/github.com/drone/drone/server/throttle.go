@@ -0,0 +1,83 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/cncd/queue"
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/store"
+)
+
+// reasonThrottled is the reason returned for pending builds that are held
+// in the queue by a concurrency limit.
+const reasonThrottled = "waiting on concurrency limit"
+
+// Throttle is a queue limit that holds the pipelines of a build in the
+// queue while the repository or the organization is running the maximum
+// number of concurrent builds. Pipelines of a build that is already running
+// are not held.
+func Throttle(task *queue.Task, running []*queue.Task) bool {
+	repoLimit, _ := strconv.Atoi(task.Labels["throttle"])
+	orgLimit := Config.Pipeline.Throttle
+	if repoLimit <= 0 && orgLimit <= 0 {
+		return false
+	}
+
+	repoBuilds := map[string]bool{}
+	orgBuilds := map[string]bool{}
+	for _, t := range running {
+		build := t.Labels["build"]
+		if build == task.Labels["build"] {
+			return false
+		}
+		if t.Labels["repo"] == task.Labels["repo"] {
+			repoBuilds[build] = true
+		}
+		if t.Labels["org"] == task.Labels["org"] {
+			orgBuilds[build] = true
+		}
+	}
+	return (repoLimit > 0 && len(repoBuilds) >= repoLimit) ||
+		(orgLimit > 0 && len(orgBuilds) >= orgLimit)
+}
+
+// pendingReason returns the reason the pending build is not running, or
+// an empty string if the build is waiting for an available agent.
+func pendingReason(c context.Context, build *model.Build) string {
+	info := Config.Services.Queue.Info(c)
+	id := fmt.Sprint(build.ID)
+	for _, task := range info.Pending {
+		if task.Labels["build"] == id && Throttle(task, info.Running) {
+			return reasonThrottled
+		}
+	}
+	return ""
+}
+
+// checkRateLimit returns an error if the builds enqueued in the last hour in
+// the repositories of the owner reached the build rate limit. The limit of
+// the repository overrides the user limit, and a negative repository limit
+// disables the rate limit for the repository.
+func checkRateLimit(s store.Store, owner *model.User, repo *model.Repo) error {
+	limit := Config.Pipeline.RateLimit
+	if repo.RateLimit != 0 {
+		limit = repo.RateLimit
+	}
+	if limit <= 0 {
+		return nil
+	}
+	since := time.Now().Add(-time.Hour).Unix()
+	count, err := s.GetBuildCountUser(owner, since)
+	if err != nil {
+		logrus.Errorf("cannot count the builds of %s. %s", owner.Login, err)
+		return nil
+	}
+	if count >= limit {
+		return fmt.Errorf("%s reached the limit of %d builds per hour", owner.Login, limit)
+	}
+	return nil
+}
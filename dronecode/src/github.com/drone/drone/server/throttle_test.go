@@ -0,0 +1,43 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/cncd/queue"
+)
+
+func TestThrottle(t *testing.T) {
+	defer func(limit int) {
+		Config.Pipeline.Throttle = limit
+	}(Config.Pipeline.Throttle)
+
+	task := func(org, repo, build, throttle string) *queue.Task {
+		return &queue.Task{Labels: map[string]string{"org": org, "repo": repo, "build": build, "throttle": throttle}}
+	}
+	running := []*queue.Task{
+		task("octocat", "octocat/hello-world", "1", "1"),
+		task("octocat", "octocat/hello-world", "1", "1"),
+		task("octocat", "octocat/spoon-knife", "2", ""),
+	}
+
+	tests := []struct {
+		task     *queue.Task
+		orgLimit int
+		want     bool
+	}{
+		{task: task("octocat", "octocat/hello-world", "3", "")},
+		{task: task("octocat", "octocat/hello-world", "3", "1"), want: true},
+		{task: task("octocat", "octocat/hello-world", "3", "2")},
+		{task: task("octocat", "octocat/hello-world", "1", "1")},
+		{task: task("octocat", "octocat/linguist", "3", ""), orgLimit: 2, want: true},
+		{task: task("octocat", "octocat/linguist", "3", ""), orgLimit: 3},
+		{task: task("spaceghost", "spaceghost/hello-world", "3", ""), orgLimit: 2},
+		{task: task("octocat", "octocat/spoon-knife", "2", ""), orgLimit: 1},
+	}
+	for i, test := range tests {
+		Config.Pipeline.Throttle = test.orgLimit
+		if got := Throttle(test.task, running); got != test.want {
+			t.Errorf("Want task %d throttled %v, got %v", i, test.want, got)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/cncd/pipeline/pipeline/rpc"
+	"github.com/cncd/queue"
+	"github.com/gin-gonic/gin"
+
+	"github.com/drone/drone/shared/trace"
+	"github.com/drone/drone/store"
+)
+
+// traceSpan returns the trace span of the request, or nil if the request
+// is not traced.
+func traceSpan(c *gin.Context) *trace.Span {
+	v, ok := c.Get("trace")
+	if !ok {
+		return nil
+	}
+	span, _ := v.(*trace.Span)
+	return span
+}
+
+// traceQueue records the time the pipeline waited in the queue, from the
+// time the build was enqueued until the pipeline is dispatched to an agent,
+// as a child of the hook span that created the build. The agent traces the
+// pipeline execution as a child of the queue span.
+func traceQueue(s store.Store, task *queue.Task, pipeline *rpc.Pipeline) {
+	if Config.Services.Tracer == nil {
+		return
+	}
+	start := time.Now()
+	id, _ := strconv.ParseInt(task.Labels["build"], 10, 64)
+	if build, err := s.GetBuild(id); err == nil && build.Enqueued != 0 {
+		start = time.Unix(build.Enqueued, 0)
+	}
+	span := Config.Services.Tracer.StartAt("queue", pipeline.Trace, start)
+	span.Tag("repo", task.Labels["repo"])
+	span.Tag("proc", task.ID)
+	span.Finish()
+	pipeline.Trace = span.Context()
+}
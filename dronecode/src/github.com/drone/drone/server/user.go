@@ -4,6 +4,7 @@ import (
 	"encoding/base32"
 	"net/http"
 	"strconv"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/gin-gonic/gin"
@@ -20,6 +21,24 @@ func GetSelf(c *gin.Context) {
 	c.JSON(200, session.User(c))
 }
 
+// PatchSelf updates the preferences of the authenticated user.
+func PatchSelf(c *gin.Context) {
+	in := new(model.UserPatch)
+	if err := c.Bind(in); err != nil {
+		c.String(400, "Error parsing request. %s", err)
+		return
+	}
+	user := session.User(c)
+	if in.EmailNotify != nil {
+		user.EmailNotify = *in.EmailNotify
+	}
+	if err := store.UpdateUser(c, user); err != nil {
+		c.String(500, "Error updating user. %s", err)
+		return
+	}
+	c.JSON(200, user)
+}
+
 func GetFeed(c *gin.Context) {
 	latest, _ := strconv.ParseBool(c.Query("latest"))
 
@@ -46,6 +65,7 @@ func GetRepos(c *gin.Context) {
 
 	if flush {
 		log.Debugf("Evicting repository cache for user %s.", user.Login)
+		evictTeamPerms(c, user)
 		cache.DeleteRepos(c, user)
 	}
 
@@ -98,11 +118,12 @@ func GetRemoteRepos(c *gin.Context) {
 	c.JSON(http.StatusOK, repos)
 }
 
+// PostToken returns a legacy user token of the user, which is deprecated in
+// favor of access tokens.
 func PostToken(c *gin.Context) {
 	user := session.User(c)
 
-	token := token.New(token.UserToken, user.Login)
-	tokenstr, err := token.Sign(user.Hash)
+	tokenstr, err := userToken(user)
 	if err != nil {
 		c.AbortWithError(http.StatusInternalServerError, err)
 		return
@@ -112,19 +133,119 @@ func PostToken(c *gin.Context) {
 
 func DeleteToken(c *gin.Context) {
 	user := session.User(c)
-	user.Hash = base32.StdEncoding.EncodeToString(
-		securecookie.GenerateRandomKey(32),
-	)
-	if err := store.UpdateUser(c, user); err != nil {
+	if err := revokeSessions(store.FromContext(c), user); err != nil {
 		c.String(500, "Error revoking tokens. %s", err)
 		return
 	}
 
-	token := token.New(token.UserToken, user.Login)
-	tokenstr, err := token.Sign(user.Hash)
+	tokenstr, err := userToken(user)
 	if err != nil {
 		c.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
 	c.String(http.StatusOK, tokenstr)
 }
+
+// GetAccessTokens returns the personal access tokens of the authenticated
+// user.
+func GetAccessTokens(c *gin.Context) {
+	listAccessTokens(c, session.User(c))
+}
+
+// PostAccessToken creates a personal access token with the requested name,
+// scopes and expiration date. The signed token is returned once, and cannot
+// be retrieved later.
+func PostAccessToken(c *gin.Context) {
+	createAccessToken(c, session.User(c))
+}
+
+// DeleteAccessToken revokes the personal access token of the authenticated
+// user.
+func DeleteAccessToken(c *gin.Context) {
+	deleteAccessToken(c, session.User(c))
+}
+
+func listAccessTokens(c *gin.Context, user *model.User) {
+	list, err := store.FromContext(c).AccessTokenList(user)
+	if err != nil {
+		c.String(500, "Error getting token list. %s", err)
+		return
+	}
+	c.JSON(200, list)
+}
+
+func createAccessToken(c *gin.Context, user *model.User) {
+	in := new(model.AccessToken)
+	if err := c.Bind(in); err != nil {
+		c.String(http.StatusBadRequest, "Error parsing token. %s", err)
+		return
+	}
+	access := &model.AccessToken{
+		UserID:  user.ID,
+		Name:    in.Name,
+		Scopes:  in.Scopes,
+		Expires: in.Expires,
+		Created: time.Now().Unix(),
+		Hash: base32.StdEncoding.EncodeToString(
+			securecookie.GenerateRandomKey(32),
+		),
+	}
+	if len(access.Scopes) == 0 {
+		access.Scopes = []string{model.ScopeRead}
+	}
+	if err := access.Validate(); err != nil {
+		c.String(400, "Error inserting token. %s", err)
+		return
+	}
+	if err := store.FromContext(c).AccessTokenCreate(access); err != nil {
+		c.String(500, "Error inserting token %q. %s", in.Name, err)
+		return
+	}
+
+	t := token.New(token.AccessToken, strconv.FormatInt(access.ID, 10))
+	tokenstr, err := t.SignExpires(access.Hash, access.Expires)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	access.Token = tokenstr
+	recordAudit(c, model.AuditTokenCreate, "", user.Login+"/"+access.Name)
+	c.JSON(200, access)
+}
+
+func deleteAccessToken(c *gin.Context, user *model.User) {
+	id, err := strconv.ParseInt(c.Param("token"), 10, 64)
+	if err != nil {
+		c.String(400, "Error parsing token id. %s", err)
+		return
+	}
+	access, err := store.FromContext(c).AccessTokenFind(id)
+	if err != nil || access.UserID != user.ID {
+		c.String(404, "Cannot find token %d.", id)
+		return
+	}
+	if err := store.FromContext(c).AccessTokenDelete(access); err != nil {
+		c.String(500, "Error deleting token. %s", err)
+		return
+	}
+	recordAudit(c, model.AuditTokenDelete, "", user.Login+"/"+access.Name)
+	c.String(204, "")
+}
+
+// helper function evicts the cached organization permissions of the user
+// for the owners of the cached user repositories, so that changes to the
+// organization roles are synced from the remote.
+func evictTeamPerms(c *gin.Context, user *model.User) {
+	repos, err := cache.GetRepos(c, user)
+	if err != nil {
+		return
+	}
+	owners := map[string]bool{}
+	for _, repo := range repos {
+		if repo.Owner == user.Login || owners[repo.Owner] {
+			continue
+		}
+		owners[repo.Owner] = true
+		cache.DeleteTeamPerms(c, user, repo.Owner)
+	}
+}
@@ -49,6 +49,7 @@ func PatchUser(c *gin.Context) {
 		c.AbortWithStatus(http.StatusConflict)
 		return
 	}
+	recordAudit(c, model.AuditUserUpdate, "", user.Login)
 
 	c.JSON(http.StatusOK, user)
 }
@@ -61,10 +62,12 @@ func PostUser(c *gin.Context) {
 		return
 	}
 	user := &model.User{
-		Active: true,
-		Login:  in.Login,
-		Email:  in.Email,
-		Avatar: in.Avatar,
+		Active:      true,
+		Login:       in.Login,
+		Email:       in.Email,
+		Avatar:      in.Avatar,
+		Machine:     in.Machine,
+		EmailNotify: !in.Machine,
 		Hash: base32.StdEncoding.EncodeToString(
 			securecookie.GenerateRandomKey(32),
 		),
@@ -73,6 +76,7 @@ func PostUser(c *gin.Context) {
 		c.String(http.StatusInternalServerError, err.Error())
 		return
 	}
+	recordAudit(c, model.AuditUserCreate, "", user.Login)
 	c.JSON(http.StatusOK, user)
 }
 
@@ -86,5 +90,118 @@ func DeleteUser(c *gin.Context) {
 		c.String(500, "Error deleting user. %s", err)
 		return
 	}
+	recordAudit(c, model.AuditUserDelete, "", user.Login)
 	c.String(200, "")
 }
+
+// GetUserTokens returns the access tokens of the machine user.
+func GetUserTokens(c *gin.Context) {
+	if user, ok := machineUser(c); ok {
+		listAccessTokens(c, user)
+	}
+}
+
+// PostUserToken creates an access token for the machine user.
+func PostUserToken(c *gin.Context) {
+	if user, ok := machineUser(c); ok {
+		createAccessToken(c, user)
+	}
+}
+
+// DeleteUserToken revokes an access token of the machine user.
+func DeleteUserToken(c *gin.Context) {
+	if user, ok := machineUser(c); ok {
+		deleteAccessToken(c, user)
+	}
+}
+
+// GetUserPerms returns the repository permissions of the machine user.
+func GetUserPerms(c *gin.Context) {
+	user, ok := machineUser(c)
+	if !ok {
+		return
+	}
+	perms, err := store.FromContext(c).PermList(user)
+	if err != nil {
+		c.String(500, "Error getting permission list. %s", err)
+		return
+	}
+	for _, perm := range perms {
+		if repo, err := store.GetRepo(c, perm.RepoID); err == nil {
+			perm.Repo = repo.FullName
+		}
+	}
+	c.JSON(200, perms)
+}
+
+// PostUserPerm grants the machine user permissions to the repository.
+func PostUserPerm(c *gin.Context) {
+	user, ok := machineUser(c)
+	if !ok {
+		return
+	}
+	repo, err := store.GetRepoOwnerName(c, c.Param("owner"), c.Param("name"))
+	if err != nil {
+		c.String(404, "Cannot find repository. %s", err)
+		return
+	}
+	in := new(model.RepoPerm)
+	if err := c.Bind(in); err != nil {
+		c.String(http.StatusBadRequest, "Error parsing permission. %s", err)
+		return
+	}
+	perm := &model.RepoPerm{
+		UserID: user.ID,
+		RepoID: repo.ID,
+		Repo:   repo.FullName,
+		Pull:   in.Pull,
+		Push:   in.Push,
+		Admin:  in.Admin,
+	}
+	if err := store.FromContext(c).PermUpsert(perm); err != nil {
+		c.String(500, "Error updating permission. %s", err)
+		return
+	}
+	recordAudit(c, model.AuditUserUpdate, repo.FullName, user.Login)
+	c.JSON(200, perm)
+}
+
+// DeleteUserPerm revokes the permissions of the machine user to the
+// repository.
+func DeleteUserPerm(c *gin.Context) {
+	user, ok := machineUser(c)
+	if !ok {
+		return
+	}
+	repo, err := store.GetRepoOwnerName(c, c.Param("owner"), c.Param("name"))
+	if err != nil {
+		c.String(404, "Cannot find repository. %s", err)
+		return
+	}
+	perm, err := store.FromContext(c).PermFind(user, repo)
+	if err != nil {
+		c.String(404, "Cannot find permission. %s", err)
+		return
+	}
+	if err := store.FromContext(c).PermDelete(perm); err != nil {
+		c.String(500, "Error deleting permission. %s", err)
+		return
+	}
+	recordAudit(c, model.AuditUserUpdate, repo.FullName, user.Login)
+	c.String(204, "")
+}
+
+// helper function returns the machine user of the request, or writes an
+// error response if the user does not exist or is not a machine user.
+func machineUser(c *gin.Context) (*model.User, bool) {
+	user, err := store.GetUserLogin(c, c.Param("login"))
+	if err != nil {
+		c.String(404, "Cannot find user. %s", err)
+		return nil, false
+	}
+	if !user.Machine {
+		c.String(400, "User %s is not a machine user.", user.Login)
+		return nil, false
+	}
+	return user, true
+}
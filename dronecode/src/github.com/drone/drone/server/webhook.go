@@ -0,0 +1,55 @@
+package server
+
+import (
+	"strconv"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetDeliveries returns the most recent system webhook deliveries.
+func GetDeliveries(c *gin.Context) {
+	list, err := store.FromContext(c).DeliveryList(100)
+	if err != nil {
+		c.String(500, "Error getting delivery list. %s", err)
+		return
+	}
+	c.JSON(200, list)
+}
+
+// PostRedelivery posts the payload of a system webhook delivery to the
+// endpoint again.
+func PostRedelivery(c *gin.Context) {
+	if Config.Services.Webhooks == nil {
+		c.String(400, "System webhooks are not enabled.")
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("delivery"), 10, 64)
+	if err != nil {
+		c.String(400, "Error parsing delivery id. %s", err)
+		return
+	}
+	delivery, err := store.FromContext(c).DeliveryFind(id)
+	if err != nil {
+		c.String(404, "Cannot find delivery. %s", err)
+		return
+	}
+	if err := Config.Services.Webhooks.WebhookRedeliver(delivery); err != nil {
+		c.JSON(502, delivery)
+		return
+	}
+	c.JSON(200, delivery)
+}
+
+// sendWebhook sends the system webhook, if system webhooks are enabled.
+func sendWebhook(payload *model.WebhookPayload) {
+	if Config.Services.Webhooks == nil {
+		return
+	}
+	if err := Config.Services.Webhooks.WebhookSend(payload); err != nil {
+		logrus.Errorf("Error sending %s webhook. %s", payload.Event, err)
+	}
+}
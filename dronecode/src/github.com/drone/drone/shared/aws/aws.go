@@ -0,0 +1,228 @@
+// Package aws provides a minimal client for the JSON based AWS APIs, such
+// as KMS and Secrets Manager, signed using AWS Signature Version 4.
+package aws
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Credentials defines the AWS access credentials.
+type Credentials struct {
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+}
+
+// Client is a client for the AWS JSON APIs.
+type Client struct {
+	Region      string
+	Credentials Credentials
+
+	// Endpoint overrides the service endpoint, for testing purposes.
+	Endpoint string
+
+	client *http.Client
+}
+
+// New returns a new Client for the region.
+func New(region string, creds Credentials) *Client {
+	return &Client{
+		Region:      region,
+		Credentials: creds,
+		client:      http.DefaultClient,
+	}
+}
+
+// NewEnv returns a new Client using the standard AWS environment variables.
+func NewEnv() *Client {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	return New(region, Credentials{
+		AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	})
+}
+
+// Error represents an error returned by the AWS API.
+type Error struct {
+	Code    int
+	Type    string `json:"__type"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("aws: %s: %s (%d)", e.Type, e.Message, e.Code)
+}
+
+// Do invokes the service target, for example TrentService.Decrypt, with
+// the JSON encoded input and decodes the response into out.
+func (c *Client) Do(service, target string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.%s.amazonaws.com/", service, c.Region)
+	}
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", target)
+	if c.Credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.Credentials.SessionToken)
+	}
+	Sign(req, body, service, c.Region, c.Credentials, time.Now())
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode > 299 {
+		e := &Error{Code: res.StatusCode}
+		json.Unmarshal(data, e)
+		return e
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// Sign signs the request using AWS Signature Version 4.
+func Sign(req *http.Request, body []byte, service, region string, creds Credentials, now time.Time) {
+	now = now.UTC()
+	date := now.Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", date)
+
+	// the host header is not part of the header map, but must be signed.
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	var names []string
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonical bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&canonical, "%s:%s\n", name, headers[name])
+	}
+	signed := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	query := strings.Replace(req.URL.Query().Encode(), "+", "%20", -1)
+
+	request := strings.Join([]string{
+		req.Method,
+		path,
+		query,
+		canonical.String(),
+		signed,
+		hexsum(body),
+	}, "\n")
+
+	scope := strings.Join([]string{now.Format("20060102"), region, service, "aws4_request"}, "/")
+	payload := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		date,
+		scope,
+		hexsum([]byte(request)),
+	}, "\n")
+
+	signature := hex.EncodeToString(mac(signingKey(service, region, creds, now), payload))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKey, scope, signed, signature,
+	))
+}
+
+// Presign signs the request url using AWS Signature Version 4 query
+// parameters, so that the url can be requested without the credentials
+// until it expires. Only the host header is signed, and the payload is
+// not signed.
+func Presign(req *http.Request, service, region string, creds Credentials, now time.Time, expires time.Duration) {
+	now = now.UTC()
+	date := now.Format("20060102T150405Z")
+	scope := strings.Join([]string{now.Format("20060102"), region, service, "aws4_request"}, "/")
+
+	params := req.URL.Query()
+	params.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	params.Set("X-Amz-Credential", creds.AccessKey+"/"+scope)
+	params.Set("X-Amz-Date", date)
+	params.Set("X-Amz-Expires", fmt.Sprint(int64(expires/time.Second)))
+	params.Set("X-Amz-SignedHeaders", "host")
+	if creds.SessionToken != "" {
+		params.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+	query := strings.Replace(params.Encode(), "+", "%20", -1)
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	request := strings.Join([]string{
+		req.Method,
+		path,
+		query,
+		"host:" + req.URL.Host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	payload := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		date,
+		scope,
+		hexsum([]byte(request)),
+	}, "\n")
+	signature := hex.EncodeToString(mac(signingKey(service, region, creds, now), payload))
+
+	req.URL.RawQuery = query + "&X-Amz-Signature=" + signature
+}
+
+// signingKey derives the signing key of the date, region and service from
+// the secret key.
+func signingKey(service, region string, creds Credentials, now time.Time) []byte {
+	key := mac([]byte("AWS4"+creds.SecretKey), now.Format("20060102"))
+	key = mac(key, region)
+	key = mac(key, service)
+	return mac(key, "aws4_request")
+}
+
+func mac(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func hexsum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,39 @@
+package aws
+
+// KMS is a client for the AWS Key Management Service.
+type KMS struct {
+	client *Client
+	keyID  string
+}
+
+// NewKMS returns a KMS client that generates data keys using the master key.
+func NewKMS(client *Client, keyID string) *KMS {
+	return &KMS{client, keyID}
+}
+
+// GenerateDataKey returns a new 256-bit data key in plaintext, and
+// encrypted with the master key.
+func (k *KMS) GenerateDataKey() (plaintext, ciphertext []byte, err error) {
+	in := map[string]string{
+		"KeyId":   k.keyID,
+		"KeySpec": "AES_256",
+	}
+	out := struct {
+		Plaintext      []byte
+		CiphertextBlob []byte
+	}{}
+	err = k.client.Do("kms", "TrentService.GenerateDataKey", in, &out)
+	return out.Plaintext, out.CiphertextBlob, err
+}
+
+// Decrypt decrypts a data key encrypted with the master key.
+func (k *KMS) Decrypt(ciphertext []byte) ([]byte, error) {
+	in := map[string][]byte{
+		"CiphertextBlob": ciphertext,
+	}
+	out := struct {
+		Plaintext []byte
+	}{}
+	err := k.client.Do("kms", "TrentService.Decrypt", in, &out)
+	return out.Plaintext, err
+}
@@ -0,0 +1,99 @@
+package aws
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// S3 is a client for an Amazon S3 bucket, or a bucket of an S3 compatible
+// object store such as MinIO or Google Cloud Storage.
+type S3 struct {
+	client *Client
+	bucket string
+}
+
+// NewS3 returns an S3 client for the bucket. If the client endpoint is set,
+// objects are addressed using path-style urls relative to the endpoint.
+func NewS3(client *Client, bucket string) *S3 {
+	return &S3{client, bucket}
+}
+
+// PutObject uploads the object data to the key.
+func (s *S3) PutObject(key string, data []byte) error {
+	_, err := s.do("PUT", key, data)
+	return err
+}
+
+// GetObject returns the object data of the key.
+func (s *S3) GetObject(key string) ([]byte, error) {
+	return s.do("GET", key, nil)
+}
+
+// DeleteObject deletes the object of the key.
+func (s *S3) DeleteObject(key string) error {
+	_, err := s.do("DELETE", key, nil)
+	return err
+}
+
+// PresignPut returns a pre-signed url that uploads the object data to the
+// key, without the credentials, until the url expires.
+func (s *S3) PresignPut(key string, expires time.Duration) (string, error) {
+	req, err := http.NewRequest("PUT", s.endpoint(key), nil)
+	if err != nil {
+		return "", err
+	}
+	Presign(req, "s3", s.client.Region, s.client.Credentials, time.Now(), expires)
+	return req.URL.String(), nil
+}
+
+func (s *S3) do(method, key string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, s.endpoint(key), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Amz-Content-Sha256", hexsum(body))
+	if s.client.Credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.client.Credentials.SessionToken)
+	}
+	Sign(req, body, "s3", s.client.Region, s.client.Credentials, time.Now())
+
+	res, err := s.client.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode > 299 {
+		return nil, &Error{
+			Code:    res.StatusCode,
+			Type:    "s3",
+			Message: http.StatusText(res.StatusCode),
+		}
+	}
+	return data, nil
+}
+
+// helper function returns the url of the object key.
+func (s *S3) endpoint(key string) string {
+	if s.client.Endpoint != "" {
+		return fmt.Sprintf("%s/%s/%s", strings.TrimRight(s.client.Endpoint, "/"), s.bucket, escapeKey(key))
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.bucket, s.client.Region, escapeKey(key))
+}
+
+// helper function escapes each segment of the object key.
+func escapeKey(key string) string {
+	parts := strings.Split(key, "/")
+	for i, part := range parts {
+		parts[i] = strings.Replace(url.QueryEscape(part), "+", "%20", -1)
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,58 @@
+package aws
+
+// SecretsManager is a client for the AWS Secrets Manager service.
+type SecretsManager struct {
+	client *Client
+}
+
+// NewSecretsManager returns a Secrets Manager client.
+func NewSecretsManager(client *Client) *SecretsManager {
+	return &SecretsManager{client}
+}
+
+// GetSecretValue returns the string value of the named secret.
+func (s *SecretsManager) GetSecretValue(name string) (string, error) {
+	in := map[string]string{
+		"SecretId": name,
+	}
+	out := struct {
+		SecretString string
+	}{}
+	err := s.client.Do("secretsmanager", "secretsmanager.GetSecretValue", in, &out)
+	return out.SecretString, err
+}
+
+// ListSecrets returns the names of the secrets that begin with the prefix.
+func (s *SecretsManager) ListSecrets(prefix string) ([]string, error) {
+	type filter struct {
+		Key    string
+		Values []string
+	}
+	var names []string
+	var token string
+	for {
+		in := struct {
+			Filters   []filter
+			NextToken string `json:",omitempty"`
+		}{
+			Filters:   []filter{{"name", []string{prefix}}},
+			NextToken: token,
+		}
+		out := struct {
+			SecretList []struct {
+				Name string
+			}
+			NextToken string
+		}{}
+		if err := s.client.Do("secretsmanager", "secretsmanager.ListSecrets", in, &out); err != nil {
+			return nil, err
+		}
+		for _, secret := range out.SecretList {
+			names = append(names, secret.Name)
+		}
+		if out.NextToken == "" {
+			return names, nil
+		}
+		token = out.NextToken
+	}
+}
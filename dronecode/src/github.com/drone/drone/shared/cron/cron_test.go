@@ -0,0 +1,71 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	for _, expr := range []string{
+		"* * * * *",
+		"*/15 * * * *",
+		"0 0 * * mon-fri",
+		"30 4 1,15 * 5",
+		"0 0 1 jan *",
+		"0 0 * * 7",
+		"@daily",
+		"@hourly",
+	} {
+		if _, err := Parse(expr); err != nil {
+			t.Errorf("Want expression %q parsed, got error %s", expr, err)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"foo * * * *",
+		"@never",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Want error parsing expression %q", expr)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	now := time.Date(2017, time.March, 15, 10, 30, 45, 0, time.UTC) // wednesday
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2017, time.March, 15, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2017, time.March, 15, 10, 45, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2017, time.March, 15, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2017, time.March, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 9 * * mon", time.Date(2017, time.March, 20, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2017, time.March, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2017, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2018, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * fri", time.Date(2017, time.March, 17, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		s, err := Parse(test.expr)
+		if err != nil {
+			t.Errorf("Unexpected error parsing %q: %s", test.expr, err)
+			continue
+		}
+		if got := s.Next(now); !got.Equal(test.want) {
+			t.Errorf("Want next activation for %q at %s, got %s", test.expr, test.want, got)
+		}
+	}
+}
@@ -17,7 +17,7 @@ func IsHttps(r *http.Request) bool {
 		return true
 	case strings.HasPrefix(r.Proto, "HTTPS"):
 		return true
-	case r.Header.Get("X-Forwarded-Proto") == "https":
+	case forwarded(r, "X-Forwarded-Proto") == "https":
 		return true
 	default:
 		return false
@@ -36,7 +36,7 @@ func GetScheme(r *http.Request) string {
 		return "https"
 	case strings.HasPrefix(r.Proto, "HTTPS"):
 		return "https"
-	case r.Header.Get("X-Forwarded-Proto") == "https":
+	case forwarded(r, "X-Forwarded-Proto") == "https":
 		return "https"
 	default:
 		return "http"
@@ -45,10 +45,12 @@ func GetScheme(r *http.Request) string {
 
 // GetHost is a helper function that evaluates the http.Request
 // and returns the hostname. It is able to detect, using the
-// X-Forarded-For header, the original hostname when routed
-// through a reverse proxy.
+// X-Forwarded-Host and X-Forarded-For headers, the original
+// hostname when routed through a reverse proxy.
 func GetHost(r *http.Request) string {
 	switch {
+	case len(forwarded(r, "X-Forwarded-Host")) != 0:
+		return forwarded(r, "X-Forwarded-Host")
 	case len(r.Host) != 0:
 		return r.Host
 	case len(r.URL.Host) != 0:
@@ -66,11 +68,74 @@ func GetHost(r *http.Request) string {
 	}
 }
 
+// GetPrefix is a helper function that evaluates the http.Request
+// and returns the path prefix under which the server is served,
+// without a trailing slash. It is able to detect, using the
+// X-Forwarded-Prefix header, the path prefix when routed through
+// a reverse proxy.
+func GetPrefix(r *http.Request) string {
+	prefix := strings.TrimRight(forwarded(r, "X-Forwarded-Prefix"), "/")
+	if prefix != "" && !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+	// the prefix is used in redirects, and prefixes that are not a local
+	// path, such as //example.com or a url with a scheme, are ignored.
+	if strings.HasPrefix(prefix, "//") || strings.ContainsAny(prefix, ":\\") {
+		return ""
+	}
+	return prefix
+}
+
 // GetURL is a helper function that evaluates the http.Request
 // and returns the URL as a string. Only the scheme + hostname
-// are included; the path is excluded.
+// and the path prefix are included; the path is excluded.
 func GetURL(r *http.Request) string {
-	return GetScheme(r) + "://" + GetHost(r)
+	return GetScheme(r) + "://" + GetHost(r) + GetPrefix(r)
+}
+
+// Root returns an http.Handler that serves the server under the root
+// path, removing the root path from the request path. The root path is
+// used as the path prefix of the request unless the reverse proxy sets
+// the X-Forwarded-Prefix header.
+func Root(root string, h http.Handler) http.Handler {
+	root = strings.TrimRight(root, "/")
+	if root == "" {
+		return h
+	}
+	if !strings.HasPrefix(root, "/") {
+		root = "/" + root
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == root || strings.HasPrefix(r.URL.Path, root+"/") {
+			r.URL.Path = "/" + strings.TrimLeft(strings.TrimPrefix(r.URL.Path, root), "/")
+			r.URL.RawPath = ""
+		}
+		if r.Header.Get("X-Forwarded-Prefix") == "" {
+			r.Header.Set("X-Forwarded-Prefix", root)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// Untrusted returns an http.Handler that removes the X-Forwarded-Host and
+// X-Forwarded-Prefix headers from the request, for servers that are not
+// routed through a trusted reverse proxy.
+func Untrusted(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Del("X-Forwarded-Host")
+		r.Header.Del("X-Forwarded-Prefix")
+		h.ServeHTTP(w, r)
+	})
+}
+
+// helper function returns the first value of the forwarded header, which
+// is a comma separated list when routed through multiple proxies.
+func forwarded(r *http.Request, header string) string {
+	value := r.Header.Get(header)
+	if i := strings.Index(value, ","); i != -1 {
+		value = value[:i]
+	}
+	return strings.TrimSpace(value)
 }
 
 // GetCookie retrieves and verifies the cookie value.
@@ -88,7 +153,7 @@ func SetCookie(w http.ResponseWriter, r *http.Request, name, value string) {
 	cookie := http.Cookie{
 		Name:     name,
 		Value:    value,
-		Path:     "/",
+		Path:     GetPrefix(r) + "/",
 		Domain:   r.URL.Host,
 		HttpOnly: true,
 		Secure:   IsHttps(r),
@@ -103,7 +168,7 @@ func DelCookie(w http.ResponseWriter, r *http.Request, name string) {
 	cookie := http.Cookie{
 		Name:   name,
 		Value:  "deleted",
-		Path:   "/",
+		Path:   GetPrefix(r) + "/",
 		Domain: r.URL.Host,
 		MaxAge: -1,
 	}
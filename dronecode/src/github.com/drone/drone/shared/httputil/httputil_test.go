@@ -0,0 +1,73 @@
+package httputil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetURL(t *testing.T) {
+	r := httptest.NewRequest("GET", "http://drone:8000/api/user", nil)
+	if got, want := GetURL(r), "http://drone:8000"; got != want {
+		t.Errorf("Want url %s, got %s", want, got)
+	}
+
+	r.Header.Set("X-Forwarded-Proto", "https, http")
+	r.Header.Set("X-Forwarded-Host", "ci.example.com")
+	r.Header.Set("X-Forwarded-Prefix", "/drone/")
+	if got, want := GetURL(r), "https://ci.example.com/drone"; got != want {
+		t.Errorf("Want forwarded url %s, got %s", want, got)
+	}
+}
+
+func TestRoot(t *testing.T) {
+	var path, prefix string
+	h := Root("/drone", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, prefix = r.URL.Path, GetPrefix(r)
+	}))
+
+	for _, test := range []struct {
+		path, want string
+	}{
+		{"/drone/api/user", "/api/user"},
+		{"/drone", "/"},
+		{"/api/user", "/api/user"},
+		{"/droneci/api/user", "/droneci/api/user"},
+	} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", test.path, nil))
+		if path != test.want {
+			t.Errorf("Want path %s served as %s, got %s", test.path, test.want, path)
+		}
+		if prefix != "/drone" {
+			t.Errorf("Want prefix /drone, got %s", prefix)
+		}
+	}
+}
+
+func TestGetPrefixInvalid(t *testing.T) {
+	for _, prefix := range []string{"//example.com", "///example.com/", "https://example.com", "/\\example.com", "javascript:alert(1)"} {
+		r := httptest.NewRequest("GET", "/login", nil)
+		r.Header.Set("X-Forwarded-Prefix", prefix)
+		if got := GetPrefix(r); got != "" {
+			t.Errorf("Want prefix %q ignored, got %s", prefix, got)
+		}
+	}
+}
+
+func TestUntrusted(t *testing.T) {
+	var host, prefix string
+	h := Untrusted(Root("/drone", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, prefix = GetHost(r), GetPrefix(r)
+	})))
+
+	r := httptest.NewRequest("GET", "http://drone:8000/drone/api/user", nil)
+	r.Header.Set("X-Forwarded-Host", "example.com")
+	r.Header.Set("X-Forwarded-Prefix", "/evil")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if host != "drone:8000" {
+		t.Errorf("Want untrusted forwarded host ignored, got %s", host)
+	}
+	if prefix != "/drone" {
+		t.Errorf("Want untrusted forwarded prefix ignored, got %s", prefix)
+	}
+}
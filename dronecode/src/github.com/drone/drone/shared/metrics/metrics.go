@@ -0,0 +1,51 @@
+// Package metrics provides the prometheus collectors that are updated by
+// the server and the store.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// HookDuration observes the time taken to process a webhook.
+	HookDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name: "drone_webhook_duration_seconds",
+		Help: "Webhook processing latency.",
+	})
+
+	// QueryDuration observes the time taken to execute a database query.
+	QueryDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "drone_database_query_duration_seconds",
+		Help:    "Database query latency.",
+		Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	})
+
+	// Agents tracks the number of connected agents.
+	Agents = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "drone_agent_count",
+		Help: "Number of connected agents.",
+	})
+
+	// Builds counts completed builds by status.
+	Builds = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "drone_build_total",
+		Help: "Total number of completed builds.",
+	}, []string{"status"})
+
+	// RateLimited counts the requests rejected by the rate limits, by
+	// endpoint.
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "drone_ratelimit_rejected_total",
+		Help: "Total number of requests rejected by the rate limits.",
+	}, []string{"endpoint"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		HookDuration,
+		QueryDuration,
+		Agents,
+		Builds,
+		RateLimited,
+	)
+}
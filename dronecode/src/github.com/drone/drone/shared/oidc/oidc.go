@@ -0,0 +1,263 @@
+// Package oidc provides a minimal OpenID Connect relying party, which
+// authenticates users with the authorization code flow of a provider such
+// as Keycloak, Okta or Dex, and verifies the signed id token.
+package oidc
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"golang.org/x/oauth2"
+)
+
+// Claims defines the claims of a verified id token.
+type Claims struct {
+	Subject string
+	Email   string
+	Name    string
+	Groups  []string
+}
+
+// Provider is an OpenID Connect provider.
+type Provider struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+
+	// GroupsClaim is the name of the id token claim that lists the user
+	// groups.
+	GroupsClaim string
+
+	client *http.Client
+
+	mu        sync.Mutex
+	discovery *discovery
+	keys      map[string]*rsa.PublicKey
+}
+
+// discovery defines the provider metadata.
+type discovery struct {
+	Issuer   string `json:"issuer"`
+	AuthURL  string `json:"authorization_endpoint"`
+	TokenURL string `json:"token_endpoint"`
+	KeysURL  string `json:"jwks_uri"`
+}
+
+// New returns a provider for the issuer url. The provider metadata is
+// discovered when the provider is first used.
+func New(issuer, clientID, clientSecret string) *Provider {
+	return &Provider{
+		Issuer:       strings.TrimSuffix(issuer, "/"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scopes:       []string{"openid", "profile", "email"},
+		GroupsClaim:  "groups",
+		client:       &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// AuthCodeURL returns the url of the provider login page. The provider
+// redirects to the redirect url with the state and authorization code,
+// and the nonce is included in the id token.
+func (p *Provider) AuthCodeURL(redirect, state, nonce string) (string, error) {
+	config, err := p.config(redirect)
+	if err != nil {
+		return "", err
+	}
+	return config.AuthCodeURL(state, oauth2.SetAuthURLParam("nonce", nonce)), nil
+}
+
+// Exchange exchanges the authorization code for the id token, and returns
+// the verified claims of the id token.
+func (p *Provider) Exchange(redirect, code, nonce string) (*Claims, error) {
+	config, err := p.config(redirect)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.WithValue(oauth2.NoContext, oauth2.HTTPClient, p.client)
+	token, err := config.Exchange(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	raw, ok := token.Extra("id_token").(string)
+	if !ok || raw == "" {
+		return nil, errors.New("oidc: token response does not include an id token")
+	}
+	return p.Verify(raw, nonce)
+}
+
+// Verify verifies the signature, issuer, audience, expiry and nonce of the
+// id token, and returns the claims.
+func (p *Provider) Verify(raw, nonce string) (*Claims, error) {
+	d, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := jwt.Parse(raw, func(t *jwt.Token) (interface{}, error) {
+		if t.Method.Alg() != "RS256" {
+			return nil, fmt.Errorf("oidc: unsupported signing algorithm %s", t.Method.Alg())
+		}
+		kid, _ := t.Header["kid"].(string)
+		return p.key(kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+	claims := parsed.Claims
+	if iss, _ := claims["iss"].(string); iss != d.Issuer {
+		return nil, fmt.Errorf("oidc: unexpected issuer %s", iss)
+	}
+	if !audience(claims["aud"], p.ClientID) {
+		return nil, errors.New("oidc: id token is not issued for the client")
+	}
+	if _, ok := claims["exp"].(float64); !ok {
+		return nil, errors.New("oidc: id token does not expire")
+	}
+	if got, _ := claims["nonce"].(string); got != nonce {
+		return nil, errors.New("oidc: invalid nonce")
+	}
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return nil, errors.New("oidc: id token does not include a subject")
+	}
+	out := &Claims{Subject: sub}
+	out.Email, _ = claims["email"].(string)
+	out.Name, _ = claims["name"].(string)
+	switch groups := claims[p.GroupsClaim].(type) {
+	case string:
+		out.Groups = []string{groups}
+	case []interface{}:
+		for _, group := range groups {
+			if s, ok := group.(string); ok {
+				out.Groups = append(out.Groups, s)
+			}
+		}
+	}
+	return out, nil
+}
+
+// config returns the oauth2 configuration of the provider.
+func (p *Provider) config(redirect string) (*oauth2.Config, error) {
+	d, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+	return &oauth2.Config{
+		ClientID:     p.ClientID,
+		ClientSecret: p.ClientSecret,
+		Scopes:       p.Scopes,
+		RedirectURL:  redirect,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  d.AuthURL,
+			TokenURL: d.TokenURL,
+		},
+	}, nil
+}
+
+// discover returns the provider metadata, which is fetched once.
+func (p *Provider) discover() (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+	d := new(discovery)
+	if err := p.get(p.Issuer+"/.well-known/openid-configuration", d); err != nil {
+		return nil, err
+	}
+	if d.Issuer != p.Issuer {
+		return nil, fmt.Errorf("oidc: issuer %s does not match the discovered issuer %s", p.Issuer, d.Issuer)
+	}
+	p.discovery = d
+	return d, nil
+}
+
+// key returns the signing key of the key id. The provider keys are fetched
+// again if the key id is unknown, since providers rotate their keys.
+func (p *Provider) key(kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	set := struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}{}
+	if err := p.get(p.discovery.KeysURL, &set); err != nil {
+		return nil, err
+	}
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	p.keys = keys
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	// tokens without a key id are verified with the only key.
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("oidc: unknown signing key %q", kid)
+}
+
+// helper function gets the json document at the url.
+func (p *Provider) get(url string, v interface{}) error {
+	res, err := p.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		return fmt.Errorf("oidc: cannot get %s. %s", url, res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// helper function returns true if the audience claim includes the client.
+func audience(aud interface{}, client string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == client
+	case []interface{}:
+		for _, v := range aud {
+			if v == client {
+				return true
+			}
+		}
+	}
+	return false
+}
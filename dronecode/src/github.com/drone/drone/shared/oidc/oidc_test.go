@@ -0,0 +1,122 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+func TestProvider(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		server *httptest.Server
+		claims map[string]interface{}
+	)
+	sign := func(claims map[string]interface{}) string {
+		token := jwt.New(jwt.SigningMethodRS256)
+		token.Header["kid"] = "1"
+		token.Claims = claims
+		raw, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 server.URL,
+			"authorization_endpoint": server.URL + "/auth",
+			"token_endpoint":         server.URL + "/token",
+			"jwks_uri":               server.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": "1",
+				"kty": "RSA",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "c0de" {
+			w.WriteHeader(400)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "a",
+			"token_type":   "Bearer",
+			"id_token":     sign(claims),
+		})
+	})
+	server = httptest.NewServer(mux)
+	defer server.Close()
+
+	p := New(server.URL, "drone", "s3cr3t")
+	link, err := p.AuthCodeURL("http://drone.example.com/login/oidc", "state", "nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(link, server.URL+"/auth?") || !strings.Contains(link, "nonce=nonce") {
+		t.Errorf("Unexpected auth url %s", link)
+	}
+
+	claims = map[string]interface{}{
+		"iss":    server.URL,
+		"aud":    "drone",
+		"sub":    "248289761001",
+		"email":  "janedoe@example.com",
+		"exp":    float64(time.Now().Add(time.Hour).Unix()),
+		"nonce":  "nonce",
+		"groups": []string{"developers", "admins"},
+	}
+	got, err := p.Exchange("http://drone.example.com/login/oidc", "c0de", "nonce")
+	if err != nil {
+		t.Fatalf("Unexpected exchange error. %s", err)
+	}
+	if got.Subject != "248289761001" || got.Email != "janedoe@example.com" {
+		t.Errorf("Unexpected claims %+v", got)
+	}
+	if len(got.Groups) != 2 || got.Groups[1] != "admins" {
+		t.Errorf("Want groups claim, got %v", got.Groups)
+	}
+
+	invalid := []map[string]interface{}{
+		{"iss": "https://evil.example.com", "aud": "drone", "sub": "1", "exp": claims["exp"], "nonce": "nonce"},
+		{"iss": server.URL, "aud": "other", "sub": "1", "exp": claims["exp"], "nonce": "nonce"},
+		{"iss": server.URL, "aud": "drone", "sub": "1", "nonce": "nonce"},
+		{"iss": server.URL, "aud": "drone", "sub": "1", "exp": claims["exp"], "nonce": "replayed"},
+		{"iss": server.URL, "aud": "drone", "sub": "1", "exp": float64(time.Now().Add(-time.Hour).Unix()), "nonce": "nonce"},
+	}
+	for _, claims := range invalid {
+		if _, err := p.Verify(sign(claims), "nonce"); err == nil {
+			t.Errorf("Want error verifying id token with claims %v", claims)
+		}
+	}
+
+	// tokens signed with a shared secret are rejected.
+	hs := jwt.New(jwt.SigningMethodHS256)
+	hs.Claims = claims
+	raw, _ := hs.SignedString([]byte("s3cr3t"))
+	if _, err := p.Verify(raw, "nonce"); err == nil {
+		t.Errorf("Want error verifying id token signed with HS256")
+	}
+}
@@ -1,6 +1,9 @@
 package token
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"net/http"
 
@@ -10,11 +13,16 @@ import (
 type SecretFunc func(*Token) (string, error)
 
 const (
-	UserToken  = "user"
-	SessToken  = "sess"
-	HookToken  = "hook"
-	CsrfToken  = "csrf"
-	AgentToken = "agent"
+	UserToken   = "user"
+	SessToken   = "sess"
+	HookToken   = "hook"
+	CsrfToken   = "csrf"
+	AgentToken  = "agent"
+	AccessToken = "access"
+	OIDCToken   = "oidc"
+	BadgeToken  = "badge"
+	CacheToken  = "cache"
+	CacheRead   = "cache-read"
 )
 
 // Default algorithm used to sign JWT tokens.
@@ -78,6 +86,15 @@ func CheckCsrf(r *http.Request, fn SecretFunc) error {
 	return err
 }
 
+// KindSecret derives the secret used to sign the tokens of the kind from the
+// secret, so that a token of one kind is not verified as a token of another
+// kind signed with the same secret.
+func KindSecret(secret, kind string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(kind))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
 func New(kind, text string) *Token {
 	return &Token{Kind: kind, Text: text}
 }
@@ -0,0 +1,241 @@
+// Package trace provides minimal distributed tracing, which records spans
+// and exports them in batches to a Zipkin compatible collector such as
+// Zipkin or Jaeger.
+package trace
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	batchSize     = 100
+	bufferSize    = 1000
+	flushInterval = time.Second * 5
+)
+
+// Tracer records spans and exports them to the collector endpoint. A nil
+// Tracer is valid and records nothing.
+type Tracer struct {
+	endpoint string
+	service  string
+	client   *http.Client
+
+	spans chan *Span
+	done  chan struct{}
+	once  sync.Once
+}
+
+// New returns a tracer that exports spans of the named service to the
+// Zipkin v2 api of the collector, e.g. http://localhost:9411/api/v2/spans.
+func New(endpoint, service string) *Tracer {
+	t := &Tracer{
+		endpoint: endpoint,
+		service:  service,
+		client:   &http.Client{Timeout: time.Second * 10},
+		spans:    make(chan *Span, bufferSize),
+		done:     make(chan struct{}),
+	}
+	go t.loop()
+	return t
+}
+
+// Start starts a span as a child of the parent span context, or as the root
+// of a new trace if the parent context is empty or invalid.
+func (t *Tracer) Start(name, parent string) *Span {
+	return t.StartAt(name, parent, time.Now())
+}
+
+// StartAt starts a span at the given time, which is used to record spans
+// that began before they were observed, such as the time spent queued.
+func (t *Tracer) StartAt(name, parent string, start time.Time) *Span {
+	if t == nil {
+		return nil
+	}
+	s := &Span{
+		tracer: t,
+		name:   name,
+		start:  start,
+		id:     newID(),
+		tags:   map[string]string{},
+	}
+	if traceID, parentID, ok := parse(parent); ok {
+		s.traceID = traceID
+		s.parentID = parentID
+	} else {
+		s.traceID = newID()
+	}
+	return s
+}
+
+// Close exports the remaining spans and stops the tracer.
+func (t *Tracer) Close() {
+	if t == nil {
+		return
+	}
+	t.once.Do(func() {
+		close(t.spans)
+		<-t.done
+	})
+}
+
+// helper function batches finished spans and exports them when the batch
+// is full, or periodically.
+func (t *Tracer) loop() {
+	defer close(t.done)
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	var batch []*span
+	for {
+		select {
+		case s, ok := <-t.spans:
+			if !ok {
+				t.export(batch)
+				return
+			}
+			batch = append(batch, s.zipkin())
+			if len(batch) >= batchSize {
+				t.export(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			t.export(batch)
+			batch = nil
+		}
+	}
+}
+
+// helper function posts the spans to the collector. Spans that cannot be
+// exported are dropped.
+func (t *Tracer) export(batch []*span) {
+	if len(batch) == 0 {
+		return
+	}
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return
+	}
+	resp, err := t.client.Post(t.endpoint, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+	resp.Body.Close()
+}
+
+// Span is a timed operation of a trace. A nil Span is valid and records
+// nothing.
+type Span struct {
+	tracer   *Tracer
+	name     string
+	traceID  string
+	parentID string
+	id       string
+	start    time.Time
+	duration time.Duration
+	tags     map[string]string
+}
+
+// Tag sets the tag of the span.
+func (s *Span) Tag(key, value string) {
+	if s == nil {
+		return
+	}
+	s.tags[key] = value
+}
+
+// Finish ends the span and queues it for export. The span is dropped if
+// the export buffer is full.
+func (s *Span) Finish() {
+	s.FinishAt(time.Now())
+}
+
+// FinishAt ends the span at the given time.
+func (s *Span) FinishAt(end time.Time) {
+	if s == nil {
+		return
+	}
+	s.duration = end.Sub(s.start)
+	defer func() {
+		// sending on the closed buffer of a closed tracer panics.
+		recover()
+	}()
+	select {
+	case s.tracer.spans <- s:
+	default:
+	}
+}
+
+// Context returns the span context, which is propagated to start child
+// spans in other processes.
+func (s *Span) Context() string {
+	if s == nil {
+		return ""
+	}
+	return s.traceID + "-" + s.id
+}
+
+// span is the Zipkin v2 json representation of a span.
+type span struct {
+	TraceID       string            `json:"traceId"`
+	ID            string            `json:"id"`
+	ParentID      string            `json:"parentId,omitempty"`
+	Name          string            `json:"name"`
+	Timestamp     int64             `json:"timestamp"`
+	Duration      int64             `json:"duration"`
+	LocalEndpoint endpoint          `json:"localEndpoint"`
+	Tags          map[string]string `json:"tags,omitempty"`
+}
+
+type endpoint struct {
+	ServiceName string `json:"serviceName"`
+}
+
+func (s *Span) zipkin() *span {
+	d := s.duration
+	if d < time.Microsecond {
+		d = time.Microsecond
+	}
+	return &span{
+		TraceID:       s.traceID,
+		ID:            s.id,
+		ParentID:      s.parentID,
+		Name:          s.name,
+		Timestamp:     s.start.UnixNano() / int64(time.Microsecond),
+		Duration:      int64(d / time.Microsecond),
+		LocalEndpoint: endpoint{ServiceName: s.tracer.service},
+		Tags:          s.tags,
+	}
+}
+
+// helper function parses the trace and span id of the span context.
+func parse(context string) (traceID, spanID string, ok bool) {
+	parts := strings.Split(context, "-")
+	if len(parts) != 2 || !isID(parts[0]) || !isID(parts[1]) {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// helper function returns true if the id is a 64 or 128 bit hex id.
+func isID(id string) bool {
+	if len(id) != 16 && len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+// helper function returns a random 64 bit hex id.
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
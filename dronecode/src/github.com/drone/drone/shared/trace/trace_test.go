@@ -0,0 +1,74 @@
+package trace
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTracer(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		spans []*span
+	)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in []*span
+		json.NewDecoder(r.Body).Decode(&in)
+		mu.Lock()
+		spans = append(spans, in...)
+		mu.Unlock()
+	}))
+	defer s.Close()
+
+	tracer := New(s.URL, "drone-server")
+	root := tracer.Start("hook", "")
+	root.Tag("repo", "octocat/hello-world")
+	child := tracer.StartAt("queue", root.Context(), time.Now().Add(-time.Second))
+	child.Finish()
+	root.Finish()
+	tracer.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(spans) != 2 {
+		t.Fatalf("Want 2 exported spans, got %d", len(spans))
+	}
+	queue, hook := spans[0], spans[1]
+	if hook.Name != "hook" || hook.ParentID != "" || hook.Tags["repo"] != "octocat/hello-world" {
+		t.Errorf("Unexpected root span %+v", hook)
+	}
+	if queue.TraceID != hook.TraceID || queue.ParentID != hook.ID {
+		t.Errorf("Want queue span child of the hook span")
+	}
+	if queue.Duration < int64(time.Second/time.Microsecond) {
+		t.Errorf("Want queue span duration of at least 1s, got %dus", queue.Duration)
+	}
+	if queue.LocalEndpoint.ServiceName != "drone-server" {
+		t.Errorf("Want service name drone-server, got %s", queue.LocalEndpoint.ServiceName)
+	}
+}
+
+func TestTracerNil(t *testing.T) {
+	var tracer *Tracer
+	span := tracer.Start("hook", "")
+	span.Tag("repo", "octocat/hello-world")
+	span.Finish()
+	if span.Context() != "" {
+		t.Errorf("Want empty context of nil span")
+	}
+	tracer.Close()
+}
+
+func TestParse(t *testing.T) {
+	if _, _, ok := parse("4bf92f3577b34da6-00f067aa0ba902b7"); !ok {
+		t.Errorf("Want valid span context")
+	}
+	for _, context := range []string{"", "foo-bar", "4bf92f3577b34da6"} {
+		if _, _, ok := parse(context); ok {
+			t.Errorf("Want invalid span context %q", context)
+		}
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"golang.org/x/net/context"
 )
 
-const key = "store"
+const (
+	key        = "store"
+	replicaKey = "store_replica"
+)
 
 // Setter defines a context that enables setting values.
 type Setter interface {
@@ -21,3 +24,16 @@ func FromContext(c context.Context) Store {
 func ToContext(c Setter, store Store) {
 	c.Set(key, store)
 }
+
+// ReplicaFromContext returns the read replica Store associated with this
+// context, or nil if no read replica is configured.
+func ReplicaFromContext(c context.Context) Store {
+	store, _ := c.Value(replicaKey).(Store)
+	return store
+}
+
+// ReplicaToContext adds the read replica Store to this context if it
+// supports the Setter interface.
+func ReplicaToContext(c Setter, store Store) {
+	c.Set(replicaKey, store)
+}
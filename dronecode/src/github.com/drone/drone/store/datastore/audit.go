@@ -0,0 +1,24 @@
+package datastore
+
+import (
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/store/datastore/sql"
+	"github.com/russross/meddler"
+)
+
+func (db *datastore) AuditCreate(audit *model.Audit) error {
+	return meddler.Insert(db, "audit", audit)
+}
+
+func (db *datastore) AuditList(f *model.AuditFilter) ([]*model.Audit, error) {
+	stmt := sql.Lookup(db.driver, "audit-find")
+	data := []*model.Audit{}
+	err := meddler.QueryAll(db, &data, stmt,
+		f.User, f.User,
+		f.Repo, f.Repo,
+		f.After, f.After,
+		f.Before, f.Before,
+		f.Limit,
+	)
+	return data, err
+}
@@ -0,0 +1,53 @@
+package datastore
+
+import (
+	"testing"
+
+	"github.com/drone/drone/model"
+)
+
+func TestAuditList(t *testing.T) {
+	s := newTest()
+	defer func() {
+		s.Exec("delete from audit")
+		s.Close()
+	}()
+
+	entries := []*model.Audit{
+		{Action: model.AuditRepoActivate, User: "octocat", Repo: "octocat/hello-world", Created: 100},
+		{Action: model.AuditSecretCreate, User: "octocat", Repo: "octocat/hello-world", Target: "password", Created: 200},
+		{Action: model.AuditUserCreate, User: "admin", Target: "octocat", Created: 300},
+	}
+	for _, entry := range entries {
+		if err := s.AuditCreate(entry); err != nil {
+			t.Errorf("Unexpected error: insert audit: %s", err)
+			return
+		}
+	}
+
+	list, err := s.AuditList(&model.AuditFilter{Limit: 10})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := len(list), 3; got != want {
+		t.Errorf("Want %d audit entries, got %d", want, got)
+		return
+	}
+	if got, want := list[0].Action, model.AuditUserCreate; got != want {
+		t.Errorf("Want most recent audit action %s, got %s", want, got)
+	}
+
+	list, _ = s.AuditList(&model.AuditFilter{User: "octocat", Limit: 10})
+	if got, want := len(list), 2; got != want {
+		t.Errorf("Want %d audit entries for user, got %d", want, got)
+	}
+	list, _ = s.AuditList(&model.AuditFilter{Repo: "octocat/hello-world", After: 150, Limit: 10})
+	if got, want := len(list), 1; got != want {
+		t.Errorf("Want %d audit entries for repo after time, got %d", want, got)
+	}
+	list, _ = s.AuditList(&model.AuditFilter{Before: 200, Limit: 10})
+	if got, want := len(list), 1; got != want {
+		t.Errorf("Want %d audit entries before time, got %d", want, got)
+	}
+}
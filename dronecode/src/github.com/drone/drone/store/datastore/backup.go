@@ -0,0 +1,165 @@
+package datastore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/store/datastore/ddl"
+	"github.com/russross/meddler"
+)
+
+var errRestoreNotEmpty = errors.New("datastore: cannot restore into a database with existing users or repositories")
+
+// backupTables defines the tables included in the backup, in the order in
+// which they are restored. Logs, artifacts, queued tasks, hooks and webhook
+// deliveries are not included.
+var backupTables = []struct {
+	name string
+	pk   string
+	rows func() interface{}
+}{
+	{"users", "user_id", func() interface{} { return &[]*model.User{} }},
+	{"tokens", "token_id", func() interface{} { return &[]*model.AccessToken{} }},
+	{"repos", "repo_id", func() interface{} { return &[]*model.Repo{} }},
+	{"perms", "perm_id", func() interface{} { return &[]*model.RepoPerm{} }},
+	{"config", "config_id", func() interface{} { return &[]*model.Config{} }},
+	{"builds", "build_id", func() interface{} { return &[]*model.Build{} }},
+	{"procs", "proc_id", func() interface{} { return &[]*model.Proc{} }},
+	{"secrets", "secret_id", func() interface{} { return &[]*model.Secret{} }},
+	{"org_secrets", "org_secret_id", func() interface{} { return &[]*model.OrgSecret{} }},
+	{"registry", "registry_id", func() interface{} { return &[]*model.Registry{} }},
+	{"senders", "sender_id", func() interface{} { return &[]*model.Sender{} }},
+	{"crons", "cron_id", func() interface{} { return &[]*model.Cron{} }},
+	{"templates", "template_id", func() interface{} { return &[]*model.Template{} }},
+	{"audit", "audit_id", func() interface{} { return &[]*model.Audit{} }},
+}
+
+func (db *datastore) Backup() (*model.Backup, error) {
+	backup := &model.Backup{
+		Version: model.BackupVersion,
+		Created: time.Now().Unix(),
+		Tables:  map[string][]model.BackupRow{},
+	}
+	for _, table := range backupTables {
+		dst := table.rows()
+		if err := meddler.QueryAll(db, dst, "SELECT * FROM "+table.name); err != nil {
+			return nil, err
+		}
+		rows := reflect.ValueOf(dst).Elem()
+		list := make([]model.BackupRow, 0, rows.Len())
+		for i := 0; i < rows.Len(); i++ {
+			row, err := backupRow(rows.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, row)
+		}
+		backup.Tables[table.name] = list
+	}
+	return backup, nil
+}
+
+func (db *datastore) Restore(backup *model.Backup) error {
+	if backup.Version != model.BackupVersion {
+		return fmt.Errorf("datastore: unsupported backup version %d", backup.Version)
+	}
+	if backup.Sealed {
+		return errors.New("datastore: cannot restore a sealed backup")
+	}
+	var users, repos int
+	db.QueryRow("SELECT COUNT(*) FROM users").Scan(&users)
+	db.QueryRow("SELECT COUNT(*) FROM repos").Scan(&repos)
+	if users != 0 || repos != 0 {
+		return errRestoreNotEmpty
+	}
+
+	return db.transact(func(tx *sql.Tx) error {
+		for _, table := range backupTables {
+			rows := backup.Tables[table.name]
+			for _, row := range rows {
+				if err := restoreRow(tx, table.name, row); err != nil {
+					return fmt.Errorf("datastore: cannot restore %s. %s", table.name, err)
+				}
+			}
+			// postgres does not advance the sequence of the primary key
+			// when the primary key is inserted explicitly. cockroach
+			// generates primary keys without a sequence.
+			if len(rows) != 0 && meddler.Default == meddler.PostgreSQL && db.driver != ddl.DriverCockroach {
+				stmt := fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%s', '%s'), MAX(%s)) FROM %s", table.name, table.pk, table.pk, table.name)
+				if _, err := tx.Exec(stmt); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// helper function returns the columns and values of the struct, including
+// the primary key, as a backup row.
+func backupRow(src interface{}) (model.BackupRow, error) {
+	columns, err := meddler.Columns(src, true)
+	if err != nil {
+		return nil, err
+	}
+	values, err := meddler.Values(src, true)
+	if err != nil {
+		return nil, err
+	}
+	row := model.BackupRow{}
+	for i, column := range columns {
+		// json encoded columns are written as bytes, and are stored as text.
+		if b, ok := values[i].([]byte); ok {
+			values[i] = string(b)
+		}
+		row[column] = values[i]
+	}
+	return row, nil
+}
+
+// helper function inserts the backup row into the table.
+func restoreRow(tx *sql.Tx, table string, row model.BackupRow) error {
+	columns := make([]string, 0, len(row))
+	for column := range row {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	params := make([]string, len(columns))
+	values := make([]interface{}, len(columns))
+	for i, column := range columns {
+		params[i] = "?"
+		values[i] = restoreValue(row[column])
+	}
+	stmt := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		table,
+		strings.Join(columns, ","),
+		strings.Join(params, ","),
+	)
+	_, err := tx.Exec(rebind(stmt), values...)
+	return err
+}
+
+// helper function converts json decoded numbers to integers.
+func restoreValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case float64:
+		if v == float64(int64(v)) {
+			return int64(v)
+		}
+	}
+	return value
+}
@@ -0,0 +1,137 @@
+package datastore
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/drone/drone/model"
+)
+
+func TestBackupRestore(t *testing.T) {
+	s := newTest()
+	defer func() {
+		s.Exec("delete from users")
+		s.Exec("delete from repos")
+		s.Exec("delete from secrets")
+		s.Exec("delete from builds")
+		s.Close()
+	}()
+
+	user := &model.User{Login: "octocat", Email: "octocat@github.com", Token: "e42080dddf012c718e476da161d21ad5"}
+	repo := &model.Repo{UserID: 1, Owner: "octocat", Name: "hello-world", FullName: "octocat/hello-world", Downstream: []string{"octocat/spoon-knife"}}
+	s.CreateUser(user)
+	s.CreateRepo(repo)
+	s.SecretCreate(&model.Secret{RepoID: repo.ID, Name: "password", Value: "correct-horse-battery-staple", Events: []string{"push"}})
+	s.CreateBuild(&model.Build{RepoID: repo.ID, Status: model.StatusSuccess, Params: map[string]string{"foo": "bar"}})
+
+	backup, err := s.Backup()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := len(backup.Tables["users"]), 1; got != want {
+		t.Errorf("Want %d users in backup, got %d", want, got)
+	}
+	if err := s.Restore(backup); err != errRestoreNotEmpty {
+		t.Errorf("Want error restoring into database with data, got %v", err)
+	}
+
+	// restore from the json encoded backup into an empty database.
+	data, _ := json.Marshal(backup)
+	backup = new(model.Backup)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(backup); err != nil {
+		t.Error(err)
+		return
+	}
+	s.Exec("delete from users")
+	s.Exec("delete from repos")
+	s.Exec("delete from secrets")
+	s.Exec("delete from builds")
+	if err := s.Restore(backup); err != nil {
+		t.Error(err)
+		return
+	}
+
+	restored, err := s.GetUser(user.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := restored.Token, user.Token; got != want {
+		t.Errorf("Want user token %s, got %s", want, got)
+	}
+	restoredRepo, err := s.GetRepoName("octocat/hello-world")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := restoredRepo.ID, repo.ID; got != want {
+		t.Errorf("Want repo id %d, got %d", want, got)
+	}
+	if got, want := len(restoredRepo.Downstream), 1; got != want {
+		t.Errorf("Want %d downstream repositories, got %d", want, got)
+	}
+	secret, err := s.SecretFind(restoredRepo, "password")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := secret.Value, "correct-horse-battery-staple"; got != want {
+		t.Errorf("Want secret value %s, got %s", want, got)
+	}
+	build, err := s.GetBuildNumber(restoredRepo, 1)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := build.Params["foo"], "bar"; got != want {
+		t.Errorf("Want build param %s, got %s", want, got)
+	}
+}
+
+// TestBackupSealed verifies that the string columns hidden from the api,
+// such as tokens and hashes, are encrypted when the backup is sealed.
+func TestBackupSealed(t *testing.T) {
+	// columns hidden from the api that are not secret.
+	public := map[string]bool{
+		"user_subject": true,
+	}
+
+	backup := &model.Backup{Version: model.BackupVersion, Tables: map[string][]model.BackupRow{}}
+	hidden := map[string]string{}
+	for _, table := range backupTables {
+		typ := reflect.TypeOf(table.rows()).Elem().Elem().Elem()
+		src := reflect.New(typ)
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			column := strings.Split(field.Tag.Get("meddler"), ",")[0]
+			if field.Tag.Get("json") != "-" || field.Type.Kind() != reflect.String || public[column] {
+				continue
+			}
+			src.Elem().Field(i).SetString("plaintext-" + column)
+			hidden[column] = table.name
+		}
+		row, err := backupRow(src.Interface())
+		if err != nil {
+			t.Fatal(err)
+		}
+		backup.Tables[table.name] = []model.BackupRow{row}
+	}
+	if len(hidden) == 0 {
+		t.Fatalf("Want hidden columns in the backup tables")
+	}
+
+	if err := backup.Seal("correct-horse-battery-staple"); err != nil {
+		t.Fatal(err)
+	}
+	for column, table := range hidden {
+		if got := backup.Tables[table][0][column]; got == "plaintext-"+column {
+			t.Errorf("Want column %s of table %s sealed, got plaintext", column, table)
+		}
+	}
+}
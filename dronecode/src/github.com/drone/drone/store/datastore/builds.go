@@ -1,6 +1,10 @@
 package datastore
 
 import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/drone/drone/model"
@@ -49,12 +53,154 @@ func (db *datastore) GetBuildList(repo *model.Repo) ([]*model.Build, error) {
 	return builds, err
 }
 
+func (db *datastore) GetBuildListFilter(repo *model.Repo, f *model.BuildFilter) ([]*model.Build, error) {
+	stmt, args := buildFilterWhere(repo, f)
+	if f.Before != 0 {
+		stmt += "\n  AND build_number < ?"
+		args = append(args, f.Before)
+	}
+	if f.After != 0 {
+		stmt += "\n  AND build_number > ?"
+		args = append(args, f.After)
+	}
+	order := "DESC"
+	if f.Ascending {
+		order = "ASC"
+	}
+	args = append(args, f.Limit, f.Offset)
+	builds := []*model.Build{}
+	err := meddler.QueryAll(db, &builds, rebind(fmt.Sprintf(buildFindQuery, stmt, order)), args...)
+	return builds, err
+}
+
+func (db *datastore) GetBuildCount(repo *model.Repo, f *model.BuildFilter) (count int, err error) {
+	stmt, args := buildFilterWhere(repo, f)
+	err = db.QueryRow(rebind(fmt.Sprintf(buildCountQuery, stmt)), args...).Scan(&count)
+	return
+}
+
+// helper function returns the conditions and arguments of the branch, event,
+// status and labels of the build filter. Only the conditions of the set
+// fields are included, so that the query uses the matching composite index.
+func buildFilterWhere(repo *model.Repo, f *model.BuildFilter) (string, []interface{}) {
+	var stmt string
+	args := []interface{}{repo.ID}
+	for _, cond := range []struct {
+		column string
+		value  string
+	}{
+		{"build_branch", f.Branch},
+		{"build_event", f.Event},
+		{"build_status", f.Status},
+	} {
+		if cond.value != "" {
+			stmt += "\n  AND " + cond.column + " = ?"
+			args = append(args, cond.value)
+		}
+	}
+	keys := make([]string, 0, len(f.Labels))
+	for k := range f.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		stmt += "\n  AND build_labels LIKE ? ESCAPE '!'"
+		args = append(args, "%"+escapeLike(labelJSON(k, f.Labels[k]))+"%")
+	}
+	return stmt, args
+}
+
+// helper function returns the label as encoded in the json labels column,
+// such that the label filter matches the encoded key and value.
+func labelJSON(k, v string) string {
+	key, _ := json.Marshal(k)
+	value, _ := json.Marshal(v)
+	return string(key) + ":" + string(value)
+}
+
+// helper function escapes the wildcards of a LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
+
+func (db *datastore) GetBuildCountUser(user *model.User, since int64) (count int, err error) {
+	err = db.QueryRow(rebind(buildCountUserQuery), user.ID, since).Scan(&count)
+	return
+}
+
+func (db *datastore) GetBuildTimes(repo *model.Repo, since int64) ([]*model.BuildTime, error) {
+	var times = []*model.BuildTime{}
+	var err = meddler.QueryAll(db, &times, rebind(buildTimesQuery), repo.ID, since)
+	return times, err
+}
+
 func (db *datastore) GetBuildQueue() ([]*model.Feed, error) {
 	feed := []*model.Feed{}
 	err := meddler.QueryAll(db, &feed, buildQueueList)
 	return feed, err
 }
 
+func (db *datastore) GetBuildFeed(listof []*model.RepoLite, f *model.FeedFilter) ([]*model.Feed, error) {
+	var (
+		where []string
+		args  []interface{}
+		feed  = []*model.Feed{}
+	)
+	if listof != nil {
+		if len(listof) == 0 {
+			return feed, nil
+		}
+		stmt, in := toList(listof)
+		where = append(where, fmt.Sprintf("r.repo_full_name IN (%s)", stmt))
+		args = append(args, in...)
+	}
+	if len(f.Status) != 0 {
+		params := make([]string, len(f.Status))
+		for i, status := range f.Status {
+			params[i] = "?"
+			args = append(args, status)
+		}
+		where = append(where, fmt.Sprintf("b.build_status IN (%s)", strings.Join(params, ",")))
+	}
+	if f.Branch != "" {
+		where = append(where, "b.build_branch = ?")
+		args = append(args, f.Branch)
+	}
+	if f.Event != "" {
+		where = append(where, "b.build_event = ?")
+		args = append(args, f.Event)
+	}
+	if f.Before != 0 {
+		where = append(where, "b.build_id < ?")
+		args = append(args, f.Before)
+	}
+	var stmt string
+	for _, cond := range where {
+		stmt += "\n  AND " + cond
+	}
+	args = append(args, f.Limit)
+	err := meddler.QueryAll(db, &feed, rebind(fmt.Sprintf(buildFeedQuery, stmt)), args...)
+	return feed, err
+}
+
+func (db *datastore) GetBuildActive(repo *model.Repo) ([]*model.Build, error) {
+	var builds = []*model.Build{}
+	var err = meddler.QueryAll(db, &builds, rebind(buildActiveQuery), repo.ID)
+	return builds, err
+}
+
+func (db *datastore) GetDeployList(repo *model.Repo, target string) ([]*model.Build, error) {
+	var builds = []*model.Build{}
+	var err = meddler.QueryAll(db, &builds, rebind(deployListQuery), repo.ID, target, target)
+	return builds, err
+}
+
+func (db *datastore) GetDeployLatest(repo *model.Repo) ([]*model.Build, error) {
+	var builds = []*model.Build{}
+	var err = meddler.QueryAll(db, &builds, rebind(deployLatestQuery), repo.ID)
+	return builds, err
+}
+
 func (db *datastore) CreateBuild(build *model.Build, procs ...*model.Proc) error {
 	var number int
 	db.QueryRow(rebind(buildNumberLast), build.RepoID).Scan(&number)
@@ -89,6 +235,71 @@ ORDER BY build_number DESC
 LIMIT 50
 `
 
+const buildFindQuery = `
+SELECT *
+FROM builds
+WHERE build_repo_id = ?%s
+ORDER BY build_number %s
+LIMIT ? OFFSET ?
+`
+
+const buildCountQuery = `
+SELECT count(1)
+FROM builds
+WHERE build_repo_id = ?%s
+`
+
+const buildCountUserQuery = `
+SELECT count(1)
+FROM builds
+INNER JOIN repos ON build_repo_id = repo_id
+WHERE repo_user_id = ?
+  AND build_enqueued >= ?
+`
+
+const buildTimesQuery = `
+SELECT
+ build_status
+,build_enqueued
+,build_started
+,build_finished
+FROM builds
+WHERE build_repo_id = ?
+  AND build_created >= ?
+  AND build_finished != 0
+`
+
+const buildActiveQuery = `
+SELECT *
+FROM builds
+WHERE build_repo_id = ?
+  AND build_status IN ('pending', 'running')
+ORDER BY build_number DESC
+`
+
+const deployListQuery = `
+SELECT *
+FROM builds
+WHERE build_repo_id = ?
+  AND build_event IN ('deployment', 'rollback')
+  AND (? = '' OR build_deploy = ?)
+ORDER BY build_number DESC
+LIMIT 50
+`
+
+const deployLatestQuery = `
+SELECT *
+FROM builds
+WHERE build_id IN (
+  SELECT MAX(build_id)
+  FROM builds
+  WHERE build_repo_id = ?
+    AND build_event IN ('deployment', 'rollback')
+  GROUP BY build_deploy
+)
+ORDER BY build_deploy
+`
+
 const buildNumberQuery = `
 SELECT *
 FROM builds
@@ -131,6 +342,7 @@ SELECT *
 FROM builds
 WHERE build_repo_id = ?
   AND build_ref     = ?
+ORDER BY build_number DESC
 LIMIT 1
 `
 
@@ -140,6 +352,36 @@ FROM builds
 WHERE build_repo_id = ?
 `
 
+const buildFeedQuery = `
+SELECT
+ build_id
+,repo_owner
+,repo_name
+,repo_full_name
+,build_number
+,build_event
+,build_status
+,build_created
+,build_started
+,build_finished
+,build_commit
+,build_branch
+,build_ref
+,build_refspec
+,build_remote
+,build_title
+,build_message
+,build_author
+,build_email
+,build_avatar
+FROM
+ builds b
+,repos r
+WHERE b.build_repo_id = r.repo_id%s
+ORDER BY b.build_id DESC
+LIMIT ?
+`
+
 const buildQueueList = `
 SELECT
  repo_owner
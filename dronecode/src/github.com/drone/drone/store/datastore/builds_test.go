@@ -67,6 +67,18 @@ func TestBuilds(t *testing.T) {
 			g.Assert(build.Status).Equal(getbuild.Status)
 		})
 
+		g.It("Should Get a Build with Params", func() {
+			build := model.Build{
+				RepoID: 1,
+				Status: model.StatusSuccess,
+				Params: map[string]string{"DEBUG": "true"},
+			}
+			s.CreateBuild(&build, []*model.Proc{}...)
+			getbuild, err := s.GetBuild(build.ID)
+			g.Assert(err == nil).IsTrue()
+			g.Assert(getbuild.Params["DEBUG"]).Equal("true")
+		})
+
 		g.It("Should Get a Build by Number", func() {
 			build1 := &model.Build{
 				RepoID: 1,
@@ -241,5 +253,199 @@ func TestBuilds(t *testing.T) {
 			g.Assert(builds[0].RepoID).Equal(build2.RepoID)
 			g.Assert(builds[0].Status).Equal(build2.Status)
 		})
+
+		g.It("Should get active Builds", func() {
+			build1 := &model.Build{
+				RepoID: 1,
+				Status: model.StatusRunning,
+			}
+			build2 := &model.Build{
+				RepoID: 1,
+				Status: model.StatusSuccess,
+			}
+			build3 := &model.Build{
+				RepoID: 1,
+				Status: model.StatusPending,
+			}
+			s.CreateBuild(build1, []*model.Proc{}...)
+			s.CreateBuild(build2, []*model.Proc{}...)
+			s.CreateBuild(build3, []*model.Proc{}...)
+			builds, err := s.GetBuildActive(&model.Repo{ID: 1})
+			g.Assert(err == nil).IsTrue()
+			g.Assert(len(builds)).Equal(2)
+			g.Assert(builds[0].ID).Equal(build3.ID)
+			g.Assert(builds[1].ID).Equal(build1.ID)
+		})
+
+		g.It("Should get a filtered List", func() {
+			for _, branch := range []string{"master", "dev", "master", "master"} {
+				s.CreateBuild(&model.Build{
+					RepoID: 1,
+					Branch: branch,
+					Event:  model.EventPush,
+					Status: model.StatusSuccess,
+				})
+			}
+			filter := &model.BuildFilter{Branch: "master", Limit: 2}
+			builds, err := s.GetBuildListFilter(&model.Repo{ID: 1}, filter)
+			g.Assert(err == nil).IsTrue()
+			g.Assert(len(builds)).Equal(2)
+			g.Assert(builds[0].Number).Equal(4)
+			g.Assert(builds[1].Number).Equal(3)
+
+			filter.Before = 3
+			builds, err = s.GetBuildListFilter(&model.Repo{ID: 1}, filter)
+			g.Assert(err == nil).IsTrue()
+			g.Assert(len(builds)).Equal(1)
+			g.Assert(builds[0].Number).Equal(1)
+
+			filter = &model.BuildFilter{After: 1, Ascending: true, Limit: 50}
+			builds, err = s.GetBuildListFilter(&model.Repo{ID: 1}, filter)
+			g.Assert(err == nil).IsTrue()
+			g.Assert(len(builds)).Equal(3)
+			g.Assert(builds[0].Number).Equal(2)
+
+			count, err := s.GetBuildCount(&model.Repo{ID: 1}, &model.BuildFilter{Branch: "master"})
+			g.Assert(err == nil).IsTrue()
+			g.Assert(count).Equal(3)
+		})
+
+		g.It("Should get a List filtered by Labels", func() {
+			for _, team := range []string{"payments", "pay_ents", "search"} {
+				s.CreateBuild(&model.Build{
+					RepoID: 1,
+					Labels: map[string]string{"team": team, "tier": "1"},
+				})
+			}
+			filter := &model.BuildFilter{Labels: map[string]string{"team": "payments"}, Limit: 50}
+			builds, err := s.GetBuildListFilter(&model.Repo{ID: 1}, filter)
+			g.Assert(err == nil).IsTrue()
+			g.Assert(len(builds)).Equal(1)
+			g.Assert(builds[0].Number).Equal(1)
+			g.Assert(builds[0].Labels["team"]).Equal("payments")
+
+			filter.Labels = map[string]string{"team": "pay_ents", "tier": "1"}
+			count, err := s.GetBuildCount(&model.Repo{ID: 1}, filter)
+			g.Assert(err == nil).IsTrue()
+			g.Assert(count).Equal(1)
+
+			filter.Labels = map[string]string{"tier": "2"}
+			count, err = s.GetBuildCount(&model.Repo{ID: 1}, filter)
+			g.Assert(err == nil).IsTrue()
+			g.Assert(count).Equal(0)
+		})
+
+		g.It("Should get the build Count of a User", func() {
+			defer db.Exec("DELETE FROM repos")
+			repo1 := &model.Repo{UserID: 1, Owner: "octocat", Name: "hello-world", FullName: "octocat/hello-world"}
+			repo2 := &model.Repo{UserID: 2, Owner: "drone", Name: "drone", FullName: "drone/drone"}
+			s.CreateRepo(repo1)
+			s.CreateRepo(repo2)
+			s.CreateBuild(&model.Build{RepoID: repo1.ID})
+			s.CreateBuild(&model.Build{RepoID: repo1.ID})
+			s.CreateBuild(&model.Build{RepoID: repo2.ID})
+
+			build := &model.Build{RepoID: repo1.ID}
+			s.CreateBuild(build)
+			build.Enqueued = 1
+			s.UpdateBuild(build)
+
+			count, err := s.GetBuildCountUser(&model.User{ID: 1}, 2)
+			g.Assert(err == nil).IsTrue()
+			g.Assert(count).Equal(2)
+		})
+
+		g.It("Should get the build Times", func() {
+			s.CreateBuild(&model.Build{RepoID: 1, Status: model.StatusSuccess, Started: 1, Finished: 2})
+			s.CreateBuild(&model.Build{RepoID: 1, Status: model.StatusRunning, Started: 1})
+			s.CreateBuild(&model.Build{RepoID: 2, Status: model.StatusSuccess, Started: 1, Finished: 2})
+			times, err := s.GetBuildTimes(&model.Repo{ID: 1}, 0)
+			g.Assert(err == nil).IsTrue()
+			g.Assert(len(times)).Equal(1)
+			g.Assert(times[0].Status).Equal(model.StatusSuccess)
+			g.Assert(times[0].Finished).Equal(int64(2))
+		})
+
+		g.It("Should get the build Feed", func() {
+			defer db.Exec("DELETE FROM repos")
+			repo1 := &model.Repo{UserID: 1, Owner: "octocat", Name: "hello-world", FullName: "octocat/hello-world"}
+			repo2 := &model.Repo{UserID: 1, Owner: "octocat", Name: "spoon-knife", FullName: "octocat/spoon-knife"}
+			s.CreateRepo(repo1)
+			s.CreateRepo(repo2)
+			s.CreateBuild(&model.Build{RepoID: repo1.ID, Status: model.StatusRunning})
+			s.CreateBuild(&model.Build{RepoID: repo1.ID, Status: model.StatusSuccess})
+			s.CreateBuild(&model.Build{RepoID: repo2.ID, Status: model.StatusRunning})
+
+			filter := &model.FeedFilter{Status: []string{model.StatusRunning}, Limit: 50}
+			feed, err := s.GetBuildFeed(nil, filter)
+			g.Assert(err == nil).IsTrue()
+			g.Assert(len(feed)).Equal(2)
+			g.Assert(feed[0].FullName).Equal("octocat/spoon-knife")
+
+			feed, err = s.GetBuildFeed([]*model.RepoLite{{FullName: "octocat/hello-world"}}, filter)
+			g.Assert(err == nil).IsTrue()
+			g.Assert(len(feed)).Equal(1)
+			g.Assert(feed[0].FullName).Equal("octocat/hello-world")
+
+			feed, err = s.GetBuildFeed([]*model.RepoLite{}, filter)
+			g.Assert(err == nil).IsTrue()
+			g.Assert(len(feed)).Equal(0)
+
+			feed, _ = s.GetBuildFeed(nil, filter)
+			filter.Before = feed[0].ID
+			feed, err = s.GetBuildFeed(nil, filter)
+			g.Assert(err == nil).IsTrue()
+			g.Assert(len(feed)).Equal(1)
+			g.Assert(feed[0].FullName).Equal("octocat/hello-world")
+		})
+
+		g.It("Should get Deployments", func() {
+			build1 := &model.Build{
+				RepoID: 1,
+				Event:  model.EventDeploy,
+				Deploy: "staging",
+			}
+			build2 := &model.Build{
+				RepoID: 1,
+				Event:  model.EventDeploy,
+				Deploy: "production",
+			}
+			build3 := &model.Build{
+				RepoID: 1,
+				Event:  model.EventDeploy,
+				Deploy: "staging",
+			}
+			build4 := &model.Build{
+				RepoID: 1,
+				Event:  model.EventPush,
+			}
+			build5 := &model.Build{
+				RepoID: 1,
+				Event:  model.EventRollback,
+				Deploy: "staging",
+			}
+			s.CreateBuild(build1, []*model.Proc{}...)
+			s.CreateBuild(build2, []*model.Proc{}...)
+			s.CreateBuild(build3, []*model.Proc{}...)
+			s.CreateBuild(build4, []*model.Proc{}...)
+			s.CreateBuild(build5, []*model.Proc{}...)
+
+			builds, err := s.GetDeployList(&model.Repo{ID: 1}, "")
+			g.Assert(err == nil).IsTrue()
+			g.Assert(len(builds)).Equal(4)
+
+			builds, err = s.GetDeployList(&model.Repo{ID: 1}, "staging")
+			g.Assert(err == nil).IsTrue()
+			g.Assert(len(builds)).Equal(3)
+			g.Assert(builds[0].ID).Equal(build5.ID)
+			g.Assert(builds[1].ID).Equal(build3.ID)
+			g.Assert(builds[2].ID).Equal(build1.ID)
+
+			builds, err = s.GetDeployLatest(&model.Repo{ID: 1})
+			g.Assert(err == nil).IsTrue()
+			g.Assert(len(builds)).Equal(2)
+			g.Assert(builds[0].ID).Equal(build2.ID)
+			g.Assert(builds[1].ID).Equal(build5.ID)
+		})
 	})
 }
@@ -0,0 +1,76 @@
+package datastore
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+
+	"github.com/drone/drone/model"
+	"github.com/russross/meddler"
+)
+
+func (db *datastore) CacheFind(repo *model.Repo, key string) (*model.Cache, error) {
+	cache := new(model.Cache)
+	err := meddler.QueryRow(db, cache, rebind(cacheFindQuery), repo.ID, key)
+	return cache, err
+}
+
+func (db *datastore) CacheFindPrefix(repo *model.Repo, prefix string) (*model.Cache, error) {
+	cache := new(model.Cache)
+	err := meddler.QueryRow(db, cache, rebind(cacheFindPrefixQuery), repo.ID, len(prefix), prefix)
+	return cache, err
+}
+
+func (db *datastore) CacheRead(cache *model.Cache) (io.ReadCloser, error) {
+	var data []byte
+	err := db.QueryRow(rebind(cacheDataQuery), cache.ID).Scan(&data)
+	return ioutil.NopCloser(bytes.NewReader(data)), err
+}
+
+func (db *datastore) CacheCreate(cache *model.Cache, r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return meddler.Insert(db, cacheTable, &cacheData{
+		ID:      cache.ID,
+		RepoID:  cache.RepoID,
+		Key:     cache.Key,
+		Size:    cache.Size,
+		Created: cache.Created,
+		Data:    data,
+	})
+}
+
+type cacheData struct {
+	ID      int64  `meddler:"cache_id,pk"`
+	RepoID  int64  `meddler:"cache_repo_id"`
+	Key     string `meddler:"cache_key"`
+	Size    int64  `meddler:"cache_size"`
+	Created int64  `meddler:"cache_created"`
+	Data    []byte `meddler:"cache_data"`
+}
+
+const cacheTable = "caches"
+
+const cacheFindQuery = `
+SELECT cache_id, cache_repo_id, cache_key, cache_size, cache_created
+FROM caches
+WHERE cache_repo_id = ?
+  AND cache_key = ?
+`
+
+const cacheFindPrefixQuery = `
+SELECT cache_id, cache_repo_id, cache_key, cache_size, cache_created
+FROM caches
+WHERE cache_repo_id = ?
+  AND SUBSTR(cache_key, 1, ?) = ?
+ORDER BY cache_created DESC, cache_id DESC
+LIMIT 1
+`
+
+const cacheDataQuery = `
+SELECT cache_data
+FROM caches
+WHERE cache_id = ?
+`
@@ -0,0 +1,70 @@
+package datastore
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/drone/drone/model"
+	"github.com/franela/goblin"
+)
+
+func TestCaches(t *testing.T) {
+	db := openTest()
+	defer db.Close()
+
+	s := From(db)
+	g := goblin.Goblin(t)
+	g.Describe("Caches", func() {
+
+		// before each test be sure to purge the package
+		// table data from the database.
+		g.BeforeEach(func() {
+			db.Exec("DELETE FROM caches")
+		})
+
+		g.It("Should create a cache", func() {
+			repo := &model.Repo{ID: 1}
+			err := s.CacheCreate(&model.Cache{
+				RepoID:  repo.ID,
+				Key:     "go-abc",
+				Size:    5,
+				Created: 1,
+			}, bytes.NewBufferString("hello"))
+			g.Assert(err == nil).IsTrue()
+
+			cache, err := s.CacheFind(repo, "go-abc")
+			g.Assert(err == nil).IsTrue()
+			g.Assert(cache.Key).Equal("go-abc")
+			g.Assert(cache.Size).Equal(int64(5))
+
+			rc, err := s.CacheRead(cache)
+			g.Assert(err == nil).IsTrue()
+			defer rc.Close()
+			out, _ := ioutil.ReadAll(rc)
+			g.Assert(string(out)).Equal("hello")
+		})
+
+		g.It("Should find the most recent cache by prefix", func() {
+			repo := &model.Repo{ID: 1}
+			s.CacheCreate(&model.Cache{RepoID: 1, Key: "go-abc", Created: 1}, bytes.NewBufferString("a"))
+			s.CacheCreate(&model.Cache{RepoID: 1, Key: "go-def", Created: 2}, bytes.NewBufferString("b"))
+			s.CacheCreate(&model.Cache{RepoID: 1, Key: "node-abc", Created: 3}, bytes.NewBufferString("c"))
+			s.CacheCreate(&model.Cache{RepoID: 2, Key: "go-ghi", Created: 4}, bytes.NewBufferString("d"))
+
+			cache, err := s.CacheFindPrefix(repo, "go-")
+			g.Assert(err == nil).IsTrue()
+			g.Assert(cache.Key).Equal("go-def")
+
+			_, err = s.CacheFindPrefix(repo, "ruby-")
+			g.Assert(err != nil).IsTrue()
+		})
+
+		g.It("Should enforce unique cache keys", func() {
+			err1 := s.CacheCreate(&model.Cache{RepoID: 1, Key: "go-abc"}, bytes.NewBufferString("a"))
+			err2 := s.CacheCreate(&model.Cache{RepoID: 1, Key: "go-abc"}, bytes.NewBufferString("b"))
+			g.Assert(err1 == nil).IsTrue()
+			g.Assert(err2 == nil).IsFalse()
+		})
+	})
+}
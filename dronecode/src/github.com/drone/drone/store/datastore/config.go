@@ -25,7 +25,7 @@ func (db *datastore) ConfigFind(repo *model.Repo, hash string) (*model.Config, e
 func (db *datastore) ConfigFindApproved(config *model.Config) (bool, error) {
 	var dest int64
 	stmt := sql.Lookup(db.driver, "config-find-approved")
-	err := db.DB.QueryRow(stmt, config.RepoID, config.ID).Scan(&dest)
+	err := db.QueryRow(stmt, config.RepoID, config.ID).Scan(&dest)
 	if err == gosql.ErrNoRows {
 		return false, nil
 	} else if err != nil {
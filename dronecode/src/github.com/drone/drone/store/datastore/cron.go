@@ -0,0 +1,42 @@
+package datastore
+
+import (
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/store/datastore/sql"
+	"github.com/russross/meddler"
+)
+
+func (db *datastore) CronFind(repo *model.Repo, name string) (*model.Cron, error) {
+	stmt := sql.Lookup(db.driver, "cron-find-repo-name")
+	data := new(model.Cron)
+	err := meddler.QueryRow(db, data, stmt, repo.ID, name)
+	return data, err
+}
+
+func (db *datastore) CronList(repo *model.Repo) ([]*model.Cron, error) {
+	stmt := sql.Lookup(db.driver, "cron-find-repo")
+	data := []*model.Cron{}
+	err := meddler.QueryAll(db, &data, stmt, repo.ID)
+	return data, err
+}
+
+func (db *datastore) CronListDue(before int64) ([]*model.Cron, error) {
+	stmt := sql.Lookup(db.driver, "cron-find-due")
+	data := []*model.Cron{}
+	err := meddler.QueryAll(db, &data, stmt, before)
+	return data, err
+}
+
+func (db *datastore) CronCreate(cron *model.Cron) error {
+	return meddler.Insert(db, "crons", cron)
+}
+
+func (db *datastore) CronUpdate(cron *model.Cron) error {
+	return meddler.Update(db, "crons", cron)
+}
+
+func (db *datastore) CronDelete(cron *model.Cron) error {
+	stmt := sql.Lookup(db.driver, "cron-delete")
+	_, err := db.Exec(stmt, cron.ID)
+	return err
+}
@@ -0,0 +1,193 @@
+package datastore
+
+import (
+	"testing"
+
+	"github.com/drone/drone/model"
+)
+
+func TestCronFind(t *testing.T) {
+	s := newTest()
+	defer func() {
+		s.Exec("delete from crons")
+		s.Close()
+	}()
+
+	err := s.CronCreate(&model.Cron{
+		RepoID:   1,
+		Name:     "nightly",
+		Schedule: "@daily",
+		Branch:   "master",
+		Next:     1000,
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: insert cron: %s", err)
+		return
+	}
+
+	cron, err := s.CronFind(&model.Repo{ID: 1}, "nightly")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := cron.RepoID, int64(1); got != want {
+		t.Errorf("Want repo id %d, got %d", want, got)
+	}
+	if got, want := cron.Schedule, "@daily"; got != want {
+		t.Errorf("Want cron schedule %s, got %s", want, got)
+	}
+	if got, want := cron.Branch, "master"; got != want {
+		t.Errorf("Want cron branch %s, got %s", want, got)
+	}
+}
+
+func TestCronList(t *testing.T) {
+	s := newTest()
+	defer func() {
+		s.Exec("delete from crons")
+		s.Close()
+	}()
+
+	s.CronCreate(&model.Cron{
+		RepoID:   1,
+		Name:     "nightly",
+		Schedule: "@daily",
+	})
+	s.CronCreate(&model.Cron{
+		RepoID:   1,
+		Name:     "weekly",
+		Schedule: "@weekly",
+	})
+
+	list, err := s.CronList(&model.Repo{ID: 1})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := len(list), 2; got != want {
+		t.Errorf("Want %d crons, got %d", want, got)
+	}
+}
+
+func TestCronListDue(t *testing.T) {
+	s := newTest()
+	defer func() {
+		s.Exec("delete from crons")
+		s.Close()
+	}()
+
+	s.CronCreate(&model.Cron{
+		RepoID:   1,
+		Name:     "nightly",
+		Schedule: "@daily",
+		Next:     1000,
+	})
+	s.CronCreate(&model.Cron{
+		RepoID:   1,
+		Name:     "weekly",
+		Schedule: "@weekly",
+		Next:     3000,
+	})
+	s.CronCreate(&model.Cron{
+		RepoID:   1,
+		Name:     "never",
+		Schedule: "0 0 30 2 *",
+		Next:     0,
+	})
+
+	list, err := s.CronListDue(2000)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := len(list), 1; got != want {
+		t.Errorf("Want %d due crons, got %d", want, got)
+		return
+	}
+	if got, want := list[0].Name, "nightly"; got != want {
+		t.Errorf("Want due cron %s, got %s", want, got)
+	}
+}
+
+func TestCronUpdate(t *testing.T) {
+	s := newTest()
+	defer func() {
+		s.Exec("delete from crons")
+		s.Close()
+	}()
+
+	cron := &model.Cron{
+		RepoID:   1,
+		Name:     "nightly",
+		Schedule: "@daily",
+		Next:     1000,
+	}
+	if err := s.CronCreate(cron); err != nil {
+		t.Errorf("Unexpected error: insert cron: %s", err)
+		return
+	}
+	cron.Next = 2000
+	if err := s.CronUpdate(cron); err != nil {
+		t.Errorf("Unexpected error: update cron: %s", err)
+		return
+	}
+	updated, err := s.CronFind(&model.Repo{ID: 1}, "nightly")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := updated.Next, int64(2000); got != want {
+		t.Errorf("Want next value %d, got %d", want, got)
+	}
+}
+
+func TestCronDelete(t *testing.T) {
+	s := newTest()
+	defer func() {
+		s.Exec("delete from crons")
+		s.Close()
+	}()
+
+	cron := &model.Cron{
+		RepoID:   1,
+		Name:     "nightly",
+		Schedule: "@daily",
+	}
+	if err := s.CronCreate(cron); err != nil {
+		t.Errorf("Unexpected error: insert cron: %s", err)
+		return
+	}
+	if err := s.CronDelete(cron); err != nil {
+		t.Errorf("Unexpected error: delete cron: %s", err)
+		return
+	}
+	if _, err := s.CronFind(&model.Repo{ID: 1}, "nightly"); err == nil {
+		t.Errorf("Expected error: cron not deleted")
+	}
+}
+
+func TestCronIndexes(t *testing.T) {
+	s := newTest()
+	defer func() {
+		s.Exec("delete from crons")
+		s.Close()
+	}()
+
+	if err := s.CronCreate(&model.Cron{
+		RepoID:   1,
+		Name:     "nightly",
+		Schedule: "@daily",
+	}); err != nil {
+		t.Errorf("Unexpected error: insert cron: %s", err)
+		return
+	}
+
+	// fail due to duplicate name
+	if err := s.CronCreate(&model.Cron{
+		RepoID:   1,
+		Name:     "nightly",
+		Schedule: "@weekly",
+	}); err == nil {
+		t.Errorf("Unexpected error: dupliate name")
+	}
+}
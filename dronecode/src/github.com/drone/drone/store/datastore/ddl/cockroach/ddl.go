@@ -0,0 +1,3 @@
+package cockroach
+
+//go:generate togo ddl -package cockroach -dialect postgres
@@ -0,0 +1,1047 @@
+package cockroach
+
+import (
+	"database/sql"
+)
+
+var migrations = []struct {
+	name string
+	stmt string
+}{
+	{
+		name: "create-table-users",
+		stmt: createTableUsers,
+	},
+	{
+		name: "create-table-repos",
+		stmt: createTableRepos,
+	},
+	{
+		name: "create-table-builds",
+		stmt: createTableBuilds,
+	},
+	{
+		name: "create-index-builds-repo",
+		stmt: createIndexBuildsRepo,
+	},
+	{
+		name: "create-index-builds-author",
+		stmt: createIndexBuildsAuthor,
+	},
+	{
+		name: "create-table-procs",
+		stmt: createTableProcs,
+	},
+	{
+		name: "create-index-procs-build",
+		stmt: createIndexProcsBuild,
+	},
+	{
+		name: "create-table-logs",
+		stmt: createTableLogs,
+	},
+	{
+		name: "create-table-files",
+		stmt: createTableFiles,
+	},
+	{
+		name: "create-index-files-builds",
+		stmt: createIndexFilesBuilds,
+	},
+	{
+		name: "create-index-files-procs",
+		stmt: createIndexFilesProcs,
+	},
+	{
+		name: "create-table-secrets",
+		stmt: createTableSecrets,
+	},
+	{
+		name: "create-index-secrets-repo",
+		stmt: createIndexSecretsRepo,
+	},
+	{
+		name: "create-table-registry",
+		stmt: createTableRegistry,
+	},
+	{
+		name: "create-index-registry-repo",
+		stmt: createIndexRegistryRepo,
+	},
+	{
+		name: "create-table-config",
+		stmt: createTableConfig,
+	},
+	{
+		name: "create-table-tasks",
+		stmt: createTableTasks,
+	},
+	{
+		name: "create-table-agents",
+		stmt: createTableAgents,
+	},
+	{
+		name: "create-table-senders",
+		stmt: createTableSenders,
+	},
+	{
+		name: "create-index-sender-repos",
+		stmt: createIndexSenderRepos,
+	},
+	{
+		name: "create-table-crons",
+		stmt: createTableCrons,
+	},
+	{
+		name: "create-index-cron-next",
+		stmt: createIndexCronNext,
+	},
+	{
+		name: "create-table-org-secrets",
+		stmt: createTableOrgSecrets,
+	},
+	{
+		name: "create-table-hooks",
+		stmt: createTableHooks,
+	},
+	{
+		name: "create-index-hooks-status",
+		stmt: createIndexHooksStatus,
+	},
+	{
+		name: "alter-table-repos-add-cancel-pulls",
+		stmt: alterTableReposAddCancelPulls,
+	},
+	{
+		name: "alter-table-repos-add-cancel-push",
+		stmt: alterTableReposAddCancelPush,
+	},
+	{
+		name: "alter-table-repos-add-throttle",
+		stmt: alterTableReposAddThrottle,
+	},
+	{
+		name: "alter-table-repos-add-priority",
+		stmt: alterTableReposAddPriority,
+	},
+	{
+		name: "alter-table-tasks-add-priority",
+		stmt: alterTableTasksAddPriority,
+	},
+	{
+		name: "alter-table-tasks-add-running",
+		stmt: alterTableTasksAddRunning,
+	},
+	{
+		name: "create-table-audit",
+		stmt: createTableAudit,
+	},
+	{
+		name: "create-index-audit-created",
+		stmt: createIndexAuditCreated,
+	},
+	{
+		name: "create-table-tokens",
+		stmt: createTableTokens,
+	},
+	{
+		name: "alter-table-users-add-machine",
+		stmt: alterTableUsersAddMachine,
+	},
+	{
+		name: "create-table-perms",
+		stmt: createTablePerms,
+	},
+	{
+		name: "create-table-deliveries",
+		stmt: createTableDeliveries,
+	},
+	{
+		name: "alter-table-repos-add-downstream",
+		stmt: alterTableReposAddDownstream,
+	},
+	{
+		name: "alter-table-builds-add-upstream",
+		stmt: alterTableBuildsAddUpstream,
+	},
+	{
+		name: "alter-table-builds-add-params",
+		stmt: alterTableBuildsAddParams,
+	},
+	{
+		name: "alter-table-repos-add-deploy-rules",
+		stmt: alterTableReposAddDeployRules,
+	},
+	{
+		name: "alter-table-repos-add-allow-comments",
+		stmt: alterTableReposAddAllowComments,
+	},
+	{
+		name: "alter-table-repos-add-require-signed",
+		stmt: alterTableReposAddRequireSigned,
+	},
+	{
+		name: "alter-table-users-add-group-admin",
+		stmt: alterTableUsersAddGroupAdmin,
+	},
+	{
+		name: "alter-table-users-add-subject",
+		stmt: alterTableUsersAddSubject,
+	},
+	{
+		name: "alter-table-repos-add-rate-limit",
+		stmt: alterTableReposAddRateLimit,
+	},
+	{
+		name: "alter-table-users-add-email-notify",
+		stmt: alterTableUsersAddEmailNotify,
+	},
+	{
+		name: "alter-table-repos-add-email-notify",
+		stmt: alterTableReposAddEmailNotify,
+	},
+	{
+		name: "alter-table-repos-add-email-recipients",
+		stmt: alterTableReposAddEmailRecipients,
+	},
+	{
+		name: "create-index-builds-repo-created",
+		stmt: createIndexBuildsRepoCreated,
+	},
+	{
+		name: "create-table-log-chunks",
+		stmt: createTableLogChunks,
+	},
+	{
+		name: "create-index-builds-repo-number",
+		stmt: createIndexBuildsRepoNumber,
+	},
+	{
+		name: "create-index-builds-repo-branch",
+		stmt: createIndexBuildsRepoBranch,
+	},
+	{
+		name: "create-index-builds-repo-event",
+		stmt: createIndexBuildsRepoEvent,
+	},
+	{
+		name: "create-index-builds-repo-status",
+		stmt: createIndexBuildsRepoStatus,
+	},
+	{
+		name: "create-index-builds-repo-id",
+		stmt: createIndexBuildsRepoId,
+	},
+	{
+		name: "create-index-builds-status-id",
+		stmt: createIndexBuildsStatusId,
+	},
+	{
+		name: "alter-table-secrets-add-branches",
+		stmt: alterTableSecretsAddBranches,
+	},
+	{
+		name: "alter-table-org-secrets-add-branches",
+		stmt: alterTableOrgSecretsAddBranches,
+	},
+	{
+		name: "create-table-caches",
+		stmt: createTableCaches,
+	},
+	{
+		name: "alter-table-repos-add-skip-patterns",
+		stmt: alterTableReposAddSkipPatterns,
+	},
+	{
+		name: "create-table-templates",
+		stmt: createTableTemplates,
+	},
+	{
+		name: "alter-table-builds-add-labels",
+		stmt: alterTableBuildsAddLabels,
+	},
+	{
+		name: "alter-table-procs-add-labels",
+		stmt: alterTableProcsAddLabels,
+	},
+	{
+		name: "create-table-holds",
+		stmt: createTableHolds,
+	},
+	{
+		name: "create-index-holds-build",
+		stmt: createIndexHoldsBuild,
+	},
+	{
+		name: "alter-table-repos-add-hook-signed",
+		stmt: alterTableReposAddHookSigned,
+	},
+}
+
+// Migrate performs the database migration. If the migration fails
+// and error is returned.
+func Migrate(db *sql.DB) error {
+	if err := createTable(db); err != nil {
+		return err
+	}
+	completed, err := selectCompleted(db)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	for _, migration := range migrations {
+		if _, ok := completed[migration.name]; ok {
+
+			continue
+		}
+
+		if _, err := db.Exec(migration.stmt); err != nil {
+			return err
+		}
+		if err := insertMigration(db, migration.name); err != nil {
+			return err
+		}
+
+	}
+	return nil
+}
+
+func createTable(db *sql.DB) error {
+	_, err := db.Exec(migrationTableCreate)
+	return err
+}
+
+func insertMigration(db *sql.DB, name string) error {
+	_, err := db.Exec(migrationInsert, name)
+	return err
+}
+
+func selectCompleted(db *sql.DB) (map[string]struct{}, error) {
+	migrations := map[string]struct{}{}
+	rows, err := db.Query(migrationSelect)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		migrations[name] = struct{}{}
+	}
+	return migrations, nil
+}
+
+//
+// migration table ddl and sql
+//
+
+var migrationTableCreate = `
+CREATE TABLE IF NOT EXISTS migrations (
+ name VARCHAR(512)
+,UNIQUE(name)
+)
+`
+
+var migrationInsert = `
+INSERT INTO migrations (name) VALUES ($1)
+`
+
+var migrationSelect = `
+SELECT name FROM migrations
+`
+
+//
+// 001_create_table_users.sql
+//
+
+var createTableUsers = `
+CREATE TABLE IF NOT EXISTS users (
+ user_id     INT8 PRIMARY KEY DEFAULT unique_rowid()
+,user_login  VARCHAR(250)
+,user_token  VARCHAR(500)
+,user_secret VARCHAR(500)
+,user_expiry INTEGER
+,user_email  VARCHAR(500)
+,user_avatar VARCHAR(500)
+,user_active BOOLEAN
+,user_admin  BOOLEAN
+,user_hash   VARCHAR(500)
+
+,UNIQUE(user_login)
+);
+`
+
+//
+// 002_create_table_repos.sql
+//
+
+var createTableRepos = `
+CREATE TABLE IF NOT EXISTS repos (
+ repo_id            INT8 PRIMARY KEY DEFAULT unique_rowid()
+,repo_user_id       INTEGER
+,repo_owner         VARCHAR(250)
+,repo_name          VARCHAR(250)
+,repo_full_name     VARCHAR(250)
+,repo_avatar        VARCHAR(500)
+,repo_link          VARCHAR(1000)
+,repo_clone         VARCHAR(1000)
+,repo_branch        VARCHAR(500)
+,repo_timeout       INTEGER
+,repo_private       BOOLEAN
+,repo_trusted       BOOLEAN
+,repo_allow_pr      BOOLEAN
+,repo_allow_push    BOOLEAN
+,repo_allow_deploys BOOLEAN
+,repo_allow_tags    BOOLEAN
+,repo_hash          VARCHAR(500)
+,repo_scm           VARCHAR(50)
+,repo_config_path   VARCHAR(500)
+,repo_gated         BOOLEAN
+
+,UNIQUE(repo_full_name)
+);
+`
+
+//
+// 003_create_table_builds.sql
+//
+
+var createTableBuilds = `
+CREATE TABLE IF NOT EXISTS builds (
+ build_id        INT8 PRIMARY KEY DEFAULT unique_rowid()
+,build_repo_id   INTEGER
+,build_number    INTEGER
+,build_event     VARCHAR(500)
+,build_status    VARCHAR(500)
+,build_enqueued  INTEGER
+,build_created   INTEGER
+,build_started   INTEGER
+,build_finished  INTEGER
+,build_commit    VARCHAR(500)
+,build_branch    VARCHAR(500)
+,build_ref       VARCHAR(500)
+,build_refspec   VARCHAR(1000)
+,build_remote    VARCHAR(500)
+,build_title     VARCHAR(1000)
+,build_message   VARCHAR(2000)
+,build_timestamp INTEGER
+,build_author    VARCHAR(500)
+,build_avatar    VARCHAR(1000)
+,build_email     VARCHAR(500)
+,build_link      VARCHAR(1000)
+,build_deploy    VARCHAR(500)
+,build_signed    BOOLEAN
+,build_verified  BOOLEAN
+,build_parent    INTEGER
+,build_error     VARCHAR(500)
+,build_reviewer  VARCHAR(250)
+,build_reviewed  INTEGER
+,build_sender    VARCHAR(250)
+,build_config_id INTEGER
+
+,UNIQUE(build_number, build_repo_id)
+);
+`
+
+var createIndexBuildsRepo = `
+CREATE INDEX IF NOT EXISTS ix_build_repo ON builds (build_repo_id);
+`
+
+var createIndexBuildsAuthor = `
+CREATE INDEX IF NOT EXISTS ix_build_author ON builds (build_author);
+`
+
+//
+// 004_create_table_procs.sql
+//
+
+var createTableProcs = `
+CREATE TABLE IF NOT EXISTS procs (
+ proc_id         INT8 PRIMARY KEY DEFAULT unique_rowid()
+,proc_build_id   INTEGER
+,proc_pid        INTEGER
+,proc_ppid       INTEGER
+,proc_pgid       INTEGER
+,proc_name       VARCHAR(250)
+,proc_state      VARCHAR(250)
+,proc_error      VARCHAR(500)
+,proc_exit_code  INTEGER
+,proc_started    INTEGER
+,proc_stopped    INTEGER
+,proc_machine    VARCHAR(250)
+,proc_platform   VARCHAR(250)
+,proc_environ    VARCHAR(2000)
+
+,UNIQUE(proc_build_id, proc_pid)
+);
+`
+
+var createIndexProcsBuild = `
+CREATE INDEX IF NOT EXISTS proc_build_ix ON procs (proc_build_id);
+`
+
+//
+// 005_create_table_logs.sql
+//
+
+var createTableLogs = `
+CREATE TABLE IF NOT EXISTS logs (
+ log_id     INT8 PRIMARY KEY DEFAULT unique_rowid()
+,log_job_id INTEGER
+,log_data   BYTEA
+
+,UNIQUE(log_job_id)
+);
+`
+
+//
+// 006_create_table_files.sql
+//
+
+var createTableFiles = `
+CREATE TABLE IF NOT EXISTS files (
+ file_id       INT8 PRIMARY KEY DEFAULT unique_rowid()
+,file_build_id INTEGER
+,file_proc_id  INTEGER
+,file_name     VARCHAR(250)
+,file_mime     VARCHAR(250)
+,file_size     INTEGER
+,file_time     INTEGER
+,file_data     BYTEA
+
+,UNIQUE(file_proc_id,file_name)
+);
+`
+
+var createIndexFilesBuilds = `
+CREATE INDEX IF NOT EXISTS file_build_ix ON files (file_build_id);
+`
+
+var createIndexFilesProcs = `
+CREATE INDEX IF NOT EXISTS file_proc_ix  ON files (file_proc_id);
+`
+
+//
+// 007_create_table_secets.sql
+//
+
+var createTableSecrets = `
+CREATE TABLE IF NOT EXISTS secrets (
+ secret_id          INT8 PRIMARY KEY DEFAULT unique_rowid()
+,secret_repo_id     INTEGER
+,secret_name        VARCHAR(250)
+,secret_value       BYTEA
+,secret_images      VARCHAR(2000)
+,secret_events      VARCHAR(2000)
+,secret_skip_verify BOOLEAN
+,secret_conceal     BOOLEAN
+
+,UNIQUE(secret_name, secret_repo_id)
+);
+`
+
+var createIndexSecretsRepo = `
+CREATE INDEX IF NOT EXISTS ix_secrets_repo  ON secrets  (secret_repo_id);
+`
+
+//
+// 008_create_table_registry.sql
+//
+
+var createTableRegistry = `
+CREATE TABLE IF NOT EXISTS registry (
+ registry_id        INT8 PRIMARY KEY DEFAULT unique_rowid()
+,registry_repo_id   INTEGER
+,registry_addr      VARCHAR(250)
+,registry_email     VARCHAR(500)
+,registry_username  VARCHAR(2000)
+,registry_password  VARCHAR(2000)
+,registry_token     VARCHAR(2000)
+
+,UNIQUE(registry_addr, registry_repo_id)
+);
+`
+
+var createIndexRegistryRepo = `
+CREATE INDEX IF NOT EXISTS ix_registry_repo ON registry (registry_repo_id);
+`
+
+//
+// 009_create_table_config.sql
+//
+
+var createTableConfig = `
+CREATE TABLE IF NOT EXISTS config (
+ config_id       INT8 PRIMARY KEY DEFAULT unique_rowid()
+,config_repo_id  INTEGER
+,config_hash     VARCHAR(250)
+,config_data     BYTEA
+
+,UNIQUE(config_hash, config_repo_id)
+);
+`
+
+//
+// 010_create_table_tasks.sql
+//
+
+var createTableTasks = `
+CREATE TABLE IF NOT EXISTS tasks (
+ task_id     VARCHAR(250) PRIMARY KEY
+,task_data   BYTEA
+,task_labels BYTEA
+);
+`
+
+//
+// 011_create_table_agents.sql
+//
+
+var createTableAgents = `
+CREATE TABLE IF NOT EXISTS agents (
+ agent_id       INT8 PRIMARY KEY DEFAULT unique_rowid()
+,agent_addr     VARCHAR(250)
+,agent_platform VARCHAR(500)
+,agent_capacity INTEGER
+,agent_created  INTEGER
+,agent_updated  INTEGER
+
+,UNIQUE(agent_addr)
+);
+`
+
+//
+// 012_create_table_senders.sql
+//
+
+var createTableSenders = `
+CREATE TABLE IF NOT EXISTS senders (
+ sender_id      INT8 PRIMARY KEY DEFAULT unique_rowid()
+,sender_repo_id INTEGER
+,sender_login   VARCHAR(250)
+,sender_allow   BOOLEAN
+,sender_block   BOOLEAN
+
+,UNIQUE(sender_repo_id,sender_login)
+);
+`
+
+var createIndexSenderRepos = `
+CREATE INDEX IF NOT EXISTS sender_repo_ix ON senders (sender_repo_id);
+`
+
+//
+// 013_create_table_crons.sql
+//
+
+var createTableCrons = `
+CREATE TABLE IF NOT EXISTS crons (
+ cron_id       INT8 PRIMARY KEY DEFAULT unique_rowid()
+,cron_repo_id  INTEGER
+,cron_name     VARCHAR(250)
+,cron_schedule VARCHAR(250)
+,cron_branch   VARCHAR(250)
+,cron_next     INTEGER
+,cron_prev     INTEGER
+,cron_created  INTEGER
+
+,UNIQUE(cron_repo_id,cron_name)
+);
+`
+
+var createIndexCronNext = `
+CREATE INDEX IF NOT EXISTS cron_next_ix ON crons (cron_next);
+`
+
+//
+// 014_create_table_org_secrets.sql
+//
+
+var createTableOrgSecrets = `
+CREATE TABLE IF NOT EXISTS org_secrets (
+ org_secret_id     INT8 PRIMARY KEY DEFAULT unique_rowid()
+,org_secret_owner  VARCHAR(250)
+,org_secret_name   VARCHAR(250)
+,org_secret_value  BYTEA
+,org_secret_images VARCHAR(2000)
+,org_secret_events VARCHAR(2000)
+
+,UNIQUE(org_secret_owner, org_secret_name)
+);
+`
+
+//
+// 015_create_table_hooks.sql
+//
+
+var createTableHooks = `
+CREATE TABLE IF NOT EXISTS hooks (
+ hook_id      INT8 PRIMARY KEY DEFAULT unique_rowid()
+,hook_repo    VARCHAR(250)
+,hook_query   VARCHAR(2000)
+,hook_headers BYTEA
+,hook_payload BYTEA
+,hook_status  VARCHAR(50)
+,hook_code    INTEGER
+,hook_error   VARCHAR(500)
+,hook_created INTEGER
+,hook_updated INTEGER
+);
+`
+
+var createIndexHooksStatus = `
+CREATE INDEX IF NOT EXISTS ix_hooks_status ON hooks (hook_status);
+`
+
+//
+// 016_alter_table_repos_add_auto_cancel.sql
+//
+
+var alterTableReposAddCancelPulls = `
+ALTER TABLE repos ADD COLUMN repo_cancel_pulls BOOLEAN NOT NULL DEFAULT FALSE;
+`
+
+var alterTableReposAddCancelPush = `
+ALTER TABLE repos ADD COLUMN repo_cancel_push BOOLEAN NOT NULL DEFAULT FALSE;
+`
+
+//
+// 017_alter_table_repos_add_throttle.sql
+//
+
+var alterTableReposAddThrottle = `
+ALTER TABLE repos ADD COLUMN repo_throttle INTEGER NOT NULL DEFAULT 0;
+`
+
+//
+// 018_alter_table_add_priority.sql
+//
+
+var alterTableReposAddPriority = `
+ALTER TABLE repos ADD COLUMN repo_priority INTEGER NOT NULL DEFAULT 0;
+`
+
+var alterTableTasksAddPriority = `
+ALTER TABLE tasks ADD COLUMN task_priority INTEGER NOT NULL DEFAULT 0;
+`
+
+//
+// 019_alter_table_tasks_add_running.sql
+//
+
+var alterTableTasksAddRunning = `
+ALTER TABLE tasks ADD COLUMN task_running BOOLEAN NOT NULL DEFAULT FALSE;
+`
+
+//
+// 020_create_table_audit.sql
+//
+
+var createTableAudit = `
+CREATE TABLE IF NOT EXISTS audit (
+ audit_id      INT8 PRIMARY KEY DEFAULT unique_rowid()
+,audit_action  VARCHAR(250)
+,audit_user    VARCHAR(250)
+,audit_repo    VARCHAR(250)
+,audit_target  VARCHAR(250)
+,audit_created INTEGER
+);
+`
+
+var createIndexAuditCreated = `
+CREATE INDEX IF NOT EXISTS ix_audit_created ON audit (audit_created);
+`
+
+//
+// 021_create_table_tokens.sql
+//
+
+var createTableTokens = `
+CREATE TABLE IF NOT EXISTS tokens (
+ token_id      INT8 PRIMARY KEY DEFAULT unique_rowid()
+,token_user_id INTEGER
+,token_name    VARCHAR(250)
+,token_scopes  VARCHAR(2000)
+,token_hash    VARCHAR(250)
+,token_expires INTEGER
+,token_created INTEGER
+
+,UNIQUE(token_user_id,token_name)
+);
+`
+
+//
+// 022_alter_table_users_add_machine.sql
+//
+
+var alterTableUsersAddMachine = `
+ALTER TABLE users ADD COLUMN user_machine BOOLEAN NOT NULL DEFAULT FALSE;
+`
+
+//
+// 023_create_table_perms.sql
+//
+
+var createTablePerms = `
+CREATE TABLE IF NOT EXISTS perms (
+ perm_id      INT8 PRIMARY KEY DEFAULT unique_rowid()
+,perm_user_id INTEGER
+,perm_repo_id INTEGER
+,perm_pull    BOOLEAN
+,perm_push    BOOLEAN
+,perm_admin   BOOLEAN
+
+,UNIQUE(perm_user_id,perm_repo_id)
+);
+`
+
+//
+// 024_create_table_deliveries.sql
+//
+
+var createTableDeliveries = `
+CREATE TABLE IF NOT EXISTS deliveries (
+ delivery_id       INT8 PRIMARY KEY DEFAULT unique_rowid()
+,delivery_endpoint VARCHAR(500)
+,delivery_event    VARCHAR(250)
+,delivery_payload  BYTEA
+,delivery_code     INTEGER
+,delivery_error    VARCHAR(500)
+,delivery_attempts INTEGER
+,delivery_created  INTEGER
+,delivery_updated  INTEGER
+);
+`
+
+//
+// 025_alter_table_add_downstream.sql
+//
+
+var alterTableReposAddDownstream = `
+ALTER TABLE repos ADD COLUMN repo_downstream VARCHAR(2000) NOT NULL DEFAULT '[]';
+`
+
+var alterTableBuildsAddUpstream = `
+ALTER TABLE builds ADD COLUMN build_upstream VARCHAR(2000) NOT NULL DEFAULT '';
+`
+
+//
+// 026_alter_table_builds_add_params.sql
+//
+
+var alterTableBuildsAddParams = `
+ALTER TABLE builds ADD COLUMN build_params VARCHAR(2000) NOT NULL DEFAULT '{}';
+`
+
+//
+// 027_alter_table_repos_add_deploy_rules.sql
+//
+
+var alterTableReposAddDeployRules = `
+ALTER TABLE repos ADD COLUMN repo_deploy_rules VARCHAR(2000) NOT NULL DEFAULT '[]';
+`
+
+//
+// 028_alter_table_repos_add_allow_comments.sql
+//
+
+var alterTableReposAddAllowComments = `
+ALTER TABLE repos ADD COLUMN repo_allow_comments BOOLEAN NOT NULL DEFAULT FALSE;
+`
+
+//
+// 029_alter_table_repos_add_require_signed.sql
+//
+
+var alterTableReposAddRequireSigned = `
+ALTER TABLE repos ADD COLUMN repo_require_signed BOOLEAN NOT NULL DEFAULT FALSE;
+`
+
+//
+// 030_alter_table_users_add_group_admin.sql
+//
+
+var alterTableUsersAddGroupAdmin = `
+ALTER TABLE users ADD COLUMN user_group_admin BOOLEAN NOT NULL DEFAULT FALSE;
+`
+
+//
+// 031_alter_table_users_add_subject.sql
+//
+
+var alterTableUsersAddSubject = `
+ALTER TABLE users ADD COLUMN user_subject VARCHAR(250) NOT NULL DEFAULT '';
+`
+
+//
+// 032_alter_table_repos_add_rate_limit.sql
+//
+
+var alterTableReposAddRateLimit = `
+ALTER TABLE repos ADD COLUMN repo_rate_limit INTEGER NOT NULL DEFAULT 0;
+`
+
+//
+// 033_alter_table_add_email_notify.sql
+//
+
+var alterTableUsersAddEmailNotify = `
+ALTER TABLE users ADD COLUMN user_email_notify BOOLEAN NOT NULL DEFAULT TRUE;
+`
+
+var alterTableReposAddEmailNotify = `
+ALTER TABLE repos ADD COLUMN repo_email_notify BOOLEAN NOT NULL DEFAULT FALSE;
+`
+
+var alterTableReposAddEmailRecipients = `
+ALTER TABLE repos ADD COLUMN repo_email_recipients VARCHAR(2000) NOT NULL DEFAULT '[]';
+`
+
+//
+// 034_create_index_builds_repo_created.sql
+//
+
+var createIndexBuildsRepoCreated = `
+CREATE INDEX IF NOT EXISTS ix_build_repo_created ON builds (build_repo_id, build_created);
+`
+
+//
+// 035_create_table_log_chunks.sql
+//
+
+var createTableLogChunks = `
+CREATE TABLE IF NOT EXISTS log_chunks (
+ chunk_id      INT8 PRIMARY KEY DEFAULT unique_rowid()
+,chunk_proc_id INTEGER
+,chunk_seq     INTEGER
+,chunk_lines   INTEGER
+,chunk_data    BYTEA
+
+,UNIQUE(chunk_proc_id, chunk_seq)
+);
+`
+
+//
+// 036_create_index_builds_keyset.sql
+//
+
+var createIndexBuildsRepoNumber = `
+CREATE INDEX IF NOT EXISTS ix_build_repo_number ON builds (build_repo_id, build_number);
+`
+
+var createIndexBuildsRepoBranch = `
+CREATE INDEX IF NOT EXISTS ix_build_repo_branch ON builds (build_repo_id, build_branch, build_number);
+`
+
+var createIndexBuildsRepoEvent = `
+CREATE INDEX IF NOT EXISTS ix_build_repo_event ON builds (build_repo_id, build_event, build_number);
+`
+
+var createIndexBuildsRepoStatus = `
+CREATE INDEX IF NOT EXISTS ix_build_repo_status ON builds (build_repo_id, build_status, build_number);
+`
+
+var createIndexBuildsRepoId = `
+CREATE INDEX IF NOT EXISTS ix_build_repo_id ON builds (build_repo_id, build_id);
+`
+
+var createIndexBuildsStatusId = `
+CREATE INDEX IF NOT EXISTS ix_build_status_id ON builds (build_status, build_id);
+`
+
+//
+// 037_alter_table_secrets_add_branches.sql
+//
+
+var alterTableSecretsAddBranches = `
+ALTER TABLE secrets ADD COLUMN secret_branches VARCHAR(2000) NOT NULL DEFAULT '[]';
+`
+
+var alterTableOrgSecretsAddBranches = `
+ALTER TABLE org_secrets ADD COLUMN org_secret_branches VARCHAR(2000) NOT NULL DEFAULT '[]';
+`
+
+//
+// 038_create_table_caches.sql
+//
+
+var createTableCaches = `
+CREATE TABLE IF NOT EXISTS caches (
+ cache_id      INT8 PRIMARY KEY DEFAULT unique_rowid()
+,cache_repo_id INTEGER
+,cache_key     VARCHAR(250)
+,cache_size    INTEGER
+,cache_created INTEGER
+,cache_data    BYTEA
+
+,UNIQUE(cache_repo_id, cache_key)
+);
+`
+
+//
+// 039_alter_table_repos_add_skip_patterns.sql
+//
+
+var alterTableReposAddSkipPatterns = `
+ALTER TABLE repos ADD COLUMN repo_skip_patterns VARCHAR(2000) NOT NULL DEFAULT '[]';
+`
+
+//
+// 040_create_table_templates.sql
+//
+
+var createTableTemplates = `
+CREATE TABLE IF NOT EXISTS templates (
+ template_id        INT8 PRIMARY KEY DEFAULT unique_rowid()
+,template_namespace VARCHAR(250)
+,template_name      VARCHAR(250)
+,template_data      BYTEA
+,template_created   INTEGER
+,template_updated   INTEGER
+
+,UNIQUE(template_namespace, template_name)
+);
+`
+
+//
+// 041_alter_table_builds_add_labels.sql
+//
+
+var alterTableBuildsAddLabels = `
+ALTER TABLE builds ADD COLUMN build_labels VARCHAR(2000) NOT NULL DEFAULT '{}';
+`
+
+//
+// 042_alter_table_procs_add_labels.sql
+//
+
+var alterTableProcsAddLabels = `
+ALTER TABLE procs ADD COLUMN proc_labels VARCHAR(2000) NOT NULL DEFAULT '{}';
+`
+
+//
+// 043_create_table_holds.sql
+//
+
+var createTableHolds = `
+CREATE TABLE IF NOT EXISTS holds (
+ hold_id         INT8 PRIMARY KEY DEFAULT unique_rowid()
+,hold_build_id   INTEGER
+,hold_proc_id    INTEGER
+,hold_depends_on VARCHAR(2000)
+,hold_task       BYTEA
+);
+`
+
+var createIndexHoldsBuild = `
+CREATE INDEX IF NOT EXISTS ix_holds_build ON holds (hold_build_id);
+`
+
+//
+// 044_alter_table_repos_add_hook_signed.sql
+//
+
+var alterTableReposAddHookSigned = `
+ALTER TABLE repos ADD COLUMN repo_hook_signed BOOLEAN NOT NULL DEFAULT FALSE;
+`
@@ -0,0 +1,9 @@
+-- name: create-table-logs
+
+CREATE TABLE IF NOT EXISTS logs (
+ log_id     INT8 PRIMARY KEY DEFAULT unique_rowid()
+,log_job_id INTEGER
+,log_data   BYTEA
+
+,UNIQUE(log_job_id)
+);
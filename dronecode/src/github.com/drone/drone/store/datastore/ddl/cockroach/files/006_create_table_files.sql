@@ -0,0 +1,22 @@
+-- name: create-table-files
+
+CREATE TABLE IF NOT EXISTS files (
+ file_id       INT8 PRIMARY KEY DEFAULT unique_rowid()
+,file_build_id INTEGER
+,file_proc_id  INTEGER
+,file_name     VARCHAR(250)
+,file_mime     VARCHAR(250)
+,file_size     INTEGER
+,file_time     INTEGER
+,file_data     BYTEA
+
+,UNIQUE(file_proc_id,file_name)
+);
+
+-- name: create-index-files-builds
+
+CREATE INDEX IF NOT EXISTS file_build_ix ON files (file_build_id);
+
+-- name: create-index-files-procs
+
+CREATE INDEX IF NOT EXISTS file_proc_ix  ON files (file_proc_id);
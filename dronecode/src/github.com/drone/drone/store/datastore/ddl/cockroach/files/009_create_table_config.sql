@@ -0,0 +1,10 @@
+-- name: create-table-config
+
+CREATE TABLE IF NOT EXISTS config (
+ config_id       INT8 PRIMARY KEY DEFAULT unique_rowid()
+,config_repo_id  INTEGER
+,config_hash     VARCHAR(250)
+,config_data     BYTEA
+
+,UNIQUE(config_hash, config_repo_id)
+);
@@ -0,0 +1,12 @@
+-- name: create-table-agents
+
+CREATE TABLE IF NOT EXISTS agents (
+ agent_id       INT8 PRIMARY KEY DEFAULT unique_rowid()
+,agent_addr     VARCHAR(250)
+,agent_platform VARCHAR(500)
+,agent_capacity INTEGER
+,agent_created  INTEGER
+,agent_updated  INTEGER
+
+,UNIQUE(agent_addr)
+);
@@ -0,0 +1,12 @@
+-- name: create-table-org-secrets
+
+CREATE TABLE IF NOT EXISTS org_secrets (
+ org_secret_id     INT8 PRIMARY KEY DEFAULT unique_rowid()
+,org_secret_owner  VARCHAR(250)
+,org_secret_name   VARCHAR(250)
+,org_secret_value  BYTEA
+,org_secret_images VARCHAR(2000)
+,org_secret_events VARCHAR(2000)
+
+,UNIQUE(org_secret_owner, org_secret_name)
+);
@@ -0,0 +1,18 @@
+-- name: create-table-hooks
+
+CREATE TABLE IF NOT EXISTS hooks (
+ hook_id      INT8 PRIMARY KEY DEFAULT unique_rowid()
+,hook_repo    VARCHAR(250)
+,hook_query   VARCHAR(2000)
+,hook_headers BYTEA
+,hook_payload BYTEA
+,hook_status  VARCHAR(50)
+,hook_code    INTEGER
+,hook_error   VARCHAR(500)
+,hook_created INTEGER
+,hook_updated INTEGER
+);
+
+-- name: create-index-hooks-status
+
+CREATE INDEX IF NOT EXISTS ix_hooks_status ON hooks (hook_status);
@@ -0,0 +1,7 @@
+-- name: alter-table-repos-add-cancel-pulls
+
+ALTER TABLE repos ADD COLUMN repo_cancel_pulls BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- name: alter-table-repos-add-cancel-push
+
+ALTER TABLE repos ADD COLUMN repo_cancel_push BOOLEAN NOT NULL DEFAULT FALSE;
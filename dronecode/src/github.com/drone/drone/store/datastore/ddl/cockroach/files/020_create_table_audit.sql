@@ -0,0 +1,14 @@
+-- name: create-table-audit
+
+CREATE TABLE IF NOT EXISTS audit (
+ audit_id      INT8 PRIMARY KEY DEFAULT unique_rowid()
+,audit_action  VARCHAR(250)
+,audit_user    VARCHAR(250)
+,audit_repo    VARCHAR(250)
+,audit_target  VARCHAR(250)
+,audit_created INTEGER
+);
+
+-- name: create-index-audit-created
+
+CREATE INDEX IF NOT EXISTS ix_audit_created ON audit (audit_created);
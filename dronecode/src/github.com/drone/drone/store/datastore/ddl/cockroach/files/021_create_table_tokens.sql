@@ -0,0 +1,13 @@
+-- name: create-table-tokens
+
+CREATE TABLE IF NOT EXISTS tokens (
+ token_id      INT8 PRIMARY KEY DEFAULT unique_rowid()
+,token_user_id INTEGER
+,token_name    VARCHAR(250)
+,token_scopes  VARCHAR(2000)
+,token_hash    VARCHAR(250)
+,token_expires INTEGER
+,token_created INTEGER
+
+,UNIQUE(token_user_id,token_name)
+);
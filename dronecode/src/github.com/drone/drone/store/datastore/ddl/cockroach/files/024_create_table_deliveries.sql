@@ -0,0 +1,13 @@
+-- name: create-table-deliveries
+
+CREATE TABLE IF NOT EXISTS deliveries (
+ delivery_id       INT8 PRIMARY KEY DEFAULT unique_rowid()
+,delivery_endpoint VARCHAR(500)
+,delivery_event    VARCHAR(250)
+,delivery_payload  BYTEA
+,delivery_code     INTEGER
+,delivery_error    VARCHAR(500)
+,delivery_attempts INTEGER
+,delivery_created  INTEGER
+,delivery_updated  INTEGER
+);
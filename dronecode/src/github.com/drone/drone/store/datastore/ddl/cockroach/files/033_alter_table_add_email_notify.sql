@@ -0,0 +1,11 @@
+-- name: alter-table-users-add-email-notify
+
+ALTER TABLE users ADD COLUMN user_email_notify BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- name: alter-table-repos-add-email-notify
+
+ALTER TABLE repos ADD COLUMN repo_email_notify BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- name: alter-table-repos-add-email-recipients
+
+ALTER TABLE repos ADD COLUMN repo_email_recipients VARCHAR(2000) NOT NULL DEFAULT '[]';
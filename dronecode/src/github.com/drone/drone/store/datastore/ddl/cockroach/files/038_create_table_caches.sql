@@ -0,0 +1,12 @@
+-- name: create-table-caches
+
+CREATE TABLE IF NOT EXISTS caches (
+ cache_id      INT8 PRIMARY KEY DEFAULT unique_rowid()
+,cache_repo_id INTEGER
+,cache_key     VARCHAR(250)
+,cache_size    INTEGER
+,cache_created INTEGER
+,cache_data    BYTEA
+
+,UNIQUE(cache_repo_id, cache_key)
+);
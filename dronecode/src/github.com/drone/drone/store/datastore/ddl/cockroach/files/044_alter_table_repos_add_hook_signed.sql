@@ -0,0 +1,3 @@
+-- name: alter-table-repos-add-hook-signed
+
+ALTER TABLE repos ADD COLUMN repo_hook_signed BOOLEAN NOT NULL DEFAULT FALSE;
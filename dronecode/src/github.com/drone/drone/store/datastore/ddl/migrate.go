@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"errors"
 
+	"github.com/drone/drone/store/datastore/ddl/cockroach"
 	"github.com/drone/drone/store/datastore/ddl/mysql"
 	"github.com/drone/drone/store/datastore/ddl/postgres"
 	"github.com/drone/drone/store/datastore/ddl/sqlite"
@@ -14,6 +15,10 @@ const (
 	DriverSqlite   = "sqlite3"
 	DriverMysql    = "mysql"
 	DriverPostgres = "postgres"
+
+	// DriverCockroach uses the postgres driver and sql dialect, with
+	// migrations that do not create sequences for the primary keys.
+	DriverCockroach = "cockroach"
 )
 
 // Migrate performs the database migration. If the migration fails
@@ -27,6 +32,8 @@ func Migrate(driver string, db *sql.DB) error {
 		return mysql.Migrate(db)
 	case DriverPostgres:
 		return postgres.Migrate(db)
+	case DriverCockroach:
+		return cockroach.Migrate(db)
 	default:
 		return sqlite.Migrate(db)
 	}
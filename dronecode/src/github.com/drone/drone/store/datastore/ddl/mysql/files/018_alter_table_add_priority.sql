@@ -0,0 +1,7 @@
+-- name: alter-table-repos-add-priority
+
+ALTER TABLE repos ADD COLUMN repo_priority INTEGER NOT NULL DEFAULT 0;
+
+-- name: alter-table-tasks-add-priority
+
+ALTER TABLE tasks ADD COLUMN task_priority INTEGER NOT NULL DEFAULT 0;
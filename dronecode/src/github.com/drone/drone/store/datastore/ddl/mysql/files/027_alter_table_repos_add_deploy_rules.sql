@@ -0,0 +1,3 @@
+-- name: alter-table-repos-add-deploy-rules
+
+ALTER TABLE repos ADD COLUMN repo_deploy_rules VARCHAR(2000) NOT NULL DEFAULT '[]';
@@ -0,0 +1,3 @@
+-- name: alter-table-users-add-subject
+
+ALTER TABLE users ADD COLUMN user_subject VARCHAR(250) NOT NULL DEFAULT '';
@@ -0,0 +1,23 @@
+-- name: create-index-builds-repo-number
+
+CREATE INDEX ix_build_repo_number ON builds (build_repo_id, build_number);
+
+-- name: create-index-builds-repo-branch
+
+CREATE INDEX ix_build_repo_branch ON builds (build_repo_id, build_branch, build_number);
+
+-- name: create-index-builds-repo-event
+
+CREATE INDEX ix_build_repo_event ON builds (build_repo_id, build_event, build_number);
+
+-- name: create-index-builds-repo-status
+
+CREATE INDEX ix_build_repo_status ON builds (build_repo_id, build_status, build_number);
+
+-- name: create-index-builds-repo-id
+
+CREATE INDEX ix_build_repo_id ON builds (build_repo_id, build_id);
+
+-- name: create-index-builds-status-id
+
+CREATE INDEX ix_build_status_id ON builds (build_status, build_id);
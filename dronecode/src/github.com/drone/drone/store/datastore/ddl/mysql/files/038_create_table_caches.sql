@@ -0,0 +1,12 @@
+-- name: create-table-caches
+
+CREATE TABLE IF NOT EXISTS caches (
+ cache_id      INTEGER PRIMARY KEY AUTO_INCREMENT
+,cache_repo_id INTEGER
+,cache_key     VARCHAR(250)
+,cache_size    INTEGER
+,cache_created INTEGER
+,cache_data    LONGBLOB
+
+,UNIQUE(cache_repo_id, cache_key)
+);
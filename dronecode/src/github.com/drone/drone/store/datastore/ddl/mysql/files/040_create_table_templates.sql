@@ -0,0 +1,12 @@
+-- name: create-table-templates
+
+CREATE TABLE IF NOT EXISTS templates (
+ template_id        INTEGER PRIMARY KEY AUTO_INCREMENT
+,template_namespace VARCHAR(250)
+,template_name      VARCHAR(250)
+,template_data      MEDIUMBLOB
+,template_created   INTEGER
+,template_updated   INTEGER
+
+,UNIQUE(template_namespace, template_name)
+);
@@ -0,0 +1,3 @@
+-- name: alter-table-builds-add-labels
+
+ALTER TABLE builds ADD COLUMN build_labels VARCHAR(2000) NOT NULL DEFAULT '{}';
@@ -0,0 +1,3 @@
+-- name: alter-table-procs-add-labels
+
+ALTER TABLE procs ADD COLUMN proc_labels VARCHAR(2000) NOT NULL DEFAULT '{}';
@@ -0,0 +1,13 @@
+-- name: create-table-holds
+
+CREATE TABLE IF NOT EXISTS holds (
+ hold_id         INTEGER PRIMARY KEY AUTO_INCREMENT
+,hold_build_id   INTEGER
+,hold_proc_id    INTEGER
+,hold_depends_on VARCHAR(2000)
+,hold_task       MEDIUMBLOB
+);
+
+-- name: create-index-holds-build
+
+CREATE INDEX ix_holds_build ON holds (hold_build_id);
@@ -88,6 +88,194 @@ var migrations = []struct {
 		name: "create-index-sender-repos",
 		stmt: createIndexSenderRepos,
 	},
+	{
+		name: "create-table-crons",
+		stmt: createTableCrons,
+	},
+	{
+		name: "create-index-cron-next",
+		stmt: createIndexCronNext,
+	},
+	{
+		name: "create-table-org-secrets",
+		stmt: createTableOrgSecrets,
+	},
+	{
+		name: "create-table-hooks",
+		stmt: createTableHooks,
+	},
+	{
+		name: "create-index-hooks-status",
+		stmt: createIndexHooksStatus,
+	},
+	{
+		name: "alter-table-repos-add-cancel-pulls",
+		stmt: alterTableReposAddCancelPulls,
+	},
+	{
+		name: "alter-table-repos-add-cancel-push",
+		stmt: alterTableReposAddCancelPush,
+	},
+	{
+		name: "alter-table-repos-add-throttle",
+		stmt: alterTableReposAddThrottle,
+	},
+	{
+		name: "alter-table-repos-add-priority",
+		stmt: alterTableReposAddPriority,
+	},
+	{
+		name: "alter-table-tasks-add-priority",
+		stmt: alterTableTasksAddPriority,
+	},
+	{
+		name: "alter-table-tasks-add-running",
+		stmt: alterTableTasksAddRunning,
+	},
+	{
+		name: "create-table-audit",
+		stmt: createTableAudit,
+	},
+	{
+		name: "create-index-audit-created",
+		stmt: createIndexAuditCreated,
+	},
+	{
+		name: "create-table-tokens",
+		stmt: createTableTokens,
+	},
+	{
+		name: "alter-table-users-add-machine",
+		stmt: alterTableUsersAddMachine,
+	},
+	{
+		name: "create-table-perms",
+		stmt: createTablePerms,
+	},
+	{
+		name: "create-table-deliveries",
+		stmt: createTableDeliveries,
+	},
+	{
+		name: "alter-table-repos-add-downstream",
+		stmt: alterTableReposAddDownstream,
+	},
+	{
+		name: "alter-table-builds-add-upstream",
+		stmt: alterTableBuildsAddUpstream,
+	},
+	{
+		name: "alter-table-builds-add-params",
+		stmt: alterTableBuildsAddParams,
+	},
+	{
+		name: "alter-table-repos-add-deploy-rules",
+		stmt: alterTableReposAddDeployRules,
+	},
+	{
+		name: "alter-table-repos-add-allow-comments",
+		stmt: alterTableReposAddAllowComments,
+	},
+	{
+		name: "alter-table-repos-add-require-signed",
+		stmt: alterTableReposAddRequireSigned,
+	},
+	{
+		name: "alter-table-users-add-group-admin",
+		stmt: alterTableUsersAddGroupAdmin,
+	},
+	{
+		name: "alter-table-users-add-subject",
+		stmt: alterTableUsersAddSubject,
+	},
+	{
+		name: "alter-table-repos-add-rate-limit",
+		stmt: alterTableReposAddRateLimit,
+	},
+	{
+		name: "alter-table-users-add-email-notify",
+		stmt: alterTableUsersAddEmailNotify,
+	},
+	{
+		name: "alter-table-repos-add-email-notify",
+		stmt: alterTableReposAddEmailNotify,
+	},
+	{
+		name: "alter-table-repos-add-email-recipients",
+		stmt: alterTableReposAddEmailRecipients,
+	},
+	{
+		name: "create-index-builds-repo-created",
+		stmt: createIndexBuildsRepoCreated,
+	},
+	{
+		name: "create-table-log-chunks",
+		stmt: createTableLogChunks,
+	},
+	{
+		name: "create-index-builds-repo-number",
+		stmt: createIndexBuildsRepoNumber,
+	},
+	{
+		name: "create-index-builds-repo-branch",
+		stmt: createIndexBuildsRepoBranch,
+	},
+	{
+		name: "create-index-builds-repo-event",
+		stmt: createIndexBuildsRepoEvent,
+	},
+	{
+		name: "create-index-builds-repo-status",
+		stmt: createIndexBuildsRepoStatus,
+	},
+	{
+		name: "create-index-builds-repo-id",
+		stmt: createIndexBuildsRepoId,
+	},
+	{
+		name: "create-index-builds-status-id",
+		stmt: createIndexBuildsStatusId,
+	},
+	{
+		name: "alter-table-secrets-add-branches",
+		stmt: alterTableSecretsAddBranches,
+	},
+	{
+		name: "alter-table-org-secrets-add-branches",
+		stmt: alterTableOrgSecretsAddBranches,
+	},
+	{
+		name: "create-table-caches",
+		stmt: createTableCaches,
+	},
+	{
+		name: "alter-table-repos-add-skip-patterns",
+		stmt: alterTableReposAddSkipPatterns,
+	},
+	{
+		name: "create-table-templates",
+		stmt: createTableTemplates,
+	},
+	{
+		name: "alter-table-builds-add-labels",
+		stmt: alterTableBuildsAddLabels,
+	},
+	{
+		name: "alter-table-procs-add-labels",
+		stmt: alterTableProcsAddLabels,
+	},
+	{
+		name: "create-table-holds",
+		stmt: createTableHolds,
+	},
+	{
+		name: "create-index-holds-build",
+		stmt: createIndexHoldsBuild,
+	},
+	{
+		name: "alter-table-repos-add-hook-signed",
+		stmt: alterTableReposAddHookSigned,
+	},
 }
 
 // Migrate performs the database migration. If the migration fails
@@ -442,3 +630,418 @@ CREATE TABLE IF NOT EXISTS senders (
 var createIndexSenderRepos = `
 CREATE INDEX IF NOT EXISTS sender_repo_ix ON senders (sender_repo_id);
 `
+
+//
+// 013_create_table_crons.sql
+//
+
+var createTableCrons = `
+CREATE TABLE IF NOT EXISTS crons (
+ cron_id       SERIAL PRIMARY KEY
+,cron_repo_id  INTEGER
+,cron_name     VARCHAR(250)
+,cron_schedule VARCHAR(250)
+,cron_branch   VARCHAR(250)
+,cron_next     INTEGER
+,cron_prev     INTEGER
+,cron_created  INTEGER
+
+,UNIQUE(cron_repo_id,cron_name)
+);
+`
+
+var createIndexCronNext = `
+CREATE INDEX IF NOT EXISTS cron_next_ix ON crons (cron_next);
+`
+
+//
+// 014_create_table_org_secrets.sql
+//
+
+var createTableOrgSecrets = `
+CREATE TABLE IF NOT EXISTS org_secrets (
+ org_secret_id     SERIAL PRIMARY KEY
+,org_secret_owner  VARCHAR(250)
+,org_secret_name   VARCHAR(250)
+,org_secret_value  BYTEA
+,org_secret_images VARCHAR(2000)
+,org_secret_events VARCHAR(2000)
+
+,UNIQUE(org_secret_owner, org_secret_name)
+);
+`
+
+//
+// 015_create_table_hooks.sql
+//
+
+var createTableHooks = `
+CREATE TABLE IF NOT EXISTS hooks (
+ hook_id      SERIAL PRIMARY KEY
+,hook_repo    VARCHAR(250)
+,hook_query   VARCHAR(2000)
+,hook_headers BYTEA
+,hook_payload BYTEA
+,hook_status  VARCHAR(50)
+,hook_code    INTEGER
+,hook_error   VARCHAR(500)
+,hook_created INTEGER
+,hook_updated INTEGER
+);
+`
+
+var createIndexHooksStatus = `
+CREATE INDEX IF NOT EXISTS ix_hooks_status ON hooks (hook_status);
+`
+
+//
+// 016_alter_table_repos_add_auto_cancel.sql
+//
+
+var alterTableReposAddCancelPulls = `
+ALTER TABLE repos ADD COLUMN repo_cancel_pulls BOOLEAN NOT NULL DEFAULT FALSE;
+`
+
+var alterTableReposAddCancelPush = `
+ALTER TABLE repos ADD COLUMN repo_cancel_push BOOLEAN NOT NULL DEFAULT FALSE;
+`
+
+//
+// 017_alter_table_repos_add_throttle.sql
+//
+
+var alterTableReposAddThrottle = `
+ALTER TABLE repos ADD COLUMN repo_throttle INTEGER NOT NULL DEFAULT 0;
+`
+
+//
+// 018_alter_table_add_priority.sql
+//
+
+var alterTableReposAddPriority = `
+ALTER TABLE repos ADD COLUMN repo_priority INTEGER NOT NULL DEFAULT 0;
+`
+
+var alterTableTasksAddPriority = `
+ALTER TABLE tasks ADD COLUMN task_priority INTEGER NOT NULL DEFAULT 0;
+`
+
+//
+// 019_alter_table_tasks_add_running.sql
+//
+
+var alterTableTasksAddRunning = `
+ALTER TABLE tasks ADD COLUMN task_running BOOLEAN NOT NULL DEFAULT FALSE;
+`
+
+//
+// 020_create_table_audit.sql
+//
+
+var createTableAudit = `
+CREATE TABLE IF NOT EXISTS audit (
+ audit_id      SERIAL PRIMARY KEY
+,audit_action  VARCHAR(250)
+,audit_user    VARCHAR(250)
+,audit_repo    VARCHAR(250)
+,audit_target  VARCHAR(250)
+,audit_created INTEGER
+);
+`
+
+var createIndexAuditCreated = `
+CREATE INDEX IF NOT EXISTS ix_audit_created ON audit (audit_created);
+`
+
+//
+// 021_create_table_tokens.sql
+//
+
+var createTableTokens = `
+CREATE TABLE IF NOT EXISTS tokens (
+ token_id      SERIAL PRIMARY KEY
+,token_user_id INTEGER
+,token_name    VARCHAR(250)
+,token_scopes  VARCHAR(2000)
+,token_hash    VARCHAR(250)
+,token_expires INTEGER
+,token_created INTEGER
+
+,UNIQUE(token_user_id,token_name)
+);
+`
+
+//
+// 022_alter_table_users_add_machine.sql
+//
+
+var alterTableUsersAddMachine = `
+ALTER TABLE users ADD COLUMN user_machine BOOLEAN NOT NULL DEFAULT FALSE;
+`
+
+//
+// 023_create_table_perms.sql
+//
+
+var createTablePerms = `
+CREATE TABLE IF NOT EXISTS perms (
+ perm_id      SERIAL PRIMARY KEY
+,perm_user_id INTEGER
+,perm_repo_id INTEGER
+,perm_pull    BOOLEAN
+,perm_push    BOOLEAN
+,perm_admin   BOOLEAN
+
+,UNIQUE(perm_user_id,perm_repo_id)
+);
+`
+
+//
+// 024_create_table_deliveries.sql
+//
+
+var createTableDeliveries = `
+CREATE TABLE IF NOT EXISTS deliveries (
+ delivery_id       SERIAL PRIMARY KEY
+,delivery_endpoint VARCHAR(500)
+,delivery_event    VARCHAR(250)
+,delivery_payload  BYTEA
+,delivery_code     INTEGER
+,delivery_error    VARCHAR(500)
+,delivery_attempts INTEGER
+,delivery_created  INTEGER
+,delivery_updated  INTEGER
+);
+`
+
+//
+// 025_alter_table_add_downstream.sql
+//
+
+var alterTableReposAddDownstream = `
+ALTER TABLE repos ADD COLUMN repo_downstream VARCHAR(2000) NOT NULL DEFAULT '[]';
+`
+
+var alterTableBuildsAddUpstream = `
+ALTER TABLE builds ADD COLUMN build_upstream VARCHAR(2000) NOT NULL DEFAULT '';
+`
+
+//
+// 026_alter_table_builds_add_params.sql
+//
+
+var alterTableBuildsAddParams = `
+ALTER TABLE builds ADD COLUMN build_params VARCHAR(2000) NOT NULL DEFAULT '{}';
+`
+
+//
+// 027_alter_table_repos_add_deploy_rules.sql
+//
+
+var alterTableReposAddDeployRules = `
+ALTER TABLE repos ADD COLUMN repo_deploy_rules VARCHAR(2000) NOT NULL DEFAULT '[]';
+`
+
+//
+// 028_alter_table_repos_add_allow_comments.sql
+//
+
+var alterTableReposAddAllowComments = `
+ALTER TABLE repos ADD COLUMN repo_allow_comments BOOLEAN NOT NULL DEFAULT FALSE;
+`
+
+//
+// 029_alter_table_repos_add_require_signed.sql
+//
+
+var alterTableReposAddRequireSigned = `
+ALTER TABLE repos ADD COLUMN repo_require_signed BOOLEAN NOT NULL DEFAULT FALSE;
+`
+
+//
+// 030_alter_table_users_add_group_admin.sql
+//
+
+var alterTableUsersAddGroupAdmin = `
+ALTER TABLE users ADD COLUMN user_group_admin BOOLEAN NOT NULL DEFAULT FALSE;
+`
+
+//
+// 031_alter_table_users_add_subject.sql
+//
+
+var alterTableUsersAddSubject = `
+ALTER TABLE users ADD COLUMN user_subject VARCHAR(250) NOT NULL DEFAULT '';
+`
+
+//
+// 032_alter_table_repos_add_rate_limit.sql
+//
+
+var alterTableReposAddRateLimit = `
+ALTER TABLE repos ADD COLUMN repo_rate_limit INTEGER NOT NULL DEFAULT 0;
+`
+
+//
+// 033_alter_table_add_email_notify.sql
+//
+
+var alterTableUsersAddEmailNotify = `
+ALTER TABLE users ADD COLUMN user_email_notify BOOLEAN NOT NULL DEFAULT TRUE;
+`
+
+var alterTableReposAddEmailNotify = `
+ALTER TABLE repos ADD COLUMN repo_email_notify BOOLEAN NOT NULL DEFAULT FALSE;
+`
+
+var alterTableReposAddEmailRecipients = `
+ALTER TABLE repos ADD COLUMN repo_email_recipients VARCHAR(2000) NOT NULL DEFAULT '[]';
+`
+
+//
+// 034_create_index_builds_repo_created.sql
+//
+
+var createIndexBuildsRepoCreated = `
+CREATE INDEX IF NOT EXISTS ix_build_repo_created ON builds (build_repo_id, build_created);
+`
+
+//
+// 035_create_table_log_chunks.sql
+//
+
+var createTableLogChunks = `
+CREATE TABLE IF NOT EXISTS log_chunks (
+ chunk_id      SERIAL PRIMARY KEY
+,chunk_proc_id INTEGER
+,chunk_seq     INTEGER
+,chunk_lines   INTEGER
+,chunk_data    BYTEA
+
+,UNIQUE(chunk_proc_id, chunk_seq)
+);
+`
+
+//
+// 036_create_index_builds_keyset.sql
+//
+
+var createIndexBuildsRepoNumber = `
+CREATE INDEX IF NOT EXISTS ix_build_repo_number ON builds (build_repo_id, build_number);
+`
+
+var createIndexBuildsRepoBranch = `
+CREATE INDEX IF NOT EXISTS ix_build_repo_branch ON builds (build_repo_id, build_branch, build_number);
+`
+
+var createIndexBuildsRepoEvent = `
+CREATE INDEX IF NOT EXISTS ix_build_repo_event ON builds (build_repo_id, build_event, build_number);
+`
+
+var createIndexBuildsRepoStatus = `
+CREATE INDEX IF NOT EXISTS ix_build_repo_status ON builds (build_repo_id, build_status, build_number);
+`
+
+var createIndexBuildsRepoId = `
+CREATE INDEX IF NOT EXISTS ix_build_repo_id ON builds (build_repo_id, build_id);
+`
+
+var createIndexBuildsStatusId = `
+CREATE INDEX IF NOT EXISTS ix_build_status_id ON builds (build_status, build_id);
+`
+
+//
+// 037_alter_table_secrets_add_branches.sql
+//
+
+var alterTableSecretsAddBranches = `
+ALTER TABLE secrets ADD COLUMN secret_branches VARCHAR(2000) NOT NULL DEFAULT '[]';
+`
+
+var alterTableOrgSecretsAddBranches = `
+ALTER TABLE org_secrets ADD COLUMN org_secret_branches VARCHAR(2000) NOT NULL DEFAULT '[]';
+`
+
+//
+// 038_create_table_caches.sql
+//
+
+var createTableCaches = `
+CREATE TABLE IF NOT EXISTS caches (
+ cache_id      SERIAL PRIMARY KEY
+,cache_repo_id INTEGER
+,cache_key     VARCHAR(250)
+,cache_size    INTEGER
+,cache_created INTEGER
+,cache_data    BYTEA
+
+,UNIQUE(cache_repo_id, cache_key)
+);
+`
+
+//
+// 039_alter_table_repos_add_skip_patterns.sql
+//
+
+var alterTableReposAddSkipPatterns = `
+ALTER TABLE repos ADD COLUMN repo_skip_patterns VARCHAR(2000) NOT NULL DEFAULT '[]';
+`
+
+//
+// 040_create_table_templates.sql
+//
+
+var createTableTemplates = `
+CREATE TABLE IF NOT EXISTS templates (
+ template_id        SERIAL PRIMARY KEY
+,template_namespace VARCHAR(250)
+,template_name      VARCHAR(250)
+,template_data      BYTEA
+,template_created   INTEGER
+,template_updated   INTEGER
+
+,UNIQUE(template_namespace, template_name)
+);
+`
+
+//
+// 041_alter_table_builds_add_labels.sql
+//
+
+var alterTableBuildsAddLabels = `
+ALTER TABLE builds ADD COLUMN build_labels VARCHAR(2000) NOT NULL DEFAULT '{}';
+`
+
+//
+// 042_alter_table_procs_add_labels.sql
+//
+
+var alterTableProcsAddLabels = `
+ALTER TABLE procs ADD COLUMN proc_labels VARCHAR(2000) NOT NULL DEFAULT '{}';
+`
+
+//
+// 043_create_table_holds.sql
+//
+
+var createTableHolds = `
+CREATE TABLE IF NOT EXISTS holds (
+ hold_id         SERIAL PRIMARY KEY
+,hold_build_id   INTEGER
+,hold_proc_id    INTEGER
+,hold_depends_on VARCHAR(2000)
+,hold_task       BYTEA
+);
+`
+
+var createIndexHoldsBuild = `
+CREATE INDEX IF NOT EXISTS ix_holds_build ON holds (hold_build_id);
+`
+
+//
+// 044_alter_table_repos_add_hook_signed.sql
+//
+
+var alterTableReposAddHookSigned = `
+ALTER TABLE repos ADD COLUMN repo_hook_signed BOOLEAN NOT NULL DEFAULT FALSE;
+`
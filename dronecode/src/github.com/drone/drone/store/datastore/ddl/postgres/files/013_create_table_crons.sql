@@ -0,0 +1,18 @@
+-- name: create-table-crons
+
+CREATE TABLE IF NOT EXISTS crons (
+ cron_id       SERIAL PRIMARY KEY
+,cron_repo_id  INTEGER
+,cron_name     VARCHAR(250)
+,cron_schedule VARCHAR(250)
+,cron_branch   VARCHAR(250)
+,cron_next     INTEGER
+,cron_prev     INTEGER
+,cron_created  INTEGER
+
+,UNIQUE(cron_repo_id,cron_name)
+);
+
+-- name: create-index-cron-next
+
+CREATE INDEX IF NOT EXISTS cron_next_ix ON crons (cron_next);
@@ -0,0 +1,3 @@
+-- name: alter-table-repos-add-throttle
+
+ALTER TABLE repos ADD COLUMN repo_throttle INTEGER NOT NULL DEFAULT 0;
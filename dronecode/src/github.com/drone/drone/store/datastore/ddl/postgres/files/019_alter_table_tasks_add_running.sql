@@ -0,0 +1,3 @@
+-- name: alter-table-tasks-add-running
+
+ALTER TABLE tasks ADD COLUMN task_running BOOLEAN NOT NULL DEFAULT FALSE;
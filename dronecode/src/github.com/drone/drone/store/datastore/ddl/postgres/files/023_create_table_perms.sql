@@ -0,0 +1,12 @@
+-- name: create-table-perms
+
+CREATE TABLE IF NOT EXISTS perms (
+ perm_id      SERIAL PRIMARY KEY
+,perm_user_id INTEGER
+,perm_repo_id INTEGER
+,perm_pull    BOOLEAN
+,perm_push    BOOLEAN
+,perm_admin   BOOLEAN
+
+,UNIQUE(perm_user_id,perm_repo_id)
+);
@@ -0,0 +1,3 @@
+-- name: alter-table-builds-add-params
+
+ALTER TABLE builds ADD COLUMN build_params VARCHAR(2000) NOT NULL DEFAULT '{}';
@@ -0,0 +1,3 @@
+-- name: alter-table-users-add-group-admin
+
+ALTER TABLE users ADD COLUMN user_group_admin BOOLEAN NOT NULL DEFAULT FALSE;
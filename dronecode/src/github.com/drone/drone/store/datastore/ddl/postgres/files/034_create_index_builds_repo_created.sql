@@ -0,0 +1,3 @@
+-- name: create-index-builds-repo-created
+
+CREATE INDEX IF NOT EXISTS ix_build_repo_created ON builds (build_repo_id, build_created);
@@ -0,0 +1,11 @@
+-- name: create-table-log-chunks
+
+CREATE TABLE IF NOT EXISTS log_chunks (
+ chunk_id      SERIAL PRIMARY KEY
+,chunk_proc_id INTEGER
+,chunk_seq     INTEGER
+,chunk_lines   INTEGER
+,chunk_data    BYTEA
+
+,UNIQUE(chunk_proc_id, chunk_seq)
+);
@@ -0,0 +1,3 @@
+-- name: alter-table-repos-add-skip-patterns
+
+ALTER TABLE repos ADD COLUMN repo_skip_patterns VARCHAR(2000) NOT NULL DEFAULT '[]';
@@ -0,0 +1,18 @@
+-- name: create-table-crons
+
+CREATE TABLE IF NOT EXISTS crons (
+ cron_id       INTEGER PRIMARY KEY AUTOINCREMENT
+,cron_repo_id  INTEGER
+,cron_name     TEXT
+,cron_schedule TEXT
+,cron_branch   TEXT
+,cron_next     INTEGER
+,cron_prev     INTEGER
+,cron_created  INTEGER
+
+,UNIQUE(cron_repo_id,cron_name)
+);
+
+-- name: create-index-cron-next
+
+CREATE INDEX IF NOT EXISTS cron_next_ix ON crons (cron_next);
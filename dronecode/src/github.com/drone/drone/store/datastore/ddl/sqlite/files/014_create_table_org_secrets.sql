@@ -0,0 +1,12 @@
+-- name: create-table-org-secrets
+
+CREATE TABLE IF NOT EXISTS org_secrets (
+ org_secret_id     INTEGER PRIMARY KEY AUTOINCREMENT
+,org_secret_owner  TEXT
+,org_secret_name   TEXT
+,org_secret_value  TEXT
+,org_secret_images TEXT
+,org_secret_events TEXT
+
+,UNIQUE(org_secret_owner, org_secret_name)
+);
@@ -0,0 +1,18 @@
+-- name: create-table-hooks
+
+CREATE TABLE IF NOT EXISTS hooks (
+ hook_id      INTEGER PRIMARY KEY AUTOINCREMENT
+,hook_repo    TEXT
+,hook_query   TEXT
+,hook_headers TEXT
+,hook_payload TEXT
+,hook_status  TEXT
+,hook_code    INTEGER
+,hook_error   TEXT
+,hook_created INTEGER
+,hook_updated INTEGER
+);
+
+-- name: create-index-hooks-status
+
+CREATE INDEX IF NOT EXISTS ix_hooks_status ON hooks (hook_status);
@@ -0,0 +1,14 @@
+-- name: create-table-audit
+
+CREATE TABLE IF NOT EXISTS audit (
+ audit_id      INTEGER PRIMARY KEY AUTOINCREMENT
+,audit_action  TEXT
+,audit_user    TEXT
+,audit_repo    TEXT
+,audit_target  TEXT
+,audit_created INTEGER
+);
+
+-- name: create-index-audit-created
+
+CREATE INDEX IF NOT EXISTS ix_audit_created ON audit (audit_created);
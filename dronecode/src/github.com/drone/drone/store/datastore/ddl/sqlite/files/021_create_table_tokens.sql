@@ -0,0 +1,13 @@
+-- name: create-table-tokens
+
+CREATE TABLE IF NOT EXISTS tokens (
+ token_id      INTEGER PRIMARY KEY AUTOINCREMENT
+,token_user_id INTEGER
+,token_name    TEXT
+,token_scopes  TEXT
+,token_hash    TEXT
+,token_expires INTEGER
+,token_created INTEGER
+
+,UNIQUE(token_user_id,token_name)
+);
@@ -0,0 +1,3 @@
+-- name: alter-table-users-add-machine
+
+ALTER TABLE users ADD COLUMN user_machine BOOLEAN NOT NULL DEFAULT 0;
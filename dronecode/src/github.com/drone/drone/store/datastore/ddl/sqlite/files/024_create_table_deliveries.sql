@@ -0,0 +1,13 @@
+-- name: create-table-deliveries
+
+CREATE TABLE IF NOT EXISTS deliveries (
+ delivery_id       INTEGER PRIMARY KEY AUTOINCREMENT
+,delivery_endpoint TEXT
+,delivery_event    TEXT
+,delivery_payload  TEXT
+,delivery_code     INTEGER
+,delivery_error    TEXT
+,delivery_attempts INTEGER
+,delivery_created  INTEGER
+,delivery_updated  INTEGER
+);
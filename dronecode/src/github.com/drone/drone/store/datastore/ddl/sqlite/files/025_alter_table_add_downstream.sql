@@ -0,0 +1,7 @@
+-- name: alter-table-repos-add-downstream
+
+ALTER TABLE repos ADD COLUMN repo_downstream VARCHAR(2000) NOT NULL DEFAULT '[]';
+
+-- name: alter-table-builds-add-upstream
+
+ALTER TABLE builds ADD COLUMN build_upstream VARCHAR(2000) NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+-- name: alter-table-repos-add-allow-comments
+
+ALTER TABLE repos ADD COLUMN repo_allow_comments BOOLEAN NOT NULL DEFAULT 0;
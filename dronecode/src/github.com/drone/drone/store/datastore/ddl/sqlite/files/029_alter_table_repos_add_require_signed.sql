@@ -0,0 +1,3 @@
+-- name: alter-table-repos-add-require-signed
+
+ALTER TABLE repos ADD COLUMN repo_require_signed BOOLEAN NOT NULL DEFAULT 0;
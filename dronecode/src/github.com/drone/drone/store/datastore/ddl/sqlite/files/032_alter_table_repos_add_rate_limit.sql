@@ -0,0 +1,3 @@
+-- name: alter-table-repos-add-rate-limit
+
+ALTER TABLE repos ADD COLUMN repo_rate_limit INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,7 @@
+-- name: alter-table-secrets-add-branches
+
+ALTER TABLE secrets ADD COLUMN secret_branches VARCHAR(2000) NOT NULL DEFAULT '[]';
+
+-- name: alter-table-org-secrets-add-branches
+
+ALTER TABLE org_secrets ADD COLUMN org_secret_branches VARCHAR(2000) NOT NULL DEFAULT '[]';
@@ -0,0 +1,12 @@
+-- name: create-table-caches
+
+CREATE TABLE IF NOT EXISTS caches (
+ cache_id      INTEGER PRIMARY KEY AUTOINCREMENT
+,cache_repo_id INTEGER
+,cache_key     VARCHAR(250)
+,cache_size    INTEGER
+,cache_created INTEGER
+,cache_data    BLOB
+
+,UNIQUE(cache_repo_id, cache_key)
+);
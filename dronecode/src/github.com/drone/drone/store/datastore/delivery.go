@@ -0,0 +1,28 @@
+package datastore
+
+import (
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/store/datastore/sql"
+	"github.com/russross/meddler"
+)
+
+func (db *datastore) DeliveryFind(id int64) (*model.Delivery, error) {
+	delivery := new(model.Delivery)
+	err := meddler.Load(db, "deliveries", delivery, id)
+	return delivery, err
+}
+
+func (db *datastore) DeliveryList(limit int) ([]*model.Delivery, error) {
+	stmt := sql.Lookup(db.driver, "delivery-find")
+	data := []*model.Delivery{}
+	err := meddler.QueryAll(db, &data, stmt, limit)
+	return data, err
+}
+
+func (db *datastore) DeliveryCreate(delivery *model.Delivery) error {
+	return meddler.Insert(db, "deliveries", delivery)
+}
+
+func (db *datastore) DeliveryUpdate(delivery *model.Delivery) error {
+	return meddler.Update(db, "deliveries", delivery)
+}
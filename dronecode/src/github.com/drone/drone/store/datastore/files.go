@@ -51,6 +51,19 @@ func (db *datastore) FileCreate(file *model.File, r io.Reader) error {
 	return meddler.Insert(db, "files", &f)
 }
 
+func (db *datastore) FileListBefore(before int64, limit int) ([]*model.File, error) {
+	stmt := sql.Lookup(db.driver, "files-find-before")
+	list := []*model.File{}
+	err := meddler.QueryAll(db, &list, stmt, before, limit)
+	return list, err
+}
+
+func (db *datastore) FileDelete(file *model.File) error {
+	stmt := sql.Lookup(db.driver, "files-delete")
+	_, err := db.Exec(stmt, file.ID)
+	return err
+}
+
 type fileData struct {
 	ID      int64  `meddler:"file_id,pk"`
 	BuildID int64  `meddler:"file_build_id"`
@@ -103,6 +103,59 @@ func TestFileList(t *testing.T) {
 	}
 }
 
+func TestFileListBefore(t *testing.T) {
+	s := newTest()
+	defer func() {
+		s.Exec("delete from files")
+		s.Close()
+	}()
+
+	s.FileCreate(
+		&model.File{
+			BuildID: 1,
+			ProcID:  1,
+			Name:    "hello.txt",
+			Mime:    "text/plain",
+			Size:    11,
+			Time:    100,
+		},
+		bytes.NewBufferString("hello world"),
+	)
+	s.FileCreate(
+		&model.File{
+			BuildID: 1,
+			ProcID:  1,
+			Name:    "hola.txt",
+			Mime:    "text/plain",
+			Size:    10,
+			Time:    200,
+		},
+		bytes.NewBufferString("hola mundo"),
+	)
+
+	files, err := s.FileListBefore(150, 10)
+	if err != nil {
+		t.Errorf("Unexpected error: select files: %s", err)
+		return
+	}
+	if got, want := len(files), 1; got != want {
+		t.Errorf("Wanted %d files, got %d", want, got)
+		return
+	}
+	if got, want := files[0].Name, "hello.txt"; got != want {
+		t.Errorf("Want file name %s, got %s", want, got)
+	}
+
+	if err := s.FileDelete(files[0]); err != nil {
+		t.Errorf("Unexpected error: delete file: %s", err)
+		return
+	}
+	files, _ = s.FileList(&model.Build{ID: 1})
+	if got, want := len(files), 1; got != want {
+		t.Errorf("Wanted %d files after delete, got %d", want, got)
+	}
+}
+
 func TestFileIndexes(t *testing.T) {
 	s := newTest()
 	defer func() {
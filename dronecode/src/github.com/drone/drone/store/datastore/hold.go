@@ -0,0 +1,36 @@
+package datastore
+
+import (
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/store/datastore/sql"
+	"github.com/russross/meddler"
+)
+
+func (db *datastore) HoldList(build *model.Build) ([]*model.Hold, error) {
+	stmt := sql.Lookup(db.driver, "hold-find-build")
+	data := []*model.Hold{}
+	err := meddler.QueryAll(db, &data, stmt, build.ID)
+	return data, err
+}
+
+func (db *datastore) HoldCreate(hold *model.Hold) error {
+	return meddler.Insert(db, "holds", hold)
+}
+
+// HoldDelete deletes the held pipeline, and returns false if the held
+// pipeline was already deleted.
+func (db *datastore) HoldDelete(hold *model.Hold) (bool, error) {
+	stmt := sql.Lookup(db.driver, "hold-delete")
+	res, err := db.Exec(stmt, hold.ID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n != 0, err
+}
+
+func (db *datastore) HoldClear(build *model.Build) error {
+	stmt := sql.Lookup(db.driver, "hold-delete-build")
+	_, err := db.Exec(stmt, build.ID)
+	return err
+}
@@ -0,0 +1,54 @@
+package datastore
+
+import (
+	"testing"
+
+	"github.com/drone/drone/model"
+)
+
+func TestHolds(t *testing.T) {
+	s := newTest()
+	defer func() {
+		s.Exec("delete from holds")
+		s.Close()
+	}()
+
+	build := &model.Build{ID: 1}
+	s.HoldCreate(&model.Hold{BuildID: 1, ProcID: 2, DependsOn: []string{"backend"}, Task: []byte("{}")})
+	s.HoldCreate(&model.Hold{BuildID: 1, ProcID: 3, DependsOn: []string{"backend", "frontend"}})
+	s.HoldCreate(&model.Hold{BuildID: 2, ProcID: 5})
+
+	list, err := s.HoldList(build)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(list), 2; got != want {
+		t.Fatalf("Want %d held pipelines, got %d", want, got)
+	}
+	if got, want := list[0].ProcID, int64(2); got != want {
+		t.Errorf("Want held proc_id %d, got %d", want, got)
+	}
+	if got, want := len(list[1].DependsOn), 2; got != want {
+		t.Errorf("Want %d dependencies, got %d", want, got)
+	}
+	if got, want := string(list[0].Task), "{}"; got != want {
+		t.Errorf("Want task %s, got %s", want, got)
+	}
+
+	if ok, err := s.HoldDelete(list[0]); !ok || err != nil {
+		t.Errorf("Want held pipeline deleted, got %v %v", ok, err)
+	}
+	if ok, _ := s.HoldDelete(list[0]); ok {
+		t.Errorf("Want held pipeline deleted once")
+	}
+
+	if err := s.HoldClear(build); err != nil {
+		t.Fatal(err)
+	}
+	if list, _ := s.HoldList(build); len(list) != 0 {
+		t.Errorf("Want held pipelines of the build removed, got %d", len(list))
+	}
+	if list, _ := s.HoldList(&model.Build{ID: 2}); len(list) != 1 {
+		t.Errorf("Want held pipelines of other builds kept, got %d", len(list))
+	}
+}
@@ -0,0 +1,28 @@
+package datastore
+
+import (
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/store/datastore/sql"
+	"github.com/russross/meddler"
+)
+
+func (db *datastore) HookFind(id int64) (*model.Hook, error) {
+	hook := new(model.Hook)
+	err := meddler.Load(db, "hooks", hook, id)
+	return hook, err
+}
+
+func (db *datastore) HookListFailed(limit int) ([]*model.Hook, error) {
+	stmt := sql.Lookup(db.driver, "hook-find-failed")
+	data := []*model.Hook{}
+	err := meddler.QueryAll(db, &data, stmt, limit)
+	return data, err
+}
+
+func (db *datastore) HookCreate(hook *model.Hook) error {
+	return meddler.Insert(db, "hooks", hook)
+}
+
+func (db *datastore) HookUpdate(hook *model.Hook) error {
+	return meddler.Update(db, "hooks", hook)
+}
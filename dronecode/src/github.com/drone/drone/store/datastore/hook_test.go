@@ -0,0 +1,53 @@
+package datastore
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/drone/drone/model"
+)
+
+func TestHookListFailed(t *testing.T) {
+	s := newTest()
+	defer func() {
+		s.Exec("delete from hooks")
+		s.Close()
+	}()
+
+	hook := &model.Hook{
+		Query:   "access_token=f0e4c2f76c58916ec258f246851bea091d14d4247a2fc3e18694461b1816e13b",
+		Headers: http.Header{"X-Github-Event": []string{"push"}},
+		Payload: `{"ref":"refs/heads/master"}`,
+		Status:  model.StatusSuccess,
+	}
+	if err := s.HookCreate(hook); err != nil {
+		t.Errorf("Unexpected error: insert hook: %s", err)
+		return
+	}
+	s.HookCreate(&model.Hook{Status: model.StatusFailure, Error: "cannot find repo"})
+
+	hooks, err := s.HookListFailed(10)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := len(hooks), 1; got != want {
+		t.Errorf("Want %d failed hooks, got %d", want, got)
+		return
+	}
+	if got, want := hooks[0].Error, "cannot find repo"; got != want {
+		t.Errorf("Want hook error %s, got %s", want, got)
+	}
+
+	found, err := s.HookFind(hook.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := found.Headers.Get("X-Github-Event"), "push"; got != want {
+		t.Errorf("Want hook header %s, got %s", want, got)
+	}
+	if got, want := found.Payload, hook.Payload; got != want {
+		t.Errorf("Want hook payload %s, got %s", want, got)
+	}
+}
@@ -2,6 +2,9 @@ package datastore
 
 import (
 	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"encoding/json"
 	"io"
 	"io/ioutil"
 
@@ -9,21 +12,231 @@ import (
 	"github.com/russross/meddler"
 )
 
+// logs are stored as gzip compressed chunks of at most logChunkLines lines,
+// or of at most logChunkSize uncompressed bytes.
+const (
+	logChunkLines = 1000
+	logChunkSize  = 256 << 10
+)
+
 func (db *datastore) LogFind(proc *model.Proc) (io.ReadCloser, error) {
-	var log = new(logData)
-	var err = meddler.QueryRow(db, log, rebind(logQuery), proc.ID)
-	var buf = bytes.NewBuffer(log.Data)
-	return ioutil.NopCloser(buf), err
+	chunks, err := db.logChunks(proc)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 {
+		data, err := db.logLegacy(proc)
+		return ioutil.NopCloser(bytes.NewReader(data)), err
+	}
+	return &logReader{db: db, chunks: chunks}, nil
+}
+
+func (db *datastore) LogTail(proc *model.Proc, lines int) (io.ReadCloser, error) {
+	if lines <= 0 {
+		return db.LogFind(proc)
+	}
+	chunks, err := db.logChunks(proc)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 {
+		data, err := db.logLegacy(proc)
+		return ioutil.NopCloser(bytes.NewReader(model.TailLogs(data, lines))), err
+	}
+	// logs that are not json lines are returned in full.
+	if chunks[0].Lines == 0 {
+		return &logReader{db: db, chunks: chunks}, nil
+	}
+	i, n := len(chunks), 0
+	for i > 0 && n < lines {
+		i--
+		n += chunks[i].Lines
+	}
+	r := &logReader{db: db, chunks: chunks[i:]}
+	if n > lines {
+		r.skip = n - lines
+	}
+	return r, nil
 }
 
 func (db *datastore) LogSave(proc *model.Proc, r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	chunks, err := splitLog(proc, data)
+	if err != nil {
+		return err
+	}
+	return db.transact(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(rebind(logChunkDeleteStmt), proc.ID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(rebind(logDeleteStmt), proc.ID); err != nil {
+			return err
+		}
+		for _, chunk := range chunks {
+			if err := meddler.Insert(tx, logChunkTable, chunk); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// helper function returns the chunk metadata of the proc logs, without the
+// chunk data.
+func (db *datastore) logChunks(proc *model.Proc) ([]*logChunk, error) {
+	var chunks []*logChunk
+	err := meddler.QueryAll(db, &chunks, rebind(logChunkListQuery), proc.ID)
+	return chunks, err
+}
+
+// helper function returns the uncompressed data of the chunk.
+func (db *datastore) logChunkData(chunk *logChunk) ([]byte, error) {
+	var data []byte
+	if err := db.QueryRow(rebind(logChunkDataQuery), chunk.ID).Scan(&data); err != nil {
+		return nil, err
+	}
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// helper function returns the proc logs saved to the logs table, before
+// logs were stored in chunks.
+func (db *datastore) logLegacy(proc *model.Proc) ([]byte, error) {
 	var log = new(logData)
 	var err = meddler.QueryRow(db, log, rebind(logQuery), proc.ID)
+	return log.Data, err
+}
+
+// helper function splits the logs into compressed chunks. Logs that are a
+// json array of lines are split at line boundaries, and the chunk holds the
+// comma separated lines, which allows fetching the trailing lines without
+// reading the full logs. Other logs are split at byte boundaries.
+func splitLog(proc *model.Proc, data []byte) ([]*logChunk, error) {
+	var chunks []*logChunk
+	add := func(lines int, data []byte) error {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+		chunks = append(chunks, &logChunk{
+			ProcID: proc.ID,
+			Seq:    len(chunks),
+			Lines:  lines,
+			Data:   buf.Bytes(),
+		})
+		return nil
+	}
+
+	var lines []json.RawMessage
+	if err := json.Unmarshal(data, &lines); err != nil || len(lines) == 0 {
+		for len(chunks) == 0 || len(data) != 0 {
+			n := len(data)
+			if n > logChunkSize {
+				n = logChunkSize
+			}
+			if err := add(0, data[:n]); err != nil {
+				return nil, err
+			}
+			data = data[n:]
+		}
+		return chunks, nil
+	}
+
+	var buf bytes.Buffer
+	var n int
+	for i, line := range lines {
+		if n != 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(line)
+		n++
+		if n == logChunkLines || buf.Len() >= logChunkSize || i == len(lines)-1 {
+			if err := add(n, buf.Bytes()); err != nil {
+				return nil, err
+			}
+			buf.Reset()
+			n = 0
+		}
+	}
+	return chunks, nil
+}
+
+// logReader reassembles the logs from the chunks, reading one chunk at a
+// time from the database.
+type logReader struct {
+	db     *datastore
+	chunks []*logChunk
+	skip   int // lines skipped from the first chunk
+	pos    int
+	buf    bytes.Reader
+}
+
+func (r *logReader) Read(p []byte) (int, error) {
+	for r.buf.Len() == 0 {
+		if err := r.next(); err != nil {
+			return 0, err
+		}
+	}
+	return r.buf.Read(p)
+}
+
+func (r *logReader) Close() error {
+	return nil
+}
+
+// helper function loads the next chunk into the buffer. Chunks of json lines
+// are joined into a json array.
+func (r *logReader) next() error {
+	lines := r.chunks[0].Lines != 0
+	if r.pos == len(r.chunks) {
+		r.pos++
+		if lines {
+			r.buf.Reset([]byte("]"))
+			return nil
+		}
+	}
+	if r.pos > len(r.chunks) {
+		return io.EOF
+	}
+	data, err := r.db.logChunkData(r.chunks[r.pos])
 	if err != nil {
-		log = &logData{ProcID: proc.ID}
+		return err
+	}
+	if lines && r.pos == 0 && r.skip != 0 {
+		var list []json.RawMessage
+		if err := json.Unmarshal(append(append([]byte("["), data...), ']'), &list); err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		for i, line := range list[r.skip:] {
+			if i != 0 {
+				buf.WriteByte(',')
+			}
+			buf.Write(line)
+		}
+		data = buf.Bytes()
+	}
+	if lines {
+		sep := []byte(",")
+		if r.pos == 0 {
+			sep = []byte("[")
+		}
+		data = append(sep, data...)
 	}
-	log.Data, _ = ioutil.ReadAll(r)
-	return meddler.Save(db, logTable, log)
+	r.pos++
+	r.buf.Reset(data)
+	return nil
 }
 
 type logData struct {
@@ -32,7 +245,15 @@ type logData struct {
 	Data   []byte `meddler:"log_data"`
 }
 
-const logTable = "logs"
+type logChunk struct {
+	ID     int64  `meddler:"chunk_id,pk"`
+	ProcID int64  `meddler:"chunk_proc_id"`
+	Seq    int    `meddler:"chunk_seq"`
+	Lines  int    `meddler:"chunk_lines"`
+	Data   []byte `meddler:"chunk_data"`
+}
+
+const logChunkTable = "log_chunks"
 
 const logQuery = `
 SELECT *
@@ -40,3 +261,26 @@ FROM logs
 WHERE log_job_id=?
 LIMIT 1
 `
+
+const logChunkListQuery = `
+SELECT chunk_id, chunk_proc_id, chunk_seq, chunk_lines
+FROM log_chunks
+WHERE chunk_proc_id=?
+ORDER BY chunk_seq
+`
+
+const logChunkDataQuery = `
+SELECT chunk_data
+FROM log_chunks
+WHERE chunk_id=?
+`
+
+const logDeleteStmt = `
+DELETE FROM logs
+WHERE log_job_id=?
+`
+
+const logChunkDeleteStmt = `
+DELETE FROM log_chunks
+WHERE chunk_proc_id=?
+`
@@ -2,7 +2,10 @@ package datastore
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"strings"
 	"testing"
 
 	"github.com/drone/drone/model"
@@ -21,6 +24,7 @@ func TestLogs(t *testing.T) {
 		// table data from the database.
 		g.BeforeEach(func() {
 			db.Exec("DELETE FROM logs")
+			db.Exec("DELETE FROM log_chunks")
 		})
 
 		g.It("Should create a log", func() {
@@ -56,5 +60,51 @@ func TestLogs(t *testing.T) {
 			g.Assert(string(out)).Equal("echo allo?")
 		})
 
+		g.It("Should store json lines in chunks", func() {
+			proc := model.Proc{
+				ID: 1,
+			}
+			var lines []string
+			for i := 0; i < logChunkLines*2+500; i++ {
+				lines = append(lines, fmt.Sprintf(`{"pos":%d,"out":"line %d"}`, i, i))
+			}
+			buf := bytes.NewBufferString("[" + strings.Join(lines, ",") + "]")
+			g.Assert(s.LogSave(&proc, buf) == nil).IsTrue()
+
+			var count int
+			db.QueryRow("SELECT COUNT(*) FROM log_chunks WHERE chunk_proc_id = 1").Scan(&count)
+			g.Assert(count).Equal(3)
+
+			rc, err := s.LogFind(&proc)
+			g.Assert(err == nil).IsTrue()
+			out, _ := ioutil.ReadAll(rc)
+			rc.Close()
+			var got []json.RawMessage
+			g.Assert(json.Unmarshal(out, &got) == nil).IsTrue()
+			g.Assert(len(got)).Equal(len(lines))
+
+			rc, err = s.LogTail(&proc, 600)
+			g.Assert(err == nil).IsTrue()
+			out, _ = ioutil.ReadAll(rc)
+			rc.Close()
+			got = nil
+			g.Assert(json.Unmarshal(out, &got) == nil).IsTrue()
+			g.Assert(len(got)).Equal(600)
+			g.Assert(string(got[0])).Equal(lines[len(lines)-600])
+		})
+
+		g.It("Should find a log saved before chunks", func() {
+			proc := model.Proc{
+				ID: 1,
+			}
+			db.Exec(rebind("INSERT INTO logs (log_job_id, log_data) VALUES (?, ?)"), 1, []byte(`[{"pos":0},{"pos":1}]`))
+
+			rc, err := s.LogTail(&proc, 1)
+			g.Assert(err == nil).IsTrue()
+			defer rc.Close()
+			out, _ := ioutil.ReadAll(rc)
+			g.Assert(string(out)).Equal(`[{"pos":1}]`)
+		})
+
 	})
 }
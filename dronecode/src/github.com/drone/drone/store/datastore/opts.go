@@ -0,0 +1,179 @@
+package datastore
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Opts defines the database connection options.
+type Opts struct {
+	Driver string
+	Config string
+
+	// MaxOpen is the maximum number of open connections, and MaxIdle the
+	// maximum number of idle connections. Zero uses the driver default.
+	MaxOpen int
+	MaxIdle int
+
+	// MaxLifetime is the maximum duration a connection is reused, which
+	// should be below the idle timeout of a managed database or proxy.
+	MaxLifetime time.Duration
+
+	// SSLMode is the postgres sslmode (disable, require, verify-ca or
+	// verify-full), which is mapped to the tls option of mysql. SSLCA,
+	// SSLCert and SSLKey are the paths of the certificate authority and
+	// client certificate files.
+	SSLMode string
+	SSLCA   string
+	SSLCert string
+	SSLKey  string
+
+	// StatementTimeout aborts statements that run longer than the
+	// timeout. It is not supported by sqlite.
+	StatementTimeout time.Duration
+}
+
+// mysqlTLSConfig is the name of the registered mysql tls configuration.
+const mysqlTLSConfig = "drone"
+
+// helper function returns the datasource of the driver with the connection
+// options of the tls settings and statement timeout.
+func datasource(opts Opts) (string, error) {
+	params := map[string]string{}
+	switch opts.Driver {
+	case "postgres", "cockroach":
+		for k, v := range map[string]string{
+			"sslmode":     opts.SSLMode,
+			"sslrootcert": opts.SSLCA,
+			"sslcert":     opts.SSLCert,
+			"sslkey":      opts.SSLKey,
+		} {
+			if v != "" {
+				params[k] = v
+			}
+		}
+		if opts.StatementTimeout != 0 {
+			params["statement_timeout"] = fmt.Sprint(int64(opts.StatementTimeout / time.Millisecond))
+		}
+		return withPostgresParams(opts.Config, params)
+	case "mysql":
+		tls, err := mysqlTLS(opts)
+		if err != nil {
+			return "", err
+		}
+		if tls != "" {
+			params["tls"] = tls
+		}
+		if opts.StatementTimeout != 0 {
+			params["max_execution_time"] = fmt.Sprint(int64(opts.StatementTimeout / time.Millisecond))
+		}
+		return withMysqlParams(opts.Config, params), nil
+	}
+	return opts.Config, nil
+}
+
+// helper function returns the mysql tls option of the ssl mode. The tls
+// configuration is registered if a certificate authority or client
+// certificate is provided.
+func mysqlTLS(opts Opts) (string, error) {
+	switch opts.SSLMode {
+	case "":
+		if opts.SSLCA == "" && opts.SSLCert == "" {
+			return "", nil
+		}
+	case "disable":
+		return "false", nil
+	case "require":
+		return "skip-verify", nil
+	case "verify-ca", "verify-full":
+	default:
+		return "", fmt.Errorf("unsupported database ssl mode %s", opts.SSLMode)
+	}
+	if opts.SSLCA == "" && opts.SSLCert == "" {
+		return "true", nil
+	}
+
+	config := &tls.Config{}
+	if opts.SSLCA != "" {
+		pem, err := ioutil.ReadFile(opts.SSLCA)
+		if err != nil {
+			return "", err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return "", fmt.Errorf("cannot parse database certificate authority %s", opts.SSLCA)
+		}
+	}
+	if opts.SSLCert != "" {
+		cert, err := tls.LoadX509KeyPair(opts.SSLCert, opts.SSLKey)
+		if err != nil {
+			return "", err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if err := mysql.RegisterTLSConfig(mysqlTLSConfig, config); err != nil {
+		return "", err
+	}
+	return mysqlTLSConfig, nil
+}
+
+// helper function adds the parameters to the postgres datasource, which is
+// either a url or a list of key=value settings. Parameters already in the
+// datasource take precedence.
+func withPostgresParams(config string, params map[string]string) (string, error) {
+	if strings.HasPrefix(config, "postgres://") || strings.HasPrefix(config, "postgresql://") {
+		u, err := url.Parse(config)
+		if err != nil {
+			return "", err
+		}
+		q := u.Query()
+		for k, v := range params {
+			if q.Get(k) == "" {
+				q.Set(k, v)
+			}
+		}
+		u.RawQuery = q.Encode()
+		return u.String(), nil
+	}
+	for _, k := range sortedKeys(params) {
+		if strings.Contains(config, k+"=") {
+			continue
+		}
+		v := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(params[k])
+		config = strings.TrimSpace(config + fmt.Sprintf(" %s='%s'", k, v))
+	}
+	return config, nil
+}
+
+// helper function adds the parameters to the mysql datasource. Parameters
+// already in the datasource take precedence.
+func withMysqlParams(config string, params map[string]string) string {
+	for _, k := range sortedKeys(params) {
+		if strings.Contains(config, "?"+k+"=") || strings.Contains(config, "&"+k+"=") {
+			continue
+		}
+		sep := "?"
+		if strings.Contains(config, "?") {
+			sep = "&"
+		}
+		config += sep + k + "=" + url.QueryEscape(params[k])
+	}
+	return config
+}
+
+func sortedKeys(params map[string]string) []string {
+	var keys []string
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
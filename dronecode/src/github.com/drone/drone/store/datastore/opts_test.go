@@ -0,0 +1,48 @@
+package datastore
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDatasource(t *testing.T) {
+	tests := []struct {
+		opts Opts
+		want string
+	}{
+		{
+			opts: Opts{Driver: "sqlite3", Config: "drone.sqlite", StatementTimeout: time.Second},
+			want: "drone.sqlite",
+		},
+		{
+			opts: Opts{Driver: "postgres", Config: "postgres://drone@localhost/drone", SSLMode: "verify-full", SSLCA: "/etc/ssl/rds.pem", StatementTimeout: time.Second * 30},
+			want: "postgres://drone@localhost/drone?sslmode=verify-full&sslrootcert=%2Fetc%2Fssl%2Frds.pem&statement_timeout=30000",
+		},
+		{
+			opts: Opts{Driver: "postgres", Config: "postgres://drone@localhost/drone?sslmode=disable", SSLMode: "require"},
+			want: "postgres://drone@localhost/drone?sslmode=disable",
+		},
+		{
+			opts: Opts{Driver: "postgres", Config: "host=localhost dbname=drone", SSLMode: "require", StatementTimeout: time.Second},
+			want: "host=localhost dbname=drone sslmode='require' statement_timeout='1000'",
+		},
+		{
+			opts: Opts{Driver: "mysql", Config: "root@tcp(localhost:3306)/drone?parseTime=true", SSLMode: "require", StatementTimeout: time.Second},
+			want: "root@tcp(localhost:3306)/drone?parseTime=true&max_execution_time=1000&tls=skip-verify",
+		},
+	}
+	for _, test := range tests {
+		got, err := datasource(test.opts)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Want datasource %q, got %q", test.want, got)
+		}
+	}
+
+	if _, err := datasource(Opts{Driver: "mysql", SSLMode: "prefer"}); err == nil {
+		t.Errorf("Want error for unsupported mysql ssl mode")
+	}
+}
@@ -0,0 +1,35 @@
+package datastore
+
+import (
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/store/datastore/sql"
+	"github.com/russross/meddler"
+)
+
+func (db *datastore) OrgSecretFind(owner, name string) (*model.OrgSecret, error) {
+	stmt := sql.Lookup(db.driver, "org-secret-find-owner-name")
+	data := new(model.OrgSecret)
+	err := meddler.QueryRow(db, data, stmt, owner, name)
+	return data, err
+}
+
+func (db *datastore) OrgSecretList(owner string) ([]*model.OrgSecret, error) {
+	stmt := sql.Lookup(db.driver, "org-secret-find-owner")
+	data := []*model.OrgSecret{}
+	err := meddler.QueryAll(db, &data, stmt, owner)
+	return data, err
+}
+
+func (db *datastore) OrgSecretCreate(secret *model.OrgSecret) error {
+	return meddler.Insert(db, "org_secrets", secret)
+}
+
+func (db *datastore) OrgSecretUpdate(secret *model.OrgSecret) error {
+	return meddler.Update(db, "org_secrets", secret)
+}
+
+func (db *datastore) OrgSecretDelete(secret *model.OrgSecret) error {
+	stmt := sql.Lookup(db.driver, "org-secret-delete")
+	_, err := db.Exec(stmt, secret.ID)
+	return err
+}
@@ -0,0 +1,96 @@
+package datastore
+
+import (
+	"testing"
+
+	"github.com/drone/drone/model"
+)
+
+func TestOrgSecretFind(t *testing.T) {
+	s := newTest()
+	defer func() {
+		s.Exec("delete from org_secrets")
+		s.Close()
+	}()
+
+	err := s.OrgSecretCreate(&model.OrgSecret{
+		Owner:  "octocat",
+		Name:   "password",
+		Value:  "correct-horse-battery-staple",
+		Images: []string{"golang"},
+		Events: []string{"push"},
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: insert secret: %s", err)
+		return
+	}
+
+	secret, err := s.OrgSecretFind("octocat", "password")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := secret.Owner, "octocat"; got != want {
+		t.Errorf("Want secret owner %s, got %s", want, got)
+	}
+	if got, want := secret.Value, "correct-horse-battery-staple"; got != want {
+		t.Errorf("Want secret value %s, got %s", want, got)
+	}
+	if got, want := secret.Events[0], "push"; got != want {
+		t.Errorf("Want secret event %s, got %s", want, got)
+	}
+	if got, want := secret.Images[0], "golang"; got != want {
+		t.Errorf("Want secret image %s, got %s", want, got)
+	}
+}
+
+func TestOrgSecretList(t *testing.T) {
+	s := newTest()
+	defer func() {
+		s.Exec("delete from org_secrets")
+		s.Close()
+	}()
+
+	s.OrgSecretCreate(&model.OrgSecret{Owner: "octocat", Name: "foo", Value: "bar"})
+	s.OrgSecretCreate(&model.OrgSecret{Owner: "octocat", Name: "baz", Value: "qux"})
+	s.OrgSecretCreate(&model.OrgSecret{Owner: "", Name: "foo", Value: "bar"})
+
+	list, err := s.OrgSecretList("octocat")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := len(list), 2; got != want {
+		t.Errorf("Want %d organization secrets, got %d", want, got)
+	}
+
+	list, err = s.OrgSecretList("")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := len(list), 1; got != want {
+		t.Errorf("Want %d global secrets, got %d", want, got)
+	}
+}
+
+func TestOrgSecretDelete(t *testing.T) {
+	s := newTest()
+	defer func() {
+		s.Exec("delete from org_secrets")
+		s.Close()
+	}()
+
+	secret := &model.OrgSecret{Owner: "octocat", Name: "foo", Value: "bar"}
+	if err := s.OrgSecretCreate(secret); err != nil {
+		t.Errorf("Unexpected error: insert secret: %s", err)
+		return
+	}
+	if err := s.OrgSecretDelete(secret); err != nil {
+		t.Errorf("Unexpected error: delete secret: %s", err)
+		return
+	}
+	if _, err := s.OrgSecretFind("octocat", "foo"); err == nil {
+		t.Errorf("Expect error: sql.ErrNoRows")
+	}
+}
@@ -0,0 +1,36 @@
+package datastore
+
+import (
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/store/datastore/sql"
+	"github.com/russross/meddler"
+)
+
+func (db *datastore) PermFind(user *model.User, repo *model.Repo) (*model.RepoPerm, error) {
+	stmt := sql.Lookup(db.driver, "perms-find-user-repo")
+	data := new(model.RepoPerm)
+	err := meddler.QueryRow(db, data, stmt, user.ID, repo.ID)
+	return data, err
+}
+
+func (db *datastore) PermList(user *model.User) ([]*model.RepoPerm, error) {
+	stmt := sql.Lookup(db.driver, "perms-find-user")
+	data := []*model.RepoPerm{}
+	err := meddler.QueryAll(db, &data, stmt, user.ID)
+	return data, err
+}
+
+func (db *datastore) PermUpsert(perm *model.RepoPerm) error {
+	stmt := sql.Lookup(db.driver, "perms-find-user-repo")
+	prev := new(model.RepoPerm)
+	if err := meddler.QueryRow(db, prev, stmt, perm.UserID, perm.RepoID); err == nil {
+		perm.ID = prev.ID
+	}
+	return meddler.Save(db, "perms", perm)
+}
+
+func (db *datastore) PermDelete(perm *model.RepoPerm) error {
+	stmt := sql.Lookup(db.driver, "perms-delete")
+	_, err := db.Exec(stmt, perm.ID)
+	return err
+}
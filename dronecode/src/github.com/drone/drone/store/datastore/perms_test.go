@@ -0,0 +1,55 @@
+package datastore
+
+import (
+	"testing"
+
+	"github.com/drone/drone/model"
+)
+
+func TestPermUpsert(t *testing.T) {
+	s := newTest()
+	defer func() {
+		s.Exec("delete from perms")
+		s.Close()
+	}()
+
+	user := &model.User{ID: 1}
+	repo := &model.Repo{ID: 2}
+	if err := s.PermUpsert(&model.RepoPerm{UserID: 1, RepoID: 2, Pull: true}); err != nil {
+		t.Errorf("Unexpected error: insert perm: %s", err)
+		return
+	}
+	if err := s.PermUpsert(&model.RepoPerm{UserID: 1, RepoID: 2, Push: true}); err != nil {
+		t.Errorf("Unexpected error: update perm: %s", err)
+		return
+	}
+
+	perm, err := s.PermFind(user, repo)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := perm.Push, true; got != want {
+		t.Errorf("Want perm push %v, got %v", want, got)
+	}
+	if got, want := perm.Perm().Pull, true; got != want {
+		t.Errorf("Want push permission to grant pull %v, got %v", want, got)
+	}
+
+	perms, err := s.PermList(user)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := len(perms), 1; got != want {
+		t.Errorf("Want %d perms, got %d", want, got)
+	}
+
+	if err := s.PermDelete(perm); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := s.PermFind(user, repo); err == nil {
+		t.Errorf("Want error finding deleted perm")
+	}
+}
@@ -1,6 +1,8 @@
 package datastore
 
 import (
+	gosql "database/sql"
+
 	"github.com/drone/drone/model"
 	"github.com/drone/drone/store/datastore/sql"
 	"github.com/russross/meddler"
@@ -47,6 +49,17 @@ func (db *datastore) ProcUpdate(proc *model.Proc) error {
 	return meddler.Update(db, "procs", proc)
 }
 
+func (db *datastore) ProcUpdateBatch(procs []*model.Proc) error {
+	return db.transact(func(tx *gosql.Tx) error {
+		for _, proc := range procs {
+			if err := meddler.Update(tx, "procs", proc); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 func (db *datastore) ProcClear(build *model.Build) (err error) {
 	stmt1 := sql.Lookup(db.driver, "files-delete-build")
 	stmt2 := sql.Lookup(db.driver, "procs-delete-build")
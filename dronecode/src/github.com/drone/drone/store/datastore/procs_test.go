@@ -26,6 +26,7 @@ func TestProcFind(t *testing.T) {
 			Machine:  "localhost",
 			Platform: "linux/amd64",
 			Environ:  map[string]string{"GOLANG": "tip"},
+			Labels:   map[string]string{"team": "payments"},
 		},
 	})
 	if err != nil {
@@ -56,6 +57,9 @@ func TestProcFind(t *testing.T) {
 	if got, want := proc.Name, "build"; got != want {
 		t.Errorf("Want proc name %s, got %s", want, got)
 	}
+	if got, want := proc.Labels["team"], "payments"; got != want {
+		t.Errorf("Want proc label %s, got %s", want, got)
+	}
 }
 
 func TestProcChild(t *testing.T) {
@@ -184,6 +188,40 @@ func TestProcUpdate(t *testing.T) {
 	}
 }
 
+func TestProcUpdateBatch(t *testing.T) {
+	s := newTest()
+	defer func() {
+		s.Exec("delete from procs")
+		s.Close()
+	}()
+
+	procs := []*model.Proc{
+		{BuildID: 1, PID: 1, Name: "clone", State: "pending"},
+		{BuildID: 1, PID: 2, Name: "build", State: "pending"},
+	}
+	if err := s.ProcCreate(procs); err != nil {
+		t.Errorf("Unexpected error: insert procs: %s", err)
+		return
+	}
+	procs[0].State = "success"
+	procs[1].State = "running"
+	if err := s.ProcUpdateBatch(procs); err != nil {
+		t.Errorf("Unexpected error: update procs: %s", err)
+		return
+	}
+	updated, err := s.ProcList(&model.Build{ID: 1})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := updated[0].State, "success"; got != want {
+		t.Errorf("Want proc state %s, got %s", want, got)
+	}
+	if got, want := updated[1].State, "running"; got != want {
+		t.Errorf("Want proc state %s, got %s", want, got)
+	}
+}
+
 func TestProcIndexes(t *testing.T) {
 	s := newTest()
 	defer func() {
@@ -0,0 +1,196 @@
+package datastore
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/drone/drone/model"
+	"github.com/russross/meddler"
+)
+
+func (db *datastore) LogPrune(before int64) (int64, error) {
+	var pruned int64
+	err := db.transact(func(tx *sql.Tx) error {
+		pruned = 0
+		// the trailing chunks are deleted first, so that the deleted first
+		// chunks count the pruned logs.
+		for _, stmt := range []string{
+			logPruneChunksStmt,
+			logPruneFirstChunksStmt,
+			logPruneStmt,
+		} {
+			res, err := tx.Exec(rebind(stmt), before)
+			if err != nil {
+				return err
+			}
+			if stmt != logPruneChunksStmt {
+				n, _ := res.RowsAffected()
+				pruned += n
+			}
+		}
+		return nil
+	})
+	return pruned, err
+}
+
+func (db *datastore) BuildPrune(keep int) (int64, error) {
+	var res sql.Result
+	err := db.transact(func(tx *sql.Tx) error {
+		for _, stmt := range []string{
+			buildPruneLogsStmt,
+			buildPruneLogChunksStmt,
+			buildPruneFilesStmt,
+			buildPruneProcsStmt,
+		} {
+			if _, err := tx.Exec(rebind(expand(stmt)), keep); err != nil {
+				return err
+			}
+		}
+		var err error
+		res, err = tx.Exec(rebind(expand(buildPruneStmt)), keep)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (db *datastore) LogPruneList(before int64) ([]*model.Proc, error) {
+	procs := []*model.Proc{}
+	err := meddler.QueryAll(db, &procs, rebind(logPruneListQuery), before)
+	return procs, err
+}
+
+func (db *datastore) BuildPruneList(keep int) ([]*model.Proc, []*model.File, error) {
+	procs := []*model.Proc{}
+	if err := meddler.QueryAll(db, &procs, rebind(expand(buildPruneProcListQuery)), keep); err != nil {
+		return nil, nil, err
+	}
+	files := []*model.File{}
+	if err := meddler.QueryAll(db, &files, rebind(expand(buildPruneFileListQuery)), keep); err != nil {
+		return nil, nil, err
+	}
+	return procs, files, nil
+}
+
+func (db *datastore) HookPrune(before int64) (int64, error) {
+	res, err := db.Exec(rebind(hookPruneStmt), before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// helper function expands the expired builds subquery in the statement.
+func expand(stmt string) string {
+	return strings.Replace(stmt, "$expired", expiredBuildsQuery, 1)
+}
+
+const logPruneStmt = `
+DELETE FROM logs
+WHERE log_job_id IN (
+ SELECT proc_id
+ FROM procs
+ WHERE proc_stopped != 0
+   AND proc_stopped < ?
+)
+`
+
+const logPruneChunksStmt = `
+DELETE FROM log_chunks
+WHERE chunk_seq != 0
+  AND chunk_proc_id IN (
+ SELECT proc_id
+ FROM procs
+ WHERE proc_stopped != 0
+   AND proc_stopped < ?
+)
+`
+
+const logPruneFirstChunksStmt = `
+DELETE FROM log_chunks
+WHERE chunk_proc_id IN (
+ SELECT proc_id
+ FROM procs
+ WHERE proc_stopped != 0
+   AND proc_stopped < ?
+)
+`
+
+const logPruneListQuery = `
+SELECT proc_id, proc_build_id
+FROM procs
+WHERE proc_stopped != 0
+  AND proc_stopped < ?
+  AND (
+   proc_id IN (SELECT chunk_proc_id FROM log_chunks WHERE chunk_seq = 0)
+   OR proc_id IN (SELECT log_job_id FROM logs)
+  )
+`
+
+// expiredBuildsQuery selects the completed builds that are not among the
+// most recent builds of the repository. The derived table is required by
+// mysql, which cannot select from the table that is being deleted from.
+const expiredBuildsQuery = `
+SELECT build_id FROM (
+ SELECT b.build_id
+ FROM builds b
+ WHERE b.build_status NOT IN ('pending', 'running', 'blocked')
+   AND b.build_number <= (
+    SELECT MAX(c.build_number)
+    FROM builds c
+    WHERE c.build_repo_id = b.build_repo_id
+   ) - ?
+) expired
+`
+
+const buildPruneProcListQuery = `
+SELECT proc_id, proc_build_id
+FROM procs
+WHERE proc_build_id IN ($expired)
+`
+
+const buildPruneFileListQuery = `
+SELECT file_id, file_build_id, file_proc_id, file_name
+FROM files
+WHERE file_build_id IN ($expired)
+`
+
+const buildPruneLogsStmt = `
+DELETE FROM logs
+WHERE log_job_id IN (
+ SELECT proc_id
+ FROM procs
+ WHERE proc_build_id IN ($expired)
+)
+`
+
+const buildPruneLogChunksStmt = `
+DELETE FROM log_chunks
+WHERE chunk_proc_id IN (
+ SELECT proc_id
+ FROM procs
+ WHERE proc_build_id IN ($expired)
+)
+`
+
+const buildPruneFilesStmt = `
+DELETE FROM files
+WHERE file_build_id IN ($expired)
+`
+
+const buildPruneProcsStmt = `
+DELETE FROM procs
+WHERE proc_build_id IN ($expired)
+`
+
+const buildPruneStmt = `
+DELETE FROM builds
+WHERE build_id IN ($expired)
+`
+
+const hookPruneStmt = `
+DELETE FROM hooks
+WHERE hook_created < ?
+`
@@ -0,0 +1,138 @@
+package datastore
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/drone/drone/model"
+)
+
+func TestBuildPrune(t *testing.T) {
+	s := newTest()
+	defer func() {
+		s.Exec("delete from builds")
+		s.Exec("delete from procs")
+		s.Exec("delete from logs")
+		s.Exec("delete from log_chunks")
+		s.Close()
+	}()
+
+	for i := 0; i < 3; i++ {
+		proc := &model.Proc{PID: 1, State: model.StatusSuccess}
+		build := &model.Build{RepoID: 1, Status: model.StatusSuccess}
+		if err := s.CreateBuild(build, proc); err != nil {
+			t.Errorf("Unexpected error: insert build: %s", err)
+			return
+		}
+		s.LogSave(proc, bytes.NewBufferString("hello world"))
+	}
+	s.CreateBuild(&model.Build{RepoID: 1, Status: model.StatusRunning})
+	s.CreateBuild(&model.Build{RepoID: 2, Status: model.StatusSuccess})
+
+	s.FileCreate(&model.File{BuildID: 1, ProcID: 1, Name: "app.tar"}, bytes.NewBufferString("tar"))
+	defer s.Exec("delete from files")
+
+	listed, files, err := s.BuildPruneList(2)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(listed) != 2 || len(files) != 1 || files[0].Name != "app.tar" {
+		t.Errorf("Want 2 procs and 1 file of the pruned builds, got %d and %d", len(listed), len(files))
+	}
+
+	pruned, err := s.BuildPrune(2)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := pruned, int64(2); got != want {
+		t.Errorf("Want %d pruned builds, got %d", want, got)
+	}
+	if _, err := s.GetBuildNumber(&model.Repo{ID: 1}, 1); err == nil {
+		t.Errorf("Want build 1 pruned")
+	}
+	if _, err := s.GetBuildNumber(&model.Repo{ID: 1}, 3); err != nil {
+		t.Errorf("Want build 3 kept, got error %s", err)
+	}
+	if _, err := s.GetBuildNumber(&model.Repo{ID: 2}, 1); err != nil {
+		t.Errorf("Want build 1 of other repository kept, got error %s", err)
+	}
+
+	var procs, logs int
+	s.QueryRow("select count(*) from procs").Scan(&procs)
+	s.QueryRow("select count(*) from log_chunks").Scan(&logs)
+	if got, want := procs, 1; got != want {
+		t.Errorf("Want %d procs, got %d", want, got)
+	}
+	if got, want := logs, 1; got != want {
+		t.Errorf("Want %d logs, got %d", want, got)
+	}
+}
+
+func TestLogPrune(t *testing.T) {
+	s := newTest()
+	defer func() {
+		s.Exec("delete from procs")
+		s.Exec("delete from logs")
+		s.Exec("delete from log_chunks")
+		s.Close()
+	}()
+
+	procs := []*model.Proc{
+		{BuildID: 1, PID: 1, Stopped: 100},
+		{BuildID: 1, PID: 2, Stopped: 200},
+		{BuildID: 1, PID: 3},
+	}
+	if err := s.ProcCreate(procs); err != nil {
+		t.Errorf("Unexpected error: insert procs: %s", err)
+		return
+	}
+	for _, proc := range procs {
+		s.LogSave(proc, bytes.NewBufferString("hello world"))
+	}
+
+	listed, err := s.LogPruneList(150)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(listed) != 1 || listed[0].ID != procs[0].ID {
+		t.Errorf("Want the proc of the pruned log listed, got %d procs", len(listed))
+	}
+
+	pruned, err := s.LogPrune(150)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := pruned, int64(1); got != want {
+		t.Errorf("Want %d pruned logs, got %d", want, got)
+	}
+	if _, err := s.LogFind(procs[0]); err == nil {
+		t.Errorf("Want log of proc 1 pruned")
+	}
+	if _, err := s.LogFind(procs[1]); err != nil {
+		t.Errorf("Want log of proc 2 kept, got error %s", err)
+	}
+}
+
+func TestHookPrune(t *testing.T) {
+	s := newTest()
+	defer func() {
+		s.Exec("delete from hooks")
+		s.Close()
+	}()
+
+	s.HookCreate(&model.Hook{Created: 100})
+	s.HookCreate(&model.Hook{Created: 200})
+
+	pruned, err := s.HookPrune(150)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := pruned, int64(1); got != want {
+		t.Errorf("Want %d pruned hooks, got %d", want, got)
+	}
+}
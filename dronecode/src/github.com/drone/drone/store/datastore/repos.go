@@ -39,6 +39,12 @@ func (db *datastore) GetRepoListOf(listof []*model.RepoLite) ([]*model.Repo, err
 	return repos, err
 }
 
+func (db *datastore) GetRepoList() ([]*model.Repo, error) {
+	var repos []*model.Repo
+	var err = meddler.QueryAll(db, &repos, repoListQuery)
+	return repos, err
+}
+
 func (db *datastore) GetRepoCount() (count int, err error) {
 	err = db.QueryRow(
 		sql.Lookup(db.driver, "count-repos"),
@@ -75,6 +81,12 @@ WHERE repo_full_name IN (%s)
 ORDER BY repo_name
 `
 
+const repoListQuery = `
+SELECT *
+FROM repos
+ORDER BY repo_id
+`
+
 const repoCountQuery = `
 SELECT COUNT(*) FROM repos
 `
@@ -138,6 +138,29 @@ func TestRepos(t *testing.T) {
 			g.Assert(count).Equal(2)
 		})
 
+		g.It("Should Get All Repos", func() {
+			repo1 := &model.Repo{
+				UserID:   1,
+				Owner:    "octocat",
+				Name:     "hello-world",
+				FullName: "octocat/hello-world",
+			}
+			repo2 := &model.Repo{
+				UserID:   2,
+				Owner:    "drone",
+				Name:     "drone",
+				FullName: "drone/drone",
+			}
+			s.CreateRepo(repo1)
+			s.CreateRepo(repo2)
+
+			repos, err := s.GetRepoList()
+			g.Assert(err == nil).IsTrue()
+			g.Assert(len(repos)).Equal(2)
+			g.Assert(repos[0].ID).Equal(repo1.ID)
+			g.Assert(repos[1].ID).Equal(repo2.ID)
+		})
+
 		g.It("Should Delete a Repo", func() {
 			repo := model.Repo{
 				UserID:   1,
@@ -0,0 +1,82 @@
+package datastore
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// sealedColumns defines the columns that are encrypted at rest.
+var sealedColumns = []struct {
+	table   string
+	pk      string
+	columns []string
+}{
+	{"users", "user_id", []string{"user_token", "user_secret"}},
+	{"secrets", "secret_id", []string{"secret_value"}},
+	{"org_secrets", "org_secret_id", []string{"org_secret_value"}},
+	{"registry", "registry_id", []string{"registry_password", "registry_token"}},
+}
+
+func (db *datastore) Reseal(fn func(table, value string) (string, error), dryRun bool) (int, error) {
+	var count int
+	err := db.transact(func(tx *sql.Tx) error {
+		count = 0
+		for _, table := range sealedColumns {
+			for _, column := range table.columns {
+				n, err := resealColumn(tx, table.table, table.pk, column, fn, dryRun)
+				if err != nil {
+					return fmt.Errorf("datastore: cannot reseal %s. %s", column, err)
+				}
+				count += n
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+// helper function rewrites the non-empty values of the column, and returns
+// the number of rewritten values. The values are read before they are
+// rewritten, since the driver cannot execute statements while the rows of
+// a query are read.
+func resealColumn(tx *sql.Tx, table, pk, column string, fn func(table, value string) (string, error), dryRun bool) (int, error) {
+	rows, err := tx.Query(fmt.Sprintf("SELECT %s, %s FROM %s", pk, column, table))
+	if err != nil {
+		return 0, err
+	}
+	var (
+		ids    []int64
+		values []string
+	)
+	for rows.Next() {
+		var id int64
+		var value sql.NullString
+		if err := rows.Scan(&id, &value); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if value.String != "" {
+			ids = append(ids, id)
+			values = append(values, value.String)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	stmt := rebind(fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = ?", table, column, pk))
+	for i, value := range values {
+		value, err := fn(table, value)
+		if err != nil {
+			return 0, err
+		}
+		if dryRun {
+			continue
+		}
+		if _, err := tx.Exec(stmt, value, ids[i]); err != nil {
+			return 0, err
+		}
+	}
+	return len(values), nil
+}
@@ -0,0 +1,51 @@
+package datastore
+
+import (
+	"testing"
+
+	"github.com/drone/drone/model"
+)
+
+func TestReseal(t *testing.T) {
+	s := newTest()
+	defer func() {
+		s.Exec("delete from users")
+		s.Exec("delete from secrets")
+		s.Exec("delete from registry")
+		s.Close()
+	}()
+
+	user := &model.User{Login: "octocat", Email: "octocat@github.com", Token: "e42080dddf012c718e476da161d21ad5"}
+	s.CreateUser(user)
+	s.SecretCreate(&model.Secret{RepoID: 1, Name: "password", Value: "correct-horse-battery-staple"})
+	s.RegistryCreate(&model.Registry{RepoID: 1, Address: "index.docker.io", Username: "octocat", Password: "hunter2"})
+
+	reseal := func(table, value string) (string, error) {
+		return "sealed:" + value, nil
+	}
+	count, err := s.Reseal(reseal, true)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if count != 3 {
+		t.Errorf("Want 3 values resealed, got %d", count)
+	}
+	if got, _ := s.GetUser(user.ID); got.Token != user.Token {
+		t.Errorf("Want token unchanged in dry run mode, got %q", got.Token)
+	}
+
+	if _, err := s.Reseal(reseal, false); err != nil {
+		t.Error(err)
+		return
+	}
+	if got, _ := s.GetUser(user.ID); got.Token != "sealed:"+user.Token {
+		t.Errorf("Want token resealed, got %q", got.Token)
+	}
+	if got, _ := s.SecretFind(&model.Repo{ID: 1}, "password"); got.Value != "sealed:correct-horse-battery-staple" {
+		t.Errorf("Want secret resealed, got %q", got.Value)
+	}
+	if got, _ := s.RegistryFind(&model.Repo{ID: 1}, "index.docker.io"); got.Password != "sealed:hunter2" {
+		t.Errorf("Want registry password resealed, got %q", got.Password)
+	}
+}
@@ -14,11 +14,12 @@ func TestSecretFind(t *testing.T) {
 	}()
 
 	err := s.SecretCreate(&model.Secret{
-		RepoID: 1,
-		Name:   "password",
-		Value:  "correct-horse-battery-staple",
-		Images: []string{"golang", "node"},
-		Events: []string{"push", "tag"},
+		RepoID:   1,
+		Name:     "password",
+		Value:    "correct-horse-battery-staple",
+		Images:   []string{"golang", "node"},
+		Events:   []string{"push", "tag"},
+		Branches: []string{"master"},
 	})
 	if err != nil {
 		t.Errorf("Unexpected error: insert secret: %s", err)
@@ -51,6 +52,9 @@ func TestSecretFind(t *testing.T) {
 	if got, want := secret.Images[1], "node"; got != want {
 		t.Errorf("Want secret image %s, got %s", want, got)
 	}
+	if got, want := secret.Branches[0], "master"; got != want {
+		t.Errorf("Want secret branch %s, got %s", want, got)
+	}
 }
 
 func TestSecretList(t *testing.T) {
@@ -7,16 +7,17 @@ import (
 
 // Supported database drivers
 const (
-	DriverSqlite   = "sqlite3"
-	DriverMysql    = "mysql"
-	DriverPostgres = "postgres"
+	DriverSqlite    = "sqlite3"
+	DriverMysql     = "mysql"
+	DriverPostgres  = "postgres"
+	DriverCockroach = "cockroach"
 )
 
 // Lookup returns the named sql statement compatible with
 // the specified database driver.
 func Lookup(driver string, name string) string {
 	switch driver {
-	case DriverPostgres:
+	case DriverPostgres, DriverCockroach:
 		return postgres.Lookup(name)
 	default:
 		return sqlite.Lookup(name)
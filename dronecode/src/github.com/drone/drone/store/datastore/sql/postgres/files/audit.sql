@@ -0,0 +1,16 @@
+-- name: audit-find
+
+SELECT
+ audit_id
+,audit_action
+,audit_user
+,audit_repo
+,audit_target
+,audit_created
+FROM audit
+WHERE ($1 = '' OR audit_user = $2)
+  AND ($3 = '' OR audit_repo = $4)
+  AND ($5 = 0 OR audit_created >= $6)
+  AND ($7 = 0 OR audit_created < $8)
+ORDER BY audit_id DESC
+LIMIT $9
@@ -0,0 +1,48 @@
+-- name: cron-find-repo
+
+SELECT
+ cron_id
+,cron_repo_id
+,cron_name
+,cron_schedule
+,cron_branch
+,cron_next
+,cron_prev
+,cron_created
+FROM crons
+WHERE cron_repo_id = $1
+
+-- name: cron-find-repo-name
+
+SELECT
+ cron_id
+,cron_repo_id
+,cron_name
+,cron_schedule
+,cron_branch
+,cron_next
+,cron_prev
+,cron_created
+FROM crons
+WHERE cron_repo_id = $1
+  AND cron_name = $2
+
+-- name: cron-find-due
+
+SELECT
+ cron_id
+,cron_repo_id
+,cron_name
+,cron_schedule
+,cron_branch
+,cron_next
+,cron_prev
+,cron_created
+FROM crons
+WHERE cron_next <= $1
+  AND cron_next > 0
+ORDER BY cron_next ASC
+
+-- name: cron-delete
+
+DELETE FROM crons WHERE cron_id = $1
@@ -0,0 +1,15 @@
+-- name: delivery-find
+
+SELECT
+ delivery_id
+,delivery_endpoint
+,delivery_event
+,delivery_payload
+,delivery_code
+,delivery_error
+,delivery_attempts
+,delivery_created
+,delivery_updated
+FROM deliveries
+ORDER BY delivery_id DESC
+LIMIT $1
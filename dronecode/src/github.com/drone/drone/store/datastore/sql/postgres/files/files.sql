@@ -43,3 +43,21 @@ WHERE file_proc_id = $1
 -- name: files-delete-build
 
 DELETE FROM files WHERE file_build_id = $1
+
+-- name: files-find-before
+
+SELECT
+ file_id
+,file_build_id
+,file_proc_id
+,file_name
+,file_mime
+,file_size
+,file_time
+FROM files
+WHERE file_time < $1
+LIMIT $2
+
+-- name: files-delete
+
+DELETE FROM files WHERE file_id = $1
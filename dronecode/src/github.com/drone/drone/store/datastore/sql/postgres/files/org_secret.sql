@@ -0,0 +1,31 @@
+-- name: org-secret-find-owner
+
+SELECT
+ org_secret_id
+,org_secret_owner
+,org_secret_name
+,org_secret_value
+,org_secret_images
+,org_secret_events
+,org_secret_branches
+FROM org_secrets
+WHERE org_secret_owner = $1
+ORDER BY org_secret_name
+
+-- name: org-secret-find-owner-name
+
+SELECT
+ org_secret_id
+,org_secret_owner
+,org_secret_name
+,org_secret_value
+,org_secret_images
+,org_secret_events
+,org_secret_branches
+FROM org_secrets
+WHERE org_secret_owner = $1
+  AND org_secret_name = $2
+
+-- name: org-secret-delete
+
+DELETE FROM org_secrets WHERE org_secret_id = $1
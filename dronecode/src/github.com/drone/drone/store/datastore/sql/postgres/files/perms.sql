@@ -0,0 +1,28 @@
+-- name: perms-find-user
+
+SELECT
+ perm_id
+,perm_user_id
+,perm_repo_id
+,perm_pull
+,perm_push
+,perm_admin
+FROM perms
+WHERE perm_user_id = $1
+
+-- name: perms-find-user-repo
+
+SELECT
+ perm_id
+,perm_user_id
+,perm_repo_id
+,perm_pull
+,perm_push
+,perm_admin
+FROM perms
+WHERE perm_user_id = $1
+  AND perm_repo_id = $2
+
+-- name: perms-delete
+
+DELETE FROM perms WHERE perm_id = $1
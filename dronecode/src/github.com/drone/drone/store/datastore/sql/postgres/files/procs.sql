@@ -15,6 +15,7 @@ SELECT
 ,proc_machine
 ,proc_platform
 ,proc_environ
+,proc_labels
 FROM procs
 WHERE proc_id = $1
 
@@ -35,6 +36,7 @@ SELECT
 ,proc_machine
 ,proc_platform
 ,proc_environ
+,proc_labels
 FROM procs
 WHERE proc_build_id = $1
 ORDER BY proc_pid ASC
@@ -56,6 +58,7 @@ proc_id
 ,proc_machine
 ,proc_platform
 ,proc_environ
+,proc_labels
 FROM procs
 WHERE proc_build_id = $1
   AND proc_pid      = $2
@@ -77,6 +80,7 @@ proc_id
 ,proc_machine
 ,proc_platform
 ,proc_environ
+,proc_labels
 FROM procs
 WHERE proc_build_id = $1
   AND proc_ppid = $2
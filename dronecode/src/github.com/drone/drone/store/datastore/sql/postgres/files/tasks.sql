@@ -4,8 +4,14 @@ SELECT
  task_id
 ,task_data
 ,task_labels
+,task_priority
+,task_running
 FROM tasks
 
 -- name: task-delete
 
 DELETE FROM tasks WHERE task_id = $1
+
+-- name: task-update-running
+
+UPDATE tasks SET task_running = $1 WHERE task_id = $2
@@ -0,0 +1,29 @@
+-- name: template-find-namespace
+
+SELECT
+ template_id
+,template_namespace
+,template_name
+,template_data
+,template_created
+,template_updated
+FROM templates
+WHERE template_namespace = $1
+ORDER BY template_name
+
+-- name: template-find-namespace-name
+
+SELECT
+ template_id
+,template_namespace
+,template_name
+,template_data
+,template_created
+,template_updated
+FROM templates
+WHERE template_namespace = $1
+  AND template_name = $2
+
+-- name: template-delete
+
+DELETE FROM templates WHERE template_id = $1
@@ -0,0 +1,17 @@
+-- name: token-find-user
+
+SELECT
+ token_id
+,token_user_id
+,token_name
+,token_scopes
+,token_hash
+,token_expires
+,token_created
+FROM tokens
+WHERE token_user_id = $1
+ORDER BY token_name
+
+-- name: token-delete
+
+DELETE FROM tokens WHERE token_id = $1
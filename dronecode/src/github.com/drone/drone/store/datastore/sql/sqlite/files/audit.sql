@@ -0,0 +1,16 @@
+-- name: audit-find
+
+SELECT
+ audit_id
+,audit_action
+,audit_user
+,audit_repo
+,audit_target
+,audit_created
+FROM audit
+WHERE (? = '' OR audit_user = ?)
+  AND (? = '' OR audit_repo = ?)
+  AND (? = 0 OR audit_created >= ?)
+  AND (? = 0 OR audit_created < ?)
+ORDER BY audit_id DESC
+LIMIT ?
@@ -0,0 +1,19 @@
+-- name: hold-find-build
+
+SELECT
+ hold_id
+,hold_build_id
+,hold_proc_id
+,hold_depends_on
+,hold_task
+FROM holds
+WHERE hold_build_id = ?
+ORDER BY hold_id ASC
+
+-- name: hold-delete
+
+DELETE FROM holds WHERE hold_id = ?
+
+-- name: hold-delete-build
+
+DELETE FROM holds WHERE hold_build_id = ?
@@ -0,0 +1,17 @@
+-- name: hook-find-failed
+
+SELECT
+ hook_id
+,hook_repo
+,hook_query
+,hook_headers
+,hook_payload
+,hook_status
+,hook_code
+,hook_error
+,hook_created
+,hook_updated
+FROM hooks
+WHERE hook_status = 'failure'
+ORDER BY hook_id DESC
+LIMIT ?
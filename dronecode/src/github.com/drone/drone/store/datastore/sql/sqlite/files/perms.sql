@@ -0,0 +1,28 @@
+-- name: perms-find-user
+
+SELECT
+ perm_id
+,perm_user_id
+,perm_repo_id
+,perm_pull
+,perm_push
+,perm_admin
+FROM perms
+WHERE perm_user_id = ?
+
+-- name: perms-find-user-repo
+
+SELECT
+ perm_id
+,perm_user_id
+,perm_repo_id
+,perm_pull
+,perm_push
+,perm_admin
+FROM perms
+WHERE perm_user_id = ?
+  AND perm_repo_id = ?
+
+-- name: perms-delete
+
+DELETE FROM perms WHERE perm_id = ?
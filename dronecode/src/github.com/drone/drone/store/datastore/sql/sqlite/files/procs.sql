@@ -15,6 +15,7 @@ SELECT
 ,proc_machine
 ,proc_platform
 ,proc_environ
+,proc_labels
 FROM procs
 WHERE proc_id = ?
 
@@ -35,6 +36,7 @@ SELECT
 ,proc_machine
 ,proc_platform
 ,proc_environ
+,proc_labels
 FROM procs
 WHERE proc_build_id = ?
 
@@ -55,6 +57,7 @@ proc_id
 ,proc_machine
 ,proc_platform
 ,proc_environ
+,proc_labels
 FROM procs
 WHERE proc_build_id = ?
   AND proc_pid = ?
@@ -76,6 +79,7 @@ proc_id
 ,proc_machine
 ,proc_platform
 ,proc_environ
+,proc_labels
 FROM procs
 WHERE proc_build_id = ?
   AND proc_ppid = ?
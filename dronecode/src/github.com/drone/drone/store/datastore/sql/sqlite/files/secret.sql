@@ -7,6 +7,7 @@ SELECT
 ,secret_value
 ,secret_images
 ,secret_events
+,secret_branches
 ,secret_conceal
 ,secret_skip_verify
 FROM secrets
@@ -21,6 +22,7 @@ secret_id
 ,secret_value
 ,secret_images
 ,secret_events
+,secret_branches
 ,secret_conceal
 ,secret_skip_verify
 FROM secrets
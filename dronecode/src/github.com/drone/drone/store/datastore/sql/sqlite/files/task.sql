@@ -4,8 +4,14 @@ SELECT
  task_id
 ,task_data
 ,task_labels
+,task_priority
+,task_running
 FROM tasks
 
 -- name: task-delete
 
 DELETE FROM tasks WHERE task_id = ?
+
+-- name: task-update-running
+
+UPDATE tasks SET task_running = ? WHERE task_id = ?
@@ -6,36 +6,77 @@ func Lookup(name string) string {
 }
 
 var index = map[string]string{
-	"config-find-id":            configFindId,
-	"config-find-repo-hash":     configFindRepoHash,
-	"config-find-approved":      configFindApproved,
-	"count-users":               countUsers,
-	"count-repos":               countRepos,
-	"count-builds":              countBuilds,
-	"files-find-build":          filesFindBuild,
-	"files-find-proc-name":      filesFindProcName,
-	"files-find-proc-name-data": filesFindProcNameData,
-	"files-delete-build":        filesDeleteBuild,
-	"procs-find-id":             procsFindId,
-	"procs-find-build":          procsFindBuild,
-	"procs-find-build-pid":      procsFindBuildPid,
-	"procs-find-build-ppid":     procsFindBuildPpid,
-	"procs-delete-build":        procsDeleteBuild,
-	"registry-find-repo":        registryFindRepo,
-	"registry-find-repo-addr":   registryFindRepoAddr,
-	"registry-delete-repo":      registryDeleteRepo,
-	"registry-delete":           registryDelete,
-	"secret-find-repo":          secretFindRepo,
-	"secret-find-repo-name":     secretFindRepoName,
-	"secret-delete":             secretDelete,
-	"sender-find-repo":          senderFindRepo,
-	"sender-find-repo-login":    senderFindRepoLogin,
-	"sender-delete-repo":        senderDeleteRepo,
-	"sender-delete":             senderDelete,
-	"task-list":                 taskList,
-	"task-delete":               taskDelete,
+	"audit-find":                   auditFind,
+	"config-find-id":               configFindId,
+	"config-find-repo-hash":        configFindRepoHash,
+	"config-find-approved":         configFindApproved,
+	"count-users":                  countUsers,
+	"count-repos":                  countRepos,
+	"count-builds":                 countBuilds,
+	"cron-find-repo":               cronFindRepo,
+	"cron-find-repo-name":          cronFindRepoName,
+	"cron-find-due":                cronFindDue,
+	"cron-delete":                  cronDelete,
+	"delivery-find":                deliveryFind,
+	"files-find-build":             filesFindBuild,
+	"files-find-proc-name":         filesFindProcName,
+	"files-find-proc-name-data":    filesFindProcNameData,
+	"files-delete-build":           filesDeleteBuild,
+	"files-find-before":            filesFindBefore,
+	"files-delete":                 filesDelete,
+	"hold-find-build":              holdFindBuild,
+	"hold-delete":                  holdDelete,
+	"hold-delete-build":            holdDeleteBuild,
+	"hook-find-failed":             hookFindFailed,
+	"org-secret-find-owner":        orgSecretFindOwner,
+	"org-secret-find-owner-name":   orgSecretFindOwnerName,
+	"org-secret-delete":            orgSecretDelete,
+	"perms-find-user":              permsFindUser,
+	"perms-find-user-repo":         permsFindUserRepo,
+	"perms-delete":                 permsDelete,
+	"procs-find-id":                procsFindId,
+	"procs-find-build":             procsFindBuild,
+	"procs-find-build-pid":         procsFindBuildPid,
+	"procs-find-build-ppid":        procsFindBuildPpid,
+	"procs-delete-build":           procsDeleteBuild,
+	"registry-find-repo":           registryFindRepo,
+	"registry-find-repo-addr":      registryFindRepoAddr,
+	"registry-delete-repo":         registryDeleteRepo,
+	"registry-delete":              registryDelete,
+	"secret-find-repo":             secretFindRepo,
+	"secret-find-repo-name":        secretFindRepoName,
+	"secret-delete":                secretDelete,
+	"sender-find-repo":             senderFindRepo,
+	"sender-find-repo-login":       senderFindRepoLogin,
+	"sender-delete-repo":           senderDeleteRepo,
+	"sender-delete":                senderDelete,
+	"task-list":                    taskList,
+	"task-delete":                  taskDelete,
+	"task-update-running":          taskUpdateRunning,
+	"template-find-namespace":      templateFindNamespace,
+	"template-find-namespace-name": templateFindNamespaceName,
+	"template-delete":              templateDelete,
+	"token-find-user":              tokenFindUser,
+	"token-delete":                 tokenDelete,
 }
 
+var auditFind = `
+SELECT
+ audit_id
+,audit_action
+,audit_user
+,audit_repo
+,audit_target
+,audit_created
+FROM audit
+WHERE (? = '' OR audit_user = ?)
+  AND (? = '' OR audit_repo = ?)
+  AND (? = 0 OR audit_created >= ?)
+  AND (? = 0 OR audit_created < ?)
+ORDER BY audit_id DESC
+LIMIT ?
+`
+
 var configFindId = `
 SELECT
  config_id
@@ -80,6 +121,71 @@ SELECT count(1)
 FROM builds
 `
 
+var cronFindRepo = `
+SELECT
+ cron_id
+,cron_repo_id
+,cron_name
+,cron_schedule
+,cron_branch
+,cron_next
+,cron_prev
+,cron_created
+FROM crons
+WHERE cron_repo_id = ?
+`
+
+var cronFindRepoName = `
+SELECT
+ cron_id
+,cron_repo_id
+,cron_name
+,cron_schedule
+,cron_branch
+,cron_next
+,cron_prev
+,cron_created
+FROM crons
+WHERE cron_repo_id = ?
+  AND cron_name = ?
+`
+
+var cronFindDue = `
+SELECT
+ cron_id
+,cron_repo_id
+,cron_name
+,cron_schedule
+,cron_branch
+,cron_next
+,cron_prev
+,cron_created
+FROM crons
+WHERE cron_next <= ?
+  AND cron_next > 0
+ORDER BY cron_next ASC
+`
+
+var cronDelete = `
+DELETE FROM crons WHERE cron_id = ?
+`
+
+var deliveryFind = `
+SELECT
+ delivery_id
+,delivery_endpoint
+,delivery_event
+,delivery_payload
+,delivery_code
+,delivery_error
+,delivery_attempts
+,delivery_created
+,delivery_updated
+FROM deliveries
+ORDER BY delivery_id DESC
+LIMIT ?
+`
+
 var filesFindBuild = `
 SELECT
  file_id
@@ -126,6 +232,123 @@ var filesDeleteBuild = `
 DELETE FROM files WHERE file_build_id = ?
 `
 
+var filesFindBefore = `
+SELECT
+ file_id
+,file_build_id
+,file_proc_id
+,file_name
+,file_mime
+,file_size
+,file_time
+FROM files
+WHERE file_time < ?
+LIMIT ?
+`
+
+var filesDelete = `
+DELETE FROM files WHERE file_id = ?
+`
+
+var holdFindBuild = `
+SELECT
+ hold_id
+,hold_build_id
+,hold_proc_id
+,hold_depends_on
+,hold_task
+FROM holds
+WHERE hold_build_id = ?
+ORDER BY hold_id ASC
+`
+
+var holdDelete = `
+DELETE FROM holds WHERE hold_id = ?
+`
+
+var holdDeleteBuild = `
+DELETE FROM holds WHERE hold_build_id = ?
+`
+
+var hookFindFailed = `
+SELECT
+ hook_id
+,hook_repo
+,hook_query
+,hook_headers
+,hook_payload
+,hook_status
+,hook_code
+,hook_error
+,hook_created
+,hook_updated
+FROM hooks
+WHERE hook_status = 'failure'
+ORDER BY hook_id DESC
+LIMIT ?
+`
+
+var orgSecretFindOwner = `
+SELECT
+ org_secret_id
+,org_secret_owner
+,org_secret_name
+,org_secret_value
+,org_secret_images
+,org_secret_events
+,org_secret_branches
+FROM org_secrets
+WHERE org_secret_owner = ?
+ORDER BY org_secret_name
+`
+
+var orgSecretFindOwnerName = `
+SELECT
+ org_secret_id
+,org_secret_owner
+,org_secret_name
+,org_secret_value
+,org_secret_images
+,org_secret_events
+,org_secret_branches
+FROM org_secrets
+WHERE org_secret_owner = ?
+  AND org_secret_name = ?
+`
+
+var orgSecretDelete = `
+DELETE FROM org_secrets WHERE org_secret_id = ?
+`
+
+var permsFindUser = `
+SELECT
+ perm_id
+,perm_user_id
+,perm_repo_id
+,perm_pull
+,perm_push
+,perm_admin
+FROM perms
+WHERE perm_user_id = ?
+`
+
+var permsFindUserRepo = `
+SELECT
+ perm_id
+,perm_user_id
+,perm_repo_id
+,perm_pull
+,perm_push
+,perm_admin
+FROM perms
+WHERE perm_user_id = ?
+  AND perm_repo_id = ?
+`
+
+var permsDelete = `
+DELETE FROM perms WHERE perm_id = ?
+`
+
 var procsFindId = `
 SELECT
  proc_id
@@ -142,6 +365,7 @@ SELECT
 ,proc_machine
 ,proc_platform
 ,proc_environ
+,proc_labels
 FROM procs
 WHERE proc_id = ?
 `
@@ -162,6 +386,7 @@ SELECT
 ,proc_machine
 ,proc_platform
 ,proc_environ
+,proc_labels
 FROM procs
 WHERE proc_build_id = ?
 `
@@ -182,6 +407,7 @@ proc_id
 ,proc_machine
 ,proc_platform
 ,proc_environ
+,proc_labels
 FROM procs
 WHERE proc_build_id = ?
   AND proc_pid = ?
@@ -203,6 +429,7 @@ proc_id
 ,proc_machine
 ,proc_platform
 ,proc_environ
+,proc_labels
 FROM procs
 WHERE proc_build_id = ?
   AND proc_ppid = ?
@@ -256,6 +483,7 @@ SELECT
 ,secret_value
 ,secret_images
 ,secret_events
+,secret_branches
 ,secret_conceal
 ,secret_skip_verify
 FROM secrets
@@ -270,6 +498,7 @@ secret_id
 ,secret_value
 ,secret_images
 ,secret_events
+,secret_branches
 ,secret_conceal
 ,secret_skip_verify
 FROM secrets
@@ -317,9 +546,63 @@ SELECT
  task_id
 ,task_data
 ,task_labels
+,task_priority
+,task_running
 FROM tasks
 `
 
 var taskDelete = `
 DELETE FROM tasks WHERE task_id = ?
 `
+
+var taskUpdateRunning = `
+UPDATE tasks SET task_running = ? WHERE task_id = ?
+`
+
+var templateFindNamespace = `
+SELECT
+ template_id
+,template_namespace
+,template_name
+,template_data
+,template_created
+,template_updated
+FROM templates
+WHERE template_namespace = ?
+ORDER BY template_name
+`
+
+var templateFindNamespaceName = `
+SELECT
+ template_id
+,template_namespace
+,template_name
+,template_data
+,template_created
+,template_updated
+FROM templates
+WHERE template_namespace = ?
+  AND template_name = ?
+`
+
+var templateDelete = `
+DELETE FROM templates WHERE template_id = ?
+`
+
+var tokenFindUser = `
+SELECT
+ token_id
+,token_user_id
+,token_name
+,token_scopes
+,token_hash
+,token_expires
+,token_created
+FROM tokens
+WHERE token_user_id = ?
+ORDER BY token_name
+`
+
+var tokenDelete = `
+DELETE FROM tokens WHERE token_id = ?
+`
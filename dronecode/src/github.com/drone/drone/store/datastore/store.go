@@ -5,6 +5,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/drone/drone/shared/metrics"
 	"github.com/drone/drone/store"
 	"github.com/drone/drone/store/datastore/ddl"
 	"github.com/russross/meddler"
@@ -24,10 +25,42 @@ type datastore struct {
 // New creates a database connection for the given driver and datasource
 // and returns a new Store.
 func New(driver, config string) store.Store {
+	return NewOpts(Opts{Driver: driver, Config: config})
+}
+
+// NewOpts creates a database connection with the connection options and
+// returns a new Store.
+func NewOpts(opts Opts) store.Store {
+	return newOpts(opts, open)
+}
+
+// NewReplica creates a database connection to a read replica of the
+// database and returns a new Store. The replica is not migrated, and the
+// Store must only be used for reads.
+func NewReplica(opts Opts) store.Store {
+	return newOpts(opts, connect)
+}
+
+func newOpts(opts Opts, open func(driver, config string) *sql.DB) store.Store {
+	config, err := datasource(opts)
+	if err != nil {
+		logrus.Errorln(err)
+		logrus.Fatalln("database configuration failed")
+	}
+	db := open(opts.Driver, config)
+	if opts.MaxOpen != 0 {
+		db.SetMaxOpenConns(opts.MaxOpen)
+	}
+	if opts.MaxIdle != 0 {
+		db.SetMaxIdleConns(opts.MaxIdle)
+	}
+	if opts.MaxLifetime != 0 {
+		db.SetConnMaxLifetime(opts.MaxLifetime)
+	}
 	return &datastore{
-		DB:     open(driver, config),
-		driver: driver,
-		config: config,
+		DB:     db,
+		driver: opts.Driver,
+		config: opts.Config,
 	}
 }
 
@@ -37,9 +70,20 @@ func From(db *sql.DB) store.Store {
 }
 
 // open opens a new database connection with the specified
-// driver and connection string and returns a store.
+// driver and connection string, and migrates the database.
 func open(driver, config string) *sql.DB {
-	db, err := sql.Open(driver, config)
+	db := connect(driver, config)
+	if err := setupDatabase(driver, db); err != nil {
+		logrus.Errorln(err)
+		logrus.Fatalln("migration failed")
+	}
+	return db
+}
+
+// connect opens a new database connection with the specified
+// driver and connection string.
+func connect(driver, config string) *sql.DB {
+	db, err := sql.Open(sqlDriver(driver), config)
 	if err != nil {
 		logrus.Errorln(err)
 		logrus.Fatalln("database connection failed")
@@ -55,11 +99,6 @@ func open(driver, config string) *sql.DB {
 		logrus.Errorln(err)
 		logrus.Fatalln("database ping attempts failed")
 	}
-
-	if err := setupDatabase(driver, db); err != nil {
-		logrus.Errorln(err)
-		logrus.Fatalln("migration failed")
-	}
 	return db
 }
 
@@ -126,7 +165,42 @@ func setupMeddler(driver string) {
 		meddler.Default = meddler.SQLite
 	case "mysql":
 		meddler.Default = meddler.MySQL
-	case "postgres":
+	case "postgres", "cockroach":
 		meddler.Default = meddler.PostgreSQL
 	}
 }
+
+// helper function returns the name of the sql driver. Cockroach uses the
+// postgres wire protocol and driver.
+func sqlDriver(driver string) string {
+	if driver == ddl.DriverCockroach {
+		return ddl.DriverPostgres
+	}
+	return driver
+}
+
+// Exec executes a query without returning any rows, recording the
+// query duration.
+func (db *datastore) Exec(query string, args ...interface{}) (sql.Result, error) {
+	defer observe(time.Now())
+	return db.DB.Exec(query, args...)
+}
+
+// Query executes a query that returns rows, recording the query
+// duration.
+func (db *datastore) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	defer observe(time.Now())
+	return db.DB.Query(query, args...)
+}
+
+// QueryRow executes a query that is expected to return at most one
+// row, recording the query duration.
+func (db *datastore) QueryRow(query string, args ...interface{}) *sql.Row {
+	defer observe(time.Now())
+	return db.DB.QueryRow(query, args...)
+}
+
+// helper function to record the duration of a database query.
+func observe(start time.Time) {
+	metrics.QueryDuration.Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,35 @@
+package datastore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/drone/drone/model"
+)
+
+func TestNewReplica(t *testing.T) {
+	dir, err := ioutil.TempDir("", "drone-replica")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the replica reads the migrated database.
+	path := filepath.Join(dir, "drone.sqlite")
+	s := New("sqlite3", path)
+	if err := s.CreateUser(&model.User{Login: "octocat", Token: "cfcd2084"}); err != nil {
+		t.Fatal(err)
+	}
+	replica := NewReplica(Opts{Driver: "sqlite3", Config: path})
+	if user, err := replica.GetUserLogin("octocat"); err != nil || user.Login != "octocat" {
+		t.Errorf("Want user read from the replica, got %v", err)
+	}
+
+	// the replica does not migrate the database.
+	replica = NewReplica(Opts{Driver: "sqlite3", Config: filepath.Join(dir, "empty.sqlite")})
+	if _, err := replica.GetUserLogin("octocat"); err == nil {
+		t.Errorf("Want error reading a database that is not migrated")
+	}
+}
@@ -22,3 +22,9 @@ func (db *datastore) TaskDelete(id string) error {
 	_, err := db.Exec(stmt, id)
 	return err
 }
+
+func (db *datastore) TaskUpdateRunning(id string, running bool) error {
+	stmt := sql.Lookup(db.driver, "task-update-running")
+	_, err := db.Exec(stmt, running, id)
+	return err
+}
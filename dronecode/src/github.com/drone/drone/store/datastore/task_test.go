@@ -35,6 +35,20 @@ func TestTaskList(t *testing.T) {
 		t.Errorf("Want task data %s, got %s", want, string(got))
 	}
 
+	if list[0].Running {
+		t.Errorf("Want task not running")
+	}
+
+	err = s.TaskUpdateRunning("some_random_id", true)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	list, _ = s.TaskList()
+	if len(list) != 1 || !list[0].Running {
+		t.Errorf("Want task marked as running")
+	}
+
 	err = s.TaskDelete("some_random_id")
 	if err != nil {
 		t.Error(err)
@@ -0,0 +1,35 @@
+package datastore
+
+import (
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/store/datastore/sql"
+	"github.com/russross/meddler"
+)
+
+func (db *datastore) TemplateFind(namespace, name string) (*model.Template, error) {
+	stmt := sql.Lookup(db.driver, "template-find-namespace-name")
+	data := new(model.Template)
+	err := meddler.QueryRow(db, data, stmt, namespace, name)
+	return data, err
+}
+
+func (db *datastore) TemplateList(namespace string) ([]*model.Template, error) {
+	stmt := sql.Lookup(db.driver, "template-find-namespace")
+	data := []*model.Template{}
+	err := meddler.QueryAll(db, &data, stmt, namespace)
+	return data, err
+}
+
+func (db *datastore) TemplateCreate(template *model.Template) error {
+	return meddler.Insert(db, "templates", template)
+}
+
+func (db *datastore) TemplateUpdate(template *model.Template) error {
+	return meddler.Update(db, "templates", template)
+}
+
+func (db *datastore) TemplateDelete(template *model.Template) error {
+	stmt := sql.Lookup(db.driver, "template-delete")
+	_, err := db.Exec(stmt, template.ID)
+	return err
+}
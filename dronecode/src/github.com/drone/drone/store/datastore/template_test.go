@@ -0,0 +1,91 @@
+package datastore
+
+import (
+	"testing"
+
+	"github.com/drone/drone/model"
+)
+
+func TestTemplateFind(t *testing.T) {
+	s := newTest()
+	defer func() {
+		s.Exec("delete from templates")
+		s.Close()
+	}()
+
+	err := s.TemplateCreate(&model.Template{
+		Namespace: "octocat",
+		Name:      "golang.yaml",
+		Data:      "pipeline: {}",
+		Created:   1,
+		Updated:   1,
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: insert template: %s", err)
+		return
+	}
+
+	template, err := s.TemplateFind("octocat", "golang.yaml")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := template.Namespace, "octocat"; got != want {
+		t.Errorf("Want template namespace %s, got %s", want, got)
+	}
+	if got, want := template.Data, "pipeline: {}"; got != want {
+		t.Errorf("Want template data %s, got %s", want, got)
+	}
+
+	template.Data = "pipeline: { build: { image: golang } }"
+	if err := s.TemplateUpdate(template); err != nil {
+		t.Errorf("Unexpected error: update template: %s", err)
+		return
+	}
+	template, _ = s.TemplateFind("octocat", "golang.yaml")
+	if got, want := template.Data, "pipeline: { build: { image: golang } }"; got != want {
+		t.Errorf("Want updated template data %s, got %s", want, got)
+	}
+}
+
+func TestTemplateList(t *testing.T) {
+	s := newTest()
+	defer func() {
+		s.Exec("delete from templates")
+		s.Close()
+	}()
+
+	s.TemplateCreate(&model.Template{Namespace: "octocat", Name: "golang.yaml", Data: "pipeline: {}"})
+	s.TemplateCreate(&model.Template{Namespace: "octocat", Name: "node.yaml", Data: "pipeline: {}"})
+	s.TemplateCreate(&model.Template{Namespace: "spaceghost", Name: "golang.yaml", Data: "pipeline: {}"})
+
+	list, err := s.TemplateList("octocat")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := len(list), 2; got != want {
+		t.Errorf("Want %d templates, got %d", want, got)
+	}
+}
+
+func TestTemplateDelete(t *testing.T) {
+	s := newTest()
+	defer func() {
+		s.Exec("delete from templates")
+		s.Close()
+	}()
+
+	template := &model.Template{Namespace: "octocat", Name: "golang.yaml", Data: "pipeline: {}"}
+	if err := s.TemplateCreate(template); err != nil {
+		t.Errorf("Unexpected error: insert template: %s", err)
+		return
+	}
+	if err := s.TemplateDelete(template); err != nil {
+		t.Errorf("Unexpected error: delete template: %s", err)
+		return
+	}
+	if _, err := s.TemplateFind("octocat", "golang.yaml"); err == nil {
+		t.Errorf("Expect error when the template is deleted")
+	}
+}
@@ -0,0 +1,30 @@
+package datastore
+
+import (
+	"github.com/drone/drone/model"
+	"github.com/drone/drone/store/datastore/sql"
+	"github.com/russross/meddler"
+)
+
+func (db *datastore) AccessTokenFind(id int64) (*model.AccessToken, error) {
+	token := new(model.AccessToken)
+	err := meddler.Load(db, "tokens", token, id)
+	return token, err
+}
+
+func (db *datastore) AccessTokenList(user *model.User) ([]*model.AccessToken, error) {
+	stmt := sql.Lookup(db.driver, "token-find-user")
+	data := []*model.AccessToken{}
+	err := meddler.QueryAll(db, &data, stmt, user.ID)
+	return data, err
+}
+
+func (db *datastore) AccessTokenCreate(token *model.AccessToken) error {
+	return meddler.Insert(db, "tokens", token)
+}
+
+func (db *datastore) AccessTokenDelete(token *model.AccessToken) error {
+	stmt := sql.Lookup(db.driver, "token-delete")
+	_, err := db.Exec(stmt, token.ID)
+	return err
+}
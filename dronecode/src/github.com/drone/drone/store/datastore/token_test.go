@@ -0,0 +1,60 @@
+package datastore
+
+import (
+	"testing"
+
+	"github.com/drone/drone/model"
+)
+
+func TestAccessTokenList(t *testing.T) {
+	s := newTest()
+	defer func() {
+		s.Exec("delete from tokens")
+		s.Close()
+	}()
+
+	token := &model.AccessToken{
+		UserID:  1,
+		Name:    "ci",
+		Scopes:  []string{model.ScopeRead, model.ScopeBuildRestart},
+		Hash:    "hash",
+		Expires: 100,
+	}
+	if err := s.AccessTokenCreate(token); err != nil {
+		t.Errorf("Unexpected error: insert token: %s", err)
+		return
+	}
+	if err := s.AccessTokenCreate(&model.AccessToken{UserID: 1, Name: "ci"}); err == nil {
+		t.Errorf("Want error inserting token with duplicate name")
+	}
+	s.AccessTokenCreate(&model.AccessToken{UserID: 2, Name: "ci"})
+
+	found, err := s.AccessTokenFind(token.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := len(found.Scopes), 2; got != want {
+		t.Errorf("Want %d token scopes, got %d", want, got)
+	}
+	if got, want := found.Hash, "hash"; got != want {
+		t.Errorf("Want token hash %s, got %s", want, got)
+	}
+
+	list, err := s.AccessTokenList(&model.User{ID: 1})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := len(list), 1; got != want {
+		t.Errorf("Want %d tokens, got %d", want, got)
+	}
+
+	if err := s.AccessTokenDelete(token); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := s.AccessTokenFind(token.ID); err == nil {
+		t.Errorf("Want error finding deleted token")
+	}
+}
@@ -0,0 +1,42 @@
+package datastore
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// maxTxRetries is the maximum number of times an aborted transaction is
+// retried.
+const maxTxRetries = 5
+
+// transact executes the function in a transaction. The transaction is
+// retried if the database aborts it with a serialization failure, which
+// cockroach returns for conflicting transactions that must be retried by
+// the client.
+func (db *datastore) transact(fn func(*sql.Tx) error) (err error) {
+	for i := 0; ; i++ {
+		var tx *sql.Tx
+		tx, err = db.Begin()
+		if err != nil {
+			return err
+		}
+		if err = fn(tx); err == nil {
+			err = tx.Commit()
+		} else {
+			tx.Rollback()
+		}
+		if err == nil || !retryable(err) || i == maxTxRetries {
+			return err
+		}
+		time.Sleep(time.Duration(i+1) * 10 * time.Millisecond)
+	}
+}
+
+// helper function returns true if the error is a serialization failure of
+// the transaction.
+func retryable(err error) bool {
+	pqerr, ok := err.(*pq.Error)
+	return ok && pqerr.Code == "40001"
+}
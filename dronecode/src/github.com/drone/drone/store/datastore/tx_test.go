@@ -0,0 +1,38 @@
+package datastore
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestTransact(t *testing.T) {
+	s := newTest()
+	defer s.Close()
+
+	var calls int
+	err := s.transact(func(tx *sql.Tx) error {
+		calls++
+		if calls == 1 {
+			return &pq.Error{Code: "40001"}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if calls != 2 {
+		t.Errorf("Want serialization failure retried, got %d calls", calls)
+	}
+
+	calls = 0
+	err = s.transact(func(tx *sql.Tx) error {
+		calls++
+		return errors.New("unique constraint failed")
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("Want other errors returned without retry, got %d calls", calls)
+	}
+}
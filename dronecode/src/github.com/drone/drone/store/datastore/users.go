@@ -20,6 +20,12 @@ func (db *datastore) GetUserLogin(login string) (*model.User, error) {
 	return usr, err
 }
 
+func (db *datastore) GetUserSubject(subject string) (*model.User, error) {
+	var usr = new(model.User)
+	var err = meddler.QueryRow(db, usr, rebind(userSubjectQuery), subject)
+	return usr, err
+}
+
 func (db *datastore) GetUserList() ([]*model.User, error) {
 	var users = []*model.User{}
 	var err = meddler.QueryAll(db, &users, rebind(userListQuery))
@@ -95,6 +101,13 @@ WHERE user_login=?
 LIMIT 1
 `
 
+const userSubjectQuery = `
+SELECT *
+FROM users
+WHERE user_subject=?
+LIMIT 1
+`
+
 const userListQuery = `
 SELECT *
 FROM users
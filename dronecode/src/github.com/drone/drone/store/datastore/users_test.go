@@ -85,6 +85,22 @@ func TestUsers(t *testing.T) {
 			g.Assert(user.Login).Equal(getuser.Login)
 		})
 
+		g.It("Should Get a User By Subject", func() {
+			user := model.User{
+				Login:   "joe",
+				Email:   "foo@bar.com",
+				Token:   "e42080dddf012c718e476da161d21ad5",
+				Subject: "248289761001",
+			}
+			s.CreateUser(&user)
+			getuser, err := s.GetUserSubject(user.Subject)
+			g.Assert(err == nil).IsTrue()
+			g.Assert(user.ID).Equal(getuser.ID)
+
+			_, err = s.GetUserSubject("")
+			g.Assert(err != nil).IsTrue()
+		})
+
 		g.It("Should Enforce Unique User Login", func() {
 			user1 := model.User{
 				Login: "joe",
@@ -15,6 +15,10 @@ type Store interface {
 	// GetUserLogin gets a user by unique Login name.
 	GetUserLogin(string) (*model.User, error)
 
+	// GetUserSubject gets a user by the subject of the linked OpenID Connect
+	// identity.
+	GetUserSubject(string) (*model.User, error)
+
 	// GetUserList gets a list of all users in the system.
 	GetUserList() ([]*model.User, error)
 
@@ -46,6 +50,9 @@ type Store interface {
 	// GetRepoListOf gets the list of enumerated repos in the system.
 	GetRepoListOf([]*model.RepoLite) ([]*model.Repo, error)
 
+	// GetRepoList gets the list of all repos in the system.
+	GetRepoList() ([]*model.Repo, error)
+
 	// GetRepoCount gets a count of all repositories in the system.
 	GetRepoCount() (int, error)
 
@@ -64,7 +71,7 @@ type Store interface {
 	// GetBuildNumber gets a build by number.
 	GetBuildNumber(*model.Repo, int) (*model.Build, error)
 
-	// GetBuildRef gets a build by its ref.
+	// GetBuildRef gets the latest build by its ref.
 	GetBuildRef(*model.Repo, string) (*model.Build, error)
 
 	// GetBuildCommit gets a build by its commit sha.
@@ -79,9 +86,42 @@ type Store interface {
 	// GetBuildList gets a list of builds for the repository
 	GetBuildList(*model.Repo) ([]*model.Build, error)
 
+	// GetBuildListFilter gets a page of builds for the repository matching
+	// the filter.
+	GetBuildListFilter(*model.Repo, *model.BuildFilter) ([]*model.Build, error)
+
+	// GetBuildCount gets the number of builds for the repository matching
+	// the branch, event and status of the filter.
+	GetBuildCount(*model.Repo, *model.BuildFilter) (int, error)
+
+	// GetBuildCountUser gets the number of builds enqueued since the
+	// timestamp in the repositories of the user.
+	GetBuildCountUser(*model.User, int64) (int, error)
+
+	// GetBuildTimes gets the timestamps of the completed builds of the
+	// repository created since the timestamp.
+	GetBuildTimes(*model.Repo, int64) ([]*model.BuildTime, error)
+
 	// GetBuildQueue gets a list of build in queue.
 	GetBuildQueue() ([]*model.Feed, error)
 
+	// GetBuildFeed gets a list of builds of the repositories matching the
+	// filter, or of all repositories if the repository list is nil.
+	GetBuildFeed([]*model.RepoLite, *model.FeedFilter) ([]*model.Feed, error)
+
+	// GetBuildActive gets a list of pending and running builds for the
+	// repository.
+	GetBuildActive(*model.Repo) ([]*model.Build, error)
+
+	// GetDeployList gets a list of deployments and rollbacks for the
+	// repository to the target environment, or to all environments if the
+	// target is empty.
+	GetDeployList(*model.Repo, string) ([]*model.Build, error)
+
+	// GetDeployLatest gets the latest deployment or rollback of each
+	// environment of the repository.
+	GetDeployLatest(*model.Repo) ([]*model.Build, error)
+
 	// CreateBuild creates a new build and jobs.
 	CreateBuild(*model.Build, ...*model.Proc) error
 
@@ -109,31 +149,130 @@ type Store interface {
 	SecretUpdate(*model.Secret) error
 	SecretDelete(*model.Secret) error
 
+	OrgSecretFind(string, string) (*model.OrgSecret, error)
+	OrgSecretList(string) ([]*model.OrgSecret, error)
+	OrgSecretCreate(*model.OrgSecret) error
+	OrgSecretUpdate(*model.OrgSecret) error
+	OrgSecretDelete(*model.OrgSecret) error
+
+	TemplateFind(string, string) (*model.Template, error)
+	TemplateList(string) ([]*model.Template, error)
+	TemplateCreate(*model.Template) error
+	TemplateUpdate(*model.Template) error
+	TemplateDelete(*model.Template) error
+
+	HookFind(int64) (*model.Hook, error)
+	HookListFailed(int) ([]*model.Hook, error)
+	HookCreate(*model.Hook) error
+	HookUpdate(*model.Hook) error
+
+	AuditCreate(*model.Audit) error
+	AuditList(*model.AuditFilter) ([]*model.Audit, error)
+
+	AccessTokenFind(int64) (*model.AccessToken, error)
+	AccessTokenList(*model.User) ([]*model.AccessToken, error)
+	AccessTokenCreate(*model.AccessToken) error
+	AccessTokenDelete(*model.AccessToken) error
+
+	PermFind(*model.User, *model.Repo) (*model.RepoPerm, error)
+	PermList(*model.User) ([]*model.RepoPerm, error)
+	PermUpsert(*model.RepoPerm) error
+	PermDelete(*model.RepoPerm) error
+
+	DeliveryFind(int64) (*model.Delivery, error)
+	DeliveryList(int) ([]*model.Delivery, error)
+	DeliveryCreate(*model.Delivery) error
+	DeliveryUpdate(*model.Delivery) error
+
 	RegistryFind(*model.Repo, string) (*model.Registry, error)
 	RegistryList(*model.Repo) ([]*model.Registry, error)
 	RegistryCreate(*model.Registry) error
 	RegistryUpdate(*model.Registry) error
 	RegistryDelete(*model.Registry) error
 
+	CronFind(*model.Repo, string) (*model.Cron, error)
+	CronList(*model.Repo) ([]*model.Cron, error)
+	CronListDue(int64) ([]*model.Cron, error)
+	CronCreate(*model.Cron) error
+	CronUpdate(*model.Cron) error
+	CronDelete(*model.Cron) error
+
 	ProcLoad(int64) (*model.Proc, error)
 	ProcFind(*model.Build, int) (*model.Proc, error)
 	ProcChild(*model.Build, int, string) (*model.Proc, error)
 	ProcList(*model.Build) ([]*model.Proc, error)
 	ProcCreate([]*model.Proc) error
 	ProcUpdate(*model.Proc) error
+	ProcUpdateBatch([]*model.Proc) error
 	ProcClear(*model.Build) error
 
 	LogFind(*model.Proc) (io.ReadCloser, error)
+
+	// LogTail returns the trailing lines of the proc logs.
+	LogTail(*model.Proc, int) (io.ReadCloser, error)
+
 	LogSave(*model.Proc, io.Reader) error
 
 	FileList(*model.Build) ([]*model.File, error)
 	FileFind(*model.Proc, string) (*model.File, error)
 	FileRead(*model.Proc, string) (io.ReadCloser, error)
 	FileCreate(*model.File, io.Reader) error
+	FileListBefore(int64, int) ([]*model.File, error)
+	FileDelete(*model.File) error
+
+	// CacheFind gets the pipeline cache of the repository by key.
+	CacheFind(*model.Repo, string) (*model.Cache, error)
+
+	// CacheFindPrefix gets the most recent pipeline cache of the repository
+	// with the key prefix.
+	CacheFindPrefix(*model.Repo, string) (*model.Cache, error)
+
+	CacheRead(*model.Cache) (io.ReadCloser, error)
+	CacheCreate(*model.Cache, io.Reader) error
+
+	// LogPrune deletes the logs of the steps completed before the time.
+	LogPrune(int64) (int64, error)
+
+	// BuildPrune deletes the completed builds, and the build steps, logs
+	// and artifacts, except for the specified number of most recent
+	// builds of each repository.
+	BuildPrune(int) (int64, error)
+
+	// LogPruneList returns the steps completed before the time that have
+	// logs, which are deleted by LogPrune.
+	LogPruneList(int64) ([]*model.Proc, error)
+
+	// BuildPruneList returns the steps and artifacts of the builds that
+	// are deleted by BuildPrune.
+	BuildPruneList(int) ([]*model.Proc, []*model.File, error)
+
+	// HookPrune deletes the hooks received before the time.
+	HookPrune(int64) (int64, error)
+
+	// Backup returns a portable archive of the users, repositories, secrets,
+	// build metadata and settings.
+	Backup() (*model.Backup, error)
+
+	// Restore restores the backup into an empty database.
+	Restore(*model.Backup) error
+
+	// Reseal rewrites the encrypted secret values, registry credentials and
+	// user tokens with the function in a single transaction, and returns the
+	// number of rewritten values. In dry run mode the values are not written.
+	Reseal(func(table, value string) (string, error), bool) (int, error)
+
+	// Ping verifies the database connection is alive.
+	Ping() error
 
 	TaskList() ([]*model.Task, error)
 	TaskInsert(*model.Task) error
 	TaskDelete(string) error
+	TaskUpdateRunning(string, bool) error
+
+	HoldList(*model.Build) ([]*model.Hold, error)
+	HoldCreate(*model.Hold) error
+	HoldDelete(*model.Hold) (bool, error)
+	HoldClear(*model.Build) error
 }
 
 // GetUser gets a user by unique ID.
@@ -19,3 +19,26 @@ type Engine interface {
 	// Destroy the pipeline environment.
 	Destroy(*Config) error
 }
+
+// Prober is implemented by engines that can check the health of detached
+// steps with a readiness probe.
+type Prober interface {
+	// Healthy returns true if the step is healthy, false if the health
+	// of the step is not yet known, and an error if the step is unhealthy
+	// or exited.
+	Healthy(*Step) (bool, error)
+}
+
+// Puller is implemented by engines that pull the image of the step before
+// the step is started, so that the image pull is reported separately.
+type Puller interface {
+	// Pull pulls the image of the step, and returns the pull progress.
+	Pull(*Step) (io.ReadCloser, error)
+}
+
+// Copier is implemented by engines that can copy files from the step
+// container after the step completes.
+type Copier interface {
+	// Copy returns a tar archive of the file or directory at the path.
+	Copy(*Step, string) (io.ReadCloser, error)
+}
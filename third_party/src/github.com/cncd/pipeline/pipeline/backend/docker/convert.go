@@ -7,6 +7,7 @@ import (
 
 	"github.com/cncd/pipeline/pipeline/backend"
 
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 )
 
@@ -31,6 +32,14 @@ func toConfig(proc *backend.Step) *container.Config {
 	if len(proc.Volumes) != 0 {
 		config.Volumes = toVol(proc.Volumes)
 	}
+	if proc.HealthCheck != nil {
+		config.Healthcheck = &container.HealthConfig{
+			Test:     []string{"CMD-SHELL", proc.HealthCheck.Test},
+			Interval: proc.HealthCheck.Interval,
+			Timeout:  proc.HealthCheck.Timeout,
+			Retries:  proc.HealthCheck.Retries,
+		}
+	}
 	return config
 }
 
@@ -38,11 +47,13 @@ func toConfig(proc *backend.Step) *container.Config {
 func toHostConfig(proc *backend.Step) *container.HostConfig {
 	config := &container.HostConfig{
 		Resources: container.Resources{
-			CPUQuota:   proc.CPUQuota,
-			CPUShares:  proc.CPUShares,
-			CpusetCpus: proc.CPUSet,
-			Memory:     proc.MemLimit,
-			MemorySwap: proc.MemSwapLimit,
+			CPUQuota:          proc.CPUQuota,
+			CPUShares:         proc.CPUShares,
+			CpusetCpus:        proc.CPUSet,
+			Memory:            proc.MemLimit,
+			MemorySwap:        proc.MemSwapLimit,
+			NanoCPUs:          proc.CPULimit * 1e6,
+			MemoryReservation: proc.MemReserve,
 		},
 		Privileged: proc.Privileged,
 		ShmSize:    proc.ShmSize,
@@ -128,3 +139,13 @@ func encodeAuthToBase64(authConfig backend.Auth) (string, error) {
 	}
 	return base64.URLEncoding.EncodeToString(buf), nil
 }
+
+// helper function returns the pull options with the encoded authorization
+// credentials of the process.
+func pullOpts(proc *backend.Step) types.ImagePullOptions {
+	opts := types.ImagePullOptions{}
+	if proc.AuthConfig.Username != "" && proc.AuthConfig.Password != "" {
+		opts.RegistryAuth, _ = encodeAuthToBase64(proc.AuthConfig)
+	}
+	return opts
+}
@@ -2,8 +2,10 @@ package docker
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"strings"
 
 	"github.com/cncd/pipeline/pipeline/backend"
 
@@ -60,30 +62,28 @@ func (e *engine) Setup(conf *backend.Config) error {
 	return nil
 }
 
+// Pull pulls the latest version of the image, which is requested by the
+// process configuration, before the process is started.
+func (e *engine) Pull(proc *backend.Step) (io.ReadCloser, error) {
+	rc, err := e.client.ImagePull(noContext, proc.Image, pullOpts(proc))
+	// fix for drone/drone#1917
+	if err != nil && proc.AuthConfig.Password == "" {
+		return ioutil.NopCloser(strings.NewReader("")), nil
+	}
+	return rc, err
+}
+
 func (e *engine) Exec(proc *backend.Step) error {
 	ctx := context.Background()
 
 	config := toConfig(proc)
 	hostConfig := toHostConfig(proc)
+	pullopts := pullOpts(proc)
 
-	// create pull options with encoded authorization credentials.
-	pullopts := types.ImagePullOptions{}
-	if proc.AuthConfig.Username != "" && proc.AuthConfig.Password != "" {
-		pullopts.RegistryAuth, _ = encodeAuthToBase64(proc.AuthConfig)
-	}
-
-	// automatically pull the latest version of the image if requested
-	// by the process configuration.
-	if proc.Pull {
-		rc, perr := e.client.ImagePull(ctx, config.Image, pullopts)
-		if perr == nil {
-			io.Copy(ioutil.Discard, rc)
-			rc.Close()
-		}
-		// fix for drone/drone#1917
-		if perr != nil && proc.AuthConfig.Password != "" {
-			return perr
-		}
+	// remove the container from a previous attempt, if any, so that
+	// retried steps can be re-created under the same name.
+	if proc.Retries != 0 {
+		e.client.ContainerRemove(ctx, proc.Name, removeOpts)
 	}
 
 	_, err := e.client.ContainerCreate(ctx, config, hostConfig, nil, proc.Name)
@@ -149,6 +149,27 @@ func (e *engine) Wait(proc *backend.Step) (*backend.State, error) {
 	}, nil
 }
 
+func (e *engine) Healthy(proc *backend.Step) (bool, error) {
+	info, err := e.client.ContainerInspect(noContext, proc.Name)
+	if err != nil {
+		return false, err
+	}
+	if !info.State.Running {
+		return false, fmt.Errorf("%s exited with code %d", proc.Alias, info.State.ExitCode)
+	}
+	if info.State.Health == nil {
+		return true, nil
+	}
+	switch info.State.Health.Status {
+	case types.Healthy, types.NoHealthcheck:
+		return true, nil
+	case types.Unhealthy:
+		return false, fmt.Errorf("%s is unhealthy", proc.Alias)
+	default:
+		return false, nil
+	}
+}
+
 func (e *engine) Tail(proc *backend.Step) (io.ReadCloser, error) {
 	logs, err := e.client.ContainerLogs(noContext, proc.Name, logsOpts)
 	if err != nil {
@@ -165,6 +186,11 @@ func (e *engine) Tail(proc *backend.Step) (io.ReadCloser, error) {
 	return rc, nil
 }
 
+func (e *engine) Copy(proc *backend.Step, path string) (io.ReadCloser, error) {
+	rc, _, err := e.client.CopyFromContainer(noContext, proc.Name, path)
+	return rc, err
+}
+
 func (e *engine) Destroy(conf *backend.Config) error {
 	for _, stage := range conf.Stages {
 		for _, step := range stage.Steps {
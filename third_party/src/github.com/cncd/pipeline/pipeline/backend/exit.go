@@ -0,0 +1,61 @@
+package backend
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MatchExitCode returns true if the exit code matches the condition. The
+// condition compares the exit code to a number, such as exit_code != 0 or
+// exit_code == 137, and comparisons can be joined with || to match any of
+// them. An empty condition matches a non-zero exit code.
+func MatchExitCode(cond string, code int) (bool, error) {
+	if strings.TrimSpace(cond) == "" {
+		return code != 0, nil
+	}
+	var match bool
+	for _, expr := range strings.Split(cond, "||") {
+		ok, err := matchExitExpr(strings.TrimSpace(expr), code)
+		if err != nil {
+			return false, err
+		}
+		match = match || ok
+	}
+	return match, nil
+}
+
+// helper function evaluates a single exit code comparison.
+func matchExitExpr(expr string, code int) (bool, error) {
+	if !strings.HasPrefix(expr, "exit_code") {
+		return false, fmt.Errorf("invalid exit code condition %q", expr)
+	}
+	expr = strings.TrimSpace(strings.TrimPrefix(expr, "exit_code"))
+
+	// operators with two characters are matched first, so that >= is
+	// not parsed as >.
+	for _, op := range []string{"==", "!=", ">=", "<=", ">", "<"} {
+		if !strings.HasPrefix(expr, op) {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(expr, op)))
+		if err != nil {
+			return false, fmt.Errorf("invalid exit code condition %q", "exit_code "+expr)
+		}
+		switch op {
+		case "==":
+			return code == n, nil
+		case "!=":
+			return code != n, nil
+		case ">=":
+			return code >= n, nil
+		case "<=":
+			return code <= n, nil
+		case ">":
+			return code > n, nil
+		default:
+			return code < n, nil
+		}
+	}
+	return false, fmt.Errorf("invalid exit code condition %q", "exit_code "+expr)
+}
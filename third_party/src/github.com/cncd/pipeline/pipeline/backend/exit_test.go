@@ -0,0 +1,44 @@
+package backend
+
+import "testing"
+
+func TestMatchExitCode(t *testing.T) {
+	tests := []struct {
+		cond  string
+		code  int
+		match bool
+		err   bool
+	}{
+		{cond: "", code: 1, match: true},
+		{cond: "", code: 0, match: false},
+		{cond: "exit_code == 137", code: 137, match: true},
+		{cond: "exit_code==137", code: 1, match: false},
+		{cond: "exit_code != 0", code: 2, match: true},
+		{cond: "exit_code >= 128", code: 128, match: true},
+		{cond: "exit_code > 128", code: 128, match: false},
+		{cond: "exit_code <= 1", code: 1, match: true},
+		{cond: "exit_code < 1", code: 1, match: false},
+		{cond: "exit_code == 1 || exit_code == 137", code: 137, match: true},
+		{cond: "exit_code == 1 || exit_code == 137", code: 2, match: false},
+		{cond: "code == 1", err: true},
+		{cond: "exit_code = 1", err: true},
+		{cond: "exit_code == one", err: true},
+		{cond: "exit_code == 1 || oom", err: true},
+	}
+	for _, test := range tests {
+		match, err := MatchExitCode(test.cond, test.code)
+		if test.err {
+			if err == nil {
+				t.Errorf("Want error parsing condition %q", test.cond)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Want condition %q parsed, got %s", test.cond, err)
+			continue
+		}
+		if match != test.match {
+			t.Errorf("Want condition %q match exit code %d %v, got %v", test.cond, test.code, test.match, match)
+		}
+	}
+}
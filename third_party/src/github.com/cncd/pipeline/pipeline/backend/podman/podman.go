@@ -0,0 +1,37 @@
+// Package podman implements a Podman backend. Podman does not require
+// a daemon, and exposes a Docker compatible API over a (rootless) unix
+// socket when running the podman system service.
+package podman
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/cncd/pipeline/pipeline/backend"
+	"github.com/cncd/pipeline/pipeline/backend/docker"
+
+	"github.com/docker/docker/client"
+)
+
+// NewEnv returns a new Podman Engine using the CONTAINER_HOST
+// environment variable, falling back to the default podman socket.
+func NewEnv() (backend.Engine, error) {
+	host := os.Getenv("CONTAINER_HOST")
+	if host == "" {
+		host = defaultHost()
+	}
+	cli, err := client.NewClient(host, client.DefaultVersion, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return docker.New(cli), nil
+}
+
+// defaultHost returns the default podman socket. The rootless socket
+// is used when running as a non-root user.
+func defaultHost() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" && os.Geteuid() != 0 {
+		return "unix://" + filepath.Join(dir, "podman", "podman.sock")
+	}
+	return "unix:///run/podman/podman.sock"
+}
@@ -0,0 +1,37 @@
+package podman
+
+import (
+	"os"
+	"testing"
+)
+
+func TestDefaultHost(t *testing.T) {
+	defer os.Setenv("XDG_RUNTIME_DIR", os.Getenv("XDG_RUNTIME_DIR"))
+
+	os.Setenv("XDG_RUNTIME_DIR", "")
+	if got, want := defaultHost(), "unix:///run/podman/podman.sock"; got != want {
+		t.Errorf("Want default host %s, got %s", want, got)
+	}
+
+	os.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	want := "unix:///run/user/1000/podman/podman.sock"
+	if os.Geteuid() == 0 {
+		want = "unix:///run/podman/podman.sock"
+	}
+	if got := defaultHost(); got != want {
+		t.Errorf("Want default host %s, got %s", want, got)
+	}
+}
+
+func TestNewEnv(t *testing.T) {
+	defer os.Setenv("CONTAINER_HOST", os.Getenv("CONTAINER_HOST"))
+
+	os.Setenv("CONTAINER_HOST", "unix:///tmp/podman.sock")
+	if _, err := NewEnv(); err != nil {
+		t.Errorf("Want engine for the container host, got %s", err)
+	}
+	os.Setenv("CONTAINER_HOST", "not a host")
+	if _, err := NewEnv(); err == nil {
+		t.Errorf("Want error for an invalid container host")
+	}
+}
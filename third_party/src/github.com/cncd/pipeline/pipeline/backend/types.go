@@ -1,5 +1,7 @@
 package backend
 
+import "time"
+
 type (
 	// Config defines the runtime configuration of a pipeline.
 	Config struct {
@@ -37,13 +39,34 @@ type (
 		DNSSearch    []string          `json:"dns_search,omitempty"`
 		MemSwapLimit int64             `json:"memswap_limit,omitempty"`
 		MemLimit     int64             `json:"mem_limit,omitempty"`
+		MemReserve   int64             `json:"mem_reserve,omitempty"`
 		ShmSize      int64             `json:"shm_size,omitempty"`
 		CPUQuota     int64             `json:"cpu_quota,omitempty"`
 		CPUShares    int64             `json:"cpu_shares,omitempty"`
 		CPUSet       string            `json:"cpu_set,omitempty"`
+		CPULimit     int64             `json:"cpu_limit,omitempty"`
 		OnFailure    bool              `json:"on_failure,omitempty"`
 		OnSuccess    bool              `json:"on_success,omitempty"`
+		OnKilled     bool              `json:"on_killed,omitempty"`
+		AllowFailure bool              `json:"allow_failure,omitempty"`
 		AuthConfig   Auth              `json:"auth_config,omitempty"`
+		Retries      int               `json:"retries,omitempty"`
+		RetryBackoff time.Duration     `json:"retry_backoff,omitempty"`
+		RetryWhen    string            `json:"retry_when,omitempty"`
+		Timeout      time.Duration     `json:"timeout,omitempty"`
+		HealthCheck  *HealthCheck      `json:"healthcheck,omitempty"`
+		Artifacts    []string          `json:"artifacts,omitempty"`
+		Output       string            `json:"output,omitempty"`
+		DependsOn    []string          `json:"depends_on,omitempty"`
+	}
+
+	// HealthCheck defines the readiness probe of a detached step, which
+	// is a shell command executed in the step container.
+	HealthCheck struct {
+		Test     string        `json:"test"`
+		Interval time.Duration `json:"interval,omitempty"`
+		Timeout  time.Duration `json:"timeout,omitempty"`
+		Retries  int           `json:"retries,omitempty"`
 	}
 
 	// Auth defines registry authentication credentials.
@@ -89,6 +112,8 @@ type (
 		Exited bool `json:"exited"`
 		// Container is oom killed, true or false
 		OOMKilled bool `json:"oom_killed"`
+		// Container is killed after its timeout, true or false
+		TimedOut bool `json:"timed_out,omitempty"`
 	}
 
 	// // State defines the pipeline and process state.
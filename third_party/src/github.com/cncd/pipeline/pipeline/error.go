@@ -3,6 +3,7 @@ package pipeline
 import (
 	"errors"
 	"fmt"
+	"time"
 )
 
 var (
@@ -26,6 +27,17 @@ func (e *ExitError) Error() string {
 	return fmt.Sprintf("%s : exit code %d", e.Name, e.Code)
 }
 
+// A TimeoutError reports the process was killed after its timeout.
+type TimeoutError struct {
+	Name    string
+	Timeout time.Duration
+}
+
+// Error returns the error message in string format.
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s : timed out after %s", e.Name, e.Timeout)
+}
+
 // An OomError reports the process received an OOMKill from the kernel.
 type OomError struct {
 	Name string
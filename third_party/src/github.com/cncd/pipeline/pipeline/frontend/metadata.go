@@ -9,10 +9,12 @@ import (
 
 // Event types corresponding to scm hooks.
 const (
-	EventPush   = "push"
-	EventPull   = "pull_request"
-	EventTag    = "tag"
-	EventDeploy = "deployment"
+	EventPush     = "push"
+	EventPull     = "pull_request"
+	EventTag      = "tag"
+	EventDeploy   = "deployment"
+	EventCron     = "cron"
+	EventRollback = "rollback"
 )
 
 type (
@@ -53,12 +55,13 @@ type (
 
 	// Commit defines runtime metadata for a commit.
 	Commit struct {
-		Sha     string `json:"sha,omitempty"`
-		Ref     string `json:"ref,omitempty"`
-		Refspec string `json:"refspec,omitempty"`
-		Branch  string `json:"branch,omitempty"`
-		Message string `json:"message,omitempty"`
-		Author  Author `json:"author,omitempty"`
+		Sha     string   `json:"sha,omitempty"`
+		Ref     string   `json:"ref,omitempty"`
+		Refspec string   `json:"refspec,omitempty"`
+		Branch  string   `json:"branch,omitempty"`
+		Message string   `json:"message,omitempty"`
+		Author  Author   `json:"author,omitempty"`
+		Changed []string `json:"changed_files,omitempty"`
 	}
 
 	// Author defines runtime metadata for a commit author.
@@ -0,0 +1,51 @@
+package yaml
+
+import (
+	"bytes"
+	"text/template"
+
+	"github.com/cncd/pipeline/pipeline/frontend"
+	libcompose "github.com/docker/libcompose/yaml"
+)
+
+// Cache defines the cache of the pipeline. The cache paths are restored
+// from the cache key, or from the most recent cache that matches one of
+// the restore keys, before the pipeline steps are executed, and are saved
+// to the cache key after the pipeline steps succeed.
+type Cache struct {
+	Key         string                   `yaml:"key,omitempty"`
+	RestoreKeys libcompose.Stringorslice `yaml:"restore_keys,omitempty"`
+	Paths       libcompose.Stringorslice `yaml:"paths,omitempty"`
+}
+
+// IsEmpty returns true if the cache is not defined.
+func (c *Cache) IsEmpty() bool {
+	return len(c.Paths) == 0
+}
+
+// RenderKey renders the cache key template, which may use the branch,
+// event and arch of the build, and the checksum function to hash the
+// contents of files, such as {{ checksum "go.sum" }}. The checksum is
+// computed by the checksum function fn.
+func RenderKey(key string, metadata frontend.Metadata, fn func(files ...string) string) (string, error) {
+	t, err := template.New("_").Option("missingkey=error").Funcs(template.FuncMap{
+		"checksum": fn,
+	}).Parse(key)
+	if err != nil {
+		return "", err
+	}
+	data := struct {
+		Branch string
+		Event  string
+		Arch   string
+	}{
+		Branch: metadata.Curr.Commit.Branch,
+		Event:  metadata.Curr.Event,
+		Arch:   metadata.Sys.Arch,
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
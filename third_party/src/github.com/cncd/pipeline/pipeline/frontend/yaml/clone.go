@@ -0,0 +1,69 @@
+package yaml
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Clone defines the clone section of the pipeline, which either configures
+// the default clone step, or replaces it with the clone containers.
+type Clone struct {
+	Containers []*Container `yaml:"-"`
+
+	Depth      int    `yaml:"depth,omitempty"`
+	Tags       bool   `yaml:"tags,omitempty"`
+	Submodules bool   `yaml:"submodules,omitempty"`
+	LFS        bool   `yaml:"lfs,omitempty"`
+	Skip       bool   `yaml:"skip,omitempty"`
+	Image      string `yaml:"image,omitempty"`
+}
+
+// UnmarshalYAML implements the Unmarshaller interface. The clone section is
+// parsed as clone containers if it is a list or every value is a mapping,
+// and otherwise as the settings of the default clone step.
+func (c *Clone) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var list []interface{}
+	if err := unmarshal(&list); err == nil {
+		containers := Containers{}
+		if err := unmarshal(&containers); err != nil {
+			return err
+		}
+		c.Containers = containers.Containers
+		return nil
+	}
+
+	slice := yaml.MapSlice{}
+	if err := unmarshal(&slice); err != nil {
+		return err
+	}
+	if isContainers(slice) {
+		containers := Containers{}
+		if err := unmarshal(&containers); err != nil {
+			return err
+		}
+		c.Containers = containers.Containers
+		return nil
+	}
+
+	type plain Clone
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.Depth < 0 {
+		return fmt.Errorf("Invalid clone depth %d", c.Depth)
+	}
+	return nil
+}
+
+// helper function returns true if every value of the mapping is a mapping.
+func isContainers(slice yaml.MapSlice) bool {
+	for _, item := range slice {
+		switch item.Value.(type) {
+		case yaml.MapSlice, map[interface{}]interface{}:
+		default:
+			return false
+		}
+	}
+	return len(slice) != 0
+}
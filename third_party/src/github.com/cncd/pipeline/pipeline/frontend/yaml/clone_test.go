@@ -0,0 +1,45 @@
+package yaml
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestUnmarshalClone(t *testing.T) {
+	tests := []struct {
+		in         string
+		want       Clone
+		containers int
+		err        bool
+	}{
+		{in: "{depth: 50, tags: true}", want: Clone{Depth: 50, Tags: true}},
+		{in: "{submodules: true, lfs: true, image: \"plugins/git:next\"}", want: Clone{Submodules: true, LFS: true, Image: "plugins/git:next"}},
+		{in: "{skip: true}", want: Clone{Skip: true}},
+		{in: "{git: {image: plugins/git, depth: 1}}", containers: 1},
+		{in: "{depth: -1}", err: true},
+		{in: "[git]", err: true},
+	}
+	for _, test := range tests {
+		got := Clone{}
+		err := yaml.Unmarshal([]byte(test.in), &got)
+		if test.err {
+			if err == nil {
+				t.Errorf("Want error parsing clone %q", test.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Want clone %q parsed, got %s", test.in, err)
+			continue
+		}
+		if len(got.Containers) != test.containers {
+			t.Errorf("Want clone %q with %d containers, got %d", test.in, test.containers, len(got.Containers))
+		}
+		got.Containers = nil
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Want clone settings %+v, got %+v", test.want, got)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cncd/pipeline/pipeline/backend"
+	"github.com/cncd/pipeline/pipeline/frontend"
+	"github.com/cncd/pipeline/pipeline/frontend/yaml"
+	libcompose "github.com/docker/libcompose/yaml"
+)
+
+// Cache defines the storage of the pipeline cache. The cache is stored in
+// the named volume of the repository on the agent, unless the url of the
+// cache api of the server is set.
+type Cache struct {
+	Image string
+	URL   string
+	Token string
+}
+
+// defaultCacheImage is the image of the steps that restore and save the
+// cache, which requires a shell, tar, sha256sum and wget.
+const defaultCacheImage = "alpine:3"
+
+// cacheDir is the mount path of the cache volume in the cache steps.
+const cacheDir = "/cache"
+
+// addCacheStage adds the stage that restores or saves the cache to the
+// configuration. The cache steps never fail the pipeline, and the cache
+// is not saved by pull requests, which may run untrusted code.
+func (c *Compiler) addCacheStage(config *backend.Config, cache *yaml.Cache, save bool) {
+	if c.local || cache.IsEmpty() {
+		return
+	}
+	if save && c.metadata.Curr.Event == frontend.EventPull {
+		return
+	}
+
+	alias, commands := "restore_cache", c.restoreCommands(cache)
+	if save {
+		alias, commands = "save_cache", c.saveCommands(cache)
+	}
+
+	container := &yaml.Container{
+		Name:         alias,
+		Image:        c.cache.Image,
+		Commands:     commands,
+		AllowFailure: true,
+	}
+	if container.Image == "" {
+		container.Image = defaultCacheImage
+	}
+	if c.cache.URL != "" {
+		container.Environment = libcompose.SliceorMap{
+			"DRONE_CACHE_URL":   c.cache.URL,
+			"DRONE_CACHE_TOKEN": c.cache.Token,
+		}
+	} else {
+		container.Volumes = libcompose.Volumes{
+			Volumes: []*libcompose.Volume{{
+				Source:      namedVolume(c.metadata.Repo.Name, "cache"),
+				Destination: cacheDir,
+			}},
+		}
+	}
+
+	name := fmt.Sprintf("%s_%s", c.prefix, alias)
+	stage := new(backend.Stage)
+	stage.Name = name
+	stage.Alias = alias
+	stage.Steps = append(stage.Steps, c.createProcess(name, container))
+	config.Stages = append(config.Stages, stage)
+}
+
+// restoreCommands returns the commands that extract the cache archive of
+// the cache key, or of the most recent cache matching a restore key, to
+// the workspace.
+func (c *Compiler) restoreCommands(cache *yaml.Cache) []string {
+	var restore []string
+	for _, key := range cache.RestoreKeys {
+		restore = append(restore, c.cacheKey(key))
+	}
+
+	if c.cache.URL != "" {
+		query := `key=$CACHE_KEY`
+		for i := range restore {
+			query += fmt.Sprintf(`&restore=$RESTORE_KEY_%d`, i)
+		}
+		commands := []string{fmt.Sprintf(`CACHE_KEY="%s"`, c.cacheKey(cache.Key))}
+		for i, key := range restore {
+			commands = append(commands, fmt.Sprintf(`RESTORE_KEY_%d="%s"`, i, key))
+		}
+		return append(commands,
+			fmt.Sprintf(`if wget -q -O /tmp/cache.tar --header "Authorization: Bearer $DRONE_CACHE_TOKEN" "$DRONE_CACHE_URL?%s"; then tar -xf /tmp/cache.tar && rm /tmp/cache.tar; else echo "cache not found"; fi`, query),
+		)
+	}
+
+	commands := []string{
+		fmt.Sprintf(`CACHE_FILE="%s/%s.tar"`, cacheDir, c.cacheKey(cache.Key)),
+	}
+	for _, key := range restore {
+		commands = append(commands,
+			fmt.Sprintf(`[ -f "$CACHE_FILE" ] || CACHE_FILE=$(ls -t "%s/%s"*.tar 2>/dev/null | head -n 1)`, cacheDir, key),
+		)
+	}
+	return append(commands,
+		`if [ -f "$CACHE_FILE" ]; then tar -xf "$CACHE_FILE" && echo "restored $CACHE_FILE"; else echo "cache not found"; fi`,
+	)
+}
+
+// saveCommands returns the commands that archive the cache paths, and save
+// the archive to the cache key unless the cache key exists.
+func (c *Compiler) saveCommands(cache *yaml.Cache) []string {
+	var paths []string
+	for _, path := range cache.Paths {
+		paths = append(paths, shellQuote(path))
+	}
+
+	if c.cache.URL != "" {
+		return []string{
+			fmt.Sprintf(`CACHE_KEY="%s"`, c.cacheKey(cache.Key)),
+			fmt.Sprintf(`tar -cf /tmp/cache.tar %s`, strings.Join(paths, " ")),
+			`wget -q -O /dev/null --header "Authorization: Bearer $DRONE_CACHE_TOKEN" --post-file /tmp/cache.tar "$DRONE_CACHE_URL?key=$CACHE_KEY"`,
+		}
+	}
+	return []string{
+		fmt.Sprintf(`CACHE_FILE="%s/%s.tar"`, cacheDir, c.cacheKey(cache.Key)),
+		fmt.Sprintf(`if [ -f "$CACHE_FILE" ]; then echo "cache exists"; else tar -cf "$CACHE_FILE.tmp" %s && mv "$CACHE_FILE.tmp" "$CACHE_FILE"; fi`, strings.Join(paths, " ")),
+	}
+}
+
+// cacheKey returns the cache key as a shell expression, which is safe to
+// expand in double quotes. The literal parts of the key are restricted to
+// the characters that are valid in a file name, and the checksums of the
+// files are computed by the cache step.
+func (c *Compiler) cacheKey(key string) string {
+	var sums []string
+	rendered, err := yaml.RenderKey(key, c.metadata, func(files ...string) string {
+		var quoted []string
+		for _, file := range files {
+			quoted = append(quoted, shellQuote(file))
+		}
+		sums = append(sums, fmt.Sprintf(`$(cat %s | sha256sum | cut -c1-16)`, strings.Join(quoted, " ")))
+		return fmt.Sprintf("\x00%d\x00", len(sums)-1)
+	})
+	if err != nil {
+		rendered = key
+	}
+
+	var buf strings.Builder
+	for i, part := range strings.Split(rendered, "\x00") {
+		if i%2 == 1 {
+			var n int
+			fmt.Sscanf(part, "%d", &n)
+			buf.WriteString(sums[n])
+			continue
+		}
+		buf.WriteString(strings.Map(func(r rune) rune {
+			switch {
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+				return r
+			default:
+				return '_'
+			}
+		}, part))
+	}
+	return buf.String()
+}
+
+// shellQuote returns the string quoted for the shell.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
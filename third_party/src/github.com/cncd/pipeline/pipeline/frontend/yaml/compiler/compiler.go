@@ -40,6 +40,8 @@ type Compiler struct {
 	registries []Registry
 	secrets    map[string]Secret
 	aliases    []string
+	declared   yaml.Volumes
+	cache      Cache
 }
 
 // New creates a new Compiler with options.
@@ -65,6 +67,24 @@ func (c *Compiler) Compile(conf *yaml.Config) *backend.Config {
 		Driver: "local",
 	})
 
+	// create the temporary volumes, which are removed with the default
+	// volume when the pipeline is complete.
+	c.declared = conf.Volumes
+	for _, volume := range conf.Volumes.Volumes {
+		if volume.Temp == nil {
+			continue
+		}
+		driver := volume.Driver
+		if volume.Temp.Medium == "memory" {
+			driver = "local"
+		}
+		config.Volumes = append(config.Volumes, &backend.Volume{
+			Name:       fmt.Sprintf("%s_%s", c.prefix, volume.Name),
+			Driver:     driver,
+			DriverOpts: tempVolumeOpts(volume),
+		})
+	}
+
 	// create a default network
 	config.Networks = append(config.Networks, &backend.Network{
 		Name:   fmt.Sprintf("%s_default", c.prefix),
@@ -80,13 +100,10 @@ func (c *Compiler) Compile(conf *yaml.Config) *backend.Config {
 		c.path = conf.Workspace.Path
 	}
 
-	// add default clone step
-	if c.local == false && len(conf.Clone.Containers) == 0 {
-		container := &yaml.Container{
-			Name:  "clone",
-			Image: "plugins/git:latest",
-			Vargs: map[string]interface{}{"depth": "0"},
-		}
+	// add default clone step, unless the repository is cloned by the
+	// pipeline steps.
+	if c.local == false && conf.Clone.Skip == false && len(conf.Clone.Containers) == 0 {
+		container := cloneContainer(&conf.Clone)
 		name := fmt.Sprintf("%s_clone", c.prefix)
 		step := c.createProcess(name, container)
 
@@ -96,7 +113,7 @@ func (c *Compiler) Compile(conf *yaml.Config) *backend.Config {
 		stage.Steps = append(stage.Steps, step)
 
 		config.Stages = append(config.Stages, stage)
-	} else if c.local == false {
+	} else if c.local == false && conf.Clone.Skip == false {
 		for i, container := range conf.Clone.Containers {
 			if !container.Constraints.Match(c.metadata) {
 				continue
@@ -113,6 +130,9 @@ func (c *Compiler) Compile(conf *yaml.Config) *backend.Config {
 		}
 	}
 
+	// add the step that restores the cache
+	c.addCacheStage(config, &conf.Cache, false)
+
 	// add services steps
 	if len(conf.Services.Containers) != 0 {
 		stage := new(backend.Stage)
@@ -140,10 +160,26 @@ func (c *Compiler) Compile(conf *yaml.Config) *backend.Config {
 		config.Stages = append(config.Stages, stage)
 	}
 
-	// add pipeline steps. 1 pipeline step per stage, at the moment
+	// add pipeline steps, followed by the step that saves the cache and
+	// the finally steps, which are executed regardless of the pipeline
+	// status.
+	c.addSteps(config, conf.Pipeline.Containers, "stage", "step", false)
+	c.addCacheStage(config, &conf.Cache, true)
+	c.addSteps(config, conf.Finally.Containers, "finally_stage", "finally", true)
+
+	return config
+}
+
+// addSteps adds the steps to the configuration. 1 pipeline step per stage,
+// at the moment. If any step declares its dependencies, all steps are added
+// to a single stage, which is executed as a graph. The finally steps run on
+// success, on failure and when the pipeline is killed, unless the step
+// declares a status constraint.
+func (c *Compiler) addSteps(config *backend.Config, containers []*yaml.Container, stageKind, stepKind string, finally bool) {
+	graph := isGraph(containers)
 	var stage *backend.Stage
 	var group string
-	for i, container := range conf.Pipeline.Containers {
+	for i, container := range containers {
 		//Skip if local and should not run local
 		if c.local && !container.Constraints.Local.Bool() {
 			continue
@@ -153,21 +189,78 @@ func (c *Compiler) Compile(conf *yaml.Config) *backend.Config {
 			continue
 		}
 
-		if stage == nil || group != container.Group || container.Group == "" {
+		if stage == nil || (!graph && (group != container.Group || container.Group == "")) {
 			group = container.Group
 
 			stage = new(backend.Stage)
-			stage.Name = fmt.Sprintf("%s_stage_%v", c.prefix, i)
+			stage.Name = fmt.Sprintf("%s_%s_%v", c.prefix, stageKind, i)
 			stage.Alias = container.Name
 			config.Stages = append(config.Stages, stage)
 		}
 
-		name := fmt.Sprintf("%s_step_%d", c.prefix, i)
+		name := fmt.Sprintf("%s_%s_%d", c.prefix, stepKind, i)
 		step := c.createProcess(name, container)
+		if finally && !hasStatus(container) {
+			step.OnSuccess = true
+			step.OnFailure = true
+			step.OnKilled = true
+		}
 		stage.Steps = append(stage.Steps, step)
 	}
+}
 
-	return config
+// cloneContainer returns the default clone container, configured with the
+// clone settings of the pipeline.
+func cloneContainer(clone *yaml.Clone) *yaml.Container {
+	container := &yaml.Container{
+		Name:  "clone",
+		Image: clone.Image,
+		Vargs: map[string]interface{}{},
+	}
+	if container.Image == "" {
+		container.Image = "plugins/git:latest"
+	}
+	// the depth is set only if declared in the yaml, so that the agent
+	// applies the default clone depth otherwise.
+	if clone.Depth != 0 {
+		container.Vargs["depth"] = clone.Depth
+	}
+	if clone.Tags {
+		container.Vargs["tags"] = true
+	}
+	if clone.Submodules {
+		container.Vargs["recursive"] = true
+	}
+	if clone.LFS {
+		container.Vargs["lfs"] = true
+	}
+	return container
+}
+
+// tempVolumeOpts returns the driver options of the temporary volume. The
+// volumes stored in memory are mounted as tmpfs, and the driver options
+// are ignored, since they could mount a host path instead.
+func tempVolumeOpts(volume *yaml.Volume) map[string]string {
+	if volume.Temp.Medium != "memory" {
+		return volume.DriverOpts
+	}
+	return map[string]string{"type": "tmpfs", "device": "tmpfs"}
+}
+
+// hasStatus returns true if the container declares a status constraint.
+func hasStatus(container *yaml.Container) bool {
+	return len(container.Constraints.Status.Include)+
+		len(container.Constraints.Status.Exclude) != 0
+}
+
+// isGraph returns true if any of the containers declares its dependencies.
+func isGraph(containers []*yaml.Container) bool {
+	for _, container := range containers {
+		if len(container.DependsOn) != 0 {
+			return true
+		}
+	}
+	return false
 }
 
 // func setupNetwork(step *backend.Step, network *libcompose.Network) {
@@ -0,0 +1,224 @@
+package compiler
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cncd/pipeline/pipeline/backend"
+	"github.com/cncd/pipeline/pipeline/frontend"
+	"github.com/cncd/pipeline/pipeline/frontend/yaml"
+)
+
+func TestCompileFinally(t *testing.T) {
+	config, err := yaml.ParseString(`
+pipeline:
+  build:
+    image: golang
+finally:
+  cleanup:
+    image: alpine
+  notify:
+    image: plugins/slack
+    when:
+      status: [ failure ]
+  report:
+    image: alpine
+    when:
+      status: [ success, killed ]
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := New(WithPrefix("test")).Compile(config)
+
+	tests := []struct {
+		stage   string
+		success bool
+		failure bool
+		killed  bool
+	}{
+		{stage: "test_stage_0", success: true},
+		{stage: "test_finally_stage_0", success: true, failure: true, killed: true},
+		{stage: "test_finally_stage_1", failure: true},
+		{stage: "test_finally_stage_2", success: true, killed: true},
+	}
+	stages := map[string]*backend.Step{}
+	for _, stage := range spec.Stages {
+		stages[stage.Name] = stage.Steps[0]
+	}
+	for _, test := range tests {
+		step, ok := stages[test.stage]
+		if !ok {
+			t.Errorf("Want stage %s compiled", test.stage)
+			continue
+		}
+		if step.OnSuccess != test.success || step.OnFailure != test.failure || step.OnKilled != test.killed {
+			t.Errorf("Want stage %s on success %v, on failure %v and on killed %v, got %v, %v and %v",
+				test.stage, test.success, test.failure, test.killed, step.OnSuccess, step.OnFailure, step.OnKilled)
+		}
+	}
+}
+
+func TestHealthCheckDefaults(t *testing.T) {
+	if check := healthCheck(yaml.HealthCheck{}); check != nil {
+		t.Errorf("Want no readiness probe, got %+v", check)
+	}
+	check := healthCheck(yaml.HealthCheck{TCP: 5432, Retries: 5})
+	if check == nil {
+		t.Fatalf("Want readiness probe")
+	}
+	if check.Interval != 2*time.Second || check.Timeout != 5*time.Second || check.Retries != 5 {
+		t.Errorf("Want default interval and timeout, got %+v", check)
+	}
+}
+
+func TestCompileDetached(t *testing.T) {
+	config, err := yaml.ParseString(`
+pipeline:
+  server:
+    image: golang
+    detach: true
+    commands: [ go run main.go ]
+  test:
+    image: golang
+    commands: [ go test ./... ]
+services:
+  database:
+    image: postgres
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := New(WithPrefix("test"), WithWorkspace("/go", "src/github.com/octocat/hello-world")).Compile(config)
+
+	tests := []struct {
+		step    string
+		workdir string
+		aliases []string
+	}{
+		{step: "test_step_0", workdir: "/go/src/github.com/octocat/hello-world", aliases: []string{"database", "server"}},
+		{step: "test_step_1", workdir: "/go/src/github.com/octocat/hello-world", aliases: []string{"database"}},
+		{step: "test_services_0", aliases: []string{"database"}},
+	}
+	steps := map[string]*backend.Step{}
+	for _, stage := range spec.Stages {
+		for _, step := range stage.Steps {
+			steps[step.Name] = step
+		}
+	}
+	for _, test := range tests {
+		step, ok := steps[test.step]
+		if !ok {
+			t.Errorf("Want step %s compiled", test.step)
+			continue
+		}
+		if step.WorkingDir != test.workdir {
+			t.Errorf("Want step %s working dir %q, got %q", test.step, test.workdir, step.WorkingDir)
+		}
+		if got := strings.Join(step.Networks[0].Aliases, ","); got != strings.Join(test.aliases, ",") {
+			t.Errorf("Want step %s aliases %v, got %s", test.step, test.aliases, got)
+		}
+	}
+}
+
+func TestCompileVolumes(t *testing.T) {
+	config, err := yaml.ParseString(`
+pipeline:
+  build:
+    image: golang
+    volumes: [ "docker:/var/run/docker.sock", "cache:/cache", "gocache:/root/.cache", "/tmp:/tmp" ]
+volumes:
+  docker:
+    host: { path: /var/run/docker.sock }
+  cache:
+    temp: { medium: memory }
+    driver: other
+    driver_opts: { type: none, o: bind, device: / }
+  gocache: {}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := New(
+		WithPrefix("test"),
+		WithMetadata(frontend.Metadata{Repo: frontend.Repo{Name: "octocat/hello-world"}}),
+	).Compile(config)
+
+	var temp *backend.Volume
+	for _, volume := range spec.Volumes {
+		if volume.Name == "test_cache" {
+			temp = volume
+		}
+	}
+	if temp == nil || temp.Driver != "local" || !reflect.DeepEqual(temp.DriverOpts, map[string]string{"type": "tmpfs", "device": "tmpfs"}) {
+		t.Errorf("Want temporary volume stored in memory, got %+v", temp)
+	}
+
+	var step *backend.Step
+	for _, stage := range spec.Stages {
+		if stage.Name == "test_stage_0" {
+			step = stage.Steps[0]
+		}
+	}
+	if step == nil {
+		t.Fatalf("Want step compiled")
+	}
+	mounts := strings.Join(step.Volumes, ",")
+	for _, want := range []string{
+		"/var/run/docker.sock:/var/run/docker.sock",
+		"test_cache:/cache",
+		"drone_octocat_hello-world_gocache:/root/.cache",
+		"/tmp:/tmp",
+	} {
+		if !strings.Contains(mounts, want) {
+			t.Errorf("Want volume %s mounted, got %s", want, mounts)
+		}
+	}
+}
+
+func TestCompileClone(t *testing.T) {
+	tests := []struct {
+		clone   string
+		image   string
+		environ map[string]string
+	}{
+		{image: "plugins/git:latest", environ: map[string]string{"PLUGIN_DEPTH": ""}},
+		{clone: "clone: {depth: 50, tags: true, submodules: true, lfs: true}", image: "plugins/git:latest", environ: map[string]string{
+			"PLUGIN_DEPTH":     "50",
+			"PLUGIN_TAGS":      "true",
+			"PLUGIN_RECURSIVE": "true",
+			"PLUGIN_LFS":       "true",
+		}},
+		{clone: "clone: {image: \"plugins/git:next\"}", image: "plugins/git:next"},
+		{clone: "clone: {skip: true}"},
+	}
+	for _, test := range tests {
+		config, err := yaml.ParseString(test.clone + "\npipeline:\n  build:\n    image: golang\n")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var step *backend.Step
+		for _, stage := range New(WithPrefix("test")).Compile(config).Stages {
+			if stage.Name == "test_clone" {
+				step = stage.Steps[0]
+			}
+		}
+		switch {
+		case test.image == "" && step != nil:
+			t.Errorf("Want clone %q skipped", test.clone)
+		case test.image == "":
+		case step == nil:
+			t.Errorf("Want clone %q compiled", test.clone)
+		case step.Image != test.image:
+			t.Errorf("Want clone %q image %s, got %s", test.clone, test.image, step.Image)
+		default:
+			for k, v := range test.environ {
+				if step.Environment[k] != v {
+					t.Errorf("Want clone %q environment %s=%s, got %q", test.clone, k, v, step.Environment[k])
+				}
+			}
+		}
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/cncd/pipeline/pipeline/backend"
 	"github.com/cncd/pipeline/pipeline/frontend/yaml"
+	libcompose "github.com/docker/libcompose/yaml"
 )
 
 func (c *Compiler) createProcess(name string, container *yaml.Container) *backend.Step {
@@ -22,10 +24,17 @@ func (c *Compiler) createProcess(name string, container *yaml.Container) *backen
 		// network    = container.Network
 	)
 
+	// detached steps are reachable by name, like services, so that the
+	// steps that follow can connect to them.
+	aliases := c.aliases
+	if container.Detached && !contains(aliases, container.Name) {
+		aliases = append(aliases[:len(aliases):len(aliases)], container.Name)
+	}
+
 	networks := []backend.Conn{
 		backend.Conn{
 			Name:    fmt.Sprintf("%s_default", c.prefix),
-			Aliases: c.aliases,
+			Aliases: aliases,
 		},
 	}
 	for _, network := range c.networks {
@@ -40,7 +49,7 @@ func (c *Compiler) createProcess(name string, container *yaml.Container) *backen
 	}
 	volumes = append(volumes, c.volumes...)
 	for _, volume := range container.Volumes.Volumes {
-		volumes = append(volumes, volume.String())
+		volumes = append(volumes, c.volumeString(volume))
 	}
 	// if network == "" {
 	// 	network = fmt.Sprintf("%s_default", c.prefix)
@@ -69,7 +78,9 @@ func (c *Compiler) createProcess(name string, container *yaml.Container) *backen
 	// TODO: This is here for backward compatibility and will eventually be removed.
 	environment["DRONE_WORKSPACE"] = path.Join(c.base, c.path)
 
-	if !isService(container) {
+	// detached steps that run commands or plugins are executed in the
+	// workspace, like other steps.
+	if isShell(container) || isPlugin(container) {
 		workingdir = path.Join(c.base, c.path)
 	}
 
@@ -77,6 +88,14 @@ func (c *Compiler) createProcess(name string, container *yaml.Container) *backen
 		detached = true
 	}
 
+	// the variables that the step writes to the output file are passed
+	// to the steps that follow.
+	var output string
+	if !detached {
+		output = path.Join(c.base, ".drone_output_"+name)
+		environment["DRONE_OUTPUT"] = output
+	}
+
 	if isPlugin(container) {
 		paramsToEnv(container.Vargs, environment)
 
@@ -116,6 +135,14 @@ func (c *Compiler) createProcess(name string, container *yaml.Container) *backen
 		}
 	}
 
+	var artifacts []string
+	for _, artifact := range container.Artifacts {
+		if !path.IsAbs(artifact) {
+			artifact = path.Join(c.base, c.path, artifact)
+		}
+		artifacts = append(artifacts, artifact)
+	}
+
 	return &backend.Step{
 		Name:         name,
 		Alias:        container.Name,
@@ -135,17 +162,61 @@ func (c *Compiler) createProcess(name string, container *yaml.Container) *backen
 		DNS:          container.DNS,
 		DNSSearch:    container.DNSSearch,
 		MemSwapLimit: int64(container.MemSwapLimit),
-		MemLimit:     int64(container.MemLimit),
+		MemLimit:     memLimit(container),
+		MemReserve:   int64(container.Resources.Requests.Memory),
 		ShmSize:      int64(container.ShmSize),
 		CPUQuota:     int64(container.CPUQuota),
-		CPUShares:    int64(container.CPUShares),
+		CPUShares:    cpuShares(container),
 		CPUSet:       container.CPUSet,
+		CPULimit:     int64(container.Resources.Limits.CPU),
 		AuthConfig:   authConfig,
+		Artifacts:    artifacts,
+		Output:       output,
+		Retries:      retries(container.Retries),
+		RetryBackoff: container.Retries.Backoff,
+		RetryWhen:    container.Retries.When,
+		Timeout:      container.Timeout.Duration(),
+		HealthCheck:  healthCheck(container.HealthCheck),
+		DependsOn:    container.DependsOn,
+		AllowFailure: container.IgnoreFailure(),
 		OnSuccess:    container.Constraints.Status.Match("success"),
-		OnFailure: (len(container.Constraints.Status.Include)+
-			len(container.Constraints.Status.Exclude) != 0) &&
-			container.Constraints.Status.Match("failure"),
+		OnFailure:    hasStatus(container) && container.Constraints.Status.Match("failure"),
+		OnKilled:     container.Constraints.Status.Includes("killed"),
+	}
+}
+
+// helper function returns the memory limit of the container, which is the
+// resources limit, or the mem_limit of the container.
+func memLimit(container *yaml.Container) int64 {
+	if limit := container.Resources.Limits.Memory; limit != 0 {
+		return int64(limit)
+	}
+	return int64(container.MemLimit)
+}
+
+// helper function returns the cpu shares of the container. The cpu request
+// is converted to shares, where one cpu is 1024 shares, and the minimum
+// is 2 shares.
+func cpuShares(container *yaml.Container) int64 {
+	if container.CPUShares != 0 {
+		return int64(container.CPUShares)
+	}
+	if request := int64(container.Resources.Requests.CPU); request != 0 {
+		if shares := request * 1024 / 1000; shares > 2 {
+			return shares
+		}
+		return 2
+	}
+	return 0
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
 	}
+	return false
 }
 
 func isPlugin(c *yaml.Container) bool {
@@ -159,3 +230,72 @@ func isShell(c *yaml.Container) bool {
 func isService(c *yaml.Container) bool {
 	return c.Detached || (isPlugin(c) == false && isShell(c) == false)
 }
+
+// volumeString returns the volume mount of the container. The volumes that
+// are defined in the volumes section are mapped to the host path, to the
+// temporary volume of the pipeline, or to the named volume of the
+// repository.
+func (c *Compiler) volumeString(volume *libcompose.Volume) string {
+	declared := c.declared.Find(volume.Source)
+	if declared == nil {
+		return volume.String()
+	}
+	mount := *volume
+	switch {
+	case declared.Host != nil:
+		mount.Source = declared.Host.Path
+	case declared.Temp != nil:
+		mount.Source = fmt.Sprintf("%s_%s", c.prefix, declared.Name)
+	default:
+		mount.Source = namedVolume(c.metadata.Repo.Name, declared.Name)
+	}
+	return mount.String()
+}
+
+// namedVolume returns the name of the named volume of the repository, which
+// is stable between builds.
+func namedVolume(repo, name string) string {
+	clean := func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}
+	return fmt.Sprintf("drone_%s_%s", strings.Map(clean, repo), strings.Map(clean, name))
+}
+
+// healthCheck returns the readiness probe of the container, or nil if the
+// container has no readiness probe. The probe is retried every two seconds
+// for a minute by default, so that slow services have time to start.
+func healthCheck(h yaml.HealthCheck) *backend.HealthCheck {
+	if h.IsEmpty() {
+		return nil
+	}
+	check := &backend.HealthCheck{
+		Test:     h.Test(),
+		Interval: h.Interval,
+		Timeout:  h.Timeout,
+		Retries:  h.Retries,
+	}
+	if check.Interval == 0 {
+		check.Interval = time.Second * 2
+	}
+	if check.Timeout == 0 {
+		check.Timeout = time.Second * 5
+	}
+	if check.Retries == 0 {
+		check.Retries = 30
+	}
+	return check
+}
+
+// retries returns the number of times the container is retried after the
+// first attempt.
+func retries(r yaml.Retries) int {
+	if r.Attempts <= 1 {
+		return 0
+	}
+	return r.Attempts - 1
+}
@@ -49,6 +49,14 @@ func WithSecret(secrets ...Secret) Option {
 	}
 }
 
+// WithCache configures the compiler with the storage of the pipeline
+// cache. The cache is stored in a named volume of the agent by default.
+func WithCache(cache Cache) Option {
+	return func(compiler *Compiler) {
+		compiler.cache = cache
+	}
+}
+
 // WithMetadata configutes the compiler with the repostiory, build
 // and system metadata. The metadata is used to remove steps from
 // the compiled pipeline configuration that should be skipped. The
@@ -12,15 +12,18 @@ import (
 type (
 	// Config defines a pipeline configuration.
 	Config struct {
-		Platform  string
+		Platform  Platform
 		Branches  Constraint
 		Workspace Workspace
-		Clone     Containers
+		Clone     Clone
 		Pipeline  Containers
+		Finally   Containers
 		Services  Containers
 		Networks  Networks
 		Volumes   Volumes
+		Cache     Cache
 		Labels    libcompose.SliceorMap
+		Node      NodeSelector
 	}
 
 	// Workspace defines a pipeline workspace.
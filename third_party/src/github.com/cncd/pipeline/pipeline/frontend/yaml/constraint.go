@@ -2,6 +2,7 @@ package yaml
 
 import (
 	"path/filepath"
+	"strings"
 
 	"github.com/cncd/pipeline/pipeline/frontend"
 	libcompose "github.com/docker/libcompose/yaml"
@@ -17,12 +18,16 @@ type (
 		Environment Constraint
 		Event       Constraint
 		Branch      Constraint
+		Ref         Constraint
 		Status      Constraint
 		Matrix      ConstraintMap
+		Paths       Constraint
 		Local       types.BoolTrue
 	}
 
-	// Constraint defines a runtime constraint.
+	// Constraint defines a runtime constraint. Patterns may use brace
+	// expansion, such as release/{1.0,2.0}, and include patterns prefixed
+	// with ! are exclude patterns.
 	Constraint struct {
 		Include []string
 		Exclude []string
@@ -40,47 +45,187 @@ type (
 func (c *Constraints) Match(metadata frontend.Metadata) bool {
 	return c.Platform.Match(metadata.Sys.Arch) &&
 		c.Environment.Match(metadata.Curr.Target) &&
-		c.Event.Match(metadata.Curr.Event) &&
+		c.matchEvent(metadata.Curr.Event) &&
 		c.Branch.Match(metadata.Curr.Commit.Branch) &&
+		c.Ref.MatchRef(metadata.Curr.Commit.Ref) &&
 		c.Repo.Match(metadata.Repo.Name) &&
-		c.Matrix.Match(metadata.Job.Matrix)
+		c.Instance.Match(metadata.Sys.Host) &&
+		c.Matrix.Match(metadata.Job.Matrix) &&
+		c.Paths.MatchPaths(metadata.Curr.Commit.Changed)
+}
+
+// matchEvent returns true if the event matches the event constraint. A
+// rollback re-runs the deployment pipeline, and therefore matches the
+// deployment event unless rollbacks are explicitly excluded.
+func (c *Constraints) matchEvent(event string) bool {
+	if c.Event.Match(event) {
+		return true
+	}
+	return event == frontend.EventRollback &&
+		!c.Event.Excludes(event) &&
+		c.Event.Match(frontend.EventDeploy)
 }
 
 // Match returns true if the string matches the include patterns and does not
 // match any of the exclude patterns.
 func (c *Constraint) Match(v string) bool {
-	if c.Excludes(v) {
+	return c.match(v, matchPattern)
+}
+
+// MatchRef returns true if the git reference matches the include patterns
+// and does not match any of the exclude patterns. A pattern matches the
+// reference and the references below it, such that refs/pull/* matches
+// refs/pull/1/head.
+func (c *Constraint) MatchRef(ref string) bool {
+	return c.match(ref, matchRef)
+}
+
+// Includes returns true if the string matches the include patterns.
+func (c *Constraint) Includes(v string) bool {
+	return matchAny(c.Include, v, matchPattern)
+}
+
+// Excludes returns true if the string matches the exclude patterns.
+func (c *Constraint) Excludes(v string) bool {
+	return matchAny(c.Exclude, v, matchPattern)
+}
+
+// helper function returns true if the string matches the constraint using
+// the pattern matching function.
+func (c *Constraint) match(v string, fn func(pattern, v string) bool) bool {
+	if matchAny(c.Exclude, v, fn) {
 		return false
 	}
-	if c.Includes(v) {
-		return true
+	return len(c.Include) == 0 || matchAny(c.Include, v, fn)
+}
+
+// helper function returns true if the string matches any of the patterns.
+func matchAny(patterns []string, v string, fn func(pattern, v string) bool) bool {
+	for _, pattern := range patterns {
+		if fn(pattern, v) {
+			return true
+		}
 	}
-	if len(c.Include) == 0 {
-		return true
+	return false
+}
+
+// matchPattern returns true if the string matches the pattern, or any of
+// the patterns of the brace expansion of the pattern.
+func matchPattern(pattern, v string) bool {
+	for _, alt := range expandBraces(pattern) {
+		if ok, _ := filepath.Match(alt, v); ok {
+			return true
+		}
 	}
 	return false
 }
 
-// Includes returns true if the string matches the include patterns.
-func (c *Constraint) Includes(v string) bool {
-	for _, pattern := range c.Include {
-		if ok, _ := filepath.Match(pattern, v); ok {
+// matchRef returns true if the git reference, or a parent of the git
+// reference, matches the pattern.
+func matchRef(pattern, ref string) bool {
+	parts := strings.Split(ref, "/")
+	for i := len(parts); i > 0; i-- {
+		if matchPattern(pattern, strings.Join(parts[:i], "/")) {
 			return true
 		}
 	}
 	return false
 }
 
-// Excludes returns true if the string matches the exclude patterns.
-func (c *Constraint) Excludes(v string) bool {
-	for _, pattern := range c.Exclude {
-		if ok, _ := filepath.Match(pattern, v); ok {
+// expandBraces returns the patterns of the brace expansion of the pattern,
+// such that {a,b}/{c,d} expands to a/c, a/d, b/c and b/d. Braces that are
+// not closed are matched literally.
+func expandBraces(pattern string) []string {
+	start := strings.IndexByte(pattern, '{')
+	if start == -1 {
+		return []string{pattern}
+	}
+	var alts []string
+	depth, last, end := 0, start+1, -1
+	for i := start; i < len(pattern) && end == -1; i++ {
+		switch pattern[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				end = i
+			}
+		case ',':
+			if depth == 1 {
+				alts = append(alts, pattern[last:i])
+				last = i + 1
+			}
+		}
+	}
+	if end == -1 {
+		return []string{pattern}
+	}
+	alts = append(alts, pattern[last:end])
+
+	var patterns []string
+	for _, alt := range alts {
+		patterns = append(patterns, expandBraces(pattern[:start]+alt+pattern[end+1:])...)
+	}
+	return patterns
+}
+
+// MatchPaths returns true if any of the changed files matches the include
+// patterns and does not match the exclude patterns. Patterns may use ** to
+// match any number of directories. If the changed files are unknown the
+// constraint always matches.
+func (c *Constraint) MatchPaths(paths []string) bool {
+	if len(c.Include) == 0 && len(c.Exclude) == 0 {
+		return true
+	}
+	if paths == nil {
+		return true
+	}
+	for _, path := range paths {
+		if matchGlobs(c.Exclude, path) {
+			continue
+		}
+		if len(c.Include) == 0 || matchGlobs(c.Include, path) {
 			return true
 		}
 	}
 	return false
 }
 
+func matchGlobs(patterns []string, path string) bool {
+	for _, pattern := range patterns {
+		for _, alt := range expandBraces(pattern) {
+			if matchGlob(strings.Split(alt, "/"), strings.Split(path, "/")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchGlob matches the path segments against the pattern segments, where
+// a ** segment matches zero or more path segments.
+func matchGlob(pattern, path []string) bool {
+	for len(pattern) != 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(path); i++ {
+				if matchGlob(pattern[1:], path[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(path) == 0 {
+			return false
+		}
+		if ok, _ := filepath.Match(pattern[0], path[0]); !ok {
+			return false
+		}
+		pattern, path = pattern[1:], path[1:]
+	}
+	return len(path) == 0
+}
+
 // UnmarshalYAML unmarshals the constraint.
 func (c *Constraint) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	var out1 = struct {
@@ -93,11 +238,15 @@ func (c *Constraint) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	unmarshal(&out1)
 	unmarshal(&out2)
 
+	c.Include = nil
 	c.Exclude = out1.Exclude
-	c.Include = append(
-		out1.Include,
-		out2...,
-	)
+	for _, pattern := range append(out1.Include, out2...) {
+		if strings.HasPrefix(pattern, "!") {
+			c.Exclude = append(c.Exclude, strings.TrimPrefix(pattern, "!"))
+			continue
+		}
+		c.Include = append(c.Include, pattern)
+	}
 	return nil
 }
 
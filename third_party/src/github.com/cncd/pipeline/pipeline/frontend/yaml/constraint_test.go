@@ -0,0 +1,95 @@
+package yaml
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cncd/pipeline/pipeline/frontend"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConstraintMatch(t *testing.T) {
+	tests := []struct {
+		conf string
+		with string
+		want bool
+	}{
+		{conf: "master", with: "master", want: true},
+		{conf: "release/{1.0,2.0}", with: "release/2.0", want: true},
+		{conf: "release/{1.0,2.0}", with: "release/3.0", want: false},
+		{conf: "\"{feature,fix}/*\"", with: "fix/login", want: true},
+		{conf: "[ \"!develop\" ]", with: "master", want: true},
+		{conf: "[ \"!develop\" ]", with: "develop", want: false},
+		{conf: "[ \"release/*\", \"!release/{0.1,0.2}\" ]", with: "release/0.2", want: false},
+		{conf: "[ \"release/*\", \"!release/{0.1,0.2}\" ]", with: "release/1.0", want: true},
+		{conf: "{ include: [ \"release/*\" ], exclude: [ release/1.0 ] }", with: "release/1.0", want: false},
+		{conf: "release/{1.0", with: "release/{1.0", want: true},
+	}
+	for _, test := range tests {
+		c := Constraint{}
+		if err := yaml.Unmarshal([]byte(test.conf), &c); err != nil {
+			t.Errorf("Want constraint %q parsed, got %s", test.conf, err)
+			continue
+		}
+		if got := c.Match(test.with); got != test.want {
+			t.Errorf("Want constraint %q matching %q to be %v", test.conf, test.with, test.want)
+		}
+	}
+}
+
+func TestConstraintMatchRef(t *testing.T) {
+	tests := []struct {
+		conf string
+		with string
+		want bool
+	}{
+		{conf: "refs/tags/*", with: "refs/tags/v1.0.0", want: true},
+		{conf: "refs/tags/*", with: "refs/heads/master", want: false},
+		{conf: "refs/pull/*", with: "refs/pull/42/head", want: true},
+		{conf: "refs/heads/{master,develop}", with: "refs/heads/develop", want: true},
+		{conf: "[ \"refs/heads/*\", \"!refs/heads/wip/*\" ]", with: "refs/heads/wip/login", want: false},
+	}
+	for _, test := range tests {
+		c := Constraint{}
+		if err := yaml.Unmarshal([]byte(test.conf), &c); err != nil {
+			t.Errorf("Want constraint %q parsed, got %s", test.conf, err)
+			continue
+		}
+		if got := c.MatchRef(test.with); got != test.want {
+			t.Errorf("Want ref constraint %q matching %q to be %v", test.conf, test.with, test.want)
+		}
+	}
+}
+
+func TestExpandBraces(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{pattern: "master", want: []string{"master"}},
+		{pattern: "{a,b}/{c,d}", want: []string{"a/c", "a/d", "b/c", "b/d"}},
+		{pattern: "v{1,2{.0,.1}}", want: []string{"v1", "v2.0", "v2.1"}},
+		{pattern: "{a,b", want: []string{"{a,b"}},
+	}
+	for _, test := range tests {
+		if got := expandBraces(test.pattern); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Want pattern %q expanded to %v, got %v", test.pattern, test.want, got)
+		}
+	}
+}
+
+func TestConstraintsMatchInstance(t *testing.T) {
+	c := Constraints{}
+	if err := yaml.Unmarshal([]byte("{ instance: ci.example.com, ref: \"refs/tags/*\" }"), &c); err != nil {
+		t.Fatal(err)
+	}
+	metadata := frontend.Metadata{Sys: frontend.System{Host: "ci.example.com"}}
+	metadata.Curr.Commit.Ref = "refs/tags/v1.0.0"
+	if !c.Match(metadata) {
+		t.Errorf("Want constraints matching the instance and ref")
+	}
+	metadata.Sys.Host = "staging.example.com"
+	if c.Match(metadata) {
+		t.Errorf("Want constraints not matching another instance")
+	}
+}
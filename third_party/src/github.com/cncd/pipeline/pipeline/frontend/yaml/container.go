@@ -3,6 +3,7 @@ package yaml
 import (
 	"fmt"
 
+	"github.com/cncd/pipeline/pipeline/frontend/yaml/types"
 	libcompose "github.com/docker/libcompose/yaml"
 	"gopkg.in/yaml.v2"
 )
@@ -22,6 +23,8 @@ type (
 
 	// Container defines a container.
 	Container struct {
+		AllowFailure  bool                      `yaml:"allow_failure,omitempty"`
+		Artifacts     libcompose.Stringorslice  `yaml:"artifacts,omitempty"`
 		AuthConfig    AuthConfig                `yaml:"auth_config,omitempty"`
 		CapAdd        []string                  `yaml:"cap_add,omitempty"`
 		CapDrop       []string                  `yaml:"cap_drop,omitempty"`
@@ -30,6 +33,7 @@ type (
 		CPUQuota      libcompose.StringorInt    `yaml:"cpu_quota,omitempty"`
 		CPUSet        string                    `yaml:"cpuset,omitempty"`
 		CPUShares     libcompose.StringorInt    `yaml:"cpu_shares,omitempty"`
+		DependsOn     libcompose.Stringorslice  `yaml:"depends_on,omitempty"`
 		Detached      bool                      `yaml:"detach,omitempty"`
 		Devices       []string                  `yaml:"devices,omitempty"`
 		DNS           libcompose.Stringorslice  `yaml:"dns,omitempty"`
@@ -37,7 +41,9 @@ type (
 		Entrypoint    libcompose.Command        `yaml:"entrypoint,omitempty"`
 		Environment   libcompose.SliceorMap     `yaml:"environment,omitempty"`
 		ExtraHosts    []string                  `yaml:"extra_hosts,omitempty"`
+		Failure       string                    `yaml:"failure,omitempty"`
 		Group         string                    `yaml:"group,omitempty"`
+		HealthCheck   HealthCheck               `yaml:"healthcheck,omitempty"`
 		Image         string                    `yaml:"image,omitempty"`
 		Isolation     string                    `yaml:"isolation,omitempty"`
 		Labels        libcompose.SliceorMap     `yaml:"labels,omitempty"`
@@ -49,7 +55,10 @@ type (
 		Networks      libcompose.Networks       `yaml:"networks,omitempty"`
 		Privileged    bool                      `yaml:"privileged,omitempty"`
 		Pull          bool                      `yaml:"pull,omitempty"`
+		Retries       Retries                   `yaml:"retries,omitempty"`
+		Resources     Resources                 `yaml:"resources,omitempty"`
 		ShmSize       libcompose.MemStringorInt `yaml:"shm_size,omitempty"`
+		Timeout       types.Duration            `yaml:"timeout,omitempty"`
 		Ulimits       libcompose.Ulimits        `yaml:"ulimits,omitempty"`
 		Volumes       libcompose.Volumes        `yaml:"volumes,omitempty"`
 		Secrets       Secrets                   `yaml:"secrets,omitempty"`
@@ -58,8 +67,33 @@ type (
 	}
 )
 
+// Failure policies of a container.
+const (
+	FailureFail   = "fail"
+	FailureIgnore = "ignore"
+)
+
+// IgnoreFailure returns true if the failure of the container does not fail
+// the pipeline.
+func (c *Container) IgnoreFailure() bool {
+	return c.AllowFailure || c.Failure == FailureIgnore
+}
+
 // UnmarshalYAML implements the Unmarshaller interface.
 func (c *Containers) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// the containers may be a list of named containers, which preserves
+	// the order of generated configurations, such as jsonnet.
+	var list []*Container
+	if err := unmarshal(&list); err == nil {
+		for i, container := range list {
+			if container.Name == "" {
+				return fmt.Errorf("Invalid container %d, expected a name", i)
+			}
+		}
+		c.Containers = list
+		return nil
+	}
+
 	slice := yaml.MapSlice{}
 	if err := unmarshal(&slice); err != nil {
 		return err
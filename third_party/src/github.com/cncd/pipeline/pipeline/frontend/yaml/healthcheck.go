@@ -0,0 +1,75 @@
+package yaml
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	libcompose "github.com/docker/libcompose/yaml"
+)
+
+type (
+	// HealthCheck defines the readiness probe of a service, which is a
+	// tcp port, an http path or a command executed in the container.
+	HealthCheck struct {
+		TCP      int                      `yaml:"tcp,omitempty"`
+		HTTP     *HTTPProbe               `yaml:"http,omitempty"`
+		Command  libcompose.Stringorslice `yaml:"command,omitempty"`
+		Interval time.Duration            `yaml:"interval,omitempty"`
+		Timeout  time.Duration            `yaml:"timeout,omitempty"`
+		Retries  int                      `yaml:"retries,omitempty"`
+	}
+
+	// HTTPProbe defines an http readiness probe, which succeeds if the
+	// path returns a successful status code.
+	HTTPProbe struct {
+		Port int    `yaml:"port,omitempty"`
+		Path string `yaml:"path,omitempty"`
+	}
+)
+
+// IsEmpty returns true if the readiness probe is not defined.
+func (h *HealthCheck) IsEmpty() bool {
+	return h.TCP == 0 && h.HTTP == nil && len(h.Command) == 0
+}
+
+// Validate returns an error if the readiness probe is invalid.
+func (h *HealthCheck) Validate() error {
+	var probes int
+	if h.TCP != 0 {
+		probes++
+		if h.TCP < 0 || h.TCP > 65535 {
+			return fmt.Errorf("Invalid healthcheck tcp port %d", h.TCP)
+		}
+	}
+	if h.HTTP != nil {
+		probes++
+		if h.HTTP.Port <= 0 || h.HTTP.Port > 65535 {
+			return fmt.Errorf("Invalid healthcheck http port %d", h.HTTP.Port)
+		}
+	}
+	if len(h.Command) != 0 {
+		probes++
+	}
+	if probes > 1 {
+		return fmt.Errorf("Invalid healthcheck, expected one of tcp, http or command")
+	}
+	if h.Interval < 0 || h.Timeout < 0 || h.Retries < 0 {
+		return fmt.Errorf("Invalid healthcheck interval, timeout or retries")
+	}
+	return nil
+}
+
+// Test returns the shell command of the readiness probe. The tcp and http
+// probes use the tools that are commonly available in the service images.
+func (h *HealthCheck) Test() string {
+	switch {
+	case h.TCP != 0:
+		return fmt.Sprintf("nc -z 127.0.0.1 %d || bash -c 'echo > /dev/tcp/127.0.0.1/%d'", h.TCP, h.TCP)
+	case h.HTTP != nil:
+		url := fmt.Sprintf("http://127.0.0.1:%d/%s", h.HTTP.Port, strings.TrimPrefix(h.HTTP.Path, "/"))
+		return fmt.Sprintf("wget -q -O /dev/null %s || curl -fsS -o /dev/null %s", url, url)
+	default:
+		return strings.Join(h.Command, " && ")
+	}
+}
@@ -0,0 +1,47 @@
+package yaml
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestHealthCheck(t *testing.T) {
+	tests := []struct {
+		in   string
+		test string
+		err  bool
+	}{
+		{in: "{}"},
+		{in: "{tcp: 5432}", test: "nc -z 127.0.0.1 5432 || bash -c 'echo > /dev/tcp/127.0.0.1/5432'"},
+		{in: "{http: {port: 8080, path: /healthz}}", test: "wget -q -O /dev/null http://127.0.0.1:8080/healthz || curl -fsS -o /dev/null http://127.0.0.1:8080/healthz"},
+		{in: "{command: [pg_isready, psql -c 'select 1']}", test: "pg_isready && psql -c 'select 1'"},
+		{in: "{tcp: 70000}", err: true},
+		{in: "{http: {path: /healthz}}", err: true},
+		{in: "{tcp: 5432, command: pg_isready}", err: true},
+		{in: "{tcp: 5432, retries: -1}", err: true},
+	}
+	for _, test := range tests {
+		got := HealthCheck{}
+		if err := yaml.Unmarshal([]byte(test.in), &got); err != nil {
+			t.Errorf("Want healthcheck %q parsed, got %s", test.in, err)
+			continue
+		}
+		err := got.Validate()
+		if test.err {
+			if err == nil {
+				t.Errorf("Want healthcheck %q invalid", test.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Want healthcheck %q valid, got %s", test.in, err)
+		}
+		if got.IsEmpty() != (test.test == "") {
+			t.Errorf("Want healthcheck %q empty %v", test.in, test.test == "")
+		}
+		if test.test != "" && got.Test() != test.test {
+			t.Errorf("Want healthcheck test %q, got %q", test.test, got.Test())
+		}
+	}
+}
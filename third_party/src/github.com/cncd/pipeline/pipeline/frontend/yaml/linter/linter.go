@@ -2,13 +2,22 @@ package linter
 
 import (
 	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
 
+	"github.com/cncd/pipeline/pipeline/frontend"
 	"github.com/cncd/pipeline/pipeline/frontend/yaml"
 )
 
 // A Linter lints a pipeline configuration.
 type Linter struct {
-	trusted bool
+	trusted      bool
+	hostPaths    []string
+	namedVolumes []string
+	maxCPU       yaml.CPU
+	maxMemory    yaml.Memory
 }
 
 // New creates a new Linter with options.
@@ -24,6 +33,7 @@ func New(opts ...Option) *Linter {
 func (l *Linter) Lint(c *yaml.Config) error {
 	var containers []*yaml.Container
 	containers = append(containers, c.Pipeline.Containers...)
+	containers = append(containers, c.Finally.Containers...)
 	containers = append(containers, c.Services.Containers...)
 
 	for _, container := range containers {
@@ -31,7 +41,7 @@ func (l *Linter) Lint(c *yaml.Config) error {
 			return err
 		}
 		if l.trusted == false {
-			if err := l.lintTrusted(container); err != nil {
+			if err := l.lintTrusted(container, &c.Volumes); err != nil {
 				return err
 			}
 		}
@@ -40,11 +50,175 @@ func (l *Linter) Lint(c *yaml.Config) error {
 				return err
 			}
 		}
+		if err := l.lintHostMounts(container, &c.Volumes); err != nil {
+			return err
+		}
+		if err := l.lintSecrets(container); err != nil {
+			return err
+		}
+		if err := l.lintFailure(container); err != nil {
+			return err
+		}
+		if err := l.lintResources(container); err != nil {
+			return err
+		}
+		if err := container.HealthCheck.Validate(); err != nil {
+			return err
+		}
+		if container.Timeout.Duration() < 0 {
+			return fmt.Errorf("Invalid timeout %s", container.Timeout.Duration())
+		}
 	}
 
 	if len(c.Pipeline.Containers) == 0 {
 		return fmt.Errorf("Invalid or missing pipeline section")
 	}
+	if err := l.lintVolumes(&c.Volumes); err != nil {
+		return err
+	}
+	if err := l.lintCache(&c.Cache); err != nil {
+		return err
+	}
+	if err := l.lintDependencies(c.Pipeline.Containers); err != nil {
+		return err
+	}
+	return l.lintDependencies(c.Finally.Containers)
+}
+
+// lintSecrets verifies the secrets of the container are exposed as valid
+// and distinct environment variables.
+func (l *Linter) lintSecrets(c *yaml.Container) error {
+	targets := map[string]bool{}
+	for _, secret := range c.Secrets.Secrets {
+		if secret.Source == "" {
+			return fmt.Errorf("Invalid or missing secret source")
+		}
+		if !envName.MatchString(secret.Target) {
+			return fmt.Errorf("Invalid secret target %s", secret.Target)
+		}
+		target := strings.ToUpper(secret.Target)
+		if targets[target] {
+			return fmt.Errorf("Duplicate secret target %s", target)
+		}
+		targets[target] = true
+	}
+	return nil
+}
+
+// envName matches the names that can be used as environment variables.
+var envName = regexp.MustCompile(`^[^=\s]+$`)
+
+// lintFailure verifies the failure policy of the container.
+func (l *Linter) lintFailure(c *yaml.Container) error {
+	switch c.Failure {
+	case "", yaml.FailureFail, yaml.FailureIgnore:
+		return nil
+	default:
+		return fmt.Errorf("Invalid failure policy %s, expected %s or %s", c.Failure, yaml.FailureFail, yaml.FailureIgnore)
+	}
+}
+
+// lintResources verifies the resource requests do not exceed the limits,
+// and the limits do not exceed the maximum resources of a container.
+func (l *Linter) lintResources(c *yaml.Container) error {
+	r := &c.Resources
+	if r.Limits.CPU != 0 && c.CPUQuota != 0 {
+		return fmt.Errorf("Invalid cpu limit, expected one of cpu_quota or resources")
+	}
+	if r.Limits.Memory != 0 && c.MemLimit != 0 {
+		return fmt.Errorf("Invalid memory limit, expected one of mem_limit or resources")
+	}
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	cpu := r.Limits.CPU
+	if r.Requests.CPU > cpu {
+		cpu = r.Requests.CPU
+	}
+	if l.maxCPU != 0 && cpu > l.maxCPU {
+		return fmt.Errorf("Invalid cpu of %s, exceeds the maximum of %s", cpu, l.maxCPU)
+	}
+	memory := yaml.Memory(c.MemLimit)
+	if r.Limits.Memory > memory {
+		memory = r.Limits.Memory
+	}
+	if r.Requests.Memory > memory {
+		memory = r.Requests.Memory
+	}
+	if l.maxMemory != 0 && memory > l.maxMemory {
+		return fmt.Errorf("Invalid memory of %s, exceeds the maximum of %s", memory, l.maxMemory)
+	}
+	return nil
+}
+
+// lintCache verifies the cache key templates are valid, and the cache
+// paths are relative paths in the workspace.
+func (l *Linter) lintCache(c *yaml.Cache) error {
+	if c.IsEmpty() {
+		return nil
+	}
+	if c.Key == "" {
+		return fmt.Errorf("Invalid or missing cache key")
+	}
+	checksum := func(files ...string) string { return "" }
+	for _, key := range append([]string{c.Key}, c.RestoreKeys...) {
+		if _, err := yaml.RenderKey(key, frontend.Metadata{}, checksum); err != nil {
+			return fmt.Errorf("Invalid cache key %q. %s", key, err)
+		}
+	}
+	for _, p := range c.Paths {
+		clean := path.Clean(p)
+		if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+			return fmt.Errorf("Invalid cache path %q, expected a path in the workspace", p)
+		}
+	}
+	return nil
+}
+
+// lintDependencies verifies the steps depend on existing steps, and that
+// the dependencies do not form a cycle.
+func (l *Linter) lintDependencies(containers []*yaml.Container) error {
+	deps := map[string][]string{}
+	for _, container := range containers {
+		deps[container.Name] = container.DependsOn
+	}
+	for _, container := range containers {
+		for _, dep := range container.DependsOn {
+			if _, ok := deps[dep]; !ok {
+				return fmt.Errorf("Step %s depends on unknown step %s", container.Name, dep)
+			}
+		}
+	}
+
+	// visit the dependencies depth first, a step that is visited again
+	// before its dependencies are complete is part of a cycle.
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("Step %s has a circular dependency", name)
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		for _, dep := range deps[name] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+	for _, container := range containers {
+		if err := visit(container.Name); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -65,7 +239,78 @@ func (l *Linter) lintEntrypoint(c *yaml.Container) error {
 	return nil
 }
 
-func (l *Linter) lintTrusted(c *yaml.Container) error {
+// lintVolumes verifies the volumes are permitted by the volume policy. Host
+// volumes, and volume drivers and driver options, which can mount host
+// paths, require a trusted repository.
+func (l *Linter) lintVolumes(volumes *yaml.Volumes) error {
+	for _, volume := range volumes.Volumes {
+		if l.trusted == false && (volume.Driver != "local" || len(volume.DriverOpts) != 0) {
+			return fmt.Errorf("Insufficient privileges to use volume drivers")
+		}
+		switch {
+		case volume.Host != nil && volume.Temp != nil:
+			return fmt.Errorf("Invalid volume %s, expected a host or temp volume", volume.Name)
+		case volume.Host != nil:
+			if l.trusted == false {
+				return fmt.Errorf("Insufficient privileges to use host volumes")
+			}
+			if !path.IsAbs(volume.Host.Path) {
+				return fmt.Errorf("Invalid host volume %s, expected an absolute path", volume.Name)
+			}
+			if !l.matchHostPath(volume.Host.Path) {
+				return fmt.Errorf("Host volume path %s is not permitted", volume.Host.Path)
+			}
+		case volume.IsNamed():
+			if !l.matchNamedVolume(volume.Name) {
+				return fmt.Errorf("Named volume %s is not permitted", volume.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// lintHostMounts verifies the host paths mounted by the container are
+// permitted by the volume policy.
+func (l *Linter) lintHostMounts(c *yaml.Container, volumes *yaml.Volumes) error {
+	for _, volume := range c.Volumes.Volumes {
+		if volumes.Find(volume.Source) != nil || !path.IsAbs(volume.Source) {
+			continue
+		}
+		if !l.matchHostPath(volume.Source) {
+			return fmt.Errorf("Host volume path %s is not permitted", volume.Source)
+		}
+	}
+	return nil
+}
+
+// helper function returns true if the host path is within one of the
+// permitted host paths.
+func (l *Linter) matchHostPath(p string) bool {
+	if len(l.hostPaths) == 0 {
+		return true
+	}
+	p = path.Clean(p)
+	for _, allowed := range l.hostPaths {
+		allowed = path.Clean(allowed)
+		if p == allowed || strings.HasPrefix(p, strings.TrimSuffix(allowed, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// helper function returns true if the volume name matches one of the
+// permitted name patterns.
+func (l *Linter) matchNamedVolume(name string) bool {
+	for _, pattern := range l.namedVolumes {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *Linter) lintTrusted(c *yaml.Container, volumes *yaml.Volumes) error {
 	if c.Privileged {
 		return fmt.Errorf("Insufficient privileges to use privileged mode")
 	}
@@ -90,8 +335,13 @@ func (l *Linter) lintTrusted(c *yaml.Container) error {
 	if c.Networks.Networks != nil && len(c.Networks.Networks) != 0 {
 		return fmt.Errorf("Insufficient privileges to use networks")
 	}
-	if c.Volumes.Volumes != nil && len(c.Volumes.Volumes) != 0 {
-		return fmt.Errorf("Insufficient privileges to use volumes")
+	// untrusted repositories may only mount the temporary and named
+	// volumes of the volumes section.
+	for _, volume := range c.Volumes.Volumes {
+		declared := volumes.Find(volume.Source)
+		if declared == nil || declared.Host != nil {
+			return fmt.Errorf("Insufficient privileges to use volumes")
+		}
 	}
 	return nil
 }
@@ -0,0 +1,245 @@
+package linter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cncd/pipeline/pipeline/frontend/yaml"
+)
+
+func TestLintResources(t *testing.T) {
+	tests := []struct {
+		resources string
+		err       string
+	}{
+		{resources: "limits: {cpu: 1000m, memory: 2GiB}"},
+		{resources: "limits: {cpu: 2, memory: 4Gi}"},
+		{resources: "requests: {cpu: 500m, memory: 512Mi}"},
+		{resources: "limits: {cpu: 1000m}\n      requests: {cpu: 1500m}", err: "exceeds the limit"},
+		{resources: "limits: {memory: 1GiB}\n      requests: {memory: 2GiB}", err: "exceeds the limit"},
+		{resources: "limits: {cpu: 2500m}", err: "exceeds the maximum of 2000m"},
+		{resources: "requests: {cpu: 3}", err: "exceeds the maximum of 2000m"},
+		{resources: "limits: {memory: 5GiB}", err: "exceeds the maximum of 4GiB"},
+		{resources: "requests: {memory: 4097Mi}", err: "exceeds the maximum of 4GiB"},
+	}
+	for _, test := range tests {
+		config, err := yaml.ParseString("pipeline:\n  build:\n    image: golang\n    resources:\n      " + test.resources + "\n")
+		if err != nil {
+			t.Errorf("Want resources %q parsed, got %s", test.resources, err)
+			continue
+		}
+		err = New(WithMaxResources(2000, 4<<30)).Lint(config)
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("Want resources %q valid, got %s", test.resources, err)
+		case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Errorf("Want resources %q error %q, got %v", test.resources, test.err, err)
+		}
+	}
+}
+
+func TestLintResourcesUnlimited(t *testing.T) {
+	config, err := yaml.ParseString("pipeline:\n  build:\n    image: golang\n    resources:\n      limits: {cpu: 64, memory: 1TiB}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := New().Lint(config); err != nil {
+		t.Errorf("Want resources unlimited without a maximum, got %s", err)
+	}
+}
+
+func TestLintResourcesInvalid(t *testing.T) {
+	for _, resources := range []string{
+		"limits: {cpu: 1k}",
+		"limits: {cpu: 1Gi}",
+		"limits: {memory: 2GiBs}",
+		"limits: {memory: 2Pi}",
+		"requests: {memory: lots}",
+	} {
+		if _, err := yaml.ParseString("pipeline:\n  build:\n    image: golang\n    resources:\n      " + resources + "\n"); err == nil {
+			t.Errorf("Want error parsing resources %q with an invalid unit", resources)
+		}
+	}
+}
+
+func TestLintResourcesConflict(t *testing.T) {
+	tests := []string{
+		"cpu_quota: 100000\n    resources:\n      limits: {cpu: 1}",
+		"mem_limit: 1073741824\n    resources:\n      limits: {memory: 1GiB}",
+	}
+	for _, test := range tests {
+		config, err := yaml.ParseString("pipeline:\n  build:\n    image: golang\n    " + test + "\n")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := New(WithTrusted(true)).Lint(config); err == nil || !strings.Contains(err.Error(), "expected one of") {
+			t.Errorf("Want error declaring both limits %q, got %v", test, err)
+		}
+	}
+}
+
+func TestLintDependencies(t *testing.T) {
+	tests := []struct {
+		steps string
+		err   string
+	}{
+		{steps: "a: {image: golang}\n  b: {image: golang, depends_on: a}\n  c: {image: golang, depends_on: [a, b]}"},
+		{steps: "a: {image: golang}\n  b: {image: golang, depends_on: d}", err: "depends on unknown step d"},
+		{steps: "a: {image: golang, depends_on: b}\n  b: {image: golang, depends_on: a}", err: "circular dependency"},
+		{steps: "a: {image: golang, depends_on: a}", err: "circular dependency"},
+		{steps: "a: {image: golang}\n  b: {image: golang, depends_on: c}\n  c: {image: golang, depends_on: d}\n  d: {image: golang, depends_on: b}", err: "circular dependency"},
+	}
+	for _, test := range tests {
+		config, err := yaml.ParseString("pipeline:\n  " + test.steps + "\n")
+		if err != nil {
+			t.Errorf("Want steps %q parsed, got %s", test.steps, err)
+			continue
+		}
+		err = New().Lint(config)
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("Want dependencies %q valid, got %s", test.steps, err)
+		case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Errorf("Want dependencies %q error %q, got %v", test.steps, test.err, err)
+		}
+	}
+}
+
+func TestLintFailure(t *testing.T) {
+	tests := []struct {
+		step   string
+		ignore bool
+		err    bool
+	}{
+		{step: "image: golang"},
+		{step: "image: golang, failure: fail"},
+		{step: "image: golang, failure: ignore", ignore: true},
+		{step: "image: golang, allow_failure: true", ignore: true},
+		{step: "image: golang, failure: skip", err: true},
+	}
+	for _, test := range tests {
+		config, err := yaml.ParseString("pipeline:\n  build: {" + test.step + "}\n")
+		if err != nil {
+			t.Errorf("Want step %q parsed, got %s", test.step, err)
+			continue
+		}
+		err = New().Lint(config)
+		switch {
+		case !test.err && err != nil:
+			t.Errorf("Want failure policy %q valid, got %s", test.step, err)
+		case test.err && (err == nil || !strings.Contains(err.Error(), "Invalid failure policy")):
+			t.Errorf("Want failure policy %q invalid, got %v", test.step, err)
+		}
+		if got := config.Pipeline.Containers[0].IgnoreFailure(); got != test.ignore {
+			t.Errorf("Want step %q ignore failure %v, got %v", test.step, test.ignore, got)
+		}
+	}
+}
+
+func TestLintTimeout(t *testing.T) {
+	tests := []struct {
+		timeout string
+		want    time.Duration
+		err     bool
+	}{
+		{timeout: "10", want: 10 * time.Minute},
+		{timeout: "90s", want: 90 * time.Second},
+		{timeout: "1h30m", want: 90 * time.Minute},
+		{timeout: "-1m", err: true},
+	}
+	for _, test := range tests {
+		config, err := yaml.ParseString("pipeline:\n  build: {image: golang, timeout: " + test.timeout + "}\n")
+		if err != nil {
+			t.Errorf("Want timeout %q parsed, got %s", test.timeout, err)
+			continue
+		}
+		err = New().Lint(config)
+		if test.err {
+			if err == nil || !strings.Contains(err.Error(), "Invalid timeout") {
+				t.Errorf("Want timeout %q invalid, got %v", test.timeout, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Want timeout %q valid, got %s", test.timeout, err)
+		}
+		if got := config.Pipeline.Containers[0].Timeout.Duration(); got != test.want {
+			t.Errorf("Want timeout %q parsed as %s, got %s", test.timeout, test.want, got)
+		}
+	}
+	if _, err := yaml.ParseString("pipeline:\n  build: {image: golang, timeout: soon}\n"); err == nil {
+		t.Errorf("Want error parsing an invalid timeout")
+	}
+}
+
+func TestLintVolumes(t *testing.T) {
+	tests := []struct {
+		volumes string
+		mounts  string
+		trusted bool
+		err     string
+	}{
+		{volumes: "cache: {temp: {}}", mounts: "cache:/cache"},
+		{volumes: "cache: {temp: {medium: memory}}", mounts: "cache:/cache"},
+		{volumes: "deps: {}", mounts: "deps:/deps", err: "Named volume deps is not permitted"},
+		{volumes: "gocache: {}", mounts: "gocache:/cache"},
+		{volumes: "docker: {host: {path: /var/run/docker.sock}}", mounts: "docker:/var/run/docker.sock", err: "Insufficient privileges"},
+		{volumes: "docker: {host: {path: /var/run/docker.sock}}", mounts: "docker:/var/run/docker.sock", trusted: true},
+		{volumes: "docker: {host: {path: /etc}}", mounts: "docker:/etc", trusted: true, err: "Host volume path /etc is not permitted"},
+		{volumes: "docker: {host: {path: var/run}}", mounts: "docker:/var/run", trusted: true, err: "expected an absolute path"},
+		{volumes: "both: {host: {path: /var/run}, temp: {}}", mounts: "both:/var/run", trusted: true, err: "expected a host or temp volume"},
+		{volumes: "cache: {temp: {}}", mounts: "/var/run/docker.sock:/var/run/docker.sock", err: "Insufficient privileges"},
+		{volumes: "cache: {temp: {}}", mounts: "/var/run/docker.sock:/var/run/docker.sock", trusted: true},
+		{volumes: "cache: {temp: {}}", mounts: "/etc:/etc", trusted: true, err: "Host volume path /etc is not permitted"},
+		{volumes: "x: {temp: {}, driver_opts: {type: none, o: bind, device: /}}", mounts: "x:/host", err: "Insufficient privileges to use volume drivers"},
+		{volumes: "x: {temp: {medium: memory}, driver_opts: {o: bind}}", mounts: "x:/host", err: "Insufficient privileges to use volume drivers"},
+		{volumes: "gocache: {driver: local-persist}", mounts: "gocache:/cache", err: "Insufficient privileges to use volume drivers"},
+		{volumes: "x: {temp: {}, driver: local, driver_opts: {type: nfs}}", mounts: "x:/nfs", trusted: true},
+	}
+	for _, test := range tests {
+		config, err := yaml.ParseString("pipeline:\n  build:\n    image: golang\n    volumes: [ \"" + test.mounts + "\" ]\nvolumes:\n  " + test.volumes + "\n")
+		if err != nil {
+			t.Errorf("Want volumes %q parsed, got %s", test.volumes, err)
+			continue
+		}
+		err = New(
+			WithTrusted(test.trusted),
+			WithHostPaths("/var/run"),
+			WithNamedVolumes("go*"),
+		).Lint(config)
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("Want volumes %q mounted at %q valid, got %s", test.volumes, test.mounts, err)
+		case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Errorf("Want volumes %q mounted at %q error %q, got %v", test.volumes, test.mounts, test.err, err)
+		}
+	}
+}
+
+func TestLintSecrets(t *testing.T) {
+	tests := []struct {
+		secrets string
+		err     string
+	}{
+		{secrets: "[docker_username, {source: docker_password, target: PLUGIN_PASSWORD}]"},
+		{secrets: "[{target: PLUGIN_PASSWORD}]", err: "missing secret source"},
+		{secrets: "[{source: docker_password, target: \"PLUGIN PASSWORD\"}]", err: "Invalid secret target"},
+		{secrets: "[{source: docker_password, target: \"A=B\"}]", err: "Invalid secret target"},
+		{secrets: "[password, {source: docker_password, target: PASSWORD}]", err: "Duplicate secret target PASSWORD"},
+	}
+	for _, test := range tests {
+		config, err := yaml.ParseString("pipeline:\n  build:\n    image: golang\n    secrets: " + test.secrets + "\n")
+		if err != nil {
+			t.Errorf("Want secrets %q parsed, got %s", test.secrets, err)
+			continue
+		}
+		err = New().Lint(config)
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("Want secrets %q valid, got %s", test.secrets, err)
+		case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Errorf("Want secrets %q error %q, got %v", test.secrets, test.err, err)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package linter
 
+import "github.com/cncd/pipeline/pipeline/frontend/yaml"
+
 // Option configures a linting option.
 type Option func(*Linter)
 
@@ -9,3 +11,31 @@ func WithTrusted(trusted bool) Option {
 		linter.trusted = trusted
 	}
 }
+
+// WithHostPaths adds the host paths that trusted repositories may mount
+// with host volumes to the linter. Any host path may be mounted if no host
+// paths are configured.
+func WithHostPaths(paths ...string) Option {
+	return func(linter *Linter) {
+		linter.hostPaths = paths
+	}
+}
+
+// WithNamedVolumes adds the name patterns of the named volumes that
+// repositories may define to the linter. Named volumes may not be defined
+// if no patterns are configured.
+func WithNamedVolumes(patterns ...string) Option {
+	return func(linter *Linter) {
+		linter.namedVolumes = patterns
+	}
+}
+
+// WithMaxResources adds the maximum cpu and memory of a container to the
+// linter. The resources of a container are not limited if the maximum is
+// zero.
+func WithMaxResources(cpu yaml.CPU, memory yaml.Memory) Option {
+	return func(linter *Linter) {
+		linter.maxCPU = cpu
+		linter.maxMemory = memory
+	}
+}
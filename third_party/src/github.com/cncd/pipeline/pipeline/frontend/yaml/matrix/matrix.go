@@ -1,6 +1,7 @@
 package matrix
 
 import (
+	"fmt"
 	"strings"
 
 	"gopkg.in/yaml.v2"
@@ -27,25 +28,27 @@ func (a Axis) String() string {
 	return strings.Join(envs, " ")
 }
 
-// Parse parses the Yaml matrix definition.
+// Parse parses the Yaml matrix definition. The matrix axes are expanded
+// into every permutation, excluding the permutations that match an exclude
+// rule, followed by the permutations listed as include rules.
 func Parse(data []byte) ([]Axis, error) {
-
-	axis, err := parseList(data)
-	if err == nil && len(axis) != 0 {
-		return axis, nil
-	}
-
-	matrix, err := parse(data)
+	def, err := parse(data)
 	if err != nil {
 		return nil, err
 	}
 
+	var axisList []Axis
+	if len(def.axes) != 0 {
+		axisList = calc(def.axes)
+	}
+	axisList = exclude(axisList, def.exclude)
+	axisList = include(axisList, def.include)
+
 	// if not a matrix build return an array with just the single axis.
-	if len(matrix) == 0 {
+	if len(axisList) == 0 {
 		return nil, nil
 	}
-
-	return calc(matrix), nil
+	return axisList, nil
 }
 
 // ParseString parses the Yaml string matrix definition.
@@ -97,21 +100,114 @@ func calc(matrix Matrix) []Axis {
 	return axisList
 }
 
-func parse(raw []byte) (Matrix, error) {
-	data := struct {
-		Matrix map[string][]string
-	}{}
-	err := yaml.Unmarshal(raw, &data)
-	return data.Matrix, err
+// exclude removes the axis that match any of the exclude rules. An axis
+// matches a rule if it has the same value for every key in the rule.
+func exclude(axisList, rules []Axis) []Axis {
+	if len(rules) == 0 {
+		return axisList
+	}
+	var out []Axis
+	for _, axis := range axisList {
+		if !matchAny(axis, rules) {
+			out = append(out, axis)
+		}
+	}
+	return out
 }
 
-func parseList(raw []byte) ([]Axis, error) {
-	data := struct {
+// include appends the include rules that are not already in the list of
+// axis, up to the maximum number of axis.
+func include(axisList, rules []Axis) []Axis {
+	for _, rule := range rules {
+		if len(axisList) > limitAxis {
+			break
+		}
+		if len(rule) == 0 || matchAny(rule, axisList) {
+			continue
+		}
+		axisList = append(axisList, rule)
+	}
+	return axisList
+}
+
+func matchAny(axis Axis, rules []Axis) bool {
+	for _, rule := range rules {
+		if match(axis, rule) {
+			return true
+		}
+	}
+	return false
+}
+
+func match(axis, rule Axis) bool {
+	for k, v := range rule {
+		if axis[k] != v {
+			return false
+		}
+	}
+	return len(rule) != 0
+}
+
+// definition represents the matrix axes and the include and exclude rules.
+type definition struct {
+	axes    Matrix
+	include []Axis
+	exclude []Axis
+}
+
+// values represents the values of a matrix axis. The include and exclude
+// rules are not lists of values, and are parsed separately.
+type values struct {
+	list []string
+	ok   bool
+}
+
+// UnmarshalYAML implements the Unmarshaller interface.
+func (v *values) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&v.list); err == nil {
+		v.ok = true
+		return nil
+	}
+	var value string
+	if err := unmarshal(&value); err == nil {
+		v.list = []string{value}
+		v.ok = true
+	}
+	return nil
+}
+
+func parse(raw []byte) (*definition, error) {
+	rules := struct {
 		Matrix struct {
 			Include []Axis
+			Exclude []Axis
 		}
 	}{}
+	if err := yaml.Unmarshal(raw, &rules); err != nil {
+		return nil, err
+	}
 
-	err := yaml.Unmarshal(raw, &data)
-	return data.Matrix.Include, err
+	data := struct {
+		Matrix map[string]values
+	}{}
+	if err := yaml.Unmarshal(raw, &data); err != nil {
+		return nil, err
+	}
+
+	def := &definition{
+		axes:    Matrix{},
+		include: rules.Matrix.Include,
+		exclude: rules.Matrix.Exclude,
+	}
+	for k, v := range data.Matrix {
+		switch {
+		case k == "include" || k == "exclude":
+			continue
+		case !v.ok:
+			return nil, fmt.Errorf("matrix: invalid values for %s", k)
+		case len(v.list) != 0:
+			def.axes[k] = v.list
+		}
+	}
+	return def, nil
 }
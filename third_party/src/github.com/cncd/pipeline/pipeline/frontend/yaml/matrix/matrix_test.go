@@ -0,0 +1,104 @@
+package matrix
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		data string
+		want []string
+		err  bool
+	}{
+		{
+			data: "pipeline: {}\n",
+		},
+		{
+			data: "matrix:\n  GO_VERSION: [1.8, 1.9]\n  REDIS_VERSION: [3, 4]\n",
+			want: []string{
+				"GO_VERSION=1.8 REDIS_VERSION=3",
+				"GO_VERSION=1.8 REDIS_VERSION=4",
+				"GO_VERSION=1.9 REDIS_VERSION=3",
+				"GO_VERSION=1.9 REDIS_VERSION=4",
+			},
+		},
+		{
+			data: "matrix:\n  GO_VERSION: 1.9\n",
+			want: []string{"GO_VERSION=1.9"},
+		},
+		{
+			data: "matrix:\n  GO_VERSION: [1.8, 1.9]\n  REDIS_VERSION: [3, 4]\n  exclude:\n    - GO_VERSION: 1.8\n      REDIS_VERSION: 4\n",
+			want: []string{
+				"GO_VERSION=1.8 REDIS_VERSION=3",
+				"GO_VERSION=1.9 REDIS_VERSION=3",
+				"GO_VERSION=1.9 REDIS_VERSION=4",
+			},
+		},
+		{
+			data: "matrix:\n  GO_VERSION: [1.8, 1.9]\n  REDIS_VERSION: [3, 4]\n  exclude:\n    - REDIS_VERSION: 4\n",
+			want: []string{
+				"GO_VERSION=1.8 REDIS_VERSION=3",
+				"GO_VERSION=1.9 REDIS_VERSION=3",
+			},
+		},
+		{
+			data: "matrix:\n  GO_VERSION: [1.9]\n  include:\n    - GO_VERSION: 1.10\n      EXPERIMENTAL: true\n    - GO_VERSION: 1.9\n",
+			want: []string{
+				"EXPERIMENTAL=true GO_VERSION=1.10",
+				"GO_VERSION=1.9",
+			},
+		},
+		{
+			data: "matrix:\n  include:\n    - GO_VERSION: 1.8\n    - GO_VERSION: 1.9\n",
+			want: []string{"GO_VERSION=1.8", "GO_VERSION=1.9"},
+		},
+		{
+			data: "matrix:\n  GO_VERSION: [1.9]\n  exclude:\n    - GO_VERSION: 1.9\n",
+		},
+		{
+			data: "matrix:\n  GO_VERSION: {major: 1}\n",
+			err:  true,
+		},
+	}
+	for _, test := range tests {
+		axes, err := ParseString(test.data)
+		if test.err {
+			if err == nil {
+				t.Errorf("Want error parsing matrix %q", test.data)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Want matrix %q parsed, got %s", test.data, err)
+			continue
+		}
+		var got []string
+		for _, axis := range axes {
+			got = append(got, sortedAxis(axis))
+		}
+		sort.Strings(got)
+		if strings.Join(got, "\n") != strings.Join(test.want, "\n") {
+			t.Errorf("Want matrix %q expanded to %q, got %q", test.data, test.want, got)
+		}
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	data := "matrix:\n  A: [1, 2, 3, 4, 5, 6]\n  B: [1, 2, 3, 4, 5, 6]\n  include:\n    - A: 7\n"
+	axes, err := ParseString(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(axes) > limitAxis+2 {
+		t.Errorf("Want at most %d axes, got %d", limitAxis+2, len(axes))
+	}
+}
+
+// sortedAxis returns the axis as a sorted list of environment variables.
+func sortedAxis(axis Axis) string {
+	envs := strings.Split(axis.String(), " ")
+	sort.Strings(envs)
+	return strings.Join(envs, " ")
+}
@@ -0,0 +1,90 @@
+package yaml
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Platform defines the platform of the agents that execute the pipeline,
+// which is the operating system, architecture and architecture variant.
+type Platform struct {
+	OS      string `yaml:"os,omitempty"`
+	Arch    string `yaml:"arch,omitempty"`
+	Variant string `yaml:"variant,omitempty"`
+}
+
+// UnmarshalYAML implements the Unmarshaller interface. The platform may be
+// a string in the os/arch/variant format, such as linux/arm64.
+func (p *Platform) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err == nil {
+		parts := strings.Split(s, "/")
+		if len(parts) > 3 {
+			return fmt.Errorf("Invalid platform %q", s)
+		}
+		parts = append(parts, "", "")
+		p.OS, p.Arch, p.Variant = parts[0], parts[1], parts[2]
+		return nil
+	}
+	type plain Platform
+	return unmarshal((*plain)(p))
+}
+
+// IsEmpty returns true if the platform is not defined.
+func (p *Platform) IsEmpty() bool {
+	return p.OS == "" && p.Arch == "" && p.Variant == ""
+}
+
+// String returns the platform in the os/arch/variant format. The operating
+// system defaults to linux, and the architecture to amd64.
+func (p *Platform) String() string {
+	if p.IsEmpty() {
+		return ""
+	}
+	os, arch := p.OS, p.Arch
+	if os == "" {
+		os = "linux"
+	}
+	if arch == "" {
+		arch = "amd64"
+	}
+	if p.Variant != "" {
+		return os + "/" + arch + "/" + p.Variant
+	}
+	return os + "/" + arch
+}
+
+// NodeSelector defines the node labels of the agents that execute the
+// pipeline. The selector may be a map, or a list in the key=value format,
+// and the values may be any scalar, such as gpu: true.
+type NodeSelector map[string]string
+
+// UnmarshalYAML implements the Unmarshaller interface.
+func (n *NodeSelector) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var list []string
+	if err := unmarshal(&list); err == nil {
+		*n = NodeSelector{}
+		for _, item := range list {
+			parts := strings.SplitN(item, "=", 2)
+			if len(parts) != 2 {
+				return fmt.Errorf("Invalid node selector %q, expected key=value", item)
+			}
+			(*n)[parts[0]] = parts[1]
+		}
+		return nil
+	}
+	var m map[string]interface{}
+	if err := unmarshal(&m); err != nil {
+		return err
+	}
+	*n = NodeSelector{}
+	for k, v := range m {
+		switch v.(type) {
+		case string, bool, int, float64:
+			(*n)[k] = fmt.Sprint(v)
+		default:
+			return fmt.Errorf("Invalid node selector %q, expected a scalar value", k)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package yaml
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestPlatformUnmarshal(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Platform
+		err  bool
+	}{
+		{in: "linux/amd64", want: Platform{OS: "linux", Arch: "amd64"}},
+		{in: "linux/arm/v7", want: Platform{OS: "linux", Arch: "arm", Variant: "v7"}},
+		{in: "windows", want: Platform{OS: "windows"}},
+		{in: "{os: linux, arch: arm64}", want: Platform{OS: "linux", Arch: "arm64"}},
+		{in: "{arch: arm, variant: v6}", want: Platform{Arch: "arm", Variant: "v6"}},
+		{in: "linux/arm/v7/extra", err: true},
+	}
+	for _, test := range tests {
+		var got Platform
+		err := yaml.Unmarshal([]byte(test.in), &got)
+		if test.err {
+			if err == nil {
+				t.Errorf("Want error parsing platform %q", test.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Want platform %q parsed, got %s", test.in, err)
+		} else if got != test.want {
+			t.Errorf("Want platform %q parsed as %v, got %v", test.in, test.want, got)
+		}
+	}
+}
+
+func TestPlatformString(t *testing.T) {
+	tests := []struct {
+		in   Platform
+		want string
+	}{
+		{Platform{}, ""},
+		{Platform{OS: "linux", Arch: "amd64"}, "linux/amd64"},
+		{Platform{Arch: "arm64"}, "linux/arm64"},
+		{Platform{OS: "windows"}, "windows/amd64"},
+		{Platform{Arch: "arm", Variant: "v7"}, "linux/arm/v7"},
+	}
+	for _, test := range tests {
+		if got := test.in.String(); got != test.want {
+			t.Errorf("Want platform %v formatted as %q, got %q", test.in, test.want, got)
+		}
+	}
+}
+
+func TestPlatformConfig(t *testing.T) {
+	config, err := ParseString("platform: linux/arm64\nnode:\n  gpu: true\npipeline:\n  build:\n    image: golang\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := config.Platform.String(); got != "linux/arm64" {
+		t.Errorf("Want platform linux/arm64, got %q", got)
+	}
+	if config.Node["gpu"] != "true" {
+		t.Errorf("Want node selector gpu=true, got %v", config.Node)
+	}
+}
+
+func TestNodeSelectorUnmarshal(t *testing.T) {
+	tests := []struct {
+		in   string
+		want map[string]string
+		err  bool
+	}{
+		{in: "{gpu: true, zone: eu-west-1, cores: 8}", want: map[string]string{"gpu": "true", "zone": "eu-west-1", "cores": "8"}},
+		{in: "[gpu=true, zone=eu-west-1]", want: map[string]string{"gpu": "true", "zone": "eu-west-1"}},
+		{in: "[gpu]", err: true},
+		{in: "{gpu: [true]}", err: true},
+	}
+	for _, test := range tests {
+		var got NodeSelector
+		err := yaml.Unmarshal([]byte(test.in), &got)
+		if test.err {
+			if err == nil {
+				t.Errorf("Want error parsing node selector %q", test.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Want node selector %q parsed, got %s", test.in, err)
+			continue
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("Want node selector %q parsed as %v, got %v", test.in, test.want, got)
+		}
+		for k, v := range test.want {
+			if got[k] != v {
+				t.Errorf("Want node selector %q parsed as %v, got %v", test.in, test.want, got)
+			}
+		}
+	}
+}
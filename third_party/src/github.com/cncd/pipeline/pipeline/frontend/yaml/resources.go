@@ -0,0 +1,139 @@
+package yaml
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type (
+	// Resources defines the compute resources of a container. The limits
+	// are enforced by the backend, and the requests are reserved for the
+	// container when the host is under contention.
+	Resources struct {
+		Limits   ResourceList `yaml:"limits,omitempty"`
+		Requests ResourceList `yaml:"requests,omitempty"`
+	}
+
+	// ResourceList defines the cpu and memory of a container.
+	ResourceList struct {
+		CPU    CPU    `yaml:"cpu,omitempty"`
+		Memory Memory `yaml:"memory,omitempty"`
+	}
+
+	// CPU defines a number of millicpus, which is a number of cpus such
+	// as 1.5, or a number of millicpus such as 1500m.
+	CPU int64
+
+	// Memory defines a number of bytes, which is a number with a decimal
+	// or binary unit such as 512M, 2Gi or 2GiB.
+	Memory int64
+)
+
+var quantityRe = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)?) *([A-Za-z]*)$`)
+
+var memoryUnits = map[string]float64{
+	"":   1,
+	"k":  1e3,
+	"m":  1e6,
+	"g":  1e9,
+	"t":  1e12,
+	"ki": 1 << 10,
+	"mi": 1 << 20,
+	"gi": 1 << 30,
+	"ti": 1 << 40,
+}
+
+// Validate returns an error if the requests exceed the limits.
+func (r *Resources) Validate() error {
+	if r.Limits.CPU != 0 && r.Requests.CPU > r.Limits.CPU {
+		return fmt.Errorf("Invalid cpu request %s, exceeds the limit of %s", r.Requests.CPU, r.Limits.CPU)
+	}
+	if r.Limits.Memory != 0 && r.Requests.Memory > r.Limits.Memory {
+		return fmt.Errorf("Invalid memory request %s, exceeds the limit of %s", r.Requests.Memory, r.Limits.Memory)
+	}
+	return nil
+}
+
+// UnmarshalYAML implements the Unmarshaller interface.
+func (c *CPU) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	v, err := ParseCPU(s)
+	if err != nil {
+		return err
+	}
+	*c = v
+	return nil
+}
+
+// String returns the number of millicpus.
+func (c CPU) String() string {
+	return strconv.FormatInt(int64(c), 10) + "m"
+}
+
+// ParseCPU parses a number of cpus, such as 1.5, or a number of
+// millicpus, such as 1500m.
+func ParseCPU(s string) (CPU, error) {
+	match := quantityRe.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil || (match[2] != "" && match[2] != "m") {
+		return 0, fmt.Errorf("Invalid cpu %q", s)
+	}
+	v, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid cpu %q", s)
+	}
+	if match[2] == "" {
+		v = v * 1000
+	}
+	return CPU(math.Ceil(v)), nil
+}
+
+// UnmarshalYAML implements the Unmarshaller interface.
+func (m *Memory) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	v, err := ParseMemory(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// String returns the number of bytes in the largest binary unit that
+// represents the number exactly.
+func (m Memory) String() string {
+	for _, unit := range []string{"Ti", "Gi", "Mi", "Ki"} {
+		size := int64(memoryUnits[strings.ToLower(unit)])
+		if m != 0 && int64(m)%size == 0 {
+			return strconv.FormatInt(int64(m)/size, 10) + unit + "B"
+		}
+	}
+	return strconv.FormatInt(int64(m), 10) + "B"
+}
+
+// ParseMemory parses a number of bytes with an optional decimal or binary
+// unit, such as 512M, 2Gi or 2GiB.
+func ParseMemory(s string) (Memory, error) {
+	match := quantityRe.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return 0, fmt.Errorf("Invalid memory %q", s)
+	}
+	unit := strings.TrimSuffix(strings.ToLower(match[2]), "b")
+	size, ok := memoryUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("Invalid memory %q", s)
+	}
+	v, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid memory %q", s)
+	}
+	return Memory(math.Ceil(v * size)), nil
+}
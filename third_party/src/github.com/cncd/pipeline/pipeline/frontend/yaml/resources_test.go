@@ -0,0 +1,147 @@
+package yaml
+
+import "testing"
+
+func TestParseCPU(t *testing.T) {
+	tests := []struct {
+		in   string
+		want CPU
+		err  bool
+	}{
+		{in: "1", want: 1000},
+		{in: "1.5", want: 1500},
+		{in: "0.1", want: 100},
+		{in: "1000m", want: 1000},
+		{in: "250m", want: 250},
+		{in: " 2 ", want: 2000},
+		{in: "0.0005", want: 1},
+		{in: "", err: true},
+		{in: "1k", err: true},
+		{in: "1M", err: true},
+		{in: "m", err: true},
+		{in: "-1", err: true},
+		{in: "1.5.0", err: true},
+	}
+	for _, test := range tests {
+		got, err := ParseCPU(test.in)
+		if test.err {
+			if err == nil {
+				t.Errorf("Want error parsing cpu %q, got %d", test.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Want cpu %q parsed, got %s", test.in, err)
+		} else if got != test.want {
+			t.Errorf("Want cpu %q parsed as %d, got %d", test.in, test.want, got)
+		}
+	}
+}
+
+func TestParseMemory(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Memory
+		err  bool
+	}{
+		{in: "1024", want: 1024},
+		{in: "512M", want: 512e6},
+		{in: "512m", want: 512e6},
+		{in: "2G", want: 2e9},
+		{in: "2GB", want: 2e9},
+		{in: "2Gi", want: 2 << 30},
+		{in: "2GiB", want: 2 << 30},
+		{in: "2gib", want: 2 << 30},
+		{in: "1.5Ki", want: 1536},
+		{in: "1 Ti", want: 1 << 40},
+		{in: "100B", want: 100},
+		{in: "", err: true},
+		{in: "2Pi", err: true},
+		{in: "2GiBs", err: true},
+		{in: "2 GiB B", err: true},
+		{in: "-1G", err: true},
+	}
+	for _, test := range tests {
+		got, err := ParseMemory(test.in)
+		if test.err {
+			if err == nil {
+				t.Errorf("Want error parsing memory %q, got %d", test.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Want memory %q parsed, got %s", test.in, err)
+		} else if got != test.want {
+			t.Errorf("Want memory %q parsed as %d, got %d", test.in, test.want, got)
+		}
+	}
+}
+
+func TestResourcesString(t *testing.T) {
+	tests := []struct {
+		in interface {
+			String() string
+		}
+		want string
+	}{
+		{CPU(1500), "1500m"},
+		{CPU(0), "0m"},
+		{Memory(2 << 30), "2GiB"},
+		{Memory(1536), "1536B"},
+		{Memory(3 << 10), "3KiB"},
+		{Memory(512e6), "500000KiB"},
+		{Memory(1 << 40), "1TiB"},
+		{Memory(100), "100B"},
+		{Memory(0), "0B"},
+	}
+	for _, test := range tests {
+		if got := test.in.String(); got != test.want {
+			t.Errorf("Want %d formatted as %s, got %s", test.in, test.want, got)
+		}
+	}
+}
+
+func TestResourcesValidate(t *testing.T) {
+	tests := []struct {
+		resources Resources
+		valid     bool
+	}{
+		{Resources{}, true},
+		{Resources{Requests: ResourceList{CPU: 500, Memory: 1 << 30}}, true},
+		{Resources{Limits: ResourceList{CPU: 1000}, Requests: ResourceList{CPU: 1000}}, true},
+		{Resources{Limits: ResourceList{CPU: 1000}, Requests: ResourceList{CPU: 1500}}, false},
+		{Resources{Limits: ResourceList{Memory: 1 << 30}, Requests: ResourceList{Memory: 512 << 20}}, true},
+		{Resources{Limits: ResourceList{Memory: 1 << 30}, Requests: ResourceList{Memory: 2 << 30}}, false},
+	}
+	for i, test := range tests {
+		if err := test.resources.Validate(); (err == nil) != test.valid {
+			t.Errorf("Want resources %d valid %v, got %v", i, test.valid, err)
+		}
+	}
+}
+
+func TestResourcesUnmarshal(t *testing.T) {
+	config, err := ParseString(`
+pipeline:
+  build:
+    image: golang
+    resources:
+      limits:
+        cpu: 2
+        memory: 2GiB
+      requests:
+        cpu: 500m
+        memory: 512Mi
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := config.Pipeline.Containers[0].Resources
+	if r.Limits.CPU != 2000 || r.Limits.Memory != 2<<30 || r.Requests.CPU != 500 || r.Requests.Memory != 512<<20 {
+		t.Errorf("Want resources unmarshaled, got %v", r)
+	}
+
+	if _, err := ParseString("pipeline:\n  build:\n    image: golang\n    resources:\n      limits:\n        memory: 2XB\n"); err == nil {
+		t.Errorf("Want error unmarshaling an invalid memory unit")
+	}
+}
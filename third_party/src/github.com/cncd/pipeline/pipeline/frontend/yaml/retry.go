@@ -0,0 +1,46 @@
+package yaml
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cncd/pipeline/pipeline/backend"
+)
+
+// Retries defines the retry policy of a container. The container is
+// executed at most Attempts times, until the exit code no longer matches
+// the When condition. The Backoff between attempts doubles after each
+// attempt.
+type Retries struct {
+	Attempts int           `yaml:"attempts,omitempty"`
+	Backoff  time.Duration `yaml:"backoff,omitempty"`
+	When     string        `yaml:"when,omitempty"`
+}
+
+// UnmarshalYAML implements the Unmarshaller interface.
+func (r *Retries) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// the retry policy may be the number of attempts.
+	var attempts int
+	if err := unmarshal(&attempts); err == nil {
+		r.Attempts = attempts
+		return r.validate()
+	}
+
+	type plain Retries
+	if err := unmarshal((*plain)(r)); err != nil {
+		return err
+	}
+	return r.validate()
+}
+
+// helper function validates the retry policy.
+func (r *Retries) validate() error {
+	if r.Attempts < 0 {
+		return fmt.Errorf("Invalid number of retry attempts %d", r.Attempts)
+	}
+	if r.Backoff < 0 {
+		return fmt.Errorf("Invalid retry backoff %s", r.Backoff)
+	}
+	_, err := backend.MatchExitCode(r.When, 0)
+	return err
+}
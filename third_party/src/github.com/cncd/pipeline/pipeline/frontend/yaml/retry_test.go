@@ -0,0 +1,41 @@
+package yaml
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestUnmarshalRetries(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Retries
+		err  bool
+	}{
+		{in: "3", want: Retries{Attempts: 3}},
+		{in: "{attempts: 3, backoff: 10s}", want: Retries{Attempts: 3, Backoff: 10 * time.Second}},
+		{in: "{attempts: 2, when: exit_code == 137}", want: Retries{Attempts: 2, When: "exit_code == 137"}},
+		{in: "-1", err: true},
+		{in: "{attempts: 2, backoff: -1s}", err: true},
+		{in: "{attempts: 2, when: oom}", err: true},
+		{in: "[3]", err: true},
+	}
+	for _, test := range tests {
+		got := Retries{}
+		err := yaml.Unmarshal([]byte(test.in), &got)
+		if test.err {
+			if err == nil {
+				t.Errorf("Want error parsing retries %q", test.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Want retries %q parsed, got %s", test.in, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Want retries %+v, got %+v", test.want, got)
+		}
+	}
+}
@@ -13,7 +13,9 @@ type (
 	}
 )
 
-// UnmarshalYAML implements the Unmarshaller interface.
+// UnmarshalYAML implements the Unmarshaller interface. The secrets are a
+// list of secret names, or of source and target mappings, which expose the
+// source secret as the target environment variable.
 func (s *Secrets) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	var strslice []string
 	err := unmarshal(&strslice)
@@ -28,3 +30,24 @@ func (s *Secrets) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	}
 	return unmarshal(&s.Secrets)
 }
+
+// UnmarshalYAML implements the Unmarshaller interface. The secret is the
+// secret name, or a source and target mapping. The target defaults to the
+// source if not specified.
+func (s *Secret) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var str string
+	if err := unmarshal(&str); err == nil {
+		s.Source = str
+		s.Target = str
+		return nil
+	}
+
+	type plain Secret
+	if err := unmarshal((*plain)(s)); err != nil {
+		return err
+	}
+	if s.Target == "" {
+		s.Target = s.Source
+	}
+	return nil
+}
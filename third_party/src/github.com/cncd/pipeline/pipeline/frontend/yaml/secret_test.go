@@ -0,0 +1,41 @@
+package yaml
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestUnmarshalSecrets(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []Secret
+	}{
+		{in: "[docker_password]", want: []Secret{{Source: "docker_password", Target: "docker_password"}}},
+		{in: "[{source: docker_password, target: PLUGIN_PASSWORD}]", want: []Secret{{Source: "docker_password", Target: "PLUGIN_PASSWORD"}}},
+		{in: "[{source: docker_password}]", want: []Secret{{Source: "docker_password", Target: "docker_password"}}},
+		{
+			in: "[docker_username, {source: docker_password, target: PLUGIN_PASSWORD}]",
+			want: []Secret{
+				{Source: "docker_username", Target: "docker_username"},
+				{Source: "docker_password", Target: "PLUGIN_PASSWORD"},
+			},
+		},
+	}
+	for _, test := range tests {
+		got := Secrets{}
+		if err := yaml.Unmarshal([]byte(test.in), &got); err != nil {
+			t.Errorf("Want secrets %q parsed, got %s", test.in, err)
+			continue
+		}
+		if len(got.Secrets) != len(test.want) {
+			t.Errorf("Want %d secrets parsed from %q, got %d", len(test.want), test.in, len(got.Secrets))
+			continue
+		}
+		for i, secret := range got.Secrets {
+			if *secret != test.want[i] {
+				t.Errorf("Want secret %+v, got %+v", test.want[i], *secret)
+			}
+		}
+	}
+}
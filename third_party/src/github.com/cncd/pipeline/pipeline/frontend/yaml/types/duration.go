@@ -0,0 +1,34 @@
+package types
+
+import "time"
+
+// Duration is a custom Yaml duration type, which is a duration string such
+// as 10m, or a number of minutes.
+type Duration struct {
+	value time.Duration
+}
+
+// UnmarshalYAML implements custom Yaml unmarshaling.
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var minutes int64
+	if err := unmarshal(&minutes); err == nil {
+		d.value = time.Duration(minutes) * time.Minute
+		return nil
+	}
+
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	value, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.value = value
+	return nil
+}
+
+// Duration returns the duration value.
+func (d Duration) Duration() time.Duration {
+	return d.value
+}
@@ -12,14 +12,45 @@ type (
 		Volumes []*Volume
 	}
 
-	// Volume defines a container volume.
+	// Volume defines a container volume, which is a host path, a temporary
+	// volume shared by the steps of the pipeline, or otherwise a named
+	// volume that persists between the builds of the repository.
 	Volume struct {
 		Name       string            `yaml:"name,omitempty"`
 		Driver     string            `yaml:"driver,omitempty"`
 		DriverOpts map[string]string `yaml:"driver_opts,omitempty"`
+		Host       *HostVolume       `yaml:"host,omitempty"`
+		Temp       *TempVolume       `yaml:"temp,omitempty"`
+	}
+
+	// HostVolume defines a volume that mounts a host path.
+	HostVolume struct {
+		Path string `yaml:"path,omitempty"`
+	}
+
+	// TempVolume defines a temporary volume, which is removed when the
+	// pipeline is complete. The memory medium stores the volume in memory.
+	TempVolume struct {
+		Medium string `yaml:"medium,omitempty"`
 	}
 )
 
+// Find returns the named volume, or nil if the volume is not defined.
+func (v *Volumes) Find(name string) *Volume {
+	for _, volume := range v.Volumes {
+		if volume.Name == name {
+			return volume
+		}
+	}
+	return nil
+}
+
+// IsNamed returns true if the volume is a named volume that persists
+// between builds.
+func (v *Volume) IsNamed() bool {
+	return v.Host == nil && v.Temp == nil
+}
+
 // UnmarshalYAML implements the Unmarshaller interface.
 func (v *Volumes) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	slice := yaml.MapSlice{}
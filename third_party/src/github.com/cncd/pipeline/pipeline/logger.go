@@ -1,6 +1,8 @@
 package pipeline
 
 import (
+	"io"
+
 	"github.com/cncd/pipeline/pipeline/backend"
 	"github.com/cncd/pipeline/pipeline/multipart"
 )
@@ -10,6 +12,12 @@ type Logger interface {
 	Log(*backend.Step, multipart.Reader) error
 }
 
+// PullLogger is implemented by loggers that log the image pull of the
+// process, which is reported by engines that implement backend.Puller.
+type PullLogger interface {
+	LogPull(*backend.Step, io.Reader) error
+}
+
 // LogFunc type is an adapter to allow the use of an ordinary
 // function for process logging.
 type LogFunc func(*backend.Step, multipart.Reader) error
@@ -0,0 +1,83 @@
+package pipeline
+
+import (
+	"archive/tar"
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/cncd/pipeline/pipeline/backend"
+)
+
+// outputName matches the valid names of output variables.
+var outputName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// applyOutputs adds the output variables of the completed steps to the
+// environment of the process. The output variables do not override the
+// environment defined by the pipeline.
+func (r *Runtime) applyOutputs(proc *backend.Step) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.outputs) != 0 && proc.Environment == nil {
+		proc.Environment = map[string]string{}
+	}
+	for k, v := range r.outputs {
+		if _, ok := proc.Environment[k]; !ok {
+			proc.Environment[k] = v
+		}
+	}
+}
+
+// readOutput copies the output file from the completed process, and saves
+// the output variables for the steps that start afterwards. A missing
+// output file is ignored.
+func (r *Runtime) readOutput(proc *backend.Step) {
+	copier, ok := r.engine.(backend.Copier)
+	if !ok || proc.Output == "" {
+		return
+	}
+	rc, err := copier.Copy(proc, proc.Output)
+	if err != nil {
+		return
+	}
+	defer rc.Close()
+
+	tr := tar.NewReader(rc)
+	header, err := tr.Next()
+	if err != nil || header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+		return
+	}
+	outputs := parseOutput(tr)
+
+	r.mu.Lock()
+	for k, v := range outputs {
+		r.outputs[k] = v
+	}
+	r.mu.Unlock()
+}
+
+// parseOutput parses the name=value lines of the output file. Empty lines,
+// comments and lines with an invalid name are skipped, and the last value
+// of a name wins.
+func parseOutput(r io.Reader) map[string]string {
+	outputs := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		name := strings.TrimSpace(parts[0])
+		if !outputName.MatchString(name) {
+			continue
+		}
+		outputs[name] = parts[1]
+	}
+	return outputs
+}
@@ -0,0 +1,99 @@
+package pipeline
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/cncd/pipeline/pipeline/backend"
+)
+
+// outputEngine fakes an engine that copies the output files of the steps,
+// and records the environment of the executed steps.
+type outputEngine struct {
+	exitEngine
+
+	files   map[string]string
+	environ map[string]map[string]string
+}
+
+func (e *outputEngine) Exec(proc *backend.Step) error {
+	e.Lock()
+	e.environ[proc.Name] = proc.Environment
+	e.Unlock()
+	return e.exitEngine.Exec(proc)
+}
+
+func (e *outputEngine) Copy(proc *backend.Step, path string) (io.ReadCloser, error) {
+	data, ok := e.files[path]
+	if !ok {
+		return nil, errors.New("no such file")
+	}
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "output", Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg})
+	tw.Write([]byte(data))
+	tw.Close()
+	return ioutil.NopCloser(&buf), nil
+}
+
+func TestRunOutputs(t *testing.T) {
+	engine := &outputEngine{
+		exitEngine: exitEngine{execs: map[string]int{}},
+		files: map[string]string{
+			"/drone/.drone_output_version": "VERSION=1.2.3\nTAG=latest\n",
+		},
+		environ: map[string]map[string]string{},
+	}
+	spec := &backend.Config{Stages: []*backend.Stage{
+		{Steps: []*backend.Step{{Name: "version", OnSuccess: true, Output: "/drone/.drone_output_version"}}},
+		{Steps: []*backend.Step{{Name: "build", OnSuccess: true, Output: "/drone/.drone_output_build"}}},
+		{Steps: []*backend.Step{{Name: "publish", OnSuccess: true, Environment: map[string]string{"TAG": "stable"}}}},
+	}}
+	if err := New(spec, WithEngine(engine)).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got := engine.environ["version"]["VERSION"]; got != "" {
+		t.Errorf("Want no output variables before the step completes, got %q", got)
+	}
+	if got := engine.environ["build"]["VERSION"]; got != "1.2.3" {
+		t.Errorf("Want output variable passed to the next step, got %q", got)
+	}
+	if got := engine.environ["publish"]["VERSION"]; got != "1.2.3" {
+		t.Errorf("Want output variable kept after a step without output, got %q", got)
+	}
+	if got := engine.environ["publish"]["TAG"]; got != "stable" {
+		t.Errorf("Want step environment not overridden by the output variables, got %q", got)
+	}
+}
+
+func TestParseOutput(t *testing.T) {
+	outputs := parseOutput(strings.NewReader(strings.Join([]string{
+		"# the release version",
+		"VERSION=1.2.3",
+		"",
+		"  TAG=latest ",
+		"VERSION=1.2.4",
+		"URL=https://example.com/?a=b",
+		"1INVALID=true",
+		"NO_VALUE",
+	}, "\n")))
+
+	want := map[string]string{
+		"VERSION": "1.2.4",
+		"TAG":     "latest",
+		"URL":     "https://example.com/?a=b",
+	}
+	if len(outputs) != len(want) {
+		t.Errorf("Want %d output variables, got %v", len(want), outputs)
+	}
+	for k, v := range want {
+		if outputs[k] != v {
+			t.Errorf("Want output variable %s=%q, got %q", k, v, outputs[k])
+		}
+	}
+}
@@ -2,6 +2,10 @@ package pipeline
 
 import (
 	"context"
+	"io"
+	"io/ioutil"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/sync/errgroup"
@@ -21,6 +25,8 @@ type (
 			Step *backend.Step `json:"step"`
 			// Current pipeline error state
 			Error error `json:"error"`
+			// Current pipeline killed state
+			Killed bool `json:"killed"`
 		}
 
 		// Current process state.
@@ -30,7 +36,12 @@ type (
 
 // Runtime is a configuration runtime.
 type Runtime struct {
+	mu      sync.Mutex
 	err     error
+	killed  bool
+	running map[*backend.Step]bool
+	outputs map[string]string
+	timers  []*time.Timer
 	spec    *backend.Config
 	engine  backend.Engine
 	started int64
@@ -46,6 +57,8 @@ func New(spec *backend.Config, opts ...Option) *Runtime {
 	r := new(Runtime)
 	r.spec = spec
 	r.ctx = context.Background()
+	r.running = map[*backend.Step]bool{}
+	r.outputs = map[string]string{}
 	for _, opts := range opts {
 		opts(r)
 	}
@@ -55,6 +68,7 @@ func New(spec *backend.Config, opts ...Option) *Runtime {
 // Run starts the runtime and waits for it to complete.
 func (r *Runtime) Run() error {
 	defer func() {
+		r.stopTimers()
 		r.engine.Destroy(r.spec)
 	}()
 
@@ -63,20 +77,90 @@ func (r *Runtime) Run() error {
 		return err
 	}
 
+	// when the pipeline is cancelled the running steps are killed, and
+	// the remaining stages are executed, of which only the steps that
+	// run when the pipeline is killed are started.
+	cancel := r.ctx.Done()
 	for _, stage := range r.spec.Stages {
+		done := r.execStage(stage.Steps)
 		select {
-		case <-r.ctx.Done():
-			return ErrCancel
-		case err := <-r.execAll(stage.Steps):
+		case <-cancel:
+			cancel = nil
+			r.kill()
+			<-done
+		case err := <-done:
 			if err != nil {
-				r.err = err
+				r.fail(err)
 			}
 		}
 	}
 
+	if r.isKilled() {
+		return ErrCancel
+	}
+	return r.error()
+}
+
+// error returns the error of the pipeline.
+func (r *Runtime) error() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	return r.err
 }
 
+// fail sets the error of the pipeline.
+func (r *Runtime) fail(err error) {
+	r.mu.Lock()
+	r.err = err
+	r.mu.Unlock()
+}
+
+// isKilled returns true if the pipeline is killed.
+func (r *Runtime) isKilled() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.killed
+}
+
+// kill marks the pipeline killed, and kills the running steps, except the
+// steps that run when the pipeline is killed.
+func (r *Runtime) kill() {
+	var procs []*backend.Step
+	r.mu.Lock()
+	r.killed = true
+	for proc := range r.running {
+		if !proc.OnKilled {
+			procs = append(procs, proc)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, proc := range procs {
+		r.engine.Kill(proc)
+	}
+}
+
+// cancelled returns a channel that is closed when the execution of the
+// process is cancelled. The processes that run when the pipeline is killed
+// are not cancelled.
+func (r *Runtime) cancelled(proc *backend.Step) <-chan struct{} {
+	if proc.OnKilled {
+		return nil
+	}
+	return r.ctx.Done()
+}
+
+// execStage executes the steps of the stage in parallel, or as a graph if
+// any step declares its dependencies.
+func (r *Runtime) execStage(procs []*backend.Step) <-chan error {
+	for _, proc := range procs {
+		if len(proc.DependsOn) != 0 {
+			return r.execGraph(procs)
+		}
+	}
+	return r.execAll(procs)
+}
+
 //
 //
 //
@@ -99,22 +183,72 @@ func (r *Runtime) execAll(procs []*backend.Step) <-chan error {
 	return done
 }
 
+// execGraph executes the steps in dependency order with maximal
+// parallelism. Each step starts as soon as the steps it depends on are
+// complete, and the error of a failed step is set immediately, so that the
+// steps that start afterwards are skipped unless they run on failure.
+// Dependencies on steps of other stages are ignored, since earlier stages
+// are complete.
+func (r *Runtime) execGraph(procs []*backend.Step) <-chan error {
+	var g errgroup.Group
+	done := make(chan error)
+
+	complete := map[string]chan struct{}{}
+	for _, proc := range procs {
+		complete[proc.Alias] = make(chan struct{})
+	}
+
+	for _, proc := range procs {
+		proc := proc
+		g.Go(func() error {
+			defer close(complete[proc.Alias])
+			for _, dep := range proc.DependsOn {
+				wait, ok := complete[dep]
+				if !ok || dep == proc.Alias {
+					continue
+				}
+				select {
+				case <-r.cancelled(proc):
+					return ErrCancel
+				case <-wait:
+				}
+			}
+			err := r.exec(proc)
+			if err != nil {
+				r.fail(err)
+			}
+			return err
+		})
+	}
+
+	go func() {
+		done <- g.Wait()
+		close(done)
+	}()
+	return done
+}
+
 //
 //
 //
 
 func (r *Runtime) exec(proc *backend.Step) error {
-	switch {
-	case r.err != nil && proc.OnFailure == false:
+	switch err := r.error(); {
+	case r.isKilled():
+		if proc.OnKilled == false {
+			return nil
+		}
+	case err != nil && proc.OnFailure == false:
 		return nil
-	case r.err == nil && proc.OnSuccess == false:
+	case err == nil && proc.OnSuccess == false:
 		return nil
 	}
 
 	if r.tracer != nil {
 		state := new(State)
 		state.Pipeline.Time = r.started
-		state.Pipeline.Error = r.err
+		state.Pipeline.Error = r.error()
+		state.Pipeline.Killed = r.isKilled()
 		state.Pipeline.Step = proc
 		state.Process = new(backend.State) // empty
 		if err := r.tracer.Trace(state); err == ErrSkip {
@@ -124,35 +258,28 @@ func (r *Runtime) exec(proc *backend.Step) error {
 		}
 	}
 
-	if err := r.engine.Exec(proc); err != nil {
-		return err
-	}
-
-	if r.logger != nil {
-		rc, err := r.engine.Tail(proc)
-		if err != nil {
-			return err
+	wait, err := r.attempt(proc)
+	for i := 0; i < proc.Retries && retryable(proc, wait, err); i++ {
+		select {
+		case <-r.cancelled(proc):
+			return ErrCancel
+		case <-time.After(proc.RetryBackoff << uint(i)):
 		}
-
-		go func() {
-			r.logger.Log(proc, multipart.New(rc))
-			rc.Close()
-		}()
-	}
-
-	if proc.Detached {
-		return nil
+		wait, err = r.attempt(proc)
 	}
-
-	wait, err := r.engine.Wait(proc)
 	if err != nil {
 		return err
 	}
+	if wait == nil {
+		return nil // detached
+	}
+	r.readOutput(proc)
 
 	if r.tracer != nil {
 		state := new(State)
 		state.Pipeline.Time = r.started
-		state.Pipeline.Error = r.err
+		state.Pipeline.Error = r.error()
+		state.Pipeline.Killed = r.isKilled()
 		state.Pipeline.Step = proc
 		state.Process = wait
 		if err := r.tracer.Trace(state); err != nil {
@@ -160,7 +287,18 @@ func (r *Runtime) exec(proc *backend.Step) error {
 		}
 	}
 
-	if wait.OOMKilled {
+	// the failure of the process is reported to the tracer, but does not
+	// fail the pipeline if the failure is ignored.
+	if proc.AllowFailure {
+		return nil
+	}
+
+	if wait.TimedOut {
+		return &TimeoutError{
+			Name:    proc.Name,
+			Timeout: proc.Timeout,
+		}
+	} else if wait.OOMKilled {
 		return &OomError{
 			Name: proc.Name,
 			Code: wait.ExitCode,
@@ -173,3 +311,151 @@ func (r *Runtime) exec(proc *backend.Step) error {
 	}
 	return nil
 }
+
+// attempt starts the process, streams its logs and waits for it to
+// complete. A nil state is returned for detached processes.
+func (r *Runtime) attempt(proc *backend.Step) (*backend.State, error) {
+	r.mu.Lock()
+	if r.killed && !proc.OnKilled {
+		r.mu.Unlock()
+		return nil, ErrCancel
+	}
+	r.running[proc] = true
+	r.mu.Unlock()
+
+	r.applyOutputs(proc)
+
+	defer func() {
+		r.mu.Lock()
+		delete(r.running, proc)
+		r.mu.Unlock()
+	}()
+
+	if err := r.pull(proc); err != nil {
+		return nil, err
+	}
+	if err := r.engine.Exec(proc); err != nil {
+		return nil, err
+	}
+
+	// the process is killed if the pipeline was killed while the process
+	// was starting.
+	if r.isKilled() && !proc.OnKilled {
+		r.engine.Kill(proc)
+	}
+	timedOut := r.timeout(proc)
+
+	if r.logger != nil {
+		rc, err := r.engine.Tail(proc)
+		if err != nil {
+			return nil, err
+		}
+
+		go func() {
+			r.logger.Log(proc, multipart.New(rc))
+			rc.Close()
+		}()
+	}
+
+	// detached processes, such as services, are killed after their
+	// timeout while the pipeline is running.
+	if proc.Detached {
+		return nil, r.waitHealthy(proc)
+	}
+
+	state, err := r.engine.Wait(proc)
+	if err == nil && timedOut() {
+		state.TimedOut = true
+	}
+	return state, err
+}
+
+// pull pulls the image of the process before it is started, if the engine
+// pulls images separately, and streams the pull progress to the logger.
+func (r *Runtime) pull(proc *backend.Step) error {
+	puller, ok := r.engine.(backend.Puller)
+	if !ok || !proc.Pull {
+		return nil
+	}
+	rc, err := puller.Pull(proc)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	if logger, ok := r.logger.(PullLogger); ok {
+		return logger.LogPull(proc, rc)
+	}
+	_, err = io.Copy(ioutil.Discard, rc)
+	return err
+}
+
+// waitHealthy waits until the readiness probe of the detached process
+// succeeds, so that the steps that follow do not start before the process
+// is ready.
+func (r *Runtime) waitHealthy(proc *backend.Step) error {
+	prober, ok := r.engine.(backend.Prober)
+	if !ok || proc.HealthCheck == nil {
+		return nil
+	}
+	interval := proc.HealthCheck.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	for {
+		healthy, err := prober.Healthy(proc)
+		if err != nil || healthy {
+			return err
+		}
+		select {
+		case <-r.cancelled(proc):
+			return ErrCancel
+		case <-time.After(interval):
+		}
+	}
+}
+
+// timeout kills the process if it does not complete before its timeout,
+// and returns a function that stops the timer and reports whether the
+// process was killed.
+func (r *Runtime) timeout(proc *backend.Step) func() bool {
+	if proc.Timeout <= 0 {
+		return func() bool { return false }
+	}
+	var fired int32
+	timer := time.AfterFunc(proc.Timeout, func() {
+		atomic.StoreInt32(&fired, 1)
+		r.engine.Kill(proc)
+	})
+	r.mu.Lock()
+	r.timers = append(r.timers, timer)
+	r.mu.Unlock()
+
+	return func() bool {
+		timer.Stop()
+		return atomic.LoadInt32(&fired) == 1
+	}
+}
+
+// stopTimers stops the timeouts of the processes.
+func (r *Runtime) stopTimers() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, timer := range r.timers {
+		timer.Stop()
+	}
+}
+
+// retryable returns true if the process exited with a non-zero exit
+// code that was not caused by the kernel oom killer, or with an exit code
+// that matches the retry condition of the process.
+func retryable(proc *backend.Step, state *backend.State, err error) bool {
+	if err != nil || state == nil || state.ExitCode == 0 {
+		return false
+	}
+	if proc.RetryWhen == "" {
+		return !state.OOMKilled
+	}
+	match, err := backend.MatchExitCode(proc.RetryWhen, state.ExitCode)
+	return err == nil && match
+}
@@ -0,0 +1,338 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cncd/pipeline/pipeline/backend"
+	"github.com/cncd/pipeline/pipeline/multipart"
+)
+
+// exitEngine fakes an engine where each step exits with the next of its
+// exit codes, and with the last exit code once the list is exhausted. Steps
+// without exit codes succeed.
+type exitEngine struct {
+	sync.Mutex
+
+	codes map[string][]int
+	oom   bool
+	execs map[string]int
+	order []string
+}
+
+func (e *exitEngine) Setup(*backend.Config) error   { return nil }
+func (e *exitEngine) Destroy(*backend.Config) error { return nil }
+func (e *exitEngine) Kill(*backend.Step) error      { return nil }
+
+func (e *exitEngine) Exec(proc *backend.Step) error {
+	e.Lock()
+	e.execs[proc.Name]++
+	e.order = append(e.order, proc.Name)
+	e.Unlock()
+	return nil
+}
+
+func (e *exitEngine) Wait(proc *backend.Step) (*backend.State, error) {
+	e.Lock()
+	defer e.Unlock()
+	codes := e.codes[proc.Name]
+	if len(codes) == 0 {
+		return &backend.State{Exited: true}, nil
+	}
+	i := e.execs[proc.Name] - 1
+	if i >= len(codes) {
+		i = len(codes) - 1
+	}
+	return &backend.State{Exited: true, ExitCode: codes[i], OOMKilled: e.oom}, nil
+}
+
+func (e *exitEngine) Tail(*backend.Step) (io.ReadCloser, error) {
+	return ioutil.NopCloser(strings.NewReader("")), nil
+}
+
+// killEngine fakes an engine where the blocking step runs until it is
+// killed.
+type killEngine struct {
+	exitEngine
+
+	block   string
+	started chan struct{}
+	killed  chan struct{}
+}
+
+func (e *killEngine) Kill(proc *backend.Step) error {
+	if proc.Name == e.block {
+		close(e.killed)
+	}
+	return nil
+}
+
+func (e *killEngine) Wait(proc *backend.Step) (*backend.State, error) {
+	if proc.Name != e.block {
+		return e.exitEngine.Wait(proc)
+	}
+	close(e.started)
+	<-e.killed
+	return &backend.State{Exited: true, ExitCode: 137}, nil
+}
+
+// probeEngine fakes an engine where the detached steps become healthy
+// after the number of probes, or are unhealthy if the count is negative.
+type probeEngine struct {
+	exitEngine
+
+	probes map[string]int
+}
+
+func (e *probeEngine) Healthy(proc *backend.Step) (bool, error) {
+	e.Lock()
+	defer e.Unlock()
+	if e.probes[proc.Name] < 0 {
+		return false, errors.New(proc.Name + " is unhealthy")
+	}
+	e.probes[proc.Name]--
+	return e.probes[proc.Name] < 0, nil
+}
+
+func TestRunRetries(t *testing.T) {
+	tests := []struct {
+		codes   []int
+		retries int
+		when    string
+		oom     bool
+		execs   int
+		code    int
+	}{
+		{codes: []int{0}, retries: 2, execs: 1},
+		{codes: []int{1, 0}, retries: 2, execs: 2},
+		{codes: []int{1}, retries: 2, execs: 3, code: 1},
+		{codes: []int{1}, execs: 1, code: 1},
+		{codes: []int{137}, retries: 2, oom: true, execs: 1, code: 137},
+		{codes: []int{2, 0}, retries: 2, when: "exit_code == 1", execs: 1, code: 2},
+		{codes: []int{1, 0}, retries: 2, when: "exit_code == 1", execs: 2},
+	}
+	for i, test := range tests {
+		proc := &backend.Step{
+			Name:      "build",
+			OnSuccess: true,
+			Retries:   test.retries,
+			RetryWhen: test.when,
+		}
+		engine := &exitEngine{
+			codes: map[string][]int{"build": test.codes},
+			oom:   test.oom,
+			execs: map[string]int{},
+		}
+		spec := &backend.Config{Stages: []*backend.Stage{{Steps: []*backend.Step{proc}}}}
+		err := New(spec, WithEngine(engine)).Run()
+
+		if got := engine.execs["build"]; got != test.execs {
+			t.Errorf("Want step %d executed %d times, got %d", i, test.execs, got)
+		}
+		var code int
+		switch err := err.(type) {
+		case nil:
+		case *ExitError:
+			code = err.Code
+		case *OomError:
+			code = err.Code
+		default:
+			t.Errorf("Want step %d exit error, got %v", i, err)
+		}
+		if code != test.code {
+			t.Errorf("Want step %d exit code %d, got %d", i, test.code, code)
+		}
+	}
+}
+
+func TestRunGraph(t *testing.T) {
+	steps := func() []*backend.Step {
+		return []*backend.Step{
+			{Name: "d", Alias: "d", OnSuccess: true, DependsOn: []string{"b", "c"}},
+			{Name: "b", Alias: "b", OnSuccess: true, DependsOn: []string{"a"}},
+			{Name: "c", Alias: "c", OnSuccess: true, DependsOn: []string{"a"}},
+			{Name: "a", Alias: "a", OnSuccess: true},
+			{Name: "notify", Alias: "notify", OnSuccess: true, OnFailure: true, DependsOn: []string{"d"}},
+		}
+	}
+
+	engine := &exitEngine{execs: map[string]int{}}
+	spec := &backend.Config{Stages: []*backend.Stage{{Steps: steps()}}}
+	if err := New(spec, WithEngine(engine)).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if len(engine.order) != 5 || engine.order[0] != "a" || engine.order[3] != "d" || engine.order[4] != "notify" {
+		t.Errorf("Want steps executed in dependency order, got %v", engine.order)
+	}
+
+	// the steps that depend on a failed step are skipped, unless they run
+	// on failure.
+	engine = &exitEngine{codes: map[string][]int{"a": {1}}, execs: map[string]int{}}
+	spec = &backend.Config{Stages: []*backend.Stage{{Steps: steps()}}}
+	if err := New(spec, WithEngine(engine)).Run(); err == nil {
+		t.Errorf("Want pipeline failed")
+	}
+	if got := strings.Join(engine.order, ","); got != "a,notify" {
+		t.Errorf("Want dependent steps skipped after a failure, got %s", got)
+	}
+}
+
+func TestRunAllowFailure(t *testing.T) {
+	engine := &exitEngine{codes: map[string][]int{"lint": {1}}, execs: map[string]int{}}
+	spec := &backend.Config{Stages: []*backend.Stage{
+		{Steps: []*backend.Step{{Name: "lint", OnSuccess: true, AllowFailure: true}}},
+		{Steps: []*backend.Step{{Name: "build", OnSuccess: true}}},
+	}}
+	if err := New(spec, WithEngine(engine)).Run(); err != nil {
+		t.Errorf("Want pipeline passed when the failure is allowed, got %s", err)
+	}
+	if got := strings.Join(engine.order, ","); got != "lint,build" {
+		t.Errorf("Want steps after the allowed failure executed, got %s", got)
+	}
+}
+
+func TestRunKilled(t *testing.T) {
+	engine := &killEngine{
+		exitEngine: exitEngine{execs: map[string]int{}},
+		block:      "build",
+		started:    make(chan struct{}),
+		killed:     make(chan struct{}),
+	}
+	spec := &backend.Config{Stages: []*backend.Stage{
+		{Steps: []*backend.Step{{Name: "build", OnSuccess: true}}},
+		{Steps: []*backend.Step{{Name: "deploy", OnSuccess: true}}},
+		{Steps: []*backend.Step{{Name: "notify", OnSuccess: true, OnFailure: true}}},
+		{Steps: []*backend.Step{{Name: "cleanup", OnSuccess: true, OnFailure: true, OnKilled: true}}},
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-engine.started
+		cancel()
+	}()
+	if err := New(spec, WithEngine(engine), WithContext(ctx)).Run(); err != ErrCancel {
+		t.Errorf("Want pipeline cancelled, got %v", err)
+	}
+	if got := strings.Join(engine.order, ","); got != "build,cleanup" {
+		t.Errorf("Want only the steps that run when killed executed after the cancel, got %s", got)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	engine := &killEngine{
+		exitEngine: exitEngine{execs: map[string]int{}},
+		block:      "build",
+		started:    make(chan struct{}),
+		killed:     make(chan struct{}),
+	}
+	spec := &backend.Config{Stages: []*backend.Stage{
+		{Steps: []*backend.Step{{Name: "build", OnSuccess: true, Timeout: 10 * time.Millisecond}}},
+		{Steps: []*backend.Step{{Name: "test", OnSuccess: true, Timeout: time.Minute}}},
+	}}
+	err := New(spec, WithEngine(engine)).Run()
+	if err, ok := err.(*TimeoutError); !ok || err.Name != "build" || err.Timeout != 10*time.Millisecond {
+		t.Errorf("Want build step timed out, got %v", err)
+	}
+	if got := strings.Join(engine.order, ","); got != "build" {
+		t.Errorf("Want steps skipped after the timeout, got %s", got)
+	}
+}
+
+func TestRunHealthy(t *testing.T) {
+	steps := func() []*backend.Stage {
+		check := &backend.HealthCheck{Test: "pg_isready", Interval: time.Millisecond}
+		return []*backend.Stage{
+			{Steps: []*backend.Step{{Name: "database", Detached: true, OnSuccess: true, HealthCheck: check}}},
+			{Steps: []*backend.Step{{Name: "test", OnSuccess: true}}},
+		}
+	}
+
+	engine := &probeEngine{exitEngine: exitEngine{execs: map[string]int{}}, probes: map[string]int{"database": 3}}
+	if err := New(&backend.Config{Stages: steps()}, WithEngine(engine)).Run(); err != nil {
+		t.Errorf("Want pipeline passed once the service is healthy, got %s", err)
+	}
+	if got := engine.probes["database"]; got != -1 {
+		t.Errorf("Want the service probed until healthy, got %d probes left", got)
+	}
+	if got := strings.Join(engine.order, ","); got != "database,test" {
+		t.Errorf("Want steps executed after the service is healthy, got %s", got)
+	}
+
+	engine = &probeEngine{exitEngine: exitEngine{execs: map[string]int{}}, probes: map[string]int{"database": -1}}
+	if err := New(&backend.Config{Stages: steps()}, WithEngine(engine)).Run(); err == nil {
+		t.Errorf("Want pipeline failed when the service is unhealthy")
+	}
+	if got := strings.Join(engine.order, ","); got != "database" {
+		t.Errorf("Want steps skipped when the service is unhealthy, got %s", got)
+	}
+}
+
+// pullEngine fakes an engine that pulls the images of the steps before
+// they are started.
+type pullEngine struct {
+	exitEngine
+}
+
+func (e *pullEngine) Pull(proc *backend.Step) (io.ReadCloser, error) {
+	e.Lock()
+	e.order = append(e.order, "pull:"+proc.Name)
+	e.Unlock()
+	return ioutil.NopCloser(strings.NewReader("Pulling " + proc.Image)), nil
+}
+
+// pullLogger records the pull progress of the steps.
+type pullLogger struct {
+	LogFunc
+
+	pulls map[string]string
+}
+
+func (l *pullLogger) LogPull(proc *backend.Step, r io.Reader) error {
+	out, err := ioutil.ReadAll(r)
+	l.pulls[proc.Name] = string(out)
+	return err
+}
+
+func TestRunPull(t *testing.T) {
+	steps := []*backend.Stage{
+		{Steps: []*backend.Step{{Name: "build", Image: "golang", Pull: true, OnSuccess: true}}},
+		{Steps: []*backend.Step{{Name: "test", Image: "golang", OnSuccess: true}}},
+	}
+	engine := &pullEngine{exitEngine: exitEngine{execs: map[string]int{}}}
+	logger := &pullLogger{
+		LogFunc: func(*backend.Step, multipart.Reader) error { return nil },
+		pulls:   map[string]string{},
+	}
+	if err := New(&backend.Config{Stages: steps}, WithEngine(engine), WithLogger(logger)).Run(); err != nil {
+		t.Error(err)
+		return
+	}
+	if got := strings.Join(engine.order, ","); got != "pull:build,build,test" {
+		t.Errorf("Want the image pulled before the step is started, got %s", got)
+	}
+	if got := logger.pulls["build"]; got != "Pulling golang" {
+		t.Errorf("Want the pull progress logged, got %q", got)
+	}
+	if _, ok := logger.pulls["test"]; ok {
+		t.Errorf("Want the image not pulled unless requested")
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	proc := &backend.Step{}
+	if retryable(proc, &backend.State{ExitCode: 1}, errors.New("exec failed")) {
+		t.Errorf("Want step not retried when the engine fails")
+	}
+	if retryable(proc, nil, nil) {
+		t.Errorf("Want detached step not retried")
+	}
+	if !retryable(proc, &backend.State{ExitCode: 1}, nil) {
+		t.Errorf("Want failed step retried")
+	}
+}
@@ -16,6 +16,15 @@ const (
 	LineProgress
 )
 
+// Identifies the step phase of a line in the logs. The pull phase pulls
+// the image, the setup phase creates the container, and the run phase
+// writes the output of the container.
+const (
+	PhasePull  = "pull"
+	PhaseSetup = "setup"
+	PhaseRun   = "run"
+)
+
 // Line is a line of console output.
 type Line struct {
 	Proc string `json:"proc,omitempty"`
@@ -23,6 +32,15 @@ type Line struct {
 	Type int    `json:"type,omitempty"`
 	Pos  int    `json:"pos,omityempty"`
 	Out  string `json:"out,omitempty"`
+
+	// Elapsed is the number of milliseconds spent in the phase of the
+	// line, measured using the monotonic clock.
+	Elapsed int64  `json:"elapsed,omitempty"`
+	Phase   string `json:"phase,omitempty"`
+
+	// Attempt is the attempt of a retried step, starting at 1. It is
+	// omitted for steps that are not retried.
+	Attempt int `json:"attempt,omitempty"`
 }
 
 func (l *Line) String() string {
@@ -41,6 +59,8 @@ type LineWriter struct {
 	name  string
 	num   int
 	now   time.Time
+	since time.Time
+	retry int
 	rep   *strings.Replacer
 	lines []*Line
 }
@@ -52,7 +72,8 @@ func NewLineWriter(peer Peer, id, name string, secret ...string) *LineWriter {
 	w.id = id
 	w.name = name
 	w.num = 0
-	w.now = time.Now().UTC()
+	w.now = time.Now()
+	w.since = w.now
 
 	var oldnew []string
 	for _, old := range secret {
@@ -71,13 +92,7 @@ func (w *LineWriter) Write(p []byte) (n int, err error) {
 		out = w.rep.Replace(out)
 	}
 
-	line := &Line{
-		Out:  out,
-		Proc: w.name,
-		Pos:  w.num,
-		Time: int64(time.Since(w.now).Seconds()),
-		Type: LineStdout,
-	}
+	line := w.line(LineStdout, out)
 	w.peer.Log(context.Background(), w.id, line)
 	w.num++
 
@@ -96,6 +111,54 @@ func (w *LineWriter) Write(p []byte) (n int, err error) {
 	return len(p), nil
 }
 
+// Pull writes a progress line of the image pull, which is attached to the
+// pull phase.
+func (w *LineWriter) Pull(out string) {
+	if w.rep != nil {
+		out = w.rep.Replace(out)
+	}
+	line := w.line(LineProgress, out)
+	line.Phase = PhasePull
+	w.peer.Log(context.Background(), w.id, line)
+	w.num++
+	w.lines = append(w.lines, line)
+}
+
+// Meta writes a metadata line reporting the time spent in the given
+// step phase, and starts measuring the elapsed time of the next phase.
+func (w *LineWriter) Meta(phase string, d time.Duration) {
+	line := w.line(LineMetadata, fmt.Sprintf("%s completed in %s", phase, d))
+	line.Phase = phase
+	line.Elapsed = int64(d / time.Millisecond)
+	w.peer.Log(context.Background(), w.id, line)
+	w.num++
+	w.lines = append(w.lines, line)
+	w.since = time.Now()
+}
+
+// Attempt writes a metadata line marking the start of the given attempt of
+// a retried step, and attaches the attempt to subsequent lines.
+func (w *LineWriter) Attempt(attempt, attempts int) {
+	w.retry = attempt
+	line := w.line(LineMetadata, fmt.Sprintf("attempt %d of %d", attempt, attempts))
+	w.peer.Log(context.Background(), w.id, line)
+	w.num++
+	w.lines = append(w.lines, line)
+}
+
+func (w *LineWriter) line(typ int, out string) *Line {
+	return &Line{
+		Out:     out,
+		Proc:    w.name,
+		Pos:     w.num,
+		Time:    int64(time.Since(w.now).Seconds()),
+		Type:    typ,
+		Elapsed: int64(time.Since(w.since) / time.Millisecond),
+		Phase:   PhaseRun,
+		Attempt: w.retry,
+	}
+}
+
 // Lines returns the line history
 func (w *LineWriter) Lines() []*Line {
 	return w.lines
@@ -0,0 +1,68 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// logPeer records the log lines.
+type logPeer struct {
+	Peer
+
+	lines []*Line
+}
+
+func (p *logPeer) Log(c context.Context, id string, line *Line) error {
+	p.lines = append(p.lines, line)
+	return nil
+}
+
+func TestLineWriterAttempt(t *testing.T) {
+	peer := new(logPeer)
+	w := NewLineWriter(peer, "1", "build", "correct-horse")
+	w.Write([]byte("go build"))
+	w.Attempt(2, 3)
+	w.Write([]byte("password correct-horse"))
+
+	if len(peer.lines) != 3 {
+		t.Fatalf("Want 3 lines, got %d", len(peer.lines))
+	}
+	if line := peer.lines[0]; line.Attempt != 0 {
+		t.Errorf("Want no attempt before the retry, got %d", line.Attempt)
+	}
+	if line := peer.lines[1]; line.Type != LineMetadata || line.Out != "attempt 2 of 3" || line.Pos != 1 {
+		t.Errorf("Want attempt metadata line, got %+v", line)
+	}
+	if line := peer.lines[2]; line.Attempt != 2 || line.Out != "password ********" || line.Pos != 2 {
+		t.Errorf("Want masked line of attempt 2, got %+v", line)
+	}
+	if len(w.Lines()) != 3 {
+		t.Errorf("Want line history of 3 lines, got %d", len(w.Lines()))
+	}
+}
+
+func TestLineWriterPull(t *testing.T) {
+	peer := new(logPeer)
+	w := NewLineWriter(peer, "1", "build")
+	w.Pull("Pulling from library/golang")
+	w.Meta(PhasePull, time.Second)
+	w.Meta(PhaseSetup, time.Millisecond)
+	w.Write([]byte("go build"))
+
+	if len(peer.lines) != 4 {
+		t.Fatalf("Want 4 lines, got %d", len(peer.lines))
+	}
+	if line := peer.lines[0]; line.Type != LineProgress || line.Phase != PhasePull || line.Out != "Pulling from library/golang" {
+		t.Errorf("Want pull progress line, got %+v", line)
+	}
+	if line := peer.lines[1]; line.Type != LineMetadata || line.Phase != PhasePull || line.Elapsed != 1000 {
+		t.Errorf("Want pull metadata line, got %+v", line)
+	}
+	if line := peer.lines[2]; line.Phase != PhaseSetup || line.Elapsed != 1 {
+		t.Errorf("Want setup metadata line, got %+v", line)
+	}
+	if line := peer.lines[3]; line.Phase != PhaseRun || line.Elapsed > 1000 {
+		t.Errorf("Want run line measured from the end of the setup, got %+v", line)
+	}
+}
@@ -12,8 +12,11 @@ import (
 type (
 	// Filter defines filters for fetching items from the queue.
 	Filter struct {
-		Labels map[string]string `json:"labels"`
-		Expr   string            `json:"expr"`
+		Labels   map[string]string `json:"labels"`
+		Expr     string            `json:"expr"`
+		Weight   int               `json:"weight,omitempty"`   // max task weight
+		Capacity int               `json:"capacity,omitempty"` // total agent weight
+		Node     map[string]string `json:"node,omitempty"`     // agent node labels
 	}
 
 	// State defines the pipeline state.
@@ -24,13 +27,26 @@ type (
 		Started  int64  `json:"started"`
 		Finished int64  `json:"finished"`
 		Error    string `json:"error"`
+
+		// AllowFailure reports the failure of the step does not fail
+		// the pipeline.
+		AllowFailure bool `json:"allow_failure,omitempty"`
 	}
 
 	// Pipeline defines the pipeline execution details.
 	Pipeline struct {
-		ID      string          `json:"id"`
-		Config  *backend.Config `json:"config"`
-		Timeout int64           `json:"timeout"`
+		ID      string             `json:"id"`
+		Config  *backend.Config    `json:"config"`
+		Timeout int64              `json:"timeout"`
+		Uploads map[string]*Upload `json:"uploads,omitempty"` // keyed by step alias
+		Weight  int                `json:"weight,omitempty"`
+		Trace   string             `json:"trace,omitempty"` // parent span context
+	}
+
+	// Upload defines the pre-signed upload urls of a pipeline step, which
+	// the agent uploads to directly instead of through the server.
+	Upload struct {
+		Logs string `json:"logs,omitempty"`
 	}
 
 	// File defines a pipeline artifact.
@@ -47,6 +63,12 @@ type (
 // NoFilter is an empty filter.
 var NoFilter = Filter{}
 
+// Identifies the agent hooks executed before and after the pipeline.
+const (
+	HookPre  = "pre_hook"
+	HookPost = "post_hook"
+)
+
 // Peer defines a peer-to-peer connection.
 type Peer interface {
 	// Next returns the next pipeline in the queue.
@@ -29,12 +29,19 @@ func NewServer(peer Peer) *Server {
 
 // ServeHTTP implements an http.Handler that answers rpc requests.
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Serve(context.Background(), w, r)
+}
+
+// Serve answers rpc requests until the client disconnects, or until the
+// context is cancelled, which cancels the pending requests and closes the
+// connection.
+func (s *Server) Serve(parent context.Context, w http.ResponseWriter, r *http.Request) {
 	upgrader := websocket.Upgrader{}
 	c, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		return
 	}
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(parent)
 	conn := jsonrpc2.NewConn(ctx,
 		websocketrpc.NewObjectStream(c),
 		jsonrpc2.HandlerWithError(s.router),
@@ -43,7 +50,10 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		cancel()
 		conn.Close()
 	}()
-	<-conn.DisconnectNotify()
+	select {
+	case <-conn.DisconnectNotify():
+	case <-ctx.Done():
+	}
 }
 
 // router implements an jsonrpc2.Handler that answers RPC requests.
@@ -29,6 +29,8 @@ type fifo struct {
 	running   map[string]*entry
 	pending   *list.List
 	extension time.Duration
+	limit     Limit
+	paused    bool
 }
 
 // New returns a new fifo queue.
@@ -41,10 +43,30 @@ func New() Queue {
 	}
 }
 
-// Push pushes an item to the tail of this queue.
+// NewLimited returns a new fifo queue that holds pending tasks while the
+// limit is exceeded by the running tasks.
+func NewLimited(limit Limit) Queue {
+	q := New().(*fifo)
+	q.limit = limit
+	return q
+}
+
+// Push pushes an item to the tail of this queue, ahead of any pending
+// items with a lower priority.
 func (q *fifo) Push(c context.Context, task *Task) error {
 	q.Lock()
-	q.pending.PushBack(task)
+	var mark *list.Element
+	for e := q.pending.Back(); e != nil; e = e.Prev() {
+		if e.Value.(*Task).Priority >= task.Priority {
+			break
+		}
+		mark = e
+	}
+	if mark != nil {
+		q.pending.InsertBefore(task, mark)
+	} else {
+		q.pending.PushBack(task)
+	}
 	q.Unlock()
 	go q.process()
 	return nil
@@ -89,6 +111,10 @@ func (q *fifo) Error(c context.Context, id string, err error) error {
 		delete(q.running, id)
 	}
 	q.Unlock()
+	// pending tasks held by the limit may run once the task completes.
+	if ok && q.limit != nil {
+		go q.process()
+	}
 	return nil
 }
 
@@ -137,6 +163,20 @@ func (q *fifo) Extend(c context.Context, id string) error {
 	return ErrNotFound
 }
 
+// Lease adds the task to the running tasks, as if it was retrieved by a
+// worker. The task is returned to the pending tasks if the deadline is not
+// extended before it expires.
+func (q *fifo) Lease(c context.Context, task *Task) error {
+	q.Lock()
+	q.running[task.ID] = &entry{
+		item:     task,
+		done:     make(chan bool),
+		deadline: time.Now().Add(q.extension),
+	}
+	q.Unlock()
+	return nil
+}
+
 // Info returns internal queue information.
 func (q *fifo) Info(c context.Context) InfoT {
 	q.Lock()
@@ -144,6 +184,7 @@ func (q *fifo) Info(c context.Context) InfoT {
 	stats.Stats.Workers = len(q.workers)
 	stats.Stats.Pending = q.pending.Len()
 	stats.Stats.Running = len(q.running)
+	stats.Paused = q.paused
 
 	for e := q.pending.Front(); e != nil; e = e.Next() {
 		stats.Pending = append(stats.Pending, e.Value.(*Task))
@@ -156,6 +197,21 @@ func (q *fifo) Info(c context.Context) InfoT {
 	return stats
 }
 
+// Pause stops the queue from dispatching pending tasks to workers.
+func (q *fifo) Pause() {
+	q.Lock()
+	q.paused = true
+	q.Unlock()
+}
+
+// Resume resumes dispatching pending tasks to workers.
+func (q *fifo) Resume() {
+	q.Lock()
+	q.paused = false
+	q.Unlock()
+	go q.process()
+}
+
 // helper function that loops through the queue and attempts to
 // match the item to a single subscriber.
 func (q *fifo) process() {
@@ -183,11 +239,25 @@ func (q *fifo) process() {
 		}
 	}
 
+	if q.paused {
+		return
+	}
+
+	var running []*Task
+	if q.limit != nil {
+		for _, state := range q.running {
+			running = append(running, state.item)
+		}
+	}
+
 	var next *list.Element
 loop:
 	for e := q.pending.Front(); e != nil; e = next {
 		next = e.Next()
 		item := e.Value.(*Task)
+		if q.limit != nil && q.limit(item, running) {
+			continue
+		}
 		for w := range q.workers {
 			if w.filter(item) {
 				delete(q.workers, w)
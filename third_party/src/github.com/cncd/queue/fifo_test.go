@@ -0,0 +1,81 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFifoLimit(t *testing.T) {
+	// at most one task of each repository runs at a time.
+	limit := func(task *Task, running []*Task) bool {
+		for _, t := range running {
+			if t.Labels["repo"] == task.Labels["repo"] {
+				return true
+			}
+		}
+		return false
+	}
+	q := NewLimited(limit)
+	q.Push(noContext, &Task{ID: "1", Labels: map[string]string{"repo": "octocat/hello-world"}})
+	q.Push(noContext, &Task{ID: "2", Labels: map[string]string{"repo": "octocat/hello-world"}})
+	q.Push(noContext, &Task{ID: "3", Labels: map[string]string{"repo": "octocat/spoon-knife"}})
+
+	if got := poll(q); got == nil || got.ID != "1" {
+		t.Fatalf("Want task 1, got %v", got)
+	}
+	if got := poll(q); got == nil || got.ID != "3" {
+		t.Fatalf("Want task 3 of another repository, got %v", got)
+	}
+	if got := poll(q); got != nil {
+		t.Fatalf("Want task 2 held by the limit, got task %s", got.ID)
+	}
+
+	q.Done(noContext, "1")
+	if got := poll(q); got == nil || got.ID != "2" {
+		t.Errorf("Want task 2 once task 1 is done, got %v", got)
+	}
+}
+
+func TestFifoPriority(t *testing.T) {
+	q := New()
+	q.Push(noContext, &Task{ID: "1"})
+	q.Push(noContext, &Task{ID: "2", Priority: 10})
+	q.Push(noContext, &Task{ID: "3", Priority: -5})
+	q.Push(noContext, &Task{ID: "4", Priority: 10})
+	q.Push(noContext, &Task{ID: "5"})
+
+	for _, want := range []string{"2", "4", "1", "5", "3"} {
+		if got := poll(q); got == nil || got.ID != want {
+			t.Errorf("Want task %s, got %v", want, got)
+		}
+	}
+}
+
+func TestFifoPause(t *testing.T) {
+	q := New()
+	q.Pause()
+	q.Push(noContext, &Task{ID: "1"})
+	if got := poll(q); got != nil {
+		t.Fatalf("Want no task while the queue is paused, got task %s", got.ID)
+	}
+	if info := q.Info(noContext); !info.Paused || len(info.Pending) != 1 {
+		t.Errorf("Want paused queue with one pending task, got paused %v with %d pending", info.Paused, len(info.Pending))
+	}
+
+	q.Resume()
+	if got := poll(q); got == nil || got.ID != "1" {
+		t.Errorf("Want task 1 once the queue is resumed, got %v", got)
+	}
+}
+
+var noContext = context.Background()
+
+// poll returns the next task of the queue, or nil if no task is dispatched
+// before the timeout.
+func poll(q Queue) *Task {
+	c, cancel := context.WithTimeout(noContext, 50*time.Millisecond)
+	defer cancel()
+	task, _ := q.Poll(c, func(*Task) bool { return true })
+	return task
+}
@@ -23,12 +23,17 @@ type Task struct {
 
 	// Labels represents the key-value pairs the entry is lebeled with.
 	Labels map[string]string `json:"labels,omitempty"`
+
+	// Priority is the task priority. Tasks with a higher priority are
+	// dispatched before pending tasks with a lower priority.
+	Priority int `json:"priority,omitempty"`
 }
 
 // InfoT provides runtime information.
 type InfoT struct {
 	Pending []*Task `json:"pending"`
 	Running []*Task `json:"running"`
+	Paused  bool    `json:"paused"`
 	Stats   struct {
 		Workers  int `json:"worker_count"`
 		Pending  int `json:"pending_count"`
@@ -41,6 +46,10 @@ type InfoT struct {
 // the Task is skipped and not returned to the subscriber.
 type Filter func(*Task) bool
 
+// Limit limits the tasks that run concurrently. If the Limit returns true,
+// the Task remains pending until the running tasks complete.
+type Limit func(task *Task, running []*Task) bool
+
 // Queue defines a task queue for scheduling tasks among
 // a pool of workers.
 type Queue interface {
@@ -67,6 +76,21 @@ type Queue interface {
 
 	// Info returns internal queue information.
 	Info(c context.Context) InfoT
+
+	// Pause stops the queue from dispatching pending tasks to workers.
+	Pause()
+
+	// Resume resumes dispatching pending tasks to workers.
+	Resume()
+}
+
+// Leaser is implemented by queues that can restore the tasks that were
+// running before the queue was stopped.
+type Leaser interface {
+	// Lease adds the task to the running tasks, as if it was retrieved by
+	// a worker. The task is returned to the pending tasks if the deadline
+	// is not extended before it expires.
+	Lease(c context.Context, task *Task) error
 }
 
 // // global instance of the queue.
@@ -0,0 +1,62 @@
+package envsubst
+
+import "testing"
+
+func TestEval(t *testing.T) {
+	environ := map[string]string{
+		"BRANCH": "feature/login",
+		"COMMIT": "4fd7a52c",
+		"EMPTY":  "",
+	}
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "${COMMIT:0:4}", want: "4fd7"},
+		{in: "${COMMIT:4}", want: "a52c"},
+		{in: "${COMMIT: -3}", want: "52c"},
+		{in: "${COMMIT:(-3)}", want: "52c"},
+		{in: "${COMMIT:2:-2}", want: "d7a5"},
+		{in: "${COMMIT:6:10}", want: "2c"},
+		{in: "${UNSET:-}", want: ""},
+		{in: "${COMMIT:8}", want: ""},
+		{in: "${COMMIT:9}", want: ""},
+		{in: "${COMMIT: -9}", want: ""},
+		{in: "${COMMIT:x}", want: "4fd7a52c"},
+		{in: "${COMMIT:+set}", want: "set"},
+		{in: "${EMPTY:+set}", want: ""},
+		{in: "${UNSET:-default}", want: "default"},
+		{in: "${BRANCH#*/}", want: "login"},
+		{in: "${BRANCH%/*}", want: "feature"},
+		{in: "${BRANCH##*/}", want: "login"},
+		{in: "${BRANCH#[!f]*/}", want: "feature/login"},
+		{in: "${COMMIT:?missing}", want: "4fd7a52c"},
+	}
+	for _, test := range tests {
+		got, err := Eval(test.in, func(s string) string { return environ[s] })
+		if err != nil {
+			t.Errorf("Want %q evaluated, got %s", test.in, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Want %q evaluated to %q, got %q", test.in, test.want, got)
+		}
+	}
+}
+
+func TestEvalRequired(t *testing.T) {
+	tests := []struct {
+		in  string
+		err string
+	}{
+		{in: "${UNSET:?}", err: "UNSET: parameter null or not set"},
+		{in: "${UNSET:?tag is required}", err: "UNSET: tag is required"},
+		{in: "${EMPTY:?}", err: "EMPTY: parameter null or not set"},
+	}
+	for _, test := range tests {
+		_, err := Eval(test.in, func(string) string { return "" })
+		if err == nil || err.Error() != test.err {
+			t.Errorf("Want %q error %q, got %v", test.in, test.err, err)
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package envsubst
 
 import (
-	"path"
 	"strconv"
 	"strings"
 	"unicode"
@@ -57,43 +56,68 @@ func toDefault(s string, args ...string) string {
 	return s
 }
 
+// toAlternate returns a copy of the first string argument if the
+// string s is not empty, else returns an empty string.
+func toAlternate(s string, args ...string) string {
+	if len(s) == 0 || len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
+
 // toSubstr returns a slice of the string s at the specified
-// length and position.
+// length and position. A negative position is counted from the
+// end of the string, and a negative length is the position from
+// the end of the string at which the slice ends.
 func toSubstr(s string, args ...string) string {
 	if len(args) == 0 {
 		return s // should never happen
 	}
 
-	pos, err := strconv.Atoi(args[0])
+	// a negative position is written as " -1" or "(-1)", since ":-"
+	// is the default value operator.
+	pos, err := strconv.Atoi(strings.Trim(strings.TrimSpace(args[0]), "()"))
 	if err != nil {
 		// bash returns the string if the position
 		// cannot be parsed.
 		return s
 	}
-
-	if len(args) == 1 {
-		if pos < len(s) {
-			return s[pos:]
+	if pos < 0 {
+		pos += len(s)
+		if pos < 0 {
+			// bash returns an empty string if the
+			// position precedes the start of the string
+			return ""
 		}
+	}
+	if pos > len(s) {
 		// if the position exceeds the length of the
 		// string an empty string is returned
 		return ""
 	}
 
-	length, err := strconv.Atoi(args[1])
+	if len(args) == 1 {
+		return s[pos:]
+	}
+
+	length, err := strconv.Atoi(strings.TrimSpace(args[1]))
 	if err != nil {
 		// bash returns the string if the length
 		// cannot be parsed.
 		return s
 	}
 
-	if pos+length >= len(s) {
-		// if the position exceeds the length of the
-		// string an empty string is returned
+	end := pos + length
+	if length < 0 {
+		end = len(s) + length
+	}
+	if end > len(s) {
+		end = len(s)
+	}
+	if end < pos {
 		return ""
 	}
-
-	return s[pos : pos+length]
+	return s[pos:end]
 }
 
 // replaceAll returns a copy of the string s with all instances
@@ -184,7 +208,7 @@ func trimLongestSuffix(s string, args ...string) string {
 func trimShortest(s, arg string) string {
 	var shortestMatch string
 	for i :=0 ; i < len(s); i++ {
-		match, err := path.Match(arg, s[0:len(s)-i])
+		match, err := matchPattern(arg, s[0:len(s)-i])
 
 		if err != nil {
 			return s
@@ -204,7 +228,7 @@ func trimShortest(s, arg string) string {
 
 func trimLongest(s, arg string) string {
 	for i :=0 ; i < len(s); i++ {
-		match, err := path.Match(arg, s[0:len(s)-i])
+		match, err := matchPattern(arg, s[0:len(s)-i])
 
 		if err != nil {
 			return s
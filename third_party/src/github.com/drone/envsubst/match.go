@@ -1,20 +1,50 @@
 package envsubst
 
-func matches() {
+import (
+	"regexp"
+	"strings"
+)
 
+// matchPattern reports whether the string s matches the shell pattern. Unlike
+// path.Match, the * and ? wildcards also match the / character, which is
+// the behavior of the shell parameter expansion patterns.
+func matchPattern(pattern, s string) (bool, error) {
+	re, err := regexp.Compile("^" + patternToRegexp(pattern) + "$")
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(s), nil
 }
 
-// alnum
-// alpha
-// ascii
-// blank
-// cntrl
-// digit
-// graph
-// lower
-// print
-// punct
-// space
-// upper
-// word
-// xdigit
+// helper function converts the shell pattern to a regular expression.
+func patternToRegexp(pattern string) string {
+	var buf strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			buf.WriteString(".*")
+		case '?':
+			buf.WriteString(".")
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				buf.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+			}
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				buf.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			buf.WriteString("[" + strings.Replace(class, `\`, `\\`, -1) + "]")
+			i += end + 1
+		default:
+			buf.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return buf.String()
+}
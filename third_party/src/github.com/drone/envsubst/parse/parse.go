@@ -290,6 +290,11 @@ func (t *Tree) parseDefaultFunc(name string) (Node, error) {
 		return nil, ErrBadSubstitution
 	}
 
+	// the word is optional, e.g. ${parameter:?}
+	if t.scanner.peek() == '}' {
+		return node, t.consumeRbrack()
+	}
+
 	// scan arg[1]
 	{
 		param, err := t.parseParam(acceptNotClosing, scanIdent)
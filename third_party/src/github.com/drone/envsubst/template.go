@@ -2,6 +2,7 @@ package envsubst
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"io/ioutil"
 
@@ -108,6 +109,16 @@ func (t *Template) evalFunc(s *state, node *parse.FuncNode) error {
 
 	v := s.mapper(node.Param)
 
+	// the parameter is required if the error function is used, and
+	// the argument is the error message.
+	if (node.Name == ":?" || node.Name == "?") && v == "" {
+		msg := "parameter null or not set"
+		if len(args) != 0 && args[0] != "" {
+			msg = args[0]
+		}
+		return fmt.Errorf("%s: %s", node.Param, msg)
+	}
+
 	fn := lookupFunc(node.Name, len(args))
 
 	_, err := io.WriteString(s.writer, fn(v, args...))
@@ -149,7 +160,9 @@ func lookupFunc(name string, args int) substituteFunc {
 		return replaceAll
 	case "=", ":=", ":-":
 		return toDefault
-	case ":?", ":+", "-", "+":
+	case ":+", "+":
+		return toAlternate
+	case ":?", "?", "-":
 		return toDefault
 	default:
 		return toDefault
@@ -0,0 +1,27 @@
+/*
+Copyright 2016 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package jsonnet implements a parser and evaluator for jsonnet.
+
+Jsonnet is a domain specific configuration language that helps you define JSON
+data. Jsonnet lets you compute fragments of JSON within the structure, bringing
+the same benefit to structured data that templating languages bring to plain
+text.
+
+See http://jsonnet.org/ for a full language description and tutorial.
+*/
+package jsonnet